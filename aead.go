@@ -0,0 +1,67 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"runtime"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/sys/cpu"
+)
+
+// WithAutoAlgorithm makes the provider pick the AEAD for new encryptions
+// from the CPU's capabilities: AES-256-GCM where the CPU accelerates AES and
+// GCM, and ChaCha20-Poly1305 otherwise, which is several times faster in
+// software. Decryption always follows the algorithm byte in the header, so
+// values written by either choice stay readable everywhere.
+//
+// Both algorithms use 32-byte keys, 12-byte nonces, and 16-byte tags, so the
+// header layout is unchanged. Without this option, providers always use
+// AES-256-GCM.
+func WithAutoAlgorithm() ProviderOption {
+	return func(o *providerOptions) {
+		o.algorithm = autoAlgorithm()
+	}
+}
+
+// autoAlgorithm returns algAES256GCM when the CPU has hardware AES and
+// carry-less multiplication, and algChaCha20Poly1305 otherwise.
+func autoAlgorithm() byte {
+	if hasAESGCMHardware() {
+		return algAES256GCM
+	}
+	return algChaCha20Poly1305
+}
+
+// hasAESGCMHardware reports whether AES-GCM is hardware-accelerated on this
+// CPU, using the same feature checks as crypto/tls.
+func hasAESGCMHardware() bool {
+	switch runtime.GOARCH {
+	case "amd64", "386":
+		return cpu.X86.HasAES && cpu.X86.HasPCLMULQDQ
+	case "arm64":
+		return cpu.ARM64.HasAES && cpu.ARM64.HasPMULL
+	case "s390x":
+		return cpu.S390X.HasAES && cpu.S390X.HasAESCBC && cpu.S390X.HasAESCTR &&
+			(cpu.S390X.HasGHASH || cpu.S390X.HasAESGCM)
+	default:
+		return false
+	}
+}
+
+// newAEAD returns the AEAD identified by a header algorithm byte.
+func newAEAD(alg byte, key []byte) (cipher.AEAD, error) {
+	switch alg {
+	case algAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case algChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %d", ErrInvalidFormat, alg)
+	}
+}
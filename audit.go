@@ -0,0 +1,153 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Operation names reported by an audited KeySource.
+const (
+	OpCurrentKey = "current_key" // KeySource.CurrentKey
+	OpKeyByID    = "key_by_id"   // KeySource.KeyByID
+)
+
+// AuditLogger writes one structured slog record per key access: which
+// operation used which key, through which codec or provider, and whether it
+// succeeded. It implements Metrics, so it attaches to a Codec with
+// WithMetrics and to a Provider with InstrumentProvider; NewAuditProvider
+// and NewAuditKeySource are shorthands.
+//
+// Records never contain plaintext or key material: the attributes are built
+// only from Observation fields and key IDs, and Key redacts its bytes when
+// logged.
+type AuditLogger struct {
+	logger   *slog.Logger
+	level    slog.Level
+	ctxAttrs func(context.Context) []slog.Attr
+}
+
+// AuditOption configures NewAuditLogger.
+type AuditOption func(*AuditLogger)
+
+// WithAuditLevel sets the level of records for successful operations.
+// Failures are always logged at slog.LevelWarn or above. Default:
+// slog.LevelInfo.
+func WithAuditLevel(level slog.Level) AuditOption {
+	return func(a *AuditLogger) { a.level = level }
+}
+
+// WithAuditContext adds caller-supplied attributes to every record, such as
+// a request ID or the authenticated principal taken from ctx.
+func WithAuditContext(fn func(ctx context.Context) []slog.Attr) AuditOption {
+	return func(a *AuditLogger) { a.ctxAttrs = fn }
+}
+
+// Compile-time interface check.
+var _ Metrics = (*AuditLogger)(nil)
+
+// NewAuditLogger returns an AuditLogger writing to logger, or to
+// slog.Default() if logger is nil.
+func NewAuditLogger(logger *slog.Logger, opts ...AuditOption) *AuditLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	a := &AuditLogger{logger: logger, level: slog.LevelInfo}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Observe logs obs as a "crypto: key access" record.
+func (a *AuditLogger) Observe(ctx context.Context, obs Observation) {
+	a.log(ctx, obs.Operation, obs.Component, obs.KeyID, obs.Err,
+		slog.Int("size", obs.Size), slog.Duration("duration", obs.Duration))
+}
+
+func (a *AuditLogger) log(ctx context.Context, op, component, keyID string, err error, extra ...slog.Attr) {
+	level := a.level
+	result := "ok"
+	if err != nil {
+		level = max(level, slog.LevelWarn)
+		result = "error"
+	}
+	if !a.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := make([]slog.Attr, 0, 6+len(extra))
+	attrs = append(attrs,
+		slog.String("operation", op),
+		slog.String("component", component),
+		slog.String("key_id", keyID),
+		slog.String("result", result),
+	)
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()), slog.String("error_kind", ErrorKind(err)))
+	}
+	attrs = append(attrs, extra...)
+	if a.ctxAttrs != nil {
+		attrs = append(attrs, a.ctxAttrs(ctx)...)
+	}
+	a.logger.LogAttrs(ctx, level, "crypto: key access", attrs...)
+}
+
+// NewAuditProvider returns a Provider that audits every Encrypt and Decrypt
+// on p to logger. It is InstrumentProvider(p, NewAuditLogger(logger, opts...)).
+func NewAuditProvider(p Provider, logger *slog.Logger, opts ...AuditOption) Provider {
+	return InstrumentProvider(p, NewAuditLogger(logger, opts...))
+}
+
+// auditKeySource logs every lookup on a KeySource.
+type auditKeySource struct {
+	src   KeySource
+	audit *AuditLogger
+}
+
+// NewAuditKeySource returns a KeySource that audits every CurrentKey and
+// KeyByID call on src to logger, with the component "keysource". Place it
+// beneath a CachingKeySource to audit only fetches from the backend, or
+// above it to audit every use. Close closes src if it implements io.Closer.
+func NewAuditKeySource(src KeySource, logger *slog.Logger, opts ...AuditOption) KeySource {
+	return &auditKeySource{src: src, audit: NewAuditLogger(logger, opts...)}
+}
+
+func (s *auditKeySource) CurrentKey(ctx context.Context) (Key, error) {
+	start := time.Now()
+	k, err := s.src.CurrentKey(ctx)
+	s.audit.log(ctx, OpCurrentKey, "keysource", k.ID, err, slog.Duration("duration", time.Since(start)))
+	return k, err
+}
+
+func (s *auditKeySource) KeyByID(ctx context.Context, id string) (Key, error) {
+	start := time.Now()
+	k, err := s.src.KeyByID(ctx, id)
+	s.audit.log(ctx, OpKeyByID, "keysource", id, err, slog.Duration("duration", time.Since(start)))
+	return k, err
+}
+
+// Close closes the wrapped source if it implements io.Closer.
+func (s *auditKeySource) Close() error {
+	if c, ok := s.src.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Compile-time interface checks.
+var (
+	_ slog.LogValuer = Key{}
+	_ fmt.Formatter  = Key{}
+)
+
+// LogValue redacts the key bytes so a Key passed to slog logs only its ID.
+func (k Key) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", k.ID), slog.String("bytes", "[REDACTED]"))
+}
+
+// Format redacts the key bytes for every fmt verb, including %v, %+v, %#v,
+// %x, and %s.
+func (k Key) Format(f fmt.State, _ rune) {
+	_, _ = fmt.Fprintf(f, "Key{ID:%q, Bytes:[REDACTED]}", k.ID)
+}
@@ -0,0 +1,157 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+type requestIDKey struct{}
+
+// auditRecords decodes the JSON lines written by a slog.JSONHandler.
+func auditRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("bad log line %q: %v", line, err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// assertNoKeyBytes fails if key appears in out in any common encoding.
+func assertNoKeyBytes(t *testing.T, out string, key []byte) {
+	t.Helper()
+	for _, enc := range []string{
+		string(key),
+		hex.EncodeToString(key),
+		base64.StdEncoding.EncodeToString(key),
+		fmt.Sprint([]byte(key)),
+	} {
+		if strings.Contains(out, enc) {
+			t.Fatalf("key bytes leaked into log output:\n%s", out)
+		}
+	}
+}
+
+func TestAuditProvider(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	key := makeKey(32)
+	p := NewAuditProvider(mustNewProvider(t, key, "key-1"), logger,
+		WithAuditContext(func(ctx context.Context) []slog.Attr {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			return []slog.Attr{slog.String("request_id", id)}
+		}))
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-42")
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := p.Decrypt(ctx, ct); err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if _, err := p.Decrypt(ctx, []byte("garbage")); err == nil {
+		t.Fatal("Decrypt garbage: expected error")
+	}
+
+	recs := auditRecords(t, &buf)
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3:\n%s", len(recs), buf.String())
+	}
+	for i, want := range []struct{ op, result, level string }{
+		{OpEncrypt, "ok", "INFO"},
+		{OpDecrypt, "ok", "INFO"},
+		{OpDecrypt, "error", "WARN"},
+	} {
+		r := recs[i]
+		if r["msg"] != "crypto: key access" || r["operation"] != want.op || r["result"] != want.result ||
+			r["level"] != want.level || r["request_id"] != "req-42" {
+			t.Errorf("record %d = %v, want %+v", i, r, want)
+		}
+	}
+	if recs[0]["key_id"] != "key-1" || recs[1]["key_id"] != "key-1" {
+		t.Errorf("key IDs = %v, %v", recs[0]["key_id"], recs[1]["key_id"])
+	}
+	if recs[2]["error_kind"] != "invalid_format" {
+		t.Errorf("error_kind = %v", recs[2]["error_kind"])
+	}
+	assertNoKeyBytes(t, buf.String(), key)
+}
+
+func TestAuditLoggerOnCodec(t *testing.T) {
+	var buf bytes.Buffer
+	audit := NewAuditLogger(slog.New(slog.NewTextHandler(&buf, nil)), WithAuditLevel(slog.LevelDebug))
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "key-1"), WithMetrics(audit))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	if _, err := c.Encode(context.Background(), "v"); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	// Debug is below the handler's Info threshold, so nothing is written.
+	if buf.Len() != 0 {
+		t.Errorf("debug-level audit record was written: %s", buf.String())
+	}
+}
+
+func TestAuditKeySource(t *testing.T) {
+	var buf bytes.Buffer
+	src := newFakeKeySource("k1")
+	ks := NewAuditKeySource(src, slog.New(slog.NewJSONHandler(&buf, nil)))
+	ctx := context.Background()
+
+	k, err := ks.CurrentKey(ctx)
+	if err != nil {
+		t.Fatalf("CurrentKey: %v", err)
+	}
+	// Logging the Key itself must not leak its bytes either.
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("fetched", "key", k)
+	if _, err := ks.KeyByID(ctx, "nope"); !IsKeyNotFound(err) {
+		t.Fatalf("KeyByID: got %v, want ErrKeyNotFound", err)
+	}
+
+	recs := auditRecords(t, &buf)
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3:\n%s", len(recs), buf.String())
+	}
+	if recs[0]["operation"] != OpCurrentKey || recs[0]["key_id"] != "k1" || recs[0]["component"] != "keysource" {
+		t.Errorf("CurrentKey record = %v", recs[0])
+	}
+	if recs[2]["operation"] != OpKeyByID || recs[2]["key_id"] != "nope" || recs[2]["result"] != "error" {
+		t.Errorf("KeyByID record = %v", recs[2])
+	}
+	assertNoKeyBytes(t, buf.String(), src.keys["k1"])
+
+	if err := ks.(interface{ Close() error }).Close(); err != nil || !src.closed.Load() {
+		t.Errorf("Close = %v, source closed = %v", err, src.closed.Load())
+	}
+}
+
+func TestKeyRedactedFormatting(t *testing.T) {
+	k := Key{ID: "k1", Bytes: []byte("super-secret-key-material-32byte")}
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%x", "%q"} {
+		out := fmt.Sprintf(verb, k)
+		if strings.Contains(out, "super-secret") || strings.Contains(out, hex.EncodeToString(k.Bytes)) {
+			t.Errorf("%s leaked key bytes: %s", verb, out)
+		}
+		if !strings.Contains(out, "k1") {
+			t.Errorf("%s lost the key ID: %s", verb, out)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package awskms
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"crypto/sha1" // #nosec G505 -- RSAES-OAEP default hash, as KMS may encode it
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// CMS (RFC 5652) object identifiers used by KMS CiphertextForRecipient.
+var (
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidRSAESOAEP     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 7}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidAES256CBC     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// errCMS reports a CiphertextForRecipient this package cannot open.
+var errCMS = errors.New("awskms: malformed CMS enveloped data")
+
+// berNode is one BER TLV. Children are parsed for constructed encodings.
+type berNode struct {
+	class, tag  int
+	constructed bool
+	content     []byte // primitive contents
+	children    []berNode
+}
+
+// parseBER reads one TLV from b, accepting the indefinite lengths and
+// constructed strings that KMS emits, and returns the rest of b.
+func parseBER(b []byte, depth int) (berNode, []byte, error) {
+	if depth > 32 || len(b) < 2 {
+		return berNode{}, nil, errCMS
+	}
+	n := berNode{class: int(b[0] >> 6), constructed: b[0]&0x20 != 0, tag: int(b[0] & 0x1f)}
+	b = b[1:]
+	if n.tag == 0x1f { // high tag number form
+		n.tag = 0
+		for {
+			if len(b) == 0 || n.tag > 1<<20 {
+				return berNode{}, nil, errCMS
+			}
+			c := b[0]
+			b = b[1:]
+			n.tag = n.tag<<7 | int(c&0x7f)
+			if c&0x80 == 0 {
+				break
+			}
+		}
+	}
+	if len(b) == 0 {
+		return berNode{}, nil, errCMS
+	}
+	l := int(b[0])
+	b = b[1:]
+	if l == 0x80 { // indefinite: children up to the end-of-contents octets
+		if !n.constructed {
+			return berNode{}, nil, errCMS
+		}
+		for {
+			if len(b) >= 2 && b[0] == 0 && b[1] == 0 {
+				return n, b[2:], nil
+			}
+			child, rest, err := parseBER(b, depth+1)
+			if err != nil {
+				return berNode{}, nil, err
+			}
+			n.children = append(n.children, child)
+			b = rest
+		}
+	}
+	if l > 0x80 {
+		size := l & 0x7f
+		if size > 4 || len(b) < size {
+			return berNode{}, nil, errCMS
+		}
+		l = 0
+		for _, c := range b[:size] {
+			l = l<<8 | int(c)
+		}
+		b = b[size:]
+	}
+	if l < 0 || len(b) < l {
+		return berNode{}, nil, errCMS
+	}
+	body, rest := b[:l], b[l:]
+	if !n.constructed {
+		n.content = body
+		return n, rest, nil
+	}
+	for len(body) > 0 {
+		child, r, err := parseBER(body, depth+1)
+		if err != nil {
+			return berNode{}, nil, err
+		}
+		n.children = append(n.children, child)
+		body = r
+	}
+	return n, rest, nil
+}
+
+// bytes returns the contents of a primitive string, or the concatenated
+// segments of a constructed one.
+func (n berNode) bytes() []byte {
+	if !n.constructed {
+		return n.content
+	}
+	var out []byte
+	for _, c := range n.children {
+		out = append(out, c.bytes()...)
+	}
+	return out
+}
+
+// oid decodes n as an OBJECT IDENTIFIER.
+func (n berNode) oid() (asn1.ObjectIdentifier, bool) {
+	if n.class != 0 || n.tag != asn1.TagOID || n.constructed {
+		return nil, false
+	}
+	der, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagOID, Bytes: n.content})
+	if err != nil {
+		return nil, false
+	}
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(der, &oid); err != nil {
+		return nil, false
+	}
+	return oid, true
+}
+
+// universal reports whether n is a universal-class node with the tag.
+func (n berNode) universal(tag int) bool { return n.class == 0 && n.tag == tag }
+
+// openEnveloped decrypts a CMS EnvelopedData whose content key was
+// transported with RSAES-OAEP to priv and whose content is AES-256-CBC
+// encrypted, which is what KMS returns in CiphertextForRecipient.
+func openEnveloped(data []byte, priv *rsa.PrivateKey) ([]byte, error) {
+	ci, _, err := parseBER(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	// ContentInfo ::= SEQUENCE { contentType, [0] EXPLICIT content }
+	if !ci.universal(asn1.TagSequence) || len(ci.children) < 2 {
+		return nil, errCMS
+	}
+	if oid, ok := ci.children[0].oid(); !ok || !oid.Equal(oidEnvelopedData) {
+		return nil, fmt.Errorf("%w: not enveloped data", errCMS)
+	}
+	wrapper := ci.children[1]
+	if wrapper.class != 2 || wrapper.tag != 0 || len(wrapper.children) != 1 {
+		return nil, errCMS
+	}
+
+	// EnvelopedData ::= SEQUENCE { version, [0] originatorInfo OPTIONAL,
+	//   recipientInfos SET, encryptedContentInfo, [1] unprotectedAttrs OPTIONAL }
+	ed := wrapper.children[0]
+	if !ed.universal(asn1.TagSequence) {
+		return nil, errCMS
+	}
+	var recipients, eci *berNode
+	for i := range ed.children {
+		c := &ed.children[i]
+		switch {
+		case c.universal(asn1.TagSet) && recipients == nil:
+			recipients = c
+		case c.universal(asn1.TagSequence) && recipients != nil && eci == nil:
+			eci = c
+		}
+	}
+	if recipients == nil || eci == nil {
+		return nil, errCMS
+	}
+
+	contentKey, err := openRecipient(recipients.children, priv)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(contentKey)
+	return decryptContent(*eci, contentKey)
+}
+
+// openRecipient decrypts the content key from the first key transport
+// recipient the private key opens:
+//
+//	KeyTransRecipientInfo ::= SEQUENCE { version, rid,
+//	  keyEncryptionAlgorithm, encryptedKey OCTET STRING }
+func openRecipient(infos []berNode, priv *rsa.PrivateKey) ([]byte, error) {
+	var errs []error
+	for _, ri := range infos {
+		if !ri.universal(asn1.TagSequence) || len(ri.children) != 4 {
+			continue // not key transport
+		}
+		alg, encryptedKey := ri.children[2], ri.children[3]
+		h, err := oaepHash(alg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		key, err := rsa.DecryptOAEP(h, nil, priv, encryptedKey.bytes(), nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return key, nil
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w: no key transport recipient", errCMS)
+	}
+	return nil, fmt.Errorf("awskms: open CMS recipient: %w", errors.Join(errs...))
+}
+
+// oaepHash returns the hash of an RSAES-OAEP AlgorithmIdentifier; RFC 4055
+// defaults it to SHA-1.
+func oaepHash(alg berNode) (hash.Hash, error) {
+	if !alg.universal(asn1.TagSequence) || len(alg.children) == 0 {
+		return nil, errCMS
+	}
+	if oid, ok := alg.children[0].oid(); !ok || !oid.Equal(oidRSAESOAEP) {
+		return nil, fmt.Errorf("%w: key encryption is not RSAES-OAEP", errCMS)
+	}
+	if len(alg.children) < 2 || !alg.children[1].universal(asn1.TagSequence) {
+		return sha1.New(), nil // #nosec G401 -- RFC 4055 default
+	}
+	for _, p := range alg.children[1].children {
+		if p.class != 2 || p.tag != 0 || len(p.children) != 1 || len(p.children[0].children) == 0 {
+			continue
+		}
+		oid, ok := p.children[0].children[0].oid()
+		if !ok || !oid.Equal(oidSHA256) {
+			return nil, fmt.Errorf("%w: unsupported RSAES-OAEP hash", errCMS)
+		}
+		return sha256.New(), nil
+	}
+	return sha1.New(), nil // #nosec G401 -- RFC 4055 default
+}
+
+// decryptContent decrypts an AES-256-CBC EncryptedContentInfo:
+//
+//	EncryptedContentInfo ::= SEQUENCE { contentType,
+//	  contentEncryptionAlgorithm, [0] IMPLICIT encryptedContent }
+func decryptContent(eci berNode, key []byte) ([]byte, error) {
+	if len(eci.children) != 3 {
+		return nil, errCMS
+	}
+	alg, content := eci.children[1], eci.children[2]
+	if !alg.universal(asn1.TagSequence) || len(alg.children) != 2 || content.class != 2 || content.tag != 0 {
+		return nil, errCMS
+	}
+	if oid, ok := alg.children[0].oid(); !ok || !oid.Equal(oidAES256CBC) {
+		return nil, fmt.Errorf("%w: content encryption is not AES-256-CBC", errCMS)
+	}
+	iv, ct := alg.children[1].bytes(), content.bytes()
+	if len(key) != 32 || len(iv) != aes.BlockSize || len(ct) == 0 || len(ct)%aes.BlockSize != 0 {
+		return nil, errCMS
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	pt := make([]byte, len(ct))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(pt, ct)
+	pad := int(pt[len(pt)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(pt[len(pt)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		clear(pt)
+		return nil, fmt.Errorf("%w: bad padding", errCMS)
+	}
+	out := bytes.Clone(pt[:len(pt)-pad])
+	clear(pt)
+	return out, nil
+}
@@ -0,0 +1,102 @@
+package awskms
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+)
+
+// RecipientClient decrypts with AWS KMS on behalf of a Nitro Enclave.
+// Implement it with the KMS Decrypt API's Recipient parameter:
+//
+//	func (c *myAWSClient) DecryptForRecipient(ctx context.Context, keyID string, ciphertext, attestationDocument []byte) ([]byte, error) {
+//	    in := &kms.DecryptInput{CiphertextBlob: ciphertext, Recipient: &types.RecipientInfo{
+//	        AttestationDocument:    attestationDocument,
+//	        KeyEncryptionAlgorithm: types.KeyEncryptionMechanismRsaesOaepSha256,
+//	    }}
+//	    if keyID != "" { in.KeyId = aws.String(keyID) }
+//	    out, err := c.kms.Decrypt(ctx, in)
+//	    if err != nil { return nil, err }
+//	    return out.CiphertextForRecipient, nil
+//	}
+type RecipientClient interface {
+	// DecryptForRecipient decrypts ciphertext with the KMS key keyID (or
+	// the key named in the ciphertext when keyID is empty) and returns the
+	// plaintext enveloped to the public key in attestationDocument, as
+	// KMS's CiphertextForRecipient.
+	DecryptForRecipient(ctx context.Context, keyID string, ciphertext, attestationDocument []byte) (ciphertextForRecipient []byte, err error)
+}
+
+// Attester obtains attestation documents from the Nitro Security Module.
+// Implement it with an NSM library such as github.com/hf/nsm:
+//
+//	func (a *nsmAttester) Attest(_ context.Context, publicKey []byte) ([]byte, error) {
+//	    res, err := a.session.Send(&request.Attestation{PublicKey: publicKey})
+//	    if err != nil { return nil, err }
+//	    return res.Attestation.Document, nil
+//	}
+type Attester interface {
+	// Attest returns a signed attestation document embedding publicKey, a
+	// DER-encoded SubjectPublicKeyInfo.
+	Attest(ctx context.Context, publicKey []byte) (document []byte, err error)
+}
+
+// EnclaveClient is a Client for code running in an AWS Nitro Enclave. It
+// holds an RSA key pair generated inside the enclave and sends KMS an
+// attestation document for its public key with every Decrypt, so KMS
+// returns the plaintext enveloped to that key rather than in the clear.
+// Keys whose policies require enclave measurements (kms:RecipientAttestation
+// condition keys) can then be unwrapped, and plaintext KEKs never exist
+// outside the enclave:
+//
+//	client, err := awskms.NewEnclaveClient(&myAWSClient{...}, &nsmAttester{...})
+//	provider, err := awskms.New(ctx, client, awskms.WithEncryptedKey(blob, "key-1"))
+//
+// The parent instance only relays KMS requests; it sees the attestation
+// document and enveloped ciphertext, never the key. EnclaveClient works
+// with New and NewRefreshable and is safe for concurrent use.
+type EnclaveClient struct {
+	client    RecipientClient
+	attester  Attester
+	key       *rsa.PrivateKey
+	publicKey []byte
+}
+
+var _ Client = (*EnclaveClient)(nil)
+
+// NewEnclaveClient generates the enclave's RSA-2048 key pair and returns a
+// Client that decrypts through client with attestations from attester.
+func NewEnclaveClient(client RecipientClient, attester Attester) (*EnclaveClient, error) {
+	if client == nil {
+		return nil, fmt.Errorf("awskms: RecipientClient must not be nil")
+	}
+	if attester == nil {
+		return nil, fmt.Errorf("awskms: Attester must not be nil")
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("awskms: generate recipient key: %w", err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("awskms: marshal recipient key: %w", err)
+	}
+	return &EnclaveClient{client: client, attester: attester, key: key, publicKey: pub}, nil
+}
+
+// Decrypt has KMS decrypt ciphertext for this enclave and opens the
+// enveloped result with the enclave's private key. A fresh attestation
+// document is requested for each call.
+func (c *EnclaveClient) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	doc, err := c.attester.Attest(ctx, c.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("awskms: attest: %w", err)
+	}
+	enveloped, err := c.client.DecryptForRecipient(ctx, keyID, ciphertext, doc)
+	if err != nil {
+		return nil, err
+	}
+	return openEnveloped(enveloped, c.key)
+}
@@ -0,0 +1,184 @@
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeNSM returns attestation documents that are just "doc:" and the
+// public key.
+type fakeNSM struct{ err error }
+
+func (a fakeNSM) Attest(_ context.Context, publicKey []byte) ([]byte, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	return append([]byte("doc:"), publicKey...), nil
+}
+
+// enclaveKMS decrypts like mockClient and envelopes the result to the key
+// in the attestation document, as KMS does for a Recipient.
+type enclaveKMS struct {
+	keys map[string][]byte
+	ber  bool
+}
+
+func (m *enclaveKMS) DecryptForRecipient(_ context.Context, _ string, ciphertext, doc []byte) ([]byte, error) {
+	pt, ok := m.keys[string(ciphertext)]
+	if !ok {
+		return nil, errors.New("kms: InvalidCiphertextException")
+	}
+	der, ok := bytes.CutPrefix(doc, []byte("doc:"))
+	if !ok {
+		return nil, errors.New("kms: InvalidRequestException: bad attestation document")
+	}
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return envelope(pub.(*rsa.PublicKey), pt, m.ber)
+}
+
+var _ RecipientClient = (*enclaveKMS)(nil)
+
+// tlv encodes a DER TLV, or a BER one with indefinite length when indef
+// is set.
+func tlv(tag byte, indef bool, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	if indef {
+		return append(append([]byte{tag, 0x80}, body...), 0, 0)
+	}
+	der, _ := asn1.Marshal(asn1.RawValue{Class: int(tag >> 6), Tag: int(tag & 0x1f), IsCompound: tag&0x20 != 0, Bytes: body})
+	return der
+}
+
+func mustMarshal(v any) []byte {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// envelope builds the CMS EnvelopedData KMS returns in
+// CiphertextForRecipient: RSAES-OAEP-SHA256 key transport and AES-256-CBC
+// content. With ber set it uses indefinite lengths and a segmented
+// encryptedContent, as KMS does.
+func envelope(pub *rsa.PublicKey, pt []byte, ber bool) ([]byte, error) {
+	cek, iv := make([]byte, 32), make([]byte, aes.BlockSize)
+	_, _ = rand.Read(cek)
+	_, _ = rand.Read(iv)
+	encKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, cek, nil)
+	if err != nil {
+		return nil, err
+	}
+	pad := aes.BlockSize - len(pt)%aes.BlockSize
+	padded := append(bytes.Clone(pt), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	block, _ := aes.NewCipher(cek)
+	ct := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ct, padded)
+
+	sha256ID := tlv(0x30, false, mustMarshal(oidSHA256))
+	oaepParams := tlv(0x30, false,
+		tlv(0xa0, false, sha256ID),
+		tlv(0xa1, false, mustMarshal(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 8}), sha256ID),
+	)
+	recipient := tlv(0x30, false,
+		mustMarshal(2),
+		tlv(0x80, false, []byte("subject-key-id")),
+		tlv(0x30, false, mustMarshal(oidRSAESOAEP), oaepParams),
+		mustMarshal(encKey),
+	)
+	content := tlv(0x80, false, ct)
+	if ber {
+		half := len(ct) / 2
+		content = tlv(0xa0, true, mustMarshal(ct[:half]), mustMarshal(ct[half:]))
+	}
+	eci := tlv(0x30, ber,
+		mustMarshal(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}),
+		tlv(0x30, false, mustMarshal(oidAES256CBC), mustMarshal(iv)),
+		content,
+	)
+	ed := tlv(0x30, ber, mustMarshal(2), tlv(0x31, false, recipient), eci)
+	return tlv(0x30, ber, mustMarshal(oidEnvelopedData), tlv(0xa0, ber, ed)), nil
+}
+
+func TestEnclaveClient_New(t *testing.T) {
+	for _, ber := range []bool{false, true} {
+		name := "DER"
+		if ber {
+			name = "BER"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			kms := &enclaveKMS{keys: map[string][]byte{"ct-1": makeKey(1), "ct-2": makeKey(2)}, ber: ber}
+			client, err := NewEnclaveClient(kms, fakeNSM{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := New(ctx, client, WithEncryptedKey([]byte("ct-1"), "key-1"), WithEncryptedKey([]byte("ct-2"), "key-2"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+			ct, err := p.Encrypt(ctx, []byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := p.Decrypt(ctx, ct); err != nil || string(got) != "secret" {
+				t.Errorf("Decrypt = %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestEnclaveClient_Errors(t *testing.T) {
+	ctx := context.Background()
+	kms := &enclaveKMS{keys: map[string][]byte{"ct-1": makeKey(1)}}
+
+	nsmErr := errors.New("nsm: device unavailable")
+	client, err := NewEnclaveClient(kms, fakeNSM{err: nsmErr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Decrypt(ctx, "", []byte("ct-1")); !errors.Is(err, nsmErr) {
+		t.Errorf("expected attestation error, got %v", err)
+	}
+
+	// An envelope for another enclave's key does not open.
+	client, err = NewEnclaveClient(kms, fakeNSM{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	env, err := envelope(&other.PublicKey, makeKey(1), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openEnveloped(env, client.key); err == nil {
+		t.Error("expected error opening another recipient's envelope")
+	}
+	if _, err := openEnveloped([]byte("not cms"), client.key); err == nil || !strings.Contains(err.Error(), "CMS") {
+		t.Errorf("expected CMS error, got %v", err)
+	}
+	if _, err := openEnveloped(env[:len(env)/2], client.key); err == nil {
+		t.Error("expected error for truncated envelope")
+	}
+
+	if _, err := NewEnclaveClient(nil, fakeNSM{}); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := NewEnclaveClient(kms, nil); err == nil {
+		t.Error("expected error for nil attester")
+	}
+}
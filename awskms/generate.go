@@ -0,0 +1,64 @@
+package awskms
+
+import (
+	"context"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// DataKeyGenerator generates data keys with AWS KMS. Implement it with the
+// KMS GenerateDataKey API:
+//
+//	func (c *myAWSClient) GenerateDataKey(ctx context.Context, keyID string) ([]byte, []byte, error) {
+//	    out, err := c.kms.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+//	        KeyId: aws.String(keyID), KeySpec: types.DataKeySpecAes256,
+//	    })
+//	    if err != nil { return nil, nil, err }
+//	    return out.Plaintext, out.CiphertextBlob, nil
+//	}
+type DataKeyGenerator interface {
+	// GenerateDataKey returns a new AES-256 key in plaintext and encrypted
+	// under the KMS key keyID.
+	GenerateDataKey(ctx context.Context, keyID string) (plaintext, ciphertext []byte, err error)
+}
+
+// GenerateWrappedKey bootstraps a new environment in one call: it has KMS
+// generate a data key under kmsKeyID and returns the ciphertext blob to
+// persist together with a provider that already holds the plaintext key as
+// its current key. id names the key in the ring; if empty, the key's
+// crypto.KeyFingerprint is used. Later processes load the same key with
+//
+//	awskms.New(ctx, client, awskms.WithEncryptedKeyForKMSKey(ciphertext, id, kmsKeyID))
+//
+// where id is the returned provider's CurrentKeyID. opts configure the
+// provider as for crypto.NewKeyRingProvider. The plaintext is wiped once it
+// is in the ring. The caller owns the returned provider and must Close it.
+func GenerateWrappedKey(ctx context.Context, g DataKeyGenerator, kmsKeyID, id string, opts ...crypto.ProviderOption) ([]byte, crypto.KeyRingProvider, error) {
+	if g == nil {
+		return nil, nil, fmt.Errorf("awskms: DataKeyGenerator must not be nil")
+	}
+	if kmsKeyID == "" {
+		return nil, nil, fmt.Errorf("awskms: %w: empty KMS key ID", crypto.ErrInvalidKeyID)
+	}
+	plaintext, ciphertext, err := g.GenerateDataKey(ctx, kmsKeyID)
+	defer clear(plaintext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("awskms: generate data key: %w", err)
+	}
+	if len(plaintext) != kmsring.KeySize {
+		return nil, nil, fmt.Errorf("awskms: %w: generated key is %d bytes", crypto.ErrInvalidKeySize, len(plaintext))
+	}
+	if len(ciphertext) == 0 {
+		return nil, nil, fmt.Errorf("awskms: generate data key: empty ciphertext blob")
+	}
+	if id == "" {
+		id = crypto.KeyFingerprint(plaintext)
+	}
+	ring, err := crypto.NewKeyRingProvider(plaintext, id, 0, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("awskms: %w", err)
+	}
+	return ciphertext, ring, nil
+}
@@ -0,0 +1,87 @@
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// generator is a DataKeyGenerator backed by remoteKMS.
+type generator struct {
+	kms *remoteKMS
+	key []byte
+	err error
+}
+
+func (g *generator) GenerateDataKey(ctx context.Context, keyID string) ([]byte, []byte, error) {
+	if g.err != nil {
+		return nil, nil, g.err
+	}
+	ct, _, err := g.kms.Encrypt(ctx, keyID, g.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes.Clone(g.key), ct, nil
+}
+
+func TestGenerateWrappedKey_Bootstrap(t *testing.T) {
+	ctx := context.Background()
+	kms := &remoteKMS{keys: map[string]byte{"arn:aws:kms:us-east-1:111:key/a": 0x5a}}
+	key := makeKey(7)
+	blob, ring, err := GenerateWrappedKey(ctx, &generator{kms: kms, key: key}, "arn:aws:kms:us-east-1:111:key/a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if want := crypto.KeyFingerprint(key); ring.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want fingerprint %q", ring.CurrentKeyID(), want)
+	}
+	ct, err := ring.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A later process loads the persisted blob and reads the value.
+	p, err := New(ctx, kms, WithEncryptedKeyForKMSKey(blob, ring.CurrentKeyID(), "arn:aws:kms:us-east-1:111:key/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestGenerateWrappedKey_ExplicitID(t *testing.T) {
+	kms := &remoteKMS{keys: map[string]byte{"arn:k": 1}}
+	_, ring, err := GenerateWrappedKey(context.Background(), &generator{kms: kms, key: makeKey(1)}, "arn:k", "prod-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if ring.CurrentKeyID() != "prod-1" {
+		t.Errorf("CurrentKeyID = %q, want prod-1", ring.CurrentKeyID())
+	}
+}
+
+func TestGenerateWrappedKey_Errors(t *testing.T) {
+	ctx := context.Background()
+	kms := &remoteKMS{keys: map[string]byte{"arn:k": 1}}
+	if _, _, err := GenerateWrappedKey(ctx, nil, "arn:k", ""); err == nil {
+		t.Error("expected error for nil generator")
+	}
+	if _, _, err := GenerateWrappedKey(ctx, &generator{kms: kms, key: makeKey(1)}, "", ""); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+	if _, _, err := GenerateWrappedKey(ctx, &generator{kms: kms, key: make([]byte, 16)}, "arn:k", ""); !errors.Is(err, crypto.ErrInvalidKeySize) {
+		t.Errorf("expected ErrInvalidKeySize, got %v", err)
+	}
+	kmsErr := errors.New("kms: AccessDeniedException")
+	if _, _, err := GenerateWrappedKey(ctx, &generator{err: kmsErr}, "arn:k", ""); !errors.Is(err, kmsErr) {
+		t.Errorf("expected KMS error, got %v", err)
+	}
+}
@@ -21,7 +21,10 @@ package awskms
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	crypto "github.com/rbaliyan/config-crypto"
 	"github.com/rbaliyan/config-crypto/internal/kmsring"
@@ -33,7 +36,8 @@ import (
 type Client interface {
 	// Decrypt decrypts a data key ciphertext produced by AWS KMS.
 	// keyID is the KMS key ARN or alias; pass an empty string to let KMS
-	// determine the key from the ciphertext context.
+	// determine the key from the ciphertext context. New calls Decrypt
+	// concurrently when several keys are configured.
 	Decrypt(ctx context.Context, keyID string, ciphertext []byte) (plaintext []byte, err error)
 }
 
@@ -42,12 +46,21 @@ type Option func(*options)
 
 type options struct {
 	encryptedKeys []encryptedKeyEntry
+	resolver      KeyResolver
+	onResolved    func([]ResolvedKey)
+	retry         retryPolicy
+	timeout       time.Duration
+	onSkip        kmsring.SkipFn
+	roleClient    RoleClientFunc
 }
 
 type encryptedKeyEntry struct {
 	ciphertext []byte
 	id         string
-	kmsKeyID   string // KMS key ARN or alias; empty = let KMS determine
+	kmsKeyID   string   // KMS key ARN or alias; empty = let KMS determine
+	replicas   []string // multi-Region replica key ARNs tried after kmsKeyID
+	roleARN    string   // role whose credentials decrypt this key
+	client     Client   // overrides the provider's client when set
 }
 
 // WithEncryptedKey adds an encrypted key to be unwrapped via KMS Decrypt.
@@ -66,13 +79,63 @@ func WithEncryptedKey(ciphertext []byte, id string) Option {
 // WithEncryptedKeyForKMSKey is like WithEncryptedKey but specifies the KMS key
 // ARN or alias to use for decryption. Use this when the ciphertext was
 // encrypted with a specific KMS key.
-func WithEncryptedKeyForKMSKey(ciphertext []byte, id, kmsKeyID string) Option {
+//
+// If kmsKeyID is a multi-Region key, replicaKeyIDs may list the ARNs of its
+// replicas in other Regions. When Decrypt with kmsKeyID fails, each replica
+// is tried in order, so the key stays recoverable during a regional KMS
+// outage. The Client must route each call to the Region named in the ARN.
+func WithEncryptedKeyForKMSKey(ciphertext []byte, id, kmsKeyID string, replicaKeyIDs ...string) Option {
 	return func(o *options) {
 		o.encryptedKeys = append(o.encryptedKeys, encryptedKeyEntry{
 			ciphertext: ciphertext,
 			id:         id,
 			kmsKeyID:   kmsKeyID,
+			replicas:   slices.Clone(replicaKeyIDs),
+		})
+	}
+}
+
+// decrypt unwraps e with its KMS key, retrying as p allows and then
+// failing over to its replicas in order. It returns every key's error if
+// all fail.
+func (e encryptedKeyEntry) decrypt(ctx context.Context, client Client, p retryPolicy) ([]byte, error) {
+	try := func(keyID string) (pt []byte, err error) {
+		err = p.do(ctx, func(ctx context.Context) error {
+			pt, err = client.Decrypt(ctx, keyID, e.ciphertext)
+			return err
 		})
+		return pt, err
+	}
+	pt, err := try(e.kmsKeyID)
+	if err == nil || len(e.replicas) == 0 {
+		return pt, err
+	}
+	errs := []error{fmt.Errorf("%s: %w", e.kmsKeyID, err)}
+	for _, replica := range e.replicas {
+		if ctx.Err() != nil {
+			break
+		}
+		pt, err := try(replica)
+		if err == nil {
+			return pt, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", replica, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// WithSkipFailedKeys lets New succeed when a key other than the current one
+// cannot be unwrapped, for example because its KMS key was deleted or
+// access to it was revoked. Each such key is left out of the ring and
+// reported to fn, which may be nil, so values it encrypted fail to decrypt
+// with crypto.ErrKeyNotFound instead of taking the whole provider down.
+// New still fails if the current key cannot be unwrapped.
+func WithSkipFailedKeys(fn func(id string, err error)) Option {
+	return func(o *options) {
+		if fn == nil {
+			fn = func(string, error) {}
+		}
+		o.onSkip = fn
 	}
 }
 
@@ -82,8 +145,14 @@ func WithEncryptedKeyForKMSKey(ciphertext []byte, id, kmsKeyID string) Option {
 // WithEncryptedKeyForKMSKey. The first key added becomes the current key for
 // new encryptions; additional keys are available for decryption (key rotation).
 //
-// Keys are decrypted during construction and cached. The KMS client is not
-// retained after construction.
+// Keys are decrypted concurrently during construction and cached. If any key
+// fails, the errors for all failed keys are returned together, unless
+// WithSkipFailedKeys allows old keys to be skipped. The KMS client
+// is not retained after construction; use NewRefreshable to keep it and
+// pick up new keys at runtime.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the unwrapped key material and is safe to call more than once.
 func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
 	if client == nil {
 		return nil, fmt.Errorf("awskms: Client must not be nil")
@@ -93,10 +162,21 @@ func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProv
 	for _, opt := range opts {
 		opt(&o)
 	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if err := o.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if err := o.bindClients(ctx, &roleClients{}); err != nil {
+		return nil, err
+	}
 
-	return kmsring.Build(len(o.encryptedKeys), "awskms", func(i int) ([]byte, string, error) {
+	return kmsring.BuildSkipping(len(o.encryptedKeys), "awskms", o.onSkip, func(i int) ([]byte, string, error) {
 		ek := o.encryptedKeys[i]
-		pt, err := client.Decrypt(ctx, ek.kmsKeyID, ek.ciphertext)
+		pt, err := ek.decrypt(ctx, ek.clientOr(client), o.retry)
 		return pt, ek.id, err
 	})
 }
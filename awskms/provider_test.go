@@ -2,8 +2,13 @@ package awskms
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	crypto "github.com/rbaliyan/config-crypto"
 )
@@ -171,3 +176,164 @@ func TestNew_DecryptedKeyZeroed(t *testing.T) {
 		t.Error("decrypted KMS key bytes were not zeroed after construction")
 	}
 }
+
+// slowClient decrypts "enc-N" to makeKey(N) after a short delay, failing for
+// ciphertexts in fail, and records the peak number of concurrent calls.
+type slowClient struct {
+	fail     map[string]bool
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (c *slowClient) Decrypt(_ context.Context, _ string, ciphertext []byte) ([]byte, error) {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		p := c.peak.Load()
+		if n <= p || c.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if c.fail[string(ciphertext)] {
+		return nil, fmt.Errorf("kms: access denied for %s", ciphertext)
+	}
+	var seed byte
+	_, _ = fmt.Sscanf(string(ciphertext), "enc-%d", &seed)
+	return makeKey(seed), nil
+}
+
+func TestNew_ParallelUnwrap(t *testing.T) {
+	ctx := context.Background()
+	client := &slowClient{}
+	var opts []Option
+	for i := range 20 {
+		opts = append(opts, WithEncryptedKey(fmt.Appendf(nil, "enc-%d", i), fmt.Sprintf("key-%d", i)))
+	}
+	provider, err := New(ctx, client, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer provider.Close()
+
+	if got := provider.CurrentKeyID(); got != "key-0" {
+		t.Errorf("current key = %q, want key-0", got)
+	}
+	if p := client.peak.Load(); p < 2 || p > 8 {
+		t.Errorf("peak concurrent Decrypt calls = %d, want 2..8", p)
+	}
+	if ids := provider.(crypto.KeyLister).ListKeyIDs(); len(ids) != 20 {
+		t.Errorf("ListKeyIDs returned %d IDs, want 20", len(ids))
+	}
+
+	// Every key must be present: encrypt with the last one, decrypt with a
+	// ring that only has it.
+	if err := provider.SetCurrentKey("key-19"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	ct, err := provider.Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	p19, err := crypto.NewProvider(makeKey(19), "key-19")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer p19.Close()
+	if _, err := p19.Decrypt(ctx, ct); err != nil {
+		t.Errorf("key-19 mismatch: %v", err)
+	}
+}
+
+func TestNew_ParallelUnwrapJoinsErrors(t *testing.T) {
+	ctx := context.Background()
+	client := &slowClient{fail: map[string]bool{"enc-2": true, "enc-5": true}}
+	var opts []Option
+	for i := range 8 {
+		opts = append(opts, WithEncryptedKey(fmt.Appendf(nil, "enc-%d", i), fmt.Sprintf("key-%d", i)))
+	}
+	_, err := New(ctx, client, opts...)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 2 {
+		t.Fatalf("got %v, want two joined errors", err)
+	}
+}
+
+// regionalClient fails Decrypt for key ARNs in down Regions.
+type regionalClient struct {
+	mockClient
+	down  map[string]bool // region -> outage
+	calls []string
+}
+
+func (c *regionalClient) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	c.calls = append(c.calls, keyID)
+	if parts := strings.Split(keyID, ":"); len(parts) > 3 && c.down[parts[3]] {
+		return nil, fmt.Errorf("kms: %s unavailable", parts[3])
+	}
+	return c.mockClient.Decrypt(ctx, "", ciphertext)
+}
+
+func TestNew_ReplicaFailover(t *testing.T) {
+	const (
+		primary = "arn:aws:kms:us-east-1:111:key/mrk-1"
+		west    = "arn:aws:kms:us-west-2:111:key/mrk-1"
+		eu      = "arn:aws:kms:eu-west-1:111:key/mrk-1"
+	)
+	client := &regionalClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		down:       map[string]bool{"us-east-1": true, "us-west-2": true},
+	}
+	provider, err := New(context.Background(), client,
+		WithEncryptedKeyForKMSKey([]byte("enc-1"), "key-1", primary, west, eu))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer provider.Close()
+	if want := []string{primary, west, eu}; !slices.Equal(client.calls, want) {
+		t.Errorf("Decrypt calls = %v, want %v", client.calls, want)
+	}
+
+	client.down["eu-west-1"] = true
+	_, err = New(context.Background(), client,
+		WithEncryptedKeyForKMSKey([]byte("enc-1"), "key-1", primary, west, eu))
+	if err == nil {
+		t.Fatal("expected error when every Region is down")
+	}
+	for _, region := range []string{"us-east-1", "us-west-2", "eu-west-1"} {
+		if !strings.Contains(err.Error(), region+" unavailable") {
+			t.Errorf("error does not report %s: %v", region, err)
+		}
+	}
+}
+
+func TestNew_SkipFailedKeys(t *testing.T) {
+	ctx := context.Background()
+	client := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1), "enc-3": makeKey(3)}, failOn: "enc-2"}
+	var skipped []string
+	provider, err := New(ctx, client,
+		WithEncryptedKey([]byte("enc-1"), "key-1"),
+		WithEncryptedKey([]byte("enc-2"), "key-2"),
+		WithEncryptedKey([]byte("enc-3"), "key-3"),
+		WithSkipFailedKeys(func(id string, err error) { skipped = append(skipped, id) }),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer provider.Close()
+	if len(skipped) != 1 || skipped[0] != "key-2" {
+		t.Errorf("skipped = %v, want [key-2]", skipped)
+	}
+	if provider.CurrentKeyID() != "key-1" {
+		t.Errorf("CurrentKeyID = %q, want key-1", provider.CurrentKeyID())
+	}
+
+	// The current key failing is still fatal.
+	client = &mockClient{keys: map[string][]byte{"enc-2": makeKey(2)}, failOn: "enc-1"}
+	if _, err := New(ctx, client, WithEncryptedKey([]byte("enc-1"), "key-1"), WithEncryptedKey([]byte("enc-2"), "key-2"), WithSkipFailedKeys(nil)); err == nil {
+		t.Error("expected error when the current key fails")
+	}
+}
@@ -0,0 +1,63 @@
+package awskms
+
+import (
+	"context"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// KeySetFunc returns the options describing the keys a refreshable provider
+// should hold, typically built from wrapped keys stored in a config store.
+// As with New, the first key is current.
+type KeySetFunc func(ctx context.Context) ([]Option, error)
+
+// NewRefreshable is like New but retains client and returns a
+// crypto.Refresher that calls keys again to pick up newly added wrapped keys
+// and current-key changes without a restart. Only keys the ring does not
+// hold yet are sent to AWS KMS, so a refresh with no changes costs one keys call.
+//
+// Call Refresher.Refresh on demand, or pass crypto.WithRefreshInterval to
+// refresh in the background. Add crypto.WithRefreshRecheck to also re-run
+// Decrypt for the ciphertexts the ring already holds, so a disabled or
+// revoked KMS key, or a ciphertext replaced under an existing ID, is
+// reported by the refresh error handler. Call
+// Refresher.Stop before closing the ring.
+//
+//	ring, r, err := awskms.NewRefreshable(ctx, client, loadKeys,
+//	    crypto.WithRefreshInterval(10*time.Minute))
+//	defer ring.Close()
+//	defer r.Stop()
+func NewRefreshable(ctx context.Context, client Client, keys KeySetFunc, opts ...crypto.RefreshOption) (crypto.KeyRingProvider, *crypto.Refresher, error) {
+	if client == nil {
+		return nil, nil, fmt.Errorf("awskms: Client must not be nil")
+	}
+	if keys == nil {
+		return nil, nil, fmt.Errorf("awskms: KeySetFunc must not be nil")
+	}
+	var roles roleClients
+	list := func(ctx context.Context) ([]encryptedKeyEntry, error) {
+		set, err := keys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var o options
+		for _, opt := range set {
+			opt(&o)
+		}
+		if err := o.resolve(ctx); err != nil {
+			return nil, err
+		}
+		if err := o.bindClients(ctx, &roles); err != nil {
+			return nil, err
+		}
+		return o.encryptedKeys, nil
+	}
+	return kmsring.Refreshable(ctx, "awskms", list,
+		func(e encryptedKeyEntry) string { return e.id },
+		func(ctx context.Context, e encryptedKeyEntry) ([]byte, error) {
+			return e.decrypt(ctx, e.clientOr(client), retryPolicy{})
+		},
+		opts...)
+}
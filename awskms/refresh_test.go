@@ -0,0 +1,59 @@
+package awskms
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestNewRefreshable(t *testing.T) {
+	ctx := context.Background()
+	client := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1), "enc-2": makeKey(2)}}
+
+	var mu sync.Mutex
+	set := []Option{WithEncryptedKey([]byte("enc-1"), "key-1")}
+	keys := func(context.Context) ([]Option, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return set, nil
+	}
+
+	ring, r, err := NewRefreshable(ctx, client, keys)
+	if err != nil {
+		t.Fatalf("NewRefreshable: %v", err)
+	}
+	defer ring.Close()
+	defer r.Stop()
+
+	old, err := ring.Encrypt(ctx, []byte("old"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	mu.Lock()
+	set = []Option{WithEncryptedKey([]byte("enc-2"), "key-2"), WithEncryptedKey([]byte("enc-1"), "key-1")}
+	mu.Unlock()
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := ring.CurrentKeyID(); got != "key-2" {
+		t.Errorf("CurrentKeyID = %q, want key-2", got)
+	}
+	if got, err := ring.Decrypt(ctx, old); err != nil || string(got) != "old" {
+		t.Errorf("Decrypt old = %q, %v", got, err)
+	}
+}
+
+func TestNewRefreshable_Validation(t *testing.T) {
+	ctx := context.Background()
+	keys := func(context.Context) ([]Option, error) { return nil, nil }
+	if _, _, err := NewRefreshable(ctx, nil, keys); err == nil {
+		t.Error("nil client accepted")
+	}
+	if _, _, err := NewRefreshable(ctx, &mockClient{}, nil); err == nil {
+		t.Error("nil KeySetFunc accepted")
+	}
+	if _, _, err := NewRefreshable(ctx, &mockClient{}, keys); err == nil {
+		t.Error("empty key set accepted")
+	}
+}
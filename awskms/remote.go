@@ -0,0 +1,84 @@
+package awskms
+
+import (
+	"context"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// Encrypter encrypts data under an AWS KMS key. Implement it with the KMS
+// Encrypt API:
+//
+//	func (c *myAWSClient) Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, string, error) {
+//	    out, err := c.kms.Encrypt(ctx, &kms.EncryptInput{KeyId: aws.String(keyID), Plaintext: plaintext})
+//	    if err != nil { return nil, "", err }
+//	    return out.CiphertextBlob, aws.ToString(out.KeyId), nil
+//	}
+type Encrypter interface {
+	// Encrypt encrypts plaintext under the KMS key keyID (key ID, ARN,
+	// alias name, or alias ARN) and returns the ciphertext blob and the
+	// ARN of the key that was used.
+	Encrypt(ctx context.Context, keyID string, plaintext []byte) (ciphertext []byte, keyARN string, err error)
+}
+
+// RemoteClient both encrypts and decrypts with AWS KMS.
+type RemoteClient interface {
+	Client
+	Encrypter
+}
+
+// NewRemote returns a crypto.Provider that never holds a plaintext KEK:
+// every Encrypt generates a DEK locally and has KMS encrypt it under
+// kmsKeyID, embedding the KMS ciphertext blob in the envelope, and every
+// Decrypt has KMS decrypt that blob. Long-lived key material stays in KMS,
+// and reading values needs only kms:Decrypt on the key, not access to any
+// stored wrapped KEK:
+//
+//	provider, err := awskms.NewRemote(client, "alias/config")
+//
+// Each Encrypt and Decrypt costs one KMS call, so for hot paths enable
+// crypto.WithDecodeCache on the codec. The header records the ARN that KMS
+// reports for the key, not the alias, so values stay decryptable after the
+// alias is moved to a new key. opts are passed to
+// crypto.NewWrappingProvider. Values use the wrapped-DEK envelope format; a
+// key ring provider cannot decrypt them.
+func NewRemote(client RemoteClient, kmsKeyID string, opts ...crypto.ProviderOption) (crypto.Provider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("awskms: Client must not be nil")
+	}
+	if kmsKeyID == "" {
+		return nil, fmt.Errorf("awskms: %w: empty KMS key ID", crypto.ErrInvalidKeyID)
+	}
+	return crypto.NewWrappingProvider(&remoteWrapper{client: client, kmsKeyID: kmsKeyID}, opts...)
+}
+
+// remoteWrapper adapts a RemoteClient to crypto.KeyWrapper.
+type remoteWrapper struct {
+	client   RemoteClient
+	kmsKeyID string
+}
+
+// Name returns "awskms".
+func (w *remoteWrapper) Name() string { return "awskms" }
+
+// WrapKey encrypts dek under the configured KMS key.
+func (w *remoteWrapper) WrapKey(ctx context.Context, dek []byte) (string, []byte, error) {
+	ciphertext, keyARN, err := w.client.Encrypt(ctx, w.kmsKeyID, dek)
+	if err != nil {
+		return "", nil, fmt.Errorf("awskms: encrypt DEK: %w", err)
+	}
+	if keyARN == "" {
+		keyARN = w.kmsKeyID
+	}
+	return keyARN, ciphertext, nil
+}
+
+// UnwrapKey decrypts a DEK with the KMS key recorded in the header.
+func (w *remoteWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	dek, err := w.client.Decrypt(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("awskms: decrypt DEK: %w", err)
+	}
+	return dek, nil
+}
@@ -0,0 +1,133 @@
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// remoteKMS is an in-memory KMS: ciphertext blobs are the key ARN, a
+// separator, and the plaintext XORed with a per-key byte.
+type remoteKMS struct {
+	aliases  map[string]string // alias -> key ARN
+	keys     map[string]byte   // key ARN -> XOR byte
+	decrypts atomic.Int32
+}
+
+func (m *remoteKMS) Encrypt(_ context.Context, keyID string, plaintext []byte) ([]byte, string, error) {
+	arn := keyID
+	if a, ok := m.aliases[keyID]; ok {
+		arn = a
+	}
+	x, ok := m.keys[arn]
+	if !ok {
+		return nil, "", fmt.Errorf("kms: NotFoundException: %s", keyID)
+	}
+	ct := append([]byte(arn+"|"), plaintext...)
+	for i := len(arn) + 1; i < len(ct); i++ {
+		ct[i] ^= x
+	}
+	return ct, arn, nil
+}
+
+func (m *remoteKMS) Decrypt(_ context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	m.decrypts.Add(1)
+	arn, body, ok := bytes.Cut(ciphertext, []byte("|"))
+	if !ok {
+		return nil, errors.New("kms: InvalidCiphertextException")
+	}
+	if keyID != "" && keyID != string(arn) {
+		return nil, errors.New("kms: IncorrectKeyException")
+	}
+	x := m.keys[string(arn)]
+	pt := bytes.Clone(body)
+	for i := range pt {
+		pt[i] ^= x
+	}
+	return pt, nil
+}
+
+var _ RemoteClient = (*remoteKMS)(nil)
+
+func TestNewRemote_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	kms := &remoteKMS{
+		aliases: map[string]string{"alias/config": "arn:aws:kms:us-east-1:111:key/a"},
+		keys:    map[string]byte{"arn:aws:kms:us-east-1:111:key/a": 0x5a, "arn:aws:kms:us-east-1:111:key/b": 0xa5},
+	}
+	p, err := NewRemote(kms, "alias/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.Name() != "awskms" {
+		t.Errorf("Name = %q, want awskms", p.Name())
+	}
+
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "arn:aws:kms:us-east-1:111:key/a" {
+		t.Errorf("KeyIDOf = %q, want the key ARN", id)
+	}
+
+	// Moving the alias does not affect existing values.
+	kms.aliases["alias/config"] = "arn:aws:kms:us-east-1:111:key/b"
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+	if kms.decrypts.Load() != 1 {
+		t.Errorf("KMS Decrypt calls = %d, want 1", kms.decrypts.Load())
+	}
+}
+
+func TestNewRemote_KeyRingCannotDecrypt(t *testing.T) {
+	ctx := context.Background()
+	kms := &remoteKMS{keys: map[string]byte{"arn:k": 1}}
+	p, err := NewRemote(kms, "arn:k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := crypto.NewProvider(makeKey(1), "arn:k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if _, err := ring.Decrypt(ctx, ct); !crypto.IsUnsupportedFormat(err) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestNewRemote_EncryptError(t *testing.T) {
+	p, err := NewRemote(&remoteKMS{}, "alias/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	_, err = p.Encrypt(context.Background(), []byte("secret"))
+	if err == nil || !strings.Contains(err.Error(), "NotFoundException") {
+		t.Errorf("expected KMS error, got %v", err)
+	}
+}
+
+func TestNewRemote_InvalidArgs(t *testing.T) {
+	if _, err := NewRemote(nil, "alias/config"); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := NewRemote(&remoteKMS{}, ""); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package awskms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// KeyResolver resolves a KMS key identifier to the ARN of the key it
+// currently names. Implement it with the KMS DescribeKey API:
+//
+//	func (c *myAWSClient) DescribeKey(ctx context.Context, keyID string) (string, error) {
+//	    out, err := c.kms.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+//	    if err != nil { return "", err }
+//	    return aws.ToString(out.KeyMetadata.Arn), nil
+//	}
+type KeyResolver interface {
+	// DescribeKey returns the key ARN for keyID, which may be a key ID,
+	// alias name ("alias/config"), or alias ARN.
+	DescribeKey(ctx context.Context, keyID string) (keyARN string, err error)
+}
+
+// ResolvedKey records the KMS key an encrypted key was pinned to.
+type ResolvedKey struct {
+	// ID is the key's ID in the config-crypto ring.
+	ID string
+
+	// KMSKeyID is the identifier given to WithEncryptedKeyForKMSKey,
+	// often an alias.
+	KMSKeyID string
+
+	// KeyARN is the ARN KMSKeyID resolved to at construction.
+	KeyARN string
+}
+
+// WithKeyResolution resolves each KMS key given to
+// WithEncryptedKeyForKMSKey that is not already a key ARN, such as an
+// alias, to the key it names when New runs, and decrypts with that ARN.
+// Pinning means an alias moved to a new key during startup cannot make
+// some keys decrypt under the old key and some under the new, and lets you
+// log exactly which keys are in use (see WithResolvedKeysHandler). Each
+// distinct identifier is described once; with NewRefreshable, once per
+// refresh. Keys added with WithEncryptedKey carry no KMS key ID and are not
+// resolved.
+func WithKeyResolution(r KeyResolver) Option {
+	return func(o *options) {
+		o.resolver = r
+	}
+}
+
+// WithResolvedKeysHandler sets a callback that receives the result of
+// WithKeyResolution, one entry per key in option order, before any key is
+// decrypted. Use it to log or expose the key ARNs behind a provider.
+func WithResolvedKeysHandler(fn func([]ResolvedKey)) Option {
+	return func(o *options) {
+		o.onResolved = fn
+	}
+}
+
+// isKeyARN reports whether keyID is the ARN of a key, which needs no
+// resolution.
+func isKeyARN(keyID string) bool {
+	return strings.HasPrefix(keyID, "arn:") && strings.Contains(keyID, ":key/")
+}
+
+// resolve pins every entry's KMS key ID to a key ARN with o.resolver and
+// reports the result to o.onResolved. It is a no-op without a resolver.
+func (o *options) resolve(ctx context.Context) error {
+	if o.resolver == nil {
+		return nil
+	}
+	var ids []string
+	seen := make(map[string]bool)
+	for _, ek := range o.encryptedKeys {
+		if ek.kmsKeyID != "" && !isKeyARN(ek.kmsKeyID) && !seen[ek.kmsKeyID] {
+			seen[ek.kmsKeyID] = true
+			ids = append(ids, ek.kmsKeyID)
+		}
+	}
+
+	var mu sync.Mutex
+	arns := make(map[string]string, len(ids))
+	err := kmsring.ForEach(len(ids), func(i int) error {
+		var arn string
+		err := o.retry.do(ctx, func(ctx context.Context) (err error) {
+			arn, err = o.resolver.DescribeKey(ctx, ids[i])
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("awskms: resolve %q: %w", ids[i], err)
+		}
+		if !isKeyARN(arn) {
+			return fmt.Errorf("awskms: resolve %q: got %q, want a key ARN", ids[i], arn)
+		}
+		mu.Lock()
+		arns[ids[i]] = arn
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	resolved := make([]ResolvedKey, len(o.encryptedKeys))
+	for i := range o.encryptedKeys {
+		ek := &o.encryptedKeys[i]
+		resolved[i] = ResolvedKey{ID: ek.id, KMSKeyID: ek.kmsKeyID, KeyARN: ek.kmsKeyID}
+		if arn, ok := arns[ek.kmsKeyID]; ok {
+			ek.kmsKeyID = arn
+			resolved[i].KeyARN = arn
+		}
+	}
+	if o.onResolved != nil {
+		o.onResolved(resolved)
+	}
+	return nil
+}
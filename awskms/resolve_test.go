@@ -0,0 +1,106 @@
+package awskms
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// resolvingClient resolves aliases from a table and records the key IDs
+// Decrypt is called with.
+type resolvingClient struct {
+	mockClient
+	aliases map[string]string
+
+	mu        sync.Mutex
+	described []string
+	decrypted []string
+}
+
+func (c *resolvingClient) DescribeKey(_ context.Context, keyID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.described = append(c.described, keyID)
+	arn, ok := c.aliases[keyID]
+	if !ok {
+		return "", errors.New("kms: NotFoundException")
+	}
+	return arn, nil
+}
+
+func (c *resolvingClient) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	c.mu.Lock()
+	c.decrypted = append(c.decrypted, keyID)
+	c.mu.Unlock()
+	return c.mockClient.Decrypt(ctx, keyID, ciphertext)
+}
+
+var _ KeyResolver = (*resolvingClient)(nil)
+
+func TestNew_KeyResolution(t *testing.T) {
+	const arn = "arn:aws:kms:us-east-1:111:key/abc"
+	client := &resolvingClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1), "enc-2": makeKey(2), "enc-3": makeKey(3)}},
+		aliases:    map[string]string{"alias/config": arn},
+	}
+	var got []ResolvedKey
+	ring, err := New(context.Background(), client,
+		WithEncryptedKeyForKMSKey([]byte("enc-1"), "key-1", "alias/config"),
+		WithEncryptedKeyForKMSKey([]byte("enc-2"), "key-2", "alias/config"),
+		WithEncryptedKey([]byte("enc-3"), "key-3"),
+		WithKeyResolution(client),
+		WithResolvedKeysHandler(func(keys []ResolvedKey) { got = keys }),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+
+	if !slices.Equal(client.described, []string{"alias/config"}) {
+		t.Errorf("DescribeKey calls = %v, want one for alias/config", client.described)
+	}
+	slices.Sort(client.decrypted)
+	if want := []string{"", arn, arn}; !slices.Equal(client.decrypted, want) {
+		t.Errorf("Decrypt key IDs = %q, want %q", client.decrypted, want)
+	}
+	want := []ResolvedKey{
+		{ID: "key-1", KMSKeyID: "alias/config", KeyARN: arn},
+		{ID: "key-2", KMSKeyID: "alias/config", KeyARN: arn},
+		{ID: "key-3"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("resolved = %+v, want %+v", got, want)
+	}
+}
+
+func TestNew_KeyResolutionSkipsKeyARNs(t *testing.T) {
+	const arn = "arn:aws:kms:us-east-1:111:key/abc"
+	client := &resolvingClient{mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}}
+	ring, err := New(context.Background(), client,
+		WithEncryptedKeyForKMSKey([]byte("enc-1"), "key-1", arn),
+		WithKeyResolution(client),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+	if len(client.described) != 0 {
+		t.Errorf("DescribeKey called for a key ARN: %v", client.described)
+	}
+}
+
+func TestNew_KeyResolutionFails(t *testing.T) {
+	client := &resolvingClient{mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}}
+	_, err := New(context.Background(), client,
+		WithEncryptedKeyForKMSKey([]byte("enc-1"), "key-1", "alias/missing"),
+		WithKeyResolution(client),
+	)
+	if err == nil {
+		t.Fatal("expected error for unresolvable alias")
+	}
+	if len(client.decrypted) != 0 {
+		t.Error("Decrypt called after resolution failed")
+	}
+}
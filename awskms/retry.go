@@ -0,0 +1,90 @@
+package awskms
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// retryableCodes are AWS KMS error codes that a later attempt may not see.
+var retryableCodes = map[string]bool{
+	"ThrottlingException":         true,
+	"KMSInternalException":        true,
+	"DependencyTimeoutException":  true,
+	"RequestTimeout":              true,
+	"RequestTimeoutException":     true,
+	"ServiceUnavailable":          true,
+	"ServiceUnavailableException": true,
+}
+
+// retryPolicy controls how New retries KMS calls. The zero value makes one
+// attempt with no timeout.
+type retryPolicy kmsring.RetryPolicy
+
+// WithRetries makes New retry each failed KMS call (Decrypt, DescribeKey)
+// up to n more times with exponential backoff, for errors that look
+// transient: throttling, KMS internal errors, timeouts, and errors that
+// crypto.IsRetryable accepts. Errors from the AWS SDK are classified by
+// their ErrorCode. Set the classifier with WithRetryIf and the delays with
+// WithBackoff. Retry and timeout options apply to New; NewRefreshable
+// already retries on its next refresh.
+func WithRetries(n int) Option {
+	return func(o *options) {
+		o.retry.Attempts = n + 1
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the cap it doubles
+// up to. Each delay is jittered down by up to half. Defaults to 100ms and
+// 5s.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		o.retry.Initial, o.retry.Max = initial, max
+	}
+}
+
+// WithRetryIf replaces the classifier WithRetries uses to decide whether
+// an error is worth retrying.
+func WithRetryIf(fn func(error) bool) Option {
+	return func(o *options) {
+		o.retry.RetryIf = fn
+	}
+}
+
+// WithCallTimeout bounds each KMS call New makes, so one hung request is
+// abandoned and, with WithRetries, retried.
+func WithCallTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.retry.CallTimeout = d
+	}
+}
+
+// WithConstructionTimeout bounds the whole of New, including every
+// resolution, decryption, retry, and backoff. When it expires New fails
+// with an error wrapping context.DeadlineExceeded.
+func WithConstructionTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// IsRetryableError is the default WithRetries classifier.
+func IsRetryableError(err error) bool {
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) && retryableCodes[coded.ErrorCode()] {
+		return true
+	}
+	return crypto.IsRetryable(err)
+}
+
+// do calls fn as the policy allows, classifying errors with
+// IsRetryableError unless WithRetryIf replaced it.
+func (p retryPolicy) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.RetryIf == nil {
+		p.RetryIf = IsRetryableError
+	}
+	return kmsring.RetryPolicy(p).Do(ctx, fn)
+}
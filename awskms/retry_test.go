@@ -0,0 +1,136 @@
+package awskms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// apiError mimics a smithy API error from the AWS SDK.
+type apiError struct{ code string }
+
+func (e *apiError) Error() string     { return "api error " + e.code }
+func (e *apiError) ErrorCode() string { return e.code }
+
+// flakyClient fails the first failures calls with err, then delegates.
+type flakyClient struct {
+	mockClient
+	failures int32
+	err      error
+	hang     bool // block until the context is done instead of failing
+	calls    atomic.Int32
+}
+
+func (c *flakyClient) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	if c.calls.Add(1) <= c.failures {
+		if c.hang {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, c.err
+	}
+	return c.mockClient.Decrypt(ctx, keyID, ciphertext)
+}
+
+func TestNew_RetriesThrottling(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   2,
+		err:        &apiError{"ThrottlingException"},
+	}
+	ring, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1"),
+		WithRetries(3), WithBackoff(time.Millisecond, 2*time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+	if client.calls.Load() != 3 {
+		t.Errorf("Decrypt calls = %d, want 3", client.calls.Load())
+	}
+}
+
+func TestNew_NoRetriesByDefault(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   1,
+		err:        &apiError{"ThrottlingException"},
+	}
+	if _, err := New(context.Background(), client, WithEncryptedKey([]byte("enc-1"), "key-1")); err == nil {
+		t.Fatal("expected error without WithRetries")
+	}
+	if client.calls.Load() != 1 {
+		t.Errorf("Decrypt calls = %d, want 1", client.calls.Load())
+	}
+}
+
+func TestNew_DoesNotRetryPermanentErrors(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   1,
+		err:        &apiError{"AccessDeniedException"},
+	}
+	_, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1"),
+		WithRetries(3), WithBackoff(time.Millisecond, time.Millisecond))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if client.calls.Load() != 1 {
+		t.Errorf("Decrypt calls = %d, want 1", client.calls.Load())
+	}
+}
+
+func TestNew_RetryIf(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   1,
+		err:        errors.New("connection reset"),
+	}
+	ring, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1"),
+		WithRetries(1), WithBackoff(time.Millisecond, time.Millisecond),
+		WithRetryIf(func(err error) bool { return strings.Contains(err.Error(), "reset") }))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_ = ring.Close()
+}
+
+func TestNew_CallTimeoutRetries(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   1,
+		hang:       true,
+	}
+	ring, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1"),
+		WithCallTimeout(10*time.Millisecond),
+		WithRetries(1), WithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_ = ring.Close()
+}
+
+func TestNew_ConstructionTimeout(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   100,
+		err:        &apiError{"ThrottlingException"},
+	}
+	start := time.Now()
+	_, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1"),
+		WithRetries(100), WithBackoff(20*time.Millisecond, 20*time.Millisecond),
+		WithConstructionTimeout(50*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("New took %v after a 50ms construction timeout", elapsed)
+	}
+}
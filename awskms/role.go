@@ -0,0 +1,112 @@
+package awskms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RoleClientFunc returns a Client that calls KMS with the credentials of
+// roleARN, typically by wrapping an SDK client configured with an
+// stscreds.AssumeRoleProvider:
+//
+//	func(ctx context.Context, roleARN string) (awskms.Client, error) {
+//	    cfg := baseCfg.Copy()
+//	    cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(baseCfg), roleARN))
+//	    return &myAWSClient{kms.NewFromConfig(cfg)}, nil
+//	}
+type RoleClientFunc func(ctx context.Context, roleARN string) (Client, error)
+
+// WithAssumeRole sets how clients for the roles named by
+// WithEncryptedKeyForRole are created. Each distinct role is requested
+// once per provider and shared by its keys.
+func WithAssumeRole(fn RoleClientFunc) Option {
+	return func(o *options) {
+		o.roleClient = fn
+	}
+}
+
+// WithEncryptedKeyForRole is like WithEncryptedKeyForKMSKey but decrypts
+// with the credentials of roleARN, obtained through WithAssumeRole. Use it
+// for keys in another account whose key policy grants decryption to a role
+// there rather than to the provider's own principal, so one provider can
+// hold keys from several accounts.
+func WithEncryptedKeyForRole(ciphertext []byte, id, kmsKeyID, roleARN string) Option {
+	return func(o *options) {
+		o.encryptedKeys = append(o.encryptedKeys, encryptedKeyEntry{
+			ciphertext: ciphertext,
+			id:         id,
+			kmsKeyID:   kmsKeyID,
+			roleARN:    roleARN,
+		})
+	}
+}
+
+// WithEncryptedKeyClient is like WithEncryptedKeyForKMSKey but decrypts
+// with client instead of the client passed to New, for a key that needs
+// its own Region, endpoint, or credentials.
+func WithEncryptedKeyClient(client Client, ciphertext []byte, id, kmsKeyID string) Option {
+	return func(o *options) {
+		o.encryptedKeys = append(o.encryptedKeys, encryptedKeyEntry{
+			ciphertext: ciphertext,
+			id:         id,
+			kmsKeyID:   kmsKeyID,
+			client:     client,
+		})
+	}
+}
+
+// roleClients caches the client created for each role.
+type roleClients struct {
+	mu      sync.Mutex
+	clients map[string]Client
+}
+
+// get returns the client for roleARN, creating it with fn on first use.
+func (c *roleClients) get(ctx context.Context, fn RoleClientFunc, roleARN string) (Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[roleARN]; ok {
+		return client, nil
+	}
+	client, err := fn(ctx, roleARN)
+	if err != nil {
+		return nil, fmt.Errorf("awskms: assume role %s: %w", roleARN, err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("awskms: assume role %s: nil Client", roleARN)
+	}
+	if c.clients == nil {
+		c.clients = make(map[string]Client)
+	}
+	c.clients[roleARN] = client
+	return client, nil
+}
+
+// bindClients sets the client of every entry that names a role, creating
+// role clients through roles.
+func (o *options) bindClients(ctx context.Context, roles *roleClients) error {
+	for i := range o.encryptedKeys {
+		ek := &o.encryptedKeys[i]
+		if ek.roleARN == "" {
+			continue
+		}
+		if o.roleClient == nil {
+			return fmt.Errorf("awskms: key %q names role %s but WithAssumeRole is not set", ek.id, ek.roleARN)
+		}
+		client, err := roles.get(ctx, o.roleClient, ek.roleARN)
+		if err != nil {
+			return err
+		}
+		ek.client = client
+	}
+	return nil
+}
+
+// clientOr returns the entry's own client, or def if it has none.
+func (e encryptedKeyEntry) clientOr(def Client) Client {
+	if e.client != nil {
+		return e.client
+	}
+	return def
+}
@@ -0,0 +1,89 @@
+package awskms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNew_PerKeyClients(t *testing.T) {
+	ctx := context.Background()
+	home := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}
+	other := &mockClient{keys: map[string][]byte{"enc-2": makeKey(2)}}
+	regional := &mockClient{keys: map[string][]byte{"enc-3": makeKey(3)}}
+	roleCalls := 0
+	assume := func(_ context.Context, roleARN string) (Client, error) {
+		roleCalls++
+		if roleARN != "arn:aws:iam::222:role/config-reader" {
+			return nil, errors.New("sts: AccessDenied")
+		}
+		return other, nil
+	}
+
+	p, err := New(ctx, home,
+		WithEncryptedKey([]byte("enc-1"), "key-1"),
+		WithEncryptedKeyForRole([]byte("enc-2"), "key-2", "arn:aws:kms:us-east-1:222:key/b", "arn:aws:iam::222:role/config-reader"),
+		WithEncryptedKeyClient(regional, []byte("enc-3"), "key-3", "arn:aws:kms:eu-west-1:111:key/c"),
+		WithEncryptedKeyForRole([]byte("enc-2"), "key-2b", "arn:aws:kms:us-east-1:222:key/b", "arn:aws:iam::222:role/config-reader"),
+		WithAssumeRole(assume),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if roleCalls != 1 {
+		t.Errorf("role client created %d times, want 1", roleCalls)
+	}
+}
+
+func TestNew_RoleErrors(t *testing.T) {
+	ctx := context.Background()
+	home := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}
+
+	_, err := New(ctx, home, WithEncryptedKeyForRole([]byte("enc-1"), "key-1", "arn:k", "arn:aws:iam::222:role/r"))
+	if err == nil || !strings.Contains(err.Error(), "WithAssumeRole") {
+		t.Errorf("expected missing WithAssumeRole error, got %v", err)
+	}
+
+	stsErr := errors.New("sts: AccessDenied")
+	_, err = New(ctx, home,
+		WithEncryptedKeyForRole([]byte("enc-1"), "key-1", "arn:k", "arn:aws:iam::222:role/r"),
+		WithAssumeRole(func(context.Context, string) (Client, error) { return nil, stsErr }),
+	)
+	if !errors.Is(err, stsErr) {
+		t.Errorf("expected STS error, got %v", err)
+	}
+}
+
+func TestNewRefreshable_RoleClientReused(t *testing.T) {
+	ctx := context.Background()
+	other := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1), "enc-2": makeKey(2)}}
+	roleCalls := 0
+	assume := func(context.Context, string) (Client, error) {
+		roleCalls++
+		return other, nil
+	}
+	blobs := []string{"enc-1"}
+	keys := func(context.Context) ([]Option, error) {
+		opts := []Option{WithAssumeRole(assume)}
+		for i, b := range blobs {
+			opts = append(opts, WithEncryptedKeyForRole([]byte(b), "key-"+string(rune('1'+i)), "arn:k", "arn:aws:iam::222:role/r"))
+		}
+		return opts, nil
+	}
+	ring, r, err := NewRefreshable(ctx, &mockClient{}, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	defer r.Stop()
+
+	blobs = append(blobs, "enc-2")
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if roleCalls != 1 {
+		t.Errorf("role client created %d times, want 1", roleCalls)
+	}
+}
@@ -0,0 +1,221 @@
+// Package awsssm provides a crypto.Provider whose keys are AWS Systems
+// Manager Parameter Store SecureString parameters.
+//
+// Each parameter value is a key in a form crypto.ParseKey accepts, such as
+// "base64:...". Parameters are read at construction time through a Client
+// that requests decryption, so SSM decrypts the value with the parameter's
+// KMS key and the caller needs both ssm:GetParameter and kms:Decrypt on
+// that key. Wire up the AWS SDK v2 with a one-method wrapper:
+//
+//	type mySSMClient struct{ ssm *ssm.Client }
+//
+//	func (c *mySSMClient) GetParameter(ctx context.Context, name string, version int64) (string, int64, error) {
+//	    if version > 0 { name = fmt.Sprintf("%s:%d", name, version) }
+//	    out, err := c.ssm.GetParameter(ctx, &ssm.GetParameterInput{
+//	        Name: aws.String(name), WithDecryption: aws.Bool(true),
+//	    })
+//	    if err != nil { return "", 0, err }
+//	    return aws.ToString(out.Parameter.Value), out.Parameter.Version, nil
+//	}
+//
+//	provider, err := awsssm.New(ctx, &mySSMClient{ssm.NewFromConfig(cfg)},
+//	    awsssm.WithParameterHistory("/myapp/kek", 3),
+//	)
+//	// the latest version is current; the two before it decrypt existing data
+package awsssm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// Client reads decrypted SecureString parameters.
+type Client interface {
+	// GetParameter returns the decrypted value of parameter name at version,
+	// or at its latest version when version is 0, together with the version
+	// that was read. New calls GetParameter concurrently when several keys
+	// are configured.
+	GetParameter(ctx context.Context, name string, version int64) (value string, readVersion int64, err error)
+}
+
+// Option configures the Parameter Store provider.
+type Option func(*options)
+
+type options struct {
+	params      []parameter
+	keyIDFormat func(name string, version int64) string
+}
+
+type parameter struct {
+	name    string
+	version int64 // 0 = latest
+	history int   // versions to load counting back from the latest; 0 = one
+	id      string
+}
+
+// WithParameter registers the latest version of the parameter name. The id
+// identifies this key in the config-crypto system; an empty id is derived
+// from the name and the version read (see WithKeyIDFormat). A fixed id
+// suits parameters that are never overwritten; otherwise prefer an empty
+// id or WithParameterHistory, so values encrypted before an update still
+// name the key that encrypted them.
+//
+// The first parameter option sets the current key used for new
+// encryptions. Subsequent options register additional keys for decryption
+// during key rotation.
+func WithParameter(name, id string) Option {
+	return func(o *options) {
+		o.params = append(o.params, parameter{name: name, id: id})
+	}
+}
+
+// WithParameterVersion registers a specific version of the parameter name,
+// so an update to the parameter cannot change the key until the pin is
+// moved. An empty id is derived as for WithParameter.
+func WithParameterVersion(name string, version int64, id string) Option {
+	return func(o *options) {
+		o.params = append(o.params, parameter{name: name, version: version, id: id})
+	}
+}
+
+// WithParameterHistory registers the latest version of the parameter name
+// and up to n-1 versions before it, newest first, with IDs from
+// WithKeyIDFormat. Rotating is then a matter of putting a new key into the
+// parameter with --overwrite: the new version becomes current on the next
+// New and the previous ones keep decrypting existing data. Parameter Store
+// keeps the last 100 versions.
+func WithParameterHistory(name string, n int) Option {
+	return func(o *options) {
+		o.params = append(o.params, parameter{name: name, history: n})
+	}
+}
+
+// WithKeyIDFormat sets the function that derives a key ID from a parameter
+// name and version when no id is given. The mapping must be deterministic
+// and stable across restarts, otherwise old ciphertexts will fail to
+// decrypt. Default: "name:version", e.g. "/myapp/kek:3".
+func WithKeyIDFormat(fn func(name string, version int64) string) Option {
+	return func(o *options) { o.keyIDFormat = fn }
+}
+
+// formatKeyID is the default WithKeyIDFormat, matching Parameter Store's
+// own name:version selector syntax.
+func formatKeyID(name string, version int64) string {
+	return name + ":" + strconv.FormatInt(version, 10)
+}
+
+// read is one GetParameter call New makes. When value is set the version
+// was already read while expanding a history.
+type read struct {
+	name    string
+	version int64
+	id      string
+	value   string
+	done    bool
+}
+
+// New creates a crypto.KeyRingProvider from SecureString parameters.
+//
+// At least one key must be provided via WithParameter, WithParameterVersion,
+// or WithParameterHistory. The first key is the current key for new
+// encryptions; additional keys support decryption during key rotation.
+//
+// All keys are read during construction and cached. If any read fails, the
+// errors for all failed keys are returned together. The Client is not
+// retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("awsssm: Client must not be nil")
+	}
+
+	o := options{keyIDFormat: formatKeyID}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.keyIDFormat == nil {
+		return nil, fmt.Errorf("awsssm: keyIDFormat must not be nil")
+	}
+	for _, p := range o.params {
+		switch {
+		case p.name == "":
+			return nil, fmt.Errorf("awsssm: parameter name must not be empty")
+		case p.version < 0:
+			return nil, fmt.Errorf("awsssm: parameter %q: version %d must be positive", p.name, p.version)
+		case p.history < 0:
+			return nil, fmt.Errorf("awsssm: parameter %q: history %d must be positive", p.name, p.history)
+		}
+	}
+
+	reads, err := o.expand(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return kmsring.Build(len(reads), "awsssm", func(i int) ([]byte, string, error) {
+		r := reads[i]
+		name := r.id
+		if name == "" {
+			name = r.name
+		}
+		if !r.done {
+			value, version, err := client.GetParameter(ctx, r.name, r.version)
+			if err != nil {
+				return nil, name, err
+			}
+			if r.version != 0 && version != r.version {
+				return nil, name, fmt.Errorf("read version %d, want %d", version, r.version)
+			}
+			r.value, r.version = value, version
+		}
+		key, err := crypto.ParseKey(r.value)
+		if err != nil {
+			return nil, name, fmt.Errorf("parameter %s: %w", r.name, err)
+		}
+		if r.id == "" {
+			return key, o.keyIDFormat(r.name, r.version), nil
+		}
+		return key, r.id, nil
+	})
+}
+
+// expand turns the configured parameters into the reads New makes, in
+// option order. Each history is expanded by reading its latest version,
+// concurrently, and counting back from there.
+func (o *options) expand(ctx context.Context, client Client) ([]read, error) {
+	latest := make([]read, len(o.params))
+	err := kmsring.ForEach(len(o.params), func(i int) error {
+		p := o.params[i]
+		if p.history <= 1 {
+			return nil
+		}
+		value, version, err := client.GetParameter(ctx, p.name, 0)
+		if err != nil {
+			return fmt.Errorf("awsssm: failed to read %q: %w", p.name, err)
+		}
+		latest[i] = read{name: p.name, version: version, value: value, done: true}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reads []read
+	for i, p := range o.params {
+		if p.history <= 1 {
+			reads = append(reads, read{name: p.name, version: p.version, id: p.id})
+			continue
+		}
+		reads = append(reads, latest[i])
+		for v := latest[i].version - 1; v > latest[i].version-int64(p.history) && v > 0; v-- {
+			reads = append(reads, read{name: p.name, version: v})
+		}
+	}
+	return reads, nil
+}
@@ -0,0 +1,203 @@
+package awsssm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockSSM is an in-memory Parameter Store: each parameter is a list of
+// values, version n at index n-1.
+type mockSSM struct {
+	mu     sync.Mutex
+	params map[string][]string
+	reads  int
+}
+
+func (m *mockSSM) GetParameter(ctx context.Context, name string, version int64) (string, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return "", 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	values, ok := m.params[name]
+	if !ok {
+		return "", 0, fmt.Errorf("ssm: ParameterNotFound: %s", name)
+	}
+	if version == 0 {
+		version = int64(len(values))
+	}
+	if version > int64(len(values)) {
+		return "", 0, fmt.Errorf("ssm: ParameterVersionNotFound: %s:%d", name, version)
+	}
+	return values[version-1], version, nil
+}
+
+var _ Client = (*mockSSM)(nil)
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func encodeKey(seed byte) string {
+	return "base64:" + base64.StdEncoding.EncodeToString(makeKey(seed))
+}
+
+// encryptWith encrypts a value with a single-key provider holding key seed
+// under id.
+func encryptWith(t *testing.T, seed byte, id string) []byte {
+	t.Helper()
+	p, err := crypto.NewProvider(makeKey(seed), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(context.Background(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func TestNew_Parameter(t *testing.T) {
+	ctx := context.Background()
+	ssm := &mockSSM{params: map[string][]string{"/app/kek": {encodeKey(1), encodeKey(2)}}}
+	p, err := New(ctx, ssm, WithParameter("/app/kek", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "/app/kek:2" {
+		t.Errorf("CurrentKeyID = %q, want /app/kek:2", p.CurrentKeyID())
+	}
+	pt, err := p.Decrypt(ctx, encryptWith(t, 2, "/app/kek:2"))
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_ParameterVersion(t *testing.T) {
+	ctx := context.Background()
+	ssm := &mockSSM{params: map[string][]string{"/app/kek": {encodeKey(1), encodeKey(2)}}}
+	p, err := New(ctx, ssm, WithParameterVersion("/app/kek", 1, "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "kek-1" {
+		t.Errorf("CurrentKeyID = %q, want kek-1", p.CurrentKeyID())
+	}
+	pt, err := p.Decrypt(ctx, encryptWith(t, 1, "kek-1"))
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_ParameterHistory(t *testing.T) {
+	ctx := context.Background()
+	ssm := &mockSSM{params: map[string][]string{"/app/kek": {encodeKey(1), encodeKey(2), encodeKey(3), encodeKey(4)}}}
+	p, err := New(ctx, ssm, WithParameterHistory("/app/kek", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "/app/kek:4" {
+		t.Errorf("CurrentKeyID = %q, want /app/kek:4", p.CurrentKeyID())
+	}
+	if ssm.reads != 3 {
+		t.Errorf("GetParameter calls = %d, want 3", ssm.reads)
+	}
+	for v := byte(2); v <= 4; v++ {
+		if _, err := p.Decrypt(ctx, encryptWith(t, v, fmt.Sprintf("/app/kek:%d", v))); err != nil {
+			t.Errorf("version %d: %v", v, err)
+		}
+	}
+	if _, err := p.Decrypt(ctx, encryptWith(t, 1, "/app/kek:1")); !crypto.IsKeyNotFound(err) {
+		t.Errorf("version 1: expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestNew_ParameterHistoryShorterThanN(t *testing.T) {
+	ssm := &mockSSM{params: map[string][]string{"/app/kek": {encodeKey(1), encodeKey(2)}}}
+	p, err := New(context.Background(), ssm, WithParameterHistory("/app/kek", 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if ssm.reads != 2 {
+		t.Errorf("GetParameter calls = %d, want 2", ssm.reads)
+	}
+}
+
+func TestNew_KeyIDFormat(t *testing.T) {
+	ssm := &mockSSM{params: map[string][]string{"/app/kek": {encodeKey(1)}}}
+	p, err := New(context.Background(), ssm,
+		WithParameter("/app/kek", ""),
+		WithKeyIDFormat(func(_ string, v int64) string { return fmt.Sprintf("v%d", v) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "v1" {
+		t.Errorf("CurrentKeyID = %q, want v1", p.CurrentKeyID())
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	ctx := context.Background()
+	ssm := &mockSSM{params: map[string][]string{
+		"/app/kek":   {encodeKey(1)},
+		"/app/plain": {"not a key"},
+	}}
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"no keys", nil, "at least one"},
+		{"empty name", []Option{WithParameter("", "k")}, "name must not be empty"},
+		{"negative version", []Option{WithParameterVersion("/app/kek", -1, "k")}, "must be positive"},
+		{"missing parameter", []Option{WithParameter("/app/missing", "k")}, "ParameterNotFound"},
+		{"missing version", []Option{WithParameterVersion("/app/kek", 7, "k")}, "ParameterVersionNotFound"},
+		{"missing history", []Option{WithParameterHistory("/app/missing", 2)}, "ParameterNotFound"},
+		{"unparsable value", []Option{WithParameter("/app/plain", "k")}, "/app/plain"},
+		{"nil format", []Option{WithParameter("/app/kek", ""), WithKeyIDFormat(nil)}, "keyIDFormat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(ctx, ssm, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := New(ctx, nil, WithParameter("/app/kek", "k")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
+
+func TestNew_PinnedVersionMismatch(t *testing.T) {
+	_, err := New(context.Background(), wrongVersion{}, WithParameterVersion("/app/kek", 2, "k"))
+	if err == nil || !strings.Contains(err.Error(), "want 2") {
+		t.Errorf("expected version mismatch, got %v", err)
+	}
+}
+
+// wrongVersion ignores the requested version, as a client that forgets the
+// name:version selector would.
+type wrongVersion struct{}
+
+func (wrongVersion) GetParameter(context.Context, string, int64) (string, int64, error) {
+	return encodeKey(1), 3, nil
+}
@@ -0,0 +1,272 @@
+package azurekv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credential obtains Microsoft Entra ID access tokens for HTTPClient.
+type Credential interface {
+	// Token returns an access token for resource, such as
+	// "https://vault.azure.net", and when it expires.
+	Token(ctx context.Context, resource string) (token string, expires time.Time, err error)
+}
+
+// maxTokenResponse bounds the token endpoint responses read into memory.
+const maxTokenResponse = 1 << 20
+
+// imdsEndpoint is the Azure Instance Metadata Service token endpoint.
+const imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// probeTimeout bounds each attempt of a DefaultCredential source that has
+// a fallback, so a missing IMDS endpoint does not stall construction.
+const probeTimeout = 3 * time.Second
+
+// DefaultCredential returns a Credential configured from the environment
+// in the same order as the Azure SDK's DefaultAzureCredential:
+//
+//   - a service principal secret, if AZURE_TENANT_ID, AZURE_CLIENT_ID, and
+//     AZURE_CLIENT_SECRET are set;
+//   - workload identity (AKS), if AZURE_FEDERATED_TOKEN_FILE,
+//     AZURE_TENANT_ID, and AZURE_CLIENT_ID are set;
+//   - otherwise managed identity (App Service, Functions, VMs, and
+//     Container Apps), user-assigned if AZURE_CLIENT_ID is set, falling
+//     back to the Azure CLI's signed-in account for local development.
+//
+// AZURE_AUTHORITY_HOST overrides the Entra ID endpoint for sovereign
+// clouds. The first source that returns a token is used from then on.
+func DefaultCredential() Credential {
+	tenant, clientID := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authority == "" {
+		authority = "https://login.microsoftonline.com/"
+	}
+	switch {
+	case tenant != "" && clientID != "" && os.Getenv("AZURE_CLIENT_SECRET") != "":
+		return &entraCredential{authority: authority, tenant: tenant, clientID: clientID, secret: os.Getenv("AZURE_CLIENT_SECRET")}
+	case tenant != "" && clientID != "" && os.Getenv("AZURE_FEDERATED_TOKEN_FILE") != "":
+		return &entraCredential{authority: authority, tenant: tenant, clientID: clientID, assertionFile: os.Getenv("AZURE_FEDERATED_TOKEN_FILE")}
+	}
+	return &chainCredential{sources: []Credential{ManagedIdentityCredential(clientID), cliCredential{}}}
+}
+
+// ManagedIdentityCredential returns a Credential for the managed identity
+// of the Azure host: the App Service identity endpoint if
+// IDENTITY_ENDPOINT and IDENTITY_HEADER are set, otherwise the Instance
+// Metadata Service. clientID selects a user-assigned identity; empty means
+// the system-assigned one.
+func ManagedIdentityCredential(clientID string) Credential {
+	return managedIdentityCredential{clientID: clientID}
+}
+
+// tokenResponse is the union of the token response formats: Entra ID
+// returns expires_in as a number, managed identity endpoints return
+// expires_on and expires_in as strings.
+type tokenResponse struct {
+	AccessToken string          `json:"access_token"`
+	ExpiresIn   json.RawMessage `json:"expires_in"`
+	ExpiresOn   json.RawMessage `json:"expires_on"`
+}
+
+// expiry returns when the token expires, preferring the absolute time.
+func (r tokenResponse) expiry(now time.Time) (time.Time, error) {
+	if n, ok := jsonInt(r.ExpiresOn); ok {
+		return time.Unix(n, 0), nil
+	}
+	if n, ok := jsonInt(r.ExpiresIn); ok {
+		return now.Add(time.Duration(n) * time.Second), nil
+	}
+	return time.Time{}, errors.New("token response has no expiry")
+}
+
+// jsonInt parses a JSON number or a string holding one.
+func jsonInt(raw json.RawMessage) (int64, bool) {
+	s := strings.Trim(string(raw), `"`)
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// fetchToken sends req and decodes a token response from it.
+func fetchToken(req *http.Request, source string) (string, time.Time, error) {
+	now := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: %s: %w", source, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponse))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: %s: %w", source, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("azurekv: %s: %w", source, newStatusError(resp.StatusCode, body))
+	}
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: %s: decode token: %w", source, err)
+	}
+	if tr.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("azurekv: %s: empty access token", source)
+	}
+	expires, err := tr.expiry(now)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: %s: %w", source, err)
+	}
+	return tr.AccessToken, expires, nil
+}
+
+// entraCredential authenticates a service principal with the OAuth 2.0
+// client credentials flow, using either a secret or, for workload
+// identity, a federated token read from assertionFile on every request.
+type entraCredential struct {
+	authority     string
+	tenant        string
+	clientID      string
+	secret        string
+	assertionFile string
+}
+
+// Token requests a token for resource from Entra ID.
+func (c *entraCredential) Token(ctx context.Context, resource string) (string, time.Time, error) {
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {c.clientID},
+		"scope":      {strings.TrimSuffix(resource, "/") + "/.default"},
+	}
+	source := "client secret credential"
+	if c.assertionFile != "" {
+		source = "workload identity credential"
+		assertion, err := os.ReadFile(c.assertionFile)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("azurekv: %s: %w", source, err)
+		}
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	} else {
+		form.Set("client_secret", c.secret)
+	}
+	endpoint := strings.TrimSuffix(c.authority, "/") + "/" + url.PathEscape(c.tenant) + "/oauth2/v2.0/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: %s: %w", source, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return fetchToken(req, source)
+}
+
+// managedIdentityCredential requests tokens from the host's managed
+// identity endpoint.
+type managedIdentityCredential struct {
+	clientID string
+}
+
+// Token requests a token for resource from the managed identity endpoint.
+func (c managedIdentityCredential) Token(ctx context.Context, resource string) (string, time.Time, error) {
+	q := url.Values{"resource": {resource}}
+	if c.clientID != "" {
+		q.Set("client_id", c.clientID)
+	}
+	endpoint, header, value := imdsEndpoint, "Metadata", "true"
+	q.Set("api-version", "2018-02-01")
+	if ep, h := os.Getenv("IDENTITY_ENDPOINT"), os.Getenv("IDENTITY_HEADER"); ep != "" && h != "" {
+		endpoint, header, value = ep, "X-IDENTITY-HEADER", h
+		q.Set("api-version", "2019-08-01")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: managed identity credential: %w", err)
+	}
+	req.Header.Set(header, value)
+	return fetchToken(req, "managed identity credential")
+}
+
+// cliCredential gets tokens for the account signed in to the Azure CLI.
+type cliCredential struct{}
+
+// Token runs az account get-access-token for resource.
+func (cliCredential) Token(ctx context.Context, resource string) (string, time.Time, error) {
+	if _, err := exec.LookPath("az"); err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: Azure CLI credential: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "az", "account", "get-access-token", "--resource", resource, "--output", "json") // #nosec G204 -- resource is derived from the vault URL
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	defer clear(stdout.Bytes())
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		return "", time.Time{}, fmt.Errorf("azurekv: Azure CLI credential: %w: %s", err, msg)
+	}
+	var out struct {
+		AccessToken string          `json:"accessToken"`
+		ExpiresOn   json.RawMessage `json:"expires_on"`
+		ExpiresOnLT string          `json:"expiresOn"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil || out.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("azurekv: Azure CLI credential: unexpected output")
+	}
+	if n, ok := jsonInt(out.ExpiresOn); ok {
+		return out.AccessToken, time.Unix(n, 0), nil
+	}
+	// Older CLI versions report only local time.
+	expires, err := time.ParseInLocation("2006-01-02 15:04:05.999999", out.ExpiresOnLT, time.Local)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("azurekv: Azure CLI credential: parse expiry: %w", err)
+	}
+	return out.AccessToken, expires, nil
+}
+
+// chainCredential tries its sources in order until one returns a token,
+// then uses that source alone.
+type chainCredential struct {
+	sources []Credential
+
+	mu       sync.Mutex
+	selected Credential
+}
+
+// Token returns a token from the selected source, selecting one first if
+// needed. Every source but the last is given at most probeTimeout.
+func (c *chainCredential) Token(ctx context.Context, resource string) (string, time.Time, error) {
+	c.mu.Lock()
+	selected := c.selected
+	c.mu.Unlock()
+	if selected != nil {
+		return selected.Token(ctx, resource)
+	}
+
+	var errs []error
+	for i, src := range c.sources {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if i < len(c.sources)-1 {
+			attemptCtx, cancel = context.WithTimeout(ctx, probeTimeout)
+		}
+		token, expires, err := src.Token(attemptCtx, resource)
+		cancel()
+		if err == nil {
+			c.mu.Lock()
+			c.selected = src
+			c.mu.Unlock()
+			return token, expires, nil
+		}
+		if ctx.Err() != nil {
+			return "", time.Time{}, ctx.Err()
+		}
+		errs = append(errs, err)
+	}
+	return "", time.Time{}, fmt.Errorf("azurekv: no credential available: %w", errors.Join(errs...))
+}
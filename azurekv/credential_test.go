@@ -0,0 +1,118 @@
+package azurekv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultCredential_ClientSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant-1/oauth2/v2.0/token" || r.FormValue("client_secret") != "s3cret" ||
+			r.FormValue("scope") != "https://vault.azure.net/.default" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad secret"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+	}))
+	defer srv.Close()
+	t.Setenv("AZURE_AUTHORITY_HOST", srv.URL)
+	t.Setenv("AZURE_TENANT_ID", "tenant-1")
+	t.Setenv("AZURE_CLIENT_ID", "app-1")
+	t.Setenv("AZURE_CLIENT_SECRET", "s3cret")
+
+	token, expires, err := DefaultCredential().Token(context.Background(), "https://vault.azure.net")
+	if err != nil || token != "tok" {
+		t.Fatalf("Token = %q, %v", token, err)
+	}
+	if d := time.Until(expires); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expires in %v, want about an hour", d)
+	}
+
+	t.Setenv("AZURE_CLIENT_SECRET", "wrong")
+	_, _, err = DefaultCredential().Token(context.Background(), "https://vault.azure.net")
+	var se *StatusError
+	if !errors.As(err, &se) || se.Code != "invalid_client" || se.Message != "bad secret" {
+		t.Errorf("expected invalid_client StatusError, got %v", err)
+	}
+}
+
+func TestDefaultCredential_WorkloadIdentity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_assertion") != "federated-jwt" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+	}))
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("federated-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AZURE_AUTHORITY_HOST", srv.URL)
+	t.Setenv("AZURE_TENANT_ID", "tenant-1")
+	t.Setenv("AZURE_CLIENT_ID", "app-1")
+	t.Setenv("AZURE_CLIENT_SECRET", "")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", file)
+
+	if token, _, err := DefaultCredential().Token(context.Background(), "https://vault.azure.net"); err != nil || token != "tok" {
+		t.Errorf("Token = %q, %v", token, err)
+	}
+}
+
+func TestManagedIdentityCredential_AppService(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Header.Get("X-IDENTITY-HEADER") != "h" || q.Get("resource") != "https://vault.azure.net" || q.Get("client_id") != "uami" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"tok","expires_on":"4102444800"}`))
+	}))
+	defer srv.Close()
+	t.Setenv("IDENTITY_ENDPOINT", srv.URL)
+	t.Setenv("IDENTITY_HEADER", "h")
+
+	token, expires, err := ManagedIdentityCredential("uami").Token(context.Background(), "https://vault.azure.net")
+	if err != nil || token != "tok" {
+		t.Fatalf("Token = %q, %v", token, err)
+	}
+	if expires.Unix() != 4102444800 {
+		t.Errorf("expires = %v", expires)
+	}
+}
+
+// funcCredential adapts a function to Credential.
+type funcCredential func(ctx context.Context, resource string) (string, time.Time, error)
+
+func (f funcCredential) Token(ctx context.Context, resource string) (string, time.Time, error) {
+	return f(ctx, resource)
+}
+
+func TestChainCredential_SelectsFirstWorking(t *testing.T) {
+	var firstCalls int
+	chain := &chainCredential{sources: []Credential{
+		funcCredential(func(context.Context, string) (string, time.Time, error) {
+			firstCalls++
+			return "", time.Time{}, errors.New("no IMDS")
+		}),
+		funcCredential(func(context.Context, string) (string, time.Time, error) {
+			return "cli-token", time.Now().Add(time.Hour), nil
+		}),
+	}}
+	for range 2 {
+		if token, _, err := chain.Token(context.Background(), "r"); err != nil || token != "cli-token" {
+			t.Fatalf("Token = %q, %v", token, err)
+		}
+	}
+	if firstCalls != 1 {
+		t.Errorf("first source called %d times, want 1", firstCalls)
+	}
+}
@@ -0,0 +1,83 @@
+package azurekv
+
+import (
+	"context"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// RandomGenerator draws random bytes from a Managed HSM pool. Implement it
+// with the Key Vault GetRandomBytes API:
+//
+//	func (c *myAzureClient) GetRandomBytes(ctx context.Context, n int) ([]byte, error) {
+//	    resp, err := c.kv.GetRandomBytes(ctx, azkeys.GetRandomBytesParameters{Count: to.Ptr(int32(n))}, nil)
+//	    if err != nil { return nil, err }
+//	    return resp.Value, nil
+//	}
+type RandomGenerator interface {
+	// GetRandomBytes returns n random bytes generated in the HSM.
+	GetRandomBytes(ctx context.Context, n int) ([]byte, error)
+}
+
+// GenerateWrappedKey bootstraps a new environment in one call: it generates
+// a 32-byte key, has Key Vault wrap it with the key keyName at keyVersion
+// (its current version if empty) using algorithm (AlgorithmRSAOAEP256 if
+// empty), and returns the wrapped key and the key version that wrapped it,
+// to persist together, plus a provider that already holds the key as its
+// current key. The key comes from crypto/rand, or from the Managed HSM if w
+// also implements RandomGenerator. id names the key in the ring; if empty,
+// the key's crypto.KeyFingerprint is used. Later processes load the same
+// key with
+//
+//	azurekv.New(ctx, client, azurekv.WithWrappedKeyAlgorithm(ciphertext, id, keyName, version, algorithm))
+//
+// where id is the returned provider's CurrentKeyID. opts configure the
+// provider as for crypto.NewKeyRingProvider. The plaintext is wiped once it
+// is in the ring. The caller owns the returned provider and must Close it.
+func GenerateWrappedKey(ctx context.Context, w Wrapper, keyName, keyVersion, algorithm, id string, opts ...crypto.ProviderOption) (ciphertext []byte, version string, ring crypto.KeyRingProvider, err error) {
+	if w == nil {
+		return nil, "", nil, fmt.Errorf("azurekv: Wrapper must not be nil")
+	}
+	if keyName == "" {
+		return nil, "", nil, fmt.Errorf("azurekv: %w: empty Key Vault key name", crypto.ErrInvalidKeyID)
+	}
+	if algorithm == "" {
+		algorithm = AlgorithmRSAOAEP256
+	}
+
+	var key []byte
+	rg, ok := w.(RandomGenerator)
+	if hsm, isHTTP := w.(*HTTPClient); isHTTP && !hsm.IsManagedHSM() {
+		ok = false // vaults have no rng operation
+	}
+	if ok {
+		key, err = rg.GetRandomBytes(ctx, kmsring.KeySize)
+		if err == nil && len(key) != kmsring.KeySize {
+			err = fmt.Errorf("%w: got %d random bytes", crypto.ErrInvalidKeySize, len(key))
+		}
+	} else {
+		key, err = crypto.GenerateKey()
+	}
+	defer clear(key)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("azurekv: generate key: %w", err)
+	}
+
+	ciphertext, version, err = w.WrapKey(ctx, keyName, keyVersion, algorithm, key)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("azurekv: wrap key: %w", err)
+	}
+	if version == "" {
+		version = keyVersion
+	}
+	if id == "" {
+		id = crypto.KeyFingerprint(key)
+	}
+	ring, err = crypto.NewKeyRingProvider(key, id, 0, opts...)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("azurekv: %w", err)
+	}
+	return ciphertext, version, ring, nil
+}
@@ -0,0 +1,79 @@
+package azurekv
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// hsmVault is a remoteVault that also generates random bytes.
+type hsmVault struct {
+	remoteVault
+	requested int
+}
+
+func (m *hsmVault) GetRandomBytes(_ context.Context, n int) ([]byte, error) {
+	m.requested = n
+	return makeKey(9)[:n], nil
+}
+
+func TestGenerateWrappedKey_Bootstrap(t *testing.T) {
+	ctx := context.Background()
+	kv := &remoteVault{
+		current:  map[string]string{"config-kek": "v3"},
+		versions: map[string]byte{"config-kek/v3": 0x5a},
+	}
+	blob, version, ring, err := GenerateWrappedKey(ctx, kv, "config-kek", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if version != "v3" {
+		t.Errorf("version = %q, want v3", version)
+	}
+	ct, err := ring.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A later process loads the persisted blob and reads the value.
+	p, err := New(ctx, kv, WithWrappedKey(blob, ring.CurrentKeyID(), "config-kek", version))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestGenerateWrappedKey_HSMRandom(t *testing.T) {
+	kv := &hsmVault{remoteVault: remoteVault{versions: map[string]byte{"hsm-kek/v1": 1}}}
+	_, _, ring, err := GenerateWrappedKey(context.Background(), kv, "hsm-kek", "v1", AlgorithmA256KW, "key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if kv.requested != 32 {
+		t.Errorf("GetRandomBytes(%d), want 32", kv.requested)
+	}
+	if ring.CurrentKeyID() != "key-1" {
+		t.Errorf("CurrentKeyID = %q, want key-1", ring.CurrentKeyID())
+	}
+}
+
+func TestGenerateWrappedKey_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, _, _, err := GenerateWrappedKey(ctx, nil, "config-kek", "", "", ""); err == nil {
+		t.Error("expected error for nil Wrapper")
+	}
+	if _, _, _, err := GenerateWrappedKey(ctx, &remoteVault{}, "", "", "", ""); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+	_, _, _, err := GenerateWrappedKey(ctx, &remoteVault{}, "config-kek", "v1", "", "")
+	if err == nil || errors.Is(err, crypto.ErrInvalidKeyID) {
+		t.Errorf("expected Key Vault error, got %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+package azurekv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// DefaultAPIVersion is the Key Vault REST API version HTTPClient uses
+// unless WithAPIVersion sets another.
+const DefaultAPIVersion = "7.5"
+
+// maxResponse bounds the Key Vault responses read into memory.
+const maxResponse = 4 << 20
+
+// tokenSkew is how long before expiry a cached access token is renewed.
+const tokenSkew = 5 * time.Minute
+
+// StatusError is a Key Vault error response. IsRetryableError classifies
+// it by StatusCode.
+type StatusError struct {
+	StatusCode int    // HTTP status
+	Code       string // Key Vault error code, such as "Throttled" or "Forbidden"
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// newStatusError builds a StatusError from a Key Vault error body,
+// {"error": {"code", "message"}}, or an Entra ID one,
+// {"error": code, "error_description": message}.
+func newStatusError(status int, body []byte) *StatusError {
+	e := &StatusError{StatusCode: status}
+	var resp struct {
+		Error       json.RawMessage `json:"error"`
+		Description string          `json:"error_description"`
+	}
+	if json.Unmarshal(body, &resp) != nil || len(resp.Error) == 0 {
+		return e
+	}
+	var kv struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(resp.Error, &kv) == nil {
+		e.Code, e.Message = kv.Code, kv.Message
+	} else if json.Unmarshal(resp.Error, &e.Code) == nil {
+		e.Message = resp.Description
+	}
+	return e
+}
+
+// HTTPClient calls the Key Vault and Managed HSM REST API directly with
+// net/http, authenticating with a Credential. It implements Client,
+// Wrapper, ListingClient, and RandomGenerator (Managed HSM only), so it
+// works with every constructor in this package without the Azure SDK. It
+// is safe for concurrent use.
+type HTTPClient struct {
+	vaultURL   string
+	resource   string
+	cred       Credential
+	apiVersion string
+	hc         *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Compile-time interface checks.
+var (
+	_ RemoteClient    = (*HTTPClient)(nil)
+	_ ListingClient   = (*HTTPClient)(nil)
+	_ RandomGenerator = (*HTTPClient)(nil)
+)
+
+// HTTPOption configures an HTTPClient.
+type HTTPOption func(*HTTPClient)
+
+// WithAPIVersion sets the api-version query parameter sent with every
+// request. Defaults to DefaultAPIVersion.
+func WithAPIVersion(v string) HTTPOption {
+	return func(c *HTTPClient) {
+		c.apiVersion = v
+	}
+}
+
+// WithHTTPClient sets the http.Client used for Key Vault requests, for
+// example to configure proxies or TLS. Defaults to http.DefaultClient.
+func WithHTTPClient(hc *http.Client) HTTPOption {
+	return func(c *HTTPClient) {
+		c.hc = hc
+	}
+}
+
+// WithTokenResource overrides the resource access tokens are requested
+// for. It defaults to the vault URL's parent domain, such as
+// "https://vault.azure.net" or "https://managedhsm.azure.net", which is
+// right for public and sovereign clouds alike.
+func WithTokenResource(resource string) HTTPOption {
+	return func(c *HTTPClient) {
+		c.resource = resource
+	}
+}
+
+// NewHTTPClient returns an HTTPClient for the vault or Managed HSM pool at
+// vaultURL, such as "https://my-vault.vault.azure.net/" or
+// "https://my-pool.managedhsm.azure.net/". Use DefaultCredential for
+// cred unless the host needs a specific identity.
+func NewHTTPClient(vaultURL string, cred Credential, opts ...HTTPOption) (*HTTPClient, error) {
+	if cred == nil {
+		return nil, fmt.Errorf("azurekv: Credential must not be nil")
+	}
+	u, err := url.Parse(vaultURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("azurekv: vault URL %q must be an https URL", vaultURL)
+	}
+	c := &HTTPClient{
+		vaultURL:   "https://" + u.Host,
+		cred:       cred,
+		apiVersion: DefaultAPIVersion,
+		hc:         http.DefaultClient,
+	}
+	if _, parent, ok := strings.Cut(u.Hostname(), "."); ok {
+		c.resource = "https://" + parent
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.resource == "" {
+		return nil, fmt.Errorf("azurekv: cannot derive token resource from %q; use WithTokenResource", vaultURL)
+	}
+	return c, nil
+}
+
+// IsManagedHSM reports whether the client points at a Managed HSM pool.
+func (c *HTTPClient) IsManagedHSM() bool {
+	return strings.Contains(c.vaultURL, ".managedhsm.")
+}
+
+// UnwrapKey calls the unwrapkey operation. An empty keyVersion uses the
+// key's current version.
+func (c *HTTPClient) UnwrapKey(ctx context.Context, keyName, keyVersion, algorithm string, ciphertext []byte) ([]byte, error) {
+	var out keyOperationResult
+	err := c.do(ctx, http.MethodPost, keyPath(keyName, keyVersion, "unwrapkey"),
+		keyOperation{Algorithm: algorithm, Value: base64.RawURLEncoding.EncodeToString(ciphertext)}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBase64URL(out.Value)
+}
+
+// WrapKey calls the wrapkey operation and reports the version Key Vault
+// used, taken from the returned key ID.
+func (c *HTTPClient) WrapKey(ctx context.Context, keyName, keyVersion, algorithm string, plaintext []byte) ([]byte, string, error) {
+	value := base64.RawURLEncoding.EncodeToString(plaintext)
+	var out keyOperationResult
+	err := c.do(ctx, http.MethodPost, keyPath(keyName, keyVersion, "wrapkey"),
+		keyOperation{Algorithm: algorithm, Value: value}, &out)
+	if err != nil {
+		return nil, "", err
+	}
+	ciphertext, err := decodeBase64URL(out.Value)
+	if err != nil {
+		return nil, "", err
+	}
+	return ciphertext, path.Base(out.KID), nil
+}
+
+// ListKeyVersions lists the enabled versions of keyName and marks the one
+// that GetKey reports as current.
+func (c *HTTPClient) ListKeyVersions(ctx context.Context, keyName string) ([]KeyVersionInfo, error) {
+	var current struct {
+		Key struct {
+			KID string `json:"kid"`
+		} `json:"key"`
+	}
+	if err := c.do(ctx, http.MethodGet, keyPath(keyName, "", ""), nil, &current); err != nil {
+		return nil, err
+	}
+	currentVersion := path.Base(current.Key.KID)
+
+	var infos []KeyVersionInfo
+	next := keyPath(keyName, "", "") + "/versions"
+	for next != "" {
+		var page struct {
+			Value []struct {
+				KID        string `json:"kid"`
+				Attributes struct {
+					Enabled bool `json:"enabled"`
+				} `json:"attributes"`
+			} `json:"value"`
+			NextLink string `json:"nextLink"`
+		}
+		if err := c.do(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, v := range page.Value {
+			if v.Attributes.Enabled {
+				version := path.Base(v.KID)
+				infos = append(infos, KeyVersionInfo{KeyVersion: version, IsCurrent: version == currentVersion})
+			}
+		}
+		var err error
+		if next, err = c.relative(page.NextLink); err != nil {
+			return nil, err
+		}
+	}
+	return infos, nil
+}
+
+// GetRandomBytes calls the Managed HSM rng operation.
+func (c *HTTPClient) GetRandomBytes(ctx context.Context, n int) ([]byte, error) {
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/rng", map[string]int{"count": n}, &out); err != nil {
+		return nil, err
+	}
+	return decodeBase64URL(out.Value)
+}
+
+// keyOperation is the request body of wrapkey and unwrapkey.
+type keyOperation struct {
+	Algorithm string `json:"alg"`
+	Value     string `json:"value"`
+}
+
+// keyOperationResult is the response body of wrapkey and unwrapkey.
+type keyOperationResult struct {
+	KID   string `json:"kid"`
+	Value string `json:"value"`
+}
+
+// keyPath returns /keys/{name}/{version}/{op}. Like the Azure SDK, it
+// keeps an empty version segment, which Key Vault reads as the current
+// version.
+func keyPath(name, version, op string) string {
+	p := "/keys/" + url.PathEscape(name)
+	if op == "" {
+		if version != "" {
+			p += "/" + url.PathEscape(version)
+		}
+		return p
+	}
+	return p + "/" + url.PathEscape(version) + "/" + op
+}
+
+// decodeBase64URL decodes Key Vault's base64url values, which are
+// normally unpadded.
+func decodeBase64URL(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, fmt.Errorf("azurekv: decode response value: %w", err)
+	}
+	return b, nil
+}
+
+// relative turns a nextLink into a path and query on the vault, refusing
+// links to other hosts so the access token is never sent elsewhere.
+func (c *HTTPClient) relative(link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+	u, err := url.Parse(link)
+	if err != nil || "https://"+u.Host != c.vaultURL {
+		return "", fmt.Errorf("azurekv: unexpected nextLink %q", link)
+	}
+	return u.RequestURI(), nil
+}
+
+// accessToken returns a cached token, requesting a new one when it is
+// within tokenSkew of expiring.
+func (c *HTTPClient) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Until(c.expires) > tokenSkew {
+		return c.token, nil
+	}
+	token, expires, err := c.cred.Token(ctx, c.resource)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expires = token, expires
+	return token, nil
+}
+
+// do sends a request to the vault and decodes the JSON response into out.
+// Request and response bodies, which may hold key material, are cleared
+// after use.
+func (c *HTTPClient) do(ctx context.Context, method, pathAndQuery string, in, out any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if in != nil {
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("azurekv: encode request: %w", err)
+		}
+		defer clear(body)
+	}
+	u := c.vaultURL + pathAndQuery
+	if !strings.Contains(pathAndQuery, "api-version=") {
+		sep := "?"
+		if strings.Contains(pathAndQuery, "?") {
+			sep = "&"
+		}
+		u += sep + "api-version=" + url.QueryEscape(c.apiVersion)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("azurekv: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("azurekv: %s %s: %w", method, pathAndQuery, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	defer clear(respBody)
+	if err != nil {
+		return fmt.Errorf("azurekv: %s %s: %w", method, pathAndQuery, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("azurekv: %s %s: %w", method, pathAndQuery, newStatusError(resp.StatusCode, respBody))
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("azurekv: decode response: %w", err)
+	}
+	return nil
+}
+
+// NewFromVaultURL is New for services that do not want to wire up a
+// client: it builds an HTTPClient for vaultURL authenticated with
+// DefaultCredential, typically the host's managed identity, and unwraps
+// the keys in opts with it:
+//
+//	provider, err := azurekv.NewFromVaultURL(ctx, "https://my-vault.vault.azure.net/",
+//	    azurekv.WithWrappedKey(wrappedKeyBytes, "key-1", "my-key-name", ""),
+//	)
+//
+// For a Managed HSM pool URL, WithManagedHSM is applied before opts. The
+// client is not retained; use NewHTTPClient with NewRefreshable or
+// NewRemote to keep one.
+func NewFromVaultURL(ctx context.Context, vaultURL string, opts ...Option) (crypto.KeyRingProvider, error) {
+	client, err := NewHTTPClient(vaultURL, DefaultCredential())
+	if err != nil {
+		return nil, err
+	}
+	if client.IsManagedHSM() {
+		opts = append([]Option{WithManagedHSM()}, opts...)
+	}
+	return New(ctx, client, opts...)
+}
@@ -0,0 +1,205 @@
+package azurekv
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// staticCredential returns a fixed token and records the resource asked for.
+type staticCredential struct {
+	resource string
+	calls    atomic.Int32
+}
+
+func (c *staticCredential) Token(_ context.Context, resource string) (string, time.Time, error) {
+	c.calls.Add(1)
+	c.resource = resource
+	return "tok", time.Now().Add(time.Hour), nil
+}
+
+// fakeKeyVault serves the Key Vault REST operations HTTPClient uses for a
+// key "kek" with versions v1 (disabled), v2, and v3 (current). Wrapped
+// values are the plaintext XORed with the version's last byte.
+func fakeKeyVault(t *testing.T, status *atomic.Int32) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if s := status.Load(); s != 0 {
+			w.WriteHeader(int(s))
+			_, _ = w.Write([]byte(`{"error":{"code":"Throttled","message":"slow down"}}`))
+			return
+		}
+		kid := func(v string) string { return srv.URL + "/keys/kek/" + v }
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/keys/"), "/")
+		w.Header().Set("Api-Version-Seen", r.URL.Query().Get("api-version"))
+		switch {
+		case r.Method == http.MethodGet && len(parts) == 1:
+			_ = json.NewEncoder(w).Encode(map[string]any{"key": map[string]string{"kid": kid("v3")}})
+		case r.Method == http.MethodGet && parts[1] == "versions" && r.URL.Query().Get("page") == "":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"value": []map[string]any{
+					{"kid": kid("v1"), "attributes": map[string]bool{"enabled": false}},
+					{"kid": kid("v2"), "attributes": map[string]bool{"enabled": true}},
+				},
+				"nextLink": srv.URL + "/keys/kek/versions?page=2&api-version=7.5",
+			})
+		case r.Method == http.MethodGet && parts[1] == "versions":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"value": []map[string]any{{"kid": kid("v3"), "attributes": map[string]bool{"enabled": true}}},
+			})
+		case r.Method == http.MethodPost && len(parts) == 3:
+			var op keyOperation
+			if err := json.NewDecoder(r.Body).Decode(&op); err != nil || op.Algorithm != AlgorithmRSAOAEP256 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			version := parts[1]
+			if version == "" {
+				version = "v3"
+			}
+			b, _ := base64.RawURLEncoding.DecodeString(op.Value)
+			for i := range b {
+				b[i] ^= version[1]
+			}
+			_ = json.NewEncoder(w).Encode(keyOperationResult{KID: kid(version), Value: base64.RawURLEncoding.EncodeToString(b)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestHTTPClient(t *testing.T, srv *httptest.Server, cred Credential, opts ...HTTPOption) *HTTPClient {
+	t.Helper()
+	c, err := NewHTTPClient(srv.URL, cred, append([]HTTPOption{WithHTTPClient(srv.Client())}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestHTTPClient_RemoteRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	cred := &staticCredential{}
+	client := newTestHTTPClient(t, fakeKeyVault(t, new(atomic.Int32)), cred,
+		WithTokenResource("https://vault.azure.net"))
+	p, err := NewRemote(client, "kek", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "kek/v3" {
+		t.Errorf("KeyIDOf = %q, want kek/v3", id)
+	}
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+	if cred.calls.Load() != 1 || cred.resource != "https://vault.azure.net" {
+		t.Errorf("Token calls = %d for %q, want 1 for https://vault.azure.net", cred.calls.Load(), cred.resource)
+	}
+}
+
+func TestHTTPClient_ListKeyVersions(t *testing.T) {
+	client := newTestHTTPClient(t, fakeKeyVault(t, new(atomic.Int32)), &staticCredential{})
+	infos, err := client.ListKeyVersions(context.Background(), "kek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyVersionInfo{{KeyVersion: "v2"}, {KeyVersion: "v3", IsCurrent: true}}
+	if len(infos) != len(want) || infos[0] != want[0] || infos[1] != want[1] {
+		t.Errorf("ListKeyVersions = %+v, want %+v", infos, want)
+	}
+}
+
+func TestHTTPClient_ResolvesVersionForNew(t *testing.T) {
+	ctx := context.Background()
+	client := newTestHTTPClient(t, fakeKeyVault(t, new(atomic.Int32)), &staticCredential{})
+	blob, version, ring, err := GenerateWrappedKey(ctx, client, "kek", "", "", "key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring.Close()
+
+	var got []ResolvedKey
+	p, err := New(ctx, client, WithWrappedKey(blob, "key-1", "kek", ""),
+		WithResolvedKeysHandler(func(keys []ResolvedKey) { got = keys }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if version != "v3" || len(got) != 1 || got[0].KeyVersion != "v3" {
+		t.Errorf("version = %q, resolved = %+v, want v3", version, got)
+	}
+}
+
+func TestHTTPClient_StatusError(t *testing.T) {
+	status := new(atomic.Int32)
+	status.Store(http.StatusTooManyRequests)
+	client := newTestHTTPClient(t, fakeKeyVault(t, status), &staticCredential{})
+	_, err := client.UnwrapKey(context.Background(), "kek", "v2", AlgorithmRSAOAEP256, []byte("x"))
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusTooManyRequests || se.Code != "Throttled" {
+		t.Fatalf("expected Throttled StatusError, got %v", err)
+	}
+	if !IsRetryableError(err) {
+		t.Error("429 should be retryable")
+	}
+}
+
+func TestHTTPClient_APIVersion(t *testing.T) {
+	var seen string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.URL.Query().Get("api-version")
+		_, _ = w.Write([]byte(`{"value":"AAEC"}`))
+	}))
+	defer srv.Close()
+	client := newTestHTTPClient(t, srv, &staticCredential{}, WithAPIVersion("7.4"))
+	if _, err := client.GetRandomBytes(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+	if seen != "7.4" {
+		t.Errorf("api-version = %q, want 7.4", seen)
+	}
+}
+
+func TestHTTPClient_RejectsForeignNextLink(t *testing.T) {
+	client := newTestHTTPClient(t, fakeKeyVault(t, new(atomic.Int32)), &staticCredential{})
+	if _, err := client.relative("https://evil.example/keys/kek/versions"); err == nil {
+		t.Error("expected error for nextLink on another host")
+	}
+}
+
+func TestNewHTTPClient_Invalid(t *testing.T) {
+	if _, err := NewHTTPClient("http://my-vault.vault.azure.net/", &staticCredential{}); err == nil {
+		t.Error("expected error for http URL")
+	}
+	if _, err := NewHTTPClient("https://my-vault.vault.azure.net/", nil); err == nil {
+		t.Error("expected error for nil credential")
+	}
+	c, err := NewHTTPClient("https://my-pool.managedhsm.azure.net/", &staticCredential{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsManagedHSM() || c.resource != "https://managedhsm.azure.net" {
+		t.Errorf("IsManagedHSM = %v, resource = %q", c.IsManagedHSM(), c.resource)
+	}
+}
@@ -17,11 +17,28 @@
 //	provider, err := azurekv.New(ctx, &myAzureClient{kv},
 //	    azurekv.WithWrappedKey(wrappedKeyBytes, "key-1", "my-key-name", "key-version"),
 //	)
+//
+// A Managed HSM pool works the same way: create the azkeys client with the
+// pool's endpoint, such as "https://my-pool.managedhsm.azure.net/", and add
+// WithManagedHSM for its stricter throttling. Managed HSM also wraps with
+// AES keys (AlgorithmA256KW). The REST API version is a property of the SDK
+// client; pin it with azkeys.ClientOptions{ClientOptions:
+// azcore.ClientOptions{APIVersion: "7.5"}} when a pool or vault requires a
+// specific version.
+//
+// To skip the SDK entirely, HTTPClient implements every client interface
+// in this package over net/http with DefaultCredential, and
+// NewFromVaultURL builds one and calls New:
+//
+//	provider, err := azurekv.NewFromVaultURL(ctx, "https://my-vault.vault.azure.net/",
+//	    azurekv.WithWrappedKey(wrappedKeyBytes, "key-1", "my-key-name", ""),
+//	)
 package azurekv
 
 import (
 	"context"
 	"fmt"
+	"time"
 
 	crypto "github.com/rbaliyan/config-crypto"
 	"github.com/rbaliyan/config-crypto/internal/kmsring"
@@ -33,6 +50,11 @@ const (
 	AlgorithmRSAOAEP256 = "RSA-OAEP-256"
 	AlgorithmRSAOAEP    = "RSA-OAEP"
 	AlgorithmRSA15      = "RSA1_5"
+
+	// AES key wrap (RFC 3394) with an oct-HSM key. Managed HSM only.
+	AlgorithmA128KW = "A128KW"
+	AlgorithmA192KW = "A192KW"
+	AlgorithmA256KW = "A256KW"
 )
 
 // Client unwraps an AES-256 data key that was wrapped by Azure Key Vault.
@@ -41,7 +63,8 @@ const (
 // azure-sdk-for-go.
 type Client interface {
 	// UnwrapKey unwraps a data key that was wrapped by the specified Key Vault key.
-	// algorithm is the wrapping algorithm (e.g. AlgorithmRSAOAEP256).
+	// algorithm is the wrapping algorithm (e.g. AlgorithmRSAOAEP256). New
+	// calls UnwrapKey concurrently when several keys are configured.
 	UnwrapKey(ctx context.Context, keyName, keyVersion, algorithm string, ciphertext []byte) (plaintext []byte, err error)
 }
 
@@ -50,6 +73,11 @@ type Option func(*options)
 
 type options struct {
 	wrappedKeys []wrappedKeyEntry
+	onSkip      kmsring.SkipFn
+	retry       retryPolicy
+	timeout     time.Duration
+	fallback    bool
+	onResolved  func([]ResolvedKey)
 }
 
 type wrappedKeyEntry struct {
@@ -58,10 +86,27 @@ type wrappedKeyEntry struct {
 	keyName    string
 	keyVersion string
 	algorithm  string
+	fallback   bool
+}
+
+// keyRef names the Key Vault key for error messages, as keyName/keyVersion
+// and with the algorithm when it is not the default.
+func (e wrappedKeyEntry) keyRef() string {
+	ref := e.keyName
+	if e.keyVersion != "" {
+		ref += "/" + e.keyVersion
+	}
+	if e.algorithm != AlgorithmRSAOAEP256 {
+		ref += " (" + e.algorithm + ")"
+	}
+	return ref
 }
 
 // WithWrappedKey adds a wrapped key to be unwrapped via Key Vault.
 // The keyName and keyVersion identify the Key Vault key used for wrapping.
+// keyVersion may be empty to use the key's current version; if client
+// implements ListingClient, New resolves and records it (see
+// WithResolvedKeysHandler).
 // The id identifies this key in the config-crypto system.
 // Uses AlgorithmRSAOAEP256 by default.
 // The first key added becomes the current key for new encryptions.
@@ -78,7 +123,8 @@ func WithWrappedKey(ciphertext []byte, id, keyName, keyVersion string) Option {
 }
 
 // WithWrappedKeyAlgorithm is like WithWrappedKey but allows specifying the
-// unwrap algorithm (e.g. AlgorithmRSAOAEP or AlgorithmRSA15).
+// unwrap algorithm (e.g. AlgorithmRSAOAEP, AlgorithmRSA15, or, for a
+// Managed HSM, AlgorithmA256KW).
 func WithWrappedKeyAlgorithm(ciphertext []byte, id, keyName, keyVersion, algorithm string) Option {
 	return func(o *options) {
 		o.wrappedKeys = append(o.wrappedKeys, wrappedKeyEntry{
@@ -91,14 +137,38 @@ func WithWrappedKeyAlgorithm(ciphertext []byte, id, keyName, keyVersion, algorit
 	}
 }
 
+// WithSkipFailedKeys lets New succeed when a key other than the current one
+// cannot be unwrapped, for example because its Key Vault key was deleted or
+// access to it was revoked. Each such key is left out of the ring and
+// reported to fn, which may be nil, so values it encrypted fail to decrypt
+// with crypto.ErrKeyNotFound instead of taking the whole provider down.
+// New still fails if the current key cannot be unwrapped.
+func WithSkipFailedKeys(fn func(id string, err error)) Option {
+	return func(o *options) {
+		if fn == nil {
+			fn = func(string, error) {}
+		}
+		o.onSkip = fn
+	}
+}
+
 // New creates a crypto.KeyRingProvider that unwraps keys using Azure Key Vault.
 //
 // At least one key must be provided via WithWrappedKey. The first key is the
 // current key for new encryptions; additional keys are available for
 // decryption (key rotation).
 //
-// Keys are unwrapped during construction and cached. The Key Vault client is
-// not retained after construction.
+// Keys are unwrapped concurrently during construction and cached. If any key
+// fails, the errors for all failed keys are returned together, each naming
+// the Key Vault key and version it was unwrapped with, unless
+// WithSkipFailedKeys allows old keys to be skipped. WithRetries,
+// WithCallTimeout, and WithConstructionTimeout bound how long a slow or
+// throttled vault can stall construction. The Key Vault
+// client is not retained after construction; use NewRefreshable to keep it
+// and pick up new keys at runtime.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the unwrapped key material and is safe to call more than once.
 func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
 	if client == nil {
 		return nil, fmt.Errorf("azurekv: Client must not be nil")
@@ -108,10 +178,41 @@ func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProv
 	for _, opt := range opts {
 		opt(&o)
 	}
+	lc, err := o.listingClient(client)
+	if err != nil {
+		return nil, err
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if err := o.resolve(ctx, lc); err != nil {
+		return nil, err
+	}
 
-	return kmsring.Build(len(o.wrappedKeys), "azurekv", func(i int) ([]byte, string, error) {
+	resolved := make([]ResolvedKey, len(o.wrappedKeys))
+	ok := make([]bool, len(o.wrappedKeys))
+	ring, err := kmsring.BuildSkipping(len(o.wrappedKeys), "azurekv", o.onSkip, func(i int) ([]byte, string, error) {
 		wk := o.wrappedKeys[i]
-		pt, err := client.UnwrapKey(ctx, wk.keyName, wk.keyVersion, wk.algorithm, wk.ciphertext)
+		pt, rk, err := o.retry.unwrap(ctx, client, lc, wk)
+		if err != nil {
+			err = fmt.Errorf("unwrap with %s: %w", wk.keyRef(), err)
+		}
+		resolved[i], ok[i] = rk, err == nil
 		return pt, wk.id, err
 	})
+	if err != nil {
+		return nil, err
+	}
+	if o.onResolved != nil {
+		var keys []ResolvedKey
+		for i, rk := range resolved {
+			if ok[i] {
+				keys = append(keys, rk)
+			}
+		}
+		o.onResolved(keys)
+	}
+	return ring, nil
 }
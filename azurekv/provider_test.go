@@ -142,3 +142,31 @@ func TestNew_WithAlgorithm(t *testing.T) {
 	}
 	defer provider.Close()
 }
+
+func TestNew_SkipFailedKeys(t *testing.T) {
+	ctx := context.Background()
+	client := &mockClient{keys: map[string][]byte{"wrap-1": makeKey(1), "wrap-3": makeKey(3)}, failOn: "wrap-2"}
+	var skipped []string
+	provider, err := New(ctx, client,
+		WithWrappedKey([]byte("wrap-1"), "key-1", "my-key", "v1"),
+		WithWrappedKey([]byte("wrap-2"), "key-2", "my-key", "v1"),
+		WithWrappedKey([]byte("wrap-3"), "key-3", "my-key", "v1"),
+		WithSkipFailedKeys(func(id string, err error) { skipped = append(skipped, id) }),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer provider.Close()
+	if len(skipped) != 1 || skipped[0] != "key-2" {
+		t.Errorf("skipped = %v, want [key-2]", skipped)
+	}
+	if provider.CurrentKeyID() != "key-1" {
+		t.Errorf("CurrentKeyID = %q, want key-1", provider.CurrentKeyID())
+	}
+
+	// The current key failing is still fatal.
+	client = &mockClient{keys: map[string][]byte{"wrap-2": makeKey(2)}, failOn: "wrap-1"}
+	if _, err := New(ctx, client, WithWrappedKey([]byte("wrap-1"), "key-1", "my-key", "v1"), WithWrappedKey([]byte("wrap-2"), "key-2", "my-key", "v1"), WithSkipFailedKeys(nil)); err == nil {
+		t.Error("expected error when the current key fails")
+	}
+}
@@ -0,0 +1,63 @@
+package azurekv
+
+import (
+	"context"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// KeySetFunc returns the options describing the keys a refreshable provider
+// should hold, typically built from wrapped keys stored in a config store.
+// As with New, the first key is current.
+type KeySetFunc func(ctx context.Context) ([]Option, error)
+
+// NewRefreshable is like New but retains client and returns a
+// crypto.Refresher that calls keys again to pick up newly added wrapped keys
+// and current-key changes without a restart. Only keys the ring does not
+// hold yet are sent to Key Vault, so a refresh with no changes costs one keys call,
+// plus one ListKeyVersions per key name if keys omit their version and
+// client implements ListingClient.
+//
+// Call Refresher.Refresh on demand, or pass crypto.WithRefreshInterval to
+// refresh in the background. Call Refresher.Stop before closing the ring.
+//
+//	ring, r, err := azurekv.NewRefreshable(ctx, client, loadKeys,
+//	    crypto.WithRefreshInterval(10*time.Minute))
+//	defer ring.Close()
+//	defer r.Stop()
+func NewRefreshable(ctx context.Context, client Client, keys KeySetFunc, opts ...crypto.RefreshOption) (crypto.KeyRingProvider, *crypto.Refresher, error) {
+	if client == nil {
+		return nil, nil, fmt.Errorf("azurekv: Client must not be nil")
+	}
+	if keys == nil {
+		return nil, nil, fmt.Errorf("azurekv: KeySetFunc must not be nil")
+	}
+	list := func(ctx context.Context) ([]wrappedKeyEntry, error) {
+		set, err := keys(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var o options
+		for _, opt := range set {
+			opt(&o)
+		}
+		lc, err := o.listingClient(client)
+		if err != nil {
+			return nil, err
+		}
+		if err := o.resolve(ctx, lc); err != nil {
+			return nil, err
+		}
+		return o.wrappedKeys, nil
+	}
+	lc, _ := client.(ListingClient)
+	return kmsring.Refreshable(ctx, "azurekv", list,
+		func(e wrappedKeyEntry) string { return e.id },
+		func(ctx context.Context, e wrappedKeyEntry) ([]byte, error) {
+			pt, _, err := retryPolicy{}.unwrap(ctx, client, lc, e)
+			return pt, err
+		},
+		opts...)
+}
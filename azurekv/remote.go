@@ -0,0 +1,105 @@
+package azurekv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// Wrapper wraps data keys with a Key Vault key. Implement it with the Key
+// Vault WrapKey API:
+//
+//	func (c *myAzureClient) WrapKey(ctx context.Context, keyName, keyVersion, algorithm string, plaintext []byte) ([]byte, string, error) {
+//	    alg := azkeys.EncryptionAlgorithm(algorithm)
+//	    resp, err := c.kv.WrapKey(ctx, keyName, keyVersion, azkeys.KeyOperationParameters{Algorithm: &alg, Value: plaintext}, nil)
+//	    if err != nil { return nil, "", err }
+//	    return resp.Result, resp.KID.Version(), nil
+//	}
+type Wrapper interface {
+	// WrapKey wraps plaintext with the Key Vault key keyName at keyVersion,
+	// or at its current version if keyVersion is empty, and returns the
+	// wrapped bytes and the version that was used.
+	WrapKey(ctx context.Context, keyName, keyVersion, algorithm string, plaintext []byte) (ciphertext []byte, usedVersion string, err error)
+}
+
+// RemoteClient both wraps and unwraps with Key Vault.
+type RemoteClient interface {
+	Client
+	Wrapper
+}
+
+// NewRemote returns a crypto.Provider that never holds a plaintext KEK:
+// every Encrypt generates a DEK locally and has Key Vault wrap it with the
+// key keyName, embedding the wrapped blob in the envelope, and every
+// Decrypt has Key Vault unwrap it. Key material never leaves the vault or
+// Managed HSM except as a per-value DEK, and Key Vault access policies or
+// RBAC (the unwrapKey permission) govern every read of config:
+//
+//	provider, err := azurekv.NewRemote(client, "config-kek", "", azurekv.AlgorithmRSAOAEP256)
+//
+// An empty keyVersion wraps with the key's current version. The header
+// records "keyName/version" with the version Key Vault used, so values stay
+// decryptable after the key rotates as long as the old version is enabled.
+// algorithm is used for both wrapping and unwrapping; empty means
+// AlgorithmRSAOAEP256. Each Encrypt and Decrypt costs one Key Vault call, so
+// for hot paths enable crypto.WithDecodeCache on the codec. opts are passed
+// to crypto.NewWrappingProvider. Values use the wrapped-DEK envelope format;
+// a key ring provider cannot decrypt them.
+func NewRemote(client RemoteClient, keyName, keyVersion, algorithm string, opts ...crypto.ProviderOption) (crypto.Provider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("azurekv: Client must not be nil")
+	}
+	if keyName == "" || strings.Contains(keyName, "/") {
+		return nil, fmt.Errorf("azurekv: %w: invalid Key Vault key name %q", crypto.ErrInvalidKeyID, keyName)
+	}
+	if algorithm == "" {
+		algorithm = AlgorithmRSAOAEP256
+	}
+	return crypto.NewWrappingProvider(&remoteWrapper{
+		client:     client,
+		keyName:    keyName,
+		keyVersion: keyVersion,
+		algorithm:  algorithm,
+	}, opts...)
+}
+
+// remoteWrapper adapts a RemoteClient to crypto.KeyWrapper.
+type remoteWrapper struct {
+	client     RemoteClient
+	keyName    string
+	keyVersion string
+	algorithm  string
+}
+
+// Name returns "azurekv".
+func (w *remoteWrapper) Name() string { return "azurekv" }
+
+// WrapKey wraps dek with the configured Key Vault key.
+func (w *remoteWrapper) WrapKey(ctx context.Context, dek []byte) (string, []byte, error) {
+	ciphertext, version, err := w.client.WrapKey(ctx, w.keyName, w.keyVersion, w.algorithm, dek)
+	if err != nil {
+		return "", nil, fmt.Errorf("azurekv: wrap DEK: %w", err)
+	}
+	if version == "" {
+		version = w.keyVersion
+	}
+	if version == "" {
+		return "", nil, fmt.Errorf("azurekv: wrap DEK: Key Vault did not report the key version")
+	}
+	return w.keyName + "/" + version, ciphertext, nil
+}
+
+// UnwrapKey unwraps a DEK with the key version recorded in the header.
+func (w *remoteWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	name, version, ok := strings.Cut(keyID, "/")
+	if !ok || name == "" || version == "" {
+		return nil, fmt.Errorf("azurekv: %w: %q is not keyName/version", crypto.ErrKeyNotFound, keyID)
+	}
+	dek, err := w.client.UnwrapKey(ctx, name, version, w.algorithm, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("azurekv: unwrap DEK: %w", err)
+	}
+	return dek, nil
+}
@@ -0,0 +1,113 @@
+package azurekv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// remoteVault is an in-memory Key Vault: wrapped blobs are the key
+// reference, a separator, and the plaintext XORed with a per-version byte.
+type remoteVault struct {
+	current  map[string]string // key name -> current version
+	versions map[string]byte   // "name/version" -> XOR byte
+	unwraps  atomic.Int64
+}
+
+func (m *remoteVault) WrapKey(_ context.Context, keyName, keyVersion, algorithm string, plaintext []byte) ([]byte, string, error) {
+	if keyVersion == "" {
+		keyVersion = m.current[keyName]
+	}
+	ref := keyName + "/" + keyVersion
+	x, ok := m.versions[ref]
+	if !ok {
+		return nil, "", fmt.Errorf("keyvault: KeyNotFound: %s", ref)
+	}
+	ct := append([]byte(ref+"|"+algorithm+"|"), plaintext...)
+	for i := len(ct) - len(plaintext); i < len(ct); i++ {
+		ct[i] ^= x
+	}
+	return ct, keyVersion, nil
+}
+
+func (m *remoteVault) UnwrapKey(_ context.Context, keyName, keyVersion, algorithm string, ciphertext []byte) ([]byte, error) {
+	m.unwraps.Add(1)
+	parts := bytes.SplitN(ciphertext, []byte("|"), 3)
+	ref := keyName + "/" + keyVersion
+	if len(parts) != 3 || string(parts[0]) != ref || string(parts[1]) != algorithm {
+		return nil, errors.New("keyvault: BadParameter: invalid ciphertext")
+	}
+	pt := bytes.Clone(parts[2])
+	for i := range pt {
+		pt[i] ^= m.versions[ref]
+	}
+	return pt, nil
+}
+
+var _ RemoteClient = (*remoteVault)(nil)
+
+func TestNewRemote_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	kv := &remoteVault{
+		current:  map[string]string{"config-kek": "v1"},
+		versions: map[string]byte{"config-kek/v1": 0x5a, "config-kek/v2": 0xa5},
+	}
+	p, err := NewRemote(kv, "config-kek", "", AlgorithmA256KW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.Name() != "azurekv" {
+		t.Errorf("Name = %q, want azurekv", p.Name())
+	}
+
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "config-kek/v1" {
+		t.Errorf("KeyIDOf = %q, want config-kek/v1", id)
+	}
+
+	// Rotating the key does not affect existing values.
+	kv.current["config-kek"] = "v2"
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+	if kv.unwraps.Load() != 1 {
+		t.Errorf("UnwrapKey calls = %d, want 1", kv.unwraps.Load())
+	}
+	ct, err = p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "config-kek/v2" {
+		t.Errorf("KeyIDOf after rotation = %q, want config-kek/v2", id)
+	}
+}
+
+func TestNewRemote_Errors(t *testing.T) {
+	p, err := NewRemote(&remoteVault{}, "config-kek", "v1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.Encrypt(context.Background(), []byte("secret")); err == nil || !strings.Contains(err.Error(), "KeyNotFound") {
+		t.Errorf("expected Key Vault error, got %v", err)
+	}
+	if _, err := NewRemote(nil, "config-kek", "", ""); err == nil {
+		t.Error("expected error for nil client")
+	}
+	for _, name := range []string{"", "a/b"} {
+		if _, err := NewRemote(&remoteVault{}, name, "", ""); !crypto.IsInvalidKeyID(err) {
+			t.Errorf("NewRemote(%q): expected ErrInvalidKeyID, got %v", name, err)
+		}
+	}
+}
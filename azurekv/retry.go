@@ -0,0 +1,140 @@
+package azurekv
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// retryableStatus are HTTP statuses from Key Vault and Managed HSM that a
+// later attempt may not see. Managed HSM pools throttle per pool and
+// answer with 429 far sooner than a vault does.
+var retryableStatus = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// retryPolicy controls how New retries Key Vault calls. The zero value
+// makes one attempt with no timeout.
+type retryPolicy kmsring.RetryPolicy
+
+// WithRetries makes New retry each failed UnwrapKey call up to n more times
+// with exponential backoff, for errors that look transient: HTTP 408, 429,
+// 500, 502, 503, and 504, and errors that crypto.IsRetryable accepts.
+// Errors from the Azure SDK (*azcore.ResponseError) are classified by their
+// StatusCode. The SDK's own retry policy runs inside each attempt; these
+// retries sit on top of it and also cover per-call timeouts. Set the
+// classifier with WithRetryIf and the delays with WithBackoff. Retry and
+// timeout options apply to New; NewRefreshable already retries on its next
+// refresh.
+func WithRetries(n int) Option {
+	return func(o *options) {
+		o.retry.Attempts = n + 1
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the cap it doubles
+// up to. Each delay is jittered down by up to half. Defaults to 100ms and
+// 5s.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		o.retry.Initial, o.retry.Max = initial, max
+	}
+}
+
+// WithRetryIf replaces the classifier WithRetries uses to decide whether
+// an error is worth retrying.
+func WithRetryIf(fn func(error) bool) Option {
+	return func(o *options) {
+		o.retry.RetryIf = fn
+	}
+}
+
+// WithCallTimeout bounds each UnwrapKey call New makes, so one hung
+// request is abandoned and, with WithRetries, retried.
+func WithCallTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.retry.CallTimeout = d
+	}
+}
+
+// WithConstructionTimeout bounds the whole of New, including every
+// unwrap, retry, and backoff. When it expires New fails with an error
+// wrapping context.DeadlineExceeded.
+func WithConstructionTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithManagedHSM tunes retries for keys in an Azure Key Vault Managed HSM
+// pool, which throttles per pool with HTTP 429 and whose HSM-backed
+// operations are slower than a vault's: 4 retries with backoff from 1s up
+// to 30s, and 30s per call. Options after it override these values.
+func WithManagedHSM() Option {
+	return func(o *options) {
+		o.retry.Attempts = 5
+		o.retry.Initial, o.retry.Max = time.Second, 30*time.Second
+		o.retry.CallTimeout = 30 * time.Second
+	}
+}
+
+// IsRetryableError is the default WithRetries classifier.
+func IsRetryableError(err error) bool {
+	if code, ok := statusCode(err); ok && retryableStatus[code] {
+		return true
+	}
+	return crypto.IsRetryable(err)
+}
+
+// statusCode returns the HTTP status of the first error in err's chain
+// that reports one, either with a StatusCode method or, like
+// *azcore.ResponseError, an int StatusCode field. Matching by shape keeps
+// this package free of the Azure SDK.
+func statusCode(err error) (int, bool) {
+	for err != nil {
+		if s, ok := err.(interface{ StatusCode() int }); ok {
+			return s.StatusCode(), true
+		}
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() == reflect.Struct {
+			if f := v.FieldByName("StatusCode"); f.IsValid() && f.CanInt() {
+				return int(f.Int()), true
+			}
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				if code, ok := statusCode(e); ok {
+					return code, true
+				}
+			}
+			return 0, false
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// do calls fn as the policy allows, classifying errors with
+// IsRetryableError unless WithRetryIf replaced it.
+func (p retryPolicy) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.RetryIf == nil {
+		p.RetryIf = IsRetryableError
+	}
+	return kmsring.RetryPolicy(p).Do(ctx, fn)
+}
@@ -0,0 +1,134 @@
+package azurekv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// responseError has the shape of *azcore.ResponseError.
+type responseError struct {
+	ErrorCode  string
+	StatusCode int
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("RESPONSE %d: %s", e.StatusCode, e.ErrorCode)
+}
+
+// flakyClient fails the first failures calls with err, then delegates.
+type flakyClient struct {
+	mockClient
+	failures int32
+	err      error
+	hang     bool // block until the context is done instead of failing
+	calls    atomic.Int32
+}
+
+func (c *flakyClient) UnwrapKey(ctx context.Context, keyName, keyVersion, algorithm string, ciphertext []byte) ([]byte, error) {
+	if c.calls.Add(1) <= c.failures {
+		if c.hang {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, c.err
+	}
+	return c.mockClient.UnwrapKey(ctx, keyName, keyVersion, algorithm, ciphertext)
+}
+
+func TestNew_RetriesThrottling(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"wrap-1": makeKey(1)}},
+		failures:   2,
+		err:        fmt.Errorf("unwrap: %w", &responseError{ErrorCode: "Throttled", StatusCode: 429}),
+	}
+	ring, err := New(context.Background(), client,
+		WithWrappedKey([]byte("wrap-1"), "key-1", "my-key", "v1"),
+		WithRetries(3), WithBackoff(time.Millisecond, 2*time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+	if client.calls.Load() != 3 {
+		t.Errorf("UnwrapKey calls = %d, want 3", client.calls.Load())
+	}
+}
+
+func TestNew_DoesNotRetryPermanentErrors(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"wrap-1": makeKey(1)}},
+		failures:   1,
+		err:        &responseError{ErrorCode: "Forbidden", StatusCode: 403},
+	}
+	_, err := New(context.Background(), client,
+		WithWrappedKey([]byte("wrap-1"), "key-1", "my-key", "v1"),
+		WithRetries(3), WithBackoff(time.Millisecond, time.Millisecond))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if client.calls.Load() != 1 {
+		t.Errorf("UnwrapKey calls = %d, want 1", client.calls.Load())
+	}
+	if !strings.Contains(err.Error(), "my-key/v1") {
+		t.Errorf("error %q does not name the Key Vault key", err)
+	}
+}
+
+func TestNew_CallTimeout(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"wrap-1": makeKey(1)}},
+		failures:   1,
+		hang:       true,
+	}
+	ring, err := New(context.Background(), client,
+		WithWrappedKey([]byte("wrap-1"), "key-1", "my-key", "v1"),
+		WithRetries(1), WithCallTimeout(10*time.Millisecond), WithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+}
+
+func TestNew_ConstructionTimeout(t *testing.T) {
+	client := &flakyClient{failures: 1 << 30, hang: true}
+	_, err := New(context.Background(), client,
+		WithWrappedKey([]byte("wrap-1"), "key-1", "my-key", "v1"),
+		WithConstructionTimeout(10*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWithManagedHSM(t *testing.T) {
+	var o options
+	WithManagedHSM()(&o)
+	WithCallTimeout(time.Minute)(&o)
+	if o.retry.Attempts != 5 || o.retry.Initial != time.Second || o.retry.CallTimeout != time.Minute {
+		t.Errorf("retry = %+v", o.retry)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&responseError{StatusCode: 429}, true},
+		{&responseError{StatusCode: 503}, true},
+		{fmt.Errorf("wrapped: %w", &responseError{StatusCode: 500}), true},
+		{errors.Join(errors.New("a"), &responseError{StatusCode: 504}), true},
+		{&responseError{StatusCode: 403}, false},
+		{&responseError{StatusCode: 404}, false},
+		{context.DeadlineExceeded, true},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableError(tt.err); got != tt.want {
+			t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
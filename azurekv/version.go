@@ -0,0 +1,154 @@
+package azurekv
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// ResolvedKey records the Key Vault key version that unwrapped a key in
+// the ring.
+type ResolvedKey struct {
+	// ID is the key's ID in the config-crypto ring.
+	ID string
+
+	// KeyName is the Key Vault key the key was unwrapped with.
+	KeyName string
+
+	// KeyVersion is the version that unwrapped the key: the one given to
+	// WithWrappedKey, the key's current version if none was given, or
+	// another enabled version if WithVersionFallback had to try it.
+	KeyVersion string
+
+	// Fallback reports that KeyVersion was found by WithVersionFallback
+	// after the configured or current version failed.
+	Fallback bool
+}
+
+// WithVersionFallback makes New and NewRefreshable retry a failed unwrap
+// against the key's other enabled versions, current version first, and
+// keep the first that succeeds. Use it when wrapped keys may be recorded
+// against a version other than the one that wrapped them, for example
+// because the version was omitted and the key has rotated since, or the
+// recorded version was disabled by a rotation policy after the key was
+// rewrapped. It costs a ListKeyVersions call and one UnwrapKey per
+// version tried, only for keys whose unwrap failed. The client must
+// implement ListingClient.
+func WithVersionFallback() Option {
+	return func(o *options) {
+		o.fallback = true
+	}
+}
+
+// WithResolvedKeysHandler sets a callback that receives the Key Vault key
+// version behind every unwrapped key, one entry per key in option order,
+// once New has unwrapped them all. Use it to log or expose exactly which
+// versions must stay enabled.
+func WithResolvedKeysHandler(fn func([]ResolvedKey)) Option {
+	return func(o *options) {
+		o.onResolved = fn
+	}
+}
+
+// listingClient returns client as a ListingClient, or nil if it is not
+// one, and marks every key for WithVersionFallback if it is set, which
+// requires a ListingClient.
+func (o *options) listingClient(client Client) (ListingClient, error) {
+	lc, ok := client.(ListingClient)
+	if o.fallback && !ok {
+		return nil, fmt.Errorf("azurekv: WithVersionFallback requires a client implementing ListingClient")
+	}
+	for i := range o.wrappedKeys {
+		o.wrappedKeys[i].fallback = o.fallback
+	}
+	return lc, nil
+}
+
+// resolve sets the version of every key added without one to its Key
+// Vault key's current version. It is a no-op without a ListingClient, in
+// which case Key Vault unwraps with the current version without it being
+// recorded. Each distinct key name is listed once.
+func (o *options) resolve(ctx context.Context, lc ListingClient) error {
+	if lc == nil {
+		return nil
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, wk := range o.wrappedKeys {
+		if wk.keyVersion == "" && !seen[wk.keyName] {
+			seen[wk.keyName] = true
+			names = append(names, wk.keyName)
+		}
+	}
+
+	var mu sync.Mutex
+	current := make(map[string]string, len(names))
+	err := kmsring.ForEach(len(names), func(i int) error {
+		var infos []KeyVersionInfo
+		err := o.retry.do(ctx, func(ctx context.Context) (err error) {
+			infos, err = lc.ListKeyVersions(ctx, names[i])
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("azurekv: list versions of %q: %w", names[i], err)
+		}
+		for _, info := range infos {
+			if info.IsCurrent {
+				mu.Lock()
+				current[names[i]] = info.KeyVersion
+				mu.Unlock()
+				return nil
+			}
+		}
+		return fmt.Errorf("azurekv: key %q has no enabled current version", names[i])
+	})
+	if err != nil {
+		return err
+	}
+	for i := range o.wrappedKeys {
+		if v, ok := current[o.wrappedKeys[i].keyName]; ok && o.wrappedKeys[i].keyVersion == "" {
+			o.wrappedKeys[i].keyVersion = v
+		}
+	}
+	return nil
+}
+
+// unwrap unwraps wk with its version and, if that fails and
+// WithVersionFallback applies to it, with each other enabled version of
+// its key. It returns the version that succeeded. A failure is reported
+// with the original error.
+func (p retryPolicy) unwrap(ctx context.Context, client Client, lc ListingClient, wk wrappedKeyEntry) ([]byte, ResolvedKey, error) {
+	rk := ResolvedKey{ID: wk.id, KeyName: wk.keyName, KeyVersion: wk.keyVersion}
+	try := func(version string) ([]byte, error) {
+		var pt []byte
+		err := p.do(ctx, func(ctx context.Context) (err error) {
+			pt, err = client.UnwrapKey(ctx, wk.keyName, version, wk.algorithm, wk.ciphertext)
+			return err
+		})
+		return pt, err
+	}
+	pt, err := try(wk.keyVersion)
+	if err == nil || !wk.fallback || lc == nil || ctx.Err() != nil {
+		return pt, rk, err
+	}
+
+	infos, lerr := lc.ListKeyVersions(ctx, wk.keyName)
+	if lerr != nil {
+		return nil, rk, fmt.Errorf("%w (fallback: list versions: %v)", err, lerr)
+	}
+	// Current version first, then the rest in the order Key Vault lists them.
+	for pass := range 2 {
+		for _, info := range infos {
+			if info.KeyVersion == wk.keyVersion || info.IsCurrent != (pass == 0) {
+				continue
+			}
+			if pt, ferr := try(info.KeyVersion); ferr == nil {
+				rk.KeyVersion, rk.Fallback = info.KeyVersion, true
+				return pt, rk, nil
+			}
+		}
+	}
+	return nil, rk, err
+}
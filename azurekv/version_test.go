@@ -0,0 +1,105 @@
+package azurekv
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// listingVault is a remoteVault that lists the versions of its keys.
+type listingVault struct {
+	remoteVault
+	lists int
+}
+
+func (m *listingVault) ListKeyVersions(_ context.Context, keyName string) ([]KeyVersionInfo, error) {
+	m.lists++
+	var infos []KeyVersionInfo
+	for ref := range m.versions {
+		if name, version, _ := strings.Cut(ref, "/"); name == keyName {
+			infos = append(infos, KeyVersionInfo{KeyVersion: version, IsCurrent: m.current[name] == version})
+		}
+	}
+	slices.SortFunc(infos, func(a, b KeyVersionInfo) int { return strings.Compare(a.KeyVersion, b.KeyVersion) })
+	return infos, nil
+}
+
+var _ ListingClient = (*listingVault)(nil)
+
+func newListingVault() *listingVault {
+	return &listingVault{remoteVault: remoteVault{
+		current:  map[string]string{"config-kek": "v1"},
+		versions: map[string]byte{"config-kek/v1": 0x11, "config-kek/v2": 0x22, "config-kek/v3": 0x33},
+	}}
+}
+
+func wrapWith(t *testing.T, kv *listingVault, version string, key []byte) []byte {
+	t.Helper()
+	ct, _, err := kv.WrapKey(context.Background(), "config-kek", version, AlgorithmRSAOAEP256, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func TestNew_ResolvesOmittedVersion(t *testing.T) {
+	kv := newListingVault()
+	kv.current["config-kek"] = "v2"
+	blob := wrapWith(t, kv, "v2", makeKey(1))
+	pinned := wrapWith(t, kv, "v1", makeKey(2))
+
+	var got []ResolvedKey
+	ring, err := New(context.Background(), kv,
+		WithWrappedKey(blob, "key-2", "config-kek", ""),
+		WithWrappedKey(pinned, "key-1", "config-kek", "v1"),
+		WithResolvedKeysHandler(func(keys []ResolvedKey) { got = keys }))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+	want := []ResolvedKey{
+		{ID: "key-2", KeyName: "config-kek", KeyVersion: "v2"},
+		{ID: "key-1", KeyName: "config-kek", KeyVersion: "v1"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("resolved = %+v, want %+v", got, want)
+	}
+	if kv.lists != 1 {
+		t.Errorf("ListKeyVersions calls = %d, want 1", kv.lists)
+	}
+}
+
+func TestNew_VersionFallback(t *testing.T) {
+	kv := newListingVault()
+	blob := wrapWith(t, kv, "v2", makeKey(1))
+	kv.current["config-kek"] = "v3" // rotated since the key was wrapped
+
+	if _, err := New(context.Background(), kv, WithWrappedKey(blob, "key-1", "config-kek", "")); err == nil {
+		t.Fatal("expected error without WithVersionFallback")
+	}
+
+	var got []ResolvedKey
+	ring, err := New(context.Background(), kv,
+		WithWrappedKey(blob, "key-1", "config-kek", ""),
+		WithVersionFallback(),
+		WithResolvedKeysHandler(func(keys []ResolvedKey) { got = keys }))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+	want := []ResolvedKey{{ID: "key-1", KeyName: "config-kek", KeyVersion: "v2", Fallback: true}}
+	if !slices.Equal(got, want) {
+		t.Errorf("resolved = %+v, want %+v", got, want)
+	}
+}
+
+func TestNew_VersionFallbackNeedsListingClient(t *testing.T) {
+	client := &mockClient{keys: map[string][]byte{"wrap-1": makeKey(1)}}
+	_, err := New(context.Background(), client,
+		WithWrappedKey([]byte("wrap-1"), "key-1", "my-key", "v1"),
+		WithVersionFallback())
+	if err == nil || !strings.Contains(err.Error(), "ListingClient") {
+		t.Errorf("expected ListingClient error, got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+// Package azuresecret provides a crypto.Provider whose keys are Azure Key
+// Vault secrets, for teams that store raw data keys as secrets rather than
+// wrapping them with a Key Vault key (see package azurekv for that).
+//
+// Secrets are read at construction time through a Client. Wire up the Azure
+// SDK with a one-method wrapper:
+//
+//	type mySecretClient struct{ kv *azsecrets.Client }
+//
+//	func (c *mySecretClient) GetSecret(ctx context.Context, name, version string) (string, string, error) {
+//	    resp, err := c.kv.GetSecret(ctx, name, version, nil)
+//	    if err != nil { return "", "", err }
+//	    return *resp.Value, resp.ID.Version(), nil
+//	}
+//
+//	cred, err := azidentity.NewDefaultAzureCredential(nil) // handle error
+//	kv, err := azsecrets.NewClient("https://my-vault.vault.azure.net/", cred, nil) // handle error
+//	provider, err := azuresecret.New(ctx, &mySecretClient{kv},
+//	    azuresecret.WithSecret("config-kek", ""),
+//	    azuresecret.WithRawBase64(),
+//	)
+package azuresecret
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// Client reads Key Vault secrets.
+type Client interface {
+	// GetSecret returns the value of version of the secret name, or of its
+	// latest version when version is empty, together with the version ID
+	// that was read. New calls GetSecret concurrently when several keys are
+	// configured.
+	GetSecret(ctx context.Context, name, version string) (value, readVersion string, err error)
+}
+
+// VersionLister is a Client that can also list a secret's versions, as
+// WithSecretHistory requires:
+//
+//	func (c *mySecretClient) ListSecretVersions(ctx context.Context, name string) ([]azuresecret.SecretVersion, error) {
+//	    var out []azuresecret.SecretVersion
+//	    pager := c.kv.NewListSecretPropertiesVersionsPager(name, nil)
+//	    for pager.More() {
+//	        page, err := pager.NextPage(ctx)
+//	        if err != nil { return nil, err }
+//	        for _, p := range page.Value {
+//	            out = append(out, azuresecret.SecretVersion{
+//	                Version: p.ID.Version(), Enabled: *p.Attributes.Enabled, Created: *p.Attributes.Created,
+//	            })
+//	        }
+//	    }
+//	    return out, nil
+//	}
+type VersionLister interface {
+	Client
+
+	// ListSecretVersions returns every version of the secret name, in any
+	// order.
+	ListSecretVersions(ctx context.Context, name string) ([]SecretVersion, error)
+}
+
+// SecretVersion describes one version of a secret.
+type SecretVersion struct {
+	Version string
+	Enabled bool
+	Created time.Time
+}
+
+// Option configures the Key Vault secrets provider.
+type Option func(*options)
+
+type options struct {
+	secrets     []secret
+	keyIDFormat func(name, version string) string
+	rawBase64   bool
+}
+
+type secret struct {
+	name    string
+	version string // empty = latest
+	history int    // enabled versions to load, newest first; 0 = one
+	id      string
+}
+
+// WithSecret registers the latest version of the secret name. The id
+// identifies this key in the config-crypto system; an empty id is derived
+// from the name and the version read (see WithKeyIDFormat), so values
+// encrypted before the secret gets a new version still name the key that
+// encrypted them.
+//
+// The first secret option sets the current key used for new encryptions.
+// Subsequent options register additional keys for decryption during key
+// rotation.
+func WithSecret(name, id string) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secret{name: name, id: id})
+	}
+}
+
+// WithSecretVersion registers a specific version of the secret name, so a
+// new version of the secret cannot change the key until the pin is moved.
+// An empty id is derived as for WithSecret.
+func WithSecretVersion(name, version, id string) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secret{name: name, version: version, id: id})
+	}
+}
+
+// WithSecretHistory registers the n most recently created enabled versions
+// of the secret name, newest first, with IDs from WithKeyIDFormat. Rotating
+// is then a matter of setting a new value on the secret: the new version
+// becomes current on the next New, and disabling a version retires it. The
+// Client must implement VersionLister.
+func WithSecretHistory(name string, n int) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secret{name: name, history: n})
+	}
+}
+
+// WithKeyIDFormat sets the function that derives a key ID from a secret name
+// and version ID when no id is given. The mapping must be deterministic and
+// stable across restarts, otherwise old ciphertexts will fail to decrypt.
+// Default: "name/version".
+func WithKeyIDFormat(fn func(name, version string) string) Option {
+	return func(o *options) { o.keyIDFormat = fn }
+}
+
+// WithRawBase64 reads secret values as bare standard base64, such as the
+// output of "openssl rand -base64 32", instead of the prefixed forms
+// crypto.ParseKey accepts.
+func WithRawBase64() Option {
+	return func(o *options) { o.rawBase64 = true }
+}
+
+// formatKeyID is the default WithKeyIDFormat, matching the path of a
+// secret version in its Key Vault ID.
+func formatKeyID(name, version string) string {
+	return name + "/" + version
+}
+
+// read is one GetSecret call New makes.
+type read struct {
+	name    string
+	version string
+	id      string
+}
+
+// New creates a crypto.KeyRingProvider from Key Vault secrets.
+//
+// At least one key must be provided via WithSecret, WithSecretVersion, or
+// WithSecretHistory. The first key is the current key for new encryptions;
+// additional keys support decryption during key rotation.
+//
+// All keys are read during construction and cached. If any read fails, the
+// errors for all failed keys are returned together. The Client is not
+// retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("azuresecret: Client must not be nil")
+	}
+
+	o := options{keyIDFormat: formatKeyID}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.keyIDFormat == nil {
+		return nil, fmt.Errorf("azuresecret: keyIDFormat must not be nil")
+	}
+	for _, s := range o.secrets {
+		if s.name == "" {
+			return nil, fmt.Errorf("azuresecret: secret name must not be empty")
+		}
+		if s.history < 0 {
+			return nil, fmt.Errorf("azuresecret: secret %q: history %d must be positive", s.name, s.history)
+		}
+	}
+
+	reads, err := o.expand(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return kmsring.Build(len(reads), "azuresecret", func(i int) ([]byte, string, error) {
+		r := reads[i]
+		name := cmp.Or(r.id, r.name)
+		value, version, err := client.GetSecret(ctx, r.name, r.version)
+		if err != nil {
+			return nil, name, err
+		}
+		if r.version != "" && version != r.version {
+			return nil, name, fmt.Errorf("read version %q, want %q", version, r.version)
+		}
+		key, err := o.decode(value)
+		if err != nil {
+			return nil, name, fmt.Errorf("secret %s: %w", r.name, err)
+		}
+		if r.id == "" {
+			return key, o.keyIDFormat(r.name, version), nil
+		}
+		return key, r.id, nil
+	})
+}
+
+// decode turns a secret value into key bytes.
+func (o *options) decode(value string) ([]byte, error) {
+	if !o.rawBase64 {
+		return crypto.ParseKey(value)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+	return key, nil
+}
+
+// expand turns the configured secrets into the reads New makes, in option
+// order, listing the versions of every history concurrently.
+func (o *options) expand(ctx context.Context, client Client) ([]read, error) {
+	versions := make([][]string, len(o.secrets))
+	err := kmsring.ForEach(len(o.secrets), func(i int) error {
+		s := o.secrets[i]
+		if s.history <= 1 {
+			return nil
+		}
+		lister, ok := client.(VersionLister)
+		if !ok {
+			return fmt.Errorf("azuresecret: WithSecretHistory(%q) needs a Client that implements VersionLister", s.name)
+		}
+		all, err := lister.ListSecretVersions(ctx, s.name)
+		if err != nil {
+			return fmt.Errorf("azuresecret: list versions of %q: %w", s.name, err)
+		}
+		all = slices.DeleteFunc(all, func(v SecretVersion) bool { return !v.Enabled })
+		if len(all) == 0 {
+			return fmt.Errorf("azuresecret: secret %q has no enabled versions", s.name)
+		}
+		slices.SortFunc(all, func(a, b SecretVersion) int { return b.Created.Compare(a.Created) })
+		for _, v := range all[:min(s.history, len(all))] {
+			versions[i] = append(versions[i], v.Version)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reads []read
+	for i, s := range o.secrets {
+		if s.history <= 1 {
+			reads = append(reads, read{name: s.name, version: s.version, id: s.id})
+			continue
+		}
+		for _, v := range versions[i] {
+			reads = append(reads, read{name: s.name, version: v})
+		}
+	}
+	return reads, nil
+}
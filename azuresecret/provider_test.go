@@ -0,0 +1,174 @@
+package azuresecret
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockVault is an in-memory Key Vault: each secret is a list of versions,
+// oldest first, with IDs "v1", "v2", ...
+type mockVault struct {
+	mu       sync.Mutex
+	secrets  map[string][]string
+	disabled map[string]bool // "name/version"
+}
+
+func (m *mockVault) GetSecret(ctx context.Context, name, version string) (string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values, ok := m.secrets[name]
+	if !ok {
+		return "", "", fmt.Errorf("keyvault: SecretNotFound: %s", name)
+	}
+	if version == "" {
+		version = fmt.Sprintf("v%d", len(values))
+	}
+	var n int
+	if _, err := fmt.Sscanf(version, "v%d", &n); err != nil || n < 1 || n > len(values) {
+		return "", "", fmt.Errorf("keyvault: SecretNotFound: %s/%s", name, version)
+	}
+	return values[n-1], version, nil
+}
+
+func (m *mockVault) ListSecretVersions(_ context.Context, name string) ([]SecretVersion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values, ok := m.secrets[name]
+	if !ok {
+		return nil, fmt.Errorf("keyvault: SecretNotFound: %s", name)
+	}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var out []SecretVersion
+	for i := range values {
+		v := fmt.Sprintf("v%d", i+1)
+		out = append(out, SecretVersion{Version: v, Enabled: !m.disabled[name+"/"+v], Created: base.Add(time.Duration(i) * time.Hour)})
+	}
+	// Key Vault lists versions in no particular order.
+	out[0], out[len(out)-1] = out[len(out)-1], out[0]
+	return out, nil
+}
+
+var _ VersionLister = (*mockVault)(nil)
+
+// getOnly hides ListSecretVersions.
+type getOnly struct{ Client }
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func encodeKey(seed byte) string {
+	return "base64:" + base64.StdEncoding.EncodeToString(makeKey(seed))
+}
+
+func encryptWith(t *testing.T, seed byte, id string) []byte {
+	t.Helper()
+	p, err := crypto.NewProvider(makeKey(seed), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(context.Background(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func TestNew_Secret(t *testing.T) {
+	ctx := context.Background()
+	kv := &mockVault{secrets: map[string][]string{"kek": {encodeKey(1), encodeKey(2)}}}
+	p, err := New(ctx, kv, WithSecret("kek", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "kek/v2" {
+		t.Errorf("CurrentKeyID = %q, want kek/v2", p.CurrentKeyID())
+	}
+	if pt, err := p.Decrypt(ctx, encryptWith(t, 2, "kek/v2")); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_SecretVersionRawBase64(t *testing.T) {
+	ctx := context.Background()
+	raw := base64.StdEncoding.EncodeToString(makeKey(1)) + "\n"
+	kv := &mockVault{secrets: map[string][]string{"kek": {raw, encodeKey(2)}}}
+	p, err := New(ctx, kv, WithSecretVersion("kek", "v1", "kek-1"), WithRawBase64())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if pt, err := p.Decrypt(ctx, encryptWith(t, 1, "kek-1")); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_SecretHistory(t *testing.T) {
+	ctx := context.Background()
+	kv := &mockVault{
+		secrets:  map[string][]string{"kek": {encodeKey(1), encodeKey(2), encodeKey(3), encodeKey(4)}},
+		disabled: map[string]bool{"kek/v3": true},
+	}
+	p, err := New(ctx, kv, WithSecretHistory("kek", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "kek/v4" {
+		t.Errorf("CurrentKeyID = %q, want kek/v4", p.CurrentKeyID())
+	}
+	if _, err := p.Decrypt(ctx, encryptWith(t, 2, "kek/v2")); err != nil {
+		t.Errorf("v2: %v", err)
+	}
+	for _, id := range []string{"kek/v1", "kek/v3"} {
+		if _, err := p.Decrypt(ctx, encryptWith(t, 1, id)); !crypto.IsKeyNotFound(err) {
+			t.Errorf("%s: expected ErrKeyNotFound, got %v", id, err)
+		}
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	ctx := context.Background()
+	kv := &mockVault{secrets: map[string][]string{"kek": {encodeKey(1)}, "plain": {"not a key"}}}
+	tests := []struct {
+		name   string
+		client Client
+		opts   []Option
+		want   string
+	}{
+		{"no keys", kv, nil, "at least one"},
+		{"empty name", kv, []Option{WithSecret("", "k")}, "name must not be empty"},
+		{"missing secret", kv, []Option{WithSecret("missing", "k")}, "SecretNotFound"},
+		{"missing version", kv, []Option{WithSecretVersion("kek", "v9", "k")}, "SecretNotFound"},
+		{"unparsable", kv, []Option{WithSecret("plain", "k")}, "secret plain"},
+		{"history without lister", getOnly{kv}, []Option{WithSecretHistory("kek", 2)}, "VersionLister"},
+		{"nil format", kv, []Option{WithSecret("kek", ""), WithKeyIDFormat(nil)}, "keyIDFormat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(ctx, tt.client, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := New(ctx, nil, WithSecret("kek", "k")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
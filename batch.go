@@ -0,0 +1,91 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// BatchOption configures EncodeAll and DecodeAll.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	workers int
+}
+
+// WithWorkers sets the number of goroutines EncodeAll and DecodeAll use.
+// Values below 1 are ignored. The default is runtime.GOMAXPROCS(0).
+func WithWorkers(n int) BatchOption {
+	return func(o *batchOptions) {
+		if n > 0 {
+			o.workers = n
+		}
+	}
+}
+
+// EncodeAll encodes values concurrently. The returned slices are the same
+// length as values: out[i] holds the ciphertext for values[i] and errs[i]
+// its error, if any. Use errors.Join(errs...) to check for any failure.
+//
+// Items not yet started when ctx is cancelled fail with ctx.Err().
+func (c *Codec) EncodeAll(ctx context.Context, values []any, opts ...BatchOption) ([][]byte, []error) {
+	out := make([][]byte, len(values))
+	errs := runBatch(ctx, len(values), opts, func(i int) error {
+		data, err := c.Encode(ctx, values[i])
+		out[i] = data
+		return err
+	})
+	return out, errs
+}
+
+// DecodeAll decodes data[i] into targets[i] concurrently. The returned
+// slice is the same length as data and errs[i] holds the error for item i,
+// if any. Each target must be a pointer, as for Decode. If data and targets
+// differ in length, every item fails without being decoded.
+//
+// Items not yet started when ctx is cancelled fail with ctx.Err().
+func (c *Codec) DecodeAll(ctx context.Context, data [][]byte, targets []any, opts ...BatchOption) []error {
+	if len(data) != len(targets) {
+		err := fmt.Errorf("crypto: DecodeAll got %d ciphertexts but %d targets", len(data), len(targets))
+		errs := make([]error, len(data))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	return runBatch(ctx, len(data), opts, func(i int) error {
+		return c.Decode(ctx, data[i], targets[i])
+	})
+}
+
+// runBatch calls fn for each index in [0, n) on a bounded pool of workers
+// and collects the per-index errors.
+func runBatch(ctx context.Context, n int, opts []BatchOption, fn func(i int) error) []error {
+	o := &batchOptions{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	workers := min(o.workers, n)
+
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = fn(i)
+			}
+		})
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
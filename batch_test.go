@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCodecEncodeAllDecodeAll(t *testing.T) {
+	ctx := context.Background()
+	c := testCodec(t)
+
+	values := make([]any, 100)
+	for i := range values {
+		values[i] = fmt.Sprintf("value-%d", i)
+	}
+	data, errs := c.EncodeAll(ctx, values, WithWorkers(8))
+	if err := errors.Join(errs...); err != nil {
+		t.Fatalf("EncodeAll: %v", err)
+	}
+	if len(data) != len(values) {
+		t.Fatalf("EncodeAll returned %d items, want %d", len(data), len(values))
+	}
+
+	got := make([]string, len(data))
+	targets := make([]any, len(data))
+	for i := range targets {
+		targets[i] = &got[i]
+	}
+	if err := errors.Join(c.DecodeAll(ctx, data, targets, WithWorkers(8))...); err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	for i, v := range got {
+		if v != values[i] {
+			t.Errorf("item %d = %q, want %q", i, v, values[i])
+		}
+	}
+}
+
+func TestCodecDecodeAllPerItemErrors(t *testing.T) {
+	ctx := context.Background()
+	c := testCodec(t)
+
+	good, err := c.Encode(ctx, "ok")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var a, b, d string
+	errs := c.DecodeAll(ctx, [][]byte{good, []byte("garbage"), good}, []any{&a, &b, &d})
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("good items failed: %v, %v", errs[0], errs[2])
+	}
+	if !IsInvalidFormat(errs[1]) {
+		t.Errorf("bad item: got %v, want ErrInvalidFormat", errs[1])
+	}
+	if a != "ok" || d != "ok" {
+		t.Errorf("decoded values = %q, %q", a, d)
+	}
+}
+
+func TestCodecDecodeAllLengthMismatch(t *testing.T) {
+	c := testCodec(t)
+	var s string
+	errs := c.DecodeAll(context.Background(), [][]byte{nil, nil}, []any{&s})
+	if len(errs) != 2 || errs[0] == nil || errs[1] == nil {
+		t.Errorf("DecodeAll with mismatched lengths: %v", errs)
+	}
+}
+
+func TestCodecEncodeAllCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := testCodec(t)
+	_, errs := c.EncodeAll(ctx, []any{"a", "b", "c"}, WithWorkers(2))
+	for i, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("item %d: got %v, want context.Canceled", i, err)
+		}
+	}
+}
+
+func TestCodecEncodeAllEmpty(t *testing.T) {
+	c := testCodec(t)
+	data, errs := c.EncodeAll(context.Background(), nil)
+	if len(data) != 0 || len(errs) != 0 {
+		t.Errorf("EncodeAll(nil) = %v, %v", data, errs)
+	}
+}
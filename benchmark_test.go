@@ -3,6 +3,7 @@ package crypto
 import (
 	"context"
 	"testing"
+	"time"
 
 	jsoncodec "github.com/rbaliyan/config/codec/json"
 )
@@ -138,6 +139,28 @@ func BenchmarkDecode1MB(b *testing.B) {
 	}
 }
 
+func BenchmarkDecryptTo1MB(b *testing.B) {
+	ctx := context.Background()
+	p, err := NewProvider(makeKey(32), "bench-key")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = p.Close() })
+	data, err := p.Encrypt(ctx, make([]byte, 1<<20))
+	if err != nil {
+		b.Fatal(err)
+	}
+	buf := make([]byte, 0, 1<<20)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := p.(BufferDecrypter).DecryptTo(ctx, buf[:0], data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkEncodeString(b *testing.B) {
 	ctx := context.Background()
 	c := benchmarkCodec(b)
@@ -168,3 +191,69 @@ func BenchmarkDecodeString(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkDecodeInto1KB(b *testing.B) {
+	ctx := context.Background()
+	c := benchmarkCodec(b)
+	payload := make([]byte, 1024)
+	for i := range payload {
+		payload[i] = byte(i % 256)
+	}
+	data, err := c.Encode(ctx, payload)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf []byte
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for b.Loop() {
+		var got []byte
+		if buf, err = c.DecodeInto(ctx, data, &got, buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeStringDEKReuse(b *testing.B) {
+	ctx := context.Background()
+	p, err := NewProvider(makeKey(32), "bench-key", WithDEKReuse(1000, time.Minute))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = p.Close() })
+	c, err := NewCodec(jsoncodec.New(), p)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := c.Encode(ctx, "sk-secret-api-key"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecryptParallel(b *testing.B) {
+	ctx := context.Background()
+	p, err := NewProvider(makeKey(32), "bench-key")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = p.Close() })
+	data, err := p.Encrypt(ctx, []byte("sk-secret-api-key"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := p.Decrypt(ctx, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
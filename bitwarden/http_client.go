@@ -0,0 +1,421 @@
+package bitwarden
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bitwarden cloud (US) endpoints, used unless WithServerURL or
+// BWS_SERVER_URL names another server.
+const (
+	DefaultAPIURL      = "https://api.bitwarden.com"
+	DefaultIdentityURL = "https://identity.bitwarden.com"
+)
+
+// maxResponse bounds the Bitwarden responses read into memory.
+const maxResponse = 4 << 20
+
+// tokenSkew renews the access token this long before it expires.
+const tokenSkew = time.Minute
+
+// StatusError is a Bitwarden API or identity server error response.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPClient reads secrets from Bitwarden Secrets Manager's REST API with
+// net/http, logging in with a machine account access token. Secrets
+// Manager encrypts secrets client-side, so HTTPClient also decrypts the
+// organization key delivered at login and, with it, the secrets' names and
+// values. It implements Client and is safe for concurrent use.
+type HTTPClient struct {
+	apiURL      string
+	identityURL string
+	hc          *http.Client
+
+	clientID     string
+	clientSecret string
+	tokenKey     []byte // encryption and MAC keys derived from the access token
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+	orgID       string
+	orgKey      []byte
+}
+
+// Compile-time interface check.
+var _ Client = (*HTTPClient)(nil)
+
+// HTTPOption configures an HTTPClient.
+type HTTPOption func(*HTTPClient)
+
+// WithServerURL uses the Bitwarden server at base, such as
+// "https://vault.bitwarden.eu" or a self-hosted server, whose API and
+// identity services are served under /api and /identity.
+func WithServerURL(base string) HTTPOption {
+	return func(c *HTTPClient) {
+		base = strings.TrimSuffix(base, "/")
+		c.apiURL, c.identityURL = base+"/api", base+"/identity"
+	}
+}
+
+// WithHTTPClient sets the http.Client used for Bitwarden requests. Defaults
+// to http.DefaultClient.
+func WithHTTPClient(hc *http.Client) HTTPOption {
+	return func(c *HTTPClient) {
+		c.hc = hc
+	}
+}
+
+// NewHTTPClient returns an HTTPClient for the machine account whose access
+// token, "0.<id>.<secret>:<key>", is accessToken. An empty accessToken uses
+// BWS_ACCESS_TOKEN, and without WithServerURL, BWS_SERVER_URL is honoured,
+// as by the bws CLI. The client logs in on first use and again when its
+// session expires.
+func NewHTTPClient(accessToken string, opts ...HTTPOption) (*HTTPClient, error) {
+	if accessToken == "" {
+		accessToken = os.Getenv("BWS_ACCESS_TOKEN")
+	}
+	clientID, clientSecret, key, err := parseAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	c := &HTTPClient{
+		apiURL:       DefaultAPIURL,
+		identityURL:  DefaultIdentityURL,
+		hc:           http.DefaultClient,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		tokenKey:     deriveTokenKey(key),
+	}
+	if base := os.Getenv("BWS_SERVER_URL"); base != "" {
+		WithServerURL(base)(c)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	for _, u := range []string{c.apiURL, c.identityURL} {
+		if p, err := url.Parse(u); err != nil || (p.Scheme != "https" && p.Scheme != "http") || p.Host == "" {
+			return nil, fmt.Errorf("bitwarden: invalid server URL %q", u)
+		}
+	}
+	return c, nil
+}
+
+// parseAccessToken splits a machine account access token into its client
+// ID, client secret, and 16-byte encryption key.
+func parseAccessToken(token string) (clientID, clientSecret string, key []byte, err error) {
+	invalid := errors.New("bitwarden: invalid machine account access token")
+	version, rest, ok := strings.Cut(token, ".")
+	if !ok || version != "0" {
+		return "", "", nil, invalid
+	}
+	creds, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", "", nil, invalid
+	}
+	clientID, clientSecret, ok = strings.Cut(creds, ".")
+	if !ok || clientID == "" || clientSecret == "" {
+		return "", "", nil, invalid
+	}
+	key, err = base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 16 {
+		return "", "", nil, invalid
+	}
+	return clientID, clientSecret, key, nil
+}
+
+// deriveTokenKey derives the 64-byte key that decrypts the login payload
+// from an access token's encryption key, as Bitwarden's
+// derive_shareable_key does.
+func deriveTokenKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, []byte("bitwarden-accesstoken"))
+	mac.Write(key)
+	prk := mac.Sum(nil)
+	defer clear(prk)
+	out, err := hkdf.Expand(sha256.New, prk, "sm-access-token", 64)
+	if err != nil {
+		panic(err) // unreachable: 64 bytes is within HKDF-SHA256's limit
+	}
+	return out
+}
+
+// decryptEncString decrypts a type 2 EncString, "2.iv|data|mac"
+// (AES-256-CBC with HMAC-SHA256), with a 64-byte encryption and MAC key.
+func decryptEncString(s string, key []byte) ([]byte, error) {
+	body, ok := strings.CutPrefix(s, "2.")
+	parts := strings.Split(body, "|")
+	if !ok || len(parts) != 3 {
+		return nil, errors.New("unsupported encrypted string")
+	}
+	var raw [3][]byte
+	for i, p := range parts {
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return nil, fmt.Errorf("decode encrypted string: %w", err)
+		}
+		raw[i] = b
+	}
+	iv, data, tag := raw[0], raw[1], raw[2]
+	mac := hmac.New(sha256.New, key[32:])
+	mac.Write(iv)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, errors.New("encrypted string MAC mismatch")
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("malformed encrypted string")
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+	pad := int(data[len(data)-1])
+	if pad == 0 || pad > aes.BlockSize || !bytes.Equal(data[len(data)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		clear(data)
+		return nil, errors.New("malformed encrypted string padding")
+	}
+	return data[:len(data)-pad], nil
+}
+
+// session returns a valid access token, organization ID, and organization
+// key, logging in first if needed.
+func (c *HTTPClient) session(ctx context.Context) (token, orgID string, orgKey []byte, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken == "" || time.Now().After(c.expires.Add(-tokenSkew)) {
+		if err := c.login(ctx); err != nil {
+			return "", "", nil, err
+		}
+	}
+	return c.accessToken, c.orgID, c.orgKey, nil
+}
+
+// invalidate drops token so the next call logs in again.
+func (c *HTTPClient) invalidate(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken == token {
+		c.accessToken = ""
+	}
+}
+
+// login exchanges the client credentials for an access token and decrypts
+// the organization key. c.mu must be held.
+func (c *HTTPClient) login(ctx context.Context) error {
+	form := url.Values{
+		"scope":         {"api.secrets"},
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.clientID},
+		"client_secret": {c.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.identityURL+"/connect/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("bitwarden: login: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var out struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		EncryptedPayload string `json:"encrypted_payload"`
+	}
+	now := time.Now()
+	if err := c.do(req, &out); err != nil {
+		return fmt.Errorf("bitwarden: login: %w", err)
+	}
+	orgID, err := jwtOrganization(out.AccessToken)
+	if err != nil {
+		return fmt.Errorf("bitwarden: login: %w", err)
+	}
+	payload, err := decryptEncString(out.EncryptedPayload, c.tokenKey)
+	if err != nil {
+		return fmt.Errorf("bitwarden: login: decrypt payload: %w", err)
+	}
+	defer clear(payload)
+	var p struct {
+		EncryptionKey string `json:"encryptionKey"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return fmt.Errorf("bitwarden: login: decode payload: %w", err)
+	}
+	orgKey, err := base64.StdEncoding.DecodeString(p.EncryptionKey)
+	if err != nil || len(orgKey) != 64 {
+		return errors.New("bitwarden: login: invalid organization key")
+	}
+	c.accessToken, c.orgID, c.orgKey = out.AccessToken, orgID, orgKey
+	c.expires = now.Add(time.Duration(out.ExpiresIn) * time.Second)
+	return nil
+}
+
+// jwtOrganization reads the organization claim of a machine account
+// access token.
+func jwtOrganization(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("access token is not a JWT")
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("decode access token: %w", err)
+	}
+	var c struct {
+		Organization string `json:"organization"`
+	}
+	if err := json.Unmarshal(claims, &c); err != nil || c.Organization == "" {
+		return "", errors.New("access token has no organization")
+	}
+	return c.Organization, nil
+}
+
+// apiSecret is a secret as the API returns it, with encrypted strings.
+type apiSecret struct {
+	ID           string    `json:"id"`
+	Key          string    `json:"key"`
+	Value        string    `json:"value"`
+	CreationDate time.Time `json:"creationDate"`
+}
+
+// GetSecretsByKey lists the organization's secrets the machine account can
+// read, decrypts their names, and fetches and decrypts every secret named
+// key.
+func (c *HTTPClient) GetSecretsByKey(ctx context.Context, key string) ([]Secret, error) {
+	var list struct {
+		Secrets []apiSecret `json:"secrets"`
+	}
+	if err := c.api(ctx, http.MethodGet, "/organizations/{org}/secrets", nil, &list); err != nil {
+		return nil, err
+	}
+	_, _, orgKey, err := c.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, s := range list.Secrets {
+		name, err := decryptEncString(s.Key, orgKey)
+		if err != nil {
+			return nil, fmt.Errorf("bitwarden: decrypt name of secret %s: %w", s.ID, err)
+		}
+		if string(name) == key {
+			ids = append(ids, s.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var got struct {
+		Data []apiSecret `json:"data"`
+	}
+	if err := c.api(ctx, http.MethodPost, "/secrets/get-by-ids", map[string]any{"ids": ids}, &got); err != nil {
+		return nil, err
+	}
+	out := make([]Secret, 0, len(got.Data))
+	for _, s := range got.Data {
+		value, err := decryptEncString(s.Value, orgKey)
+		if err != nil {
+			return nil, fmt.Errorf("bitwarden: decrypt secret %s: %w", s.ID, err)
+		}
+		out = append(out, Secret{ID: s.ID, Key: key, Value: string(value), Created: s.CreationDate})
+		clear(value)
+	}
+	return out, nil
+}
+
+// api sends an authenticated API request, with {org} in path replaced by
+// the organization ID. A 401 logs in again and retries once.
+func (c *HTTPClient) api(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("bitwarden: encode request: %w", err)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		token, orgID, _, err := c.session(ctx)
+		if err != nil {
+			return err
+		}
+		p := strings.ReplaceAll(path, "{org}", url.PathEscape(orgID))
+		req, err := http.NewRequestWithContext(ctx, method, c.apiURL+p, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("bitwarden: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		err = c.do(req, out)
+		var se *StatusError
+		if attempt == 0 && errors.As(err, &se) && se.StatusCode == http.StatusUnauthorized {
+			c.invalidate(token)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("bitwarden: %s %s: %w", method, p, err)
+		}
+		return nil
+	}
+}
+
+// do sends req and decodes the JSON response into out. The response body
+// is cleared after use.
+func (c *HTTPClient) do(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	defer clear(body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		se := &StatusError{StatusCode: resp.StatusCode}
+		var e struct {
+			Message          string `json:"message"`
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &e) == nil {
+			se.Message = e.Message
+			if se.Message == "" {
+				se.Message = strings.TrimSuffix(e.Error+": "+e.ErrorDescription, ": ")
+			}
+		}
+		return se
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package bitwarden
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// encryptEncString is the inverse of decryptEncString.
+func encryptEncString(t *testing.T, plaintext, key []byte) string {
+	t.Helper()
+	pad := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := append([]byte(nil), plaintext...)
+	for range pad {
+		data = append(data, byte(pad))
+	}
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	mac := hmac.New(sha256.New, key[32:])
+	mac.Write(iv)
+	mac.Write(data)
+	enc := base64.StdEncoding.EncodeToString
+	return "2." + enc(iv) + "|" + enc(data) + "|" + enc(mac.Sum(nil))
+}
+
+// fakeBitwarden serves the identity and API endpoints HTTPClient uses for
+// one organization.
+type fakeBitwarden struct {
+	t      *testing.T
+	token  string // machine account access token
+	orgKey []byte
+
+	mu      sync.Mutex
+	secrets []apiSecret // plaintext names and values
+	logins  int
+	revoked bool
+}
+
+func newFakeBitwarden(t *testing.T) (*fakeBitwarden, *httptest.Server) {
+	t.Helper()
+	tokenKey := make([]byte, 16)
+	orgKey := make([]byte, 64)
+	_, _ = rand.Read(tokenKey)
+	_, _ = rand.Read(orgKey)
+	f := &fakeBitwarden{
+		t:      t,
+		token:  "0.client-id.client-secret:" + base64.StdEncoding.EncodeToString(tokenKey),
+		orgKey: orgKey,
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeBitwarden) add(id, key, value string, created time.Time) {
+	f.secrets = append(f.secrets, apiSecret{ID: id, Key: key, Value: value, CreationDate: created})
+}
+
+func (f *fakeBitwarden) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reply := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	encrypt := func(s string) string { return encryptEncString(f.t, []byte(s), f.orgKey) }
+
+	if r.URL.Path == "/identity/connect/token" {
+		_ = r.ParseForm()
+		if r.PostForm.Get("client_id") != "client-id" || r.PostForm.Get("client_secret") != "client-secret" || r.PostForm.Get("scope") != "api.secrets" {
+			reply(http.StatusBadRequest, map[string]any{"error": "invalid_client"})
+			return
+		}
+		f.logins++
+		f.revoked = false
+		_, _, key, _ := parseAccessToken(f.token)
+		payload, _ := json.Marshal(map[string]string{"encryptionKey": base64.StdEncoding.EncodeToString(f.orgKey)})
+		claims := base64.RawURLEncoding.EncodeToString([]byte(`{"organization":"org-1"}`))
+		reply(http.StatusOK, map[string]any{
+			"access_token":      "hdr." + claims + ".sig",
+			"expires_in":        3600,
+			"token_type":        "Bearer",
+			"encrypted_payload": encryptEncString(f.t, payload, deriveTokenKey(key)),
+		})
+		return
+	}
+	if f.revoked || r.Header.Get("Authorization") == "" {
+		reply(http.StatusUnauthorized, map[string]any{"message": "Unauthorized"})
+		return
+	}
+	switch r.URL.Path {
+	case "/api/organizations/org-1/secrets":
+		var list []map[string]any
+		for _, s := range f.secrets {
+			list = append(list, map[string]any{"id": s.ID, "key": encrypt(s.Key), "creationDate": s.CreationDate})
+		}
+		reply(http.StatusOK, map[string]any{"secrets": list})
+	case "/api/secrets/get-by-ids":
+		var in struct {
+			IDs []string `json:"ids"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		var data []map[string]any
+		for _, id := range in.IDs {
+			for _, s := range f.secrets {
+				if s.ID == id {
+					data = append(data, map[string]any{"id": s.ID, "key": encrypt(s.Key), "value": encrypt(s.Value), "creationDate": s.CreationDate})
+				}
+			}
+		}
+		reply(http.StatusOK, map[string]any{"data": data})
+	default:
+		reply(http.StatusNotFound, map[string]any{"message": "Resource not found."})
+	}
+}
+
+func TestHTTPClient_New(t *testing.T) {
+	f, srv := newFakeBitwarden(t)
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	f.add("s1", "config-kek", encodeKey(1), base)
+	f.add("s2", "unrelated", encodeKey(9), base.Add(time.Hour))
+	f.add("s3", "config-kek", encodeKey(2), base.Add(2*time.Hour))
+	ctx := context.Background()
+
+	c, err := NewHTTPClient(f.token, WithServerURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(ctx, c, WithSecretHistory("config-kek", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "config-kek/s3" {
+		t.Errorf("CurrentKeyID = %q, want config-kek/s3", p.CurrentKeyID())
+	}
+	if pt, err := p.Decrypt(ctx, encryptWith(t, 1, "config-kek/s1")); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt with the older secret = %q, %v", pt, err)
+	}
+
+	// A revoked session logs in again.
+	f.mu.Lock()
+	f.revoked = true
+	f.mu.Unlock()
+	if secrets, err := c.GetSecretsByKey(ctx, "unrelated"); err != nil || len(secrets) != 1 || secrets[0].Value != encodeKey(9) {
+		t.Fatalf("GetSecretsByKey = %v, %v", secrets, err)
+	}
+	if f.logins != 2 {
+		t.Errorf("logins = %d, want 2", f.logins)
+	}
+	if secrets, err := c.GetSecretsByKey(ctx, "missing"); err != nil || secrets != nil {
+		t.Errorf("GetSecretsByKey(missing) = %v, %v; want none", secrets, err)
+	}
+}
+
+func TestHTTPClient_Errors(t *testing.T) {
+	f, srv := newFakeBitwarden(t)
+	t.Setenv("BWS_ACCESS_TOKEN", "")
+	t.Setenv("BWS_SERVER_URL", "")
+	for _, tok := range []string{"", "1.a.b:AAAAAAAAAAAAAAAAAAAAAA==", "0.a:AAAAAAAAAAAAAAAAAAAAAA==", "0.a.b:c2hvcnQ="} {
+		if _, err := NewHTTPClient(tok); err == nil {
+			t.Errorf("NewHTTPClient(%q): expected error", tok)
+		}
+	}
+	if _, err := NewHTTPClient(f.token, WithServerURL("vault.example.com")); err == nil {
+		t.Error("expected error for a server URL without a scheme")
+	}
+
+	// The right key but the wrong credentials.
+	_, _, key, _ := parseAccessToken(f.token)
+	c, err := NewHTTPClient("0.client-id.wrong:"+base64.StdEncoding.EncodeToString(key), WithServerURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetSecretsByKey(context.Background(), "config-kek")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusBadRequest || se.Message != "invalid_client" {
+		t.Errorf("err = %v, want a 400 invalid_client StatusError", err)
+	}
+
+	// The right credentials but the wrong key cannot read the payload.
+	c, err = NewHTTPClient("0.client-id.client-secret:AAAAAAAAAAAAAAAAAAAAAA==", WithServerURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetSecretsByKey(context.Background(), "config-kek"); err == nil {
+		t.Error("expected a payload decryption error")
+	}
+}
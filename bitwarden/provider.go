@@ -0,0 +1,241 @@
+// Package bitwarden provides a crypto.KeyRingProvider whose keys are
+// Bitwarden Secrets Manager secrets, read with a machine account.
+//
+// Secrets are found by their key (name) and read at construction time
+// through a Client. Use NewHTTPClient, which logs in with a machine account
+// access token, or wire up the Bitwarden SDK with a one-method wrapper:
+//
+//	type myBitwarden struct {
+//	    c     sdk.BitwardenClientInterface
+//	    orgID string
+//	}
+//
+//	func (m *myBitwarden) GetSecretsByKey(ctx context.Context, key string) ([]bitwarden.Secret, error) {
+//	    list, err := m.c.Secrets().List(m.orgID)
+//	    if err != nil { return nil, err }
+//	    var ids []string
+//	    for _, s := range list.Data {
+//	        if s.Key == key { ids = append(ids, s.ID) }
+//	    }
+//	    if len(ids) == 0 { return nil, nil }
+//	    got, err := m.c.Secrets().GetByIDS(ids)
+//	    if err != nil { return nil, err }
+//	    var out []bitwarden.Secret
+//	    for _, s := range got.Data {
+//	        created, _ := time.Parse(time.RFC3339, s.CreationDate)
+//	        out = append(out, bitwarden.Secret{ID: s.ID, Key: s.Key, Value: s.Value, Created: created})
+//	    }
+//	    return out, nil
+//	}
+//
+//	provider, err := bitwarden.New(ctx, client, bitwarden.WithSecretHistory("config-kek", 3))
+package bitwarden
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// Client reads Secrets Manager secrets.
+type Client interface {
+	// GetSecretsByKey returns every secret named key that the machine
+	// account can read, with values, in any order. Secret names need not
+	// be unique, which is how rotation keeps older keys. New calls
+	// GetSecretsByKey concurrently for different keys.
+	GetSecretsByKey(ctx context.Context, key string) ([]Secret, error)
+}
+
+// Secret is one Secrets Manager secret.
+type Secret struct {
+	ID      string
+	Key     string
+	Value   string
+	Created time.Time
+}
+
+// Option configures the Bitwarden provider.
+type Option func(*options)
+
+type options struct {
+	secrets     []secretRef
+	keyIDFormat func(key, secretID string) string
+	rawBase64   bool
+}
+
+type secretRef struct {
+	key     string
+	history int // newest secrets to load; 0 = one
+	id      string
+}
+
+// WithSecret registers the newest secret named key. The id identifies this
+// key in the config-crypto system; an empty id is derived from the name and
+// the secret's ID (see WithKeyIDFormat), so values encrypted before a newer
+// secret of the same name was created still name the key that encrypted
+// them.
+//
+// The first secret option sets the current key used for new encryptions.
+// Subsequent options register additional keys for decryption during key
+// rotation.
+func WithSecret(key, id string) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secretRef{key: key, id: id})
+	}
+}
+
+// WithSecretHistory registers the n most recently created secrets named
+// key, newest first, with IDs from WithKeyIDFormat. Secrets Manager keeps
+// no history of a secret's value, so each key version is its own secret:
+// to rotate, create a new secret with the same name. It becomes current on
+// the next New, older ones keep decrypting, and deleting one retires it.
+// Editing a secret's value in place would change the key behind its ID and
+// make existing values undecryptable.
+func WithSecretHistory(key string, n int) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secretRef{key: key, history: n})
+	}
+}
+
+// WithKeyIDFormat sets the function that derives a key ID from a secret name
+// and secret ID when no id is given. The mapping must be deterministic and
+// stable across restarts, otherwise old ciphertexts will fail to decrypt.
+// Default: "key/secretID".
+func WithKeyIDFormat(fn func(key, secretID string) string) Option {
+	return func(o *options) { o.keyIDFormat = fn }
+}
+
+// WithRawBase64 reads secret values as bare standard base64, such as the
+// output of "openssl rand -base64 32", instead of the prefixed forms
+// crypto.ParseKey accepts.
+func WithRawBase64() Option {
+	return func(o *options) { o.rawBase64 = true }
+}
+
+// formatKeyID is the default WithKeyIDFormat.
+func formatKeyID(key, secretID string) string {
+	return key + "/" + secretID
+}
+
+// read is one secret New turns into a key.
+type read struct {
+	secret Secret
+	id     string
+}
+
+// New creates a crypto.KeyRingProvider from Secrets Manager secrets.
+//
+// At least one key must be provided via WithSecret or WithSecretHistory.
+// The first key is the current key for new encryptions; additional keys
+// support decryption during key rotation.
+//
+// All secrets are read during construction and cached. If any read fails,
+// the errors for all failed keys are returned together. The Client is not
+// retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, errors.New("bitwarden: Client must not be nil")
+	}
+
+	o := options{keyIDFormat: formatKeyID}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.keyIDFormat == nil {
+		return nil, errors.New("bitwarden: keyIDFormat must not be nil")
+	}
+	for _, s := range o.secrets {
+		if s.key == "" {
+			return nil, errors.New("bitwarden: secret name must not be empty")
+		}
+		if s.history < 0 {
+			return nil, fmt.Errorf("bitwarden: secret %q: history %d must be positive", s.key, s.history)
+		}
+	}
+
+	reads, err := o.expand(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return kmsring.Build(len(reads), "bitwarden", func(i int) ([]byte, string, error) {
+		r := reads[i]
+		key, err := o.decode(r.secret.Value)
+		if err != nil {
+			return nil, cmp.Or(r.id, r.secret.Key), fmt.Errorf("secret %s: %w", r.secret.ID, err)
+		}
+		if r.id == "" {
+			return key, o.keyIDFormat(r.secret.Key, r.secret.ID), nil
+		}
+		return key, r.id, nil
+	})
+}
+
+// expand fetches the secrets behind every option, each name once and
+// concurrently, and returns the reads in option order.
+func (o *options) expand(ctx context.Context, client Client) ([]read, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range o.secrets {
+		if !seen[s.key] {
+			seen[s.key] = true
+			names = append(names, s.key)
+		}
+	}
+
+	var mu sync.Mutex
+	byName := make(map[string][]Secret, len(names))
+	err := kmsring.ForEach(len(names), func(i int) error {
+		secrets, err := client.GetSecretsByKey(ctx, names[i])
+		if err != nil {
+			return fmt.Errorf("bitwarden: get secrets named %q: %w", names[i], err)
+		}
+		if len(secrets) == 0 {
+			return fmt.Errorf("bitwarden: no secret named %q", names[i])
+		}
+		slices.SortStableFunc(secrets, func(a, b Secret) int { return b.Created.Compare(a.Created) })
+		mu.Lock()
+		byName[names[i]] = secrets
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reads []read
+	for _, s := range o.secrets {
+		secrets := byName[s.key]
+		if s.history <= 1 {
+			reads = append(reads, read{secret: secrets[0], id: s.id})
+			continue
+		}
+		for _, sec := range secrets[:min(s.history, len(secrets))] {
+			reads = append(reads, read{secret: sec})
+		}
+	}
+	return reads, nil
+}
+
+// decode turns a secret value into key bytes.
+func (o *options) decode(value string) ([]byte, error) {
+	if !o.rawBase64 {
+		return crypto.ParseKey(value)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+	return key, nil
+}
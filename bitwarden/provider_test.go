@@ -0,0 +1,168 @@
+package bitwarden
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockSM is an in-memory Secrets Manager: secrets in creation order.
+type mockSM struct {
+	mu      sync.Mutex
+	secrets []Secret
+	calls   map[string]int
+}
+
+func newMockSM() *mockSM {
+	return &mockSM{calls: map[string]int{}}
+}
+
+func (m *mockSM) add(id, key, value string) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.secrets = append(m.secrets, Secret{ID: id, Key: key, Value: value, Created: base.Add(time.Duration(len(m.secrets)) * time.Hour)})
+}
+
+func (m *mockSM) GetSecretsByKey(ctx context.Context, key string) ([]Secret, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[key]++
+	var out []Secret
+	for _, s := range m.secrets {
+		if s.Key == key {
+			out = append(out, s)
+		}
+	}
+	// Secrets Manager returns secrets in no particular order.
+	if len(out) > 1 {
+		out[0], out[len(out)-1] = out[len(out)-1], out[0]
+	}
+	return out, nil
+}
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func encodeKey(seed byte) string {
+	return "base64:" + base64.StdEncoding.EncodeToString(makeKey(seed))
+}
+
+func encryptWith(t *testing.T, seed byte, id string) []byte {
+	t.Helper()
+	p, err := crypto.NewProvider(makeKey(seed), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(context.Background(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func TestNew_SecretHistory(t *testing.T) {
+	ctx := context.Background()
+	m := newMockSM()
+	m.add("s1", "config-kek", encodeKey(1))
+	m.add("s2", "other", encodeKey(9))
+	m.add("s3", "config-kek", encodeKey(2))
+	m.add("s4", "config-kek", encodeKey(3))
+
+	p, err := New(ctx, m, WithSecretHistory("config-kek", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "config-kek/s4" {
+		t.Errorf("CurrentKeyID = %q, want config-kek/s4", p.CurrentKeyID())
+	}
+	if pt, err := p.Decrypt(ctx, encryptWith(t, 2, "config-kek/s3")); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt with the previous key = %q, %v", pt, err)
+	}
+	if _, err := p.Decrypt(ctx, encryptWith(t, 1, "config-kek/s1")); !crypto.IsKeyNotFound(err) {
+		t.Errorf("Decrypt with a key outside the history: err = %v, want key not found", err)
+	}
+}
+
+func TestNew_SecretAndIDs(t *testing.T) {
+	m := newMockSM()
+	m.add("s1", "config-kek", base64.StdEncoding.EncodeToString(makeKey(1)))
+	m.add("s2", "config-kek", base64.StdEncoding.EncodeToString(makeKey(2)))
+	m.add("s3", "legacy-kek", base64.StdEncoding.EncodeToString(makeKey(3)))
+
+	p, err := New(context.Background(), m,
+		WithSecret("config-kek", "kek-2"),
+		WithSecret("legacy-kek", ""),
+		WithSecretHistory("config-kek", 2),
+		WithRawBase64(),
+		WithKeyIDFormat(func(key, secretID string) string { return key + ":" + secretID }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if m.calls["config-kek"] != 1 {
+		t.Errorf("config-kek fetched %d times, want once", m.calls["config-kek"])
+	}
+	if p.CurrentKeyID() != "kek-2" {
+		t.Errorf("CurrentKeyID = %q, want kek-2", p.CurrentKeyID())
+	}
+	for seed, id := range map[byte]string{3: "legacy-kek:s3", 1: "config-kek:s1"} {
+		if pt, err := p.Decrypt(context.Background(), encryptWith(t, seed, id)); err != nil || string(pt) != "secret" {
+			t.Errorf("Decrypt with %s = %q, %v", id, pt, err)
+		}
+	}
+}
+
+// failingSM fails every read.
+type failingSM struct{}
+
+func (failingSM) GetSecretsByKey(context.Context, string) ([]Secret, error) {
+	return nil, errors.New("bws: 403 forbidden")
+}
+
+func TestNew_Errors(t *testing.T) {
+	ctx := context.Background()
+	m := newMockSM()
+	m.add("s1", "config-kek", encodeKey(1))
+	m.add("s2", "bad", "not a key")
+	tests := []struct {
+		name   string
+		client Client
+		opts   []Option
+		want   string
+	}{
+		{"no keys", m, nil, "at least one"},
+		{"empty name", m, []Option{WithSecret("", "")}, "must not be empty"},
+		{"negative history", m, []Option{WithSecretHistory("config-kek", -1)}, "must be positive"},
+		{"nil format", m, []Option{WithSecret("config-kek", ""), WithKeyIDFormat(nil)}, "keyIDFormat"},
+		{"missing", m, []Option{WithSecret("nope", "")}, `no secret named "nope"`},
+		{"bad value", m, []Option{WithSecret("bad", "")}, "secret s2"},
+		{"client error", failingSM{}, []Option{WithSecret("config-kek", "")}, "403"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(ctx, tt.client, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := New(ctx, nil, WithSecret("config-kek", "")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
@@ -0,0 +1,106 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CanaryEvent describes a decrypt attempt on a value sealed under a canary
+// key, passed to the alert callback of NewCanaryProvider.
+type CanaryEvent struct {
+	// Provider is the name of the wrapped provider.
+	Provider string
+
+	// KeyID is the canary key ID recorded in the ciphertext header.
+	KeyID string
+
+	// CiphertextSize is the size of the encrypted value in bytes.
+	CiphertextSize int
+
+	// Err is the decryption error, or nil if the decoy was decrypted.
+	Err error
+}
+
+// canaryProvider alerts on any decrypt attempt under a canary key ID.
+type canaryProvider struct {
+	Provider
+	alert  func(context.Context, CanaryEvent)
+	canary map[string]struct{}
+}
+
+// Compile-time interface checks.
+var (
+	_ BufferDecrypter     = (*canaryProvider)(nil)
+	_ decodeCacheExempter = (*canaryProvider)(nil)
+)
+
+// NewCanaryProvider wraps p so that every decrypt attempt on a value whose
+// header names one of canaryIDs calls alert, whether or not the decrypt
+// succeeds. Seed the store with decoy values encrypted under a canary key
+// that no legitimate reader uses; any decryption of them signals that
+// someone is reading data they should not.
+//
+// The canary key does not need to be in p: an attempt against a missing
+// key still alerts, with an error wrapping ErrKeyNotFound. Encrypt a decoy
+// with a separate provider holding the canary key:
+//
+//	decoys, _ := crypto.NewProvider(canaryKey, "canary-2024")
+//	ct, _ := decoys.Encrypt(ctx, []byte(`{"password":"hunter2"}`))
+//	p, _ := crypto.NewCanaryProvider(ring, pageOnCall, "canary-2024")
+//
+// alert runs synchronously before Decrypt returns and must be safe for
+// concurrent use. A Codec decode cache never holds canary values, so cached
+// reads cannot skip the alert. Rotation methods of a KeyRingProvider remain reachable
+// through Unwrap.
+func NewCanaryProvider(p Provider, alert func(ctx context.Context, ev CanaryEvent), canaryIDs ...string) (Provider, error) {
+	if p == nil {
+		return nil, errors.New("crypto: NewCanaryProvider provider is nil")
+	}
+	if alert == nil {
+		return nil, errors.New("crypto: NewCanaryProvider alert is nil")
+	}
+	if len(canaryIDs) == 0 {
+		return nil, errors.New("crypto: NewCanaryProvider needs at least one canary key ID")
+	}
+	canary := make(map[string]struct{}, len(canaryIDs))
+	for _, id := range canaryIDs {
+		if id == "" || len(id) > maxKeyIDLen {
+			return nil, fmt.Errorf("%w: canary key ID %q", ErrInvalidKeyID, id)
+		}
+		canary[id] = struct{}{}
+	}
+	return &canaryProvider{Provider: p, alert: alert, canary: canary}, nil
+}
+
+// Unwrap returns the underlying Provider.
+func (p *canaryProvider) Unwrap() Provider { return p.Provider }
+
+// Decrypt decrypts ciphertext, alerting first if it is under a canary key.
+func (p *canaryProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.DecryptTo(ctx, nil, ciphertext)
+}
+
+// exemptFromDecodeCache keeps canary values out of a Codec decode cache, so
+// a cache hit cannot skip the alert.
+func (p *canaryProvider) exemptFromDecodeCache(keyID string) bool {
+	_, ok := p.canary[keyID]
+	return ok
+}
+
+// DecryptTo appends the plaintext of ciphertext to dst, alerting if it is
+// under a canary key.
+func (p *canaryProvider) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	out, err := DecryptTo(ctx, dst, ciphertext, p.Provider)
+	if id, herr := KeyIDOf(ciphertext); herr == nil {
+		if _, ok := p.canary[id]; ok {
+			p.alert(ctx, CanaryEvent{
+				Provider:       p.Provider.Name(),
+				KeyID:          id,
+				CiphertextSize: len(ciphertext),
+				Err:            err,
+			})
+		}
+	}
+	return out, err
+}
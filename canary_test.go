@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+func TestCanaryProvider(t *testing.T) {
+	ctx := context.Background()
+	canaryKey := makeKey(32)
+	canaryKey[0] = 0xCA
+
+	ring := mustNewKeyRingProvider(t, makeKey(32), "prod-1", 1)
+	decoys := mustNewProvider(t, canaryKey, "canary-1")
+	decoy, err := decoys.Encrypt(ctx, []byte(`"hunter2"`))
+	if err != nil {
+		t.Fatalf("Encrypt decoy: %v", err)
+	}
+
+	var events []CanaryEvent
+	p, err := NewCanaryProvider(ring, func(_ context.Context, ev CanaryEvent) {
+		events = append(events, ev)
+	}, "canary-1")
+	if err != nil {
+		t.Fatalf("NewCanaryProvider: %v", err)
+	}
+
+	// Legitimate values never alert.
+	ct, _ := p.Encrypt(ctx, []byte("real"))
+	if _, err := p.Decrypt(ctx, ct); err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("alert fired for a non-canary key: %+v", events)
+	}
+
+	// An attempt fails without the canary key but still alerts.
+	if _, err := p.Decrypt(ctx, decoy); !IsKeyNotFound(err) {
+		t.Fatalf("Decrypt decoy: got %v, want ErrKeyNotFound", err)
+	}
+	// A successful decrypt through a Codec alerts too.
+	if err := ring.AddKey(canaryKey, "canary-1", 0); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	c, err := NewCodec(jsoncodec.New(), p)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	var s string
+	if err := c.Decode(ctx, decoy, &s); err != nil || s != "hunter2" {
+		t.Fatalf("Decode decoy = %q, %v", s, err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d alerts, want 2: %+v", len(events), events)
+	}
+	if ev := events[0]; ev.KeyID != "canary-1" || ev.Provider != ring.Name() || !IsKeyNotFound(ev.Err) || ev.CiphertextSize != len(decoy) {
+		t.Errorf("attempt alert = %+v", ev)
+	}
+	if ev := events[1]; ev.KeyID != "canary-1" || ev.Err != nil {
+		t.Errorf("success alert = %+v", ev)
+	}
+	if p.(interface{ Unwrap() Provider }).Unwrap() != ring {
+		t.Error("Unwrap did not return the wrapped provider")
+	}
+}
+
+func TestNewCanaryProviderValidation(t *testing.T) {
+	p := mustNewProvider(t, makeKey(32), "k")
+	alert := func(context.Context, CanaryEvent) {}
+	if _, err := NewCanaryProvider(nil, alert, "c"); err == nil {
+		t.Error("nil provider: expected error")
+	}
+	if _, err := NewCanaryProvider(p, nil, "c"); err == nil {
+		t.Error("nil alert: expected error")
+	}
+	if _, err := NewCanaryProvider(p, alert); err == nil {
+		t.Error("no canary IDs: expected error")
+	}
+	if _, err := NewCanaryProvider(p, alert, ""); !IsInvalidKeyID(err) {
+		t.Errorf("empty canary ID: got %v, want ErrInvalidKeyID", err)
+	}
+}
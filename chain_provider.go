@@ -0,0 +1,150 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+)
+
+// chainProvider encrypts with its first provider and decrypts with the
+// first provider in order that succeeds.
+type chainProvider struct {
+	providers []Provider
+	closed    atomic.Bool
+}
+
+// Compile-time interface checks.
+var (
+	_ BufferDecrypter  = (*chainProvider)(nil)
+	_ KeyLister        = (*chainProvider)(nil)
+	_ KeyStatsProvider = (*chainProvider)(nil)
+)
+
+// ChainProviders returns a Provider that layers providers as fallbacks.
+// Encrypt always uses the first provider. Decrypt tries each provider in
+// order and returns the first success, so a local break-glass key can sit
+// behind a KMS-backed provider:
+//
+//	p, _ := crypto.ChainProviders(kmsProvider, emergencyProvider)
+//
+// A malformed ciphertext or a cancelled context stops the chain at once;
+// any other failure moves on to the next provider. If every provider fails,
+// the errors are joined in order.
+//
+// Closing the chain closes every provider in it. It returns an error if no
+// providers are given or any is nil.
+func ChainProviders(providers ...Provider) (Provider, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("crypto: ChainProviders needs at least one provider")
+	}
+	if slices.Contains(providers, nil) {
+		return nil, errors.New("crypto: ChainProviders provider is nil")
+	}
+	return &chainProvider{providers: slices.Clone(providers)}, nil
+}
+
+// Name returns "chain:" followed by the first provider's name.
+func (c *chainProvider) Name() string {
+	return "chain:" + c.providers[0].Name()
+}
+
+// Connect connects every provider in the chain, joining their errors.
+func (c *chainProvider) Connect(ctx context.Context) error {
+	var errs []error
+	for i, p := range c.providers {
+		if err := p.Connect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("chain[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Encrypt encrypts with the first provider.
+func (c *chainProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	return c.providers[0].Encrypt(ctx, plaintext)
+}
+
+// Decrypt returns the first successful decryption in chain order.
+func (c *chainProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return c.DecryptTo(ctx, nil, ciphertext)
+}
+
+// DecryptTo is Decrypt, appending the plaintext to dst.
+func (c *chainProvider) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	if c.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	var errs []error
+	for i, p := range c.providers {
+		out, err := DecryptTo(ctx, dst, ciphertext, p)
+		if err == nil {
+			return out, nil
+		}
+		if IsInvalidFormat(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("chain[%d]: %w", i, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// HealthCheck reports the health of the first provider, which handles all
+// encryption. Fallbacks are only needed when it cannot decrypt, so their
+// health does not affect readiness.
+func (c *chainProvider) HealthCheck(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrProviderClosed
+	}
+	return c.providers[0].HealthCheck(ctx)
+}
+
+// ListKeyIDs returns the sorted, de-duplicated key IDs of every provider in
+// the chain that implements KeyLister.
+func (c *chainProvider) ListKeyIDs() []string {
+	if c.closed.Load() {
+		return nil
+	}
+	var ids []string
+	for _, p := range c.providers {
+		if kl, ok := p.(KeyLister); ok {
+			ids = append(ids, kl.ListKeyIDs()...)
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// KeyStats merges the usage statistics of every provider in the chain that
+// implements KeyStatsProvider, summing counts for IDs held by several.
+func (c *chainProvider) KeyStats() []KeyStats {
+	if c.closed.Load() {
+		return nil
+	}
+	var all []KeyStats
+	for _, p := range c.providers {
+		if sp, ok := p.(KeyStatsProvider); ok {
+			all = append(all, sp.KeyStats()...)
+		}
+	}
+	return mergeKeyStats(all)
+}
+
+// Close closes every provider in the chain, joining their errors. Safe to
+// call multiple times; subsequent calls are no-ops.
+func (c *chainProvider) Close() error {
+	if c.closed.Swap(true) {
+		return nil
+	}
+	var errs []error
+	for i, p := range c.providers {
+		if err := p.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close chain[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
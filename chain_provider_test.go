@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestChainProviders_Fallback(t *testing.T) {
+	ctx := context.Background()
+	primary := mustNewProvider(t, makeKey(32), "kms-1")
+	emergency := mustNewProvider(t, makeKey(32), "break-glass")
+
+	chain, err := ChainProviders(primary, emergency)
+	if err != nil {
+		t.Fatalf("ChainProviders: %v", err)
+	}
+
+	// Encrypt uses the first provider.
+	ct, err := chain.Encrypt(ctx, []byte("primary"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if pt, err := primary.Decrypt(ctx, ct); err != nil || string(pt) != "primary" {
+		t.Errorf("primary.Decrypt = %q, %v", pt, err)
+	}
+
+	// Ciphertext only the fallback can read.
+	ct, err = emergency.Encrypt(ctx, []byte("emergency"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	pt, err := chain.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "emergency" {
+		t.Errorf("chain.Decrypt via fallback = %q, %v", pt, err)
+	}
+
+	if got := chain.(KeyLister).ListKeyIDs(); !slices.Equal(got, []string{"break-glass", "kms-1"}) {
+		t.Errorf("ListKeyIDs = %v", got)
+	}
+	if chain.Name() != "chain:kms-1" {
+		t.Errorf("Name = %q", chain.Name())
+	}
+}
+
+func TestChainProviders_AllFail(t *testing.T) {
+	ctx := context.Background()
+	chain, err := ChainProviders(mustNewProvider(t, makeKey(32), "a"), mustNewProvider(t, makeKey(32), "b"))
+	if err != nil {
+		t.Fatalf("ChainProviders: %v", err)
+	}
+	ct, err := mustNewProvider(t, makeKey(32), "c").Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := chain.Decrypt(ctx, ct); !IsKeyNotFound(err) {
+		t.Errorf("Decrypt unknown key = %v, want ErrKeyNotFound", err)
+	}
+	if _, err := chain.Decrypt(ctx, []byte("garbage")); !IsInvalidFormat(err) {
+		t.Errorf("Decrypt garbage = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestChainProviders_Invalid(t *testing.T) {
+	if _, err := ChainProviders(); err == nil {
+		t.Error("empty chain accepted")
+	}
+	if _, err := ChainProviders(mustNewProvider(t, makeKey(32), "a"), nil); err == nil {
+		t.Error("nil provider accepted")
+	}
+}
+
+func TestChainProviders_Close(t *testing.T) {
+	ctx := context.Background()
+	a, _ := NewProvider(makeKey(32), "a")
+	b, _ := NewProvider(makeKey(32), "b")
+	chain, err := ChainProviders(a, b)
+	if err != nil {
+		t.Fatalf("ChainProviders: %v", err)
+	}
+	if err := chain.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	if err := chain.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := chain.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if err := b.HealthCheck(ctx); !IsProviderClosed(err) {
+		t.Errorf("fallback not closed: %v", err)
+	}
+	if _, err := chain.Encrypt(ctx, []byte("x")); !IsProviderClosed(err) {
+		t.Errorf("Encrypt after Close = %v", err)
+	}
+}
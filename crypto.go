@@ -31,7 +31,11 @@ package crypto
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
+	"io"
+	"sync"
+	"time"
 
 	"github.com/rbaliyan/config/codec"
 )
@@ -46,19 +50,49 @@ type Codec struct {
 	inner    codec.Codec
 	provider Provider
 	name     string
+	hooks    []Hook
+	paranoid bool
+	cache    *decodeCache     // nil unless WithDecodeCache was given
+	escrow   *keyRingProvider // nil unless WithEscrow was given
+
+	onExpiredKey func(context.Context, KeyInfo) // nil unless WithExpiredKeyWarning was given
+	metrics      Metrics                        // nil unless WithMetrics was given
+	onDecrypt    []func(context.Context, DecryptEvent)
+
+	stopWatch    context.CancelFunc    // nil unless the decode cache watches the provider
+	exempters    []decodeCacheExempter // providers whose keys bypass the decode cache
+	ownsProvider bool
+	closeOnce    sync.Once
+	closeErr     error
 }
 
 // Compile-time interface checks.
 var (
 	_ codec.Codec       = (*Codec)(nil)
 	_ codec.Transformer = (*Codec)(nil)
+	_ io.Closer         = (*Codec)(nil)
 )
 
 // CodecOption configures NewCodec behavior.
 type CodecOption func(*codecOptions)
 
 type codecOptions struct {
-	prefix string
+	prefix        string
+	fieldPatterns []pathMatcher
+	signingID     string
+	signingKey    ed25519.PrivateKey
+	verifyKeys    map[string]ed25519.PublicKey
+	encryptWith   Provider
+	escrow        Provider
+	hooks         []Hook
+	paranoid      bool
+	cacheEntries  int
+	cacheBytes    int
+	cacheTTL      time.Duration
+	onExpiredKey  func(context.Context, KeyInfo)
+	ownsProvider  bool
+	metrics       Metrics
+	onDecrypt     []func(context.Context, DecryptEvent)
 }
 
 // WithClientCodec prefixes the codec name with "client:" so the config-server
@@ -77,6 +111,15 @@ func WithCodecPrefix(prefix string) CodecOption {
 	}
 }
 
+// WithProviderOwnership makes the Codec own its provider: Codec.Close then
+// closes the provider, wiping its key material. Use it when the provider is
+// created solely for this Codec, so one Close releases everything.
+func WithProviderOwnership() CodecOption {
+	return func(o *codecOptions) {
+		o.ownsProvider = true
+	}
+}
+
 // NewCodec creates an encrypting codec that wraps the given inner codec.
 // The codec name is "encrypted:<inner>", e.g. "encrypted:json".
 // With WithClientCodec the name becomes "client:encrypted:<inner>".
@@ -99,11 +142,55 @@ func NewCodec(inner codec.Codec, p Provider, opts ...CodecOption) (*Codec, error
 		name = o.prefix + ":" + name
 	}
 
-	return &Codec{
+	var escrow *keyRingProvider
+	if o.escrow != nil {
+		var err error
+		if escrow, err = escrowRing(p, o.escrow); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &Codec{
 		inner:    inner,
 		provider: p,
 		name:     name,
-	}, nil
+		hooks:    o.hooks,
+		paranoid: o.paranoid || paranoidBuild,
+		cache:    newDecodeCache(o.cacheEntries, o.cacheBytes, o.cacheTTL),
+		escrow:   escrow,
+
+		onExpiredKey: o.onExpiredKey,
+		metrics:      o.metrics,
+		onDecrypt:    o.onDecrypt,
+		ownsProvider: o.ownsProvider,
+	}
+	if c.cache != nil {
+		c.exempters = decodeCacheExempters(p)
+	}
+	if w, ok := p.(Watcher); ok && c.cache != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.stopWatch = cancel
+		go c.watchProvider(w.Watch(ctx))
+	}
+	return c, nil
+}
+
+// Close releases the Codec's resources: it stops watching the provider, wipes
+// the decode cache, if any, and closes the provider when the Codec was
+// created with WithProviderOwnership. Without ownership the provider is left
+// open for its other users. Close is safe to call more than once; later
+// calls return the first call's result.
+func (c *Codec) Close() error {
+	c.closeOnce.Do(func() {
+		if c.stopWatch != nil {
+			c.stopWatch()
+		}
+		c.PurgeDecodeCache()
+		if c.ownsProvider {
+			c.closeErr = c.provider.Close()
+		}
+	})
+	return c.closeErr
 }
 
 // Name returns the codec name, e.g. "encrypted:json".
@@ -118,34 +205,106 @@ func (c *Codec) Encode(ctx context.Context, v any) ([]byte, error) {
 		return nil, fmt.Errorf("crypto: inner encode failed: %w", err)
 	}
 
-	ciphertext, err := c.provider.Encrypt(ctx, plaintext)
+	start := time.Now()
+	ciphertext, err := c.transform(ctx, plaintext)
+	c.observe(ctx, OpEncode, start, len(plaintext), ciphertext, err)
 	if err != nil {
+		if isHookError(err) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("crypto: encrypt failed: %w", err)
 	}
 	return ciphertext, nil
 }
 
 // Decode decrypts the data, then deserializes the plaintext using the inner codec.
+// The plaintext is decrypted into a pooled buffer and wiped once the inner
+// codec returns, so inner codecs must copy any bytes they keep (json, yaml,
+// and toml do).
 func (c *Codec) Decode(ctx context.Context, data []byte, v any) error {
-	plaintext, err := c.provider.Decrypt(ctx, data)
+	bp := getBuf()
+	buf, err := c.DecodeInto(ctx, data, v, *bp)
+	putBuf(bp, buf)
+	return err
+}
+
+// DecodeInto is Decode with a caller-supplied plaintext buffer. The
+// plaintext is decrypted into buf[:0], deserialized into v, and wiped; the
+// returned slice (buf, or a larger replacement) should be passed to the next
+// call. Hot paths that decode many small values can reuse one buffer per
+// goroutine and avoid allocating for the plaintext.
+//
+// The inner codec must not retain references to the plaintext after Decode
+// returns; the standard json, yaml, and toml codecs do not.
+func (c *Codec) DecodeInto(ctx context.Context, data []byte, v any, buf []byte) ([]byte, error) {
+	start := time.Now()
+	plaintext, err := c.decrypt(ctx, buf[:0], data)
+	c.observe(ctx, OpDecode, start, len(data), data, err)
+	c.emitDecrypt(ctx, data, err)
 	if err != nil {
-		return fmt.Errorf("crypto: decrypt failed: %w", err)
+		return buf, fmt.Errorf("crypto: decrypt failed: %w", err)
 	}
+	defer clear(plaintext)
 
+	if err := c.runAfterDecrypt(ctx, data, plaintext); err != nil {
+		return plaintext[:0], err
+	}
 	if err := c.inner.Decode(ctx, plaintext, v); err != nil {
-		return fmt.Errorf("crypto: inner decode failed: %w", err)
+		return plaintext[:0], fmt.Errorf("crypto: inner decode failed: %w", err)
 	}
-	return nil
+	return plaintext[:0], nil
 }
 
-// Transform encrypts the raw bytes using envelope encryption.
+// Transform encrypts the raw bytes using envelope encryption, running any
+// registered BeforeEncrypt hooks first.
 // This implements codec.Transformer for use with codec.NewChain.
 func (c *Codec) Transform(ctx context.Context, data []byte) ([]byte, error) {
+	start := time.Now()
+	ciphertext, err := c.transform(ctx, data)
+	c.observe(ctx, OpEncode, start, len(data), ciphertext, err)
+	return ciphertext, err
+}
+
+// transform is Transform without metrics, shared with Encode.
+func (c *Codec) transform(ctx context.Context, data []byte) ([]byte, error) {
+	if err := c.runBeforeEncrypt(ctx, data); err != nil {
+		return nil, err
+	}
+	ciphertext, err := c.encrypt(ctx, data)
+	if err != nil {
+		return nil, newProviderError(c.provider, OpEncrypt, encryptKeyID(c.provider), err)
+	}
+	if c.paranoid {
+		if err := checkNoPlaintext(data, ciphertext); err != nil {
+			return nil, err
+		}
+	}
+	return ciphertext, nil
+}
+
+// encrypt encrypts data with the provider, adding the escrow recipient when
+// WithEscrow was given.
+func (c *Codec) encrypt(ctx context.Context, data []byte) ([]byte, error) {
+	if c.escrow != nil {
+		return c.provider.(*keyRingProvider).encryptEscrowed(data, c.escrow)
+	}
 	return c.provider.Encrypt(ctx, data)
 }
 
-// Reverse decrypts the raw bytes, recovering the original plaintext.
+// Reverse decrypts the raw bytes, recovering the original plaintext, then
+// runs any registered AfterDecrypt hooks.
 // This implements codec.Transformer for use with codec.NewChain.
 func (c *Codec) Reverse(ctx context.Context, data []byte) ([]byte, error) {
-	return c.provider.Decrypt(ctx, data)
+	start := time.Now()
+	plaintext, err := c.decrypt(ctx, nil, data)
+	c.observe(ctx, OpDecode, start, len(data), data, err)
+	c.emitDecrypt(ctx, data, err)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.runAfterDecrypt(ctx, data, plaintext); err != nil {
+		clear(plaintext)
+		return nil, err
+	}
+	return plaintext, nil
 }
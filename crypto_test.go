@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"runtime"
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 
 	"github.com/rbaliyan/config"
 	"github.com/rbaliyan/config/codec"
@@ -470,3 +472,210 @@ func TestChainWithCryptoTransformer(t *testing.T) {
 		t.Errorf("got %+v, want %+v", got, original)
 	}
 }
+
+func TestCodecDecodeIntoReusesBuffer(t *testing.T) {
+	ctx := context.Background()
+	c := testCodec(t)
+
+	buf := make([]byte, 0, 256)
+	for _, want := range []string{"first", "second value", "third"} {
+		data, err := c.Encode(ctx, want)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		var got string
+		out, err := c.DecodeInto(ctx, data, &got, buf)
+		if err != nil {
+			t.Fatalf("DecodeInto: %v", err)
+		}
+		if got != want {
+			t.Errorf("DecodeInto = %q, want %q", got, want)
+		}
+		if len(out) != 0 || cap(out) != cap(buf) || &out[:1][0] != &buf[:1][0] {
+			t.Errorf("DecodeInto did not reuse the buffer: len=%d cap=%d", len(out), cap(out))
+		}
+		if bytes.Contains(buf[:cap(buf)], []byte(want)) {
+			t.Error("plaintext left in buffer after DecodeInto")
+		}
+		buf = out
+	}
+}
+
+func TestDecryptToDoesNotCopyPayload(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewProvider(t, makeKey(32), "k")
+	data, err := p.Encrypt(ctx, make([]byte, 1<<20))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	buf := make([]byte, 0, 1<<20)
+
+	// Decrypting into a large enough buffer should allocate only small,
+	// per-call state (header fields, AEAD setup), never the payload.
+	const runs = 8
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range runs {
+		if _, err := DecryptTo(ctx, buf[:0], data, p); err != nil {
+			t.Fatalf("DecryptTo: %v", err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	if perOp := (after.TotalAlloc - before.TotalAlloc) / runs; perOp > 64<<10 {
+		t.Errorf("DecryptTo allocated %d bytes per call for a 1 MiB payload, want under 64 KiB", perOp)
+	}
+}
+
+func TestDecryptToFallback(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewProvider(t, makeKey(32), "k")
+	data, err := p.Encrypt(ctx, []byte("payload"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// A provider without BufferDecrypter goes through Decrypt.
+	wrapped := struct{ Provider }{p}
+	got, err := DecryptTo(ctx, []byte("prefix:"), data, wrapped)
+	if err != nil {
+		t.Fatalf("DecryptTo: %v", err)
+	}
+	if string(got) != "prefix:payload" {
+		t.Errorf("DecryptTo = %q, want %q", got, "prefix:payload")
+	}
+
+	got, err = DecryptTo(ctx, []byte("prefix:"), []byte("garbage"), p)
+	if !IsInvalidFormat(err) || string(got) != "prefix:" {
+		t.Errorf("DecryptTo garbage = %q, %v; want dst unchanged and ErrInvalidFormat", got, err)
+	}
+}
+
+func TestDecryptToWriter(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewProvider(t, makeKey(32), "k")
+	want := bytes.Repeat([]byte("payload-"), 10000)
+	data, err := p.Encrypt(ctx, want)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	for name, prov := range map[string]Provider{"buffer": p, "fallback": struct{ Provider }{p}} {
+		var out bytes.Buffer
+		n, err := DecryptToWriter(ctx, &out, data, prov)
+		if err != nil {
+			t.Fatalf("%s: DecryptToWriter: %v", name, err)
+		}
+		if n != int64(len(want)) || !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("%s: wrote %d bytes, mismatch with plaintext", name, n)
+		}
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 0xFF
+	var out bytes.Buffer
+	if _, err := DecryptToWriter(ctx, &out, tampered, p); !IsDecryptionFailed(err) {
+		t.Errorf("tampered: got %v, want ErrDecryptionFailed", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("tampered: %d bytes written, want none", out.Len())
+	}
+}
+
+func TestDecryptStream(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewProvider(t, makeKey(32), "k")
+	want := bytes.Repeat([]byte("stream-"), 10000)
+	data, err := p.Encrypt(ctx, want)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	for name, prov := range map[string]Provider{"in place": p, "fallback": struct{ Provider }{p}} {
+		var out bytes.Buffer
+		n, err := DecryptStream(ctx, &out, bytes.NewReader(data), prov)
+		if err != nil {
+			t.Fatalf("%s: DecryptStream: %v", name, err)
+		}
+		if n != int64(len(want)) || !bytes.Equal(out.Bytes(), want) {
+			t.Errorf("%s: wrote %d bytes, mismatch with plaintext", name, n)
+		}
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)/2] ^= 0xFF
+	var out bytes.Buffer
+	if _, err := DecryptStream(ctx, &out, bytes.NewReader(tampered), p); !IsDecryptionFailed(err) {
+		t.Errorf("tampered: got %v, want ErrDecryptionFailed", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("tampered: %d bytes written, want none", out.Len())
+	}
+
+	if _, err := DecryptStream(ctx, &out, iotest.ErrReader(errors.New("boom")), p); err == nil {
+		t.Error("read error not reported")
+	}
+}
+
+func TestDecryptInPlace(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewProvider(t, makeKey(32), "k")
+	want := bytes.Repeat([]byte("in-place"), 1<<17)
+	data, err := p.Encrypt(ctx, want)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	got, err := p.(inPlaceDecrypter).decryptInPlace(ctx, data)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("decryptInPlace: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("plaintext mismatch")
+	}
+	if &got[0] != &data[len(data)-len(got)-gcmTagSize] {
+		t.Error("plaintext is not in the ciphertext buffer")
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 64<<10 {
+		t.Errorf("decryptInPlace allocated %d bytes for a 1 MiB payload, want under 64 KiB", n)
+	}
+}
+
+func TestCodecCloseOwnership(t *testing.T) {
+	ctx := context.Background()
+
+	shared := mustNewProvider(t, makeKey(32), "k")
+	c, err := NewCodec(jsoncodec.New(), shared)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := shared.HealthCheck(ctx); err != nil {
+		t.Errorf("non-owning Close closed the provider: %v", err)
+	}
+
+	owned, err := NewProvider(makeKey(32), "k")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	c, err = NewCodec(jsoncodec.New(), owned, WithProviderOwnership())
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if err := owned.HealthCheck(ctx); !IsProviderClosed(err) {
+		t.Errorf("owned provider HealthCheck = %v, want ErrProviderClosed", err)
+	}
+	if _, err := c.Encode(ctx, "v"); !IsProviderClosed(err) {
+		t.Errorf("Encode after Close = %v, want ErrProviderClosed", err)
+	}
+}
@@ -0,0 +1,246 @@
+package crypto
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// WithDecodeCache enables an in-memory cache of decrypted plaintext on a
+// Codec created by NewCodec, keyed by the SHA-256 of the ciphertext. Repeated
+// Decode calls for the same unchanged store entry — common with polling
+// config loaders — skip the provider and AES entirely; only the inner codec
+// runs. AfterDecrypt hooks still run on every Decode.
+//
+// The cache is bounded by maxEntries and by maxBytes of cached plaintext,
+// evicting least-recently-used entries first; entries also expire ttl after
+// they were cached. A non-positive maxEntries disables the cache; a
+// non-positive maxBytes or ttl leaves that bound off.
+//
+// A cache hit returns the plaintext without reaching the provider, so
+// per-key decrypt statistics and provider-level instrumentation such as
+// InstrumentProvider count only misses. Values under a canary key of a
+// NewCanaryProvider reached through Unwrap are never cached, so every
+// decrypt of a decoy still alerts.
+//
+// Cached plaintext lives in ordinary heap memory until evicted. When the
+// provider implements Watcher, as the built-in key ring does, the cache is
+// purged whenever a key is removed or its metadata changes; the Codec then
+// runs a watch goroutine until Codec.Close. Otherwise a value stays
+// decodable from the cache after its key is removed from the provider, so
+// call Codec.PurgeDecodeCache after revoking a key.
+func WithDecodeCache(maxEntries, maxBytes int, ttl time.Duration) CodecOption {
+	return func(o *codecOptions) {
+		o.cacheEntries = maxEntries
+		o.cacheBytes = maxBytes
+		o.cacheTTL = ttl
+	}
+}
+
+// decodeCache is an LRU cache of plaintext keyed by ciphertext hash. Cached
+// slices are owned by the cache and wiped on eviction; callers get copies.
+type decodeCache struct {
+	maxEntries int
+	maxBytes   int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	lru   *list.List // front is most recently used
+	items map[[sha256.Size]byte]*list.Element
+	bytes int
+}
+
+type decodeCacheEntry struct {
+	key       [sha256.Size]byte
+	plaintext []byte
+	expires   time.Time // zero when there is no TTL
+}
+
+// newDecodeCache returns a decodeCache with the given bounds, or nil if
+// maxEntries disables caching.
+func newDecodeCache(maxEntries, maxBytes int, ttl time.Duration) *decodeCache {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &decodeCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+		now:        time.Now,
+		lru:        list.New(),
+		items:      make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// appendCached appends the cached plaintext for ciphertext to dst. It
+// reports false on a miss or an expired entry.
+func (c *decodeCache) appendCached(dst, ciphertext []byte) ([]byte, bool) {
+	key := sha256.Sum256(ciphertext)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return dst, false
+	}
+	e := el.Value.(*decodeCacheEntry)
+	if !e.expires.IsZero() && !c.now().Before(e.expires) {
+		c.removeLocked(el)
+		return dst, false
+	}
+	c.lru.MoveToFront(el)
+	return append(dst, e.plaintext...), true
+}
+
+// add caches a copy of plaintext for ciphertext, evicting as needed.
+// Plaintexts larger than maxBytes are not cached.
+func (c *decodeCache) add(ciphertext, plaintext []byte) {
+	if c.maxBytes > 0 && len(plaintext) > c.maxBytes {
+		return
+	}
+	key := sha256.Sum256(ciphertext)
+	e := &decodeCacheEntry{key: key, plaintext: append([]byte(nil), plaintext...)}
+	if c.ttl > 0 {
+		e.expires = c.now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+	c.items[key] = c.lru.PushFront(e)
+	c.bytes += len(e.plaintext)
+	for c.lru.Len() > c.maxEntries || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+// invalidate drops the entry for ciphertext, if any.
+func (c *decodeCache) invalidate(ciphertext []byte) {
+	key := sha256.Sum256(ciphertext)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeLocked(el)
+	}
+}
+
+// purge drops every entry.
+func (c *decodeCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.lru.Len() > 0 {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+// len returns the number of cached entries.
+func (c *decodeCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// removeLocked unlinks el and wipes its plaintext. Caller must hold mu.
+func (c *decodeCache) removeLocked(el *list.Element) {
+	e := c.lru.Remove(el).(*decodeCacheEntry)
+	delete(c.items, e.key)
+	c.bytes -= len(e.plaintext)
+	clear(e.plaintext)
+}
+
+// InvalidateDecodeCache drops the cached plaintext for ciphertext data, if
+// the Codec has a decode cache. It is a no-op otherwise.
+func (c *Codec) InvalidateDecodeCache(data []byte) {
+	if c.cache != nil {
+		c.cache.invalidate(data)
+	}
+}
+
+// PurgeDecodeCache drops and wipes all cached plaintext, if the Codec has a
+// decode cache. Call it after removing or revoking a key.
+func (c *Codec) PurgeDecodeCache() {
+	if c.cache != nil {
+		c.cache.purge()
+	}
+}
+
+// decrypt appends the plaintext of data to dst, serving it from the decode
+// cache when possible and populating the cache on a miss.
+// It also reports values decrypted with an expired key.
+func (c *Codec) decrypt(ctx context.Context, dst, data []byte) ([]byte, error) {
+	out, err := c.decryptCached(ctx, dst, data)
+	if err == nil {
+		c.warnIfExpired(ctx, data)
+	}
+	return out, err
+}
+
+func (c *Codec) decryptCached(ctx context.Context, dst, data []byte) ([]byte, error) {
+	out, err := c.decryptCachedRaw(ctx, dst, data)
+	if err != nil {
+		id, _ := KeyIDOf(data)
+		return out, newProviderError(c.provider, OpDecrypt, id, err)
+	}
+	return out, nil
+}
+
+func (c *Codec) decryptCachedRaw(ctx context.Context, dst, data []byte) ([]byte, error) {
+	if c.cache == nil || c.cacheExempt(data) {
+		return DecryptTo(ctx, dst, data, c.provider)
+	}
+	if out, ok := c.cache.appendCached(dst, data); ok {
+		return out, nil
+	}
+	out, err := DecryptTo(ctx, dst, data, c.provider)
+	if err != nil {
+		return out, err
+	}
+	c.cache.add(data, out[len(dst):])
+	return out, nil
+}
+
+// decodeCacheExempter is implemented by providers that must see every
+// decrypt under some key IDs; values under those keys bypass the decode
+// cache.
+type decodeCacheExempter interface {
+	exemptFromDecodeCache(keyID string) bool
+}
+
+// decodeCacheExempters returns the decodeCacheExempters among p and the
+// providers it wraps, following Unwrap.
+func decodeCacheExempters(p Provider) []decodeCacheExempter {
+	var out []decodeCacheExempter
+	for p != nil {
+		if e, ok := p.(decodeCacheExempter); ok {
+			out = append(out, e)
+		}
+		u, ok := p.(interface{ Unwrap() Provider })
+		if !ok {
+			break
+		}
+		p = u.Unwrap()
+	}
+	return out
+}
+
+// cacheExempt reports whether data must bypass the decode cache. Values
+// whose key ID cannot be read are exempt, so they always reach the provider.
+func (c *Codec) cacheExempt(data []byte) bool {
+	if len(c.exempters) == 0 {
+		return false
+	}
+	id, err := KeyIDOf(data)
+	if err != nil {
+		return true
+	}
+	for _, e := range c.exempters {
+		if e.exemptFromDecodeCache(id) {
+			return true
+		}
+	}
+	return false
+}
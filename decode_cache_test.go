@@ -0,0 +1,216 @@
+package crypto
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+// countingProvider counts Decrypt calls on a wrapped Provider.
+type countingProvider struct {
+	Provider
+	decrypts atomic.Int64
+}
+
+func (p *countingProvider) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	p.decrypts.Add(1)
+	return p.Provider.Decrypt(ctx, data)
+}
+
+func newCachedCodec(t *testing.T, maxEntries, maxBytes int, ttl time.Duration) (*Codec, *countingProvider) {
+	t.Helper()
+	p := &countingProvider{Provider: mustNewProvider(t, makeKey(32), "k")}
+	c, err := NewCodec(jsoncodec.New(), p, WithDecodeCache(maxEntries, maxBytes, ttl))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	return c, p
+}
+
+func mustEncode(t *testing.T, c *Codec, v any) []byte {
+	t.Helper()
+	data, err := c.Encode(context.Background(), v)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return data
+}
+
+func TestDecodeCacheHit(t *testing.T) {
+	ctx := context.Background()
+	c, p := newCachedCodec(t, 10, 0, 0)
+	data := mustEncode(t, c, map[string]string{"k": "v"})
+
+	for range 3 {
+		var got map[string]string
+		if err := c.Decode(ctx, data, &got); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if got["k"] != "v" {
+			t.Fatalf("Decode: got %v", got)
+		}
+	}
+	if n := p.decrypts.Load(); n != 1 {
+		t.Errorf("provider Decrypt calls = %d, want 1", n)
+	}
+}
+
+func TestDecodeCacheHitsAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newCachedCodec(t, 10, 0, 0)
+	data := mustEncode(t, c, "value")
+
+	first, err := c.Reverse(ctx, data)
+	if err != nil {
+		t.Fatalf("Reverse: %v", err)
+	}
+	clear(first)
+	second, err := c.Reverse(ctx, data)
+	if err != nil {
+		t.Fatalf("Reverse: %v", err)
+	}
+	if string(second) != `"value"` {
+		t.Errorf("cached plaintext corrupted by caller: %q", second)
+	}
+}
+
+func TestDecodeCacheEvictsByCount(t *testing.T) {
+	ctx := context.Background()
+	c, p := newCachedCodec(t, 2, 0, 0)
+	a, b, d := mustEncode(t, c, "a"), mustEncode(t, c, "b"), mustEncode(t, c, "d")
+
+	var s string
+	for _, data := range [][]byte{a, b, d, a} {
+		if err := c.Decode(ctx, data, &s); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+	}
+	if n := p.decrypts.Load(); n != 4 {
+		t.Errorf("provider Decrypt calls = %d, want 4 (a evicted by d)", n)
+	}
+	if n := c.cache.len(); n != 2 {
+		t.Errorf("cache len = %d, want 2", n)
+	}
+}
+
+func TestDecodeCacheEvictsByBytes(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newCachedCodec(t, 100, 10, 0)
+	var s string
+	for _, v := range []string{"aaaa", "bbbb", "cccc"} { // 6 bytes each as JSON
+		if err := c.Decode(ctx, mustEncode(t, c, v), &s); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+	}
+	if n := c.cache.len(); n != 1 {
+		t.Errorf("cache len = %d, want 1", n)
+	}
+	if err := c.Decode(ctx, mustEncode(t, c, "this is longer than ten bytes"), &s); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if c.cache.bytes > 10 {
+		t.Errorf("cache bytes = %d, want <= 10", c.cache.bytes)
+	}
+}
+
+func TestDecodeCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c, p := newCachedCodec(t, 10, 0, time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	c.cache.now = func() time.Time { return now }
+	data := mustEncode(t, c, "v")
+
+	var s string
+	_ = c.Decode(ctx, data, &s)
+	now = now.Add(30 * time.Second)
+	_ = c.Decode(ctx, data, &s)
+	now = now.Add(30 * time.Second)
+	_ = c.Decode(ctx, data, &s)
+	if n := p.decrypts.Load(); n != 2 {
+		t.Errorf("provider Decrypt calls = %d, want 2 (one refresh after TTL)", n)
+	}
+}
+
+func TestDecodeCacheInvalidateAndPurge(t *testing.T) {
+	ctx := context.Background()
+	c, p := newCachedCodec(t, 10, 0, 0)
+	a, b := mustEncode(t, c, "a"), mustEncode(t, c, "b")
+
+	var s string
+	_ = c.Decode(ctx, a, &s)
+	_ = c.Decode(ctx, b, &s)
+	c.InvalidateDecodeCache(a)
+	if n := c.cache.len(); n != 1 {
+		t.Errorf("cache len after invalidate = %d, want 1", n)
+	}
+	c.PurgeDecodeCache()
+	if n := c.cache.len(); n != 0 {
+		t.Errorf("cache len after purge = %d, want 0", n)
+	}
+	_ = c.Decode(ctx, b, &s)
+	if n := p.decrypts.Load(); n != 3 {
+		t.Errorf("provider Decrypt calls = %d, want 3", n)
+	}
+}
+
+func TestDecodeCacheDisabled(t *testing.T) {
+	c := testCodec(t)
+	if c.cache != nil {
+		t.Error("decode cache should be off by default")
+	}
+	c.PurgeDecodeCache() // no-op
+	c.InvalidateDecodeCache(nil)
+
+	c, _ = newCachedCodec(t, 0, 0, 0)
+	if c.cache != nil {
+		t.Error("maxEntries 0 should disable the cache")
+	}
+}
+
+func TestDecodeCacheSkipsCanaryKeys(t *testing.T) {
+	ctx := context.Background()
+	canaryKey := makeKey(32)
+	canaryKey[0] = 0xCA
+	ring := mustNewProvider(t, makeKey(32), "k")
+	if err := ring.(*keyRingProvider).AddKey(canaryKey, "canary-1", 0); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	var alerts, decrypts atomic.Int64
+	canary, err := NewCanaryProvider(ring, func(context.Context, CanaryEvent) { alerts.Add(1) }, "canary-1")
+	if err != nil {
+		t.Fatalf("NewCanaryProvider: %v", err)
+	}
+	// The canary sits below another wrapper and is found through Unwrap.
+	p := InstrumentProvider(canary, MetricsFunc(func(_ context.Context, obs Observation) {
+		if obs.Operation == OpDecrypt {
+			decrypts.Add(1)
+		}
+	}))
+	c, err := NewCodec(jsoncodec.New(), p, WithDecodeCache(10, 0, 0))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+
+	decoy, err := mustNewProvider(t, canaryKey, "canary-1").Encrypt(ctx, []byte(`"decoy"`))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	data := mustEncode(t, c, "value")
+	for range 3 {
+		for want, ct := range map[string][]byte{"decoy": decoy, "value": data} {
+			var got string
+			if err := c.Decode(ctx, ct, &got); err != nil || got != want {
+				t.Fatalf("Decode = %q, %v; want %q", got, err, want)
+			}
+		}
+	}
+	if n := alerts.Load(); n != 3 {
+		t.Errorf("canary alerts = %d, want 3", n)
+	}
+	if n := decrypts.Load(); n != 4 {
+		t.Errorf("provider decrypts = %d, want 4 (every decoy, the value once)", n)
+	}
+}
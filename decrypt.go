@@ -1,68 +1,204 @@
 package crypto
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"context"
 	"fmt"
+	"io"
 )
 
-// keyLookupFunc returns a defensive copy of key bytes for the given ID.
-type keyLookupFunc func(id string) ([]byte, error)
+// keyLookupFunc returns the key bytes for the given ID together with a
+// release func that wipes them. The bytes are only valid until release is
+// called.
+type keyLookupFunc func(id string) (key []byte, release func(), err error)
 
 // decryptEnvelope decrypts data that was encrypted with envelope encryption.
 // It supports both v1 and v2 header formats.
 func decryptEnvelope(data []byte, lookupKey keyLookupFunc) ([]byte, error) {
+	return decryptEnvelopeTo(nil, data, lookupKey)
+}
+
+// decryptEnvelopeTo is decryptEnvelope, appending the plaintext to dst.
+// dst must not overlap data, except as set up by decryptEnvelopeInPlace.
+func decryptEnvelopeTo(dst, data []byte, lookupKey keyLookupFunc) ([]byte, error) {
 	h, ciphertext, err := readHeader(data)
 	if err != nil {
 		return nil, err
 	}
+	return openEnvelope(dst, h, ciphertext, lookupKey)
+}
 
+// openEnvelope unwraps the DEK of a parsed envelope and decrypts its payload,
+// appending the plaintext to dst.
+func openEnvelope(dst []byte, h *header, ciphertext []byte, lookupKey keyLookupFunc) ([]byte, error) {
 	// GCM ciphertext must contain at least the authentication tag.
 	if len(ciphertext) < gcmTagSize {
 		return nil, fmt.Errorf("%w: ciphertext too short", ErrInvalidFormat)
 	}
 
-	// Look up the KEK by key ID.
-	kekBytes, err := lookupKey(h.keyID)
+	dek, err := unwrapDEK(h, lookupKey)
 	if err != nil {
 		return nil, err
 	}
-	defer clear(kekBytes)
+	defer clear(dek)
+	return openPayload(dst, h, ciphertext, dek)
+}
 
-	if len(kekBytes) != aesKeySize {
-		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidKeySize, len(kekBytes))
+// openPayload decrypts the payload of an envelope with its unwrapped DEK
+// and appends the plaintext to dst.
+func openPayload(dst []byte, h *header, ciphertext, dek []byte) ([]byte, error) {
+	dekAEAD, err := newAEAD(h.algorithm, dek)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
 	}
 
-	// Decrypt the DEK, using key ID as AAD.
-	kekBlock, err := aes.NewCipher(kekBytes)
+	plaintext, err := dekAEAD.Open(dst, h.dataNonce, ciphertext, h.dataAAD())
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+		return nil, fmt.Errorf("%w: failed to decrypt data", ErrDecryptionFailed)
 	}
-	kekGCM, err := cipher.NewGCM(kekBlock)
+
+	return plaintext, nil
+}
+
+// unwrapDEK decrypts the DEK from h. For multi-recipient envelopes each
+// recipient is tried in header order, skipping those whose key the lookup
+// does not have; if none is found the primary's lookup error is returned.
+func unwrapDEK(h *header, lookupKey keyLookupFunc) ([]byte, error) {
+	if h.format == formatRemoteWrap {
+		return nil, fmt.Errorf("%w: DEK for key %q is wrapped by a KeyWrapper; decrypt with NewWrappingProvider", ErrUnsupportedFormat, h.keyID)
+	}
+	dek, err := unwrapDEKFor(h.algorithm, h.keyID, h.dekNonce, h.encryptedDEK, lookupKey)
+	if err == nil || !IsKeyNotFound(err) {
+		return dek, err
+	}
+	for _, r := range h.recipients {
+		dek, rerr := unwrapDEKFor(h.algorithm, r.keyID, r.dekNonce, r.encryptedDEK, lookupKey)
+		if rerr == nil || !IsKeyNotFound(rerr) {
+			return dek, rerr
+		}
+	}
+	return nil, err
+}
+
+// unwrapDEKFor looks up the KEK for keyID and decrypts encryptedDEK with it,
+// using the key ID as AAD. The KEK is released as soon as the DEK is
+// unwrapped so it is exposed only for the AEAD setup.
+func unwrapDEKFor(alg byte, keyID string, dekNonce, encryptedDEK []byte, lookupKey keyLookupFunc) ([]byte, error) {
+	kekBytes, release, err := lookupKey(keyID)
 	if err != nil {
+		return nil, err
+	}
+	if len(kekBytes) != aesKeySize {
+		release()
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidKeySize, len(kekBytes))
+	}
+
+	kekAEAD, err := newAEAD(alg, kekBytes)
+	if err != nil {
+		release()
 		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
 	}
 
-	dek, err := kekGCM.Open(nil, h.dekNonce, h.encryptedDEK, []byte(h.keyID))
+	dek, err := kekAEAD.Open(nil, dekNonce, encryptedDEK, []byte(keyID))
+	release()
 	if err != nil {
 		return nil, fmt.Errorf("%w: failed to decrypt DEK", ErrDecryptionFailed)
 	}
-	defer clear(dek)
+	return dek, nil
+}
+
+// BufferDecrypter is implemented by Providers that can decrypt into a
+// caller-supplied buffer. The built-in NewProvider and NewKeyRingProvider
+// implementations satisfy it.
+type BufferDecrypter interface {
+	// DecryptTo decrypts ciphertext and appends the plaintext to dst,
+	// returning the updated slice. dst must not overlap ciphertext.
+	DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error)
+}
 
-	// Decrypt the data with the DEK.
-	dekBlock, err := aes.NewCipher(dek)
+// DecryptTo decrypts data with p and appends the plaintext to dst, returning
+// the updated slice. Pass buf[:0] to reuse an existing buffer: when its
+// capacity suffices, no plaintext allocation is made. Providers that do not
+// implement BufferDecrypter fall back to Decrypt followed by a copy.
+//
+// On error the returned slice is dst unchanged. dst must not overlap data.
+func DecryptTo(ctx context.Context, dst, data []byte, p Provider) ([]byte, error) {
+	if bd, ok := p.(BufferDecrypter); ok {
+		out, err := bd.DecryptTo(ctx, dst, data)
+		if err != nil {
+			return dst, err
+		}
+		return out, nil
+	}
+	plaintext, err := p.Decrypt(ctx, data)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+		return dst, err
 	}
-	dekGCM, err := cipher.NewGCM(dekBlock)
+	dst = append(dst, plaintext...)
+	clear(plaintext)
+	return dst, nil
+}
+
+// DecryptToWriter decrypts data with p and writes the plaintext to w,
+// returning the number of bytes written. The plaintext is decrypted into a
+// pooled scratch buffer that is wiped after the write, so no long-lived copy
+// remains in memory.
+//
+// The envelope is authenticated as a whole, so nothing is written to w
+// unless the entire ciphertext verifies: a tampered payload never reaches w.
+func DecryptToWriter(ctx context.Context, w io.Writer, data []byte, p Provider) (int64, error) {
+	bp := getBuf()
+	plaintext, err := DecryptTo(ctx, *bp, data, p)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+		putBuf(bp, plaintext)
+		return 0, err
 	}
+	n, err := w.Write(plaintext)
+	clear(plaintext)
+	putBuf(bp, plaintext)
+	return int64(n), err
+}
 
-	plaintext, err := dekGCM.Open(nil, h.dataNonce, ciphertext, []byte(h.keyID))
+// DecryptStream reads an encrypted payload from r, decrypts it with p, and
+// writes the plaintext to w, returning the number of bytes written.
+//
+// Because the envelope is authenticated as a whole, r is read to EOF before
+// anything is written. With the built-in providers the payload is then
+// decrypted in place in the buffer it was read into, so no second buffer is
+// allocated for the plaintext. As with DecryptToWriter, w only ever receives
+// verified plaintext.
+func DecryptStream(ctx context.Context, w io.Writer, r io.Reader, p Provider) (int64, error) {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to decrypt data", ErrDecryptionFailed)
+		return 0, fmt.Errorf("crypto: read ciphertext: %w", err)
+	}
+	ipd, ok := p.(inPlaceDecrypter)
+	if !ok {
+		return DecryptToWriter(ctx, w, data, p)
+	}
+	plaintext, err := ipd.decryptInPlace(ctx, data)
+	if err != nil {
+		return 0, err
 	}
+	defer clear(plaintext)
+	n, err := w.Write(plaintext)
+	return int64(n), err
+}
 
-	return plaintext, nil
+// inPlaceDecrypter is implemented by providers that can overwrite a
+// ciphertext buffer they own with its plaintext.
+type inPlaceDecrypter interface {
+	decryptInPlace(ctx context.Context, data []byte) ([]byte, error)
+}
+
+// decryptEnvelopeInPlace decrypts data, overwriting its payload with the
+// plaintext, and returns the plaintext as a subslice of data. data is
+// modified even on failure.
+func decryptEnvelopeInPlace(data []byte, lookupKey keyLookupFunc) ([]byte, error) {
+	h, ciphertext, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	// ciphertext is the tail of data, so dst starting exactly at it makes
+	// AEAD Open an in-place operation.
+	return openEnvelope(ciphertext[:0], h, ciphertext, lookupKey)
 }
@@ -0,0 +1,57 @@
+package crypto
+
+import "context"
+
+// DecryptEvent describes one decryption attempt by a Codec, passed to the
+// callbacks registered with OnDecrypt. It never carries plaintext or key
+// material.
+type DecryptEvent struct {
+	// Codec is the name of the codec performing the operation.
+	Codec string
+
+	// KeyID is the key ID recorded in the ciphertext header, or empty if the
+	// header could not be parsed.
+	KeyID string
+
+	// Algorithm is the data-encryption algorithm recorded in the header,
+	// e.g. "AES-256-GCM", or empty if the header could not be parsed.
+	Algorithm string
+
+	// CiphertextSize is the size of the encrypted value in bytes.
+	CiphertextSize int
+
+	// Err is the decryption error, or nil on success. AfterDecrypt hook and
+	// inner-codec failures are not decryption failures and are not reported.
+	Err error
+}
+
+// OnDecrypt registers fn to be called after every decryption by a Codec
+// created by NewCodec — successful, failed, or served from the decode cache —
+// so applications can forward decryption events to a SIEM without wrapping
+// the Codec. It may be repeated; callbacks run in registration order.
+//
+// Callbacks run synchronously on the decoding goroutine and must be safe for
+// concurrent use; hand events to a channel or queue if forwarding is slow.
+func OnDecrypt(fn func(ctx context.Context, event DecryptEvent)) CodecOption {
+	return func(o *codecOptions) {
+		if fn != nil {
+			o.onDecrypt = append(o.onDecrypt, fn)
+		}
+	}
+}
+
+// emitDecrypt reports a decryption of data with outcome err to the OnDecrypt
+// callbacks.
+func (c *Codec) emitDecrypt(ctx context.Context, data []byte, err error) {
+	if len(c.onDecrypt) == 0 {
+		return
+	}
+	ev := DecryptEvent{Codec: c.name, CiphertextSize: len(data), Err: err}
+	if hp, _, herr := readHeaderPrefix(data); herr == nil {
+		ev.KeyID = hp.keyID
+		ev.Algorithm = algorithmName(hp.algorithm)
+	}
+	for _, fn := range c.onDecrypt {
+		fn(ctx, ev)
+	}
+}
@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+func TestOnDecrypt(t *testing.T) {
+	ctx := context.Background()
+	var events []DecryptEvent
+	record := func(_ context.Context, ev DecryptEvent) { events = append(events, ev) }
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "key-1"),
+		OnDecrypt(record), WithDecodeCache(10, 0, time.Minute))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+
+	data, err := c.Encode(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var s string
+	if err := c.Decode(ctx, data, &s); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if err := c.Decode(ctx, data, &s); err != nil { // served from the decode cache
+		t.Fatalf("cached Decode: %v", err)
+	}
+	other := mustNewProvider(t, makeKey(32), "key-2")
+	foreign, _ := other.Encrypt(ctx, []byte(`"x"`))
+	if _, err := c.Reverse(ctx, foreign); !IsKeyNotFound(err) {
+		t.Fatalf("Reverse foreign: got %v, want ErrKeyNotFound", err)
+	}
+	_ = c.Decode(ctx, []byte("garbage"), &s)
+
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want 4: %+v", len(events), events)
+	}
+	for i := range 2 {
+		ev := events[i]
+		if ev.Codec != "encrypted:json" || ev.KeyID != "key-1" || ev.Algorithm != "AES-256-GCM" ||
+			ev.CiphertextSize != len(data) || ev.Err != nil {
+			t.Errorf("event %d = %+v", i, ev)
+		}
+	}
+	if ev := events[2]; ev.KeyID != "key-2" || !IsKeyNotFound(ev.Err) {
+		t.Errorf("failed event = %+v", ev)
+	}
+	if ev := events[3]; ev.KeyID != "" || ev.Algorithm != "" || !IsInvalidFormat(ev.Err) {
+		t.Errorf("garbage event = %+v", ev)
+	}
+}
+
+func TestOnDecryptInnerFailureNotReported(t *testing.T) {
+	ctx := context.Background()
+	var events []DecryptEvent
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "key-1"),
+		OnDecrypt(func(_ context.Context, ev DecryptEvent) { events = append(events, ev) }),
+		OnDecrypt(nil))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	data, _ := c.Encode(ctx, "hello")
+	var n int
+	if err := c.Decode(ctx, data, &n); err == nil {
+		t.Fatal("decoding a string into an int: expected error")
+	}
+	if len(events) != 1 || events[0].Err != nil {
+		t.Errorf("events = %+v, want one successful decryption", events)
+	}
+}
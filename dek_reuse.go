@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// maxDEKReuses caps WithDEKReuse's use count. Data nonces are random, and
+// NIST SP 800-38D limits random 96-bit nonces to 2^32 encryptions per key;
+// the cap stays far below that.
+const maxDEKReuses = 1 << 24
+
+// WithDEKReuse lets the provider reuse one DEK for up to maxUses
+// encryptions or maxAge, whichever comes first, instead of generating and
+// KEK-wrapping a fresh DEK per value. Each encryption still uses a fresh
+// random data nonce, and the output format is unchanged, so readers need no
+// configuration.
+//
+// This trades blast radius for throughput: anyone who recovers one DEK can
+// read every value encrypted in its window. The window ends early when the
+// current key changes or the provider is closed. maxUses is capped at 2^24;
+// a maxUses below 2 or a non-positive maxAge leaves reuse disabled.
+func WithDEKReuse(maxUses int, maxAge time.Duration) ProviderOption {
+	return func(o *providerOptions) {
+		o.dekReuseUses = min(maxUses, maxDEKReuses)
+		o.dekReuseAge = maxAge
+	}
+}
+
+// newDEKCache returns a dekCache for the given window, or nil if the window
+// disables reuse.
+func newDEKCache(maxUses int, maxAge time.Duration) *dekCache {
+	if maxUses < 2 || maxAge <= 0 {
+		return nil
+	}
+	return &dekCache{maxUses: maxUses, maxAge: maxAge, now: time.Now}
+}
+
+// dekCache holds the DEK currently being reused. Encrypt calls run
+// concurrently against the ring's lock-free snapshot, so mu serializes the
+// check, replacement, and use count: without it two calls could both take
+// the last use of a window or race to install a new DEK.
+type dekCache struct {
+	maxUses int
+	maxAge  time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	current *wrappedDEK
+	uses    int
+	expires time.Time
+}
+
+// encrypt seals plaintext with the cached DEK for keyID, first replacing it
+// with a DEK wrapped by kek() if it is missing, belongs to another key, or
+// has exhausted its window. kek returns the KEK bytes and a release func.
+func (c *dekCache) encrypt(random io.Reader, alg byte, plaintext []byte, keyID string, kek func() ([]byte, func(), error)) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.current == nil || c.current.keyID != keyID || c.current.alg != alg || c.uses >= c.maxUses || !now.Before(c.expires) {
+		kekBytes, release, err := kek()
+		if err != nil {
+			return nil, err
+		}
+		w, err := newWrappedDEK(random, alg, keyID, kekBytes)
+		release()
+		if err != nil {
+			return nil, err
+		}
+		c.current, c.uses, c.expires = w, 0, now.Add(c.maxAge)
+	}
+	c.uses++
+	return c.current.seal(random, plaintext)
+}
+
+// reset discards the cached DEK so the next encryption wraps a new one.
+func (c *dekCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current = nil
+	c.uses = 0
+}
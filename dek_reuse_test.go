@@ -0,0 +1,173 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// encryptedDEKOf returns the wrapped DEK from a ciphertext header.
+func encryptedDEKOf(t *testing.T, ct []byte) []byte {
+	t.Helper()
+	h, _, err := readHeader(ct)
+	if err != nil {
+		t.Fatalf("readHeader: %v", err)
+	}
+	return h.encryptedDEK
+}
+
+func TestDEKReuseByCount(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewProvider(makeKey(32), "k", WithDEKReuse(3, time.Hour))
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	var deks, nonces [][]byte
+	for i := range 4 {
+		ct, err := p.Encrypt(ctx, []byte("value"))
+		if err != nil {
+			t.Fatalf("Encrypt %d: %v", i, err)
+		}
+		pt, err := p.Decrypt(ctx, ct)
+		if err != nil || string(pt) != "value" {
+			t.Fatalf("Decrypt %d: got %q, %v", i, pt, err)
+		}
+		h, _, _ := readHeader(ct)
+		deks = append(deks, h.encryptedDEK)
+		nonces = append(nonces, h.dataNonce)
+	}
+	if !bytes.Equal(deks[0], deks[1]) || !bytes.Equal(deks[1], deks[2]) {
+		t.Error("DEK should be reused within the window")
+	}
+	if bytes.Equal(deks[2], deks[3]) {
+		t.Error("DEK should be replaced after maxUses encryptions")
+	}
+	for i := range nonces {
+		for j := i + 1; j < len(nonces); j++ {
+			if bytes.Equal(nonces[i], nonces[j]) {
+				t.Errorf("data nonce reused between encryptions %d and %d", i, j)
+			}
+		}
+	}
+}
+
+func TestDEKReuseByAge(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewKeyRingProvider(makeKey(32), "k", 0, WithDEKReuse(100, time.Minute))
+	if err != nil {
+		t.Fatalf("NewKeyRingProvider: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+	now := time.Unix(1_700_000_000, 0)
+	p.(*keyRingProvider).dekReuse.now = func() time.Time { return now }
+
+	a, _ := p.Encrypt(ctx, []byte("a"))
+	now = now.Add(59 * time.Second)
+	b, _ := p.Encrypt(ctx, []byte("b"))
+	now = now.Add(time.Second)
+	c, _ := p.Encrypt(ctx, []byte("c"))
+
+	if !bytes.Equal(encryptedDEKOf(t, a), encryptedDEKOf(t, b)) {
+		t.Error("DEK should be reused before maxAge")
+	}
+	if bytes.Equal(encryptedDEKOf(t, b), encryptedDEKOf(t, c)) {
+		t.Error("DEK should be replaced at maxAge")
+	}
+}
+
+func TestDEKReuseEndsOnRotation(t *testing.T) {
+	ctx := context.Background()
+	ring, err := NewKeyRingProvider(makeKey(32), "v1", 1, WithDEKReuse(100, time.Hour))
+	if err != nil {
+		t.Fatalf("NewKeyRingProvider: %v", err)
+	}
+	defer func() { _ = ring.Close() }()
+	if err := ring.AddKey(makeKey(32), "v2", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+
+	before, _ := ring.Encrypt(ctx, []byte("x"))
+	if err := ring.SetCurrentKey("v2"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	after, err := ring.Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	h, _, _ := readHeader(after)
+	if h.keyID != "v2" || bytes.Equal(encryptedDEKOf(t, before), h.encryptedDEK) {
+		t.Errorf("encryption after rotation used key %q and the old DEK", h.keyID)
+	}
+}
+
+func TestDEKReuseDisabled(t *testing.T) {
+	for _, opt := range []ProviderOption{WithDEKReuse(1, time.Hour), WithDEKReuse(10, 0)} {
+		p, err := NewProvider(makeKey(32), "k", opt)
+		if err != nil {
+			t.Fatalf("NewProvider: %v", err)
+		}
+		if p.(*keyRingProvider).dekReuse != nil {
+			t.Error("degenerate window should leave reuse disabled")
+		}
+		_ = p.Close()
+	}
+}
+
+func TestDEKReuseOptionNotShared(t *testing.T) {
+	ctx := context.Background()
+	opt := WithDEKReuse(10, time.Hour)
+	k2 := makeKey(32)
+	k2[0] ^= 0xFF
+	p1, err := NewProvider(makeKey(32), "k", opt)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer func() { _ = p1.Close() }()
+	p2, err := NewProvider(k2, "k", opt)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer func() { _ = p2.Close() }()
+
+	if _, err := p1.Encrypt(ctx, []byte("x")); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	ct, err := p2.Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := p2.Decrypt(ctx, ct); err != nil {
+		t.Errorf("providers sharing an option must not share a DEK: %v", err)
+	}
+}
+
+func TestDEKReuseConcurrent(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewProvider(makeKey(32), "k", WithDEKReuse(50, time.Hour))
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 50 {
+				ct, err := p.Encrypt(ctx, []byte("concurrent"))
+				if err != nil {
+					t.Errorf("Encrypt: %v", err)
+					return
+				}
+				if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "concurrent" {
+					t.Errorf("Decrypt: %q, %v", pt, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// subkeyInfo prefixes the HKDF info string so derived subkeys cannot
+// collide with other uses of HKDF on the same master key.
+const subkeyInfo = "config-crypto subkey v1\x00"
+
+// subkeySep separates the master key ID from the label in derived key IDs.
+const subkeySep = "#"
+
+// derivingProvider encrypts under HKDF-SHA256 subkeys of a master key ring.
+type derivingProvider struct {
+	master *keyRingProvider
+	label  string
+	suffix string // subkeySep + label
+	closed atomic.Bool
+}
+
+// NewDerivingKeyProvider returns a Provider whose keys are derived from
+// master's keys with HKDF-SHA256, using label as the HKDF info. Every label
+// gets cryptographically independent keys from the same master, so one KMS
+// key can isolate any number of namespaces: register one deriving provider
+// per namespace with WithNamespaceProvider.
+//
+// Ciphertexts carry the key ID "<master key ID>#<label>". Decryption only
+// accepts IDs with this provider's label, so a value written for one label
+// cannot be read through another. Rotating master rotates every subkey, and
+// values under older master keys stay readable while master holds them.
+//
+// master must be built by NewKeyRingProvider, NewProvider, or a KMS
+// package's New. Close does not close master.
+func NewDerivingKeyProvider(master KeyRingProvider, label string) (Provider, error) {
+	p, ok := master.(*keyRingProvider)
+	if !ok {
+		return nil, fmt.Errorf("crypto: NewDerivingKeyProvider: %T does not expose key material", master)
+	}
+	if label == "" {
+		return nil, errors.New("crypto: NewDerivingKeyProvider label must not be empty")
+	}
+	return &derivingProvider{master: p, label: label, suffix: subkeySep + label}, nil
+}
+
+// deriveSubkey returns the HKDF-SHA256 subkey of kek for label.
+func deriveSubkey(kek []byte, label string) ([]byte, error) {
+	return hkdf.Key(sha256.New, kek, nil, subkeyInfo+label, aesKeySize)
+}
+
+// Name returns the derived ID of the current key.
+func (p *derivingProvider) Name() string {
+	return p.master.CurrentKeyID() + p.suffix
+}
+
+// Connect is a no-op.
+func (p *derivingProvider) Connect(_ context.Context) error { return nil }
+
+// Encrypt encrypts plaintext under the subkey of master's current key.
+func (p *derivingProvider) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	s := p.master.state.Load()
+	if s.closed {
+		return nil, ErrProviderClosed
+	}
+	if err := s.checkCurrent(); err != nil {
+		return nil, err
+	}
+	sub, release, err := p.open(s, s.currentID)
+	if err != nil {
+		return nil, err
+	}
+	w, err := newWrappedDEK(p.master.rand, p.master.alg, s.currentID+p.suffix, sub)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	return w.seal(p.master.rand, plaintext)
+}
+
+// Decrypt decrypts ciphertext written by a deriving provider with the same
+// label.
+func (p *derivingProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.DecryptTo(ctx, nil, ciphertext)
+}
+
+// DecryptTo decrypts ciphertext and appends the plaintext to dst.
+func (p *derivingProvider) DecryptTo(_ context.Context, dst, ciphertext []byte) ([]byte, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	s := p.master.state.Load()
+	if s.closed {
+		return nil, ErrProviderClosed
+	}
+	return decryptEnvelopeTo(dst, ciphertext, func(id string) ([]byte, func(), error) {
+		masterID, ok := strings.CutSuffix(id, p.suffix)
+		if !ok || masterID == "" {
+			return nil, nil, fmt.Errorf("%w: %s is not derived for label %q", ErrKeyNotFound, id, p.label)
+		}
+		if k, ok := s.keys[masterID]; ok && !k.usage.canDecrypt() {
+			return nil, nil, fmt.Errorf("%w: %q is %s", ErrKeyUsageDenied, masterID, k.usage)
+		}
+		return p.open(s, masterID)
+	})
+}
+
+// open derives the subkey of master key id. The release func wipes it.
+func (p *derivingProvider) open(s *keyRingState, id string) ([]byte, func(), error) {
+	kek, release, err := s.openKey(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	sub, err := deriveSubkey(kek, p.label)
+	release()
+	if err != nil {
+		return nil, nil, fmt.Errorf("crypto: derive subkey: %w", err)
+	}
+	return sub, func() { clear(sub) }, nil
+}
+
+// HealthCheck reports whether master can encrypt.
+func (p *derivingProvider) HealthCheck(ctx context.Context) error {
+	if p.closed.Load() {
+		return ErrProviderClosed
+	}
+	return p.master.HealthCheck(ctx)
+}
+
+// Close stops this provider; master stays open. Safe to call multiple times.
+func (p *derivingProvider) Close() error {
+	p.closed.Store(true)
+	return nil
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDerivingKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	master := mustNewKeyRingProvider(t, makeKey(32), "m1", 1)
+	a, err := NewDerivingKeyProvider(master, "tenant-a")
+	if err != nil {
+		t.Fatalf("NewDerivingKeyProvider: %v", err)
+	}
+	b, err := NewDerivingKeyProvider(master, "tenant-b")
+	if err != nil {
+		t.Fatalf("NewDerivingKeyProvider: %v", err)
+	}
+
+	ct, err := a.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if id, _ := KeyIDOf(ct); id != "m1#tenant-a" || a.Name() != id {
+		t.Errorf("key ID = %q, Name = %q; want m1#tenant-a", id, a.Name())
+	}
+	if got, err := a.Decrypt(ctx, ct); err != nil || string(got) != "secret" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if _, err := b.Decrypt(ctx, ct); !IsKeyNotFound(err) {
+		t.Errorf("other label Decrypt: got %v, want ErrKeyNotFound", err)
+	}
+	if _, err := master.Decrypt(ctx, ct); !IsKeyNotFound(err) {
+		t.Errorf("master Decrypt: got %v, want ErrKeyNotFound", err)
+	}
+
+	ctB, err := b.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := a.Decrypt(ctx, ctB); err == nil {
+		t.Error("tenant-a decrypted tenant-b ciphertext")
+	}
+
+	// Rotating the master rotates the subkeys; old values stay readable.
+	if _, err := Rotate(master, "m2"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	ct2, err := a.Encrypt(ctx, []byte("new"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if id, _ := KeyIDOf(ct2); id != "m2#tenant-a" {
+		t.Errorf("key ID after rotation = %q", id)
+	}
+	if _, err := a.Decrypt(ctx, ct); err != nil {
+		t.Errorf("Decrypt under old master key: %v", err)
+	}
+
+	// Closing a deriving provider leaves the master usable.
+	_ = a.Close()
+	if _, err := a.Encrypt(ctx, []byte("x")); !IsProviderClosed(err) {
+		t.Errorf("Encrypt after Close: got %v, want ErrProviderClosed", err)
+	}
+	if _, err := b.Encrypt(ctx, []byte("x")); err != nil {
+		t.Errorf("sibling Encrypt after Close: %v", err)
+	}
+}
+
+func TestDerivingKeyProviderDeterministic(t *testing.T) {
+	ctx := context.Background()
+	m1 := mustNewKeyRingProvider(t, makeKey(32), "m1", 0)
+	m2 := mustNewKeyRingProvider(t, makeKey(32), "m1", 0)
+	p1, _ := NewDerivingKeyProvider(m1, "ns")
+	p2, _ := NewDerivingKeyProvider(m2, "ns")
+	ct, err := p1.Encrypt(ctx, []byte("v"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if got, err := p2.Decrypt(ctx, ct); err != nil || string(got) != "v" {
+		t.Errorf("same master and label on another process: %q, %v", got, err)
+	}
+}
+
+func TestNewDerivingKeyProviderValidation(t *testing.T) {
+	master := mustNewKeyRingProvider(t, makeKey(32), "m1", 0)
+	if _, err := NewDerivingKeyProvider(master, ""); err == nil {
+		t.Error("empty label accepted")
+	}
+}
@@ -0,0 +1,119 @@
+//go:build windows
+
+package dpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// NativeClient protects and unprotects blobs with CryptProtectData and
+// CryptUnprotectData. Blobs are bound to the current user unless
+// WithLocalMachine is set.
+type NativeClient struct {
+	flags       uint32
+	entropy     []byte
+	description string
+}
+
+// NativeOption configures a NativeClient.
+type NativeOption func(*NativeClient)
+
+// WithLocalMachine binds blobs to the machine rather than the user, so any
+// account on the machine can unprotect them. Use it for services that run
+// under a different account than the installer. Protect access to the blob
+// files with ACLs instead.
+func WithLocalMachine() NativeOption {
+	return func(c *NativeClient) {
+		c.flags |= windows.CRYPTPROTECT_LOCAL_MACHINE
+	}
+}
+
+// WithEntropy mixes an application secret into the protection, so other
+// programs running as the same user cannot unprotect the blobs without it.
+// The same entropy must be used to protect and unprotect.
+func WithEntropy(entropy []byte) NativeOption {
+	return func(c *NativeClient) {
+		c.entropy = entropy
+	}
+}
+
+// WithDescription sets the description stored in blobs created by Protect.
+func WithDescription(description string) NativeOption {
+	return func(c *NativeClient) {
+		c.description = description
+	}
+}
+
+// NewNativeClient creates a NativeClient. It never shows UI.
+func NewNativeClient(opts ...NativeOption) *NativeClient {
+	c := &NativeClient{flags: windows.CRYPTPROTECT_UI_FORBIDDEN}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Protect protects plaintext with CryptProtectData.
+func (c *NativeClient) Protect(_ context.Context, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, errors.New("dpapi: empty plaintext")
+	}
+	var desc *uint16
+	if c.description != "" {
+		var err error
+		if desc, err = windows.UTF16PtrFromString(c.description); err != nil {
+			return nil, fmt.Errorf("dpapi: description: %w", err)
+		}
+	}
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(newBlob(plaintext), desc, newBlob(c.entropy), 0, nil, c.flags, &out); err != nil {
+		return nil, fmt.Errorf("dpapi: CryptProtectData: %w", err)
+	}
+	return takeBlob(&out), nil
+}
+
+// Unprotect recovers the plaintext protected in blob with
+// CryptUnprotectData.
+func (c *NativeClient) Unprotect(_ context.Context, blob []byte) ([]byte, error) {
+	if len(blob) == 0 {
+		return nil, errors.New("dpapi: empty blob")
+	}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(newBlob(blob), nil, newBlob(c.entropy), 0, nil, c.flags, &out); err != nil {
+		return nil, fmt.Errorf("dpapi: CryptUnprotectData: %w", err)
+	}
+	return takeBlob(&out), nil
+}
+
+// newBlob returns a DataBlob pointing at b, or nil for an empty b.
+func newBlob(b []byte) *windows.DataBlob {
+	if len(b) == 0 {
+		return nil
+	}
+	return &windows.DataBlob{Size: uint32(len(b)), Data: &b[0]} // #nosec G115 -- keys and blobs are far below 4 GiB
+}
+
+// takeBlob copies a blob allocated by DPAPI into Go memory, zeroes the
+// original, and frees it.
+func takeBlob(b *windows.DataBlob) []byte {
+	if b.Data == nil {
+		return nil
+	}
+	defer func() { _, _ = windows.LocalFree(windows.Handle(unsafe.Pointer(b.Data))) }()
+	src := unsafe.Slice(b.Data, b.Size)
+	out := make([]byte, len(src))
+	copy(out, src)
+	clear(src)
+	return out
+}
+
+// Compile-time interface checks.
+var (
+	_ Client    = (*NativeClient)(nil)
+	_ Protector = (*NativeClient)(nil)
+)
@@ -0,0 +1,29 @@
+//go:build windows
+
+package dpapi
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestNativeClientRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := NewNativeClient(WithEntropy([]byte("app")), WithDescription("config-crypto test"))
+	key := makeKey(1)
+	blob, err := c.Protect(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Unprotect(ctx, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, key) {
+		t.Error("Unprotect did not return the protected key")
+	}
+	if _, err := NewNativeClient().Unprotect(ctx, blob); err == nil {
+		t.Error("Unprotect without the entropy should fail")
+	}
+}
@@ -0,0 +1,140 @@
+// Package dpapi provides a crypto.Provider whose keys are protected with the
+// Windows Data Protection API, for Windows service deployments without KMS
+// connectivity.
+//
+// Only DPAPI-protected blobs are stored on disk; each is unprotected through
+// a Client at construction time and the plaintext key material is cached. On
+// Windows, NativeClient calls CryptProtectData and CryptUnprotectData, which
+// tie a blob to the current user or, with WithLocalMachine, to the machine:
+//
+//	client := dpapi.NewNativeClient(dpapi.WithLocalMachine())
+//	// once, at install time:
+//	_ = dpapi.ProtectKeyFile(ctx, client, `C:\ProgramData\myapp\kek-1.dpapi`, key)
+//
+//	provider, err := dpapi.New(ctx, client,
+//	    dpapi.WithProtectedKeyFile(`C:\ProgramData\myapp\kek-1.dpapi`, "kek-1"),
+//	)
+//
+// DPAPI-NG (NCryptProtectSecret), which protects to an AD group or other
+// descriptor, can be used by implementing Client around it.
+package dpapi
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// Client unprotects DPAPI blobs.
+type Client interface {
+	// Unprotect returns the plaintext protected in blob. New calls
+	// Unprotect concurrently when several keys are configured.
+	Unprotect(ctx context.Context, blob []byte) ([]byte, error)
+}
+
+// Protector produces DPAPI blobs.
+type Protector interface {
+	// Protect returns a blob that Client.Unprotect turns back into
+	// plaintext.
+	Protect(ctx context.Context, plaintext []byte) ([]byte, error)
+}
+
+// Option configures the DPAPI provider.
+type Option func(*options)
+
+type options struct {
+	keys []protectedKey
+}
+
+type protectedKey struct {
+	blob []byte
+	path string // read at New time when blob is nil
+	id   string
+}
+
+// WithProtectedKey registers a DPAPI-protected AES-256 key.
+// The id identifies this key in the config-crypto system.
+//
+// The first key registered sets the current key used for new encryptions.
+// Subsequent keys are available for decryption during key rotation.
+func WithProtectedKey(blob []byte, id string) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, protectedKey{blob: blob, id: id})
+	}
+}
+
+// WithProtectedKeyFile is like WithProtectedKey but reads the blob from
+// path, e.g. a file written by ProtectKeyFile, when New is called.
+func WithProtectedKeyFile(path, id string) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, protectedKey{path: path, id: id})
+	}
+}
+
+// New creates a crypto.KeyRingProvider from DPAPI-protected keys.
+//
+// At least one key must be provided via WithProtectedKey or
+// WithProtectedKeyFile. The first key is the current key for new
+// encryptions; additional keys support decryption during key rotation.
+//
+// All keys are unprotected during construction and cached. The Client is
+// not retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("dpapi: Client must not be nil")
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return kmsring.Build(len(o.keys), "dpapi", func(i int) ([]byte, string, error) {
+		k := o.keys[i]
+		blob := k.blob
+		if blob == nil {
+			var err error
+			if blob, err = os.ReadFile(k.path); err != nil { // #nosec G304 -- path is developer-configured
+				return nil, k.id, err
+			}
+		}
+		pt, err := client.Unprotect(ctx, blob)
+		return pt, k.id, err
+	})
+}
+
+// ProtectKeyFile protects key with p and writes the blob to path with mode
+// 0600. It fails if path already exists, so an installer cannot silently
+// replace a key that existing values were encrypted with.
+func ProtectKeyFile(ctx context.Context, p Protector, path string, key []byte) error {
+	if p == nil {
+		return fmt.Errorf("dpapi: Protector must not be nil")
+	}
+	if len(key) != kmsring.KeySize {
+		return fmt.Errorf("%w: got %d bytes", crypto.ErrInvalidKeySize, len(key))
+	}
+	blob, err := p.Protect(ctx, key)
+	if err != nil {
+		return fmt.Errorf("dpapi: protect: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) // #nosec G304 -- path is developer-configured
+	if err != nil {
+		return fmt.Errorf("dpapi: %w", err)
+	}
+	if _, err := f.Write(blob); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return fmt.Errorf("dpapi: write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("dpapi: write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package dpapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// xorClient stands in for DPAPI by XOR-ing with a fixed byte.
+type xorClient struct{}
+
+func (xorClient) Protect(_ context.Context, plaintext []byte) ([]byte, error) {
+	return xor(plaintext), nil
+}
+
+func (xorClient) Unprotect(ctx context.Context, blob []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return xor(blob), nil
+}
+
+func xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ 0x5a
+	}
+	return out
+}
+
+var (
+	_ Client    = xorClient{}
+	_ Protector = xorClient{}
+)
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func TestNew_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kek-2.dpapi")
+	if err := ProtectKeyFile(ctx, xorClient{}, path, makeKey(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(ctx, xorClient{},
+		WithProtectedKeyFile(path, "kek-2"),
+		WithProtectedKey(xor(makeKey(1)), "kek-1"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "kek-2" {
+		t.Errorf("CurrentKeyID = %q, want kek-2", p.CurrentKeyID())
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_NilClient(t *testing.T) {
+	if _, err := New(context.Background(), nil, WithProtectedKey([]byte("x"), "k")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
+
+func TestNew_NoKeys(t *testing.T) {
+	if _, err := New(context.Background(), xorClient{}); err == nil {
+		t.Error("expected error when no keys are configured")
+	}
+}
+
+func TestNew_MissingFile(t *testing.T) {
+	_, err := New(context.Background(), xorClient{},
+		WithProtectedKeyFile(filepath.Join(t.TempDir(), "missing"), "k"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestNew_InvalidKeySize(t *testing.T) {
+	_, err := New(context.Background(), xorClient{}, WithProtectedKey(xor(make([]byte, 16)), "k"))
+	if err == nil || !strings.Contains(err.Error(), "32") {
+		t.Errorf("expected key size error, got %v", err)
+	}
+}
+
+func TestProtectKeyFile_NoOverwrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "kek.dpapi")
+	if err := ProtectKeyFile(ctx, xorClient{}, path, makeKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ProtectKeyFile(ctx, xorClient{}, path, makeKey(2)); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected fs.ErrExist, got %v", err)
+	}
+	if err := ProtectKeyFile(ctx, xorClient{}, path+"2", make([]byte, 16)); !crypto.IsInvalidKeySize(err) {
+		t.Errorf("expected ErrInvalidKeySize, got %v", err)
+	}
+}
+
+func TestNew_PlaintextNotLeftInBlob(t *testing.T) {
+	blob := xor(makeKey(1))
+	orig := bytes.Clone(blob)
+	p, err := New(context.Background(), xorClient{}, WithProtectedKey(blob, "k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = p.Close()
+	if !bytes.Equal(blob, orig) {
+		t.Error("New modified the caller's blob")
+	}
+}
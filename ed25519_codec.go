@@ -0,0 +1,244 @@
+package crypto
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"maps"
+
+	"github.com/rbaliyan/config/codec"
+)
+
+// Ed25519Codec wraps an inner codec with an Ed25519 signature. Unlike
+// SignedCodec, verification needs only the producer's public key, so
+// consumers can check which producer wrote a value without holding any
+// secret that would let them write one.
+//
+// A producer is configured with WithSigningKey; consumers are configured
+// with one WithVerificationKey per trusted producer key. Signatures made
+// with an unknown key ID fail with ErrKeyNotFound; a signature that does
+// not verify fails with ErrSignatureInvalid.
+//
+// By default the signed payload is stored in the clear. WithSignThenEncrypt
+// additionally encrypts the signed payload with a Provider, so the value is
+// confidential and its origin is still verifiable after decryption.
+//
+// Wire format (before optional encryption):
+//
+//	[2B magic "SG"] [1B version = 0x01] [1B alg = 0x02 Ed25519]
+//	[1B key_id_len] [NB key_id] [64B signature] [remaining: inner codec output]
+//
+// The signature covers every header byte before it plus the payload.
+//
+// Ed25519Codec is safe for concurrent use if the underlying Provider and
+// inner codec are safe for concurrent use.
+type Ed25519Codec struct {
+	inner      codec.Codec
+	signingID  string
+	signingKey ed25519.PrivateKey
+	verifiers  map[string]ed25519.PublicKey
+	encryptor  Provider
+	name       string
+}
+
+// Compile-time interface checks.
+var (
+	_ codec.Codec       = (*Ed25519Codec)(nil)
+	_ codec.Transformer = (*Ed25519Codec)(nil)
+)
+
+// WithSigningKey sets the Ed25519 private key NewEd25519Codec signs with and
+// the key ID recorded in each payload. The matching public key is registered
+// for verification automatically. The key is copied.
+func WithSigningKey(id string, key ed25519.PrivateKey) CodecOption {
+	return func(o *codecOptions) {
+		o.signingID = id
+		o.signingKey = append(ed25519.PrivateKey(nil), key...)
+	}
+}
+
+// WithVerificationKey registers an Ed25519 public key that NewEd25519Codec
+// accepts signatures from. It may be repeated to trust several producers or
+// to keep old keys valid across a signing-key rotation. The key is copied.
+func WithVerificationKey(id string, key ed25519.PublicKey) CodecOption {
+	return func(o *codecOptions) {
+		if o.verifyKeys == nil {
+			o.verifyKeys = make(map[string]ed25519.PublicKey)
+		}
+		o.verifyKeys[id] = append(ed25519.PublicKey(nil), key...)
+	}
+}
+
+// WithSignThenEncrypt makes NewEd25519Codec encrypt the signed payload with p.
+// The codec name gains an "encrypted:" segment so tooling that looks for
+// encrypted codecs, such as the rotation sub-package, picks it up.
+func WithSignThenEncrypt(p Provider) CodecOption {
+	return func(o *codecOptions) {
+		o.encryptWith = p
+	}
+}
+
+// NewEd25519Codec creates a signing codec that wraps the given inner codec.
+// The codec name is "signed-ed25519:<inner>", or
+// "encrypted:signed-ed25519:<inner>" with WithSignThenEncrypt; the
+// WithClientCodec and WithCodecPrefix options are honoured.
+//
+// At least one of WithSigningKey or WithVerificationKey is required. A codec
+// without a signing key is verify-only and its Encode returns an error.
+func NewEd25519Codec(inner codec.Codec, opts ...CodecOption) (*Ed25519Codec, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("crypto: NewEd25519Codec inner codec is nil")
+	}
+
+	o := &codecOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	verifiers := maps.Clone(o.verifyKeys)
+	if verifiers == nil {
+		verifiers = make(map[string]ed25519.PublicKey)
+	}
+	if o.signingKey != nil {
+		if len(o.signingKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("crypto: NewEd25519Codec signing key must be %d bytes, got %d", ed25519.PrivateKeySize, len(o.signingKey))
+		}
+		pub, _ := o.signingKey.Public().(ed25519.PublicKey)
+		if existing, ok := verifiers[o.signingID]; ok && !existing.Equal(pub) {
+			return nil, fmt.Errorf("crypto: NewEd25519Codec verification key %q does not match signing key", o.signingID)
+		}
+		verifiers[o.signingID] = pub
+	}
+	if len(verifiers) == 0 {
+		return nil, fmt.Errorf("crypto: NewEd25519Codec requires a signing or verification key")
+	}
+	for id, key := range verifiers {
+		if id == "" {
+			return nil, fmt.Errorf("crypto: NewEd25519Codec key ID must not be empty")
+		}
+		if len(id) > maxKeyIDLen {
+			return nil, fmt.Errorf("crypto: NewEd25519Codec key ID %q too long (%d bytes, max %d)", id, len(id), maxKeyIDLen)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("crypto: NewEd25519Codec verification key %q must be %d bytes, got %d", id, ed25519.PublicKeySize, len(key))
+		}
+	}
+
+	name := "signed-ed25519:" + inner.Name()
+	if o.encryptWith != nil {
+		name = "encrypted:" + name
+	}
+	if o.prefix != "" {
+		name = o.prefix + ":" + name
+	}
+
+	return &Ed25519Codec{
+		inner:      inner,
+		signingID:  o.signingID,
+		signingKey: o.signingKey,
+		verifiers:  verifiers,
+		encryptor:  o.encryptWith,
+		name:       name,
+	}, nil
+}
+
+// Name returns the codec name, e.g. "signed-ed25519:json".
+func (c *Ed25519Codec) Name() string {
+	return c.name
+}
+
+// Encode serializes the value using the inner codec, signs the result, and
+// encrypts it if sign-then-encrypt is enabled.
+func (c *Ed25519Codec) Encode(ctx context.Context, v any) ([]byte, error) {
+	payload, err := c.inner.Encode(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: inner encode failed: %w", err)
+	}
+	out, err := c.Transform(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: sign failed: %w", err)
+	}
+	return out, nil
+}
+
+// Decode decrypts the data if sign-then-encrypt is enabled, verifies the
+// signature, then deserializes the payload using the inner codec.
+func (c *Ed25519Codec) Decode(ctx context.Context, data []byte, v any) error {
+	payload, err := c.Reverse(ctx, data)
+	if err != nil {
+		return fmt.Errorf("crypto: verify failed: %w", err)
+	}
+	if err := c.inner.Decode(ctx, payload, v); err != nil {
+		return fmt.Errorf("crypto: inner decode failed: %w", err)
+	}
+	return nil
+}
+
+// Transform signs data and, with sign-then-encrypt, encrypts the result.
+// This implements codec.Transformer for use with codec.NewChain.
+func (c *Ed25519Codec) Transform(ctx context.Context, data []byte) ([]byte, error) {
+	if c.signingKey == nil {
+		return nil, fmt.Errorf("crypto: Ed25519Codec %q has no signing key", c.name)
+	}
+	header, err := appendSigned(sigAlgEd25519, c.signingID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(c.signingKey, signedInput(header, data))
+	signed, err := appendSigned(sigAlgEd25519, c.signingID, sig, data)
+	if err != nil {
+		return nil, err
+	}
+	if c.encryptor == nil {
+		return signed, nil
+	}
+	return c.encryptor.Encrypt(ctx, signed)
+}
+
+// Reverse decrypts data if sign-then-encrypt is enabled, verifies the
+// signature, and returns a copy of the payload.
+// This implements codec.Transformer for use with codec.NewChain.
+func (c *Ed25519Codec) Reverse(ctx context.Context, data []byte) ([]byte, error) {
+	sp, err := c.verify(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return sp.payload, nil
+}
+
+// SignerKeyID reports the key ID that signed data, after decrypting it if
+// sign-then-encrypt is enabled and verifying the signature. Use it to find
+// out which producer wrote a value.
+func (c *Ed25519Codec) SignerKeyID(ctx context.Context, data []byte) (string, error) {
+	sp, err := c.verify(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	return sp.keyID, nil
+}
+
+// verify decrypts data if needed and checks its Ed25519 signature.
+func (c *Ed25519Codec) verify(ctx context.Context, data []byte) (*signedPayload, error) {
+	if c.encryptor != nil {
+		plaintext, err := c.encryptor.Decrypt(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+		data = plaintext
+	}
+	sp, err := parseSigned(data)
+	if err != nil {
+		return nil, err
+	}
+	if sp.alg != sigAlgEd25519 {
+		return nil, fmt.Errorf("%w: unsupported signature algorithm %d", ErrInvalidFormat, sp.alg)
+	}
+	pub, ok := c.verifiers[sp.keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, sp.keyID)
+	}
+	if !ed25519.Verify(pub, signedInput(sp.header, sp.payload), sp.sig) {
+		return nil, ErrSignatureInvalid
+	}
+	return sp, nil
+}
@@ -0,0 +1,194 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+func mustEd25519Key(t *testing.T, seed byte) ed25519.PrivateKey {
+	t.Helper()
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func mustNewEd25519Codec(t *testing.T, opts ...CodecOption) *Ed25519Codec {
+	t.Helper()
+	c, err := NewEd25519Codec(jsoncodec.New(), opts...)
+	if err != nil {
+		t.Fatalf("NewEd25519Codec: %v", err)
+	}
+	return c
+}
+
+func TestEd25519CodecName(t *testing.T) {
+	priv := mustEd25519Key(t, 1)
+	tests := []struct {
+		opts []CodecOption
+		want string
+	}{
+		{[]CodecOption{WithSigningKey("p1", priv)}, "signed-ed25519:json"},
+		{[]CodecOption{WithSigningKey("p1", priv), WithSignThenEncrypt(mustNewProvider(t, makeKey(32), "k"))}, "encrypted:signed-ed25519:json"},
+		{[]CodecOption{WithSigningKey("p1", priv), WithClientCodec()}, "client:signed-ed25519:json"},
+	}
+	for _, tt := range tests {
+		if got := mustNewEd25519Codec(t, tt.opts...).Name(); got != tt.want {
+			t.Errorf("Name() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNewEd25519CodecValidation(t *testing.T) {
+	priv := mustEd25519Key(t, 1)
+	other := mustEd25519Key(t, 2)
+	cases := map[string][]CodecOption{
+		"no keys":           nil,
+		"short signing key": {WithSigningKey("p1", priv[:10])},
+		"short public key":  {WithVerificationKey("p1", make([]byte, 5))},
+		"empty key ID":      {WithSigningKey("", priv)},
+		"long key ID":       {WithVerificationKey(strings.Repeat("k", 256), priv.Public().(ed25519.PublicKey))},
+		"mismatched key":    {WithSigningKey("p1", priv), WithVerificationKey("p1", other.Public().(ed25519.PublicKey))},
+	}
+	for name, opts := range cases {
+		if _, err := NewEd25519Codec(jsoncodec.New(), opts...); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := NewEd25519Codec(nil, WithSigningKey("p1", priv)); err == nil {
+		t.Error("expected error for nil inner codec")
+	}
+}
+
+func TestEd25519CodecVerifyWithPublicKeyOnly(t *testing.T) {
+	ctx := context.Background()
+	priv := mustEd25519Key(t, 1)
+	producer := mustNewEd25519Codec(t, WithSigningKey("producer-a", priv))
+	consumer := mustNewEd25519Codec(t, WithVerificationKey("producer-a", priv.Public().(ed25519.PublicKey)))
+
+	data, err := producer.Encode(ctx, map[string]int{"replicas": 3})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"replicas":3`)) {
+		t.Errorf("signed payload should stay readable: %q", data)
+	}
+
+	var got map[string]int
+	if err := consumer.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got["replicas"] != 3 {
+		t.Errorf("Decode: got %v", got)
+	}
+	if id, err := consumer.SignerKeyID(ctx, data); err != nil || id != "producer-a" {
+		t.Errorf("SignerKeyID = %q, %v; want producer-a", id, err)
+	}
+
+	if _, err := consumer.Encode(ctx, "x"); err == nil {
+		t.Error("verify-only codec should not encode")
+	}
+}
+
+func TestEd25519CodecTamperAndUnknownSigner(t *testing.T) {
+	ctx := context.Background()
+	a := mustEd25519Key(t, 1)
+	b := mustEd25519Key(t, 2)
+	producer := mustNewEd25519Codec(t, WithSigningKey("a", a))
+	consumer := mustNewEd25519Codec(t, WithVerificationKey("a", a.Public().(ed25519.PublicKey)))
+
+	data, err := producer.Encode(ctx, "value")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var got string
+	tampered := bytes.Replace(data, []byte("value"), []byte("vaLue"), 1)
+	if err := consumer.Decode(ctx, tampered, &got); !IsSignatureInvalid(err) {
+		t.Errorf("Decode tampered: got %v, want ErrSignatureInvalid", err)
+	}
+
+	// A producer the consumer does not trust.
+	rogue := mustNewEd25519Codec(t, WithSigningKey("b", b))
+	data, err = rogue.Encode(ctx, "value")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if err := consumer.Decode(ctx, data, &got); !IsKeyNotFound(err) {
+		t.Errorf("Decode from unknown signer: got %v, want ErrKeyNotFound", err)
+	}
+
+	// A rogue key claiming a trusted key ID.
+	impostor := mustNewEd25519Codec(t, WithSigningKey("a", b))
+	data, err = impostor.Encode(ctx, "value")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if err := consumer.Decode(ctx, data, &got); !IsSignatureInvalid(err) {
+		t.Errorf("Decode from impostor: got %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestEd25519CodecSignThenEncrypt(t *testing.T) {
+	ctx := context.Background()
+	priv := mustEd25519Key(t, 1)
+	p := mustNewProvider(t, makeKey(32), "kek")
+	producer := mustNewEd25519Codec(t, WithSigningKey("producer-a", priv), WithSignThenEncrypt(p))
+	consumer := mustNewEd25519Codec(t,
+		WithVerificationKey("producer-a", priv.Public().(ed25519.PublicKey)),
+		WithSignThenEncrypt(p))
+
+	data, err := producer.Encode(ctx, "top secret")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if bytes.Contains(data, []byte("top secret")) {
+		t.Error("sign-then-encrypt output contains plaintext")
+	}
+	if string(data[:2]) != magic {
+		t.Errorf("sign-then-encrypt output is not an envelope: % x", data[:2])
+	}
+
+	var got string
+	if err := consumer.Decode(ctx, data, &got); err != nil || got != "top secret" {
+		t.Fatalf("Decode: got %q, %v", got, err)
+	}
+	if id, err := consumer.SignerKeyID(ctx, data); err != nil || id != "producer-a" {
+		t.Errorf("SignerKeyID = %q, %v; want producer-a", id, err)
+	}
+
+	// Anyone holding the KEK can encrypt, but not forge the signature.
+	plain, err := p.Decrypt(ctx, data)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	forged, err := p.Encrypt(ctx, bytes.Replace(plain, []byte("top secret"), []byte("top SECRET"), 1))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if err := consumer.Decode(ctx, forged, &got); !IsSignatureInvalid(err) {
+		t.Errorf("Decode forged: got %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestEd25519CodecRejectsHMACPayload(t *testing.T) {
+	ctx := context.Background()
+	hmacCodec := testSignedCodec(t)
+	data, err := hmacCodec.Encode(ctx, "x")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	c := mustNewEd25519Codec(t, WithSigningKey("test-key", mustEd25519Key(t, 1)))
+	if _, err := c.Reverse(ctx, data); !IsInvalidFormat(err) {
+		t.Errorf("Reverse HMAC payload: got %v, want ErrInvalidFormat", err)
+	}
+
+	signed, err := c.Encode(ctx, "x")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := hmacCodec.Reverse(ctx, signed); !IsInvalidFormat(err) {
+		t.Errorf("SignedCodec.Reverse Ed25519 payload: got %v, want ErrInvalidFormat", err)
+	}
+}
@@ -2,77 +2,149 @@ package crypto
 
 import (
 	"bytes"
-	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
 	"fmt"
 	"io"
 )
 
 // encryptEnvelope encrypts plaintext using envelope encryption with the given KEK.
 // A random DEK is generated per call, encrypted with the KEK, and prepended
-// to the output in v2 format.
-func encryptEnvelope(plaintext []byte, keyID string, kekBytes []byte) ([]byte, error) {
+// to the output in v2 format. The DEK and both nonces are read from random.
+// alg selects the AEAD used for both the DEK wrap and the data.
+func encryptEnvelope(random io.Reader, alg byte, plaintext []byte, keyID string, kekBytes []byte) ([]byte, error) {
+	w, err := newWrappedDEK(random, alg, keyID, kekBytes)
+	if err != nil {
+		return nil, err
+	}
+	return w.seal(random, plaintext)
+}
+
+// wrappedDEK is a DEK ready to encrypt data, together with its KEK-wrapped
+// form for the header. The raw DEK is cleared once the AEAD is built.
+type wrappedDEK struct {
+	alg          byte
+	keyID        string
+	aead         cipher.AEAD
+	dekNonce     []byte
+	encryptedDEK []byte
+	recipients   []recipient
+	remote       bool // encryptedDEK is a KeyWrapper's output
+}
+
+// recipientKEK is an additional KEK the DEK is wrapped under.
+type recipientKEK struct {
+	keyID string
+	kek   []byte
+}
+
+// newWrappedDEK generates a random DEK and wraps it with the KEK, using the
+// key ID as AAD.
+func newWrappedDEK(random io.Reader, alg byte, keyID string, kekBytes []byte) (*wrappedDEK, error) {
+	return newMultiWrappedDEK(random, alg, keyID, kekBytes, nil)
+}
+
+// newMultiWrappedDEK is newWrappedDEK, additionally wrapping the DEK under
+// each of extra so any one of the KEKs can later unwrap it. With extra
+// recipients, seal emits the multi-recipient format.
+func newMultiWrappedDEK(random io.Reader, alg byte, keyID string, kekBytes []byte, extra []recipientKEK) (*wrappedDEK, error) {
 	if len(kekBytes) != aesKeySize {
 		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidKeySize, len(kekBytes))
 	}
+	for _, r := range extra {
+		if len(r.kek) != aesKeySize {
+			return nil, fmt.Errorf("%w: recipient %q: got %d bytes", ErrInvalidKeySize, r.keyID, len(r.kek))
+		}
+	}
 
 	// Generate random DEK.
 	dek := make([]byte, aesKeySize)
-	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+	if _, err := io.ReadFull(random, dek); err != nil {
 		return nil, fmt.Errorf("crypto: failed to generate DEK: %w", err)
 	}
 	defer clear(dek)
 
 	// Encrypt DEK with KEK, using key ID as AAD.
-	kekBlock, err := aes.NewCipher(kekBytes)
+	kekAEAD, err := newAEAD(alg, kekBytes)
 	if err != nil {
 		return nil, fmt.Errorf("crypto: failed to create KEK cipher: %w", err)
 	}
-	kekGCM, err := cipher.NewGCM(kekBlock)
-	if err != nil {
-		return nil, fmt.Errorf("crypto: failed to create KEK GCM: %w", err)
-	}
 
 	dekNonce := make([]byte, gcmNonceSize)
-	if _, err := io.ReadFull(rand.Reader, dekNonce); err != nil {
+	if _, err := io.ReadFull(random, dekNonce); err != nil {
 		return nil, fmt.Errorf("crypto: failed to generate DEK nonce: %w", err)
 	}
-	encryptedDEK := kekGCM.Seal(nil, dekNonce, dek, []byte(keyID))
+	encryptedDEK := kekAEAD.Seal(nil, dekNonce, dek, []byte(keyID))
 
-	// Encrypt data with DEK.
-	dekBlock, err := aes.NewCipher(dek)
-	if err != nil {
-		return nil, fmt.Errorf("crypto: failed to create DEK cipher: %w", err)
+	var recipients []recipient
+	for _, r := range extra {
+		rAEAD, err := newAEAD(alg, r.kek)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: failed to create KEK cipher for recipient %q: %w", r.keyID, err)
+		}
+		nonce := make([]byte, gcmNonceSize)
+		if _, err := io.ReadFull(random, nonce); err != nil {
+			return nil, fmt.Errorf("crypto: failed to generate DEK nonce: %w", err)
+		}
+		recipients = append(recipients, recipient{
+			keyID:        r.keyID,
+			dekNonce:     nonce,
+			encryptedDEK: rAEAD.Seal(nil, nonce, dek, []byte(r.keyID)),
+		})
 	}
-	dekGCM, err := cipher.NewGCM(dekBlock)
+
+	// Prepare the DEK cipher for the data.
+	dekAEAD, err := newAEAD(alg, dek)
 	if err != nil {
-		return nil, fmt.Errorf("crypto: failed to create DEK GCM: %w", err)
+		return nil, fmt.Errorf("crypto: failed to create DEK cipher: %w", err)
 	}
 
+	return &wrappedDEK{
+		alg:          alg,
+		keyID:        keyID,
+		aead:         dekAEAD,
+		dekNonce:     dekNonce,
+		encryptedDEK: encryptedDEK,
+		recipients:   recipients,
+	}, nil
+}
+
+// seal encrypts plaintext with the DEK under a fresh random nonce and
+// returns the v2 header followed by the ciphertext. The output is allocated
+// once at its final size; the header is written into it and Seal appends
+// the ciphertext in place.
+func (w *wrappedDEK) seal(random io.Reader, plaintext []byte) ([]byte, error) {
 	dataNonce := make([]byte, gcmNonceSize)
-	if _, err := io.ReadFull(rand.Reader, dataNonce); err != nil {
+	if _, err := io.ReadFull(random, dataNonce); err != nil {
 		return nil, fmt.Errorf("crypto: failed to generate data nonce: %w", err)
 	}
-	ciphertext := dekGCM.Seal(nil, dataNonce, plaintext, []byte(keyID))
 
 	// Assemble v2 header + ciphertext.
 	h := &header{
 		version:      formatVersionV2,
 		format:       formatEnvelopeAESGCM,
-		algorithm:    algAES256GCM,
-		keyID:        keyID,
-		dekNonce:     dekNonce,
-		encryptedDEK: encryptedDEK,
+		algorithm:    w.alg,
+		keyID:        w.keyID,
+		dekNonce:     w.dekNonce,
+		encryptedDEK: w.encryptedDEK,
 		dataNonce:    dataNonce,
 	}
 
-	var buf bytes.Buffer
-	buf.Grow(headerSizeV2(keyID, len(encryptedDEK)) + len(ciphertext))
-	if err := writeHeaderV2(&buf, h); err != nil {
+	hdrSize := headerSizeV2(w.keyID, len(w.encryptedDEK))
+	if w.remote {
+		h.format = formatRemoteWrap
+	}
+	if len(w.recipients) > 0 {
+		h.format = formatMultiRecipient
+		h.recipients = w.recipients
+		hdrSize += recipientsSize(w.recipients)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, hdrSize+len(plaintext)+gcmTagSize))
+	if err := writeHeaderV2(buf, h); err != nil {
 		return nil, fmt.Errorf("crypto: failed to write header: %w", err)
 	}
-	buf.Write(ciphertext)
-
-	return buf.Bytes(), nil
+	aad := []byte(w.keyID)
+	if h.format == formatMultiRecipient {
+		aad = buf.Bytes()
+	}
+	return w.aead.Seal(buf.Bytes(), dataNonce, plaintext, aad), nil
 }
@@ -32,6 +32,38 @@ var (
 
 	// ErrDuplicateKeyID is returned from AddKey when the key ID is already present in the ring.
 	ErrDuplicateKeyID = errors.New("crypto: duplicate key ID")
+
+	// ErrSignatureInvalid is returned when a MAC or signature does not verify (tampered or forged data).
+	ErrSignatureInvalid = errors.New("crypto: signature verification failed")
+
+	// ErrPlaintextLeak is returned by the paranoid self-check when encrypted output contains the plaintext.
+	ErrPlaintextLeak = errors.New("crypto: plaintext found in encrypted output")
+
+	// ErrMlockUnavailable is returned when WithRequireMlock is set but memory pages cannot be locked.
+	ErrMlockUnavailable = errors.New("crypto: mlock unavailable")
+
+	// ErrKeyExpired is returned when encrypting with a key whose NotAfter has passed; the
+	// error is a *KeyExpiredError. Unlike ErrKeyNotFound it means the key exists but must be
+	// rotated.
+	ErrKeyExpired = errors.New("crypto: key expired")
+
+	// ErrKeyUsageDenied is returned when a key's usage policy forbids the requested operation.
+	ErrKeyUsageDenied = errors.New("crypto: key usage denied")
+
+	// ErrNoProviderForKeyID is returned by a ProviderMux when no route matches a key ID.
+	ErrNoProviderForKeyID = errors.New("crypto: no provider for key ID")
+
+	// ErrThrottled is returned when a rate limit rejects a key lookup; the error is a *ThrottledError.
+	ErrThrottled = errors.New("crypto: key lookup throttled")
+
+	// ErrWeakKey is returned when key material fails CheckKey; the error is a *WeakKeyError.
+	ErrWeakKey = errors.New("crypto: weak key")
+
+	// ErrNoTenant is returned by a tenant provider when the context carries no tenant ID.
+	ErrNoTenant = errors.New("crypto: no tenant in context")
+
+	// ErrInvalidKDFParams is returned when passphrase KDF parameters are malformed or out of bounds.
+	ErrInvalidKDFParams = errors.New("crypto: invalid KDF parameters")
 )
 
 // IsKeyNotFound returns true if the error is or wraps ErrKeyNotFound.
@@ -83,3 +115,53 @@ func IsNoProviderForNamespace(err error) bool {
 func IsDuplicateKeyID(err error) bool {
 	return errors.Is(err, ErrDuplicateKeyID)
 }
+
+// IsSignatureInvalid returns true if the error is or wraps ErrSignatureInvalid.
+func IsSignatureInvalid(err error) bool {
+	return errors.Is(err, ErrSignatureInvalid)
+}
+
+// IsPlaintextLeak returns true if the error is or wraps ErrPlaintextLeak.
+func IsPlaintextLeak(err error) bool {
+	return errors.Is(err, ErrPlaintextLeak)
+}
+
+// IsMlockUnavailable returns true if the error is or wraps ErrMlockUnavailable.
+func IsMlockUnavailable(err error) bool {
+	return errors.Is(err, ErrMlockUnavailable)
+}
+
+// IsKeyExpired returns true if the error is or wraps ErrKeyExpired.
+func IsKeyExpired(err error) bool {
+	return errors.Is(err, ErrKeyExpired)
+}
+
+// IsKeyUsageDenied returns true if the error is or wraps ErrKeyUsageDenied.
+func IsKeyUsageDenied(err error) bool {
+	return errors.Is(err, ErrKeyUsageDenied)
+}
+
+// IsNoProviderForKeyID returns true if the error is or wraps ErrNoProviderForKeyID.
+func IsNoProviderForKeyID(err error) bool {
+	return errors.Is(err, ErrNoProviderForKeyID)
+}
+
+// IsThrottled returns true if the error is or wraps ErrThrottled.
+func IsThrottled(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+// IsWeakKey returns true if the error is or wraps ErrWeakKey.
+func IsWeakKey(err error) bool {
+	return errors.Is(err, ErrWeakKey)
+}
+
+// IsNoTenant returns true if the error is or wraps ErrNoTenant.
+func IsNoTenant(err error) bool {
+	return errors.Is(err, ErrNoTenant)
+}
+
+// IsInvalidKDFParams returns true if the error is or wraps ErrInvalidKDFParams.
+func IsInvalidKDFParams(err error) bool {
+	return errors.Is(err, ErrInvalidKDFParams)
+}
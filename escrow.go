@@ -0,0 +1,82 @@
+package crypto
+
+import "fmt"
+
+// WithEscrow makes every Encode and Transform of a Codec created by NewCodec
+// additionally wrap the data encryption key under escrow's current key, an
+// offline recovery key. The ciphertext then carries two recipients: the
+// Codec's provider, which decrypts it as usual, and the escrow key, which can
+// recover it even if the primary key is lost or access to it is revoked:
+//
+//	escrow, _ := crypto.NewProvider(recoveryKey, "escrow-2024")
+//	c, _ := crypto.NewCodec(jsoncodec.New(), primary, crypto.WithEscrow(escrow))
+//
+//	// Later, with only the recovery key:
+//	recovery, _ := crypto.NewProvider(recoveryKey, "escrow-2024")
+//	plaintext, _ := recovery.Decrypt(ctx, ciphertext)
+//
+// Escrowed values use the multi-recipient header format, and the whole
+// header is authenticated, so a recipient cannot be stripped without
+// detection. Both the Codec's provider and escrow must be built-in providers
+// (NewProvider or NewKeyRingProvider), since the DEK is wrapped locally;
+// NewCodec fails otherwise. Each escrowed value gets a fresh DEK, so
+// WithDEKReuse on the primary is ignored. The Codec does not close escrow.
+func WithEscrow(escrow Provider) CodecOption {
+	return func(o *codecOptions) {
+		o.escrow = escrow
+	}
+}
+
+// escrowRing returns the escrow provider as a key ring, checking that the
+// Codec's provider can wrap for it.
+func escrowRing(p, escrow Provider) (*keyRingProvider, error) {
+	if _, ok := p.(*keyRingProvider); !ok {
+		return nil, fmt.Errorf("crypto: WithEscrow: provider %T does not expose key material", p)
+	}
+	kr, ok := escrow.(*keyRingProvider)
+	if !ok {
+		return nil, fmt.Errorf("crypto: WithEscrow: escrow %T does not expose key material", escrow)
+	}
+	return kr, nil
+}
+
+// encryptEscrowed encrypts plaintext under the current key, also wrapping
+// the DEK under escrow's current key.
+func (p *keyRingProvider) encryptEscrowed(plaintext []byte, escrow *keyRingProvider) ([]byte, error) {
+	s := p.state.Load()
+	if s.closed {
+		return nil, ErrProviderClosed
+	}
+	if err := s.checkCurrent(); err != nil {
+		return nil, err
+	}
+	es := escrow.state.Load()
+	if es.closed {
+		return nil, fmt.Errorf("crypto: escrow: %w", ErrProviderClosed)
+	}
+	if es.currentID == s.currentID {
+		return nil, fmt.Errorf("crypto: escrow key ID %q matches the primary key ID", es.currentID)
+	}
+
+	kek, release, err := s.openKey(s.currentID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	escrowKEK, escrowRelease, err := es.openKey(es.currentID)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: escrow: %w", err)
+	}
+	defer escrowRelease()
+
+	w, err := newMultiWrappedDEK(p.rand, p.alg, s.currentID, kek, []recipientKEK{{keyID: es.currentID, kek: escrowKEK}})
+	if err != nil {
+		return nil, err
+	}
+	ct, err := w.seal(p.rand, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	s.keys[s.currentID].stats.recordEncrypt()
+	return ct, nil
+}
@@ -0,0 +1,119 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+func escrowCodec(t *testing.T) (*Codec, []byte) {
+	t.Helper()
+	escrowKey := bytes.Repeat([]byte{0x5a}, 32)
+	primary := mustNewProvider(t, makeKey(32), "primary")
+	escrow := mustNewProvider(t, escrowKey, "escrow")
+	c, err := NewCodec(jsoncodec.New(), primary, WithEscrow(escrow))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	return c, escrowKey
+}
+
+func TestEscrowRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c, _ := escrowCodec(t)
+
+	data, err := c.Encode(ctx, "secret")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if data[3] != formatMultiRecipient {
+		t.Errorf("format byte = %#x, want %#x", data[3], formatMultiRecipient)
+	}
+	if id, _ := KeyIDOf(data); id != "primary" {
+		t.Errorf("KeyIDOf = %q, want primary", id)
+	}
+	var got string
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != "secret" {
+		t.Errorf("Decode = %q, want secret", got)
+	}
+}
+
+func TestEscrowRecovery(t *testing.T) {
+	ctx := context.Background()
+	c, escrowKey := escrowCodec(t)
+
+	data, err := c.Encode(ctx, "secret")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	// Only the escrow key is available.
+	recovery := mustNewProvider(t, escrowKey, "escrow")
+	pt, err := recovery.Decrypt(ctx, data)
+	if err != nil {
+		t.Fatalf("Decrypt with escrow key: %v", err)
+	}
+	if string(pt) != `"secret"` {
+		t.Errorf("recovered %q", pt)
+	}
+
+	// Neither key: the primary's lookup error is reported.
+	other := mustNewProvider(t, makeKey(32), "other")
+	if _, err := other.Decrypt(ctx, data); !IsKeyNotFound(err) {
+		t.Errorf("Decrypt with unrelated key: got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestEscrowTamperedRecipient(t *testing.T) {
+	ctx := context.Background()
+	c, escrowKey := escrowCodec(t)
+
+	data, err := c.Encode(ctx, "secret")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	h, _, err := readHeader(data)
+	if err != nil {
+		t.Fatalf("readHeader: %v", err)
+	}
+
+	// Flip a byte of the escrow recipient's nonce. The primary can no longer
+	// open the payload, because the whole header is its AAD.
+	off := headerSizeV2(h.keyID, len(h.encryptedDEK)) - gcmNonceSize + 1 + 1 + len("escrow")
+	tampered := bytes.Clone(data)
+	tampered[off] ^= 0xff
+	if _, err := c.provider.Decrypt(ctx, tampered); !IsDecryptionFailed(err) {
+		t.Errorf("primary Decrypt of tampered header: got %v, want ErrDecryptionFailed", err)
+	}
+	recovery := mustNewProvider(t, escrowKey, "escrow")
+	if _, err := recovery.Decrypt(ctx, tampered); !IsDecryptionFailed(err) {
+		t.Errorf("escrow Decrypt of tampered header: got %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestEscrowRequiresBuiltinProviders(t *testing.T) {
+	p := mustNewProvider(t, makeKey(32), "primary")
+	if _, err := NewCodec(jsoncodec.New(), p, WithEscrow(&failingProvider{})); err == nil {
+		t.Error("NewCodec with non-local escrow: want error")
+	}
+	if _, err := NewCodec(jsoncodec.New(), &failingProvider{}, WithEscrow(p)); err == nil {
+		t.Error("NewCodec with non-local provider: want error")
+	}
+}
+
+func TestEscrowSameKeyID(t *testing.T) {
+	primary := mustNewProvider(t, makeKey(32), "k")
+	escrow := mustNewProvider(t, bytes.Repeat([]byte{1}, 32), "k")
+	c, err := NewCodec(jsoncodec.New(), primary, WithEscrow(escrow))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	if _, err := c.Encode(context.Background(), "x"); err == nil {
+		t.Error("Encode with matching escrow key ID: want error")
+	}
+}
@@ -14,10 +14,10 @@ import (
 func ExampleNewCodec() {
 	ctx := context.Background()
 
-	// 32-byte key for AES-256.
-	key := make([]byte, 32)
-	for i := range key {
-		key[i] = byte(i)
+	// Random 32-byte key for AES-256.
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
 	}
 
 	provider, err := crypto.NewProvider(key, "key-1")
@@ -49,10 +49,34 @@ func ExampleNewCodec() {
 	// Decrypted: my-secret
 }
 
+func ExampleGenerateKeyString() {
+	// Generate a key once and store it in a secret manager ...
+	s, err := crypto.GenerateKeyString(crypto.KeyEncodingBase64)
+	if err != nil {
+		panic(err)
+	}
+
+	// ... then load it at startup.
+	key, err := crypto.ParseKey(s)
+	if err != nil {
+		panic(err)
+	}
+	provider, err := crypto.NewProvider(key, "key-1")
+	clear(key)
+	if err != nil {
+		panic(err)
+	}
+	defer provider.Close()
+	fmt.Println(len(s) > len("base64:"))
+
+	// Output:
+	// true
+}
+
 func ExampleNewCodec_withConfig() {
-	key := make([]byte, 32)
-	for i := range key {
-		key[i] = byte(i)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
 	}
 
 	provider, err := crypto.NewProvider(key, "key-1")
@@ -113,9 +137,9 @@ func (m *mapCache) Stats() config.CacheStats { return config.CacheStats{} }
 func ExampleNewEncryptedCache() {
 	ctx := context.Background()
 
-	key := make([]byte, 32)
-	for i := range key {
-		key[i] = byte(i)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
 	}
 
 	provider, err := crypto.NewProvider(key, "cache-key-v1")
@@ -156,9 +180,9 @@ func ExampleNewEncryptedCache() {
 func ExampleNewKeyRingProvider_rotation() {
 	ctx := context.Background()
 
-	oldKey := make([]byte, 32)
-	for i := range oldKey {
-		oldKey[i] = byte(i)
+	oldKey, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
 	}
 
 	// Encrypt with the old key.
@@ -177,9 +201,9 @@ func ExampleNewKeyRingProvider_rotation() {
 	}
 
 	// Rotate: KeyRingProvider has both keys; current is v2.
-	newKey := make([]byte, 32)
-	for i := range newKey {
-		newKey[i] = byte(i + 100)
+	newKey, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
 	}
 	ring, err := crypto.NewKeyRingProvider(newKey, "key-v2", 2)
 	if err != nil {
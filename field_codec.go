@@ -0,0 +1,464 @@
+package crypto
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rbaliyan/config/codec"
+)
+
+// secretTag is the struct tag that marks a field for field-level encryption.
+// A field is encrypted when the tag value is "true":
+//
+//	type DB struct {
+//	    Host     string `json:"host"`
+//	    Password string `json:"password" secret:"true"`
+//	}
+const secretTag = "secret"
+
+// FieldCodec wraps an inner codec with field-level envelope encryption.
+// Only struct fields tagged `secret:"true"` are encrypted; the rest of the
+// document is written by the inner codec in the clear, so stored values stay
+// readable for diffing and search.
+//
+// On Encode, the value is serialized with the inner codec, each secret field
+// is replaced by the base64-encoded envelope of its JSON serialization, and
+// the resulting document is serialized again with the inner codec. On Decode
+// the process is reversed using the struct tags of the decode target. Each
+// secret field gets its own envelope (and therefore its own DEK).
+//
+// Field names are resolved from the struct tag named after the inner codec
+// ("json", "yaml", "toml"), falling back to a case-insensitive match on the
+// Go field name. Nested structs and pointers to structs are traversed; slices
+// and maps are not — tag the slice or map field itself to encrypt it as a
+// whole.
+//
+// Documents without a static type (e.g. map[string]any) are handled with
+// WithEncryptedPaths and WithEncryptedPathRegexp, which select leaf values by
+// their dot-separated key path. Tagged fields and matching paths are combined;
+// when both select nested values, the outermost selection wins.
+//
+// FieldCodec is safe for concurrent use if the underlying Provider and inner
+// codec are safe for concurrent use.
+type FieldCodec struct {
+	inner    codec.Codec
+	provider Provider
+	name     string
+	patterns []pathMatcher
+}
+
+// Compile-time interface check.
+var _ codec.Codec = (*FieldCodec)(nil)
+
+// NewFieldCodec creates a field-level encrypting codec that wraps the given
+// inner codec. The codec name is "encrypted-fields:<inner>", e.g.
+// "encrypted-fields:json". WithClientCodec and WithCodecPrefix are honoured.
+// Returns an error if inner or provider is nil, or if a WithEncryptedPaths
+// pattern is malformed.
+func NewFieldCodec(inner codec.Codec, p Provider, opts ...CodecOption) (*FieldCodec, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("crypto: NewFieldCodec inner codec is nil")
+	}
+	if p == nil {
+		return nil, fmt.Errorf("crypto: NewFieldCodec provider is nil")
+	}
+
+	o := &codecOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for _, m := range o.fieldPatterns {
+		if g, ok := m.(globMatcher); ok {
+			if err := g.validate(); err != nil {
+				return nil, fmt.Errorf("crypto: NewFieldCodec: %w", err)
+			}
+		}
+	}
+
+	name := "encrypted-fields:" + inner.Name()
+	if o.prefix != "" {
+		name = o.prefix + ":" + name
+	}
+
+	return &FieldCodec{
+		inner:    inner,
+		provider: p,
+		name:     name,
+		patterns: o.fieldPatterns,
+	}, nil
+}
+
+// WithEncryptedPaths selects document leaves for encryption by key path.
+// Only meaningful for NewFieldCodec; NewCodec ignores it.
+//
+// A path is the dot-separated list of map keys leading to a leaf, e.g.
+// "db.password". Each pattern segment is matched against one key with
+// path.Match syntax ("*", "?", "[a-z]"); a "**" segment matches any number of
+// keys, including none:
+//
+//	"secrets.*"     every direct child of "secrets"
+//	"*.password"    "password" one level below the root
+//	"**.password"   "password" at any depth
+//
+// Only leaves are matched: maps are traversed, while scalars and slices are
+// encrypted as a whole. NewFieldCodec rejects malformed patterns, so a typo
+// cannot silently leave secrets in the clear.
+func WithEncryptedPaths(patterns ...string) CodecOption {
+	return func(o *codecOptions) {
+		for _, p := range patterns {
+			o.fieldPatterns = append(o.fieldPatterns, globMatcher(strings.Split(p, ".")))
+		}
+	}
+}
+
+// WithEncryptedPathRegexp is like WithEncryptedPaths but matches the full
+// dot-separated leaf path against re. Anchor the expression (^…$) to avoid
+// accidental partial matches. Only meaningful for NewFieldCodec.
+func WithEncryptedPathRegexp(re *regexp.Regexp) CodecOption {
+	return func(o *codecOptions) {
+		if re != nil {
+			o.fieldPatterns = append(o.fieldPatterns, regexpMatcher{re})
+		}
+	}
+}
+
+// Name returns the codec name, e.g. "encrypted-fields:json".
+func (c *FieldCodec) Name() string {
+	return c.name
+}
+
+// Encode serializes v with the inner codec and encrypts every field tagged
+// `secret:"true"` plus every leaf selected by WithEncryptedPaths or
+// WithEncryptedPathRegexp. When nothing can be selected the plain inner codec
+// output is returned.
+func (c *FieldCodec) Encode(ctx context.Context, v any) ([]byte, error) {
+	plaintext, err := c.inner.Encode(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: inner encode failed: %w", err)
+	}
+
+	tagged := secretFieldPaths(reflect.TypeOf(v), c.inner.Name())
+	if len(tagged) == 0 && len(c.patterns) == 0 {
+		return plaintext, nil
+	}
+	defer clear(plaintext)
+
+	var doc any
+	if err := c.inner.Decode(ctx, plaintext, &doc); err != nil {
+		return nil, fmt.Errorf("crypto: inner decode failed: %w", err)
+	}
+	for _, path := range c.selectPaths(doc, tagged) {
+		if err := sealLeaf(ctx, c.provider, doc, path); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := c.inner.Encode(ctx, doc)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: inner encode failed: %w", err)
+	}
+	return out, nil
+}
+
+// Decode decrypts every secret field and matching leaf of the document and
+// deserializes the result into v using the inner codec.
+func (c *FieldCodec) Decode(ctx context.Context, data []byte, v any) error {
+	tagged := secretFieldPaths(reflect.TypeOf(v), c.inner.Name())
+	if len(tagged) == 0 && len(c.patterns) == 0 {
+		if err := c.inner.Decode(ctx, data, v); err != nil {
+			return fmt.Errorf("crypto: inner decode failed: %w", err)
+		}
+		return nil
+	}
+
+	var doc any
+	if err := c.inner.Decode(ctx, data, &doc); err != nil {
+		return fmt.Errorf("crypto: inner decode failed: %w", err)
+	}
+	for _, path := range c.selectPaths(doc, tagged) {
+		if err := openLeaf(ctx, c.provider, doc, path); err != nil {
+			return err
+		}
+	}
+
+	plaintext, err := c.inner.Encode(ctx, doc)
+	if err != nil {
+		return fmt.Errorf("crypto: inner encode failed: %w", err)
+	}
+	defer clear(plaintext)
+
+	if err := c.inner.Decode(ctx, plaintext, v); err != nil {
+		return fmt.Errorf("crypto: inner decode failed: %w", err)
+	}
+	return nil
+}
+
+// selectPaths merges the struct-tag paths with every leaf of doc matched by
+// the configured patterns. Paths nested below another selected path are
+// dropped so each value is sealed exactly once; because sealed values become
+// string leaves, the same selection is reproduced from the stored document on
+// Decode.
+func (c *FieldCodec) selectPaths(doc any, tagged [][]string) [][]string {
+	selected := append([][]string(nil), tagged...)
+	if len(c.patterns) > 0 {
+		walkLeaves(doc, nil, func(path []string) {
+			for _, m := range c.patterns {
+				if m.match(path) {
+					selected = append(selected, path)
+					return
+				}
+			}
+		})
+	}
+
+	// Shorter paths first so ancestors are kept before their descendants.
+	slices.SortStableFunc(selected, func(a, b []string) int { return len(a) - len(b) })
+	out := selected[:0]
+	for _, p := range selected {
+		if !slices.ContainsFunc(out, func(q []string) bool { return hasPathPrefix(p, q) }) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// walkLeaves calls fn with the key path of every non-map value below doc.
+func walkLeaves(doc any, prefix []string, fn func(path []string)) {
+	m, ok := doc.(map[string]any)
+	if !ok {
+		if len(prefix) > 0 {
+			fn(prefix)
+		}
+		return
+	}
+	for k, v := range m {
+		walkLeaves(v, append(append([]string(nil), prefix...), k), fn)
+	}
+}
+
+// hasPathPrefix reports whether prefix is an ancestor of (or equal to) p.
+// Segments are compared case-insensitively to match lookupLeaf.
+func hasPathPrefix(p, prefix []string) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i := range prefix {
+		if !strings.EqualFold(p[i], prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathMatcher selects document leaves by key path.
+type pathMatcher interface {
+	match(path []string) bool
+}
+
+// globMatcher matches key paths segment by segment; see WithEncryptedPaths.
+type globMatcher []string
+
+func (g globMatcher) match(p []string) bool {
+	return globMatch(g, p)
+}
+
+// validate reports the first segment path.Match cannot parse. Matching an
+// empty name checks the whole segment.
+func (g globMatcher) validate() error {
+	for _, seg := range g {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", strings.Join(g, "."), err)
+		}
+	}
+	return nil
+}
+
+// globMatch matches p against pattern. Patterns are validated by
+// NewFieldCodec, so path.Match errors do not occur here.
+func globMatch(pattern, p []string) bool {
+	if len(pattern) == 0 {
+		return len(p) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(p); i++ {
+			if globMatch(pattern[1:], p[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(p) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], p[0]); err != nil || !ok {
+		return false
+	}
+	return globMatch(pattern[1:], p[1:])
+}
+
+// regexpMatcher matches the dot-joined key path against a regular expression.
+type regexpMatcher struct {
+	re *regexp.Regexp
+}
+
+func (r regexpMatcher) match(p []string) bool {
+	return r.re.MatchString(strings.Join(p, "."))
+}
+
+// sealLeaf replaces the value at path in doc with the base64-encoded envelope
+// of its JSON serialization. Missing and null values are left untouched.
+func sealLeaf(ctx context.Context, p Provider, doc any, path []string) error {
+	parent, key, ok := lookupLeaf(doc, path)
+	if !ok || parent[key] == nil {
+		return nil
+	}
+
+	leaf, err := json.Marshal(parent[key])
+	if err != nil {
+		return fmt.Errorf("crypto: encode field %q: %w", strings.Join(path, "."), err)
+	}
+	defer clear(leaf)
+
+	ciphertext, err := p.Encrypt(ctx, leaf)
+	if err != nil {
+		return fmt.Errorf("crypto: encrypt field %q failed: %w", strings.Join(path, "."), err)
+	}
+	parent[key] = base64.StdEncoding.EncodeToString(ciphertext)
+	return nil
+}
+
+// openLeaf reverses sealLeaf for the value at path in doc.
+func openLeaf(ctx context.Context, p Provider, doc any, path []string) error {
+	parent, key, ok := lookupLeaf(doc, path)
+	if !ok || parent[key] == nil {
+		return nil
+	}
+
+	encoded, isString := parent[key].(string)
+	if !isString {
+		return fmt.Errorf("%w: field %q is not an encrypted string", ErrInvalidFormat, strings.Join(path, "."))
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("%w: field %q: %v", ErrInvalidFormat, strings.Join(path, "."), err)
+	}
+
+	leaf, err := p.Decrypt(ctx, ciphertext)
+	if err != nil {
+		return fmt.Errorf("crypto: decrypt field %q failed: %w", strings.Join(path, "."), err)
+	}
+	defer clear(leaf)
+
+	var value any
+	if err := json.Unmarshal(leaf, &value); err != nil {
+		return fmt.Errorf("crypto: decode field %q: %w", strings.Join(path, "."), err)
+	}
+	parent[key] = value
+	return nil
+}
+
+// lookupLeaf walks doc along path and returns the map holding the final
+// segment together with the matching key. Keys are matched exactly first and
+// then case-insensitively, mirroring how encoding/json resolves field names.
+func lookupLeaf(doc any, path []string) (map[string]any, string, bool) {
+	cur := doc
+	for i, seg := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, "", false
+		}
+		key, ok := matchKey(m, seg)
+		if !ok {
+			return nil, "", false
+		}
+		if i == len(path)-1 {
+			return m, key, true
+		}
+		cur = m[key]
+	}
+	return nil, "", false
+}
+
+// matchKey returns the key in m that corresponds to name.
+func matchKey(m map[string]any, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// secretFieldPaths returns the document paths of every field tagged
+// `secret:"true"` reachable from t. tagName selects the struct tag used to
+// derive document keys (usually the inner codec name).
+func secretFieldPaths(t reflect.Type, tagName string) [][]string {
+	var paths [][]string
+	collectSecretPaths(t, tagName, nil, map[reflect.Type]bool{}, &paths)
+	return paths
+}
+
+func collectSecretPaths(t reflect.Type, tagName string, prefix []string, seen map[reflect.Type]bool, out *[][]string) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, skip := fieldKey(f, tagName)
+		if skip {
+			continue
+		}
+
+		// Untagged embedded structs are flattened into the parent document.
+		if f.Anonymous && name == "" {
+			collectSecretPaths(f.Type, tagName, prefix, seen, out)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		path := append(append([]string(nil), prefix...), name)
+		if f.Tag.Get(secretTag) == "true" {
+			*out = append(*out, path)
+			continue
+		}
+		collectSecretPaths(f.Type, tagName, path, seen, out)
+	}
+}
+
+// fieldKey returns the document key declared for f by the tagName struct tag.
+// An empty name means the tag did not set one; skip reports a "-" tag.
+func fieldKey(f reflect.StructField, tagName string) (name string, skip bool) {
+	tag, ok := f.Tag.Lookup(tagName)
+	if !ok {
+		return "", false
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	if name == "-" {
+		return "", true
+	}
+	return name, false
+}
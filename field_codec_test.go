@@ -0,0 +1,358 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+	yamlcodec "github.com/rbaliyan/config/codec/yaml"
+)
+
+type fieldTestCreds struct {
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password" yaml:"password" secret:"true"`
+}
+
+type fieldTestConfig struct {
+	Host   string         `json:"host" yaml:"host"`
+	Port   int            `json:"port" yaml:"port"`
+	APIKey string         `json:"api_key" yaml:"api_key" secret:"true"`
+	Creds  fieldTestCreds `json:"creds" yaml:"creds"`
+	Tokens []string       `json:"tokens,omitempty" yaml:"tokens,omitempty" secret:"true"`
+}
+
+func testFieldCodec(t *testing.T) *FieldCodec {
+	t.Helper()
+	c, err := NewFieldCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "test-key"))
+	if err != nil {
+		t.Fatalf("NewFieldCodec: %v", err)
+	}
+	return c
+}
+
+func TestFieldCodecName(t *testing.T) {
+	c := testFieldCodec(t)
+	if c.Name() != "encrypted-fields:json" {
+		t.Errorf("Name() = %q, want %q", c.Name(), "encrypted-fields:json")
+	}
+
+	c, err := NewFieldCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "k"), WithClientCodec())
+	if err != nil {
+		t.Fatalf("NewFieldCodec: %v", err)
+	}
+	if c.Name() != "client:encrypted-fields:json" {
+		t.Errorf("Name() = %q, want %q", c.Name(), "client:encrypted-fields:json")
+	}
+}
+
+func TestNewFieldCodecNilArgs(t *testing.T) {
+	if _, err := NewFieldCodec(nil, mustNewProvider(t, makeKey(32), "k")); err == nil {
+		t.Error("expected error for nil inner codec")
+	}
+	if _, err := NewFieldCodec(jsoncodec.New(), nil); err == nil {
+		t.Error("expected error for nil provider")
+	}
+}
+
+func TestFieldCodecRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := testFieldCodec(t)
+
+	original := fieldTestConfig{
+		Host:   "db.internal",
+		Port:   5432,
+		APIKey: "sk-very-secret",
+		Creds:  fieldTestCreds{User: "admin", Password: "hunter2-password"},
+		Tokens: []string{"token-one-secret", "token-two-secret"},
+	}
+	data, err := c.Encode(ctx, original)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("stored document is not valid JSON: %v", err)
+	}
+	assertSealedField(t, doc, "api_key")
+	assertSealedField(t, doc, "creds", "password")
+	assertSealedField(t, doc, "tokens")
+	for _, secret := range []string{"sk-very-secret", "hunter2-password", "token-one-secret", "token-two-secret"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("encoded document contains secret %q", secret)
+		}
+	}
+	for _, clear := range []string{"db.internal", "5432", "admin"} {
+		if !bytes.Contains(data, []byte(clear)) {
+			t.Errorf("encoded document does not contain clear field %q", clear)
+		}
+	}
+
+	var got fieldTestConfig
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.Host != original.Host || got.Port != original.Port || got.APIKey != original.APIKey ||
+		got.Creds != original.Creds || len(got.Tokens) != 2 || got.Tokens[1] != "token-two-secret" {
+		t.Errorf("Decode: got %+v, want %+v", got, original)
+	}
+}
+
+// assertSealedField checks that the value at path in doc is a base64
+// envelope under the test key rather than plaintext.
+func assertSealedField(t *testing.T, doc map[string]any, path ...string) {
+	t.Helper()
+	var cur any = doc
+	for _, seg := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			t.Fatalf("%s: parent of %q is %T, want object", strings.Join(path, "."), seg, cur)
+		}
+		cur = m[seg]
+	}
+	s, ok := cur.(string)
+	if !ok {
+		t.Errorf("%s = %v, want envelope string", strings.Join(path, "."), cur)
+		return
+	}
+	ct, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Errorf("%s is not base64: %v", strings.Join(path, "."), err)
+		return
+	}
+	if id, err := KeyIDOf(ct); err != nil || id != "test-key" {
+		t.Errorf("%s: KeyIDOf = %q, %v; want envelope under test-key", strings.Join(path, "."), id, err)
+	}
+}
+
+func TestFieldCodecDocumentShape(t *testing.T) {
+	ctx := context.Background()
+	c := testFieldCodec(t)
+
+	data, err := c.Encode(ctx, &fieldTestConfig{Host: "h", APIKey: "k"})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("stored document is not valid JSON: %v", err)
+	}
+	if doc["host"] != "h" {
+		t.Errorf("host = %v, want h", doc["host"])
+	}
+	if s, ok := doc["api_key"].(string); !ok || s == "k" {
+		t.Errorf("api_key = %v, want ciphertext string", doc["api_key"])
+	}
+	if _, ok := doc["tokens"]; ok {
+		t.Error("omitted secret field should stay omitted")
+	}
+}
+
+func TestFieldCodecNoSecretFields(t *testing.T) {
+	ctx := context.Background()
+	c := testFieldCodec(t)
+
+	data, err := c.Encode(ctx, map[string]any{"a": "b"})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	want, _ := jsoncodec.New().Encode(ctx, map[string]any{"a": "b"})
+	if !bytes.Equal(data, want) {
+		t.Errorf("Encode without secret fields: got %s, want %s", data, want)
+	}
+}
+
+func TestFieldCodecWrongKey(t *testing.T) {
+	ctx := context.Background()
+	c := testFieldCodec(t)
+	data, err := c.Encode(ctx, fieldTestConfig{APIKey: "secret"})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	other, err := NewFieldCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "other-key"))
+	if err != nil {
+		t.Fatalf("NewFieldCodec: %v", err)
+	}
+	var got fieldTestConfig
+	if err := other.Decode(ctx, data, &got); !IsKeyNotFound(err) {
+		t.Errorf("Decode with wrong key: got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestFieldCodecTamperedField(t *testing.T) {
+	ctx := context.Background()
+	c := testFieldCodec(t)
+
+	var got fieldTestConfig
+	err := c.Decode(ctx, []byte(`{"host":"h","api_key":42}`), &got)
+	if !IsInvalidFormat(err) {
+		t.Errorf("Decode non-string secret: got %v, want ErrInvalidFormat", err)
+	}
+	err = c.Decode(ctx, []byte(`{"host":"h","api_key":"!!!"}`), &got)
+	if !IsInvalidFormat(err) {
+		t.Errorf("Decode bad base64: got %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestFieldCodecYAML(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFieldCodec(yamlcodec.New(), mustNewProvider(t, makeKey(32), "test-key"))
+	if err != nil {
+		t.Fatalf("NewFieldCodec: %v", err)
+	}
+
+	original := fieldTestConfig{Host: "yaml-host", Port: 1, Creds: fieldTestCreds{User: "u", Password: "yaml-secret-pass"}}
+	data, err := c.Encode(ctx, original)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if bytes.Contains(data, []byte("yaml-secret-pass")) {
+		t.Error("YAML document contains secret")
+	}
+	var got fieldTestConfig
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.Creds.Password != "yaml-secret-pass" || got.Host != "yaml-host" {
+		t.Errorf("Decode: got %+v", got)
+	}
+}
+
+func TestFieldCodecEncryptedPaths(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFieldCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "test-key"),
+		WithEncryptedPaths("secrets.*", "**.password"))
+	if err != nil {
+		t.Fatalf("NewFieldCodec: %v", err)
+	}
+
+	original := map[string]any{
+		"name": "svc",
+		"db": map[string]any{
+			"host":     "db.internal",
+			"password": "hunter2",
+			"replica":  map[string]any{"password": "replica-pw"},
+		},
+		"secrets": map[string]any{
+			"token": "tok-123",
+			"list":  []any{"a", "b"},
+		},
+	}
+	data, err := c.Encode(ctx, original)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "replica-pw", "tok-123"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("encoded document contains secret %q", secret)
+		}
+	}
+	for _, clear := range []string{"svc", "db.internal"} {
+		if !bytes.Contains(data, []byte(clear)) {
+			t.Errorf("encoded document does not contain clear value %q", clear)
+		}
+	}
+
+	var got map[string]any
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	db := got["db"].(map[string]any)
+	if db["password"] != "hunter2" || db["replica"].(map[string]any)["password"] != "replica-pw" {
+		t.Errorf("db = %v", db)
+	}
+	secrets := got["secrets"].(map[string]any)
+	if secrets["token"] != "tok-123" || len(secrets["list"].([]any)) != 2 {
+		t.Errorf("secrets = %v", secrets)
+	}
+}
+
+func TestFieldCodecInvalidPathPattern(t *testing.T) {
+	_, err := NewFieldCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "k"),
+		WithEncryptedPaths("db.password", "secrets.[a-"))
+	if err == nil {
+		t.Fatal("expected error for malformed path pattern")
+	}
+	if !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("err = %v, want path.ErrBadPattern", err)
+	}
+}
+
+func TestFieldCodecEncryptedPathRegexp(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFieldCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "test-key"),
+		WithEncryptedPathRegexp(regexp.MustCompile(`(^|\.)api_key$`)))
+	if err != nil {
+		t.Fatalf("NewFieldCodec: %v", err)
+	}
+
+	data, err := c.Encode(ctx, map[string]any{"api_key": "k1", "svc": map[string]any{"api_key": "k2", "url": "u"}})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if bytes.Contains(data, []byte(`"k1"`)) || bytes.Contains(data, []byte(`"k2"`)) {
+		t.Errorf("encoded document contains secrets: %s", data)
+	}
+
+	var got map[string]any
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got["api_key"] != "k1" || got["svc"].(map[string]any)["api_key"] != "k2" {
+		t.Errorf("Decode: got %v", got)
+	}
+}
+
+func TestFieldCodecPathsAndTagsOverlap(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFieldCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "test-key"),
+		WithEncryptedPaths("**.password", "api_key"))
+	if err != nil {
+		t.Fatalf("NewFieldCodec: %v", err)
+	}
+
+	original := fieldTestConfig{Host: "h", APIKey: "k", Creds: fieldTestCreds{User: "u", Password: "p"}}
+	data, err := c.Encode(ctx, original)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var got fieldTestConfig
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got.APIKey != "k" || got.Creds.Password != "p" {
+		t.Errorf("Decode: got %+v", got)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"secrets.*", "secrets.token", true},
+		{"secrets.*", "secrets.a.b", false},
+		{"*.password", "db.password", true},
+		{"*.password", "password", false},
+		{"**.password", "password", true},
+		{"**.password", "a.b.c.password", true},
+		{"db.**", "db.x.y", true},
+		{"db.pass*", "db.passphrase", true},
+		{"db.[", "db.x", false},
+	}
+	for _, tt := range tests {
+		got := globMatcher(strings.Split(tt.pattern, ".")).match(strings.Split(tt.path, "."))
+		if got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
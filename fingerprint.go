@@ -0,0 +1,31 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// fingerprintContext domain-separates key fingerprints from other SHA-256
+// uses of key material.
+const fingerprintContext = "config-crypto key fingerprint v1\x00"
+
+// KeyFingerprint returns a stable key ID derived from keyBytes: "sha256:"
+// followed by the first 16 bytes of a domain-separated SHA-256 of the key,
+// hex-encoded. The same key always yields the same ID, in every service and
+// across restarts, and the fingerprint does not reveal the key.
+func KeyFingerprint(keyBytes []byte) string {
+	h := sha256.New()
+	h.Write([]byte(fingerprintContext))
+	h.Write(keyBytes)
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// WithFingerprintKeyID makes NewProvider, NewKeyRingProvider, and the
+// ring's AddKey derive the key ID with KeyFingerprint when the given ID is
+// empty, instead of failing with ErrInvalidKeyID. Explicit IDs are still
+// used as given.
+func WithFingerprintKeyID() ProviderOption {
+	return func(o *providerOptions) {
+		o.fingerprintIDs = true
+	}
+}
@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestKeyFingerprint(t *testing.T) {
+	a := KeyFingerprint(makeKey(32))
+	if a != KeyFingerprint(makeKey(32)) {
+		t.Error("fingerprint is not deterministic")
+	}
+	if !strings.HasPrefix(a, "sha256:") || len(a) != len("sha256:")+32 {
+		t.Errorf("fingerprint %q has unexpected form", a)
+	}
+	other := makeKey(32)
+	other[0] = 0xFF
+	if a == KeyFingerprint(other) {
+		t.Error("different keys share a fingerprint")
+	}
+}
+
+func TestWithFingerprintKeyID(t *testing.T) {
+	ctx := context.Background()
+	key := makeKey(32)
+	p, err := NewProvider(key, "", WithFingerprintKeyID())
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer p.Close()
+	want := KeyFingerprint(key)
+	if p.Name() != want {
+		t.Errorf("Name = %q, want %q", p.Name(), want)
+	}
+	ct, err := p.Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if id, err := KeyIDOf(ct); err != nil || id != want {
+		t.Errorf("KeyIDOf = %q, %v; want %q", id, err, want)
+	}
+
+	ring := p.(KeyRingProvider)
+	k2 := makeKey(32)
+	k2[0] = 0xFF
+	if err := ring.AddKey(k2, "", 1); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if err := ring.SetCurrentKey(KeyFingerprint(k2)); err != nil {
+		t.Errorf("SetCurrentKey(fingerprint): %v", err)
+	}
+	if err := ring.AddKey(k2, "", 2); !IsDuplicateKeyID(err) {
+		t.Errorf("re-adding same key: got %v, want ErrDuplicateKeyID", err)
+	}
+
+	k, err := Rotate(ring, "")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if k.ID != KeyFingerprint(k.Bytes) || ring.CurrentKeyID() != k.ID {
+		t.Errorf("Rotate ID = %q, current %q", k.ID, ring.CurrentKeyID())
+	}
+}
+
+func TestEmptyKeyIDWithoutFingerprint(t *testing.T) {
+	if _, err := NewProvider(makeKey(32), ""); !IsInvalidKeyID(err) {
+		t.Errorf("got %v, want ErrInvalidKeyID", err)
+	}
+}
@@ -20,9 +20,30 @@ const (
 	// formatEnvelopeAESGCM is the v2 format byte indicating local AES-GCM envelope encryption.
 	formatEnvelopeAESGCM = 0x01
 
+	// formatMultiRecipient is the v2 format byte for envelopes whose DEK is
+	// also wrapped under additional KEKs (e.g. an escrow key). The extra
+	// recipients follow the primary encrypted DEK, and the data AAD is the
+	// whole header so no recipient can be stripped or altered.
+	formatMultiRecipient = 0x02
+
+	// formatRemoteWrap is the v2 format byte for envelopes whose DEK was
+	// wrapped by a KeyWrapper (an HSM or KMS) rather than a local KEK. The
+	// encrypted DEK field holds the wrapper's opaque output and the DEK
+	// nonce is unused (zero).
+	formatRemoteWrap = 0x03
+
+	// maxEncryptedDEKLen is the largest encrypted DEK the 2-byte length
+	// field can record.
+	maxEncryptedDEKLen = 1<<16 - 1
+
 	// algAES256GCM identifies AES-256-GCM as the encryption algorithm.
 	algAES256GCM = 0x01
 
+	// algChaCha20Poly1305 identifies ChaCha20-Poly1305 as the encryption
+	// algorithm (v2 only). It has the same key, nonce, and tag sizes as
+	// AES-256-GCM.
+	algChaCha20Poly1305 = 0x02
+
 	// aesKeySize is the required key size in bytes (AES-256).
 	aesKeySize = 32
 
@@ -51,9 +72,29 @@ type header struct {
 	format       byte // v2 only; 0 for v1
 	algorithm    byte
 	keyID        string
-	dekNonce     []byte // 12 bytes
-	encryptedDEK []byte // variable length (48 for local AES-GCM wrap)
-	dataNonce    []byte // 12 bytes
+	dekNonce     []byte      // 12 bytes
+	encryptedDEK []byte      // variable length (48 for local AES-GCM wrap)
+	recipients   []recipient // formatMultiRecipient only
+	dataNonce    []byte      // 12 bytes
+	aad          []byte      // formatMultiRecipient only: header bytes, read side
+}
+
+// recipient is one additional wrapping of the DEK in a multi-recipient
+// envelope. The DEK wrap uses the recipient's key ID as AAD, as for the
+// primary.
+type recipient struct {
+	keyID        string
+	dekNonce     []byte
+	encryptedDEK []byte
+}
+
+// dataAAD returns the AAD for the payload: the key ID for single-recipient
+// envelopes, the full header for multi-recipient ones.
+func (h *header) dataAAD() []byte {
+	if h.format == formatMultiRecipient {
+		return h.aad
+	}
+	return []byte(h.keyID)
 }
 
 // headerSizeV2 returns the total v2 header size in bytes for the given key ID
@@ -63,6 +104,16 @@ func headerSizeV2(keyID string, encDEKLen int) int {
 	return minHeaderSizeV2 + len(keyID) + gcmNonceSize + 2 + encDEKLen + gcmNonceSize
 }
 
+// recipientsSize returns the encoded size of the multi-recipient section:
+// count(1) + per recipient keyIDLen(1) + keyID + dekNonce(12) + encDEKLen(2) + encDEK.
+func recipientsSize(rs []recipient) int {
+	n := 1
+	for _, r := range rs {
+		n += 1 + len(r.keyID) + gcmNonceSize + 2 + len(r.encryptedDEK)
+	}
+	return n
+}
+
 // writeHeaderV2 writes the v2 binary header to w.
 func writeHeaderV2(w io.Writer, h *header) error {
 	if _, err := w.Write([]byte(magic)); err != nil {
@@ -97,6 +148,12 @@ func writeHeaderV2(w io.Writer, h *header) error {
 		return err
 	}
 
+	if h.format == formatMultiRecipient {
+		if err := writeRecipients(w, h.recipients); err != nil {
+			return err
+		}
+	}
+
 	if _, err := w.Write(h.dataNonce); err != nil {
 		return err
 	}
@@ -104,53 +161,157 @@ func writeHeaderV2(w io.Writer, h *header) error {
 	return nil
 }
 
-// readHeader parses the binary header from data, dispatching to v1 or v2
-// based on the version byte. All byte slices in the returned header are
-// defensive copies.
-func readHeader(data []byte) (*header, []byte, error) {
+// writeRecipients writes the multi-recipient section.
+func writeRecipients(w io.Writer, rs []recipient) error {
+	if len(rs) > 255 {
+		return fmt.Errorf("%w: too many recipients (%d, max 255)", ErrInvalidFormat, len(rs))
+	}
+	b := []byte{byte(len(rs))} // #nosec G115 -- count validated above
+	for _, r := range rs {
+		if len(r.keyID) > maxKeyIDLen {
+			return fmt.Errorf("%w: key ID too long (%d bytes, max %d)", ErrInvalidFormat, len(r.keyID), maxKeyIDLen)
+		}
+		b = append(b, byte(len(r.keyID))) // #nosec G115 -- keyID length validated above
+		b = append(b, r.keyID...)
+		b = append(b, r.dekNonce...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(r.encryptedDEK))) // #nosec G115 -- encDEK length fits uint16
+		b = append(b, r.encryptedDEK...)
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// readRecipients parses the multi-recipient section at data[offset:] and
+// returns the recipients and the offset just past them.
+func readRecipients(data []byte, offset int) ([]recipient, int, error) {
+	short := fmt.Errorf("%w: data too short for recipients", ErrInvalidFormat)
+	if len(data) < offset+1 {
+		return nil, 0, short
+	}
+	n := int(data[offset])
+	offset++
+	rs := make([]recipient, 0, n)
+	for range n {
+		if len(data) < offset+1 {
+			return nil, 0, short
+		}
+		idLen := int(data[offset])
+		offset++
+		if len(data) < offset+idLen+gcmNonceSize+2 {
+			return nil, 0, short
+		}
+		r := recipient{keyID: string(data[offset : offset+idLen])}
+		offset += idLen
+		r.dekNonce = append([]byte(nil), data[offset:offset+gcmNonceSize]...)
+		offset += gcmNonceSize
+		encLen := int(binary.BigEndian.Uint16(data[offset : offset+2]))
+		offset += 2
+		if len(data) < offset+encLen {
+			return nil, 0, short
+		}
+		r.encryptedDEK = append([]byte(nil), data[offset:offset+encLen]...)
+		offset += encLen
+		rs = append(rs, r)
+	}
+	return rs, offset, nil
+}
+
+// KeyIDOf returns the key ID recorded in the header of an encrypted value,
+// without decrypting it. Only the leading header fields are read, so the
+// call is cheap for large values; a value truncated after its key ID is
+// reported when it is decrypted. It returns an error wrapping
+// ErrInvalidFormat or ErrUnsupportedFormat if data is not an envelope this
+// package can read.
+func KeyIDOf(data []byte) (string, error) {
+	hp, _, err := readHeaderPrefix(data)
+	if err != nil {
+		return "", err
+	}
+	return hp.keyID, nil
+}
+
+// headerPrefix is the leading part of a header, up to and including the key
+// ID. It is enough to route or label a value without parsing the rest.
+type headerPrefix struct {
+	version   byte
+	format    byte // v2 only; 0 for v1
+	algorithm byte
+	keyID     string
+}
+
+// readHeaderPrefix parses and validates the header fields up to the key ID
+// and returns them with the offset just past the key ID. Nothing after the
+// key ID is read.
+func readHeaderPrefix(data []byte) (headerPrefix, int, error) {
 	if len(data) < minHeaderSizeV1 {
-		return nil, nil, fmt.Errorf("%w: data too short", ErrInvalidFormat)
+		return headerPrefix{}, 0, fmt.Errorf("%w: data too short", ErrInvalidFormat)
 	}
 
 	if string(data[0:2]) != magic {
-		return nil, nil, fmt.Errorf("%w: invalid magic bytes", ErrInvalidFormat)
+		return headerPrefix{}, 0, fmt.Errorf("%w: invalid magic bytes", ErrInvalidFormat)
 	}
 
-	version := data[2]
-	switch version {
+	hp := headerPrefix{version: data[2]}
+	var offset int
+	switch hp.version {
 	case formatVersionV1:
-		return readHeaderV1(data)
+		// v1 prefix: [2B magic][1B version=0x01][1B alg][1B keyIDLen][NB keyID]
+		hp.algorithm = data[3]
+		if hp.algorithm != algAES256GCM {
+			return headerPrefix{}, 0, fmt.Errorf("%w: unsupported algorithm %d", ErrInvalidFormat, hp.algorithm)
+		}
+		offset = minHeaderSizeV1
 	case formatVersionV2:
-		return readHeaderV2(data)
+		// v2 prefix: [2B magic][1B version=0x02][1B format][1B alg][1B keyIDLen][NB keyID]
+		if len(data) < minHeaderSizeV2 {
+			return headerPrefix{}, 0, fmt.Errorf("%w: data too short for v2 header", ErrInvalidFormat)
+		}
+		hp.format = data[3]
+		if hp.format != formatEnvelopeAESGCM && hp.format != formatMultiRecipient && hp.format != formatRemoteWrap {
+			return headerPrefix{}, 0, fmt.Errorf("%w: format byte 0x%02x", ErrUnsupportedFormat, hp.format)
+		}
+		hp.algorithm = data[4]
+		if hp.algorithm != algAES256GCM && hp.algorithm != algChaCha20Poly1305 {
+			return headerPrefix{}, 0, fmt.Errorf("%w: unsupported algorithm %d", ErrInvalidFormat, hp.algorithm)
+		}
+		offset = minHeaderSizeV2
 	default:
-		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, version)
+		return headerPrefix{}, 0, fmt.Errorf("%w: unsupported version %d", ErrInvalidFormat, hp.version)
 	}
-}
 
-// readHeaderV1 parses a v1 header (backward compatibility for DB-stored ciphertext).
-func readHeaderV1(data []byte) (*header, []byte, error) {
-	// v1 layout: [2B magic][1B version=0x01][1B alg][1B keyIDLen][NB keyID]
-	//            [12B dekNonce][48B encryptedDEK][12B dataNonce][remaining ciphertext]
-	h := &header{
-		version: formatVersionV1,
+	keyIDLen := int(data[offset-1])
+	if len(data) < offset+keyIDLen {
+		return headerPrefix{}, 0, fmt.Errorf("%w: data too short for header", ErrInvalidFormat)
 	}
+	hp.keyID = string(data[offset : offset+keyIDLen])
+	return hp, offset + keyIDLen, nil
+}
 
-	h.algorithm = data[3]
-	if h.algorithm != algAES256GCM {
-		return nil, nil, fmt.Errorf("%w: unsupported algorithm %d", ErrInvalidFormat, h.algorithm)
+// readHeader parses the binary header from data, dispatching to v1 or v2
+// based on the version byte. All byte slices in the returned header are
+// defensive copies; the returned payload is a subslice of data, so decoding
+// does not copy the ciphertext.
+func readHeader(data []byte) (*header, []byte, error) {
+	hp, offset, err := readHeaderPrefix(data)
+	if err != nil {
+		return nil, nil, err
 	}
+	h := &header{version: hp.version, format: hp.format, algorithm: hp.algorithm, keyID: hp.keyID}
+	if h.version == formatVersionV1 {
+		return readHeaderV1(h, data, offset)
+	}
+	return readHeaderV2(h, data, offset)
+}
 
-	keyIDLen := int(data[4])
-	offset := minHeaderSizeV1
-
-	needed := keyIDLen + gcmNonceSize + encryptedDEKSize + gcmNonceSize
-	if len(data) < offset+needed {
+// readHeaderV1 parses the rest of a v1 header (backward compatibility for
+// DB-stored ciphertext) from offset, just past the key ID.
+func readHeaderV1(h *header, data []byte, offset int) (*header, []byte, error) {
+	// v1 layout: [2B magic][1B version=0x01][1B alg][1B keyIDLen][NB keyID]
+	//            [12B dekNonce][48B encryptedDEK][12B dataNonce][remaining ciphertext]
+	if len(data) < offset+gcmNonceSize+encryptedDEKSize+gcmNonceSize {
 		return nil, nil, fmt.Errorf("%w: data too short for header", ErrInvalidFormat)
 	}
 
-	h.keyID = string(data[offset : offset+keyIDLen])
-	offset += keyIDLen
-
 	h.dekNonce = append([]byte(nil), data[offset:offset+gcmNonceSize]...)
 	offset += gcmNonceSize
 
@@ -160,45 +321,20 @@ func readHeaderV1(data []byte) (*header, []byte, error) {
 	h.dataNonce = append([]byte(nil), data[offset:offset+gcmNonceSize]...)
 	offset += gcmNonceSize
 
-	ciphertext := make([]byte, len(data)-offset)
-	copy(ciphertext, data[offset:])
-
-	return h, ciphertext, nil
+	return h, data[offset:], nil
 }
 
-// readHeaderV2 parses a v2 header.
-func readHeaderV2(data []byte) (*header, []byte, error) {
+// readHeaderV2 parses the rest of a v2 header from offset, just past the key
+// ID.
+func readHeaderV2(h *header, data []byte, offset int) (*header, []byte, error) {
 	// v2 layout: [2B magic][1B version=0x02][1B format][1B alg][1B keyIDLen][NB keyID]
 	//            [12B dekNonce][2B encDEKLen][MB encDEK][12B dataNonce][remaining ciphertext]
-	if len(data) < minHeaderSizeV2 {
-		return nil, nil, fmt.Errorf("%w: data too short for v2 header", ErrInvalidFormat)
-	}
-
-	h := &header{
-		version: formatVersionV2,
-		format:  data[3],
-	}
-
-	if h.format != formatEnvelopeAESGCM {
-		return nil, nil, fmt.Errorf("%w: format byte 0x%02x", ErrUnsupportedFormat, h.format)
-	}
 
-	h.algorithm = data[4]
-	if h.algorithm != algAES256GCM {
-		return nil, nil, fmt.Errorf("%w: unsupported algorithm %d", ErrInvalidFormat, h.algorithm)
-	}
-
-	keyIDLen := int(data[5])
-	offset := minHeaderSizeV2
-
-	// Need at least: keyID + dekNonce + 2B encDEKLen
-	if len(data) < offset+keyIDLen+gcmNonceSize+2 {
+	// Need at least: dekNonce + 2B encDEKLen
+	if len(data) < offset+gcmNonceSize+2 {
 		return nil, nil, fmt.Errorf("%w: data too short for v2 header", ErrInvalidFormat)
 	}
 
-	h.keyID = string(data[offset : offset+keyIDLen])
-	offset += keyIDLen
-
 	h.dekNonce = append([]byte(nil), data[offset:offset+gcmNonceSize]...)
 	offset += gcmNonceSize
 
@@ -213,11 +349,22 @@ func readHeaderV2(data []byte) (*header, []byte, error) {
 	h.encryptedDEK = append([]byte(nil), data[offset:offset+encDEKLen]...)
 	offset += encDEKLen
 
+	if h.format == formatMultiRecipient {
+		var err error
+		if h.recipients, offset, err = readRecipients(data, offset); err != nil {
+			return nil, nil, err
+		}
+		if len(data) < offset+gcmNonceSize {
+			return nil, nil, fmt.Errorf("%w: data too short for v2 header", ErrInvalidFormat)
+		}
+	}
+
 	h.dataNonce = append([]byte(nil), data[offset:offset+gcmNonceSize]...)
 	offset += gcmNonceSize
 
-	ciphertext := make([]byte, len(data)-offset)
-	copy(ciphertext, data[offset:])
+	if h.format == formatMultiRecipient {
+		h.aad = append([]byte(nil), data[:offset]...)
+	}
 
-	return h, ciphertext, nil
+	return h, data[offset:], nil
 }
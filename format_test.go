@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -192,6 +193,17 @@ func TestReadHeaderV2TruncatedBody(t *testing.T) {
 	}
 }
 
+func TestReadHeaderMultiRecipientTruncated(t *testing.T) {
+	// Recipient count says one, but its stanza is cut short.
+	data := []byte{'E', 'C', formatVersionV2, formatMultiRecipient, algAES256GCM, 0}
+	data = append(data, make([]byte, gcmNonceSize)...)
+	data = append(data, 0, 0) // empty encrypted DEK
+	data = append(data, 1, 3, 'e', 's')
+	if _, _, err := readHeader(data); !IsInvalidFormat(err) {
+		t.Errorf("expected ErrInvalidFormat, got %v", err)
+	}
+}
+
 func TestReadHeaderV2Roundtrip_VarLengthDEK(t *testing.T) {
 	// Construct a header with a non-48-byte wrapped DEK to exercise the
 	// variable-length path.
@@ -233,13 +245,13 @@ func TestWriteHeaderV2KeyIDTooLong(t *testing.T) {
 	}
 }
 
-func TestReadHeaderCiphertextIsolated(t *testing.T) {
+func TestReadHeaderPayloadAliasesInput(t *testing.T) {
 	h := &header{
 		version:      formatVersionV2,
 		format:       formatEnvelopeAESGCM,
 		algorithm:    algAES256GCM,
 		keyID:        "k",
-		dekNonce:     make([]byte, gcmNonceSize),
+		dekNonce:     bytes.Repeat([]byte{7}, gcmNonceSize),
 		encryptedDEK: make([]byte, encryptedDEKSize),
 		dataNonce:    make([]byte, gcmNonceSize),
 	}
@@ -249,17 +261,19 @@ func TestReadHeaderCiphertextIsolated(t *testing.T) {
 	}
 	original := []byte("test-ciphertext")
 	input := append(buf.Bytes(), original...)
-	inputCopy := append([]byte(nil), input...)
 
-	_, ct, err := readHeader(input)
+	// Header fields are copies, but the payload is a subslice of input so
+	// that decoding never copies the ciphertext.
+	got, ct, err := readHeader(input)
 	if err != nil {
 		t.Fatal(err)
 	}
-	for i := range ct {
-		ct[i] = 0xFF
+	if !bytes.Equal(ct, original) || &ct[0] != &input[len(input)-len(original)] {
+		t.Error("payload is not the tail of input")
 	}
-	if !bytes.Equal(input, inputCopy) {
-		t.Error("mutating ciphertext corrupted input")
+	clear(input)
+	if !bytes.Equal(got.dekNonce, h.dekNonce) || got.keyID != "k" {
+		t.Error("clearing input changed the parsed header")
 	}
 }
 
@@ -317,3 +331,77 @@ func TestEncryptedDEKLenBigEndian(t *testing.T) {
 		t.Fatalf("len encoding mismatch: %v", lenBuf)
 	}
 }
+
+func TestEncryptEnvelopeExactCapacity(t *testing.T) {
+	for _, size := range []int{0, 1, 1024, 1 << 20} {
+		out, err := encryptEnvelope(rand.Reader, algAES256GCM, make([]byte, size), "key-1", makeKey(32))
+		if err != nil {
+			t.Fatalf("encryptEnvelope(%d): %v", size, err)
+		}
+		want := headerSizeV2("key-1", encryptedDEKSize) + size + gcmTagSize
+		if len(out) != want || cap(out) != want {
+			t.Errorf("size %d: len=%d cap=%d, want both %d (single exact allocation)", size, len(out), cap(out), want)
+		}
+	}
+}
+
+func TestDecryptEnvelopeReleasesKey(t *testing.T) {
+	kek := makeKey(32)
+	ct, err := encryptEnvelope(rand.Reader, algAES256GCM, []byte("secret"), "key-1", kek)
+	if err != nil {
+		t.Fatalf("encryptEnvelope: %v", err)
+	}
+
+	tampered := bytes.Clone(ct)
+	tampered[len(tampered)-1] ^= 0xFF
+
+	for name, data := range map[string][]byte{"ok": ct, "tampered": tampered, "bad key size": ct} {
+		var key []byte
+		released := 0
+		lookup := func(string) ([]byte, func(), error) {
+			key = bytes.Clone(kek)
+			return key, func() { released++; clear(key) }, nil
+		}
+		if name == "bad key size" {
+			lookup = func(string) ([]byte, func(), error) {
+				key = makeKey(16)
+				return key, func() { released++; clear(key) }, nil
+			}
+		}
+		_, _ = decryptEnvelope(data, lookup)
+		if released != 1 {
+			t.Errorf("%s: release called %d times, want 1", name, released)
+		}
+		if !bytes.Equal(key, make([]byte, len(key))) {
+			t.Errorf("%s: key not wiped after decrypt", name)
+		}
+	}
+}
+
+func TestKeyIDOf(t *testing.T) {
+	p := mustNewProvider(t, makeKey(32), "prod/2024-01")
+	ct, err := p.Encrypt(context.Background(), []byte("v"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if id, err := KeyIDOf(ct); err != nil || id != "prod/2024-01" {
+		t.Errorf("KeyIDOf = %q, %v", id, err)
+	}
+	if _, err := KeyIDOf([]byte("plain")); !IsInvalidFormat(err) {
+		t.Errorf("KeyIDOf(plain): got %v, want ErrInvalidFormat", err)
+	}
+
+	// Only the header prefix is read: the ID string is the one allocation,
+	// however large the payload.
+	big, err := p.Encrypt(context.Background(), make([]byte, 1<<20))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if n := testing.AllocsPerRun(10, func() { _, _ = KeyIDOf(big) }); n > 1 {
+		t.Errorf("KeyIDOf made %v allocations, want at most 1", n)
+	}
+	end := minHeaderSizeV2 + len("prod/2024-01")
+	if _, err := KeyIDOf(ct[:end-1]); !IsInvalidFormat(err) {
+		t.Errorf("KeyIDOf(truncated key ID): got %v, want ErrInvalidFormat", err)
+	}
+}
@@ -0,0 +1,63 @@
+package gcpkms
+
+import (
+	"context"
+	"fmt"
+)
+
+// AsymmetricClient decrypts with CryptoKeys whose purpose is
+// ASYMMETRIC_DECRYPT. Implement it with the Cloud KMS AsymmetricDecrypt
+// RPC:
+//
+//	func (c *myGCPClient) AsymmetricDecrypt(ctx context.Context, versionName string, ciphertext []byte) ([]byte, error) {
+//	    resp, err := c.kms.AsymmetricDecrypt(ctx, &kmspb.AsymmetricDecryptRequest{Name: versionName, Ciphertext: ciphertext})
+//	    if err != nil { return nil, err }
+//	    return resp.Plaintext, nil
+//	}
+type AsymmetricClient interface {
+	// AsymmetricDecrypt decrypts ciphertext with the private half of the
+	// CryptoKeyVersion versionName:
+	// "projects/P/locations/L/keyRings/R/cryptoKeys/K/cryptoKeyVersions/V".
+	AsymmetricDecrypt(ctx context.Context, versionName string, ciphertext []byte) (plaintext []byte, err error)
+}
+
+// WithAsymmetricEncryptedKey adds a key encrypted to the public key of an
+// RSA-OAEP CryptoKeyVersion (RSA_DECRYPT_OAEP_*), for key policies that
+// mandate HSM-backed asymmetric keys. Wrapping needs no KMS call: fetch the
+// public key once with GetPublicKey and encrypt the 32-byte key with
+// rsa.EncryptOAEP using the version's hash. versionName is the full
+// CryptoKeyVersion resource name, since asymmetric keys have no primary
+// version. The client passed to New must implement AsymmetricClient.
+func WithAsymmetricEncryptedKey(ciphertext []byte, id, versionName string) Option {
+	return func(o *options) {
+		o.encryptedKeys = append(o.encryptedKeys, encryptedKeyEntry{
+			ciphertext:   ciphertext,
+			id:           id,
+			resourceName: versionName,
+			asymmetric:   true,
+		})
+	}
+}
+
+// checkAsymmetric reports an error if any entry needs AsymmetricDecrypt and
+// client does not provide it.
+func (o *options) checkAsymmetric(client Client) error {
+	if _, ok := client.(AsymmetricClient); ok {
+		return nil
+	}
+	for _, ek := range o.encryptedKeys {
+		if ek.asymmetric {
+			return fmt.Errorf("gcpkms: key %q is asymmetric but the Client does not implement AsymmetricClient", ek.id)
+		}
+	}
+	return nil
+}
+
+// decrypt unwraps e with client, using AsymmetricDecrypt for asymmetric
+// entries.
+func (e encryptedKeyEntry) decrypt(ctx context.Context, client Client) ([]byte, error) {
+	if e.asymmetric {
+		return client.(AsymmetricClient).AsymmetricDecrypt(ctx, e.resourceName, e.ciphertext)
+	}
+	return decrypt(ctx, client, e.resourceName, e.ciphertext)
+}
@@ -0,0 +1,66 @@
+package gcpkms
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const versionName = resourceName + "/cryptoKeyVersions/1"
+
+// asymmetricKMS holds the private key of one RSA_DECRYPT_OAEP_2048_SHA256
+// version.
+type asymmetricKMS struct {
+	mockClient
+	priv *rsa.PrivateKey
+}
+
+func (m *asymmetricKMS) AsymmetricDecrypt(_ context.Context, name string, ciphertext []byte) ([]byte, error) {
+	if name != versionName {
+		return nil, fmt.Errorf("kms: NotFound: %s", name)
+	}
+	return rsa.DecryptOAEP(sha256.New(), nil, m.priv, ciphertext, nil)
+}
+
+func TestNew_AsymmetricEncryptedKey(t *testing.T) {
+	ctx := context.Background()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wrapping uses only the public key, as from GetPublicKey.
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &priv.PublicKey, makeKey(7), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &asymmetricKMS{mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}, priv: priv}
+	p, err := New(ctx, client,
+		WithAsymmetricEncryptedKey(wrapped, "key-asym", versionName),
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "key-asym" {
+		t.Errorf("CurrentKeyID = %q, want key-asym", p.CurrentKeyID())
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_AsymmetricRequiresClient(t *testing.T) {
+	_, err := New(context.Background(), &mockClient{}, WithAsymmetricEncryptedKey([]byte("x"), "key-1", versionName))
+	if err == nil || !strings.Contains(err.Error(), "AsymmetricClient") {
+		t.Errorf("expected AsymmetricClient error, got %v", err)
+	}
+}
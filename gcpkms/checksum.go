@@ -0,0 +1,56 @@
+package gcpkms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// ErrChecksumMismatch is returned when the plaintext Cloud KMS returned does
+// not match the CRC32C checksum it reported, meaning the response was
+// corrupted in transit. Google recommends retrying such calls a limited
+// number of times.
+var ErrChecksumMismatch = errors.New("gcpkms: CRC32C checksum mismatch")
+
+// castagnoli is the CRC32C table Cloud KMS checksums use.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumClient is a Client that exchanges CRC32C checksums with Cloud KMS,
+// as Google recommends for end-to-end integrity. New, NewRefreshable, and
+// Poll use DecryptWithCRC32C instead of Decrypt whenever the client
+// implements it:
+//
+//	func (c *myGCPClient) DecryptWithCRC32C(ctx context.Context, name string, ciphertext []byte, crc uint32) ([]byte, uint32, error) {
+//	    resp, err := c.kms.Decrypt(ctx, &kmspb.DecryptRequest{
+//	        Name: name, Ciphertext: ciphertext, CiphertextCrc32C: wrapperspb.Int64(int64(crc)),
+//	    })
+//	    if err != nil { return nil, 0, err }
+//	    return resp.Plaintext, uint32(resp.PlaintextCrc32C.GetValue()), nil
+//	}
+type ChecksumClient interface {
+	Client
+
+	// DecryptWithCRC32C is like Decrypt but sends ciphertextCRC32C, which
+	// Cloud KMS verifies before decrypting, and returns the CRC32C of the
+	// plaintext as reported by Cloud KMS.
+	DecryptWithCRC32C(ctx context.Context, resourceName string, ciphertext []byte, ciphertextCRC32C uint32) (plaintext []byte, plaintextCRC32C uint32, err error)
+}
+
+// decrypt decrypts ciphertext with client, verifying checksums when the
+// client supports them.
+func decrypt(ctx context.Context, client Client, resourceName string, ciphertext []byte) ([]byte, error) {
+	cc, ok := client.(ChecksumClient)
+	if !ok {
+		return client.Decrypt(ctx, resourceName, ciphertext)
+	}
+	pt, crc, err := cc.DecryptWithCRC32C(ctx, resourceName, ciphertext, crc32.Checksum(ciphertext, castagnoli))
+	if err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(pt, castagnoli) != crc {
+		clear(pt)
+		return nil, fmt.Errorf("%w: plaintext from %s", ErrChecksumMismatch, resourceName)
+	}
+	return pt, nil
+}
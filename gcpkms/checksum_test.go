@@ -0,0 +1,57 @@
+package gcpkms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"testing"
+)
+
+// checksumClient is a mockClient that checks the ciphertext CRC32C and
+// reports the plaintext's, optionally corrupting the plaintext in transit.
+type checksumClient struct {
+	mockClient
+	corrupt  bool
+	checksum int
+}
+
+func (m *checksumClient) DecryptWithCRC32C(ctx context.Context, name string, ciphertext []byte, crc uint32) ([]byte, uint32, error) {
+	m.checksum++
+	if crc32.Checksum(ciphertext, castagnoli) != crc {
+		return nil, 0, fmt.Errorf("kms: InvalidArgument: ciphertext checksum mismatch")
+	}
+	pt, err := m.Decrypt(ctx, name, ciphertext)
+	if err != nil {
+		return nil, 0, err
+	}
+	pt = bytes.Clone(pt)
+	crc = crc32.Checksum(pt, castagnoli)
+	if m.corrupt {
+		pt[0] ^= 1
+	}
+	return pt, crc, nil
+}
+
+var _ ChecksumClient = (*checksumClient)(nil)
+
+func TestNew_Checksummed(t *testing.T) {
+	client := &checksumClient{mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}}
+	p, err := New(context.Background(), client, WithEncryptedKey([]byte("enc-1"), "key-1", resourceName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if client.checksum != 1 {
+		t.Errorf("DecryptWithCRC32C calls = %d, want 1", client.checksum)
+	}
+}
+
+func TestNew_ChecksumMismatch(t *testing.T) {
+	client := &checksumClient{mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}, corrupt: true}
+	_, err := New(context.Background(), client, WithEncryptedKey([]byte("enc-1"), "key-1", resourceName))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package gcpkms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// RandomGenerator draws random bytes from Cloud HSM. Implement it with the
+// Cloud KMS GenerateRandomBytes RPC:
+//
+//	func (c *myGCPClient) GenerateRandomBytes(ctx context.Context, location string, n int) ([]byte, error) {
+//	    resp, err := c.kms.GenerateRandomBytes(ctx, &kmspb.GenerateRandomBytesRequest{
+//	        Location: location, LengthBytes: int32(n), ProtectionLevel: kmspb.ProtectionLevel_HSM,
+//	    })
+//	    if err != nil { return nil, err }
+//	    return resp.Data, nil
+//	}
+type RandomGenerator interface {
+	// GenerateRandomBytes returns n random bytes generated in location,
+	// "projects/P/locations/L".
+	GenerateRandomBytes(ctx context.Context, location string, n int) ([]byte, error)
+}
+
+// GenerateWrappedKey bootstraps a new environment in one call: it generates
+// a 32-byte key, has Cloud KMS encrypt it with the CryptoKey resourceName,
+// and returns the ciphertext to persist together with a provider that
+// already holds the key as its current key. The key comes from
+// crypto/rand, or from Cloud HSM if enc also implements RandomGenerator.
+// id names the key in the ring; if empty, the key's crypto.KeyFingerprint
+// is used. Later processes load the same key with
+//
+//	gcpkms.New(ctx, client, gcpkms.WithEncryptedKey(ciphertext, id, resourceName))
+//
+// where id is the returned provider's CurrentKeyID. opts configure the
+// provider as for crypto.NewKeyRingProvider. The plaintext is wiped once it
+// is in the ring. The caller owns the returned provider and must Close it.
+func GenerateWrappedKey(ctx context.Context, enc Encrypter, resourceName, id string, opts ...crypto.ProviderOption) ([]byte, crypto.KeyRingProvider, error) {
+	if enc == nil {
+		return nil, nil, fmt.Errorf("gcpkms: Encrypter must not be nil")
+	}
+	if resourceName == "" {
+		return nil, nil, fmt.Errorf("gcpkms: %w: empty resource name", crypto.ErrInvalidKeyID)
+	}
+
+	var (
+		key []byte
+		err error
+	)
+	if rg, ok := enc.(RandomGenerator); ok {
+		location, _, found := strings.Cut(resourceName, "/keyRings/")
+		if !found {
+			return nil, nil, fmt.Errorf("gcpkms: %w: %q is not a CryptoKey name", crypto.ErrInvalidKeyID, resourceName)
+		}
+		key, err = rg.GenerateRandomBytes(ctx, location, kmsring.KeySize)
+		if err == nil && len(key) != kmsring.KeySize {
+			err = fmt.Errorf("%w: got %d random bytes", crypto.ErrInvalidKeySize, len(key))
+		}
+	} else {
+		key, err = crypto.GenerateKey()
+	}
+	defer clear(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gcpkms: generate key: %w", err)
+	}
+
+	ciphertext, err := enc.Encrypt(ctx, resourceName, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gcpkms: encrypt key: %w", err)
+	}
+	if id == "" {
+		id = crypto.KeyFingerprint(key)
+	}
+	ring, err := crypto.NewKeyRingProvider(key, id, 0, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gcpkms: %w", err)
+	}
+	return ciphertext, ring, nil
+}
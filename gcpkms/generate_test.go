@@ -0,0 +1,73 @@
+package gcpkms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// hsmKMS is a remoteKMS that also generates random bytes.
+type hsmKMS struct {
+	remoteKMS
+	location string
+}
+
+func (m *hsmKMS) GenerateRandomBytes(_ context.Context, location string, n int) ([]byte, error) {
+	m.location = location
+	return makeKey(9)[:n], nil
+}
+
+func TestGenerateWrappedKey_Bootstrap(t *testing.T) {
+	ctx := context.Background()
+	kms := &remoteKMS{keys: map[string]byte{resourceName: 0x5a}}
+	blob, ring, err := GenerateWrappedKey(ctx, kms, resourceName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	ct, err := ring.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A later process loads the persisted ciphertext and reads the value.
+	p, err := New(ctx, kms, WithEncryptedKey(blob, ring.CurrentKeyID(), resourceName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestGenerateWrappedKey_HSMRandom(t *testing.T) {
+	kms := &hsmKMS{remoteKMS: remoteKMS{keys: map[string]byte{resourceName: 1}}}
+	_, ring, err := GenerateWrappedKey(context.Background(), kms, resourceName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if kms.location != "projects/p/locations/l" {
+		t.Errorf("location = %q, want projects/p/locations/l", kms.location)
+	}
+	if want := crypto.KeyFingerprint(makeKey(9)); ring.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want fingerprint of the HSM key", ring.CurrentKeyID())
+	}
+}
+
+func TestGenerateWrappedKey_Errors(t *testing.T) {
+	ctx := context.Background()
+	if _, _, err := GenerateWrappedKey(ctx, nil, resourceName, ""); err == nil {
+		t.Error("expected error for nil Encrypter")
+	}
+	if _, _, err := GenerateWrappedKey(ctx, &remoteKMS{}, "", ""); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+	_, _, err := GenerateWrappedKey(ctx, &remoteKMS{}, resourceName, "")
+	if err == nil || errors.Is(err, crypto.ErrInvalidKeyID) {
+		t.Errorf("expected KMS error, got %v", err)
+	}
+}
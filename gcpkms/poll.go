@@ -52,8 +52,9 @@ type KeyMaterialEntry struct {
 //   - client.ListKeyVersions returns the set of enabled CryptoKey
 //     versions.
 //   - For every version that also has a matching materials entry (by
-//     VersionResourceName), client.Decrypt is invoked to recover the
-//     data-key plaintext and a crypto.KeyVersion is emitted.
+//     VersionResourceName), client.Decrypt (or DecryptWithCRC32C) is
+//     invoked to recover the data-key plaintext and a
+//     crypto.KeyVersion is emitted.
 //   - Versions present in KMS but missing from materials are silently
 //     skipped; versions present in materials but not in KMS are
 //     dropped from the current poll cycle. To retire a key version,
@@ -76,7 +77,7 @@ func NewPoller(client ListingClient, resourceName string, materials []KeyMateria
 			if !ok {
 				continue
 			}
-			plaintext, err := client.Decrypt(ctx, info.VersionResourceName, mat.Ciphertext)
+			plaintext, err := decrypt(ctx, client, info.VersionResourceName, mat.Ciphertext)
 			if err != nil {
 				return nil, fmt.Errorf("gcpkms: decrypt version %q: %w", info.VersionResourceName, err)
 			}
@@ -20,6 +20,7 @@ package gcpkms
 import (
 	"context"
 	"fmt"
+	"time"
 
 	crypto "github.com/rbaliyan/config-crypto"
 	"github.com/rbaliyan/config-crypto/internal/kmsring"
@@ -32,7 +33,8 @@ import (
 type Client interface {
 	// Decrypt decrypts a data key ciphertext using the specified Cloud KMS key.
 	// resourceName is the full CryptoKey resource name:
-	// "projects/P/locations/L/keyRings/R/cryptoKeys/K".
+	// "projects/P/locations/L/keyRings/R/cryptoKeys/K". New calls Decrypt
+	// concurrently when several keys are configured.
 	Decrypt(ctx context.Context, resourceName string, ciphertext []byte) (plaintext []byte, err error)
 }
 
@@ -41,12 +43,19 @@ type Option func(*options)
 
 type options struct {
 	encryptedKeys []encryptedKeyEntry
+	onSkip        kmsring.SkipFn
+	resolver      VersionResolver
+	onResolved    func([]ResolvedKey)
+	retry         retryPolicy
+	timeout       time.Duration
 }
 
 type encryptedKeyEntry struct {
 	ciphertext   []byte
 	id           string
-	resourceName string // projects/*/locations/*/keyRings/*/cryptoKeys/*
+	resourceName string // projects/*/locations/*/keyRings/*/cryptoKeys/*, or a version name when asymmetric
+	asymmetric   bool
+	versionName  string // pinned CryptoKeyVersion; empty = unknown
 }
 
 // WithEncryptedKey adds an encrypted key to be unwrapped via Cloud KMS Decrypt.
@@ -63,15 +72,36 @@ func WithEncryptedKey(ciphertext []byte, id, resourceName string) Option {
 	}
 }
 
+// WithSkipFailedKeys lets New succeed when a key other than the current one
+// cannot be unwrapped, for example because its CryptoKey was deleted or
+// access to it was revoked. Each such key is left out of the ring and
+// reported to fn, which may be nil, so values it encrypted fail to decrypt
+// with crypto.ErrKeyNotFound instead of taking the whole provider down.
+// New still fails if the current key cannot be unwrapped.
+func WithSkipFailedKeys(fn func(id string, err error)) Option {
+	return func(o *options) {
+		if fn == nil {
+			fn = func(string, error) {}
+		}
+		o.onSkip = fn
+	}
+}
+
 // New creates a crypto.KeyRingProvider that unwraps encrypted keys using Google
 // Cloud KMS.
 //
-// At least one key must be provided via WithEncryptedKey. The first key is
-// the current key for new encryptions; additional keys are available for
-// decryption (key rotation).
+// At least one key must be provided via WithEncryptedKey or
+// WithAsymmetricEncryptedKey. The first key is the current key for new
+// encryptions; additional keys are available for decryption (key rotation).
+//
+// Keys are decrypted concurrently during construction and cached. If any key
+// fails, the errors for all failed keys are returned together, unless
+// WithSkipFailedKeys allows old keys to be skipped. The KMS client
+// is not retained after construction; use NewRefreshable to keep it and
+// pick up new keys at runtime.
 //
-// Keys are decrypted during construction and cached. The KMS client is not
-// retained after construction.
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the unwrapped key material and is safe to call more than once.
 func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
 	if client == nil {
 		return nil, fmt.Errorf("gcpkms: Client must not be nil")
@@ -81,10 +111,25 @@ func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProv
 	for _, opt := range opts {
 		opt(&o)
 	}
+	if err := o.checkAsymmetric(client); err != nil {
+		return nil, err
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if err := o.resolve(ctx); err != nil {
+		return nil, err
+	}
 
-	return kmsring.Build(len(o.encryptedKeys), "gcpkms", func(i int) ([]byte, string, error) {
+	return kmsring.BuildSkipping(len(o.encryptedKeys), "gcpkms", o.onSkip, func(i int) ([]byte, string, error) {
 		ek := o.encryptedKeys[i]
-		pt, err := client.Decrypt(ctx, ek.resourceName, ek.ciphertext)
+		var pt []byte
+		err := o.retry.do(ctx, func(ctx context.Context) (err error) {
+			pt, err = ek.decrypt(ctx, client)
+			return err
+		})
 		return pt, ek.id, err
 	})
 }
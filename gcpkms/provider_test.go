@@ -135,3 +135,31 @@ func TestNew_DecryptedKeyZeroed(t *testing.T) {
 		}
 	}
 }
+
+func TestNew_SkipFailedKeys(t *testing.T) {
+	ctx := context.Background()
+	client := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1), "enc-3": makeKey(3)}, failOn: "enc-2"}
+	var skipped []string
+	provider, err := New(ctx, client,
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+		WithEncryptedKey([]byte("enc-2"), "key-2", resourceName),
+		WithEncryptedKey([]byte("enc-3"), "key-3", resourceName),
+		WithSkipFailedKeys(func(id string, err error) { skipped = append(skipped, id) }),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer provider.Close()
+	if len(skipped) != 1 || skipped[0] != "key-2" {
+		t.Errorf("skipped = %v, want [key-2]", skipped)
+	}
+	if provider.CurrentKeyID() != "key-1" {
+		t.Errorf("CurrentKeyID = %q, want key-1", provider.CurrentKeyID())
+	}
+
+	// The current key failing is still fatal.
+	client = &mockClient{keys: map[string][]byte{"enc-2": makeKey(2)}, failOn: "enc-1"}
+	if _, err := New(ctx, client, WithEncryptedKey([]byte("enc-1"), "key-1", resourceName), WithEncryptedKey([]byte("enc-2"), "key-2", resourceName), WithSkipFailedKeys(nil)); err == nil {
+		t.Error("expected error when the current key fails")
+	}
+}
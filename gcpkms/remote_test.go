@@ -0,0 +1,90 @@
+package gcpkms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// remoteKMS is an in-memory Cloud KMS: ciphertexts are the CryptoKey name,
+// a separator, and the plaintext XORed with a per-key byte.
+type remoteKMS struct {
+	keys     map[string]byte
+	decrypts int
+}
+
+func (m *remoteKMS) Encrypt(_ context.Context, name string, plaintext []byte) ([]byte, error) {
+	x, ok := m.keys[name]
+	if !ok {
+		return nil, fmt.Errorf("kms: NotFound: %s", name)
+	}
+	ct := append([]byte(name+"|"), plaintext...)
+	for i := len(name) + 1; i < len(ct); i++ {
+		ct[i] ^= x
+	}
+	return ct, nil
+}
+
+func (m *remoteKMS) Decrypt(_ context.Context, name string, ciphertext []byte) ([]byte, error) {
+	m.decrypts++
+	key, body, ok := bytes.Cut(ciphertext, []byte("|"))
+	if !ok || string(key) != name {
+		return nil, errors.New("kms: InvalidArgument: ciphertext is invalid")
+	}
+	pt := bytes.Clone(body)
+	for i := range pt {
+		pt[i] ^= m.keys[name]
+	}
+	return pt, nil
+}
+
+var _ RemoteClient = (*remoteKMS)(nil)
+
+func TestNewRemote_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	kms := &remoteKMS{keys: map[string]byte{resourceName: 0x5a}}
+	p, err := NewRemote(kms, resourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.Name() != "gcpkms" {
+		t.Errorf("Name = %q, want gcpkms", p.Name())
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != resourceName {
+		t.Errorf("KeyIDOf = %q, want the CryptoKey name", id)
+	}
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+	if kms.decrypts != 1 {
+		t.Errorf("KMS Decrypt calls = %d, want 1", kms.decrypts)
+	}
+}
+
+func TestNewRemote_Errors(t *testing.T) {
+	p, err := NewRemote(&remoteKMS{}, resourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.Encrypt(context.Background(), []byte("secret")); err == nil || !strings.Contains(err.Error(), "NotFound") {
+		t.Errorf("expected KMS error, got %v", err)
+	}
+	if _, err := NewRemote(nil, resourceName); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := NewRemote(&remoteKMS{}, ""); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+}
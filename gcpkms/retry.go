@@ -0,0 +1,108 @@
+package gcpkms
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// retryableCodes are gRPC status codes from Cloud KMS that a later attempt
+// may not see. Keys backed by an External Key Manager surface EKM timeouts
+// and outages as DeadlineExceeded and Unavailable.
+var retryableCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Internal:          true,
+	codes.Aborted:           true,
+}
+
+// retryPolicy controls how New retries KMS calls. The zero value makes one
+// attempt with no timeout.
+type retryPolicy kmsring.RetryPolicy
+
+// WithRetries makes New retry each failed KMS call (Decrypt,
+// AsymmetricDecrypt, PrimaryVersion) up to n more times with exponential
+// backoff, for errors that look transient: the gRPC codes Unavailable,
+// DeadlineExceeded, ResourceExhausted, Internal, and Aborted, checksum
+// mismatches, and errors that crypto.IsRetryable accepts. Set the
+// classifier with WithRetryIf and the delays with WithBackoff. Retry and
+// timeout options apply to New; NewRefreshable already retries on its next
+// refresh.
+func WithRetries(n int) Option {
+	return func(o *options) {
+		o.retry.Attempts = n + 1
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the cap it doubles
+// up to. Each delay is jittered down by up to half. Defaults to 100ms and
+// 5s.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		o.retry.Initial, o.retry.Max = initial, max
+	}
+}
+
+// WithRetryIf replaces the classifier WithRetries uses to decide whether
+// an error is worth retrying.
+func WithRetryIf(fn func(error) bool) Option {
+	return func(o *options) {
+		o.retry.RetryIf = fn
+	}
+}
+
+// WithCallTimeout bounds each KMS call New makes, so one hung request is
+// abandoned and, with WithRetries, retried.
+func WithCallTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.retry.CallTimeout = d
+	}
+}
+
+// WithConstructionTimeout bounds the whole of New, including every
+// resolution, decryption, retry, and backoff. When it expires New fails
+// with an error wrapping context.DeadlineExceeded.
+func WithConstructionTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithExternalKeyManager tunes retries for CryptoKeys with protection level
+// EXTERNAL or EXTERNAL_VPC, whose every operation is a round trip from
+// Cloud KMS to a third-party key manager: 4 retries with backoff from 500ms
+// up to 15s, and 30s per call. Options after it override these values.
+func WithExternalKeyManager() Option {
+	return func(o *options) {
+		o.retry.Attempts = 5
+		o.retry.Initial, o.retry.Max = 500*time.Millisecond, 15*time.Second
+		o.retry.CallTimeout = 30 * time.Second
+	}
+}
+
+// IsRetryableError is the default WithRetries classifier.
+func IsRetryableError(err error) bool {
+	if errors.Is(err, ErrChecksumMismatch) {
+		return true
+	}
+	if s, ok := status.FromError(err); ok && retryableCodes[s.Code()] {
+		return true
+	}
+	return crypto.IsRetryable(err)
+}
+
+// do calls fn as the policy allows, classifying errors with
+// IsRetryableError unless WithRetryIf replaced it.
+func (p retryPolicy) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.RetryIf == nil {
+		p.RetryIf = IsRetryableError
+	}
+	return kmsring.RetryPolicy(p).Do(ctx, fn)
+}
@@ -0,0 +1,123 @@
+package gcpkms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyClient fails the first failures calls with err, then delegates.
+type flakyClient struct {
+	mockClient
+	failures int32
+	err      error
+	hang     bool // block until the context is done instead of failing
+	calls    atomic.Int32
+}
+
+func (c *flakyClient) Decrypt(ctx context.Context, name string, ciphertext []byte) ([]byte, error) {
+	if c.calls.Add(1) <= c.failures {
+		if c.hang {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, c.err
+	}
+	return c.mockClient.Decrypt(ctx, name, ciphertext)
+}
+
+func TestNew_RetriesUnavailable(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   2,
+		err:        fmt.Errorf("rpc: %w", status.Error(codes.Unavailable, "EKM unreachable")),
+	}
+	ring, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+		WithRetries(3), WithBackoff(time.Millisecond, 2*time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+	if client.calls.Load() != 3 {
+		t.Errorf("Decrypt calls = %d, want 3", client.calls.Load())
+	}
+}
+
+func TestNew_DoesNotRetryPermanentErrors(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   1,
+		err:        status.Error(codes.FailedPrecondition, "key version is disabled"),
+	}
+	_, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+		WithRetries(3), WithBackoff(time.Millisecond, time.Millisecond))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if client.calls.Load() != 1 {
+		t.Errorf("Decrypt calls = %d, want 1", client.calls.Load())
+	}
+}
+
+func TestNew_CallTimeout(t *testing.T) {
+	client := &flakyClient{
+		mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}},
+		failures:   1,
+		hang:       true,
+	}
+	ring, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+		WithRetries(1), WithCallTimeout(10*time.Millisecond), WithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer ring.Close()
+}
+
+func TestNew_ConstructionTimeout(t *testing.T) {
+	client := &flakyClient{failures: 1 << 30, hang: true}
+	_, err := New(context.Background(), client,
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+		WithConstructionTimeout(10*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWithExternalKeyManager(t *testing.T) {
+	var o options
+	WithExternalKeyManager()(&o)
+	WithCallTimeout(time.Minute)(&o)
+	if o.retry.Attempts != 5 || o.retry.Initial != 500*time.Millisecond || o.retry.CallTimeout != time.Minute {
+		t.Errorf("retry = %+v", o.retry)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.Unavailable, ""), true},
+		{status.Error(codes.DeadlineExceeded, ""), true},
+		{fmt.Errorf("wrapped: %w", status.Error(codes.ResourceExhausted, "")), true},
+		{status.Error(codes.PermissionDenied, ""), false},
+		{status.Error(codes.FailedPrecondition, ""), false},
+		{fmt.Errorf("%w: plaintext", ErrChecksumMismatch), true},
+		{context.DeadlineExceeded, true},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableError(tt.err); got != tt.want {
+			t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package gcpkms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// versionSep separates a CryptoKey name from its version ID in a
+// CryptoKeyVersion resource name.
+const versionSep = "/cryptoKeyVersions/"
+
+// VersionResolver reports the primary version of a CryptoKey. Implement it
+// with the Cloud KMS GetCryptoKey RPC:
+//
+//	func (c *myGCPClient) PrimaryVersion(ctx context.Context, resourceName string) (string, error) {
+//	    key, err := c.kms.GetCryptoKey(ctx, &kmspb.GetCryptoKeyRequest{Name: resourceName})
+//	    if err != nil { return "", err }
+//	    return key.GetPrimary().GetName(), nil
+//	}
+type VersionResolver interface {
+	// PrimaryVersion returns the CryptoKeyVersion resource name of the
+	// primary version of the CryptoKey resourceName.
+	PrimaryVersion(ctx context.Context, resourceName string) (versionName string, err error)
+}
+
+// ResolvedKey records the CryptoKeyVersion behind a key in the ring.
+type ResolvedKey struct {
+	// ID is the key's ID in the config-crypto ring.
+	ID string
+
+	// ResourceName is the CryptoKey the key is decrypted with.
+	ResourceName string
+
+	// VersionName is the CryptoKeyVersion the key was encrypted under, or
+	// the CryptoKey's primary version at construction when Primary is set.
+	// It is empty if neither is known.
+	VersionName string
+
+	// Primary reports that VersionName was resolved with
+	// WithPrimaryVersionResolution rather than pinned.
+	Primary bool
+}
+
+// WithEncryptedKeyVersion is like WithEncryptedKey but names the
+// CryptoKeyVersion the ciphertext was encrypted under, as returned in the
+// Name field of the Encrypt response. Symmetric Decrypt is sent to the
+// CryptoKey and Cloud KMS selects the version from the ciphertext, so the
+// version is pinned in the provider's records (see WithResolvedKeysHandler)
+// and never re-resolved, letting audits tie each key to the exact KMS key
+// version that must stay enabled for it to decrypt.
+func WithEncryptedKeyVersion(ciphertext []byte, id, versionName string) Option {
+	return func(o *options) {
+		resourceName, _, _ := strings.Cut(versionName, versionSep)
+		o.encryptedKeys = append(o.encryptedKeys, encryptedKeyEntry{
+			ciphertext:   ciphertext,
+			id:           id,
+			resourceName: resourceName,
+			versionName:  versionName,
+		})
+	}
+}
+
+// WithPrimaryVersionResolution resolves the primary version of each
+// CryptoKey named by a key without a pinned version when New runs, and on
+// every refresh of NewRefreshable, and records it for
+// WithResolvedKeysHandler. The primary version is the one that encrypts
+// new data now; pin the version of existing ciphertexts with
+// WithEncryptedKeyVersion for an exact record. Each distinct CryptoKey is
+// looked up once.
+func WithPrimaryVersionResolution(r VersionResolver) Option {
+	return func(o *options) {
+		o.resolver = r
+	}
+}
+
+// WithResolvedKeysHandler sets a callback that receives the CryptoKey and
+// version behind every key, one entry per key in option order, before any
+// key is decrypted. Use it to log or expose the KMS key versions in use.
+func WithResolvedKeysHandler(fn func([]ResolvedKey)) Option {
+	return func(o *options) {
+		o.onResolved = fn
+	}
+}
+
+// resolve checks pinned versions, resolves primary versions with
+// o.resolver, and reports the result to o.onResolved.
+func (o *options) resolve(ctx context.Context) error {
+	for _, ek := range o.encryptedKeys {
+		if ek.versionName != "" && !strings.Contains(ek.versionName, versionSep) {
+			return fmt.Errorf("gcpkms: key %q: %q is not a CryptoKeyVersion name", ek.id, ek.versionName)
+		}
+	}
+	if o.resolver == nil && o.onResolved == nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	if o.resolver != nil {
+		for _, ek := range o.encryptedKeys {
+			if ek.versionName == "" && !ek.asymmetric && !seen[ek.resourceName] {
+				seen[ek.resourceName] = true
+				names = append(names, ek.resourceName)
+			}
+		}
+	}
+	primaries := make([]string, len(names))
+	err := kmsring.ForEach(len(names), func(i int) error {
+		var v string
+		err := o.retry.do(ctx, func(ctx context.Context) (err error) {
+			v, err = o.resolver.PrimaryVersion(ctx, names[i])
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("gcpkms: resolve primary version of %s: %w", names[i], err)
+		}
+		if !strings.HasPrefix(v, names[i]+versionSep) {
+			return fmt.Errorf("gcpkms: resolve primary version of %s: got %q", names[i], v)
+		}
+		primaries[i] = v
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	primary := make(map[string]string, len(names))
+	for i, name := range names {
+		primary[name] = primaries[i]
+	}
+
+	resolved := make([]ResolvedKey, len(o.encryptedKeys))
+	for i, ek := range o.encryptedKeys {
+		r := ResolvedKey{ID: ek.id, ResourceName: ek.resourceName, VersionName: ek.versionName}
+		switch {
+		case ek.asymmetric:
+			r.ResourceName, _, _ = strings.Cut(ek.resourceName, versionSep)
+			r.VersionName = ek.resourceName
+		case r.VersionName == "":
+			r.VersionName, r.Primary = primary[ek.resourceName], primary[ek.resourceName] != ""
+		}
+		resolved[i] = r
+	}
+	if o.onResolved != nil {
+		o.onResolved(resolved)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package gcpkms
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// primaryResolver reports a fixed primary version for every CryptoKey.
+type primaryResolver struct {
+	version string
+	calls   atomic.Int32
+	err     error
+}
+
+func (r *primaryResolver) PrimaryVersion(_ context.Context, name string) (string, error) {
+	r.calls.Add(1)
+	if r.err != nil {
+		return "", r.err
+	}
+	return name + "/cryptoKeyVersions/" + r.version, nil
+}
+
+func TestNew_VersionRecords(t *testing.T) {
+	ctx := context.Background()
+	const otherKey = "projects/p/locations/l/keyRings/r/cryptoKeys/other"
+	client := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1), "enc-2": makeKey(2), "enc-3": makeKey(3)}}
+	resolver := &primaryResolver{version: "7"}
+	var got []ResolvedKey
+	p, err := New(ctx, client,
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+		WithEncryptedKeyVersion([]byte("enc-2"), "key-2", otherKey+"/cryptoKeyVersions/3"),
+		WithEncryptedKey([]byte("enc-3"), "key-3", resourceName),
+		WithPrimaryVersionResolution(resolver),
+		WithResolvedKeysHandler(func(r []ResolvedKey) { got = r }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	want := []ResolvedKey{
+		{ID: "key-1", ResourceName: resourceName, VersionName: resourceName + "/cryptoKeyVersions/7", Primary: true},
+		{ID: "key-2", ResourceName: otherKey, VersionName: otherKey + "/cryptoKeyVersions/3"},
+		{ID: "key-3", ResourceName: resourceName, VersionName: resourceName + "/cryptoKeyVersions/7", Primary: true},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("resolved = %+v, want %+v", got, want)
+	}
+	if resolver.calls.Load() != 1 {
+		t.Errorf("PrimaryVersion calls = %d, want 1", resolver.calls.Load())
+	}
+}
+
+func TestNew_PinnedVersionDecryptsWithCryptoKey(t *testing.T) {
+	var names []string
+	client := &nameRecorder{mockClient: mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}, names: &names}
+	p, err := New(context.Background(), client, WithEncryptedKeyVersion([]byte("enc-1"), "key-1", resourceName+"/cryptoKeyVersions/2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if len(names) != 1 || names[0] != resourceName {
+		t.Errorf("Decrypt names = %v, want [%s]", names, resourceName)
+	}
+}
+
+type nameRecorder struct {
+	mockClient
+	names *[]string
+}
+
+func (m *nameRecorder) Decrypt(ctx context.Context, name string, ciphertext []byte) ([]byte, error) {
+	*m.names = append(*m.names, name)
+	return m.mockClient.Decrypt(ctx, name, ciphertext)
+}
+
+func TestNew_VersionErrors(t *testing.T) {
+	ctx := context.Background()
+	client := &mockClient{keys: map[string][]byte{"enc-1": makeKey(1)}}
+	_, err := New(ctx, client, WithEncryptedKeyVersion([]byte("enc-1"), "key-1", resourceName))
+	if err == nil || !strings.Contains(err.Error(), "CryptoKeyVersion") {
+		t.Errorf("expected version name error, got %v", err)
+	}
+	kmsErr := errors.New("kms: PermissionDenied")
+	_, err = New(ctx, client,
+		WithEncryptedKey([]byte("enc-1"), "key-1", resourceName),
+		WithPrimaryVersionResolution(&primaryResolver{err: kmsErr}),
+	)
+	if !errors.Is(err, kmsErr) {
+		t.Errorf("expected resolver error, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+)
+
+// KeyEncoding selects the text form produced by GenerateKeyString. Every
+// form is accepted by ParseKey.
+type KeyEncoding int
+
+const (
+	// KeyEncodingBase64 produces "base64:" followed by standard padded base64.
+	KeyEncodingBase64 KeyEncoding = iota
+
+	// KeyEncodingHex produces "hex:" followed by 64 lowercase hex digits.
+	KeyEncodingHex
+
+	// KeyEncodingPEM produces a PEM block of type "AES-256 KEY".
+	KeyEncodingPEM
+)
+
+// pemKeyType is the PEM block type written by GenerateKeyString.
+const pemKeyType = "AES-256 KEY"
+
+// GenerateKey returns a new random 32-byte AES-256 key read from
+// crypto/rand. The caller owns the slice and should zero it once the key has
+// been handed to a provider or wrapped for storage.
+func GenerateKey() ([]byte, error) {
+	b := make([]byte, aesKeySize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("crypto: generate key: %w", err)
+	}
+	return b, nil
+}
+
+// GenerateKeyString returns a new random 32-byte key encoded as enc, ready
+// to store in a secret manager and later decode with ParseKey.
+func GenerateKeyString(enc KeyEncoding) (string, error) {
+	b, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	defer clear(b)
+	switch enc {
+	case KeyEncodingBase64:
+		return "base64:" + base64.StdEncoding.EncodeToString(b), nil
+	case KeyEncodingHex:
+		return "hex:" + hex.EncodeToString(b), nil
+	case KeyEncodingPEM:
+		return string(pem.EncodeToMemory(&pem.Block{Type: pemKeyType, Bytes: b})), nil
+	default:
+		return "", fmt.Errorf("crypto: GenerateKeyString: unknown encoding %d", enc)
+	}
+}
@@ -0,0 +1,43 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	a, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	b, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if len(a) != 32 || bytes.Equal(a, b) {
+		t.Errorf("GenerateKey returned %x and %x", a, b)
+	}
+	if err := CheckKey(a); err != nil {
+		t.Errorf("generated key failed CheckKey: %v", err)
+	}
+}
+
+func TestGenerateKeyStringRoundTrip(t *testing.T) {
+	for _, enc := range []KeyEncoding{KeyEncodingBase64, KeyEncodingHex, KeyEncodingPEM} {
+		s, err := GenerateKeyString(enc)
+		if err != nil {
+			t.Fatalf("GenerateKeyString(%d): %v", enc, err)
+		}
+		k, err := ParseKey(s)
+		if err != nil {
+			t.Errorf("ParseKey(GenerateKeyString(%d)): %v", enc, err)
+			continue
+		}
+		if len(k) != 32 {
+			t.Errorf("encoding %d: parsed %d bytes", enc, len(k))
+		}
+	}
+	if _, err := GenerateKeyString(KeyEncoding(99)); err == nil {
+		t.Error("unknown encoding accepted")
+	}
+}
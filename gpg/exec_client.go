@@ -18,7 +18,8 @@ const maxStderrLen = 200
 // The GPG keyring must already have the appropriate private key imported.
 // No Go crypto dependencies are required.
 type ExecClient struct {
-	gpgBin string // path to gpg binary, defaults to "gpg"
+	gpgBin      string // path to gpg binary, defaults to "gpg"
+	alwaysTrust bool   // pass --trust-model always when encrypting
 }
 
 // ExecOption configures an ExecClient.
@@ -32,6 +33,16 @@ func WithGPGBinary(path string) ExecOption {
 	}
 }
 
+// WithAlwaysTrust makes Encrypt use recipient keys that the keyring does
+// not consider valid (not signed by a trusted key). Without it gpg refuses
+// such recipients in batch mode. Only use it when the recipient
+// fingerprints are pinned by configuration.
+func WithAlwaysTrust() ExecOption {
+	return func(c *ExecClient) {
+		c.alwaysTrust = true
+	}
+}
+
 // NewExecClient creates an ExecClient that delegates to the system gpg binary.
 // The calling process must have a GPG keyring with the appropriate private key
 // already imported and (if passphrase-protected) accessible via gpg-agent.
@@ -69,6 +80,33 @@ func (c *ExecClient) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, er
 	return plaintext, nil
 }
 
+// Encrypt encrypts plaintext to recipients (key IDs, fingerprints, or user
+// IDs in the keyring) using the system gpg binary, producing a binary
+// OpenPGP message.
+func (c *ExecClient) Encrypt(ctx context.Context, recipients []string, plaintext []byte) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("gpg exec: at least one recipient is required")
+	}
+	args := []string{"--batch", "--quiet", "--encrypt", "--output", "-"}
+	if c.alwaysTrust {
+		args = append(args, "--trust-model", "always")
+	}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	cmd := exec.CommandContext(ctx, c.gpgBin, args...) // #nosec G204 -- gpgBin is developer-configured, not user input
+	cmd.Stdin = bytes.NewReader(plaintext)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg exec: %w: %s", err, sanitizeStderr(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
 // sanitizeStderr returns a truncated, printable-only excerpt of gpg stderr
 // suitable for inclusion in error messages. gpg stderr can contain key
 // fingerprints, user IDs, and email addresses; this prevents them from
@@ -87,5 +125,8 @@ func sanitizeStderr(raw []byte) string {
 	return s
 }
 
-// Compile-time interface check.
-var _ Client = (*ExecClient)(nil)
+// Compile-time interface checks.
+var (
+	_ Client          = (*ExecClient)(nil)
+	_ RecipientClient = (*ExecClient)(nil)
+)
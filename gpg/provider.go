@@ -79,6 +79,9 @@ func WithEncryptedKey(ciphertext []byte, id string) Option {
 //
 // All keys are decrypted during construction and cached. The Client is not
 // retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the unwrapped key material and is safe to call more than once.
 func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
 	if client == nil {
 		return nil, fmt.Errorf("gpg: Client must not be nil")
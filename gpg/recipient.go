@@ -0,0 +1,91 @@
+package gpg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// Encrypter encrypts to OpenPGP recipients.
+type Encrypter interface {
+	// Encrypt encrypts plaintext to every one of recipients, so that any of
+	// their private keys can decrypt it.
+	Encrypt(ctx context.Context, recipients []string, plaintext []byte) ([]byte, error)
+}
+
+// RecipientClient both encrypts to and decrypts for OpenPGP recipients.
+// ExecClient implements it.
+type RecipientClient interface {
+	Client
+	Encrypter
+}
+
+// NewRecipientProvider returns a crypto.Provider that wraps each value's
+// DEK to OpenPGP recipients instead of using a local KEK, so teams already
+// distributing secrets with GPG can read and write envelopes with the keys
+// they already manage:
+//
+//	client := gpg.NewExecClient()
+//	provider, err := gpg.NewRecipientProvider(client, "team-gpg",
+//	    "5E3C...A1F0", // alice
+//	    "9B21...44C7", // bob
+//	)
+//
+// Every value is readable by any one recipient, and decryption goes through
+// gpg, so gpg-agent handles passphrases, smartcards, and caching. Each
+// Encrypt and Decrypt runs gpg once.
+//
+// keyID is recorded in every ciphertext header; use a new one when the
+// recipient list changes so values written for the old list can be found
+// and re-encrypted. Decryption does not depend on it: gpg picks whichever
+// secret key the message was encrypted to. Values use the wrapped-DEK
+// envelope format (see crypto.NewWrappingProvider).
+func NewRecipientProvider(client RecipientClient, keyID string, recipients ...string) (crypto.Provider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("gpg: Client must not be nil")
+	}
+	if keyID == "" {
+		return nil, fmt.Errorf("gpg: %w: empty key ID", crypto.ErrInvalidKeyID)
+	}
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("gpg: at least one recipient is required")
+	}
+	return crypto.NewWrappingProvider(&recipientWrapper{
+		client:     client,
+		keyID:      keyID,
+		recipients: slices.Clone(recipients),
+	})
+}
+
+// recipientWrapper adapts a RecipientClient to crypto.KeyWrapper.
+type recipientWrapper struct {
+	client     RecipientClient
+	keyID      string
+	recipients []string
+}
+
+// Name returns "gpg".
+func (w *recipientWrapper) Name() string { return "gpg" }
+
+// WrapKey encrypts dek to the recipients.
+func (w *recipientWrapper) WrapKey(ctx context.Context, dek []byte) (string, []byte, error) {
+	wrapped, err := w.client.Encrypt(ctx, w.recipients, dek)
+	if err != nil {
+		return "", nil, fmt.Errorf("gpg: wrap DEK: %w", err)
+	}
+	return w.keyID, wrapped, nil
+}
+
+// UnwrapKey decrypts a DEK wrapped by WrapKey under any key ID.
+func (w *recipientWrapper) UnwrapKey(ctx context.Context, _ string, wrapped []byte) ([]byte, error) {
+	dek, err := w.client.Decrypt(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("gpg: unwrap DEK: %w", err)
+	}
+	return dek, nil
+}
+
+// Compile-time interface check.
+var _ crypto.KeyWrapper = (*recipientWrapper)(nil)
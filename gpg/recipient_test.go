@@ -0,0 +1,110 @@
+package gpg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockRecipients "encrypts" by prefixing the recipient list and decrypts
+// only if one of its own keys is among them.
+type mockRecipients struct {
+	own string
+}
+
+func (m *mockRecipients) Encrypt(_ context.Context, recipients []string, plaintext []byte) ([]byte, error) {
+	var b bytes.Buffer
+	for _, r := range recipients {
+		b.WriteString(r + ",")
+	}
+	b.WriteByte('|')
+	b.Write(plaintext)
+	return b.Bytes(), nil
+}
+
+func (m *mockRecipients) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	head, body, ok := bytes.Cut(ciphertext, []byte("|"))
+	if !ok || !slices.Contains(strings.Split(string(head), ","), m.own) {
+		return nil, errors.New("gpg: decryption failed: No secret key")
+	}
+	return bytes.Clone(body), nil
+}
+
+var _ RecipientClient = (*mockRecipients)(nil)
+
+func TestRecipientProvider_AnyRecipientDecrypts(t *testing.T) {
+	ctx := context.Background()
+	alice, err := NewRecipientProvider(&mockRecipients{own: "alice"}, "team", "alice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	ct, err := alice.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "team" {
+		t.Errorf("KeyIDOf = %q, want team", id)
+	}
+
+	bob, _ := NewRecipientProvider(&mockRecipients{own: "bob"}, "team", "alice", "bob")
+	if pt, err := bob.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("bob Decrypt = %q, %v", pt, err)
+	}
+	eve, _ := NewRecipientProvider(&mockRecipients{own: "eve"}, "team", "eve")
+	if _, err := eve.Decrypt(ctx, ct); err == nil {
+		t.Error("non-recipient decrypted the value")
+	}
+}
+
+func TestRecipientProvider_Validation(t *testing.T) {
+	if _, err := NewRecipientProvider(nil, "team", "alice"); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := NewRecipientProvider(&mockRecipients{}, "", "alice"); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+	if _, err := NewRecipientProvider(&mockRecipients{}, "team"); err == nil {
+		t.Error("expected error without recipients")
+	}
+}
+
+func TestRecipientProvider_ExecClient(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg binary not found in PATH")
+	}
+	home := t.TempDir()
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() { _ = exec.Command("gpgconf", "--kill", "gpg-agent").Run() })
+	gen := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key",
+		"config-crypto test <test@example.com>", "future-default", "default", "never")
+	if out, err := gen.CombinedOutput(); err != nil {
+		t.Skipf("cannot generate a test key: %v: %s", err, out)
+	}
+
+	ctx := context.Background()
+	p, err := NewRecipientProvider(NewExecClient(WithAlwaysTrust()), "team", "test@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestExecClientEncryptNoRecipients(t *testing.T) {
+	if _, err := NewExecClient().Encrypt(context.Background(), nil, []byte("x")); err == nil {
+		t.Error("expected error without recipients")
+	}
+}
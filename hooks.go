@@ -0,0 +1,130 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+)
+
+// HookInfo describes a single encrypt or decrypt operation passed to a Hook.
+// It never carries plaintext or key material.
+type HookInfo struct {
+	// Codec is the name of the codec performing the operation.
+	Codec string
+
+	// KeyID is the KEK ID involved. For BeforeEncrypt it is the provider's
+	// current key ID when the provider reports one (KeyRingProvider does),
+	// and empty otherwise; the provider may still rotate before encrypting.
+	// For AfterDecrypt it is the key ID recorded in the ciphertext header.
+	KeyID string
+
+	// PlaintextSize is the size of the plaintext in bytes.
+	PlaintextSize int
+
+	// CiphertextSize is the size of the ciphertext in bytes. It is zero in
+	// BeforeEncrypt because the ciphertext does not exist yet.
+	CiphertextSize int
+}
+
+// Hook observes and optionally vetoes Codec operations. Use it to emit audit
+// events or enforce organisation policy, such as a maximum payload size,
+// without wrapping the Codec. Hooks cannot change the payload, and never see
+// it; to transform values before encryption or after decryption, chain a
+// codec.Transformer with codec.NewChain instead.
+//
+// Returning an error from BeforeEncrypt aborts the encryption; returning an
+// error from AfterDecrypt discards the plaintext and fails the decode. The
+// caller receives the hook's error with a "crypto: BeforeEncrypt hook:" or
+// "crypto: AfterDecrypt hook:" prefix, and errors.Is matches it.
+//
+// Hooks run synchronously on the calling goroutine, in registration order,
+// and must be safe for concurrent use.
+type Hook interface {
+	BeforeEncrypt(ctx context.Context, info HookInfo) error
+	AfterDecrypt(ctx context.Context, info HookInfo) error
+}
+
+// HookFuncs adapts plain functions to the Hook interface. Nil fields are
+// skipped.
+type HookFuncs struct {
+	BeforeEncryptFunc func(ctx context.Context, info HookInfo) error
+	AfterDecryptFunc  func(ctx context.Context, info HookInfo) error
+}
+
+// Compile-time interface check.
+var _ Hook = HookFuncs{}
+
+// BeforeEncrypt calls BeforeEncryptFunc if it is set.
+func (h HookFuncs) BeforeEncrypt(ctx context.Context, info HookInfo) error {
+	if h.BeforeEncryptFunc == nil {
+		return nil
+	}
+	return h.BeforeEncryptFunc(ctx, info)
+}
+
+// AfterDecrypt calls AfterDecryptFunc if it is set.
+func (h HookFuncs) AfterDecrypt(ctx context.Context, info HookInfo) error {
+	if h.AfterDecryptFunc == nil {
+		return nil
+	}
+	return h.AfterDecryptFunc(ctx, info)
+}
+
+// WithHook registers a Hook on a Codec created by NewCodec. It may be
+// repeated; hooks run in the order they were registered.
+func WithHook(h Hook) CodecOption {
+	return func(o *codecOptions) {
+		if h != nil {
+			o.hooks = append(o.hooks, h)
+		}
+	}
+}
+
+// hookError is a veto from a Hook. Encode and Decode return it as is rather
+// than wrapping it in their own failure messages.
+type hookError struct {
+	hook string
+	err  error
+}
+
+func (e *hookError) Error() string { return "crypto: " + e.hook + " hook: " + e.err.Error() }
+func (e *hookError) Unwrap() error { return e.err }
+
+// isHookError reports whether err is a veto from a Hook.
+func isHookError(err error) bool {
+	var he *hookError
+	return errors.As(err, &he)
+}
+
+// runBeforeEncrypt invokes every BeforeEncrypt hook, stopping at the first error.
+func (c *Codec) runBeforeEncrypt(ctx context.Context, plaintext []byte) error {
+	if len(c.hooks) == 0 {
+		return nil
+	}
+	info := HookInfo{Codec: c.name, PlaintextSize: len(plaintext)}
+	if kp, ok := c.provider.(interface{ CurrentKeyID() string }); ok {
+		info.KeyID = kp.CurrentKeyID()
+	}
+	for _, h := range c.hooks {
+		if err := h.BeforeEncrypt(ctx, info); err != nil {
+			return &hookError{hook: "BeforeEncrypt", err: err}
+		}
+	}
+	return nil
+}
+
+// runAfterDecrypt invokes every AfterDecrypt hook, stopping at the first error.
+func (c *Codec) runAfterDecrypt(ctx context.Context, ciphertext, plaintext []byte) error {
+	if len(c.hooks) == 0 {
+		return nil
+	}
+	info := HookInfo{Codec: c.name, PlaintextSize: len(plaintext), CiphertextSize: len(ciphertext)}
+	if hp, _, err := readHeaderPrefix(ciphertext); err == nil {
+		info.KeyID = hp.keyID
+	}
+	for _, h := range c.hooks {
+		if err := h.AfterDecrypt(ctx, info); err != nil {
+			return &hookError{hook: "AfterDecrypt", err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+type recordingHook struct {
+	mu      sync.Mutex
+	encrypt []HookInfo
+	decrypt []HookInfo
+}
+
+func (h *recordingHook) BeforeEncrypt(_ context.Context, info HookInfo) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.encrypt = append(h.encrypt, info)
+	return nil
+}
+
+func (h *recordingHook) AfterDecrypt(_ context.Context, info HookInfo) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.decrypt = append(h.decrypt, info)
+	return nil
+}
+
+func TestCodecHooksReceiveInfo(t *testing.T) {
+	ctx := context.Background()
+	rec := &recordingHook{}
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "audit-key"), WithHook(rec))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+
+	data, err := c.Encode(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var got string
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if len(rec.encrypt) != 1 || len(rec.decrypt) != 1 {
+		t.Fatalf("hook calls: encrypt=%d decrypt=%d, want 1 each", len(rec.encrypt), len(rec.decrypt))
+	}
+	enc := rec.encrypt[0]
+	if enc.Codec != "encrypted:json" || enc.KeyID != "audit-key" || enc.PlaintextSize != len(`"hello"`) || enc.CiphertextSize != 0 {
+		t.Errorf("BeforeEncrypt info = %+v", enc)
+	}
+	dec := rec.decrypt[0]
+	if dec.KeyID != "audit-key" || dec.PlaintextSize != len(`"hello"`) || dec.CiphertextSize != len(data) {
+		t.Errorf("AfterDecrypt info = %+v", dec)
+	}
+}
+
+func TestCodecBeforeEncryptHookVetoes(t *testing.T) {
+	ctx := context.Background()
+	errTooLarge := errors.New("payload too large")
+	policy := HookFuncs{BeforeEncryptFunc: func(_ context.Context, info HookInfo) error {
+		if info.PlaintextSize > 8 {
+			return errTooLarge
+		}
+		return nil
+	}}
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "k"), WithHook(policy))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+
+	if _, err := c.Encode(ctx, "small"); err != nil {
+		t.Errorf("Encode small: %v", err)
+	}
+	_, err = c.Encode(ctx, "this value is far too large")
+	if !errors.Is(err, errTooLarge) {
+		t.Errorf("Encode large: got %v, want errTooLarge", err)
+	}
+	if want := "crypto: BeforeEncrypt hook: payload too large"; err == nil || err.Error() != want {
+		t.Errorf("Encode large: error = %q, want %q", err, want)
+	}
+	if _, err := c.Transform(ctx, []byte("this value is far too large")); !errors.Is(err, errTooLarge) {
+		t.Errorf("Transform large: got %v, want errTooLarge", err)
+	}
+}
+
+func TestCodecAfterDecryptHookVetoes(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewProvider(t, makeKey(32), "k")
+	plain, err := NewCodec(jsoncodec.New(), p)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	data, err := plain.Encode(ctx, "x")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	errDenied := errors.New("denied")
+	calls := 0
+	c, err := NewCodec(jsoncodec.New(), p,
+		WithHook(HookFuncs{AfterDecryptFunc: func(context.Context, HookInfo) error { calls++; return errDenied }}),
+		WithHook(HookFuncs{AfterDecryptFunc: func(context.Context, HookInfo) error { calls++; return nil }}),
+	)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	var got string
+	err = c.Decode(ctx, data, &got)
+	if !errors.Is(err, errDenied) {
+		t.Errorf("Decode: got %v, want errDenied", err)
+	}
+	if want := "crypto: AfterDecrypt hook: denied"; err == nil || err.Error() != want {
+		t.Errorf("Decode: error = %q, want %q", err, want)
+	}
+	if got != "" {
+		t.Errorf("Decode populated value despite veto: %q", got)
+	}
+	if calls != 1 {
+		t.Errorf("hooks after a veto should not run: calls = %d", calls)
+	}
+}
+
+func TestCodecHooksSkippedOnDecryptError(t *testing.T) {
+	ctx := context.Background()
+	rec := &recordingHook{}
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "k"), WithHook(rec))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	var got string
+	if err := c.Decode(ctx, []byte("garbage"), &got); err == nil {
+		t.Fatal("expected decrypt error")
+	}
+	if len(rec.decrypt) != 0 {
+		t.Errorf("AfterDecrypt called for failed decrypt: %+v", rec.decrypt)
+	}
+}
@@ -6,10 +6,16 @@
 // bytes plus the identifier used inside the ring. Build zeroes every
 // decrypted byte slice before returning — on success (after copying into the
 // ring) and on error (before propagating).
+//
+// Unwrap calls run concurrently, at most MaxParallel at a time, so startup
+// cost grows with the slowest KMS round trip rather than with the number of
+// rotation keys.
 package kmsring
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	crypto "github.com/rbaliyan/config-crypto"
 )
@@ -17,14 +23,50 @@ import (
 // KeySize is the required AES-256 key size in bytes.
 const KeySize = 32
 
+// MaxParallel bounds the number of concurrent unwrap calls.
+const MaxParallel = 8
+
+// ForEach calls fn(i) for every i in [0, count), running at most MaxParallel
+// calls concurrently. It waits for all calls and returns their errors joined
+// in index order, or nil if every call succeeded.
+func ForEach(count int, fn func(i int) error) error {
+	errs := make([]error, count)
+	sem := make(chan struct{}, MaxParallel)
+	var wg sync.WaitGroup
+	for i := range count {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			errs[i] = fn(i)
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 // UnwrapFn unwraps the i-th encrypted key and returns (plaintext, id, err).
 // plaintext must be exactly KeySize bytes; Build zeroes it before returning.
+// Build calls it concurrently for different indexes.
 type UnwrapFn func(i int) (plaintext []byte, id string, err error)
 
 // Build unwraps count keys via unwrap and returns a crypto.KeyRingProvider
 // with the first key as current and the rest added for decryption. count must
 // be at least 1. errPrefix is prepended to wrapped errors ("awskms", ...).
+// If any unwrap fails, Build returns the failures joined in key order.
 func Build(count int, errPrefix string, unwrap UnwrapFn) (crypto.KeyRingProvider, error) {
+	return BuildSkipping(count, errPrefix, nil, unwrap)
+}
+
+// SkipFn is told about a non-current key that BuildSkipping left out of the
+// ring, with the error that prevented unwrapping it.
+type SkipFn func(id string, err error)
+
+// BuildSkipping is like Build, but when onSkip is non-nil a failed key other
+// than the first is reported to onSkip and left out of the ring instead of
+// failing the build. The first key is current, so its failure is still
+// fatal. onSkip is called sequentially, in key order, after every unwrap has
+// finished.
+func BuildSkipping(count int, errPrefix string, onSkip SkipFn, unwrap UnwrapFn) (crypto.KeyRingProvider, error) {
 	if count < 1 {
 		return nil, fmt.Errorf("%s: at least one encrypted key is required", errPrefix)
 	}
@@ -33,23 +75,41 @@ func Build(count int, errPrefix string, unwrap UnwrapFn) (crypto.KeyRingProvider
 		bytes []byte
 		id    string
 	}
-	keys := make([]decryptedKey, 0, count)
+	keys := make([]decryptedKey, count)
 	defer func() {
 		for _, k := range keys {
 			clear(k.bytes)
 		}
 	}()
 
-	for i := range count {
+	// Results are stored by index, so the first key stays current regardless
+	// of the order in which unwraps complete.
+	errs := make([]error, count)
+	_ = ForEach(count, func(i int) error {
 		plaintext, id, err := unwrap(i)
+		keys[i].id = id
 		if err != nil {
-			return nil, fmt.Errorf("%s: failed to decrypt key %q: %w", errPrefix, id, err)
+			errs[i] = fmt.Errorf("%s: failed to decrypt key %q: %w", errPrefix, id, err)
+			return nil
 		}
 		if len(plaintext) != KeySize {
 			clear(plaintext)
-			return nil, fmt.Errorf("%s: decrypted key %q is %d bytes, want %d", errPrefix, id, len(plaintext), KeySize)
+			errs[i] = fmt.Errorf("%s: decrypted key %q is %d bytes, want %d", errPrefix, id, len(plaintext), KeySize)
+			return nil
 		}
-		keys = append(keys, decryptedKey{bytes: plaintext, id: id})
+		keys[i].bytes = plaintext
+		return nil
+	})
+	if onSkip != nil && errs[0] == nil {
+		for i := 1; i < count; i++ {
+			if errs[i] != nil {
+				onSkip(keys[i].id, errs[i])
+				errs[i] = nil
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
 	}
 
 	ring, err := crypto.NewKeyRingProvider(keys[0].bytes, keys[0].id, 0)
@@ -57,6 +117,9 @@ func Build(count int, errPrefix string, unwrap UnwrapFn) (crypto.KeyRingProvider
 		return nil, fmt.Errorf("%s: %w", errPrefix, err)
 	}
 	for _, k := range keys[1:] {
+		if k.bytes == nil {
+			continue // skipped
+		}
 		if err := ring.AddKey(k.bytes, k.id, 0); err != nil {
 			return nil, fmt.Errorf("%s: %w", errPrefix, err)
 		}
@@ -0,0 +1,54 @@
+package kmsring
+
+import (
+	"context"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// Refreshable lists the keys once via list, builds a ring from them with
+// Build, and returns it with a crypto.Refresher that re-lists on demand or
+// on an interval. id must return an entry's ring ID without unwrapping it;
+// unwrap is called at startup for every entry and afterwards only for IDs
+// the ring does not hold yet. The first listed entry is current.
+func Refreshable[T any](ctx context.Context, errPrefix string,
+	list func(ctx context.Context) ([]T, error),
+	id func(T) string,
+	unwrap func(ctx context.Context, e T) ([]byte, error),
+	opts ...crypto.RefreshOption,
+) (crypto.KeyRingProvider, *crypto.Refresher, error) {
+	initial, err := list(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: list keys: %w", errPrefix, err)
+	}
+	ring, err := Build(len(initial), errPrefix, func(i int) ([]byte, string, error) {
+		pt, err := unwrap(ctx, initial[i])
+		return pt, id(initial[i]), err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fetch := func(ctx context.Context) ([]crypto.RefreshEntry, error) {
+		es, err := list(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: list keys: %w", errPrefix, err)
+		}
+		out := make([]crypto.RefreshEntry, len(es))
+		for i, e := range es {
+			out[i] = crypto.RefreshEntry{
+				ID:      id(e),
+				Current: i == 0,
+				Unwrap:  func(ctx context.Context) ([]byte, error) { return unwrap(ctx, e) },
+			}
+		}
+		return out, nil
+	}
+	r, err := crypto.NewRefresher(ring, fetch, opts...)
+	if err != nil {
+		_ = ring.Close()
+		return nil, nil, fmt.Errorf("%s: %w", errPrefix, err)
+	}
+	return ring, r, nil
+}
@@ -0,0 +1,79 @@
+package kmsring
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// Backoff defaults for RetryPolicy.
+const (
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 5 * time.Second
+)
+
+// RetryPolicy controls how an adapter retries KMS calls during
+// construction. The zero value makes one attempt with no timeout.
+type RetryPolicy struct {
+	// Attempts is the total number of calls, including the first.
+	Attempts int
+
+	// Initial is the delay before the first retry; it doubles up to Max.
+	// Each delay is jittered down by up to half. Zero uses the defaults.
+	Initial, Max time.Duration
+
+	// CallTimeout bounds each call; zero means no bound beyond ctx.
+	CallTimeout time.Duration
+
+	// RetryIf reports whether an error is worth retrying. Nil uses
+	// crypto.IsRetryable.
+	RetryIf func(error) bool
+}
+
+// Do calls fn until it succeeds, returns an error the policy does not
+// retry, or runs out of attempts or time.
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	retryIf := p.RetryIf
+	if retryIf == nil {
+		retryIf = crypto.IsRetryable
+	}
+	delay, maxDelay := p.Initial, p.Max
+	if delay <= 0 {
+		delay = DefaultInitialBackoff
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := p.call(ctx, fn)
+		if err == nil || attempt >= p.Attempts || !retryIf(err) || ctx.Err() != nil {
+			if attempt > 1 && err != nil {
+				err = fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+		wait := delay/2 + rand.N(delay/2+1) // #nosec G404 -- jitter, not a secret
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("after %d attempts: %w (%w)", attempt, ctx.Err(), err)
+		case <-t.C:
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// call runs fn once, under the per-call timeout if one is set.
+func (p RetryPolicy) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if p.CallTimeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.CallTimeout)
+	defer cancel()
+	return fn(ctx)
+}
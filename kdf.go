@@ -0,0 +1,272 @@
+package crypto
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDFAlgorithm names a passphrase key-derivation function.
+type KDFAlgorithm string
+
+const (
+	// KDFArgon2id is Argon2id (RFC 9106), the default. It is memory-hard and
+	// the best choice unless a compliance regime rules it out.
+	KDFArgon2id KDFAlgorithm = "argon2id"
+
+	// KDFScrypt is scrypt (RFC 7914), memory-hard and widely available.
+	KDFScrypt KDFAlgorithm = "scrypt"
+
+	// KDFPBKDF2 is PBKDF2 with HMAC-SHA256 (RFC 8018). It is not memory-hard;
+	// use it where FIPS 140 or legacy systems require it.
+	KDFPBKDF2 KDFAlgorithm = "pbkdf2-sha256"
+)
+
+// kdfSaltSize is the size of salts generated by the New*Params functions.
+const kdfSaltSize = 16
+
+// Upper bounds on KDF costs accepted from ParseKDFParams. Parameters come
+// from ciphertext headers when decrypting, so they are untrusted: the caps
+// hold one forged value to at most 1 GiB of memory and a few seconds of
+// CPU, a small multiple of the New*Params defaults.
+const (
+	maxArgon2Memory     = 1 << 20 // KiB, i.e. 1 GiB
+	maxArgon2Work       = 4 << 20 // memory in KiB times passes
+	maxScryptWork       = 1 << 23 // N*r*p; 1 GiB of memory at p=1
+	maxPBKDF2Iterations = 2_000_000
+	minKDFSaltSize      = 8
+)
+
+// KDFParams records everything needed to derive a key from a passphrase:
+// the algorithm, its cost parameters, and the salt. String encodes it in
+// the PHC string format (e.g. "$argon2id$v=19$m=65536,t=3,p=4$<salt>"),
+// which NewPassphraseProvider uses as the key ID so that every ciphertext
+// names the derivation that produced its key.
+type KDFParams struct {
+	// Algorithm selects the KDF.
+	Algorithm KDFAlgorithm
+
+	// Salt is the per-passphrase random salt.
+	Salt []byte
+
+	// Time is the Argon2id time cost (passes over memory).
+	Time uint32
+
+	// Memory is the Argon2id memory cost in KiB.
+	Memory uint32
+
+	// Threads is the Argon2id parallelism.
+	Threads uint8
+
+	// LogN is the scrypt CPU/memory cost as log2(N).
+	LogN uint8
+
+	// R and P are the scrypt block size and parallelism.
+	R, P int
+
+	// Iterations is the PBKDF2 iteration count.
+	Iterations int
+}
+
+// NewArgon2idParams returns Argon2id parameters with a fresh random salt and
+// the RFC 9106 second recommended profile scaled to 64 MiB: t=3, m=64 MiB,
+// p=4.
+func NewArgon2idParams() (KDFParams, error) {
+	return newKDFParams(KDFParams{Algorithm: KDFArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4})
+}
+
+// NewScryptParams returns scrypt parameters with a fresh random salt and
+// N=2^17, r=8, p=1 (128 MiB).
+func NewScryptParams() (KDFParams, error) {
+	return newKDFParams(KDFParams{Algorithm: KDFScrypt, LogN: 17, R: 8, P: 1})
+}
+
+// NewPBKDF2Params returns PBKDF2-HMAC-SHA256 parameters with a fresh random
+// salt and 600,000 iterations.
+func NewPBKDF2Params() (KDFParams, error) {
+	return newKDFParams(KDFParams{Algorithm: KDFPBKDF2, Iterations: 600_000})
+}
+
+func newKDFParams(p KDFParams) (KDFParams, error) {
+	p.Salt = make([]byte, kdfSaltSize)
+	if _, err := rand.Read(p.Salt); err != nil {
+		return KDFParams{}, fmt.Errorf("crypto: generate salt: %w", err)
+	}
+	return p, nil
+}
+
+// Validate reports whether p names a known algorithm with costs inside the
+// accepted bounds. The error wraps ErrInvalidKDFParams.
+func (p KDFParams) Validate() error {
+	if len(p.Salt) < minKDFSaltSize {
+		return fmt.Errorf("%w: salt must be at least %d bytes", ErrInvalidKDFParams, minKDFSaltSize)
+	}
+	switch p.Algorithm {
+	case KDFArgon2id:
+		if p.Threads == 0 || p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+			return fmt.Errorf("%w: argon2id memory %d KiB out of range", ErrInvalidKDFParams, p.Memory)
+		}
+		if p.Time == 0 || uint64(p.Time)*uint64(p.Memory) > maxArgon2Work {
+			return fmt.Errorf("%w: argon2id time %d out of range", ErrInvalidKDFParams, p.Time)
+		}
+	case KDFScrypt:
+		if p.LogN == 0 || p.LogN > 23 {
+			return fmt.Errorf("%w: scrypt ln %d out of range", ErrInvalidKDFParams, p.LogN)
+		}
+		if p.R <= 0 || p.P <= 0 || uint64(p.R)*uint64(p.P)<<p.LogN > maxScryptWork {
+			return fmt.Errorf("%w: scrypt r=%d p=%d out of range", ErrInvalidKDFParams, p.R, p.P)
+		}
+	case KDFPBKDF2:
+		if p.Iterations <= 0 || p.Iterations > maxPBKDF2Iterations {
+			return fmt.Errorf("%w: pbkdf2 iterations %d out of range", ErrInvalidKDFParams, p.Iterations)
+		}
+	default:
+		return fmt.Errorf("%w: unknown algorithm %q", ErrInvalidKDFParams, p.Algorithm)
+	}
+	return nil
+}
+
+// String returns p in PHC string format.
+func (p KDFParams) String() string {
+	salt := base64.RawStdEncoding.EncodeToString(p.Salt)
+	switch p.Algorithm {
+	case KDFArgon2id:
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s", argon2.Version, p.Memory, p.Time, p.Threads, salt)
+	case KDFScrypt:
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d$%s", p.LogN, p.R, p.P, salt)
+	case KDFPBKDF2:
+		return fmt.Sprintf("$pbkdf2-sha256$i=%d$%s", p.Iterations, salt)
+	default:
+		return "$" + string(p.Algorithm) + "$" + salt
+	}
+}
+
+// ParseKDFParams parses a PHC string produced by KDFParams.String and
+// validates it. Errors wrap ErrInvalidKDFParams.
+func ParseKDFParams(s string) (KDFParams, error) {
+	parts := strings.Split(s, "$")
+	if len(parts) < 4 || parts[0] != "" {
+		return KDFParams{}, fmt.Errorf("%w: %q is not a PHC string", ErrInvalidKDFParams, s)
+	}
+	p := KDFParams{Algorithm: KDFAlgorithm(parts[1])}
+	var params, salt string
+	switch p.Algorithm {
+	case KDFArgon2id:
+		if len(parts) != 5 || parts[2] != "v="+strconv.Itoa(argon2.Version) {
+			return KDFParams{}, fmt.Errorf("%w: unsupported argon2id string %q", ErrInvalidKDFParams, s)
+		}
+		params, salt = parts[3], parts[4]
+	default:
+		if len(parts) != 4 {
+			return KDFParams{}, fmt.Errorf("%w: malformed %q", ErrInvalidKDFParams, s)
+		}
+		params, salt = parts[2], parts[3]
+	}
+
+	kv, err := parsePHCParams(params)
+	if err != nil {
+		return KDFParams{}, fmt.Errorf("%w: %q: %v", ErrInvalidKDFParams, s, err)
+	}
+	switch p.Algorithm {
+	case KDFArgon2id:
+		var m, t, th uint64
+		if m, err = kv.uint("m", 32); err == nil {
+			if t, err = kv.uint("t", 32); err == nil {
+				th, err = kv.uint("p", 8)
+			}
+		}
+		p.Memory, p.Time, p.Threads = uint32(m), uint32(t), uint8(th) // #nosec G115 -- bit sizes checked by kv.uint
+	case KDFScrypt:
+		var ln, r, par uint64
+		if ln, err = kv.uint("ln", 8); err == nil {
+			if r, err = kv.uint("r", 31); err == nil {
+				par, err = kv.uint("p", 31)
+			}
+		}
+		p.LogN, p.R, p.P = uint8(ln), int(r), int(par) // #nosec G115 -- bit sizes checked by kv.uint
+	case KDFPBKDF2:
+		var i uint64
+		i, err = kv.uint("i", 31)
+		p.Iterations = int(i) // #nosec G115 -- bit size checked by kv.uint
+	}
+	if err != nil {
+		return KDFParams{}, fmt.Errorf("%w: %q: %v", ErrInvalidKDFParams, s, err)
+	}
+
+	if p.Salt, err = base64.RawStdEncoding.DecodeString(salt); err != nil {
+		return KDFParams{}, fmt.Errorf("%w: %q: salt: %v", ErrInvalidKDFParams, s, err)
+	}
+	if err := p.Validate(); err != nil {
+		return KDFParams{}, err
+	}
+	return p, nil
+}
+
+// phcParams holds the comma-separated name=value list of a PHC string.
+type phcParams map[string]string
+
+func parsePHCParams(s string) (phcParams, error) {
+	kv := phcParams{}
+	for f := range strings.SplitSeq(s, ",") {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("parameter %q has no value", f)
+		}
+		if _, dup := kv[k]; dup {
+			return nil, fmt.Errorf("duplicate parameter %q", k)
+		}
+		kv[k] = v
+	}
+	return kv, nil
+}
+
+func (kv phcParams) uint(name string, bits int) (uint64, error) {
+	v, ok := kv[name]
+	if !ok {
+		return 0, fmt.Errorf("missing parameter %q", name)
+	}
+	n, err := strconv.ParseUint(v, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("parameter %q: %w", name, err)
+	}
+	return n, nil
+}
+
+// DeriveKey derives a 32-byte key from passphrase with p. The same
+// passphrase and params always yield the same key. The caller should clear
+// the result when done.
+//
+// PBKDF2 uses the standard library implementation, which is covered by Go's
+// FIPS 140-3 module but takes the password as a string, so one copy of the
+// passphrase lives on the heap until garbage-collected.
+func DeriveKey(passphrase []byte, p KDFParams) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("%w: empty passphrase", ErrInvalidKDFParams)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	switch p.Algorithm {
+	case KDFArgon2id:
+		return argon2.IDKey(passphrase, p.Salt, p.Time, p.Memory, p.Threads, aesKeySize), nil
+	case KDFScrypt:
+		key, err := scrypt.Key(passphrase, p.Salt, 1<<p.LogN, p.R, p.P, aesKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: scrypt: %w", err)
+		}
+		return key, nil
+	default: // KDFPBKDF2, checked by Validate
+		key, err := pbkdf2.Key(sha256.New, string(passphrase), p.Salt, p.Iterations, aesKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("crypto: pbkdf2: %w", err)
+		}
+		return key, nil
+	}
+}
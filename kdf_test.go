@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// cheapKDFParams returns fast parameters for each KDF, for tests only.
+func cheapKDFParams() []KDFParams {
+	salt := []byte("0123456789abcdef")
+	return []KDFParams{
+		{Algorithm: KDFArgon2id, Salt: salt, Time: 1, Memory: 64, Threads: 1},
+		{Algorithm: KDFScrypt, Salt: salt, LogN: 10, R: 8, P: 1},
+		{Algorithm: KDFPBKDF2, Salt: salt, Iterations: 1000},
+	}
+}
+
+func TestKDFParamsStringRoundTrip(t *testing.T) {
+	for _, p := range cheapKDFParams() {
+		t.Run(string(p.Algorithm), func(t *testing.T) {
+			s := p.String()
+			if !strings.HasPrefix(s, "$"+string(p.Algorithm)+"$") {
+				t.Errorf("String() = %q", s)
+			}
+			got, err := ParseKDFParams(s)
+			if err != nil {
+				t.Fatalf("ParseKDFParams(%q): %v", s, err)
+			}
+			if got.String() != s || !bytes.Equal(got.Salt, p.Salt) {
+				t.Errorf("round trip = %+v, want %+v", got, p)
+			}
+		})
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	pass := []byte("correct horse battery staple")
+	seen := map[string]bool{}
+	for _, p := range cheapKDFParams() {
+		k1, err := DeriveKey(pass, p)
+		if err != nil {
+			t.Fatalf("DeriveKey %s: %v", p.Algorithm, err)
+		}
+		k2, _ := DeriveKey(pass, p)
+		if len(k1) != aesKeySize || !bytes.Equal(k1, k2) {
+			t.Errorf("%s: key not deterministic or wrong size (%d)", p.Algorithm, len(k1))
+		}
+		if seen[string(k1)] {
+			t.Errorf("%s: key collides with another KDF", p.Algorithm)
+		}
+		seen[string(k1)] = true
+	}
+	if _, err := DeriveKey(nil, cheapKDFParams()[0]); !IsInvalidKDFParams(err) {
+		t.Errorf("empty passphrase: got %v, want ErrInvalidKDFParams", err)
+	}
+}
+
+func TestParseKDFParamsRejects(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"key-1",
+		"$md5$x$c2FsdHNhbHQ",
+		"$argon2id$v=16$m=64,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$argon2id$v=19$m=99999999,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$argon2id$v=19$m=4194304,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg", // 4 GiB
+		"$argon2id$v=19$m=1048576,t=64,p=1$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$scrypt$ln=22,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$scrypt$ln=17,r=8,p=1024$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$pbkdf2-sha256$i=10000000$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$argon2id$v=19$m=64,t=1$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$scrypt$ln=40,r=8,p=1$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$scrypt$ln=10,r=8,p=1,p=2$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$pbkdf2-sha256$i=0$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$pbkdf2-sha256$i=1000$c2FsdA", // salt too short
+		"$pbkdf2-sha256$i=1000$!!",
+	} {
+		if _, err := ParseKDFParams(s); !IsInvalidKDFParams(err) {
+			t.Errorf("ParseKDFParams(%q): got %v, want ErrInvalidKDFParams", s, err)
+		}
+	}
+}
+
+func TestNewKDFParamsDefaults(t *testing.T) {
+	for _, fn := range []func() (KDFParams, error){NewArgon2idParams, NewScryptParams, NewPBKDF2Params} {
+		p, err := fn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s defaults invalid: %v", p.Algorithm, err)
+		}
+		if len(p.Salt) != kdfSaltSize {
+			t.Errorf("%s salt size = %d", p.Algorithm, len(p.Salt))
+		}
+	}
+}
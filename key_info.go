@@ -0,0 +1,243 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// KeyInfo describes one key held by a provider.
+type KeyInfo struct {
+	// ID is the key identifier written into ciphertext headers.
+	ID string
+
+	// Rank is the ordering value passed to NewKeyRingProvider or AddKey.
+	Rank uint64
+
+	// Algorithm names the AEAD the provider uses for new encryptions,
+	// e.g. "AES-256-GCM" or "ChaCha20-Poly1305".
+	Algorithm string
+
+	// CreatedAt is when the key was created; zero if unknown.
+	CreatedAt time.Time
+
+	// NotAfter is the key's expiry, which doubles as its rotation deadline.
+	// The provider refuses to encrypt with the key from NotAfter on, but
+	// still decrypts with it. Zero means the key never expires.
+	NotAfter time.Time
+
+	// Usage restricts the operations the key may be used for.
+	Usage KeyUsage
+}
+
+// Expired reports whether the key has expired at now.
+func (k KeyInfo) Expired(now time.Time) bool {
+	return !k.NotAfter.IsZero() && !now.Before(k.NotAfter)
+}
+
+// KeyExpiredError reports an attempt to encrypt with a key whose NotAfter
+// has passed. It tells callers to rotate, as opposed to ErrKeyNotFound,
+// which means the key is missing altogether.
+type KeyExpiredError struct {
+	// KeyID is the expired key.
+	KeyID string
+
+	// NotAfter is when the key expired.
+	NotAfter time.Time
+}
+
+// Error implements error.
+func (e *KeyExpiredError) Error() string {
+	return fmt.Sprintf("%s: %q expired at %s", ErrKeyExpired, e.KeyID, e.NotAfter.Format(time.RFC3339))
+}
+
+// Unwrap returns ErrKeyExpired so errors.Is and IsKeyExpired match.
+func (e *KeyExpiredError) Unwrap() error { return ErrKeyExpired }
+
+// KeyUsage restricts the operations a key may be used for.
+type KeyUsage uint8
+
+const (
+	// KeyUsageAny allows both encryption and decryption. It is the default.
+	KeyUsageAny KeyUsage = iota
+
+	// KeyUsageDecryptOnly marks a key that may only decrypt existing data,
+	// such as an old rotation key scheduled for retirement. It cannot be
+	// made current, and Encrypt fails if the current key is marked so.
+	KeyUsageDecryptOnly
+
+	// KeyUsageEncryptOnly marks a key that may only encrypt new data, such
+	// as a write-only key held by a producer that must not read values back.
+	KeyUsageEncryptOnly
+)
+
+// String returns the usage name.
+func (u KeyUsage) String() string {
+	switch u {
+	case KeyUsageAny:
+		return "any"
+	case KeyUsageDecryptOnly:
+		return "decrypt-only"
+	case KeyUsageEncryptOnly:
+		return "encrypt-only"
+	default:
+		return fmt.Sprintf("KeyUsage(%d)", uint8(u))
+	}
+}
+
+func (u KeyUsage) canEncrypt() bool { return u != KeyUsageDecryptOnly }
+func (u KeyUsage) canDecrypt() bool { return u != KeyUsageEncryptOnly }
+
+// KeyMetadata carries lifecycle metadata for a key.
+type KeyMetadata struct {
+	CreatedAt time.Time
+	NotAfter  time.Time
+	Usage     KeyUsage
+}
+
+// KeyInfoProvider is implemented by Providers that track per-key metadata.
+// The built-in NewProvider and NewKeyRingProvider implementations satisfy
+// it; keys start with no metadata (zero CreatedAt and NotAfter).
+type KeyInfoProvider interface {
+	Provider
+
+	// KeyInfo returns the metadata of the key identified by id. It returns
+	// ErrKeyNotFound if id is unknown.
+	KeyInfo(id string) (KeyInfo, error)
+
+	// SetKeyMetadata replaces the metadata of the key identified by id.
+	// Once the current key's NotAfter has passed, Encrypt fails with
+	// ErrKeyExpired until a newer key is made current. Keys whose Usage
+	// forbids an operation fail it with ErrKeyUsageDenied.
+	SetKeyMetadata(id string, md KeyMetadata) error
+}
+
+// KeyLister is implemented by Providers that can enumerate the keys they
+// hold, so rotation tooling and diagnostics can discover which key IDs a
+// provider can decrypt with. The built-in NewProvider and NewKeyRingProvider
+// implementations satisfy it, and so do the providers returned by the KMS,
+// Vault, and GPG packages.
+type KeyLister interface {
+	// ListKeyIDs returns the IDs of all keys held, sorted. A closed provider
+	// returns nil. Use KeyInfoProvider to check whether a key is
+	// encrypt-only.
+	ListKeyIDs() []string
+}
+
+// Compile-time interface checks.
+var (
+	_ KeyInfoProvider = (*keyRingProvider)(nil)
+	_ KeyLister       = (*keyRingProvider)(nil)
+)
+
+// ListKeyIDs returns the sorted IDs of all keys in the ring.
+func (p *keyRingProvider) ListKeyIDs() []string {
+	s := p.state.Load()
+	if s.closed {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(s.keys))
+}
+
+// KeyInfo returns the metadata of the key identified by id.
+func (p *keyRingProvider) KeyInfo(id string) (KeyInfo, error) {
+	s := p.state.Load()
+	if s.closed {
+		return KeyInfo{}, ErrProviderClosed
+	}
+	k, ok := s.keys[id]
+	if !ok {
+		return KeyInfo{}, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return KeyInfo{
+		ID:        id,
+		Rank:      k.rank,
+		Algorithm: algorithmName(p.alg),
+		CreatedAt: k.createdAt,
+		NotAfter:  k.notAfter,
+		Usage:     k.usage,
+	}, nil
+}
+
+// SetKeyMetadata replaces the metadata of the key identified by id.
+func (p *keyRingProvider) SetKeyMetadata(id string, md KeyMetadata) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.state.Load()
+	if s.closed {
+		return ErrProviderClosed
+	}
+	k, ok := s.keys[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	if md.Usage > KeyUsageEncryptOnly {
+		return fmt.Errorf("crypto: invalid key usage %s", md.Usage)
+	}
+	k.createdAt, k.notAfter, k.usage = md.CreatedAt, md.NotAfter, md.Usage
+	next := s.clone()
+	next.keys[id] = k
+	p.state.Store(next)
+	p.watchers.notify(KeyEvent{Type: KeyMetadataChanged, KeyID: id})
+	return nil
+}
+
+// openForDecrypt is openKey for decryption: it refuses encrypt-only keys.
+func (s *keyRingState) openForDecrypt(id string) ([]byte, func(), error) {
+	if k, ok := s.keys[id]; ok && !k.usage.canDecrypt() {
+		return nil, nil, fmt.Errorf("%w: %q is %s", ErrKeyUsageDenied, id, k.usage)
+	}
+	return s.openKey(id)
+}
+
+// algorithmName returns the display name of a header algorithm byte.
+func algorithmName(alg byte) string {
+	switch alg {
+	case algAES256GCM:
+		return "AES-256-GCM"
+	case algChaCha20Poly1305:
+		return "ChaCha20-Poly1305"
+	default:
+		return fmt.Sprintf("unknown(%d)", alg)
+	}
+}
+
+// WithExpiredKeyWarning makes the Codec report values decrypted with a key
+// whose NotAfter has passed, so stale data can be found and re-encrypted.
+// Decryption still succeeds. fn receives the key's metadata; a nil fn logs
+// a warning via slog. It only takes effect when the Codec's provider
+// implements KeyInfoProvider.
+func WithExpiredKeyWarning(fn func(ctx context.Context, info KeyInfo)) CodecOption {
+	return func(o *codecOptions) {
+		if fn == nil {
+			fn = func(ctx context.Context, info KeyInfo) {
+				slog.WarnContext(ctx, "crypto: value decrypted with expired key",
+					"key_id", info.ID, "not_after", info.NotAfter)
+			}
+		}
+		o.onExpiredKey = fn
+	}
+}
+
+// warnIfExpired calls the expired-key callback when data was encrypted with
+// a key whose NotAfter has passed.
+func (c *Codec) warnIfExpired(ctx context.Context, data []byte) {
+	if c.onExpiredKey == nil {
+		return
+	}
+	kip, ok := c.provider.(KeyInfoProvider)
+	if !ok {
+		return
+	}
+	id, err := KeyIDOf(data)
+	if err != nil {
+		return
+	}
+	info, err := kip.KeyInfo(id)
+	if err == nil && info.Expired(time.Now()) {
+		c.onExpiredKey(ctx, info)
+	}
+}
@@ -0,0 +1,210 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+func TestKeyInfo(t *testing.T) {
+	p := mustNewKeyRingProvider(t, makeKey(32), "key-1", 3)
+	kip := p.(KeyInfoProvider)
+
+	info, err := kip.KeyInfo("key-1")
+	if err != nil {
+		t.Fatalf("KeyInfo: %v", err)
+	}
+	if info.ID != "key-1" || info.Rank != 3 || info.Algorithm != "AES-256-GCM" {
+		t.Errorf("KeyInfo = %+v", info)
+	}
+	if !info.CreatedAt.IsZero() || !info.NotAfter.IsZero() || info.Expired(time.Now()) {
+		t.Errorf("new key has metadata: %+v", info)
+	}
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := created.AddDate(1, 0, 0)
+	if err := kip.SetKeyMetadata("key-1", KeyMetadata{CreatedAt: created, NotAfter: notAfter}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	info, _ = kip.KeyInfo("key-1")
+	if !info.CreatedAt.Equal(created) || !info.NotAfter.Equal(notAfter) {
+		t.Errorf("KeyInfo after SetKeyMetadata = %+v", info)
+	}
+	if info.Expired(notAfter.Add(-time.Second)) || !info.Expired(notAfter) {
+		t.Error("Expired boundary wrong")
+	}
+
+	if _, err := kip.KeyInfo("missing"); !IsKeyNotFound(err) {
+		t.Errorf("KeyInfo(missing) = %v, want ErrKeyNotFound", err)
+	}
+	if err := kip.SetKeyMetadata("missing", KeyMetadata{}); !IsKeyNotFound(err) {
+		t.Errorf("SetKeyMetadata(missing) = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestExpiredKeyRefusesEncrypt(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewKeyRingProvider(t, makeKey(32), "old", 1)
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if err := p.(KeyInfoProvider).SetKeyMetadata("old", KeyMetadata{NotAfter: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+
+	c, err := NewCodec(jsoncodec.New(), p)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	_, err = c.Encode(ctx, "value")
+	if !IsKeyExpired(err) || IsKeyNotFound(err) {
+		t.Errorf("Encode with expired key = %v, want ErrKeyExpired", err)
+	}
+	var expired *KeyExpiredError
+	if !errors.As(err, &expired) || expired.KeyID != "old" || expired.NotAfter.IsZero() {
+		t.Errorf("Encode error %v: want *KeyExpiredError for old", err)
+	}
+	if err := p.HealthCheck(ctx); !IsKeyExpired(err) {
+		t.Errorf("HealthCheck with expired key = %v, want ErrKeyExpired", err)
+	}
+	// Existing ciphertext stays readable.
+	if _, err := p.Decrypt(ctx, ct); err != nil {
+		t.Errorf("Decrypt with expired key: %v", err)
+	}
+
+	// Rotating to a fresh key restores encryption.
+	if err := p.AddKey(makeKey(32), "new", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if err := p.SetCurrentKey("new"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	if _, err := c.Encode(ctx, "value"); err != nil {
+		t.Errorf("Encode after rotation: %v", err)
+	}
+}
+
+func TestWithExpiredKeyWarning(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewKeyRingProvider(t, makeKey(32), "key-1", 1)
+
+	var warned []KeyInfo
+	c, err := NewCodec(jsoncodec.New(), p, WithExpiredKeyWarning(func(_ context.Context, info KeyInfo) {
+		warned = append(warned, info)
+	}))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	data, err := c.Encode(ctx, "value")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var got string
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(warned) != 0 {
+		t.Fatalf("warned for unexpired key: %v", warned)
+	}
+
+	if err := p.(KeyInfoProvider).SetKeyMetadata("key-1", KeyMetadata{NotAfter: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode with expired key: %v", err)
+	}
+	if got != "value" {
+		t.Errorf("Decode = %q", got)
+	}
+	if len(warned) != 1 || warned[0].ID != "key-1" {
+		t.Errorf("warnings = %+v, want one for key-1", warned)
+	}
+}
+
+func TestKeyUsagePolicy(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewKeyRingProvider(t, makeKey(32), "old", 1)
+	kip := p.(KeyInfoProvider)
+	c, err := NewCodec(jsoncodec.New(), p)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	oldData, err := c.Encode(ctx, "old value")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	// Retiring the current key stops new encryptions but not reads.
+	if err := kip.SetKeyMetadata("old", KeyMetadata{Usage: KeyUsageDecryptOnly}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	if _, err := c.Encode(ctx, "new value"); !IsKeyUsageDenied(err) {
+		t.Errorf("Encode with decrypt-only key = %v, want ErrKeyUsageDenied", err)
+	}
+	var got string
+	if err := c.Decode(ctx, oldData, &got); err != nil || got != "old value" {
+		t.Errorf("Decode with decrypt-only key = %q, %v", got, err)
+	}
+
+	// A decrypt-only key cannot be made current again.
+	if err := p.AddKey(makeKey(32), "new", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if err := p.SetCurrentKey("new"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	if err := p.SetCurrentKey("old"); !IsKeyUsageDenied(err) {
+		t.Errorf("SetCurrentKey(decrypt-only) = %v, want ErrKeyUsageDenied", err)
+	}
+
+	// An encrypt-only key writes but cannot read back.
+	if err := kip.SetKeyMetadata("new", KeyMetadata{Usage: KeyUsageEncryptOnly}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	newData, err := c.Encode(ctx, "new value")
+	if err != nil {
+		t.Fatalf("Encode with encrypt-only key: %v", err)
+	}
+	if err := c.Decode(ctx, newData, &got); !IsKeyUsageDenied(err) {
+		t.Errorf("Decode with encrypt-only key = %v, want ErrKeyUsageDenied", err)
+	}
+
+	if err := kip.SetKeyMetadata("new", KeyMetadata{Usage: KeyUsage(9)}); err == nil {
+		t.Error("invalid usage accepted")
+	}
+	if info, _ := kip.KeyInfo("old"); info.Usage != KeyUsageDecryptOnly || info.Usage.String() != "decrypt-only" {
+		t.Errorf("KeyInfo(old).Usage = %v", info.Usage)
+	}
+}
+
+func TestListKeyIDs(t *testing.T) {
+	p := mustNewKeyRingProvider(t, makeKey(32), "b", 1)
+	for _, id := range []string{"c", "a"} {
+		if err := p.AddKey(makeKey(32), id, 0); err != nil {
+			t.Fatalf("AddKey(%s): %v", id, err)
+		}
+	}
+	kl, ok := p.(KeyLister)
+	if !ok {
+		t.Fatal("keyRingProvider does not implement KeyLister")
+	}
+	if got := kl.ListKeyIDs(); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("ListKeyIDs = %v", got)
+	}
+	if err := p.RemoveKey("c"); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+	if got := kl.ListKeyIDs(); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("ListKeyIDs after RemoveKey = %v", got)
+	}
+	_ = p.Close()
+	if got := kl.ListKeyIDs(); got != nil {
+		t.Errorf("ListKeyIDs after Close = %v", got)
+	}
+}
@@ -0,0 +1,271 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// currentKeySlot is the cache slot for CurrentKey results. Key IDs are never
+// empty, so it cannot collide with a KeyByID entry.
+const currentKeySlot = ""
+
+// CachingKeySource is a KeySource decorator that caches keys from another
+// source for a TTL. Cached key bytes are held in the same guarded memory as
+// the key-ring provider's keys.
+//
+//   - Within the TTL, lookups are served from the cache.
+//   - With WithRefreshAhead, a lookup in the final stretch of the TTL
+//     triggers a background refresh so callers rarely wait on the source.
+//   - Once the TTL has passed, lookups fetch synchronously. If that fetch
+//     fails and WithMaxStale allows it, the expired entry is served instead.
+//
+// Stats reports hit, miss, refresh, and error counts for metrics.
+type CachingKeySource struct {
+	src          KeySource
+	ttl          time.Duration
+	refreshAhead time.Duration
+	maxStale     time.Duration
+	now          func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]*keySourceEntry
+	refreshing map[string]bool
+	closed     bool
+	wg         sync.WaitGroup
+
+	// bgCtx bounds background refreshes; Close cancels it so a hung source
+	// cannot block Close.
+	bgCtx    context.Context
+	bgCancel context.CancelFunc
+
+	hits, misses, refreshes, refreshErrors, staleServed atomic.Uint64
+}
+
+type keySourceEntry struct {
+	id      string
+	key     sealedKey
+	fetched time.Time
+}
+
+// KeyCacheOption configures NewCachingKeySource.
+type KeyCacheOption func(*CachingKeySource)
+
+// WithRefreshAhead starts a background refresh when a cached key is used
+// within d of its expiry. Each refresh is given at most the cache TTL, and
+// Close cancels refreshes still in flight. The default of 0 disables
+// refresh-ahead.
+func WithRefreshAhead(d time.Duration) KeyCacheOption {
+	return func(c *CachingKeySource) { c.refreshAhead = d }
+}
+
+// WithMaxStale lets an expired key be served for up to d past its TTL when
+// refetching it fails, so a source outage does not immediately break
+// encryption and decryption. The default of 0 never serves stale keys.
+func WithMaxStale(d time.Duration) KeyCacheOption {
+	return func(c *CachingKeySource) { c.maxStale = d }
+}
+
+// KeyCacheStats is a snapshot of CachingKeySource counters.
+type KeyCacheStats struct {
+	Hits          uint64 // lookups served from a fresh cache entry
+	Misses        uint64 // lookups that fetched synchronously from the source
+	Refreshes     uint64 // successful background refreshes
+	RefreshErrors uint64 // failed fetches, background or synchronous
+	StaleServed   uint64 // lookups served from an expired entry after a failed fetch
+}
+
+// Compile-time interface check.
+var _ KeySource = (*CachingKeySource)(nil)
+
+// NewCachingKeySource returns a CachingKeySource that caches keys from src
+// for ttl. Close it to wipe the cached keys and wait for background
+// refreshes; a keySourceProvider wrapping it does so on Close.
+func NewCachingKeySource(src KeySource, ttl time.Duration, opts ...KeyCacheOption) (*CachingKeySource, error) {
+	if src == nil {
+		return nil, errors.New("crypto: NewCachingKeySource source is nil")
+	}
+	if ttl <= 0 {
+		return nil, errors.New("crypto: NewCachingKeySource ttl must be positive")
+	}
+	c := &CachingKeySource{
+		src:        src,
+		ttl:        ttl,
+		now:        time.Now,
+		entries:    make(map[string]*keySourceEntry),
+		refreshing: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.bgCtx, c.bgCancel = context.WithCancel(context.Background())
+	return c, nil
+}
+
+// CurrentKey returns the source's current key, from the cache when fresh.
+func (c *CachingKeySource) CurrentKey(ctx context.Context) (Key, error) {
+	return c.get(ctx, currentKeySlot, func(ctx context.Context) (Key, error) {
+		return c.src.CurrentKey(ctx)
+	})
+}
+
+// KeyByID returns the key with the given ID, from the cache when fresh.
+func (c *CachingKeySource) KeyByID(ctx context.Context, id string) (Key, error) {
+	if id == currentKeySlot {
+		return Key{}, fmt.Errorf("%w: key ID must not be empty", ErrInvalidKeyID)
+	}
+	return c.get(ctx, id, func(ctx context.Context) (Key, error) {
+		return c.src.KeyByID(ctx, id)
+	})
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *CachingKeySource) Stats() KeyCacheStats {
+	return KeyCacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Refreshes:     c.refreshes.Load(),
+		RefreshErrors: c.refreshErrors.Load(),
+		StaleServed:   c.staleServed.Load(),
+	}
+}
+
+// Invalidate drops every cached key so the next lookups go to the source.
+func (c *CachingKeySource) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.wipeLocked()
+}
+
+// Close cancels and waits for background refreshes, wipes the cache, and
+// closes the underlying source if it implements io.Closer. Safe to call
+// multiple times; subsequent calls are no-ops.
+func (c *CachingKeySource) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	c.bgCancel()
+	c.wg.Wait()
+
+	c.mu.Lock()
+	c.wipeLocked()
+	c.mu.Unlock()
+	if cl, ok := c.src.(interface{ Close() error }); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+// get serves slot from the cache or fetch, following the TTL, refresh-ahead,
+// and max-stale rules. Cached entries are copied out while mu is held, since
+// store and Invalidate wipe replaced entries under mu.
+func (c *CachingKeySource) get(ctx context.Context, slot string, fetch func(context.Context) (Key, error)) (Key, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return Key{}, ErrProviderClosed
+	}
+	e := c.entries[slot]
+	now := c.now()
+	if e != nil && now.Before(e.fetched.Add(c.ttl)) {
+		if c.refreshAhead > 0 && !now.Before(e.fetched.Add(c.ttl-c.refreshAhead)) && !c.refreshing[slot] {
+			c.refreshing[slot] = true
+			c.wg.Add(1)
+			go c.refresh(slot, fetch)
+		}
+		k, err := openCached(e)
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return k, err
+	}
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	k, err := fetch(ctx)
+	if err != nil {
+		c.refreshErrors.Add(1)
+		if c.maxStale > 0 {
+			if k, ok := c.openStale(slot, now); ok {
+				c.staleServed.Add(1)
+				return k, nil
+			}
+		}
+		return Key{}, err
+	}
+	c.store(slot, k)
+	return k, nil
+}
+
+// openStale returns a copy of the entry in slot if it is within the max-stale
+// window at now. The entry may have been replaced since get looked it up.
+func (c *CachingKeySource) openStale(slot string, now time.Time) (Key, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[slot]
+	if c.closed || e == nil || !now.Before(e.fetched.Add(c.ttl+c.maxStale)) {
+		return Key{}, false
+	}
+	k, err := openCached(e)
+	return k, err == nil
+}
+
+// refresh refetches slot in the background, giving the source at most one
+// TTL. Failures leave the current entry in place until it expires.
+func (c *CachingKeySource) refresh(slot string, fetch func(context.Context) (Key, error)) {
+	defer c.wg.Done()
+	ctx, cancel := context.WithTimeout(c.bgCtx, c.ttl)
+	k, err := fetch(ctx)
+	cancel()
+
+	c.mu.Lock()
+	delete(c.refreshing, slot)
+	c.mu.Unlock()
+	if err != nil {
+		c.refreshErrors.Add(1)
+		return
+	}
+	c.refreshes.Add(1)
+	c.store(slot, k)
+	clear(k.Bytes)
+}
+
+// store seals a copy of k into slot, replacing and wiping any previous entry.
+func (c *CachingKeySource) store(slot string, k Key) {
+	e := &keySourceEntry{id: k.ID, key: sealKey(k.Bytes, MlockSupported()), fetched: c.now()}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		e.key.wipe()
+		return
+	}
+	if old := c.entries[slot]; old != nil {
+		old.key.wipe()
+	}
+	c.entries[slot] = e
+}
+
+// wipeLocked wipes and drops every entry. Caller must hold mu.
+func (c *CachingKeySource) wipeLocked() {
+	for slot, e := range c.entries {
+		e.key.wipe()
+		delete(c.entries, slot)
+	}
+}
+
+// openCached returns a caller-owned copy of a cached key.
+func openCached(e *keySourceEntry) (Key, error) {
+	b, release, err := e.key.open()
+	if err != nil {
+		return Key{}, err
+	}
+	defer release()
+	return Key{ID: e.id, Bytes: append([]byte(nil), b...)}, nil
+}
@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ThrottledError is returned by a rate-limited KeySource when a lookup
+// exceeds the limit. It wraps ErrThrottled.
+type ThrottledError struct {
+	// RetryAfter is how long until the limiter will admit another lookup.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrThrottled, e.RetryAfter)
+}
+
+// Unwrap returns ErrThrottled so errors.Is and IsThrottled match.
+func (e *ThrottledError) Unwrap() error { return ErrThrottled }
+
+// rateLimitedKeySource is a KeySource that admits lookups through a token
+// bucket before passing them to the wrapped source.
+type rateLimitedKeySource struct {
+	src   KeySource
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimitedKeySource returns a KeySource that lets at most perSecond
+// CurrentKey and KeyByID calls per second reach src, with bursts of up to
+// burst calls. Calls over the limit fail at once with a *ThrottledError
+// instead of queuing, so a decode storm after a deploy cannot hammer a
+// remote backend. Both methods share one bucket.
+//
+// Place it beneath a CachingKeySource so cache hits are not counted:
+//
+//	limited, _ := crypto.NewRateLimitedKeySource(src, 50, 100)
+//	cached, _ := crypto.NewCachingKeySource(limited, 5*time.Minute)
+//
+// Close closes src if it implements io.Closer.
+func NewRateLimitedKeySource(src KeySource, perSecond float64, burst int) (KeySource, error) {
+	if src == nil {
+		return nil, errors.New("crypto: NewRateLimitedKeySource source is nil")
+	}
+	if perSecond <= 0 || math.IsInf(perSecond, 0) || math.IsNaN(perSecond) {
+		return nil, errors.New("crypto: NewRateLimitedKeySource rate must be positive and finite")
+	}
+	if burst < 1 {
+		return nil, errors.New("crypto: NewRateLimitedKeySource burst must be at least 1")
+	}
+	return &rateLimitedKeySource{
+		src:    src,
+		rate:   perSecond,
+		burst:  float64(burst),
+		now:    time.Now,
+		tokens: float64(burst),
+	}, nil
+}
+
+// CurrentKey fetches the current key from the wrapped source if the limiter
+// admits the call.
+func (r *rateLimitedKeySource) CurrentKey(ctx context.Context) (Key, error) {
+	if err := r.take(); err != nil {
+		return Key{}, err
+	}
+	return r.src.CurrentKey(ctx)
+}
+
+// KeyByID fetches the key from the wrapped source if the limiter admits the
+// call.
+func (r *rateLimitedKeySource) KeyByID(ctx context.Context, id string) (Key, error) {
+	if err := r.take(); err != nil {
+		return Key{}, err
+	}
+	return r.src.KeyByID(ctx, id)
+}
+
+// Close closes the wrapped source if it implements io.Closer.
+func (r *rateLimitedKeySource) Close() error {
+	if c, ok := r.src.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// take refills the bucket for the time elapsed since the last call and
+// consumes one token, or returns a *ThrottledError if none is available.
+func (r *rateLimitedKeySource) take() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if !r.last.IsZero() {
+		r.tokens = min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+	}
+	r.last = now
+	if r.tokens < 1 {
+		wait := time.Duration((1 - r.tokens) / r.rate * float64(time.Second))
+		return &ThrottledError{RetryAfter: wait}
+	}
+	r.tokens--
+	return nil
+}
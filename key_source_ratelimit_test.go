@@ -0,0 +1,77 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedKeySource(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	ks, err := NewRateLimitedKeySource(src, 2, 3)
+	if err != nil {
+		t.Fatalf("NewRateLimitedKeySource: %v", err)
+	}
+	r := ks.(*rateLimitedKeySource)
+	now := time.Unix(1_700_000_000, 0)
+	r.now = func() time.Time { return now }
+
+	// The full burst is admitted, across both methods.
+	if _, err := r.CurrentKey(ctx); err != nil {
+		t.Fatalf("CurrentKey: %v", err)
+	}
+	for range 2 {
+		if _, err := r.KeyByID(ctx, "k1"); err != nil {
+			t.Fatalf("KeyByID within burst: %v", err)
+		}
+	}
+
+	_, err = r.KeyByID(ctx, "k1")
+	if !IsThrottled(err) {
+		t.Fatalf("KeyByID over burst: got %v, want ErrThrottled", err)
+	}
+	var te *ThrottledError
+	if !errors.As(err, &te) || te.RetryAfter != 500*time.Millisecond {
+		t.Errorf("ThrottledError = %+v, want RetryAfter 500ms", te)
+	}
+	if n := src.fetches.Load(); n != 3 {
+		t.Errorf("source fetched %d times, want 3", n)
+	}
+
+	// Tokens refill at the configured rate.
+	now = now.Add(500 * time.Millisecond)
+	if _, err := r.KeyByID(ctx, "k1"); err != nil {
+		t.Errorf("KeyByID after refill: %v", err)
+	}
+	if _, err := r.KeyByID(ctx, "k1"); !IsThrottled(err) {
+		t.Errorf("KeyByID after one refill: got %v, want ErrThrottled", err)
+	}
+
+	// Refill is capped at the burst size.
+	now = now.Add(time.Hour)
+	for i := range 4 {
+		_, err := r.KeyByID(ctx, "k1")
+		if want := i == 3; IsThrottled(err) != want {
+			t.Errorf("call %d after idle: throttled = %v, want %v", i, IsThrottled(err), want)
+		}
+	}
+
+	if err := r.Close(); err != nil || !src.closed.Load() {
+		t.Errorf("Close = %v, source closed = %v", err, src.closed.Load())
+	}
+}
+
+func TestNewRateLimitedKeySourceValidation(t *testing.T) {
+	src := newFakeKeySource("k1")
+	if _, err := NewRateLimitedKeySource(nil, 1, 1); err == nil {
+		t.Error("nil source: expected error")
+	}
+	if _, err := NewRateLimitedKeySource(src, 0, 1); err == nil {
+		t.Error("zero rate: expected error")
+	}
+	if _, err := NewRateLimitedKeySource(src, 1, 0); err == nil {
+		t.Error("zero burst: expected error")
+	}
+}
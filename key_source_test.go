@@ -0,0 +1,395 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeKeySource is an in-memory KeySource that counts fetches and can be
+// made to fail.
+type fakeKeySource struct {
+	mu      sync.Mutex
+	current string
+	keys    map[string][]byte
+	fail    error
+	fetches atomic.Int64
+	closed  atomic.Bool
+}
+
+func newFakeKeySource(ids ...string) *fakeKeySource {
+	s := &fakeKeySource{keys: make(map[string][]byte)}
+	for i, id := range ids {
+		k := makeKey(32)
+		k[0] = byte(i + 1)
+		s.keys[id] = k
+	}
+	s.current = ids[0]
+	return s
+}
+
+func (s *fakeKeySource) CurrentKey(ctx context.Context) (Key, error) {
+	s.mu.Lock()
+	id := s.current
+	s.mu.Unlock()
+	return s.KeyByID(ctx, id)
+}
+
+func (s *fakeKeySource) KeyByID(_ context.Context, id string) (Key, error) {
+	s.fetches.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return Key{}, s.fail
+	}
+	k, ok := s.keys[id]
+	if !ok {
+		return Key{}, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return Key{ID: id, Bytes: append([]byte(nil), k...)}, nil
+}
+
+func (s *fakeKeySource) setFail(err error) {
+	s.mu.Lock()
+	s.fail = err
+	s.mu.Unlock()
+}
+
+func (s *fakeKeySource) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func TestKeySourceProviderRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1", "k2")
+	p, err := NewKeySourceProvider(src)
+	if err != nil {
+		t.Fatalf("NewKeySourceProvider: %v", err)
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	src.mu.Lock()
+	src.current = "k2"
+	src.mu.Unlock()
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil {
+		t.Fatalf("Decrypt after rotation: %v", err)
+	}
+	if string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, want %q", pt, "secret")
+	}
+
+	// The key-ring provider reads the same format.
+	ring := mustNewProvider(t, src.keys["k1"], "k1")
+	if pt, err := ring.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("key-ring Decrypt = %q, %v", pt, err)
+	}
+
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Errorf("HealthCheck: %v", err)
+	}
+	if err := p.Close(); err != nil || !src.closed.Load() {
+		t.Errorf("Close = %v, source closed = %v", err, src.closed.Load())
+	}
+	if _, err := p.Encrypt(ctx, []byte("x")); !IsProviderClosed(err) {
+		t.Errorf("Encrypt after Close: got %v, want ErrProviderClosed", err)
+	}
+}
+
+func TestKeySourceProviderSourceErrors(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	p, _ := NewKeySourceProvider(src)
+	ct, err := p.Encrypt(ctx, []byte("v"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	other := mustNewProvider(t, makeKey(32), "missing")
+	foreign, _ := other.Encrypt(ctx, []byte("v"))
+	if _, err := p.Decrypt(ctx, foreign); !IsKeyNotFound(err) {
+		t.Errorf("Decrypt unknown key: got %v, want ErrKeyNotFound", err)
+	}
+
+	boom := errors.New("backend down")
+	src.setFail(boom)
+	if _, err := p.Decrypt(ctx, ct); !errors.Is(err, boom) {
+		t.Errorf("Decrypt with failing source: got %v, want %v", err, boom)
+	}
+	if err := p.HealthCheck(ctx); !errors.Is(err, boom) {
+		t.Errorf("HealthCheck with failing source: got %v, want %v", err, boom)
+	}
+}
+
+// newTestCachingKeySource returns a CachingKeySource over src with a
+// controllable clock.
+func newTestCachingKeySource(t *testing.T, src KeySource, ttl time.Duration, opts ...KeyCacheOption) (*CachingKeySource, *time.Time) {
+	t.Helper()
+	c, err := NewCachingKeySource(src, ttl, opts...)
+	if err != nil {
+		t.Fatalf("NewCachingKeySource: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+	t.Cleanup(func() { _ = c.Close() })
+	return c, &now
+}
+
+func TestCachingKeySourceTTL(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	c, now := newTestCachingKeySource(t, src, time.Minute)
+
+	for range 3 {
+		k, err := c.KeyByID(ctx, "k1")
+		if err != nil {
+			t.Fatalf("KeyByID: %v", err)
+		}
+		if k.ID != "k1" || string(k.Bytes) != string(src.keys["k1"]) {
+			t.Fatalf("KeyByID = %+v", k)
+		}
+		clear(k.Bytes) // callers own their copy
+	}
+	if n := src.fetches.Load(); n != 1 {
+		t.Errorf("source fetched %d times within TTL, want 1", n)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID after TTL: %v", err)
+	}
+	if n := src.fetches.Load(); n != 2 {
+		t.Errorf("source fetched %d times after TTL, want 2", n)
+	}
+	if s := c.Stats(); s.Hits != 2 || s.Misses != 2 {
+		t.Errorf("Stats = %+v, want 2 hits and 2 misses", s)
+	}
+}
+
+func TestCachingKeySourceCurrentKeySeparateSlot(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1", "k2")
+	c, _ := newTestCachingKeySource(t, src, time.Minute)
+
+	if k, err := c.CurrentKey(ctx); err != nil || k.ID != "k1" {
+		t.Fatalf("CurrentKey = %+v, %v", k, err)
+	}
+	if k, err := c.KeyByID(ctx, "k2"); err != nil || k.ID != "k2" {
+		t.Fatalf("KeyByID(k2) = %+v, %v", k, err)
+	}
+	if _, err := c.KeyByID(ctx, ""); !IsInvalidKeyID(err) {
+		t.Errorf("KeyByID(\"\"): got %v, want ErrInvalidKeyID", err)
+	}
+
+	src.mu.Lock()
+	src.current = "k2"
+	src.mu.Unlock()
+	if k, _ := c.CurrentKey(ctx); k.ID != "k1" {
+		t.Errorf("CurrentKey within TTL = %q, want cached k1", k.ID)
+	}
+	c.Invalidate()
+	if k, _ := c.CurrentKey(ctx); k.ID != "k2" {
+		t.Errorf("CurrentKey after Invalidate = %q, want k2", k.ID)
+	}
+}
+
+func TestCachingKeySourceRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	c, now := newTestCachingKeySource(t, src, time.Minute, WithRefreshAhead(10*time.Second))
+
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID: %v", err)
+	}
+	*now = now.Add(55 * time.Second)
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID in refresh window: %v", err)
+	}
+	c.wg.Wait()
+
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Refreshes != 1 {
+		t.Errorf("Stats = %+v, want 1 hit, 1 miss, 1 refresh", s)
+	}
+	// The refreshed entry is fresh for another full TTL.
+	*now = now.Add(50 * time.Second)
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID after refresh: %v", err)
+	}
+	if s := c.Stats(); s.Misses != 1 {
+		t.Errorf("Misses = %d after refresh, want 1", s.Misses)
+	}
+}
+
+func TestCachingKeySourceRefreshAheadFailureKeepsEntry(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	c, now := newTestCachingKeySource(t, src, time.Minute, WithRefreshAhead(10*time.Second))
+
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID: %v", err)
+	}
+	src.setFail(errors.New("backend down"))
+	*now = now.Add(55 * time.Second)
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID with failing refresh: %v", err)
+	}
+	c.wg.Wait()
+	if s := c.Stats(); s.RefreshErrors != 1 || s.Refreshes != 0 {
+		t.Errorf("Stats = %+v, want 1 refresh error", s)
+	}
+}
+
+func TestCachingKeySourceConcurrentRefreshHeap(t *testing.T) {
+	withoutMlock(t)
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	c, err := NewCachingKeySource(src, 200*time.Microsecond, WithRefreshAhead(200*time.Microsecond))
+	if err != nil {
+		t.Fatalf("NewCachingKeySource: %v", err)
+	}
+	defer c.Close()
+
+	// Every hit starts a refresh, so readers copy entries while store wipes
+	// the ones it replaces.
+	var failures atomic.Int64
+	deadline := time.Now().Add(100 * time.Millisecond)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for time.Now().Before(deadline) {
+				if _, err := c.CurrentKey(ctx); err != nil {
+					failures.Add(1)
+				}
+				if _, err := c.KeyByID(ctx, "k1"); err != nil {
+					failures.Add(1)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if n := failures.Load(); n != 0 {
+		t.Errorf("%d lookups failed while entries were refreshed", n)
+	}
+}
+
+// hangingKeySource serves its first lookup and then blocks until the
+// context is done.
+type hangingKeySource struct {
+	calls atomic.Int64
+}
+
+func (s *hangingKeySource) CurrentKey(ctx context.Context) (Key, error) {
+	return s.KeyByID(ctx, "k1")
+}
+
+func (s *hangingKeySource) KeyByID(ctx context.Context, id string) (Key, error) {
+	if s.calls.Add(1) == 1 {
+		return Key{ID: id, Bytes: makeKey(32)}, nil
+	}
+	<-ctx.Done()
+	return Key{}, ctx.Err()
+}
+
+func TestCachingKeySourceCloseCancelsRefresh(t *testing.T) {
+	ctx := context.Background()
+	c, now := newTestCachingKeySource(t, &hangingKeySource{}, time.Hour, WithRefreshAhead(10*time.Minute))
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID: %v", err)
+	}
+	*now = now.Add(55 * time.Minute)
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID in refresh window: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked on a hung background refresh")
+	}
+}
+
+func TestCachingKeySourceServeStale(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("backend down")
+
+	src := newFakeKeySource("k1")
+	c, now := newTestCachingKeySource(t, src, time.Minute, WithMaxStale(time.Minute))
+	if _, err := c.KeyByID(ctx, "k1"); err != nil {
+		t.Fatalf("KeyByID: %v", err)
+	}
+	src.setFail(boom)
+
+	*now = now.Add(90 * time.Second)
+	k, err := c.KeyByID(ctx, "k1")
+	if err != nil || string(k.Bytes) != string(src.keys["k1"]) {
+		t.Fatalf("KeyByID within max stale = %+v, %v", k, err)
+	}
+	if s := c.Stats(); s.StaleServed != 1 || s.RefreshErrors != 1 {
+		t.Errorf("Stats = %+v, want 1 stale served and 1 refresh error", s)
+	}
+
+	*now = now.Add(time.Minute)
+	if _, err := c.KeyByID(ctx, "k1"); !errors.Is(err, boom) {
+		t.Errorf("KeyByID past max stale: got %v, want %v", err, boom)
+	}
+
+	// Without WithMaxStale an expired entry is never served.
+	src2 := newFakeKeySource("k1")
+	c2, now2 := newTestCachingKeySource(t, src2, time.Minute)
+	_, _ = c2.KeyByID(ctx, "k1")
+	src2.setFail(boom)
+	*now2 = now2.Add(time.Minute)
+	if _, err := c2.KeyByID(ctx, "k1"); !errors.Is(err, boom) {
+		t.Errorf("KeyByID without max stale: got %v, want %v", err, boom)
+	}
+}
+
+func TestCachingKeySourceClose(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	c, err := NewCachingKeySource(src, time.Minute)
+	if err != nil {
+		t.Fatalf("NewCachingKeySource: %v", err)
+	}
+	p, _ := NewKeySourceProvider(c)
+	if _, err := p.Encrypt(ctx, []byte("v")); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !src.closed.Load() {
+		t.Error("closing the provider did not close the underlying source")
+	}
+	if _, err := c.CurrentKey(ctx); !IsProviderClosed(err) {
+		t.Errorf("CurrentKey after Close: got %v, want ErrProviderClosed", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestNewCachingKeySourceValidation(t *testing.T) {
+	if _, err := NewCachingKeySource(nil, time.Minute); err == nil {
+		t.Error("nil source: expected error")
+	}
+	if _, err := NewCachingKeySource(newFakeKeySource("k1"), 0); err == nil {
+		t.Error("zero ttl: expected error")
+	}
+	if _, err := NewKeySourceProvider(nil); err == nil {
+		t.Error("nil source provider: expected error")
+	}
+}
@@ -0,0 +1,144 @@
+package crypto
+
+import (
+	"cmp"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// KeyStats is a snapshot of how a key has been used since its provider was
+// created. Counts are held in memory and reset when the process restarts;
+// export them (or use the key_usage_total series of the prometheus package)
+// to track usage over longer windows.
+type KeyStats struct {
+	ID string
+
+	// Encryptions and Decryptions count successful operations.
+	Encryptions uint64
+	Decryptions uint64
+
+	// LastEncrypt and LastDecrypt are the times of the most recent
+	// successful operations, or zero if there has been none.
+	LastEncrypt time.Time
+	LastDecrypt time.Time
+}
+
+// LastUsed returns the later of LastEncrypt and LastDecrypt, or zero if the
+// key has not been used.
+func (s KeyStats) LastUsed() time.Time {
+	if s.LastEncrypt.After(s.LastDecrypt) {
+		return s.LastEncrypt
+	}
+	return s.LastDecrypt
+}
+
+// KeyStatsProvider is implemented by providers that track per-key usage.
+// The built-in NewProvider and NewKeyRingProvider implementations satisfy it,
+// as do the KMS, Vault, and GPG providers, ProviderMux, and ChainProviders.
+//
+// Use it to decide when an old rotation key can be removed:
+//
+//	for _, s := range p.(crypto.KeyStatsProvider).KeyStats() {
+//	    if s.ID != ring.CurrentKeyID() && time.Since(s.LastUsed()) > 90*24*time.Hour {
+//	        // no reads for 90 days of uptime; candidate for RemoveKey
+//	    }
+//	}
+type KeyStatsProvider interface {
+	// KeyStats returns a snapshot for every key held, sorted by ID. Keys
+	// that have never been used are included with zero counts. A closed
+	// provider returns nil.
+	KeyStats() []KeyStats
+}
+
+// keyStats holds the live counters for one key. It is shared by pointer
+// between key ring snapshots so counts survive copy-on-write updates.
+type keyStats struct {
+	encrypts, decrypts       atomic.Uint64
+	lastEncrypt, lastDecrypt atomic.Int64 // Unix nanoseconds; 0 if never
+}
+
+func (k *keyStats) recordEncrypt() {
+	k.encrypts.Add(1)
+	k.lastEncrypt.Store(time.Now().UnixNano())
+}
+
+func (k *keyStats) recordDecrypt() {
+	k.decrypts.Add(1)
+	k.lastDecrypt.Store(time.Now().UnixNano())
+}
+
+func (k *keyStats) snapshot(id string) KeyStats {
+	return KeyStats{
+		ID:          id,
+		Encryptions: k.encrypts.Load(),
+		Decryptions: k.decrypts.Load(),
+		LastEncrypt: unixNanoTime(k.lastEncrypt.Load()),
+		LastDecrypt: unixNanoTime(k.lastDecrypt.Load()),
+	}
+}
+
+func unixNanoTime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// Compile-time interface check.
+var _ KeyStatsProvider = (*keyRingProvider)(nil)
+
+// KeyStats returns usage statistics for every key in the ring, sorted by ID.
+func (p *keyRingProvider) KeyStats() []KeyStats {
+	s := p.state.Load()
+	if s.closed {
+		return nil
+	}
+	out := make([]KeyStats, 0, len(s.keys))
+	for id, k := range s.keys {
+		out = append(out, k.stats.snapshot(id))
+	}
+	slices.SortFunc(out, func(a, b KeyStats) int { return cmp.Compare(a.ID, b.ID) })
+	return out
+}
+
+// decryptTracked runs decrypt with a lookup that notes which key was opened,
+// and records a decryption against that key if decrypt succeeds.
+func (s *keyRingState) decryptTracked(decrypt func(keyLookupFunc) ([]byte, error)) ([]byte, error) {
+	var used *keyStats
+	out, err := decrypt(func(id string) ([]byte, func(), error) {
+		b, release, err := s.openForDecrypt(id)
+		if err == nil {
+			used = s.keys[id].stats
+		}
+		return b, release, err
+	})
+	if err == nil && used != nil {
+		used.recordDecrypt()
+	}
+	return out, err
+}
+
+// mergeKeyStats combines snapshots from several providers, summing counts
+// and keeping the latest times for IDs that appear more than once. The
+// result is sorted by ID.
+func mergeKeyStats(all []KeyStats) []KeyStats {
+	slices.SortFunc(all, func(a, b KeyStats) int { return cmp.Compare(a.ID, b.ID) })
+	out := all[:0]
+	for _, s := range all {
+		if n := len(out); n > 0 && out[n-1].ID == s.ID {
+			m := &out[n-1]
+			m.Encryptions += s.Encryptions
+			m.Decryptions += s.Decryptions
+			if s.LastEncrypt.After(m.LastEncrypt) {
+				m.LastEncrypt = s.LastEncrypt
+			}
+			if s.LastDecrypt.After(m.LastDecrypt) {
+				m.LastDecrypt = s.LastDecrypt
+			}
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
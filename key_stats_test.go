@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestKeyRingKeyStats(t *testing.T) {
+	ctx := context.Background()
+	k1, k2 := makeKey(32), makeKey(32)
+	k2[0] = 0xFF
+	ring := mustNewKeyRingProvider(t, k1, "k1", 1)
+	if err := ring.AddKey(k2, "k2", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+
+	before := time.Now()
+	old, _ := ring.Encrypt(ctx, []byte("a"))
+	if err := ring.SetCurrentKey("k2"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	for range 2 {
+		if _, err := ring.Encrypt(ctx, []byte("b")); err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+	}
+	if _, err := ring.Decrypt(ctx, old); err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if _, err := DecryptTo(ctx, nil, old, ring); err != nil {
+		t.Fatalf("DecryptTo: %v", err)
+	}
+	// Failed decryptions are not counted.
+	tampered := append([]byte(nil), old...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := ring.Decrypt(ctx, tampered); !IsDecryptionFailed(err) {
+		t.Fatalf("Decrypt tampered: got %v, want ErrDecryptionFailed", err)
+	}
+	// Metadata updates keep the counters.
+	if err := ring.(KeyInfoProvider).SetKeyMetadata("k1", KeyMetadata{Usage: KeyUsageDecryptOnly}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+
+	stats := ring.(KeyStatsProvider).KeyStats()
+	if len(stats) != 2 || stats[0].ID != "k1" || stats[1].ID != "k2" {
+		t.Fatalf("KeyStats = %+v", stats)
+	}
+	s1, s2 := stats[0], stats[1]
+	if s1.Encryptions != 1 || s1.Decryptions != 2 || s2.Encryptions != 2 || s2.Decryptions != 0 {
+		t.Errorf("counts: k1=%+v k2=%+v", s1, s2)
+	}
+	if s1.LastDecrypt.Before(before) || !s2.LastDecrypt.IsZero() || s2.LastUsed() != s2.LastEncrypt {
+		t.Errorf("timestamps: k1=%+v k2=%+v", s1, s2)
+	}
+	if s1.LastUsed() != s1.LastDecrypt {
+		t.Errorf("k1 LastUsed = %v, want LastDecrypt %v", s1.LastUsed(), s1.LastDecrypt)
+	}
+
+	if err := ring.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ring.(KeyStatsProvider).KeyStats(); got != nil {
+		t.Errorf("KeyStats after Close = %+v, want nil", got)
+	}
+}
+
+func TestMuxAndChainKeyStats(t *testing.T) {
+	ctx := context.Background()
+	a := mustNewProvider(t, makeKey(32), "tenant-a/1")
+	b := mustNewProvider(t, makeKey(32), "tenant-b/1")
+	ctA, _ := a.Encrypt(ctx, []byte("x"))
+	ctB, _ := b.Encrypt(ctx, []byte("y"))
+
+	mux, err := NewProviderMux(WithMuxRoute("tenant-a/", a), WithMuxRoute("tenant-b/", b), WithMuxEncryptProvider(a))
+	if err != nil {
+		t.Fatalf("NewProviderMux: %v", err)
+	}
+	if _, err := mux.Decrypt(ctx, ctB); err != nil {
+		t.Fatalf("mux Decrypt: %v", err)
+	}
+	stats := mux.KeyStats()
+	if len(stats) != 2 || stats[0].Encryptions != 1 || stats[1].Decryptions != 1 {
+		t.Errorf("mux KeyStats = %+v", stats)
+	}
+
+	// The same key ID held by two chain members is merged.
+	dup := mustNewProvider(t, makeKey(32), "tenant-a/1")
+	if _, err := dup.Decrypt(ctx, ctA); err != nil {
+		t.Fatalf("dup Decrypt: %v", err)
+	}
+	chain, err := ChainProviders(a, dup)
+	if err != nil {
+		t.Fatalf("ChainProviders: %v", err)
+	}
+	got := chain.(KeyStatsProvider).KeyStats()
+	if len(got) != 1 || got[0].Encryptions != 1 || got[0].Decryptions != 1 || got[0].LastDecrypt.IsZero() {
+		t.Errorf("chain KeyStats = %+v", got)
+	}
+}
@@ -0,0 +1,150 @@
+// Package keychain provides a crypto.Provider whose keys live in the macOS
+// Keychain, so developer machines decrypting local encrypted config never
+// keep plaintext keys in dotfiles or environment variables.
+//
+// Each key is a generic password item, identified by service and account,
+// whose secret is the key in a form crypto.ParseKey accepts (StoreKey writes
+// "base64:..."). Keys are read at construction time through a Client; on
+// macOS, SecurityClient reads them with the system security(1) tool, which
+// shows the usual Keychain access prompt the first time:
+//
+//	client := keychain.NewSecurityClient()
+//	provider, err := keychain.New(ctx, client,
+//	    keychain.WithKey("myapp-config", "kek-2", "kek-2"),
+//	    keychain.WithKey("myapp-config", "kek-1", "kek-1"),
+//	)
+//	// kek-2 is current; kek-1 is available for decrypting existing data
+//
+// Store a new key once with StoreKey, or by hand:
+//
+//	security add-generic-password -s myapp-config -a kek-2 -w
+//
+// The Client interface itself is portable, so tests and other platforms can
+// supply their own implementation.
+package keychain
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// ErrItemNotFound is returned by a Client when the Keychain holds no item
+// for the requested service and account.
+var ErrItemNotFound = errors.New("keychain: item not found")
+
+// Client reads generic password items from a keychain.
+type Client interface {
+	// Find returns the secret of the generic password item identified by
+	// service and account, or an error wrapping ErrItemNotFound. New calls
+	// Find concurrently when several keys are configured.
+	Find(ctx context.Context, service, account string) ([]byte, error)
+}
+
+// Storer writes generic password items to a keychain.
+type Storer interface {
+	// Store creates or replaces the generic password item identified by
+	// service and account.
+	Store(ctx context.Context, service, account string, secret []byte) error
+}
+
+// Option configures the keychain provider.
+type Option func(*options)
+
+type options struct {
+	items []item
+}
+
+type item struct {
+	service string
+	account string
+	id      string
+}
+
+// WithKey registers the key held in the generic password item identified
+// by service and account. The id identifies this key in the config-crypto
+// system; an empty id uses crypto.KeyFingerprint of the key.
+//
+// The first call to WithKey sets the current key used for new encryptions.
+// Subsequent calls register additional keys for decryption during key
+// rotation.
+func WithKey(service, account, id string) Option {
+	return func(o *options) {
+		o.items = append(o.items, item{service: service, account: account, id: id})
+	}
+}
+
+// New creates a crypto.KeyRingProvider from keys stored in the Keychain.
+//
+// At least one key must be provided via WithKey. The first key is the
+// current key for new encryptions; additional keys support decryption
+// during key rotation.
+//
+// All keys are read during construction and cached. The Client is not
+// retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("keychain: Client must not be nil")
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return kmsring.Build(len(o.items), "keychain", func(i int) ([]byte, string, error) {
+		it := o.items[i]
+		name := it.id
+		if name == "" {
+			name = it.service + "/" + it.account
+		}
+		secret, err := client.Find(ctx, it.service, it.account)
+		if err != nil {
+			return nil, name, err
+		}
+		key, err := crypto.ParseKey(string(secret))
+		clear(secret)
+		if err != nil {
+			return nil, name, err
+		}
+		if it.id == "" {
+			return key, crypto.KeyFingerprint(key), nil
+		}
+		return key, it.id, nil
+	})
+}
+
+// StoreKey stores key in the generic password item identified by service
+// and account, encoded as "base64:..." so that New can read it back. An
+// existing item is replaced.
+func StoreKey(ctx context.Context, s Storer, service, account string, key []byte) error {
+	if s == nil {
+		return fmt.Errorf("keychain: Storer must not be nil")
+	}
+	if len(key) != kmsring.KeySize {
+		return fmt.Errorf("%w: got %d bytes", crypto.ErrInvalidKeySize, len(key))
+	}
+	secret := encodeKey(key)
+	defer clear(secret)
+	if err := s.Store(ctx, service, account, secret); err != nil {
+		return fmt.Errorf("keychain: store %s/%s: %w", service, account, err)
+	}
+	return nil
+}
+
+// encodeKey returns key as "base64:<std base64>" in a fresh slice the
+// caller must clear.
+func encodeKey(key []byte) []byte {
+	const prefix = "base64:"
+	out := make([]byte, len(prefix)+base64.StdEncoding.EncodedLen(len(key)))
+	copy(out, prefix)
+	base64.StdEncoding.Encode(out[len(prefix):], key)
+	return out
+}
@@ -0,0 +1,152 @@
+package keychain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockKeychain is an in-memory Client and Storer.
+type mockKeychain struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func (m *mockKeychain) Find(ctx context.Context, service, account string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.items[service+"/"+account]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", ErrItemNotFound, service, account)
+	}
+	return append([]byte(nil), secret...), nil
+}
+
+func (m *mockKeychain) Store(_ context.Context, service, account string, secret []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.items == nil {
+		m.items = make(map[string][]byte)
+	}
+	m.items[service+"/"+account] = append([]byte(nil), secret...)
+	return nil
+}
+
+var (
+	_ Client = (*mockKeychain)(nil)
+	_ Storer = (*mockKeychain)(nil)
+)
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func TestNew_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	kc := &mockKeychain{}
+	if err := StoreKey(ctx, kc, "app", "kek-2", makeKey(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := StoreKey(ctx, kc, "app", "kek-1", makeKey(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := New(ctx, kc, WithKey("app", "kek-1", "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := old.Encrypt(ctx, []byte("secret"))
+	_ = old.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(ctx, kc, WithKey("app", "kek-2", "kek-2"), WithKey("app", "kek-1", "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "kek-2" {
+		t.Errorf("CurrentKeyID = %q, want kek-2", p.CurrentKeyID())
+	}
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_EmptyIDUsesFingerprint(t *testing.T) {
+	kc := &mockKeychain{}
+	if err := StoreKey(context.Background(), kc, "app", "kek", makeKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	p, err := New(context.Background(), kc, WithKey("app", "kek", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if want := crypto.KeyFingerprint(makeKey(1)); p.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want %q", p.CurrentKeyID(), want)
+	}
+}
+
+func TestNew_AcceptsHexAndPEM(t *testing.T) {
+	pem, err := crypto.GenerateKeyString(crypto.KeyEncodingPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc := &mockKeychain{items: map[string][]byte{
+		"app/hex": []byte("hex:" + strings.Repeat("ab", 32)),
+		"app/pem": []byte(pem),
+	}}
+	p, err := New(context.Background(), kc, WithKey("app", "hex", "h"), WithKey("app", "pem", "p"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = p.Close()
+}
+
+func TestNew_NilClient(t *testing.T) {
+	if _, err := New(context.Background(), nil, WithKey("app", "kek", "k")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
+
+func TestNew_NoKeys(t *testing.T) {
+	if _, err := New(context.Background(), &mockKeychain{}); err == nil {
+		t.Error("expected error when no keys are configured")
+	}
+}
+
+func TestNew_ItemNotFound(t *testing.T) {
+	_, err := New(context.Background(), &mockKeychain{}, WithKey("app", "missing", "k"))
+	if !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestNew_UnprefixedSecret(t *testing.T) {
+	kc := &mockKeychain{items: map[string][]byte{"app/kek": []byte("hunter2")}}
+	if _, err := New(context.Background(), kc, WithKey("app", "kek", "k")); err == nil {
+		t.Error("expected error for a secret that is not an encoded key")
+	}
+}
+
+func TestStoreKey_InvalidSize(t *testing.T) {
+	err := StoreKey(context.Background(), &mockKeychain{}, "app", "kek", make([]byte, 16))
+	if !crypto.IsInvalidKeySize(err) {
+		t.Errorf("expected ErrInvalidKeySize, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+//go:build darwin
+
+package keychain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode"
+)
+
+// errSecItemNotFound is the exit status security(1) uses when no item
+// matches.
+const errSecItemNotFound = 44
+
+// maxStderrLen is the maximum number of bytes included from security(1)
+// stderr in error messages.
+const maxStderrLen = 200
+
+// SecurityClient reads and writes generic password items by invoking the
+// system security(1) tool. No cgo is required.
+type SecurityClient struct {
+	bin      string // path to security binary, defaults to /usr/bin/security
+	keychain string // keychain file; empty uses the default search list
+}
+
+// SecurityOption configures a SecurityClient.
+type SecurityOption func(*SecurityClient)
+
+// WithSecurityBinary sets the path to the security binary.
+// Defaults to "/usr/bin/security".
+func WithSecurityBinary(path string) SecurityOption {
+	return func(c *SecurityClient) {
+		c.bin = path
+	}
+}
+
+// WithKeychain restricts the client to one keychain file, e.g. a dedicated
+// keychain created with "security create-keychain". By default the user's
+// keychain search list is used.
+func WithKeychain(path string) SecurityOption {
+	return func(c *SecurityClient) {
+		c.keychain = path
+	}
+}
+
+// NewSecurityClient creates a SecurityClient that delegates to the system
+// security(1) tool.
+func NewSecurityClient(opts ...SecurityOption) *SecurityClient {
+	c := &SecurityClient{bin: "/usr/bin/security"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Find returns the secret of the generic password item identified by
+// service and account.
+func (c *SecurityClient) Find(ctx context.Context, service, account string) ([]byte, error) {
+	args := []string{"find-generic-password", "-s", service, "-a", account, "-w"}
+	if c.keychain != "" {
+		args = append(args, c.keychain)
+	}
+	cmd := exec.CommandContext(ctx, c.bin, args...) // #nosec G204 -- bin is developer-configured, not user input
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	defer clear(stdout.Bytes())
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound {
+			return nil, fmt.Errorf("%w: %s/%s", ErrItemNotFound, service, account)
+		}
+		return nil, fmt.Errorf("keychain: security: %w: %s", err, sanitizeStderr(stderr.Bytes()))
+	}
+
+	secret := bytes.TrimRight(stdout.Bytes(), "\n")
+	return bytes.Clone(secret), nil
+}
+
+// Store creates or replaces the generic password item identified by
+// service and account. The secret is passed on security(1)'s standard input
+// in interactive mode rather than on its command line, where other
+// processes could read it.
+func (c *SecurityClient) Store(ctx context.Context, service, account string, secret []byte) error {
+	for _, s := range []string{service, account, string(secret)} {
+		if !quotable(s) {
+			return fmt.Errorf("keychain: security: %q contains characters that cannot be quoted", s)
+		}
+	}
+	var cmdline bytes.Buffer
+	cmdline.WriteString(`add-generic-password -U -s "` + service + `" -a "` + account + `" -w "`)
+	cmdline.Write(secret)
+	cmdline.WriteString("\"")
+	if c.keychain != "" {
+		cmdline.WriteString(` "` + c.keychain + `"`)
+	}
+	cmdline.WriteString("\n")
+	defer clear(cmdline.Bytes())
+
+	cmd := exec.CommandContext(ctx, c.bin, "-i") // #nosec G204 -- bin is developer-configured, not user input
+	cmd.Stdin = bytes.NewReader(cmdline.Bytes())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("keychain: security: %w: %s", err, sanitizeStderr(stderr.Bytes()))
+	}
+	// Interactive mode exits 0 even when a command fails, reporting the
+	// failure on stderr only.
+	if msg := sanitizeStderr(stderr.Bytes()); msg != "" {
+		return fmt.Errorf("keychain: security: %s", msg)
+	}
+	return nil
+}
+
+// quotable reports whether s can be passed as a double-quoted argument in
+// security(1) interactive mode.
+func quotable(s string) bool {
+	return !strings.ContainsFunc(s, func(r rune) bool {
+		return r == '"' || r == '\\' || !unicode.IsPrint(r)
+	})
+}
+
+// sanitizeStderr returns a truncated, printable-only excerpt of security(1)
+// stderr suitable for inclusion in error messages.
+func sanitizeStderr(raw []byte) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) || r == '\n' {
+			return r
+		}
+		return -1
+	}, string(raw))
+	s = strings.TrimSpace(s)
+	if len(s) > maxStderrLen {
+		s = s[:maxStderrLen] + "..."
+	}
+	return s
+}
+
+// Compile-time interface checks.
+var (
+	_ Client = (*SecurityClient)(nil)
+	_ Storer = (*SecurityClient)(nil)
+)
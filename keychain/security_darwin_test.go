@@ -0,0 +1,60 @@
+//go:build darwin
+
+package keychain
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSecurity writes a shell script standing in for security(1).
+func fakeSecurity(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "security")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewSecurityClientDefaults(t *testing.T) {
+	c := NewSecurityClient()
+	if c.bin != "/usr/bin/security" {
+		t.Errorf("default bin: got %q, want %q", c.bin, "/usr/bin/security")
+	}
+}
+
+func TestSecurityClientFind(t *testing.T) {
+	c := NewSecurityClient(WithSecurityBinary(fakeSecurity(t, `echo "base64:c2VjcmV0"`)))
+	got, err := c.Find(context.Background(), "app", "kek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "base64:c2VjcmV0" {
+		t.Errorf("Find = %q", got)
+	}
+}
+
+func TestSecurityClientFindNotFound(t *testing.T) {
+	c := NewSecurityClient(WithSecurityBinary(fakeSecurity(t, "exit 44")))
+	if _, err := c.Find(context.Background(), "app", "kek"); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestSecurityClientBinaryNotFound(t *testing.T) {
+	c := NewSecurityClient(WithSecurityBinary("/nonexistent/security"))
+	if _, err := c.Find(context.Background(), "app", "kek"); err == nil {
+		t.Error("expected error when security binary does not exist")
+	}
+}
+
+func TestSecurityClientStoreRejectsQuotes(t *testing.T) {
+	c := NewSecurityClient(WithSecurityBinary(fakeSecurity(t, "cat >/dev/null")))
+	if err := c.Store(context.Background(), `a"b`, "kek", []byte("base64:AA==")); err == nil {
+		t.Error("expected error for a service name containing a quote")
+	}
+}
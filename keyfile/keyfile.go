@@ -0,0 +1,323 @@
+// Package keyfile provides a crypto.KeyRingProvider that loads AES-256 keys
+// from files, such as Kubernetes-mounted secrets or keys provisioned by a
+// configuration-management tool.
+//
+// Three file formats are supported:
+//
+//   - raw: exactly 32 bytes of key material
+//   - PEM: a single PEM block holding the 32 bytes, as written by
+//     crypto.GenerateKeyString(crypto.KeyEncodingPEM)
+//   - keyring file: several keys in the crypto.KeyRingFile format, see
+//     WithKeyRingFile
+//
+// Usage:
+//
+//	provider, err := keyfile.New(
+//	    keyfile.WithKeyFile("/etc/secrets/kek/current", "key-2"),
+//	    keyfile.WithKeyFile("/etc/secrets/kek/previous", "key-1"),
+//	)
+//	// key-2 is current; key-1 is available for decrypting existing data
+//
+// Files readable or writable by other users are rejected with
+// ErrInsecurePermissions; for Kubernetes secret volumes set defaultMode to
+// 0400 or 0440. Every buffer that held key material is zeroed once the keys
+// are in the ring.
+package keyfile
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// ErrInsecurePermissions is returned when a key file is accessible to users
+// other than its owner and group.
+var ErrInsecurePermissions = errors.New("keyfile: key file is accessible to other users")
+
+// keySize is the required AES-256 key size in bytes.
+const keySize = 32
+
+// maxFileSize bounds how much of a key file is read. Real key files are
+// far smaller; the cap stops a misconfigured path from loading a large file
+// into memory.
+const maxFileSize = 1 << 20
+
+// Option configures the file provider.
+type Option func(*options)
+
+type options struct {
+	sources       []source
+	insecurePerms bool
+	providerOpts  []crypto.ProviderOption
+	onError       func(error)
+	pollInterval  time.Duration
+}
+
+// source is one configured file.
+type source struct {
+	path    string
+	id      string
+	keyring bool
+}
+
+// key is one loaded key.
+type key struct {
+	id    string
+	bytes []byte
+	rank  uint64
+	md    crypto.KeyMetadata
+}
+
+// WithKeyFile registers a file holding one key, either raw 32 bytes or a
+// PEM block. id identifies the key in the ring; an empty id uses
+// crypto.KeyFingerprint of the key.
+//
+// The first registered key is the current key for new encryptions.
+// Subsequent keys are available for decryption during key rotation.
+func WithKeyFile(path, id string) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, source{path: path, id: id})
+	}
+}
+
+// WithKeyRingFile registers a keyring file in the crypto.KeyRingFile format
+// (JSON or YAML) holding several keys with their states, ranks, and
+// timestamps. The file's current key comes first among its keys, so when
+// the keyring file is the first source it sets the ring's current key.
+// Retired keys load as decrypt-only.
+func WithKeyRingFile(path string) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, source{path: path, keyring: true})
+	}
+}
+
+// WithInsecurePermissions disables the file permission check. Use it only
+// on platforms or filesystems that do not report meaningful modes.
+func WithInsecurePermissions() Option {
+	return func(o *options) {
+		o.insecurePerms = true
+	}
+}
+
+// WithProviderOptions passes options such as crypto.WithWeakKeyCheck to
+// the underlying crypto.NewKeyRingProvider.
+func WithProviderOptions(opts ...crypto.ProviderOption) Option {
+	return func(o *options) {
+		o.providerOpts = append(o.providerOpts, opts...)
+	}
+}
+
+// New loads every registered file and returns a crypto.KeyRingProvider
+// holding their keys. At least one file must be registered, and loading
+// fails if any file is missing, malformed, or has insecure permissions.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(opts ...Option) (crypto.KeyRingProvider, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.sources) == 0 {
+		return nil, errors.New("keyfile: at least one key file is required")
+	}
+
+	keys, err := load(&o)
+	defer func() {
+		for _, k := range keys {
+			clear(k.bytes)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	return build(keys, &o)
+}
+
+// build creates a ring holding keys, the first one current. The caller
+// wipes keys.
+func build(keys []key, o *options) (crypto.KeyRingProvider, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyfile: no keys loaded")
+	}
+
+	ring, err := crypto.NewKeyRingProvider(keys[0].bytes, keys[0].id, keys[0].rank, o.providerOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+	for _, k := range keys[1:] {
+		if err := ring.AddKey(k.bytes, k.id, k.rank); err != nil {
+			_ = ring.Close()
+			return nil, fmt.Errorf("keyfile: %w", err)
+		}
+	}
+	if err := setMetadata(ring, keys); err != nil {
+		_ = ring.Close()
+		return nil, err
+	}
+	return ring, nil
+}
+
+// setMetadata applies the keyring file metadata of keys to ring, skipping
+// keys whose metadata is already current.
+func setMetadata(ring crypto.KeyRingProvider, keys []key) error {
+	kip, ok := ring.(crypto.KeyInfoProvider)
+	if !ok {
+		return nil
+	}
+	for _, k := range keys {
+		info, err := kip.KeyInfo(k.id)
+		if err != nil {
+			return fmt.Errorf("keyfile: %w", err)
+		}
+		have := crypto.KeyMetadata{CreatedAt: info.CreatedAt, NotAfter: info.NotAfter, Usage: info.Usage}
+		if have == k.md {
+			continue
+		}
+		if err := kip.SetKeyMetadata(k.id, k.md); err != nil {
+			return fmt.Errorf("keyfile: %q: %w", k.id, err)
+		}
+	}
+	return nil
+}
+
+// load reads every source in order. On error the keys loaded so far are
+// still returned so the caller can wipe them.
+func load(o *options) ([]key, error) {
+	var keys []key
+	for _, src := range o.sources {
+		data, err := readFile(src.path, o.insecurePerms)
+		if err != nil {
+			return keys, err
+		}
+		if src.keyring {
+			var ks []key
+			ks, err = parseKeyRing(data)
+			keys = append(keys, ks...)
+		} else {
+			var k key
+			k, err = parseKeyFile(data, src.id)
+			if err == nil {
+				keys = append(keys, k)
+			}
+		}
+		clear(data)
+		if err != nil {
+			return keys, fmt.Errorf("keyfile: %s: %w", src.path, err)
+		}
+	}
+	return keys, nil
+}
+
+// readFile reads path after checking that it is a regular file with owner-
+// or group-only permissions. The mode is checked on the open file, so a
+// swap between the check and the read is not possible.
+func readFile(path string, insecurePerms bool) ([]byte, error) {
+	f, err := os.Open(path) // #nosec G304 -- path is configured by the caller
+	if err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("keyfile: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("keyfile: %s is not a regular file", path)
+	}
+	if !insecurePerms {
+		if err := checkPerm(fi); err != nil {
+			return nil, fmt.Errorf("%w: %s has mode %v", err, path, fi.Mode().Perm())
+		}
+	}
+	if fi.Size() > maxFileSize {
+		return nil, fmt.Errorf("keyfile: %s is larger than %d bytes", path, maxFileSize)
+	}
+
+	// Read into a buffer sized from the stat so that no intermediate
+	// growth copies of the key material are left behind.
+	buf := make([]byte, fi.Size()+1)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		clear(buf)
+		return nil, fmt.Errorf("keyfile: read %s: %w", path, err)
+	}
+	if n > int(fi.Size()) {
+		clear(buf)
+		return nil, fmt.Errorf("keyfile: %s changed while being read", path)
+	}
+	return buf[:n], nil
+}
+
+// checkPerm rejects files that other users can read or write. Windows does
+// not map ACLs onto mode bits, so the check is skipped there.
+func checkPerm(fi fs.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if fi.Mode().Perm()&0o006 != 0 {
+		return ErrInsecurePermissions
+	}
+	return nil
+}
+
+// parseKeyFile decodes a raw or PEM key. The returned key bytes do not
+// alias data.
+func parseKeyFile(data []byte, id string) (key, error) {
+	var b []byte
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(data) == keySize && !bytes.HasPrefix(data, []byte("-----BEGIN ")):
+		b = bytes.Clone(data)
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN ")):
+		block, rest := pem.Decode(trimmed)
+		if block == nil {
+			return key{}, errors.New("malformed PEM block")
+		}
+		b = block.Bytes
+		if len(bytes.TrimSpace(rest)) != 0 {
+			clear(b)
+			return key{}, errors.New("trailing data after PEM block")
+		}
+	default:
+		return key{}, fmt.Errorf("%w: file is %d bytes and not PEM", crypto.ErrInvalidKeySize, len(data))
+	}
+	if len(b) != keySize {
+		clear(b)
+		return key{}, fmt.Errorf("%w: got %d bytes", crypto.ErrInvalidKeySize, len(b))
+	}
+	if id == "" {
+		id = crypto.KeyFingerprint(b)
+	}
+	return key{id: id, bytes: b}, nil
+}
+
+// parseKeyRing decodes a crypto.KeyRingFile, current key first. The
+// returned key bytes do not alias data.
+func parseKeyRing(data []byte) ([]key, error) {
+	f, err := crypto.ParseKeyRingFile(data)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]key, 0, len(f.Keys))
+	for _, k := range f.Keys {
+		md := crypto.KeyMetadata{CreatedAt: k.CreatedAt, NotAfter: k.NotAfter}
+		if k.State == crypto.KeyStateRetired {
+			md.Usage = crypto.KeyUsageDecryptOnly
+		}
+		keys = append(keys, key{id: k.ID, bytes: k.Key, rank: k.Rank, md: md})
+		if k.State == crypto.KeyStateCurrent {
+			last := len(keys) - 1
+			keys[0], keys[last] = keys[last], keys[0]
+		}
+	}
+	return keys, nil
+}
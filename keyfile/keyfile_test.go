@@ -0,0 +1,188 @@
+package keyfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+func testKey(b byte) []byte {
+	k := make([]byte, keySize)
+	for i := range k {
+		k[i] = b + byte(i)
+	}
+	return k
+}
+
+func writeFile(t *testing.T, name string, data []byte, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, mode); err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile's mode is subject to the umask.
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func roundTrip(t *testing.T, enc, dec crypto.Provider) {
+	t.Helper()
+	ctx := context.Background()
+	ct, err := enc.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	pt, err := dec.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNewRawAndPEM(t *testing.T) {
+	raw := writeFile(t, "raw", testKey(0), 0o600)
+	pemText, err := crypto.GenerateKeyString(crypto.KeyEncodingPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemPath := writeFile(t, "key.pem", []byte(pemText), 0o400)
+
+	p, err := New(WithKeyFile(pemPath, "key-2"), WithKeyFile(raw, "key-1"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+	if p.CurrentKeyID() != "key-2" {
+		t.Errorf("CurrentKeyID = %q, want key-2", p.CurrentKeyID())
+	}
+
+	old, err := crypto.NewProvider(testKey(0), "key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = old.Close() }()
+	roundTrip(t, old, p)
+}
+
+func TestNewFingerprintID(t *testing.T) {
+	p, err := New(WithKeyFile(writeFile(t, "raw", testKey(0), 0o600), ""))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+	if want := crypto.KeyFingerprint(testKey(0)); p.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want %q", p.CurrentKeyID(), want)
+	}
+}
+
+func TestNewKeyRingFile(t *testing.T) {
+	doc := fmt.Sprintf(`{"version": 1, "keys": [
+		{"id": "key-1", "key": %q, "state": "retired", "rank": 1},
+		{"id": "key-2", "key": %q, "state": "current", "rank": 2}
+	]}`, base64.StdEncoding.EncodeToString(testKey(0)), base64.StdEncoding.EncodeToString(testKey(1)))
+	p, err := New(WithKeyRingFile(writeFile(t, "ring.json", []byte(doc), 0o640)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+	if p.CurrentKeyID() != "key-2" {
+		t.Errorf("CurrentKeyID = %q, want key-2", p.CurrentKeyID())
+	}
+	info, err := p.(crypto.KeyInfoProvider).KeyInfo("key-1")
+	if err != nil || info.Usage != crypto.KeyUsageDecryptOnly {
+		t.Errorf("KeyInfo(key-1) = %+v, %v; want decrypt-only", info, err)
+	}
+
+	old, _ := crypto.NewKeyRingProvider(testKey(0), "key-1", 1)
+	defer func() { _ = old.Close() }()
+	ctx := context.Background()
+	ct, _ := old.Encrypt(ctx, []byte("x"))
+	if stale, err := p.NeedsReencryption(ct); err != nil || !stale {
+		t.Errorf("NeedsReencryption = %v, %v; want true", stale, err)
+	}
+	roundTrip(t, old, p)
+}
+
+func TestNewKeyRingFileErrors(t *testing.T) {
+	k := base64.StdEncoding.EncodeToString(testKey(0))
+	for name, doc := range map[string]string{
+		"malformed":    `{"version": 1, "keys": [`,
+		"no current":   fmt.Sprintf(`{"version": 1, "keys": [{"id": "a", "key": %q, "state": "old"}]}`, k),
+		"short key":    `{"version": 1, "keys": [{"id": "a", "key": "AAAA", "state": "current"}]}`,
+		"bad version":  fmt.Sprintf(`{"version": 9, "keys": [{"id": "a", "key": %q, "state": "current"}]}`, k),
+		"duplicate id": fmt.Sprintf(`{"version": 1, "keys": [{"id": "a", "key": %q, "state": "current"}, {"id": "a", "key": %q, "state": "old"}]}`, k, k),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(WithKeyRingFile(writeFile(t, "ring.json", []byte(doc), 0o600))); err == nil {
+				t.Error("New: want error")
+			}
+		})
+	}
+}
+
+func TestNewRejectsBadKeyFiles(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"short":         testKey(0)[:16],
+		"long":          append(testKey(0), 0),
+		"bad pem":       []byte("-----BEGIN AES-256 KEY-----\nnot base64\n"),
+		"pem wrong len": []byte("-----BEGIN AES-256 KEY-----\nAAAA\n-----END AES-256 KEY-----\n"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(WithKeyFile(writeFile(t, "k", data, 0o600), "k")); err == nil {
+				t.Error("New: want error")
+			}
+		})
+	}
+	if _, err := New(WithKeyFile(filepath.Join(t.TempDir(), "missing"), "k")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: got %v, want os.ErrNotExist", err)
+	}
+	if _, err := New(WithKeyFile(t.TempDir(), "k")); err == nil {
+		t.Error("directory: want error")
+	}
+	if _, err := New(); err == nil {
+		t.Error("no files: want error")
+	}
+}
+
+func TestNewPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits are not checked on Windows")
+	}
+	path := writeFile(t, "raw", testKey(0), 0o644)
+	if _, err := New(WithKeyFile(path, "k")); !errors.Is(err, ErrInsecurePermissions) {
+		t.Errorf("world-readable: got %v, want ErrInsecurePermissions", err)
+	}
+	p, err := New(WithKeyFile(path, "k"), WithInsecurePermissions())
+	if err != nil {
+		t.Fatalf("WithInsecurePermissions: %v", err)
+	}
+	_ = p.Close()
+}
+
+func TestParseKeyFileDoesNotAlias(t *testing.T) {
+	data := testKey(0)
+	k, err := parseKeyFile(data, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clear(data)
+	if !bytes.Equal(k.bytes, testKey(0)) {
+		t.Error("parsed key aliases the file buffer")
+	}
+}
+
+func TestWithProviderOptions(t *testing.T) {
+	path := writeFile(t, "raw", make([]byte, keySize), 0o600)
+	if _, err := New(WithKeyFile(path, "k"), WithProviderOptions(crypto.WithWeakKeyCheck())); !crypto.IsWeakKey(err) {
+		t.Errorf("all-zero key with WithWeakKeyCheck: got %v, want ErrWeakKey", err)
+	}
+}
@@ -0,0 +1,250 @@
+package keyfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// dataLink is the symlink through which the kubelet publishes the current
+// contents of a secret, configMap, or projected volume. It points at an
+// immutable timestamped directory and is swapped atomically on update.
+const dataLink = "..data"
+
+// defaultPollInterval is how often WatchSecretVolume checks the volume. The
+// kubelet syncs volumes about once a minute, so faster polling gains little.
+const defaultPollInterval = 10 * time.Second
+
+// WithPollInterval sets how often a provider built by WatchSecretVolume
+// checks whether the volume has been updated. Defaults to 10s.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = d
+	}
+}
+
+// VolumeWatcher reloads a provider built by WatchSecretVolume when the
+// kubelet publishes a new version of the volume.
+type VolumeWatcher struct {
+	ring crypto.KeyRingProvider
+	dir  string
+	opts options
+
+	mu       sync.Mutex // serialises Reload and guards the fields below
+	gen      string     // ..data target the ring was last loaded from
+	current  []string   // key IDs in gen
+	previous []string   // key IDs in the generation before gen
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// WatchSecretVolume loads keys from a Kubernetes secret or projected volume
+// mounted at dir and reloads them when the kubelet rotates the volume:
+//
+//	ring, w, err := keyfile.WatchSecretVolume("/etc/secrets/kek",
+//	    keyfile.WithKeyFile("kek.pem", ""),
+//	)
+//	defer ring.Close()
+//	defer w.Stop()
+//
+// File paths in opts are relative to the volume. Every load resolves the
+// volume's "..data" symlink once and reads all files from the directory it
+// points at, so files that change together are always read from the same
+// version. Rotation is detected by polling that symlink (see
+// WithPollInterval) rather than with fsnotify, so no event can be missed or
+// seen before the swap completes.
+//
+// On rotation the new version's keys are added, its current key is
+// promoted, and the keys of the version before are kept for decrypting
+// values written during the overlap window. Keys found only in older
+// versions are removed. As with Watch, give rotated keys new IDs, and a
+// failed reload leaves the ring unchanged and is reported to the
+// WithErrorHandler callback; the next poll retries.
+func WatchSecretVolume(dir string, opts ...Option) (crypto.KeyRingProvider, *VolumeWatcher, error) {
+	w := &VolumeWatcher{dir: dir, opts: options{pollInterval: defaultPollInterval}}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+	if len(w.opts.sources) == 0 {
+		return nil, nil, errors.New("keyfile: at least one key file is required")
+	}
+	if w.opts.pollInterval <= 0 {
+		return nil, nil, fmt.Errorf("keyfile: poll interval must be positive, got %v", w.opts.pollInterval)
+	}
+	for _, src := range w.opts.sources {
+		if !filepath.IsLocal(src.path) {
+			return nil, nil, fmt.Errorf("keyfile: %s is not a path inside the volume", src.path)
+		}
+	}
+
+	gen, keys, err := w.load()
+	defer func() {
+		for _, k := range keys {
+			clear(k.bytes)
+		}
+	}()
+	if err != nil {
+		return nil, nil, err
+	}
+	ring, err := build(keys, &w.opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.ring, w.gen, w.current = ring, gen, keyIDs(keys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	var closed <-chan crypto.KeyEvent
+	if cw, ok := ring.(crypto.Watcher); ok {
+		closed = cw.Watch(ctx)
+	}
+	w.wg.Go(func() { w.run(ctx, closed) })
+	return ring, w, nil
+}
+
+// Generation returns the name of the directory "..data" pointed at when
+// the ring was last loaded.
+func (w *VolumeWatcher) Generation() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.gen
+}
+
+// Reload reloads the ring if the volume's "..data" symlink has moved since
+// the last load. The watch goroutine calls it on every poll; call it
+// directly to check at once.
+func (w *VolumeWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	gen, err := w.resolve()
+	if err != nil {
+		return err
+	}
+	if gen == w.gen {
+		return nil
+	}
+	gen, keys, err := w.load()
+	defer func() {
+		for _, k := range keys {
+			clear(k.bytes)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("keyfile: no keys loaded")
+	}
+
+	var errs []error
+	for _, k := range keys {
+		if err := w.ring.AddKey(k.bytes, k.id, k.rank); err != nil && !crypto.IsDuplicateKeyID(err) {
+			if crypto.IsProviderClosed(err) {
+				return err
+			}
+			errs = append(errs, fmt.Errorf("keyfile: add %q: %w", k.id, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if cur := keys[0].id; w.ring.CurrentKeyID() != cur {
+		if err := w.ring.SetCurrentKey(cur); err != nil {
+			return fmt.Errorf("keyfile: promote %q: %w", cur, err)
+		}
+	}
+	if err := setMetadata(w.ring, keys); err != nil {
+		return err
+	}
+
+	// Keep this version and the one before it; drop keys only older
+	// versions had.
+	ids := keyIDs(keys)
+	for _, id := range w.previous {
+		if slices.Contains(ids, id) || slices.Contains(w.current, id) {
+			continue
+		}
+		if err := w.ring.RemoveKey(id); err != nil && !crypto.IsKeyNotFound(err) {
+			errs = append(errs, fmt.Errorf("keyfile: remove %q: %w", id, err))
+		}
+	}
+	w.gen, w.previous, w.current = gen, w.current, ids
+	return errors.Join(errs...)
+}
+
+// Stop stops polling and waits for the watch goroutine to exit. It does not
+// close the ring. Safe to call multiple times.
+func (w *VolumeWatcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *VolumeWatcher) run(ctx context.Context, closed <-chan crypto.KeyEvent) {
+	ticker := time.NewTicker(w.opts.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-closed:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			if err := w.Reload(); err != nil {
+				if crypto.IsProviderClosed(err) {
+					return
+				}
+				w.opts.report(err)
+			}
+		}
+	}
+}
+
+// resolve returns the directory "..data" currently points at.
+func (w *VolumeWatcher) resolve() (string, error) {
+	gen, err := os.Readlink(filepath.Join(w.dir, dataLink))
+	if err != nil {
+		return "", fmt.Errorf("keyfile: %s is not a Kubernetes volume: %w", w.dir, err)
+	}
+	return gen, nil
+}
+
+// load reads every source from the directory "..data" points at. On error
+// the keys loaded so far are still returned so the caller can wipe them.
+func (w *VolumeWatcher) load() (string, []key, error) {
+	gen, err := w.resolve()
+	if err != nil {
+		return "", nil, err
+	}
+	root := gen
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(w.dir, gen)
+	}
+	o := w.opts
+	o.sources = make([]source, len(w.opts.sources))
+	for i, src := range w.opts.sources {
+		src.path = filepath.Join(root, src.path)
+		o.sources[i] = src
+	}
+	keys, err := load(&o)
+	return gen, keys, err
+}
+
+// keyIDs returns the IDs of keys.
+func keyIDs(keys []key) []string {
+	ids := make([]string, len(keys))
+	for i, k := range keys {
+		ids[i] = k.id
+	}
+	return ids
+}
@@ -0,0 +1,172 @@
+package keyfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// publish writes files into a new timestamped directory under dir and
+// swaps the "..data" symlink to it, as the kubelet's atomic writer does.
+func publish(t *testing.T, dir, gen string, files map[string][]byte) {
+	t.Helper()
+	if err := os.Mkdir(filepath.Join(dir, gen), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, gen, name), data, 0o400); err != nil {
+			t.Fatal(err)
+		}
+		link := filepath.Join(dir, name)
+		if _, err := os.Lstat(link); os.IsNotExist(err) {
+			if err := os.Symlink(filepath.Join(dataLink, name), link); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tmp := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(gen, tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, dataLink)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchSecretVolumeKeepsPreviousGeneration(t *testing.T) {
+	dir := t.TempDir()
+	publish(t, dir, "..gen1", map[string][]byte{"kek": testKey(1)})
+	ring, w, err := WatchSecretVolume(dir, WithKeyFile("kek", ""), WithPollInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("WatchSecretVolume: %v", err)
+	}
+	defer func() { _ = ring.Close() }()
+	defer w.Stop()
+	if w.Generation() != "..gen1" {
+		t.Errorf("Generation = %q, want ..gen1", w.Generation())
+	}
+
+	ctx := context.Background()
+	ct1, err := ring.Encrypt(ctx, []byte("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged volume: nothing to do.
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	publish(t, dir, "..gen2", map[string][]byte{"kek": testKey(2)})
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if want := crypto.KeyFingerprint(testKey(2)); ring.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want %q", ring.CurrentKeyID(), want)
+	}
+	if pt, err := ring.Decrypt(ctx, ct1); err != nil || string(pt) != "v1" {
+		t.Errorf("Decrypt under previous generation = %q, %v", pt, err)
+	}
+
+	// A third version drops the first version's key.
+	publish(t, dir, "..gen3", map[string][]byte{"kek": testKey(3)})
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, err := ring.Decrypt(ctx, ct1); !crypto.IsKeyNotFound(err) {
+		t.Errorf("Decrypt under generation 1 after two rotations: expected ErrKeyNotFound, got %v", err)
+	}
+	if w.Generation() != "..gen3" {
+		t.Errorf("Generation = %q, want ..gen3", w.Generation())
+	}
+}
+
+func TestWatchSecretVolumeCurrentAndPreviousFiles(t *testing.T) {
+	dir := t.TempDir()
+	publish(t, dir, "..gen1", map[string][]byte{"current": testKey(1), "previous": testKey(0)})
+	ring, w, err := WatchSecretVolume(dir,
+		WithKeyFile("current", ""), WithKeyFile("previous", ""),
+		WithPollInterval(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("WatchSecretVolume: %v", err)
+	}
+	defer func() { _ = ring.Close() }()
+	defer w.Stop()
+
+	ctx := context.Background()
+	old, err := crypto.NewProvider(testKey(0), crypto.KeyFingerprint(testKey(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = old.Close() }()
+	ct0, err := old.Encrypt(ctx, []byte("v0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publish(t, dir, "..gen2", map[string][]byte{"current": testKey(2), "previous": testKey(1)})
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if want := crypto.KeyFingerprint(testKey(2)); ring.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want %q", ring.CurrentKeyID(), want)
+	}
+	// key 0 was only in the previous generation, which is kept.
+	if _, err := ring.Decrypt(ctx, ct0); err != nil {
+		t.Errorf("Decrypt under key 0: %v", err)
+	}
+
+	publish(t, dir, "..gen3", map[string][]byte{"current": testKey(3), "previous": testKey(2)})
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	ids := ring.(crypto.KeyLister).ListKeyIDs()
+	if slices.Contains(ids, crypto.KeyFingerprint(testKey(0))) {
+		t.Errorf("key 0 still in ring: %v", ids)
+	}
+	if !slices.Contains(ids, crypto.KeyFingerprint(testKey(1))) {
+		t.Errorf("key 1 from the previous generation missing: %v", ids)
+	}
+}
+
+func TestWatchSecretVolumePolls(t *testing.T) {
+	dir := t.TempDir()
+	publish(t, dir, "..gen1", map[string][]byte{"kek": testKey(1)})
+	ring, w, err := WatchSecretVolume(dir, WithKeyFile("kek", ""), WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("WatchSecretVolume: %v", err)
+	}
+	defer func() { _ = ring.Close() }()
+	defer w.Stop()
+
+	publish(t, dir, "..gen2", map[string][]byte{"kek": testKey(2)})
+	want := crypto.KeyFingerprint(testKey(2))
+	waitFor(t, func() bool { return ring.CurrentKeyID() == want })
+}
+
+func TestWatchSecretVolumeErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := WatchSecretVolume(dir, WithKeyFile("kek", "")); err == nil || !strings.Contains(err.Error(), "not a Kubernetes volume") {
+		t.Errorf("plain directory: got %v", err)
+	}
+	publish(t, dir, "..gen1", map[string][]byte{"kek": testKey(1)})
+	if _, _, err := WatchSecretVolume(dir, WithKeyFile("/etc/kek", "")); err == nil {
+		t.Error("expected error for absolute path")
+	}
+	if _, _, err := WatchSecretVolume(dir, WithKeyFile("../kek", "")); err == nil {
+		t.Error("expected error for path outside the volume")
+	}
+	if _, _, err := WatchSecretVolume(dir); err == nil {
+		t.Error("expected error without key files")
+	}
+	if _, _, err := WatchSecretVolume(dir, WithKeyFile("kek", ""), WithPollInterval(0)); err == nil {
+		t.Error("expected error for zero poll interval")
+	}
+}
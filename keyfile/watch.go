@@ -0,0 +1,191 @@
+package keyfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// reloadDelay coalesces the burst of events a single file update produces
+// (truncate, write, chmod, or a Kubernetes symlink swap) into one reload.
+const reloadDelay = 100 * time.Millisecond
+
+// WithErrorHandler sets a callback for background reload errors in a
+// provider built by Watch. The callback runs on the watch goroutine and
+// must not block. If unset, errors are logged via slog.
+func WithErrorHandler(fn func(error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// Watcher reloads a provider built by Watch when its files change.
+type Watcher struct {
+	ring crypto.KeyRingProvider
+	opts options
+	fsw  *fsnotify.Watcher
+
+	mu sync.Mutex // serialises Reload
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Watch is like New but keeps watching the registered files and reloads
+// them when they change, so a key rotated by updating a mounted secret
+// takes effect without a restart:
+//
+//	ring, w, err := keyfile.Watch(keyfile.WithKeyRingFile("/etc/secrets/kek/keyring.json"))
+//	defer ring.Close()
+//	defer w.Stop()
+//
+// Each reload adds keys the ring does not hold yet, promotes the new
+// current key, and applies keyring file states, so a key marked retired
+// becomes decrypt-only. Keys that disappear from the files stay in the
+// ring so existing values remain readable; remove them with RemoveKey. A
+// key is identified by its ID, so give rotated keys new IDs: use a keyring
+// file, or an empty WithKeyFile id to get fingerprint IDs. A reload that
+// fails, e.g. because a file is caught half-written, leaves the ring
+// unchanged and is reported to the WithErrorHandler callback; the next
+// change retries.
+//
+// The parent directories of the files are watched rather than the files
+// themselves, so atomic renames and Kubernetes' "..data" symlink swaps are
+// seen. The watch goroutine exits on Stop or when the ring is closed.
+func Watch(opts ...Option) (crypto.KeyRingProvider, *Watcher, error) {
+	ring, err := New(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	w := &Watcher{ring: ring}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		_ = ring.Close()
+		return nil, nil, fmt.Errorf("keyfile: watch: %w", err)
+	}
+	dirs := make(map[string]struct{})
+	for _, src := range w.opts.sources {
+		dirs[filepath.Dir(src.path)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err := fsw.Add(dir); err != nil {
+			_ = fsw.Close()
+			_ = ring.Close()
+			return nil, nil, fmt.Errorf("keyfile: watch %s: %w", dir, err)
+		}
+	}
+	w.fsw = fsw
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	var closed <-chan crypto.KeyEvent
+	if cw, ok := ring.(crypto.Watcher); ok {
+		closed = cw.Watch(ctx)
+	}
+	w.wg.Go(func() { w.run(ctx, closed) })
+	return ring, w, nil
+}
+
+// Reload reads the files again and updates the ring. Watch calls it on
+// every change; call it directly to force a reload.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	keys, err := load(&w.opts)
+	defer func() {
+		for _, k := range keys {
+			clear(k.bytes)
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("keyfile: no keys loaded")
+	}
+
+	var errs []error
+	for _, k := range keys {
+		if err := w.ring.AddKey(k.bytes, k.id, k.rank); err != nil && !crypto.IsDuplicateKeyID(err) {
+			if crypto.IsProviderClosed(err) {
+				return err
+			}
+			errs = append(errs, fmt.Errorf("keyfile: add %q: %w", k.id, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if cur := keys[0].id; w.ring.CurrentKeyID() != cur {
+		if err := w.ring.SetCurrentKey(cur); err != nil {
+			return fmt.Errorf("keyfile: promote %q: %w", cur, err)
+		}
+	}
+	return setMetadata(w.ring, keys)
+}
+
+// Stop stops watching and waits for the watch goroutine to exit. It does
+// not close the ring. Safe to call multiple times.
+func (w *Watcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *Watcher) run(ctx context.Context, closed <-chan crypto.KeyEvent) {
+	defer func() { _ = w.fsw.Close() }()
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-closed:
+			if !ok {
+				return
+			}
+		case _, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			timer.Reset(reloadDelay)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.report(fmt.Errorf("keyfile: watch: %w", err))
+		case <-timer.C:
+			if err := w.Reload(); err != nil {
+				if crypto.IsProviderClosed(err) {
+					return
+				}
+				w.report(err)
+			}
+		}
+	}
+}
+
+func (w *Watcher) report(err error) { w.opts.report(err) }
+
+// report passes a background reload error to the WithErrorHandler
+// callback, or logs it.
+func (o *options) report(err error) {
+	if o.onError != nil {
+		o.onError(err)
+		return
+	}
+	slog.Default().Error("config-crypto: key file reload failed", "error", err)
+}
@@ -0,0 +1,114 @@
+package keyfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// replaceFile atomically swaps the contents of path, as secret-mount
+// updaters do.
+func replaceFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for reload")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	path := writeFile(t, "kek", testKey(0), 0o600)
+	ring, w, err := Watch(WithKeyFile(path, ""))
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer func() { _ = ring.Close() }()
+	defer w.Stop()
+
+	ctx := context.Background()
+	oldCT, err := ring.Encrypt(ctx, []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replaceFile(t, path, testKey(1))
+	want := crypto.KeyFingerprint(testKey(1))
+	waitFor(t, func() bool { return ring.CurrentKeyID() == want })
+
+	// The previous key is kept for decryption.
+	if pt, err := ring.Decrypt(ctx, oldCT); err != nil || string(pt) != "old" {
+		t.Errorf("Decrypt under previous key = %q, %v", pt, err)
+	}
+}
+
+func TestWatchReportsBadFile(t *testing.T) {
+	path := writeFile(t, "kek", testKey(0), 0o600)
+	errs := make(chan error, 10)
+	ring, w, err := Watch(WithKeyFile(path, ""), WithErrorHandler(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer func() { _ = ring.Close() }()
+	defer w.Stop()
+
+	before := ring.CurrentKeyID()
+	replaceFile(t, path, []byte("truncated"))
+	select {
+	case err := <-errs:
+		if !crypto.IsInvalidKeySize(err) {
+			t.Errorf("reported %v, want ErrInvalidKeySize", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+	}
+	if ring.CurrentKeyID() != before {
+		t.Error("failed reload changed the current key")
+	}
+}
+
+func TestWatchStopsWhenRingClosed(t *testing.T) {
+	path := writeFile(t, "kek", testKey(0), 0o600)
+	ring, w, err := Watch(WithKeyFile(path, "k"))
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	_ = ring.Close()
+
+	done := make(chan struct{})
+	go func() { w.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch goroutine still running after ring Close")
+	}
+	w.Stop()
+}
+
+func TestWatchMissingDir(t *testing.T) {
+	if _, _, err := Watch(WithKeyFile(filepath.Join(t.TempDir(), "nope", "kek"), "k")); err == nil {
+		t.Error("Watch with missing file: want error")
+	}
+}
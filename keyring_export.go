@@ -0,0 +1,218 @@
+package crypto
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// keyring blob layout, before encryption under the KEK:
+//
+//	magic "CCKR" | version (1) | current ID (u16 len + bytes) | count (u32)
+//	per key: ID (u16 len + bytes) | rank (u64) | created (i64 unix ns) |
+//	         not after (i64 unix ns) | usage (u8) | key (32 bytes)
+//
+// Zero times are stored as 0.
+const (
+	keyringMagic   = "CCKR"
+	keyringVersion = 1
+)
+
+// ExportKeyRing serialises every key in ring, with its rank, metadata, and
+// which one is current, and encrypts the result with kek. kek is any
+// Provider: NewProvider with a locally held KEK, or a Provider backed by a
+// KMS. Restore the ring with ImportKeyRing and the same kek.
+//
+// ring must be built by NewKeyRingProvider, NewProvider, ImportKeyRing, or
+// a KMS package's New; other implementations do not expose key material.
+func ExportKeyRing(ctx context.Context, ring KeyRingProvider, kek Provider) ([]byte, error) {
+	p, ok := ring.(*keyRingProvider)
+	if !ok {
+		return nil, fmt.Errorf("crypto: ExportKeyRing: %T does not expose key material", ring)
+	}
+	if kek == nil {
+		return nil, errors.New("crypto: ExportKeyRing kek is nil")
+	}
+	blob, err := p.marshalKeys()
+	defer clear(blob)
+	if err != nil {
+		return nil, err
+	}
+	out, err := kek.Encrypt(ctx, blob)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: ExportKeyRing: %w", err)
+	}
+	return out, nil
+}
+
+// marshalKeys encodes the ring's keys in the keyring blob layout. It holds
+// mu so a concurrent RemoveKey cannot wipe a key while it is being copied.
+func (p *keyRingProvider) marshalKeys() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.state.Load()
+	if s.closed {
+		return nil, ErrProviderClosed
+	}
+
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	b := append([]byte(keyringMagic), keyringVersion)
+	b = appendString16(b, s.currentID)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(ids)))
+	for _, id := range ids {
+		k := s.keys[id]
+		b = appendString16(b, id)
+		b = binary.BigEndian.AppendUint64(b, k.rank)
+		b = binary.BigEndian.AppendUint64(b, uint64(unixNano(k.createdAt)))
+		b = binary.BigEndian.AppendUint64(b, uint64(unixNano(k.notAfter)))
+		b = append(b, byte(k.usage))
+		kb, release, err := s.openKey(id)
+		if err != nil {
+			clear(b)
+			return nil, fmt.Errorf("crypto: ExportKeyRing: %w", err)
+		}
+		b = append(b, kb...)
+		release()
+	}
+	return b, nil
+}
+
+// ImportKeyRing decrypts a blob produced by ExportKeyRing with kek and
+// rebuilds the key ring: the same keys, ranks, metadata, and current key.
+// opts configure the new provider as for NewKeyRingProvider. It returns an
+// error wrapping ErrInvalidFormat if the decrypted blob is malformed.
+func ImportKeyRing(ctx context.Context, blob []byte, kek Provider, opts ...ProviderOption) (KeyRingProvider, error) {
+	if kek == nil {
+		return nil, errors.New("crypto: ImportKeyRing kek is nil")
+	}
+	pt, err := kek.Decrypt(ctx, blob)
+	defer clear(pt)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: ImportKeyRing: %w", err)
+	}
+
+	current, keys, err := unmarshalKeys(pt)
+	if err != nil {
+		return nil, err
+	}
+	var cur *exportedKey
+	for i := range keys {
+		if keys[i].id == current {
+			cur = &keys[i]
+		}
+	}
+	if cur == nil {
+		return nil, fmt.Errorf("%w: keyring blob: current key %q missing", ErrInvalidFormat, current)
+	}
+
+	ring, err := NewKeyRingProvider(cur.key, cur.id, cur.rank, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: ImportKeyRing: %w", err)
+	}
+	for _, k := range keys {
+		if k.id != current {
+			if err := ring.AddKey(k.key, k.id, k.rank); err != nil {
+				_ = ring.Close()
+				return nil, fmt.Errorf("crypto: ImportKeyRing: %w", err)
+			}
+		}
+		if k.md != (KeyMetadata{}) {
+			if err := ring.(KeyInfoProvider).SetKeyMetadata(k.id, k.md); err != nil {
+				_ = ring.Close()
+				return nil, fmt.Errorf("crypto: ImportKeyRing: %w", err)
+			}
+		}
+	}
+	return ring, nil
+}
+
+// exportedKey is one decoded keyring blob entry. key aliases the decrypted
+// blob, which ImportKeyRing wipes.
+type exportedKey struct {
+	id   string
+	rank uint64
+	md   KeyMetadata
+	key  []byte
+}
+
+func unmarshalKeys(b []byte) (string, []exportedKey, error) {
+	bad := func(what string) error {
+		return fmt.Errorf("%w: keyring blob: %s", ErrInvalidFormat, what)
+	}
+	if len(b) < len(keyringMagic)+1 || string(b[:len(keyringMagic)]) != keyringMagic {
+		return "", nil, bad("bad magic")
+	}
+	b = b[len(keyringMagic):]
+	if b[0] != keyringVersion {
+		return "", nil, fmt.Errorf("%w: keyring blob version %d", ErrUnsupportedFormat, b[0])
+	}
+	b = b[1:]
+
+	current, b, ok := readString16(b)
+	if !ok || len(b) < 4 {
+		return "", nil, bad("truncated header")
+	}
+	n := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	const fixed = 8 + 8 + 8 + 1 + aesKeySize
+	if uint64(n)*(2+fixed) > uint64(len(b)) {
+		return "", nil, bad("key count exceeds blob size")
+	}
+
+	keys := make([]exportedKey, 0, n)
+	for range n {
+		var k exportedKey
+		if k.id, b, ok = readString16(b); !ok || len(b) < fixed {
+			return "", nil, bad("truncated key")
+		}
+		k.rank = binary.BigEndian.Uint64(b)
+		k.md.CreatedAt = fromUnixNano(int64(binary.BigEndian.Uint64(b[8:])))
+		k.md.NotAfter = fromUnixNano(int64(binary.BigEndian.Uint64(b[16:])))
+		k.md.Usage = KeyUsage(b[24])
+		k.key = b[25:fixed]
+		b = b[fixed:]
+		keys = append(keys, k)
+	}
+	if len(b) != 0 {
+		return "", nil, bad("trailing data")
+	}
+	return current, keys, nil
+}
+
+func appendString16(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func readString16(b []byte) (string, []byte, bool) {
+	if len(b) < 2 {
+		return "", nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, false
+	}
+	return string(b[2 : 2+n]), b[2+n:], true
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
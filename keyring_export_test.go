@@ -0,0 +1,104 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExportImportKeyRing(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 1)
+	old, err := ring.Encrypt(ctx, []byte("old"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := Rotate(ring, "k2"); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	md := KeyMetadata{NotAfter: notAfter, Usage: KeyUsageDecryptOnly}
+	if err := ring.(KeyInfoProvider).SetKeyMetadata("k1", md); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	cur, err := ring.Encrypt(ctx, []byte("new"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	kekKey := makeKey(32)
+	kekKey[0] = 0xFF
+	kek := mustNewProvider(t, kekKey, "kek")
+	blob, err := ExportKeyRing(ctx, ring, kek)
+	if err != nil {
+		t.Fatalf("ExportKeyRing: %v", err)
+	}
+
+	got, err := ImportKeyRing(ctx, blob, kek)
+	if err != nil {
+		t.Fatalf("ImportKeyRing: %v", err)
+	}
+	defer got.Close()
+
+	if id := got.CurrentKeyID(); id != "k2" {
+		t.Errorf("CurrentKeyID = %q, want k2", id)
+	}
+	for ct, want := range map[string]string{string(old): "old", string(cur): "new"} {
+		pt, err := got.Decrypt(ctx, []byte(ct))
+		if err != nil || string(pt) != want {
+			t.Errorf("Decrypt = %q, %v; want %q", pt, err, want)
+		}
+	}
+	info, err := got.(KeyInfoProvider).KeyInfo("k1")
+	if err != nil {
+		t.Fatalf("KeyInfo: %v", err)
+	}
+	if info.Rank != 1 || !info.NotAfter.Equal(notAfter) || info.Usage != KeyUsageDecryptOnly {
+		t.Errorf("KeyInfo(k1) = %+v", info)
+	}
+	if need, err := got.NeedsReencryption(old); err != nil || !need {
+		t.Errorf("NeedsReencryption(old) = %v, %v; want true", need, err)
+	}
+}
+
+func TestImportKeyRingErrors(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	kek := mustNewProvider(t, makeKey(32), "kek")
+	blob, err := ExportKeyRing(ctx, ring, kek)
+	if err != nil {
+		t.Fatalf("ExportKeyRing: %v", err)
+	}
+
+	other := makeKey(32)
+	other[0] = 0xFF
+	if _, err := ImportKeyRing(ctx, blob, mustNewProvider(t, other, "kek")); !IsDecryptionFailed(err) {
+		t.Errorf("import with wrong KEK: got %v, want ErrDecryptionFailed", err)
+	}
+
+	for _, pt := range []string{"", "XXXX\x01", "CCKR\x02", "CCKR\x01\x00\x02k1\x00\x00\x00\x05"} {
+		bad, err := kek.Encrypt(ctx, []byte(pt))
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		if _, err := ImportKeyRing(ctx, bad, kek); err == nil {
+			t.Errorf("ImportKeyRing(%q) succeeded", pt)
+		}
+	}
+}
+
+func TestExportKeyRingErrors(t *testing.T) {
+	ctx := context.Background()
+	kek := mustNewProvider(t, makeKey(32), "kek")
+	ring, err := NewKeyRingProvider(makeKey(32), "k1", 0)
+	if err != nil {
+		t.Fatalf("NewKeyRingProvider: %v", err)
+	}
+	_ = ring.Close()
+	if _, err := ExportKeyRing(ctx, ring, kek); !IsProviderClosed(err) {
+		t.Errorf("export closed ring: got %v, want ErrProviderClosed", err)
+	}
+	if _, err := ExportKeyRing(ctx, mustNewKeyRingProvider(t, makeKey(32), "k1", 0), nil); err == nil {
+		t.Error("nil kek accepted")
+	}
+}
@@ -0,0 +1,359 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyRingFileVersion is the KeyRingFile format version this package reads
+// and writes.
+const KeyRingFileVersion = 1
+
+// KeyState is a key's lifecycle state in a KeyRingFile.
+type KeyState string
+
+const (
+	// KeyStateCurrent marks the key that encrypts new data. A keyring file
+	// has exactly one.
+	KeyStateCurrent KeyState = "current"
+
+	// KeyStateOld marks a previous key that still decrypts existing data and
+	// may be made current again.
+	KeyStateOld KeyState = "old"
+
+	// KeyStateRetired marks a key kept only to decrypt data not yet
+	// re-encrypted. It loads as KeyUsageDecryptOnly, so it can never
+	// encrypt again.
+	KeyStateRetired KeyState = "retired"
+)
+
+// KeyMaterial is raw key bytes that encode as standard base64 in JSON and
+// YAML.
+type KeyMaterial []byte
+
+// MarshalText encodes k as standard base64.
+func (k KeyMaterial) MarshalText() ([]byte, error) {
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(k)))
+	base64.StdEncoding.Encode(out, k)
+	return out, nil
+}
+
+// UnmarshalText decodes standard base64 into k.
+func (k *KeyMaterial) UnmarshalText(text []byte) error {
+	b := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(b, text)
+	if err != nil {
+		clear(b)
+		return fmt.Errorf("crypto: key material: %w", err)
+	}
+	*k = b[:n]
+	return nil
+}
+
+// KeyRingFileKey is one key in a KeyRingFile.
+type KeyRingFileKey struct {
+	ID        string      `json:"id" yaml:"id"`
+	Key       KeyMaterial `json:"key" yaml:"key"`
+	State     KeyState    `json:"state" yaml:"state"`
+	Rank      uint64      `json:"rank,omitempty" yaml:"rank,omitempty"`
+	CreatedAt time.Time   `json:"created_at,omitzero" yaml:"created_at,omitempty"`
+	NotAfter  time.Time   `json:"not_after,omitzero" yaml:"not_after,omitempty"`
+}
+
+// KeyRingFile is the canonical on-disk representation of a key ring: a
+// versioned list of keys with their IDs, material, lifecycle state, rank,
+// and timestamps. It is stored as JSON or YAML:
+//
+//	{
+//	  "version": 1,
+//	  "keys": [
+//	    {"id": "key-2", "key": "<base64>", "state": "current", "rank": 2,
+//	     "created_at": "2025-01-01T00:00:00Z"},
+//	    {"id": "key-1", "key": "<base64>", "state": "retired", "rank": 1}
+//	  ]
+//	}
+//
+// The file holds plaintext keys; protect it with file permissions, or use
+// a passphrase-protected or KMS-encrypted form. Call Wipe when done with a
+// loaded file.
+type KeyRingFile struct {
+	Version int              `json:"version" yaml:"version"`
+	Keys    []KeyRingFileKey `json:"keys" yaml:"keys"`
+}
+
+// NewKeyRingFile snapshots every key in ring, with its state and metadata.
+// ring must be built by NewKeyRingProvider, NewProvider, or a KMS
+// package's New; other implementations do not expose key material.
+// Encrypt-only keys have no KeyState and are rejected.
+func NewKeyRingFile(ring KeyRingProvider) (*KeyRingFile, error) {
+	p, ok := ring.(*keyRingProvider)
+	if !ok {
+		return nil, fmt.Errorf("crypto: NewKeyRingFile: %T does not expose key material", ring)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.state.Load()
+	if s.closed {
+		return nil, ErrProviderClosed
+	}
+
+	f := &KeyRingFile{Version: KeyRingFileVersion}
+	for _, id := range slices.Sorted(maps.Keys(s.keys)) {
+		k := s.keys[id]
+		state := KeyStateOld
+		switch {
+		case id == s.currentID:
+			state = KeyStateCurrent
+		case k.usage == KeyUsageDecryptOnly:
+			state = KeyStateRetired
+		case k.usage == KeyUsageEncryptOnly:
+			f.Wipe()
+			return nil, fmt.Errorf("crypto: NewKeyRingFile: key %q is encrypt-only, which a keyring file cannot represent", id)
+		}
+		kb, release, err := s.openKey(id)
+		if err != nil {
+			f.Wipe()
+			return nil, fmt.Errorf("crypto: NewKeyRingFile: %w", err)
+		}
+		f.Keys = append(f.Keys, KeyRingFileKey{
+			ID:        id,
+			Key:       bytes.Clone(kb),
+			State:     state,
+			Rank:      k.rank,
+			CreatedAt: k.createdAt,
+			NotAfter:  k.notAfter,
+		})
+		release()
+	}
+	// Current key first, for readers scanning the file.
+	slices.SortStableFunc(f.Keys, func(a, b KeyRingFileKey) int {
+		switch {
+		case a.State == KeyStateCurrent:
+			return -1
+		case b.State == KeyStateCurrent:
+			return 1
+		}
+		return 0
+	})
+	return f, nil
+}
+
+// ParseKeyRingFile decodes a keyring file in JSON or YAML and validates it.
+// Input starting with '{' is JSON; anything else is YAML. Errors wrap
+// ErrInvalidFormat, or ErrUnsupportedFormat for an unknown version.
+//
+// JSON key material is decoded straight into slices that Wipe clears. The
+// YAML decoder makes intermediate string copies that cannot be wiped, so
+// prefer JSON where that matters.
+func ParseKeyRingFile(data []byte) (*KeyRingFile, error) {
+	f := &KeyRingFile{}
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(f)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(f)
+	}
+	if err != nil {
+		f.Wipe()
+		return nil, fmt.Errorf("%w: keyring file: %v", ErrInvalidFormat, err)
+	}
+	if err := f.Validate(); err != nil {
+		f.Wipe()
+		return nil, err
+	}
+	return f, nil
+}
+
+// LoadKeyRingFile reads and parses the keyring file at path. It does not
+// check file permissions; the keyfile package does.
+func LoadKeyRingFile(path string) (*KeyRingFile, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path is configured by the caller
+	defer clear(data)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: %w", err)
+	}
+	return ParseKeyRingFile(data)
+}
+
+// Validate checks the version, that IDs are unique and non-empty, that
+// every key is 32 bytes with a known state, and that exactly one key is
+// current.
+func (f *KeyRingFile) Validate() error {
+	bad := func(format string, args ...any) error {
+		return fmt.Errorf("%w: keyring file: %s", ErrInvalidFormat, fmt.Sprintf(format, args...))
+	}
+	if f.Version != KeyRingFileVersion {
+		return fmt.Errorf("%w: keyring file version %d", ErrUnsupportedFormat, f.Version)
+	}
+	seen := make(map[string]struct{}, len(f.Keys))
+	current := 0
+	for i, k := range f.Keys {
+		if k.ID == "" {
+			return bad("key %d has no id", i)
+		}
+		if _, dup := seen[k.ID]; dup {
+			return bad("duplicate key id %q", k.ID)
+		}
+		seen[k.ID] = struct{}{}
+		if len(k.Key) != aesKeySize {
+			return bad("key %q has %d bytes, want %d", k.ID, len(k.Key), aesKeySize)
+		}
+		switch k.State {
+		case KeyStateCurrent:
+			current++
+		case KeyStateOld, KeyStateRetired:
+		default:
+			return bad("key %q has unknown state %q", k.ID, k.State)
+		}
+	}
+	if current != 1 {
+		return bad("%d current keys, want exactly 1", current)
+	}
+	return nil
+}
+
+// Current returns the current key.
+func (f *KeyRingFile) Current() (KeyRingFileKey, bool) {
+	for _, k := range f.Keys {
+		if k.State == KeyStateCurrent {
+			return k, true
+		}
+	}
+	return KeyRingFileKey{}, false
+}
+
+// Provider builds a KeyRingProvider holding f's keys: the current key is
+// current, retired keys are decrypt-only, and ranks and timestamps carry
+// over. opts configure the provider as for NewKeyRingProvider. f is not
+// modified; Wipe it once the provider is built.
+func (f *KeyRingFile) Provider(opts ...ProviderOption) (KeyRingProvider, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	cur, _ := f.Current()
+	ring, err := NewKeyRingProvider(cur.Key, cur.ID, cur.Rank, opts...)
+	if err != nil {
+		return nil, err
+	}
+	kip := ring.(KeyInfoProvider)
+	for _, k := range f.Keys {
+		if k.State != KeyStateCurrent {
+			if err := ring.AddKey(k.Key, k.ID, k.Rank); err != nil {
+				_ = ring.Close()
+				return nil, err
+			}
+		}
+		md := KeyMetadata{CreatedAt: k.CreatedAt, NotAfter: k.NotAfter}
+		if k.State == KeyStateRetired {
+			md.Usage = KeyUsageDecryptOnly
+		}
+		if md != (KeyMetadata{}) {
+			if err := kip.SetKeyMetadata(k.ID, md); err != nil {
+				_ = ring.Close()
+				return nil, err
+			}
+		}
+	}
+	return ring, nil
+}
+
+// Encode returns f as indented JSON, or as YAML when yamlFormat is set.
+func (f *KeyRingFile) Encode(yamlFormat bool) ([]byte, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	if yamlFormat {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(f); err != nil {
+			clear(buf.Bytes())
+			return nil, fmt.Errorf("crypto: encode keyring file: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+	out, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("crypto: encode keyring file: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// Save writes f to path with mode 0600, as YAML if path ends in ".yaml" or
+// ".yml" and as JSON otherwise. The file is written to a temporary file in
+// the same directory and renamed into place, so readers never see a partial
+// keyring.
+func (f *KeyRingFile) Save(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	data, err := f.Encode(ext == ".yaml" || ext == ".yml")
+	defer clear(data)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// Wipe zeroes all key material in f.
+func (f *KeyRingFile) Wipe() {
+	for i := range f.Keys {
+		clear(f.Keys[i].Key)
+	}
+}
+
+// NewKeyRingFileProvider loads the keyring file at path and returns a
+// provider built from it, wiping the loaded key material afterwards.
+func NewKeyRingFileProvider(path string, opts ...ProviderOption) (KeyRingProvider, error) {
+	f, err := LoadKeyRingFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Wipe()
+	return f.Provider(opts...)
+}
+
+// writeFileAtomic writes data to a temporary file beside path with mode
+// 0600, syncs it, and renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("crypto: %w", err)
+	}
+	name := tmp.Name()
+	fail := func(err error) error {
+		_ = tmp.Close()
+		_ = os.Remove(name)
+		return fmt.Errorf("crypto: write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		return fail(err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("crypto: write %s: %w", path, err)
+	}
+	if err := os.Rename(name, path); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("crypto: write %s: %w", path, err)
+	}
+	return nil
+}
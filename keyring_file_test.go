@@ -0,0 +1,123 @@
+package crypto
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyRingFileRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 1)
+	old, err := ring.Encrypt(ctx, []byte("old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rotate(ring, "k2"); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := ring.(KeyInfoProvider).SetKeyMetadata("k1", KeyMetadata{CreatedAt: created, Usage: KeyUsageDecryptOnly}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewKeyRingFile(ring)
+	if err != nil {
+		t.Fatalf("NewKeyRingFile: %v", err)
+	}
+	defer f.Wipe()
+	if len(f.Keys) != 2 || f.Keys[0].ID != "k2" || f.Keys[0].State != KeyStateCurrent || f.Keys[1].State != KeyStateRetired {
+		t.Fatalf("keys = %+v", f.Keys)
+	}
+
+	for _, name := range []string{"ring.json", "ring.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := f.Save(path); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Mode().Perm() != 0o600 {
+				t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+			}
+			data, _ := os.ReadFile(path)
+			if strings.HasSuffix(name, ".yaml") == strings.HasPrefix(string(data), "{") {
+				t.Errorf("wrong encoding for %s:\n%s", name, data)
+			}
+
+			got, err := NewKeyRingFileProvider(path)
+			if err != nil {
+				t.Fatalf("NewKeyRingFileProvider: %v", err)
+			}
+			defer got.Close()
+			if got.CurrentKeyID() != "k2" {
+				t.Errorf("CurrentKeyID = %q", got.CurrentKeyID())
+			}
+			if pt, err := got.Decrypt(ctx, old); err != nil || string(pt) != "old" {
+				t.Errorf("Decrypt = %q, %v", pt, err)
+			}
+			info, err := got.(KeyInfoProvider).KeyInfo("k1")
+			if err != nil || info.Usage != KeyUsageDecryptOnly || !info.CreatedAt.Equal(created) || info.Rank != 1 {
+				t.Errorf("KeyInfo(k1) = %+v, %v", info, err)
+			}
+		})
+	}
+}
+
+func TestParseKeyRingFileErrors(t *testing.T) {
+	k := "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	for name, doc := range map[string]string{
+		"empty":         ``,
+		"unknown field": `{"version": 1, "keys": [], "extra": 1}`,
+		"bad base64":    `{"version": 1, "keys": [{"id": "a", "key": "!!", "state": "current"}]}`,
+		"bad state":     `{"version": 1, "keys": [{"id": "a", "key": "` + k + `", "state": "active"}]}`,
+		"two current":   `{"version": 1, "keys": [{"id": "a", "key": "` + k + `", "state": "current"}, {"id": "b", "key": "` + k + `", "state": "current"}]}`,
+		"missing id":    `{"version": 1, "keys": [{"key": "` + k + `", "state": "current"}]}`,
+		"yaml no keys":  "version: 1\nkeys: []\n",
+	} {
+		if _, err := ParseKeyRingFile([]byte(doc)); !IsInvalidFormat(err) {
+			t.Errorf("%s: got %v, want ErrInvalidFormat", name, err)
+		}
+	}
+	if _, err := ParseKeyRingFile([]byte(`{"version": 2, "keys": []}`)); !IsUnsupportedFormat(err) {
+		t.Errorf("version 2: got %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestParseKeyRingFileYAML(t *testing.T) {
+	doc := `version: 1
+keys:
+  - id: k1
+    key: AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=
+    state: current
+    not_after: 2030-01-01T00:00:00Z
+`
+	f, err := ParseKeyRingFile([]byte(doc))
+	if err != nil {
+		t.Fatalf("ParseKeyRingFile: %v", err)
+	}
+	defer f.Wipe()
+	cur, ok := f.Current()
+	if !ok || cur.ID != "k1" || string(cur.Key) != string(makeKey(32)) || cur.NotAfter.Year() != 2030 {
+		t.Errorf("Current = %+v, %v", cur, ok)
+	}
+}
+
+func TestNewKeyRingFileRejectsEncryptOnly(t *testing.T) {
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	if err := ring.AddKey(makeKey(32)[:32], "k2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.(KeyInfoProvider).SetKeyMetadata("k2", KeyMetadata{Usage: KeyUsageEncryptOnly}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewKeyRingFile(ring); err == nil {
+		t.Error("NewKeyRingFile with an encrypt-only key: want error")
+	}
+}
@@ -2,10 +2,13 @@ package crypto
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"io"
+	"maps"
 	"sync"
-
-	"github.com/awnumar/memguard"
+	"sync/atomic"
+	"time"
 )
 
 // KeyRingProvider is a mutable Provider that supports runtime key rotation.
@@ -29,7 +32,8 @@ type KeyRingProvider interface {
 	// The key must have been previously added via the constructor or AddKey.
 	SetCurrentKey(id string) error
 
-	// RemoveKey removes a key by ID. The current key cannot be removed.
+	// RemoveKey removes a key by ID and wipes its key material. The current
+	// key cannot be removed; promote another key with SetCurrentKey first.
 	RemoveKey(id string) error
 
 	// CurrentKeyID returns the ID of the key currently used for encryption.
@@ -47,28 +51,52 @@ type KeyRingProvider interface {
 }
 
 // keyEntry holds key material for one entry in a keyRingProvider.
-// The 32-byte AES-256 KEK is stored inside a memguard Enclave:
-//   - mlock prevents the OS from paging it to disk.
-//   - XOR-at-rest makes the plaintext invisible to heap scans between uses.
-//   - Destroy() zeroes and unlocks on removal or Close.
+// The 32-byte AES-256 KEK is stored inside a memguard Enclave when the
+// process can lock memory (see MlockSupported), and in heap memory otherwise.
+// It is wiped on removal or Close.
 type keyEntry struct {
-	enclave *memguard.Enclave
-	rank    uint64 // monotonically increasing; higher means newer
+	key       sealedKey
+	rank      uint64    // monotonically increasing; higher means newer
+	createdAt time.Time // zero if unknown
+	notAfter  time.Time // zero if the key never expires
+	usage     KeyUsage
+	stats     *keyStats // shared across state snapshots
 }
 
 // keyRingProvider is the concrete implementation of KeyRingProvider. Each
-// key is stored exactly once in keys; currentID names the entry used for
-// new encryptions. Single-copy storage keeps Close's zeroing trivially
-// correct: no aliasing, no double-clear.
+// key is stored exactly once in the current keyRingState; currentID names the
+// entry used for new encryptions. Single-copy storage keeps Close's zeroing
+// trivially correct: no aliasing, no double-clear.
+//
+// Reads load an immutable state snapshot through an atomic pointer and never
+// take a lock. Mutations are serialised by mu and publish a modified copy of
+// the state (copy-on-write), which is cheap because rings hold few keys.
 type keyRingProvider struct {
-	mu        sync.RWMutex
+	mu       sync.Mutex // serialises writers; readers never take it
+	state    atomic.Pointer[keyRingState]
+	rand     io.Reader // source for DEKs and nonces; crypto/rand by default
+	alg      byte      // AEAD for new encryptions; AES-256-GCM by default
+	locked   bool      // keys live in mlock'd memguard enclaves
+	dekReuse *dekCache // nil unless WithDEKReuse was given
+	watchers watchHub
+	fpIDs    bool     // derive empty key IDs with KeyFingerprint
+	keyCheck keyCheck // weak-key checks for new keys
+}
+
+// keyRingState is an immutable snapshot of a key ring. It is never modified
+// after being published; writers build a new state instead.
+type keyRingState struct {
 	currentID string
 	keys      map[string]keyEntry
 	closed    bool
 }
 
-// Compile-time interface check.
-var _ KeyRingProvider = (*keyRingProvider)(nil)
+// Compile-time interface checks.
+var (
+	_ KeyRingProvider  = (*keyRingProvider)(nil)
+	_ BufferDecrypter  = (*keyRingProvider)(nil)
+	_ inPlaceDecrypter = (*keyRingProvider)(nil)
+)
 
 // NewKeyRingProvider creates a mutable Provider with the given initial key.
 // The keyBytes must be 32 bytes for AES-256. The id identifies this key.
@@ -76,31 +104,51 @@ var _ KeyRingProvider = (*keyRingProvider)(nil)
 // integer cast to uint64); it is used by NeedsReencryption to determine
 // whether a given ciphertext was encrypted with an older key. Use 0 when the
 // backing store does not provide version ordering.
-// Key bytes are copied into a memguard Enclave; the caller should zero the
-// original slice after construction as a defence-in-depth measure.
-func NewKeyRingProvider(initialBytes []byte, id string, rank uint64) (KeyRingProvider, error) {
+// Key bytes are copied into a memguard Enclave (or, when MlockSupported is
+// false and WithRequireMlock is not set, into heap memory); the caller should
+// zero the original slice after construction as a defence-in-depth measure.
+// With WithFingerprintKeyID, an empty id is derived from the key bytes.
+func NewKeyRingProvider(initialBytes []byte, id string, rank uint64, opts ...ProviderOption) (KeyRingProvider, error) {
 	if len(initialBytes) != aesKeySize {
 		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidKeySize, len(initialBytes))
 	}
+	o := &providerOptions{rand: rand.Reader, algorithm: algAES256GCM}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if id == "" && o.fingerprintIDs {
+		id = KeyFingerprint(initialBytes)
+	}
 	if id == "" {
 		return nil, fmt.Errorf("%w: key ID must not be empty", ErrInvalidKeyID)
 	}
+	if err := o.keyCheck.check(initialBytes, id); err != nil {
+		return nil, err
+	}
 
-	enc := sealKey(initialBytes)
-	keys := make(map[string]keyEntry, 1)
-	keys[id] = keyEntry{enclave: enc, rank: rank}
+	locked := MlockSupported()
+	if o.requireMlock && !locked {
+		return nil, ErrMlockUnavailable
+	}
 
-	return &keyRingProvider{
-		currentID: id,
-		keys:      keys,
-	}, nil
+	keys := make(map[string]keyEntry, 1)
+	keys[id] = keyEntry{key: sealKey(initialBytes, locked), rank: rank, stats: &keyStats{}}
+
+	p := &keyRingProvider{
+		rand:     o.rand,
+		alg:      o.algorithm,
+		locked:   locked,
+		dekReuse: newDEKCache(o.dekReuseUses, o.dekReuseAge),
+		fpIDs:    o.fingerprintIDs,
+		keyCheck: o.keyCheck,
+	}
+	p.state.Store(&keyRingState{currentID: id, keys: keys})
+	return p, nil
 }
 
 // Name returns the ID of the current encryption key.
 func (p *keyRingProvider) Name() string {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return p.currentID
+	return p.state.Load().currentID
 }
 
 // Connect is a no-op for keyRingProvider.
@@ -108,58 +156,129 @@ func (p *keyRingProvider) Connect(_ context.Context) error { return nil }
 
 // Encrypt encrypts plaintext using envelope encryption with the current key.
 func (p *keyRingProvider) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	if p.closed {
+	s := p.state.Load()
+	if s.closed {
 		return nil, ErrProviderClosed
 	}
-	cur, ok := p.keys[p.currentID]
-	if !ok {
-		return nil, fmt.Errorf("%w: current %q", ErrKeyNotFound, p.currentID)
+	if err := s.checkCurrent(); err != nil {
+		return nil, err
 	}
+	ct, err := p.encryptWith(s, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	s.keys[s.currentID].stats.recordEncrypt()
+	return ct, nil
+}
 
-	lb, err := cur.enclave.Open()
+// encryptWith encrypts plaintext under the current key of s.
+func (p *keyRingProvider) encryptWith(s *keyRingState, plaintext []byte) ([]byte, error) {
+	openCurrent := func() ([]byte, func(), error) { return s.openKey(s.currentID) }
+
+	if p.dekReuse != nil {
+		return p.dekReuse.encrypt(p.rand, p.alg, plaintext, s.currentID, openCurrent)
+	}
+
+	// The KEK is only needed to wrap the DEK; release it before sealing.
+	kek, release, err := openCurrent()
+	if err != nil {
+		return nil, err
+	}
+	w, err := newWrappedDEK(p.rand, p.alg, s.currentID, kek)
+	release()
 	if err != nil {
-		return nil, fmt.Errorf("open key enclave %q: %w", p.currentID, err)
+		return nil, err
 	}
-	defer lb.Destroy()
-	return encryptEnvelope(plaintext, p.currentID, lb.Bytes())
+	return w.seal(p.rand, plaintext)
 }
 
 // Decrypt decrypts ciphertext using the key identified in the header.
 func (p *keyRingProvider) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	if p.closed {
+	s := p.state.Load()
+	if s.closed {
+		return nil, ErrProviderClosed
+	}
+	return s.decryptTracked(func(lookup keyLookupFunc) ([]byte, error) {
+		return decryptEnvelope(ciphertext, lookup)
+	})
+}
+
+// DecryptTo decrypts ciphertext and appends the plaintext to dst.
+func (p *keyRingProvider) DecryptTo(_ context.Context, dst, ciphertext []byte) ([]byte, error) {
+	s := p.state.Load()
+	if s.closed {
+		return nil, ErrProviderClosed
+	}
+	return s.decryptTracked(func(lookup keyLookupFunc) ([]byte, error) {
+		return decryptEnvelopeTo(dst, ciphertext, lookup)
+	})
+}
+
+// decryptInPlace decrypts ciphertext, overwriting its payload with the
+// plaintext.
+func (p *keyRingProvider) decryptInPlace(_ context.Context, ciphertext []byte) ([]byte, error) {
+	s := p.state.Load()
+	if s.closed {
 		return nil, ErrProviderClosed
 	}
-	return decryptEnvelope(ciphertext, p.keyByID)
+	return s.decryptTracked(func(lookup keyLookupFunc) ([]byte, error) {
+		return decryptEnvelopeInPlace(ciphertext, lookup)
+	})
 }
 
-// HealthCheck returns nil unless Close has been called.
+// HealthCheck reports whether the provider can encrypt right now: it fails
+// if Close has been called, or if the current key is missing, expired,
+// decrypt-only, or cannot be opened from its enclave. Use it in readiness
+// probes.
 func (p *keyRingProvider) HealthCheck(_ context.Context) error {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	if p.closed {
+	s := p.state.Load()
+	if s.closed {
 		return ErrProviderClosed
 	}
+	if err := s.checkCurrent(); err != nil {
+		return err
+	}
+	_, release, err := s.openKey(s.currentID)
+	if err != nil {
+		return err
+	}
+	release()
+	return nil
+}
+
+// checkCurrent reports whether the current key may encrypt now.
+func (s *keyRingState) checkCurrent() error {
+	cur, ok := s.keys[s.currentID]
+	if !ok {
+		return fmt.Errorf("%w: current %q", ErrKeyNotFound, s.currentID)
+	}
+	if !cur.usage.canEncrypt() {
+		return fmt.Errorf("%w: %q is %s", ErrKeyUsageDenied, s.currentID, cur.usage)
+	}
+	if !cur.notAfter.IsZero() && !time.Now().Before(cur.notAfter) {
+		return &KeyExpiredError{KeyID: s.currentID, NotAfter: cur.notAfter}
+	}
 	return nil
 }
 
-// Close wipes all key enclaves and blocks further operations.
+// Close wipes all keys and blocks further operations. Operations
+// that loaded the key ring before Close may still complete.
 // Safe to call multiple times; subsequent calls are no-ops.
 func (p *keyRingProvider) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.closed {
+	s := p.state.Load()
+	if s.closed {
 		return nil
 	}
-	for _, k := range p.keys {
-		wipeEnclave(k.enclave)
+	p.state.Store(&keyRingState{closed: true})
+	for _, k := range s.keys {
+		k.key.wipe()
+	}
+	if p.dekReuse != nil {
+		p.dekReuse.reset()
 	}
-	p.keys = nil
-	p.currentID = ""
-	p.closed = true
+	p.watchers.close()
 	return nil
 }
 
@@ -168,133 +287,144 @@ func (p *keyRingProvider) Close() error {
 // rank is the KV store version number for this key; it is used by
 // NeedsReencryption to establish ordering across restarts.
 // Returns ErrDuplicateKeyID if the ID already exists.
-// Key bytes are copied into a memguard Enclave; the caller should zero their
-// slice after AddKey returns as a defence-in-depth measure.
+// Key bytes are copied into a memguard Enclave (or heap memory, as for
+// NewKeyRingProvider); the caller should zero their slice after AddKey
+// returns as a defence-in-depth measure.
 func (p *keyRingProvider) AddKey(keyBytes []byte, id string, rank uint64) error {
 	if len(keyBytes) != aesKeySize {
 		return fmt.Errorf("%w: key %q has %d bytes", ErrInvalidKeySize, id, len(keyBytes))
 	}
+	if id == "" && p.fpIDs {
+		id = KeyFingerprint(keyBytes)
+	}
 	if id == "" {
 		return fmt.Errorf("%w: key ID must not be empty", ErrInvalidKeyID)
 	}
+	if err := p.keyCheck.check(keyBytes, id); err != nil {
+		return err
+	}
 
-	enc := sealKey(keyBytes)
+	sk := sealKey(keyBytes, p.locked)
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.closed {
-		wipeEnclave(enc)
+	s := p.state.Load()
+	if s.closed {
+		sk.wipe()
 		return ErrProviderClosed
 	}
-	if _, exists := p.keys[id]; exists {
-		wipeEnclave(enc)
+	if _, exists := s.keys[id]; exists {
+		sk.wipe()
 		return fmt.Errorf("%w: %q", ErrDuplicateKeyID, id)
 	}
-	p.keys[id] = keyEntry{enclave: enc, rank: rank}
+	next := s.clone()
+	next.keys[id] = keyEntry{key: sk, rank: rank, stats: &keyStats{}}
+	p.state.Store(next)
+	p.watchers.notify(KeyEvent{Type: KeyAdded, KeyID: id})
 	return nil
 }
 
 // SetCurrentKey switches the active encryption key to the given ID.
-// The key must have been previously added via the constructor or AddKey.
+// The key must have been previously added via the constructor or AddKey and
+// must not be marked KeyUsageDecryptOnly.
 func (p *keyRingProvider) SetCurrentKey(id string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.closed {
+	s := p.state.Load()
+	if s.closed {
 		return ErrProviderClosed
 	}
-	if _, ok := p.keys[id]; !ok {
+	k, ok := s.keys[id]
+	if !ok {
 		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
 	}
-	p.currentID = id
+	if !k.usage.canEncrypt() {
+		return fmt.Errorf("%w: %q is %s", ErrKeyUsageDenied, id, k.usage)
+	}
+	next := s.clone()
+	next.currentID = id
+	p.state.Store(next)
+	if p.dekReuse != nil {
+		p.dekReuse.reset()
+	}
+	p.watchers.notify(KeyEvent{Type: CurrentKeyChanged, KeyID: id})
 	return nil
 }
 
-// RemoveKey removes a key by ID. The current key cannot be removed.
+// RemoveKey removes a key by ID and wipes its key material. The current key
+// cannot be removed; promote another key with SetCurrentKey first.
 func (p *keyRingProvider) RemoveKey(id string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.closed {
+	s := p.state.Load()
+	if s.closed {
 		return ErrProviderClosed
 	}
-	if p.currentID == id {
+	if s.currentID == id {
 		return fmt.Errorf("%w: %s", ErrRemoveCurrentKey, id)
 	}
-	k, ok := p.keys[id]
+	k, ok := s.keys[id]
 	if !ok {
 		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
 	}
-	wipeEnclave(k.enclave)
-	delete(p.keys, id)
+	next := s.clone()
+	delete(next.keys, id)
+	p.state.Store(next)
+	k.key.wipe()
+	p.watchers.notify(KeyEvent{Type: KeyRemoved, KeyID: id})
 	return nil
 }
 
 // CurrentKeyID returns the ID of the key currently used for encryption.
 func (p *keyRingProvider) CurrentKeyID() string {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	return p.currentID
+	return p.state.Load().currentID
 }
 
 // NeedsReencryption reports whether ciphertext was encrypted with a key that
 // is older than the current key, based on the rank (KV store version) recorded
 // when each key was added.
 func (p *keyRingProvider) NeedsReencryption(ciphertext []byte) (bool, error) {
-	h, _, err := readHeader(ciphertext)
+	id, err := KeyIDOf(ciphertext)
 	if err != nil {
 		return false, err
 	}
 
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	if h.keyID == p.currentID {
+	s := p.state.Load()
+	if id == s.currentID {
 		return false, nil
 	}
 
-	stored, ok := p.keys[h.keyID]
+	stored, ok := s.keys[id]
 	if !ok {
 		return false, nil
 	}
-	current, ok := p.keys[p.currentID]
+	current, ok := s.keys[s.currentID]
 	if !ok {
 		return false, nil
 	}
 	return stored.rank < current.rank, nil
 }
 
-// keyByID opens the enclave for the given key ID and returns a plaintext copy.
-// The caller is responsible for zeroing the returned slice after use.
-// Caller must hold at least a read lock.
-func (p *keyRingProvider) keyByID(id string) ([]byte, error) {
-	k, ok := p.keys[id]
-	if !ok {
-		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+// clone returns a copy of s with its own keys map, for copy-on-write updates.
+func (s *keyRingState) clone() *keyRingState {
+	return &keyRingState{
+		currentID: s.currentID,
+		keys:      maps.Clone(s.keys),
 	}
-	lb, err := k.enclave.Open()
-	if err != nil {
-		return nil, fmt.Errorf("open key enclave %q: %w", id, err)
-	}
-	defer lb.Destroy()
-	b := make([]byte, lb.Size())
-	copy(b, lb.Bytes())
-	return b, nil
-}
-
-// sealKey copies keyBytes into a mutable LockedBuffer and seals it into a
-// memguard Enclave. The caller's slice is NOT modified; callers are responsible
-// for zeroing their own copy of the key material.
-func sealKey(keyBytes []byte) *memguard.Enclave {
-	lb := memguard.NewBuffer(len(keyBytes))
-	lb.Copy(keyBytes)
-	return lb.Seal()
 }
 
-// wipeEnclave opens the enclave and destroys the resulting LockedBuffer,
-// zeroing the plaintext key material in the mlock'd region.
-// The encrypted blob in the Enclave struct is left in heap but is
-// cryptographically opaque without the memguard session key.
-func wipeEnclave(enc *memguard.Enclave) {
-	if lb, err := enc.Open(); err == nil {
-		lb.Destroy()
+// openKey opens the stored key for the given key ID and returns its bytes
+// with a release func that wipes them. For enclave-backed keys the bytes live
+// in a guarded, mlock'd LockedBuffer and are never copied to ordinary heap
+// memory; callers must not retain the slice after calling release.
+func (s *keyRingState) openKey(id string) ([]byte, func(), error) {
+	k, ok := s.keys[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	b, release, err := k.key.open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("open key %q: %w", id, err)
 	}
+	return b, release, nil
 }
@@ -0,0 +1,151 @@
+// Package keyservice runs config-crypto keys behind a gRPC service, so one
+// hardened key service per cluster holds KMS credentials instead of every
+// workload. The service is defined in keyservicepb/keyservice.proto.
+//
+// The service has two modes, which a Server enables independently:
+//
+//   - Key export (CurrentKey, KeyByID): clients fetch KEKs and encrypt
+//     locally. Use the Client as a crypto.KeySource, ideally behind a
+//     crypto.CachingKeySource so that most operations need no round trip.
+//   - Key wrapping (WrapDEK, UnwrapDEK): KEKs never leave the service.
+//     Use the Client as a crypto.KeyWrapper; every Encrypt and Decrypt costs
+//     one round trip.
+//
+// Client side:
+//
+//	conn, err := grpc.NewClient("keys.internal:8443", grpc.WithTransportCredentials(creds))
+//	client := keyservice.NewClient(conn)
+//
+//	provider, err := crypto.NewWrappingProvider(client)
+//	// or, with key export enabled on the server:
+//	cached, err := crypto.NewCachingKeySource(client, 5*time.Minute)
+//	provider, err := crypto.NewKeySourceProvider(cached)
+//
+// Server side:
+//
+//	srv, err := keyservice.NewServer(keyservice.WithWrappingProvider(ring))
+//	gs := grpc.NewServer(grpc.Creds(mtlsCreds))
+//	srv.Register(gs)
+//	_ = gs.Serve(lis)
+//
+// The server performs no authentication or authorisation of its own; run it
+// with mutual TLS and, where callers need different rights, a gRPC
+// interceptor.
+package keyservice
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/keyservice/keyservicepb"
+)
+
+// keySize is the size of an AES-256 KEK or DEK.
+const keySize = 32
+
+// Client calls a KeyService. It is safe for concurrent use.
+type Client struct {
+	rpc keyservicepb.KeyServiceClient
+}
+
+// Compile-time interface checks.
+var (
+	_ crypto.KeySource  = (*Client)(nil)
+	_ crypto.KeyWrapper = (*Client)(nil)
+)
+
+// NewClient creates a Client that sends calls over conn. The caller owns
+// conn and closes it after the providers built on the Client.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: keyservicepb.NewKeyServiceClient(conn)}
+}
+
+// Name returns "keyservice".
+func (c *Client) Name() string { return "keyservice" }
+
+// CurrentKey fetches the key to encrypt new values with.
+func (c *Client) CurrentKey(ctx context.Context) (crypto.Key, error) {
+	resp, err := c.rpc.CurrentKey(ctx, &keyservicepb.CurrentKeyRequest{})
+	if err != nil {
+		return crypto.Key{}, callError("CurrentKey", err)
+	}
+	return toKey(resp)
+}
+
+// KeyByID fetches the key with the given ID.
+func (c *Client) KeyByID(ctx context.Context, id string) (crypto.Key, error) {
+	resp, err := c.rpc.KeyByID(ctx, &keyservicepb.KeyByIDRequest{Id: id})
+	if err != nil {
+		return crypto.Key{}, callError("KeyByID", err)
+	}
+	if resp.GetId() != id {
+		clear(resp.GetKey())
+		return crypto.Key{}, fmt.Errorf("keyservice: KeyByID: asked for key %q, got %q", id, resp.GetId())
+	}
+	return toKey(resp)
+}
+
+// WrapKey has the service wrap dek under its current KEK.
+func (c *Client) WrapKey(ctx context.Context, dek []byte) (string, []byte, error) {
+	resp, err := c.rpc.WrapDEK(ctx, &keyservicepb.WrapDEKRequest{Dek: dek})
+	if err != nil {
+		return "", nil, callError("WrapDEK", err)
+	}
+	return resp.GetKeyId(), resp.GetWrappedDek(), nil
+}
+
+// UnwrapKey has the service unwrap a DEK wrapped under the KEK keyID.
+func (c *Client) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	resp, err := c.rpc.UnwrapDEK(ctx, &keyservicepb.UnwrapDEKRequest{KeyId: keyID, WrappedDek: wrapped})
+	if err != nil {
+		return nil, callError("UnwrapDEK", err)
+	}
+	return resp.GetDek(), nil
+}
+
+// toKey validates a KeyResponse. The returned Key shares resp's key bytes.
+func toKey(resp *keyservicepb.KeyResponse) (crypto.Key, error) {
+	if resp.GetId() == "" {
+		clear(resp.GetKey())
+		return crypto.Key{}, fmt.Errorf("%w: service returned an empty key ID", crypto.ErrInvalidKeyID)
+	}
+	if len(resp.GetKey()) != keySize {
+		clear(resp.GetKey())
+		return crypto.Key{}, fmt.Errorf("%w: service returned %d bytes for key %q", crypto.ErrInvalidKeySize, len(resp.GetKey()), resp.GetId())
+	}
+	return crypto.Key{ID: resp.GetId(), Bytes: resp.GetKey()}, nil
+}
+
+// callError converts a failed call into an error that wraps
+// crypto.ErrKeyNotFound for NOT_FOUND and reports itself retryable for
+// codes a later attempt may not see.
+func callError(method string, err error) error {
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("keyservice: %s: %w: %s", method, crypto.ErrKeyNotFound, status.Convert(err).Message())
+	}
+	return &rpcError{method: method, err: err}
+}
+
+// rpcError is a failed call to the service.
+type rpcError struct {
+	method string
+	err    error
+}
+
+func (e *rpcError) Error() string { return "keyservice: " + e.method + ": " + e.err.Error() }
+
+func (e *rpcError) Unwrap() error { return e.err }
+
+// Retryable reports whether the call failed for a transient reason.
+func (e *rpcError) Retryable() bool {
+	switch status.Code(e.err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package keyservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+// mapSource is an in-memory crypto.KeySource.
+type mapSource struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (m *mapSource) CurrentKey(ctx context.Context) (crypto.Key, error) {
+	return m.KeyByID(ctx, m.current)
+}
+
+func (m *mapSource) KeyByID(_ context.Context, id string) (crypto.Key, error) {
+	k, ok := m.keys[id]
+	if !ok {
+		return crypto.Key{}, fmt.Errorf("%w: %s", crypto.ErrKeyNotFound, id)
+	}
+	return crypto.Key{ID: id, Bytes: append([]byte(nil), k...)}, nil
+}
+
+// dial starts srv on an in-memory listener and returns a Client for it.
+func dial(t *testing.T, srv *Server) *Client {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	srv.Register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewClient(conn)
+}
+
+func TestWrapping_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	ring, err := crypto.NewKeyRingProvider(makeKey(1), "kek-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	srv, err := NewServer(WithWrappingProvider(ring))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := crypto.NewWrappingProvider(dial(t, srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.Name() != "keyservice" {
+		t.Errorf("Name = %q, want keyservice", p.Name())
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "kek-1" {
+		t.Errorf("KeyIDOf = %q, want kek-1", id)
+	}
+
+	// Rotating the server's ring rotates new values but keeps old ones
+	// readable.
+	if err := ring.AddKey(makeKey(2), "kek-2", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.SetCurrentKey("kek-2"); err != nil {
+		t.Fatal(err)
+	}
+	ct2, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct2); id != "kek-2" {
+		t.Errorf("KeyIDOf after rotation = %q, want kek-2", id)
+	}
+	for _, c := range [][]byte{ct, ct2} {
+		pt, err := p.Decrypt(ctx, c)
+		if err != nil || string(pt) != "secret" {
+			t.Errorf("Decrypt = %q, %v", pt, err)
+		}
+	}
+
+	if err := ring.RemoveKey("kek-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Decrypt(ctx, ct); !crypto.IsKeyNotFound(err) {
+		t.Errorf("Decrypt under removed key: expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestUnwrap_KeyIDMismatch(t *testing.T) {
+	ctx := context.Background()
+	ring, err := crypto.NewKeyRingProvider(makeKey(1), "kek-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	srv, _ := NewServer(WithWrappingProvider(ring))
+	c := dial(t, srv)
+
+	_, wrapped, err := c.WrapKey(ctx, makeKey(9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.UnwrapKey(ctx, "other", wrapped)
+	if status.Code(errors.Unwrap(err)) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestUnwrap_RejectsNonDEK(t *testing.T) {
+	ctx := context.Background()
+	ring, err := crypto.NewKeyRingProvider(makeKey(1), "kek-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	srv, _ := NewServer(WithWrappingProvider(ring))
+	c := dial(t, srv)
+
+	// A config value encrypted directly under the wrapping provider must not
+	// be decryptable through the service.
+	ct, err := ring.Encrypt(ctx, []byte("db-password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dek, err := c.UnwrapKey(ctx, "kek-1", ct)
+	if status.Code(errors.Unwrap(err)) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %q, %v", dek, err)
+	}
+}
+
+func TestKeySource_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := &mapSource{current: "kek-2", keys: map[string][]byte{"kek-1": makeKey(1), "kek-2": makeKey(2)}}
+	srv, err := NewServer(WithKeySource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := dial(t, srv)
+
+	k, err := c.CurrentKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.ID != "kek-2" || string(k.Bytes) != string(makeKey(2)) {
+		t.Errorf("CurrentKey = %q", k.ID)
+	}
+	if _, err := c.KeyByID(ctx, "missing"); !crypto.IsKeyNotFound(err) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	p, err := crypto.NewKeySourceProvider(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := crypto.NewProvider(makeKey(2), "kek-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer local.Close()
+	pt, err := local.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt with local key = %q, %v", pt, err)
+	}
+}
+
+func TestServer_ModeNotEnabled(t *testing.T) {
+	ctx := context.Background()
+	srv, _ := NewServer(WithKeySource(&mapSource{}))
+	c := dial(t, srv)
+	_, _, err := c.WrapKey(ctx, makeKey(1))
+	if status.Code(errors.Unwrap(err)) != codes.Unimplemented {
+		t.Errorf("expected Unimplemented, got %v", err)
+	}
+	if crypto.IsRetryable(err) {
+		t.Error("Unimplemented should not be retryable")
+	}
+}
+
+func TestNewServer_NoMode(t *testing.T) {
+	if _, err := NewServer(); err == nil {
+		t.Error("expected error without WithKeySource or WithWrappingProvider")
+	}
+}
+
+func TestCallError_Retryable(t *testing.T) {
+	err := callError("CurrentKey", status.Error(codes.Unavailable, "down"))
+	if !crypto.IsRetryable(err) {
+		t.Errorf("Unavailable should be retryable: %v", err)
+	}
+}
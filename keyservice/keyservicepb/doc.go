@@ -0,0 +1,6 @@
+// Package keyservicepb contains the protobuf messages and gRPC bindings for
+// the key service defined in keyservice.proto. Use package keyservice for
+// the client adapters and the reference server.
+package keyservicepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative keyservice.proto
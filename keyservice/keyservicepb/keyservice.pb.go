@@ -0,0 +1,441 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: keyservice.proto
+
+package keyservicepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CurrentKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CurrentKeyRequest) Reset() {
+	*x = CurrentKeyRequest{}
+	mi := &file_keyservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CurrentKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CurrentKeyRequest) ProtoMessage() {}
+
+func (x *CurrentKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keyservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CurrentKeyRequest.ProtoReflect.Descriptor instead.
+func (*CurrentKeyRequest) Descriptor() ([]byte, []int) {
+	return file_keyservice_proto_rawDescGZIP(), []int{0}
+}
+
+type KeyByIDRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyByIDRequest) Reset() {
+	*x = KeyByIDRequest{}
+	mi := &file_keyservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyByIDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyByIDRequest) ProtoMessage() {}
+
+func (x *KeyByIDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keyservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyByIDRequest.ProtoReflect.Descriptor instead.
+func (*KeyByIDRequest) Descriptor() ([]byte, []int) {
+	return file_keyservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *KeyByIDRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type KeyResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is the key identifier written into ciphertext headers.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// key is the 32-byte AES-256 KEK.
+	Key           []byte `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyResponse) Reset() {
+	*x = KeyResponse{}
+	mi := &file_keyservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyResponse) ProtoMessage() {}
+
+func (x *KeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_keyservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyResponse.ProtoReflect.Descriptor instead.
+func (*KeyResponse) Descriptor() ([]byte, []int) {
+	return file_keyservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *KeyResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *KeyResponse) GetKey() []byte {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+type WrapDEKRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dek           []byte                 `protobuf:"bytes,1,opt,name=dek,proto3" json:"dek,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WrapDEKRequest) Reset() {
+	*x = WrapDEKRequest{}
+	mi := &file_keyservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WrapDEKRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WrapDEKRequest) ProtoMessage() {}
+
+func (x *WrapDEKRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keyservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WrapDEKRequest.ProtoReflect.Descriptor instead.
+func (*WrapDEKRequest) Descriptor() ([]byte, []int) {
+	return file_keyservice_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WrapDEKRequest) GetDek() []byte {
+	if x != nil {
+		return x.Dek
+	}
+	return nil
+}
+
+type WrapDEKResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	WrappedDek    []byte                 `protobuf:"bytes,2,opt,name=wrapped_dek,json=wrappedDek,proto3" json:"wrapped_dek,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WrapDEKResponse) Reset() {
+	*x = WrapDEKResponse{}
+	mi := &file_keyservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WrapDEKResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WrapDEKResponse) ProtoMessage() {}
+
+func (x *WrapDEKResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_keyservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WrapDEKResponse.ProtoReflect.Descriptor instead.
+func (*WrapDEKResponse) Descriptor() ([]byte, []int) {
+	return file_keyservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WrapDEKResponse) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *WrapDEKResponse) GetWrappedDek() []byte {
+	if x != nil {
+		return x.WrappedDek
+	}
+	return nil
+}
+
+type UnwrapDEKRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	WrappedDek    []byte                 `protobuf:"bytes,2,opt,name=wrapped_dek,json=wrappedDek,proto3" json:"wrapped_dek,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnwrapDEKRequest) Reset() {
+	*x = UnwrapDEKRequest{}
+	mi := &file_keyservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnwrapDEKRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnwrapDEKRequest) ProtoMessage() {}
+
+func (x *UnwrapDEKRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_keyservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnwrapDEKRequest.ProtoReflect.Descriptor instead.
+func (*UnwrapDEKRequest) Descriptor() ([]byte, []int) {
+	return file_keyservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UnwrapDEKRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *UnwrapDEKRequest) GetWrappedDek() []byte {
+	if x != nil {
+		return x.WrappedDek
+	}
+	return nil
+}
+
+type UnwrapDEKResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dek           []byte                 `protobuf:"bytes,1,opt,name=dek,proto3" json:"dek,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnwrapDEKResponse) Reset() {
+	*x = UnwrapDEKResponse{}
+	mi := &file_keyservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnwrapDEKResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnwrapDEKResponse) ProtoMessage() {}
+
+func (x *UnwrapDEKResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_keyservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnwrapDEKResponse.ProtoReflect.Descriptor instead.
+func (*UnwrapDEKResponse) Descriptor() ([]byte, []int) {
+	return file_keyservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UnwrapDEKResponse) GetDek() []byte {
+	if x != nil {
+		return x.Dek
+	}
+	return nil
+}
+
+var File_keyservice_proto protoreflect.FileDescriptor
+
+const file_keyservice_proto_rawDesc = "" +
+	"\n" +
+	"\x10keyservice.proto\x12\x1aconfigcrypto.keyservice.v1\"\x13\n" +
+	"\x11CurrentKeyRequest\" \n" +
+	"\x0eKeyByIDRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"/\n" +
+	"\vKeyResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\fR\x03key\"\"\n" +
+	"\x0eWrapDEKRequest\x12\x10\n" +
+	"\x03dek\x18\x01 \x01(\fR\x03dek\"I\n" +
+	"\x0fWrapDEKResponse\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x12\x1f\n" +
+	"\vwrapped_dek\x18\x02 \x01(\fR\n" +
+	"wrappedDek\"J\n" +
+	"\x10UnwrapDEKRequest\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x12\x1f\n" +
+	"\vwrapped_dek\x18\x02 \x01(\fR\n" +
+	"wrappedDek\"%\n" +
+	"\x11UnwrapDEKResponse\x12\x10\n" +
+	"\x03dek\x18\x01 \x01(\fR\x03dek2\xa0\x03\n" +
+	"\n" +
+	"KeyService\x12d\n" +
+	"\n" +
+	"CurrentKey\x12-.configcrypto.keyservice.v1.CurrentKeyRequest\x1a'.configcrypto.keyservice.v1.KeyResponse\x12^\n" +
+	"\aKeyByID\x12*.configcrypto.keyservice.v1.KeyByIDRequest\x1a'.configcrypto.keyservice.v1.KeyResponse\x12b\n" +
+	"\aWrapDEK\x12*.configcrypto.keyservice.v1.WrapDEKRequest\x1a+.configcrypto.keyservice.v1.WrapDEKResponse\x12h\n" +
+	"\tUnwrapDEK\x12,.configcrypto.keyservice.v1.UnwrapDEKRequest\x1a-.configcrypto.keyservice.v1.UnwrapDEKResponseB;Z9github.com/rbaliyan/config-crypto/keyservice/keyservicepbb\x06proto3"
+
+var (
+	file_keyservice_proto_rawDescOnce sync.Once
+	file_keyservice_proto_rawDescData []byte
+)
+
+func file_keyservice_proto_rawDescGZIP() []byte {
+	file_keyservice_proto_rawDescOnce.Do(func() {
+		file_keyservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_keyservice_proto_rawDesc), len(file_keyservice_proto_rawDesc)))
+	})
+	return file_keyservice_proto_rawDescData
+}
+
+var file_keyservice_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_keyservice_proto_goTypes = []any{
+	(*CurrentKeyRequest)(nil), // 0: configcrypto.keyservice.v1.CurrentKeyRequest
+	(*KeyByIDRequest)(nil),    // 1: configcrypto.keyservice.v1.KeyByIDRequest
+	(*KeyResponse)(nil),       // 2: configcrypto.keyservice.v1.KeyResponse
+	(*WrapDEKRequest)(nil),    // 3: configcrypto.keyservice.v1.WrapDEKRequest
+	(*WrapDEKResponse)(nil),   // 4: configcrypto.keyservice.v1.WrapDEKResponse
+	(*UnwrapDEKRequest)(nil),  // 5: configcrypto.keyservice.v1.UnwrapDEKRequest
+	(*UnwrapDEKResponse)(nil), // 6: configcrypto.keyservice.v1.UnwrapDEKResponse
+}
+var file_keyservice_proto_depIdxs = []int32{
+	0, // 0: configcrypto.keyservice.v1.KeyService.CurrentKey:input_type -> configcrypto.keyservice.v1.CurrentKeyRequest
+	1, // 1: configcrypto.keyservice.v1.KeyService.KeyByID:input_type -> configcrypto.keyservice.v1.KeyByIDRequest
+	3, // 2: configcrypto.keyservice.v1.KeyService.WrapDEK:input_type -> configcrypto.keyservice.v1.WrapDEKRequest
+	5, // 3: configcrypto.keyservice.v1.KeyService.UnwrapDEK:input_type -> configcrypto.keyservice.v1.UnwrapDEKRequest
+	2, // 4: configcrypto.keyservice.v1.KeyService.CurrentKey:output_type -> configcrypto.keyservice.v1.KeyResponse
+	2, // 5: configcrypto.keyservice.v1.KeyService.KeyByID:output_type -> configcrypto.keyservice.v1.KeyResponse
+	4, // 6: configcrypto.keyservice.v1.KeyService.WrapDEK:output_type -> configcrypto.keyservice.v1.WrapDEKResponse
+	6, // 7: configcrypto.keyservice.v1.KeyService.UnwrapDEK:output_type -> configcrypto.keyservice.v1.UnwrapDEKResponse
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_keyservice_proto_init() }
+func file_keyservice_proto_init() {
+	if File_keyservice_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_keyservice_proto_rawDesc), len(file_keyservice_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_keyservice_proto_goTypes,
+		DependencyIndexes: file_keyservice_proto_depIdxs,
+		MessageInfos:      file_keyservice_proto_msgTypes,
+	}.Build()
+	File_keyservice_proto = out.File
+	file_keyservice_proto_goTypes = nil
+	file_keyservice_proto_depIdxs = nil
+}
@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: keyservice.proto
+
+package keyservicepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	KeyService_CurrentKey_FullMethodName = "/configcrypto.keyservice.v1.KeyService/CurrentKey"
+	KeyService_KeyByID_FullMethodName    = "/configcrypto.keyservice.v1.KeyService/KeyByID"
+	KeyService_WrapDEK_FullMethodName    = "/configcrypto.keyservice.v1.KeyService/WrapDEK"
+	KeyService_UnwrapDEK_FullMethodName  = "/configcrypto.keyservice.v1.KeyService/UnwrapDEK"
+)
+
+// KeyServiceClient is the client API for KeyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// KeyService hands out key-encryption keys (KEKs), or wraps data-encryption
+// keys (DEKs) under them, on behalf of config-crypto clients, so that only
+// the key service needs credentials for the underlying KMS.
+type KeyServiceClient interface {
+	// CurrentKey returns the KEK to encrypt new values with.
+	CurrentKey(ctx context.Context, in *CurrentKeyRequest, opts ...grpc.CallOption) (*KeyResponse, error)
+	// KeyByID returns the KEK with the given ID. It fails with NOT_FOUND if
+	// the service does not hold it.
+	KeyByID(ctx context.Context, in *KeyByIDRequest, opts ...grpc.CallOption) (*KeyResponse, error)
+	// WrapDEK wraps a DEK under the current KEK, which never leaves the
+	// service.
+	WrapDEK(ctx context.Context, in *WrapDEKRequest, opts ...grpc.CallOption) (*WrapDEKResponse, error)
+	// UnwrapDEK returns the DEK that WrapDEK wrapped under the KEK key_id. It
+	// fails with NOT_FOUND if the service does not hold that KEK.
+	UnwrapDEK(ctx context.Context, in *UnwrapDEKRequest, opts ...grpc.CallOption) (*UnwrapDEKResponse, error)
+}
+
+type keyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKeyServiceClient(cc grpc.ClientConnInterface) KeyServiceClient {
+	return &keyServiceClient{cc}
+}
+
+func (c *keyServiceClient) CurrentKey(ctx context.Context, in *CurrentKeyRequest, opts ...grpc.CallOption) (*KeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, KeyService_CurrentKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyServiceClient) KeyByID(ctx context.Context, in *KeyByIDRequest, opts ...grpc.CallOption) (*KeyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, KeyService_KeyByID_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyServiceClient) WrapDEK(ctx context.Context, in *WrapDEKRequest, opts ...grpc.CallOption) (*WrapDEKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WrapDEKResponse)
+	err := c.cc.Invoke(ctx, KeyService_WrapDEK_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyServiceClient) UnwrapDEK(ctx context.Context, in *UnwrapDEKRequest, opts ...grpc.CallOption) (*UnwrapDEKResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnwrapDEKResponse)
+	err := c.cc.Invoke(ctx, KeyService_UnwrapDEK_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// KeyServiceServer is the server API for KeyService service.
+// All implementations must embed UnimplementedKeyServiceServer
+// for forward compatibility.
+//
+// KeyService hands out key-encryption keys (KEKs), or wraps data-encryption
+// keys (DEKs) under them, on behalf of config-crypto clients, so that only
+// the key service needs credentials for the underlying KMS.
+type KeyServiceServer interface {
+	// CurrentKey returns the KEK to encrypt new values with.
+	CurrentKey(context.Context, *CurrentKeyRequest) (*KeyResponse, error)
+	// KeyByID returns the KEK with the given ID. It fails with NOT_FOUND if
+	// the service does not hold it.
+	KeyByID(context.Context, *KeyByIDRequest) (*KeyResponse, error)
+	// WrapDEK wraps a DEK under the current KEK, which never leaves the
+	// service.
+	WrapDEK(context.Context, *WrapDEKRequest) (*WrapDEKResponse, error)
+	// UnwrapDEK returns the DEK that WrapDEK wrapped under the KEK key_id. It
+	// fails with NOT_FOUND if the service does not hold that KEK.
+	UnwrapDEK(context.Context, *UnwrapDEKRequest) (*UnwrapDEKResponse, error)
+	mustEmbedUnimplementedKeyServiceServer()
+}
+
+// UnimplementedKeyServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedKeyServiceServer struct{}
+
+func (UnimplementedKeyServiceServer) CurrentKey(context.Context, *CurrentKeyRequest) (*KeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CurrentKey not implemented")
+}
+func (UnimplementedKeyServiceServer) KeyByID(context.Context, *KeyByIDRequest) (*KeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KeyByID not implemented")
+}
+func (UnimplementedKeyServiceServer) WrapDEK(context.Context, *WrapDEKRequest) (*WrapDEKResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WrapDEK not implemented")
+}
+func (UnimplementedKeyServiceServer) UnwrapDEK(context.Context, *UnwrapDEKRequest) (*UnwrapDEKResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnwrapDEK not implemented")
+}
+func (UnimplementedKeyServiceServer) mustEmbedUnimplementedKeyServiceServer() {}
+func (UnimplementedKeyServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeKeyServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KeyServiceServer will
+// result in compilation errors.
+type UnsafeKeyServiceServer interface {
+	mustEmbedUnimplementedKeyServiceServer()
+}
+
+func RegisterKeyServiceServer(s grpc.ServiceRegistrar, srv KeyServiceServer) {
+	// If the following call pancis, it indicates UnimplementedKeyServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&KeyService_ServiceDesc, srv)
+}
+
+func _KeyService_CurrentKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CurrentKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServiceServer).CurrentKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyService_CurrentKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServiceServer).CurrentKey(ctx, req.(*CurrentKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyService_KeyByID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyByIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServiceServer).KeyByID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyService_KeyByID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServiceServer).KeyByID(ctx, req.(*KeyByIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyService_WrapDEK_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WrapDEKRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServiceServer).WrapDEK(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyService_WrapDEK_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServiceServer).WrapDEK(ctx, req.(*WrapDEKRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyService_UnwrapDEK_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnwrapDEKRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyServiceServer).UnwrapDEK(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KeyService_UnwrapDEK_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyServiceServer).UnwrapDEK(ctx, req.(*UnwrapDEKRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// KeyService_ServiceDesc is the grpc.ServiceDesc for KeyService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var KeyService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "configcrypto.keyservice.v1.KeyService",
+	HandlerType: (*KeyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CurrentKey",
+			Handler:    _KeyService_CurrentKey_Handler,
+		},
+		{
+			MethodName: "KeyByID",
+			Handler:    _KeyService_KeyByID_Handler,
+		},
+		{
+			MethodName: "WrapDEK",
+			Handler:    _KeyService_WrapDEK_Handler,
+		},
+		{
+			MethodName: "UnwrapDEK",
+			Handler:    _KeyService_UnwrapDEK_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "keyservice.proto",
+}
@@ -0,0 +1,162 @@
+package keyservice
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/keyservice/keyservicepb"
+)
+
+// Server is a reference KeyService implementation. Calls to a mode that was
+// not enabled fail with UNIMPLEMENTED.
+type Server struct {
+	keyservicepb.UnimplementedKeyServiceServer
+
+	src crypto.KeySource
+	kek crypto.Provider
+}
+
+// Compile-time interface check.
+var _ keyservicepb.KeyServiceServer = (*Server)(nil)
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithKeySource serves CurrentKey and KeyByID from src, e.g. a
+// KeySource over a KMS. Every client that can reach the server can then read
+// KEKs.
+func WithKeySource(src crypto.KeySource) ServerOption {
+	return func(s *Server) {
+		s.src = src
+	}
+}
+
+// WithWrappingProvider serves WrapDEK and UnwrapDEK by encrypting DEKs with
+// p, e.g. a key ring loaded from a KMS. The wrapped DEK is p's ciphertext and
+// its key ID is p's key ID, so rotating p's current key rotates the KEK
+// clients see.
+//
+// p must be dedicated to DEK wrapping. UnwrapDEK only returns plaintexts of
+// DEK size, but any 32-byte value p has encrypted can still be recovered by
+// every client that can reach the server, so do not use p for config
+// values.
+func WithWrappingProvider(p crypto.Provider) ServerOption {
+	return func(s *Server) {
+		s.kek = p
+	}
+}
+
+// NewServer creates a Server. At least one of WithKeySource and
+// WithWrappingProvider must be given. The Server does not close them.
+func NewServer(opts ...ServerOption) (*Server, error) {
+	s := &Server{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.src == nil && s.kek == nil {
+		return nil, errors.New("keyservice: NewServer needs WithKeySource or WithWrappingProvider")
+	}
+	return s, nil
+}
+
+// Register registers s with a gRPC server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	keyservicepb.RegisterKeyServiceServer(r, s)
+}
+
+// CurrentKey returns the key source's current key.
+func (s *Server) CurrentKey(ctx context.Context, _ *keyservicepb.CurrentKeyRequest) (*keyservicepb.KeyResponse, error) {
+	if s.src == nil {
+		return nil, status.Error(codes.Unimplemented, "keyservice: key export is not enabled")
+	}
+	k, err := s.src.CurrentKey(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &keyservicepb.KeyResponse{Id: k.ID, Key: k.Bytes}, nil
+}
+
+// KeyByID returns the key source's key with the requested ID.
+func (s *Server) KeyByID(ctx context.Context, req *keyservicepb.KeyByIDRequest) (*keyservicepb.KeyResponse, error) {
+	if s.src == nil {
+		return nil, status.Error(codes.Unimplemented, "keyservice: key export is not enabled")
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "keyservice: empty key ID")
+	}
+	k, err := s.src.KeyByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &keyservicepb.KeyResponse{Id: k.ID, Key: k.Bytes}, nil
+}
+
+// WrapDEK encrypts the DEK with the wrapping provider.
+func (s *Server) WrapDEK(ctx context.Context, req *keyservicepb.WrapDEKRequest) (*keyservicepb.WrapDEKResponse, error) {
+	if s.kek == nil {
+		return nil, status.Error(codes.Unimplemented, "keyservice: key wrapping is not enabled")
+	}
+	if len(req.GetDek()) != keySize {
+		return nil, status.Errorf(codes.InvalidArgument, "keyservice: DEK is %d bytes, want %d", len(req.GetDek()), keySize)
+	}
+	wrapped, err := s.kek.Encrypt(ctx, req.GetDek())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	keyID, err := crypto.KeyIDOf(wrapped)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &keyservicepb.WrapDEKResponse{KeyId: keyID, WrappedDek: wrapped}, nil
+}
+
+// UnwrapDEK decrypts the wrapped DEK with the wrapping provider, after
+// checking that it was wrapped under the KEK the client named. Plaintexts
+// that are not DEK-sized are withheld, so the server cannot be used to
+// decrypt arbitrary ciphertexts of the wrapping provider.
+func (s *Server) UnwrapDEK(ctx context.Context, req *keyservicepb.UnwrapDEKRequest) (*keyservicepb.UnwrapDEKResponse, error) {
+	if s.kek == nil {
+		return nil, status.Error(codes.Unimplemented, "keyservice: key wrapping is not enabled")
+	}
+	keyID, err := crypto.KeyIDOf(req.GetWrappedDek())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if keyID != req.GetKeyId() {
+		return nil, status.Errorf(codes.InvalidArgument, "keyservice: DEK is wrapped under key %q, not %q", keyID, req.GetKeyId())
+	}
+	dek, err := s.kek.Decrypt(ctx, req.GetWrappedDek())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if len(dek) != keySize {
+		clear(dek)
+		return nil, status.Error(codes.InvalidArgument, "keyservice: wrapped value is not a DEK")
+	}
+	return &keyservicepb.UnwrapDEKResponse{Dek: dek}, nil
+}
+
+// toStatus maps a config-crypto error to a gRPC status.
+func toStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case crypto.IsKeyNotFound(err):
+		code = codes.NotFound
+	case crypto.IsInvalidFormat(err), crypto.IsUnsupportedFormat(err), crypto.IsDecryptionFailed(err):
+		code = codes.InvalidArgument
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case crypto.IsThrottled(err):
+		code = codes.ResourceExhausted
+	case crypto.IsRetryable(err):
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,268 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Keystore is a KeyRingFile kept on disk encrypted under a passphrase, for
+// teams without a KMS who still must not leave plaintext KEKs on disk.
+//
+// The file is a single envelope ciphertext of the keyring JSON, encrypted
+// with NewPassphraseProvider: its key ID records the KDF and salt, so
+// OpenKeystore needs only the path and the passphrase. Every change is
+// written back atomically before the method returns.
+//
+//	params, _ := crypto.NewArgon2idParams()
+//	ks, err := crypto.CreateKeystore("keys.cks", passphrase, params)
+//	...
+//	ks, err := crypto.OpenKeystore("keys.cks", passphrase)
+//	defer ks.Close()
+//	ring, err := ks.Provider()
+//
+// A Keystore is safe for concurrent use, but not for concurrent writers in
+// different processes.
+type Keystore struct {
+	path string
+
+	mu   sync.Mutex
+	kek  Provider // passphrase provider; nil once closed
+	file *KeyRingFile
+}
+
+// CreateKeystore creates a keystore at path holding one freshly generated
+// key, whose ID is its KeyFingerprint. The keystore's KEK is derived from
+// passphrase with params; NewArgon2idParams is the recommended choice. It
+// fails if path already exists.
+func CreateKeystore(path string, passphrase []byte, params KDFParams) (*Keystore, error) {
+	if _, err := os.Lstat(path); err == nil {
+		return nil, fmt.Errorf("crypto: CreateKeystore: %w", fs.ErrExist)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("crypto: CreateKeystore: %w", err)
+	}
+	kek, err := NewPassphraseProvider(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		_ = kek.Close()
+		return nil, err
+	}
+	ks := &Keystore{
+		path: path,
+		kek:  kek,
+		file: &KeyRingFile{
+			Version: KeyRingFileVersion,
+			Keys: []KeyRingFileKey{{
+				ID:        KeyFingerprint(key),
+				Key:       key,
+				State:     KeyStateCurrent,
+				Rank:      1,
+				CreatedAt: time.Now().UTC(),
+			}},
+		},
+	}
+	if err := ks.saveLocked(); err != nil {
+		_ = ks.Close()
+		return nil, err
+	}
+	return ks, nil
+}
+
+// OpenKeystore decrypts the keystore at path with passphrase. A wrong
+// passphrase fails with ErrDecryptionFailed.
+func OpenKeystore(path string, passphrase []byte) (*Keystore, error) {
+	blob, err := os.ReadFile(path) // #nosec G304 -- path is configured by the caller
+	if err != nil {
+		return nil, fmt.Errorf("crypto: OpenKeystore: %w", err)
+	}
+	id, err := KeyIDOf(blob)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: OpenKeystore: %w", err)
+	}
+	params, err := ParseKDFParams(id)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: OpenKeystore: %w", err)
+	}
+	kek, err := NewPassphraseProvider(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := kek.Decrypt(context.Background(), blob)
+	defer clear(pt)
+	if err != nil {
+		_ = kek.Close()
+		return nil, fmt.Errorf("crypto: OpenKeystore: %w", err)
+	}
+	f, err := ParseKeyRingFile(pt)
+	if err != nil {
+		_ = kek.Close()
+		return nil, fmt.Errorf("crypto: OpenKeystore: %w", err)
+	}
+	return &Keystore{path: path, kek: kek, file: f}, nil
+}
+
+// Provider builds a KeyRingProvider from the keystore's current keys. opts
+// are as for NewKeyRingProvider. Later changes to the keystore do not
+// affect the returned provider.
+func (ks *Keystore) Provider(opts ...ProviderOption) (KeyRingProvider, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.kek == nil {
+		return nil, ErrProviderClosed
+	}
+	return ks.file.Provider(opts...)
+}
+
+// KeyIDs returns the IDs of the keys in the keystore, current key first.
+func (ks *Keystore) KeyIDs() []string {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.kek == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(ks.file.Keys))
+	if cur, ok := ks.file.Current(); ok {
+		ids = append(ids, cur.ID)
+	}
+	for _, k := range ks.file.Keys {
+		if k.State != KeyStateCurrent {
+			ids = append(ids, k.ID)
+		}
+	}
+	return ids
+}
+
+// AddKey adds key as an old key under id, e.g. to import a key that data
+// was encrypted with elsewhere, and saves the keystore. An empty id uses
+// KeyFingerprint. The keystore keeps its own copy of key.
+func (ks *Keystore) AddKey(key []byte, id string) error {
+	if len(key) != aesKeySize {
+		return fmt.Errorf("%w: got %d bytes", ErrInvalidKeySize, len(key))
+	}
+	if id == "" {
+		id = KeyFingerprint(key)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return ks.update(func(f *KeyRingFile) error {
+		f.Keys = append(f.Keys, KeyRingFileKey{
+			ID:        id,
+			Key:       bytes.Clone(key),
+			State:     KeyStateOld,
+			Rank:      maxFileRank(f) + 1,
+			CreatedAt: time.Now().UTC(),
+		})
+		return nil
+	})
+}
+
+// Rotate generates a new key, makes it current, demotes the previous
+// current key to old, and saves the keystore. An empty id uses
+// KeyFingerprint. It returns the new key's ID.
+func (ks *Keystore) Rotate(id string) (string, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		id = KeyFingerprint(key)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	err = ks.update(func(f *KeyRingFile) error {
+		for i := range f.Keys {
+			if f.Keys[i].State == KeyStateCurrent {
+				f.Keys[i].State = KeyStateOld
+			}
+		}
+		f.Keys = append([]KeyRingFileKey{{
+			ID:        id,
+			Key:       key,
+			State:     KeyStateCurrent,
+			Rank:      maxFileRank(f) + 1,
+			CreatedAt: time.Now().UTC(),
+		}}, f.Keys...)
+		return nil
+	})
+	if err != nil {
+		clear(key)
+		return "", err
+	}
+	return id, nil
+}
+
+// Close wipes the keystore's key material and passphrase. Safe to call
+// multiple times.
+func (ks *Keystore) Close() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.kek == nil {
+		return nil
+	}
+	ks.file.Wipe()
+	err := ks.kek.Close()
+	ks.kek = nil
+	return err
+}
+
+// update applies fn to a copy of the keyring, validates and saves it, and
+// only then replaces the in-memory keyring. Caller must hold mu.
+func (ks *Keystore) update(fn func(*KeyRingFile) error) error {
+	if ks.kek == nil {
+		return ErrProviderClosed
+	}
+	next := &KeyRingFile{Version: ks.file.Version, Keys: make([]KeyRingFileKey, len(ks.file.Keys))}
+	for i, k := range ks.file.Keys {
+		k.Key = bytes.Clone(k.Key)
+		next.Keys[i] = k
+	}
+	if err := fn(next); err != nil {
+		next.Wipe()
+		return err
+	}
+	if err := next.Validate(); err != nil {
+		next.Wipe()
+		return err
+	}
+	prev := ks.file
+	ks.file = next
+	if err := ks.saveLocked(); err != nil {
+		ks.file = prev
+		next.Wipe()
+		return err
+	}
+	prev.Wipe()
+	return nil
+}
+
+// saveLocked encrypts the keyring and writes it to path. Caller must hold
+// mu.
+func (ks *Keystore) saveLocked() error {
+	pt, err := ks.file.Encode(false)
+	defer clear(pt)
+	if err != nil {
+		return err
+	}
+	blob, err := ks.kek.Encrypt(context.Background(), pt)
+	if err != nil {
+		return fmt.Errorf("crypto: keystore: %w", err)
+	}
+	return writeFileAtomic(ks.path, blob)
+}
+
+// maxFileRank returns the highest rank in f.
+func maxFileRank(f *KeyRingFile) uint64 {
+	var r uint64
+	for _, k := range f.Keys {
+		r = max(r, k.Rank)
+	}
+	return r
+}
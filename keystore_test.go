@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeystoreLifecycle(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.cks")
+	pass := []byte("correct horse battery staple")
+
+	ks, err := CreateKeystore(path, pass, cheapKDFParams()[0])
+	if err != nil {
+		t.Fatalf("CreateKeystore: %v", err)
+	}
+	first := ks.KeyIDs()[0]
+	ring, err := ks.Provider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := ring.Encrypt(ctx, []byte("old"))
+	_ = ring.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newID, err := ks.Rotate("")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if err := ks.AddKey(makeKey(32), "imported"); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if err := ks.AddKey(makeKey(32), "imported"); err == nil {
+		t.Error("AddKey with duplicate ID: want error")
+	}
+	_ = ks.Close()
+
+	if _, err := CreateKeystore(path, pass, cheapKDFParams()[0]); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("CreateKeystore over existing file: got %v, want fs.ErrExist", err)
+	}
+
+	ks, err = OpenKeystore(path, pass)
+	if err != nil {
+		t.Fatalf("OpenKeystore: %v", err)
+	}
+	defer ks.Close()
+	ids := ks.KeyIDs()
+	if len(ids) != 3 || ids[0] != newID {
+		t.Fatalf("KeyIDs = %v, want %s first of 3", ids, newID)
+	}
+	ring, err = ks.Provider()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if ring.CurrentKeyID() != newID {
+		t.Errorf("CurrentKeyID = %q, want %q", ring.CurrentKeyID(), newID)
+	}
+	if pt, err := ring.Decrypt(ctx, old); err != nil || string(pt) != "old" {
+		t.Errorf("Decrypt under %s = %q, %v", first, pt, err)
+	}
+	if need, _ := ring.NeedsReencryption(old); !need {
+		t.Error("value under the first key should need re-encryption")
+	}
+}
+
+func TestOpenKeystoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.cks")
+	ks, err := CreateKeystore(path, []byte("right"), cheapKDFParams()[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ks.Close()
+	if _, err := OpenKeystore(path, []byte("wrong")); !IsDecryptionFailed(err) {
+		t.Errorf("OpenKeystore wrong passphrase: got %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestKeystoreClosed(t *testing.T) {
+	ks, err := CreateKeystore(filepath.Join(t.TempDir(), "k"), []byte("p"), cheapKDFParams()[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = ks.Close()
+	_ = ks.Close()
+	if _, err := ks.Rotate(""); !IsProviderClosed(err) {
+		t.Errorf("Rotate after Close: got %v, want ErrProviderClosed", err)
+	}
+	if _, err := ks.Provider(); !IsProviderClosed(err) {
+		t.Errorf("Provider after Close: got %v, want ErrProviderClosed", err)
+	}
+}
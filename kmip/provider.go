@@ -0,0 +1,134 @@
+// Package kmip provides a crypto.KeyRingProvider whose keys are held on a
+// KMIP key management appliance (Thales CipherTrust, Entrust KeyControl,
+// Fortanix, HashiCorp Vault's KMIP engine, PyKMIP).
+//
+// Keys are loaded at construction time in one of two ways:
+//
+//   - WithKey fetches an extractable AES-256 symmetric key object with the
+//     KMIP Get operation.
+//   - WithEncryptedKey has the appliance decrypt a locally stored KEK with
+//     the KMIP Decrypt operation, for key objects that may not leave the
+//     appliance.
+//
+// The TTLV protocol, TLS client certificates, and appliance-specific
+// cryptographic parameters are left to the Client, so this package has no
+// KMIP library dependency. Wrap the library of your choice:
+//
+//	type myKMIPClient struct{ c *kmipclient.Client }
+//
+//	func (k *myKMIPClient) Get(ctx context.Context, uid string) ([]byte, error) {
+//	    // KMIP Get with KeyFormatType Raw; return the key material bytes.
+//	}
+//
+//	func (k *myKMIPClient) Decrypt(ctx context.Context, uid string, ciphertext []byte) ([]byte, error) {
+//	    // Split ciphertext into the IV, data, and tag your Encrypt call
+//	    // produced, then call KMIP Decrypt.
+//	}
+//
+//	provider, err := kmip.New(ctx, &myKMIPClient{c},
+//	    kmip.WithKey("5f0c1a2e-...", "kek-2"),
+//	    kmip.WithEncryptedKey("8d7e3b41-...", wrappedKEK, "kek-1"),
+//	)
+package kmip
+
+import (
+	"context"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// Client performs KMIP operations on managed objects, identified by their
+// KMIP Unique Identifier.
+type Client interface {
+	// Get returns the raw key material of the symmetric key object uid.
+	// New calls Get concurrently when several keys are configured.
+	Get(ctx context.Context, uid string) ([]byte, error)
+
+	// Decrypt decrypts ciphertext with the key object uid. ciphertext is
+	// stored as given to WithEncryptedKey, so it carries whatever IV and
+	// tag framing the client's encryption produced. New calls Decrypt
+	// concurrently when several keys are configured.
+	Decrypt(ctx context.Context, uid string, ciphertext []byte) ([]byte, error)
+}
+
+// Option configures the KMIP provider.
+type Option func(*options)
+
+type options struct {
+	keys []managedKey
+}
+
+type managedKey struct {
+	uid        string
+	ciphertext []byte // nil: fetch the key object itself with Get
+	id         string
+}
+
+// WithKey registers the AES-256 symmetric key object uid, fetched with
+// KMIP Get. The object must be extractable. The id identifies this key in
+// the config-crypto system; if empty, uid is used.
+//
+// The first key registered sets the current key used for new encryptions.
+// Subsequent keys are available for decryption during key rotation.
+func WithKey(uid, id string) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, managedKey{uid: uid, id: id})
+	}
+}
+
+// WithEncryptedKey registers an AES-256 key encrypted under the key object
+// uid, recovered with KMIP Decrypt. The id identifies this key in the
+// config-crypto system; unlike WithKey it is required, since several keys
+// may be encrypted under one key object.
+func WithEncryptedKey(uid string, ciphertext []byte, id string) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, managedKey{uid: uid, ciphertext: ciphertext, id: id})
+	}
+}
+
+// New creates a crypto.KeyRingProvider from keys held on a KMIP appliance.
+//
+// At least one key must be provided via WithKey or WithEncryptedKey. The
+// first key is the current key for new encryptions; additional keys support
+// decryption during key rotation.
+//
+// All keys are fetched or decrypted during construction and cached. If any
+// key fails, the errors for all failed keys are returned together. The
+// Client is not retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("kmip: Client must not be nil")
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, k := range o.keys {
+		if k.uid == "" {
+			return nil, fmt.Errorf("kmip: empty unique identifier for key %q", k.id)
+		}
+		if k.ciphertext != nil && k.id == "" {
+			return nil, fmt.Errorf("%w: WithEncryptedKey for %q needs an id", crypto.ErrInvalidKeyID, k.uid)
+		}
+	}
+
+	return kmsring.Build(len(o.keys), "kmip", func(i int) ([]byte, string, error) {
+		k := o.keys[i]
+		if k.ciphertext != nil {
+			pt, err := client.Decrypt(ctx, k.uid, k.ciphertext)
+			return pt, k.id, err
+		}
+		id := k.id
+		if id == "" {
+			id = k.uid
+		}
+		pt, err := client.Get(ctx, k.uid)
+		return pt, id, err
+	})
+}
@@ -0,0 +1,130 @@
+package kmip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockAppliance holds extractable keys by UID and decrypts by XORing with
+// the key object's material.
+type mockAppliance struct {
+	objects map[string][]byte
+	denyGet map[string]bool
+}
+
+func (m *mockAppliance) Get(_ context.Context, uid string) ([]byte, error) {
+	k, ok := m.objects[uid]
+	if !ok {
+		return nil, fmt.Errorf("kmip: item not found: %s", uid)
+	}
+	if m.denyGet[uid] {
+		return nil, errors.New("kmip: permission denied: object is not extractable")
+	}
+	return append([]byte(nil), k...), nil
+}
+
+func (m *mockAppliance) Decrypt(_ context.Context, uid string, ciphertext []byte) ([]byte, error) {
+	k, ok := m.objects[uid]
+	if !ok {
+		return nil, fmt.Errorf("kmip: item not found: %s", uid)
+	}
+	return xor(ciphertext, k), nil
+}
+
+var _ Client = (*mockAppliance)(nil)
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i%len(b)]
+	}
+	return out
+}
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func TestNew_GetAndDecrypt(t *testing.T) {
+	ctx := context.Background()
+	wrapping := makeKey(100)
+	m := &mockAppliance{
+		objects: map[string][]byte{"uid-get": makeKey(1), "uid-wrap": wrapping},
+		denyGet: map[string]bool{"uid-wrap": true},
+	}
+	p, err := New(ctx, m,
+		WithKey("uid-get", ""),
+		WithEncryptedKey("uid-wrap", xor(makeKey(2), wrapping), "kek-2"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "uid-get" {
+		t.Errorf("CurrentKeyID = %q, want uid-get", p.CurrentKeyID())
+	}
+
+	old, err := crypto.NewProvider(makeKey(2), "kek-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	ct, err := old.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_NilClient(t *testing.T) {
+	if _, err := New(context.Background(), nil, WithKey("uid", "k")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
+
+func TestNew_NoKeys(t *testing.T) {
+	if _, err := New(context.Background(), &mockAppliance{}); err == nil {
+		t.Error("expected error when no keys are configured")
+	}
+}
+
+func TestNew_EmptyUID(t *testing.T) {
+	if _, err := New(context.Background(), &mockAppliance{}, WithKey("", "k")); err == nil {
+		t.Error("expected error for empty unique identifier")
+	}
+}
+
+func TestNew_EncryptedKeyNeedsID(t *testing.T) {
+	_, err := New(context.Background(), &mockAppliance{}, WithEncryptedKey("uid", []byte("ct"), ""))
+	if !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+}
+
+func TestNew_NotExtractable(t *testing.T) {
+	m := &mockAppliance{objects: map[string][]byte{"uid": makeKey(1)}, denyGet: map[string]bool{"uid": true}}
+	_, err := New(context.Background(), m, WithKey("uid", "k"))
+	if err == nil || !strings.Contains(err.Error(), "not extractable") {
+		t.Errorf("expected Get failure, got %v", err)
+	}
+}
+
+func TestNew_WrongKeySize(t *testing.T) {
+	m := &mockAppliance{objects: map[string][]byte{"uid": make([]byte, 16)}}
+	_, err := New(context.Background(), m, WithKey("uid", "k"))
+	if err == nil || !strings.Contains(err.Error(), "is 16 bytes") {
+		t.Errorf("expected size error, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package kubekms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	kmsapi "k8s.io/kms/apis/v2"
+	"k8s.io/kms/pkg/service"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+// dial serves srv on an in-memory listener and returns a client
+// connection to it.
+func dial(t *testing.T, srv kmsapi.KeyManagementServiceServer) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	kmsapi.RegisterKeyManagementServiceServer(gs, srv)
+	go func() { _ = gs.Serve(lis) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestWrapper_ThroughService(t *testing.T) {
+	ctx := context.Background()
+	ring, err := crypto.NewKeyRingProvider(makeKey(1), "kek-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	svc, err := NewService(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dial(t, service.NewGRPCService("", 0, svc))
+
+	p, err := crypto.NewWrappingProvider(NewWrapper(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "kek-1" {
+		t.Errorf("KeyIDOf = %q, want kek-1", id)
+	}
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestService_Status(t *testing.T) {
+	ctx := context.Background()
+	ring, err := crypto.NewKeyRingProvider(makeKey(1), "kek-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, _ := NewService(ring)
+
+	st, err := svc.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Version != "v2" || st.Healthz != "ok" || st.KeyID != "kek-1" {
+		t.Errorf("Status = %+v", st)
+	}
+
+	_ = ring.Close()
+	st, err = svc.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Healthz == "ok" {
+		t.Error("Status after Close: expected unhealthy")
+	}
+}
+
+func TestService_DecryptKeyIDMismatch(t *testing.T) {
+	ctx := context.Background()
+	ring, err := crypto.NewKeyRingProvider(makeKey(1), "kek-1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	svc, _ := NewService(ring)
+	enc, err := svc.Encrypt(ctx, "uid", []byte("seed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Decrypt(ctx, "uid", &service.DecryptRequest{Ciphertext: enc.Ciphertext, KeyID: "other"}); err == nil {
+		t.Error("expected error for mismatched key ID")
+	}
+}
+
+func TestNewService_Nil(t *testing.T) {
+	if _, err := NewService(nil); err == nil {
+		t.Error("expected error for nil provider")
+	}
+}
+
+// annotatingPlugin is a KMSv2 plugin that, like plugins with a local KEK,
+// returns annotations that must be passed back to Decrypt.
+type annotatingPlugin struct {
+	kmsapi.UnimplementedKeyManagementServiceServer
+}
+
+func (annotatingPlugin) Status(context.Context, *kmsapi.StatusRequest) (*kmsapi.StatusResponse, error) {
+	return &kmsapi.StatusResponse{Version: "v2", Healthz: "kms unreachable", KeyId: "remote"}, nil
+}
+
+func (annotatingPlugin) Encrypt(_ context.Context, req *kmsapi.EncryptRequest) (*kmsapi.EncryptResponse, error) {
+	return &kmsapi.EncryptResponse{
+		Ciphertext:  append([]byte("ct:"), req.Plaintext...),
+		KeyId:       "remote",
+		Annotations: map[string][]byte{"local-kek.example.com": []byte("wrapped-local-kek"), "a.example.com": nil},
+	}, nil
+}
+
+func (annotatingPlugin) Decrypt(_ context.Context, req *kmsapi.DecryptRequest) (*kmsapi.DecryptResponse, error) {
+	if string(req.Annotations["local-kek.example.com"]) != "wrapped-local-kek" || req.KeyId != "remote" {
+		return nil, errors.New("missing annotations")
+	}
+	return &kmsapi.DecryptResponse{Plaintext: bytes.TrimPrefix(req.Ciphertext, []byte("ct:"))}, nil
+}
+
+func TestWrapper_Annotations(t *testing.T) {
+	ctx := context.Background()
+	w := NewWrapper(dial(t, annotatingPlugin{}))
+	keyID, wrapped, err := w.WrapKey(ctx, makeKey(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dek, err := w.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dek, makeKey(7)) {
+		t.Error("unwrapped DEK does not match")
+	}
+	if err := w.HealthCheck(ctx); err == nil || !strings.Contains(err.Error(), "kms unreachable") {
+		t.Errorf("HealthCheck = %v, want unhealthy", err)
+	}
+}
+
+func TestUnmarshalWrapped_Malformed(t *testing.T) {
+	good, err := marshalWrapped([]byte("ct"), map[string][]byte{"k": []byte("v")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range [][]byte{nil, {2}, good[:len(good)-1], append(good, 0)} {
+		if _, _, err := unmarshalWrapped(b); !crypto.IsInvalidFormat(err) {
+			t.Errorf("unmarshalWrapped(%x): expected ErrInvalidFormat, got %v", b, err)
+		}
+	}
+}
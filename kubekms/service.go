@@ -0,0 +1,89 @@
+package kubekms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/kms/pkg/service"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// pluginVersion is the KMS plugin API version reported by Status.
+const pluginVersion = "v2"
+
+// statusProbe is encrypted by Status to check the provider and learn its
+// current key ID.
+var statusProbe = []byte("kubekms status")
+
+// Service is a KMSv2 plugin backed by a crypto.Provider. The API server's
+// DEK seeds are encrypted as envelopes whose key ID becomes the KMS key ID,
+// so rotating the provider's current key is seen by the API server as a KEK
+// rotation. Serve it with k8s.io/kms/pkg/service.NewGRPCService.
+type Service struct {
+	p crypto.Provider
+}
+
+// Compile-time interface check.
+var _ service.Service = (*Service)(nil)
+
+// NewService creates a Service that encrypts with p. The Service does not
+// close p.
+func NewService(p crypto.Provider) (*Service, error) {
+	if p == nil {
+		return nil, errors.New("kubekms: NewService provider is nil")
+	}
+	return &Service{p: p}, nil
+}
+
+// Encrypt encrypts data with the provider.
+func (s *Service) Encrypt(ctx context.Context, _ string, data []byte) (*service.EncryptResponse, error) {
+	ciphertext, err := s.p.Encrypt(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("kubekms: encrypt: %w", err)
+	}
+	keyID, err := crypto.KeyIDOf(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("kubekms: encrypt: %w", err)
+	}
+	return &service.EncryptResponse{Ciphertext: ciphertext, KeyID: keyID}, nil
+}
+
+// Decrypt decrypts req.Ciphertext with the provider, after checking that it
+// was encrypted under req.KeyID.
+func (s *Service) Decrypt(ctx context.Context, _ string, req *service.DecryptRequest) ([]byte, error) {
+	keyID, err := crypto.KeyIDOf(req.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("kubekms: decrypt: %w", err)
+	}
+	if keyID != req.KeyID {
+		return nil, fmt.Errorf("kubekms: decrypt: ciphertext is under key %q, not %q", keyID, req.KeyID)
+	}
+	plaintext, err := s.p.Decrypt(ctx, req.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("kubekms: decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+// Status reports the plugin healthy if the provider passes its health check
+// and can encrypt, and reports the current key ID. An unhealthy provider is
+// reported in Healthz rather than as an error, so the API server's healthz
+// endpoint shows the reason.
+func (s *Service) Status(ctx context.Context) (*service.StatusResponse, error) {
+	resp := &service.StatusResponse{Version: pluginVersion, Healthz: healthzOK}
+	if err := s.p.HealthCheck(ctx); err != nil {
+		resp.Healthz = err.Error()
+		return resp, nil
+	}
+	ciphertext, err := s.p.Encrypt(ctx, statusProbe)
+	if err != nil {
+		resp.Healthz = err.Error()
+		return resp, nil
+	}
+	if resp.KeyID, err = crypto.KeyIDOf(ciphertext); err != nil {
+		resp.Healthz = err.Error()
+	}
+	return resp, nil
+}
@@ -0,0 +1,215 @@
+// Package kubekms connects config-crypto to Kubernetes KMSv2 plugins, which
+// wrap and unwrap DEKs over the kube KMS gRPC protocol (k8s.io/kms/apis/v2).
+//
+// Delegate to a cluster's existing plugin, so config entries use the same
+// KEK backend as the API server's encryption at rest:
+//
+//	conn, err := kubekms.Dial("/var/run/kmsplugin/socket.sock")
+//	defer conn.Close()
+//	provider, err := crypto.NewWrappingProvider(kubekms.NewWrapper(conn))
+//
+// Or serve a config-crypto Provider as a KMSv2 plugin for the API server:
+//
+//	svc, err := kubekms.NewService(ring)
+//	plugin := service.NewGRPCService("/var/run/kmsplugin/socket.sock", 3*time.Second, svc)
+//	err = plugin.ListenAndServe()
+//
+// where service is k8s.io/kms/pkg/service.
+package kubekms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	kmsapi "k8s.io/kms/apis/v2"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// healthzOK is the StatusResponse.Healthz value of a healthy plugin.
+const healthzOK = "ok"
+
+// wrappedVersion is the first byte of a wrapped DEK produced by Wrapper.
+//
+// Wrapped DEK layout, which keeps the plugin's annotations with its
+// ciphertext:
+//
+//	version (1) | ciphertext (u16 len + bytes) | count (u16)
+//	per annotation, sorted by name: name (u16 len + bytes) | value (u16 len + bytes)
+const wrappedVersion = 1
+
+// Wrapper is a crypto.KeyWrapper that has a KMSv2 plugin wrap and unwrap
+// DEKs. It is safe for concurrent use.
+type Wrapper struct {
+	rpc kmsapi.KeyManagementServiceClient
+}
+
+// Compile-time interface check.
+var _ crypto.KeyWrapper = (*Wrapper)(nil)
+
+// Dial connects to a KMSv2 plugin listening on the Unix socket at path. The
+// caller closes the returned connection.
+func Dial(path string) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient("unix://"+path, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("kubekms: dial %s: %w", path, err)
+	}
+	return conn, nil
+}
+
+// NewWrapper creates a Wrapper that calls the plugin over conn. The caller
+// owns conn and closes it after the providers built on the Wrapper.
+func NewWrapper(conn *grpc.ClientConn) *Wrapper {
+	return &Wrapper{rpc: kmsapi.NewKeyManagementServiceClient(conn)}
+}
+
+// Name returns "kubekms".
+func (w *Wrapper) Name() string { return "kubekms" }
+
+// HealthCheck calls the plugin's Status method and fails unless it reports
+// healthz "ok".
+func (w *Wrapper) HealthCheck(ctx context.Context) error {
+	resp, err := w.rpc.Status(ctx, &kmsapi.StatusRequest{})
+	if err != nil {
+		return fmt.Errorf("kubekms: Status: %w", err)
+	}
+	if resp.GetHealthz() != healthzOK {
+		return fmt.Errorf("kubekms: plugin is unhealthy: %s", resp.GetHealthz())
+	}
+	return nil
+}
+
+// WrapKey has the plugin encrypt dek and returns the plugin's KEK ID.
+func (w *Wrapper) WrapKey(ctx context.Context, dek []byte) (string, []byte, error) {
+	uid, err := newUID()
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := w.rpc.Encrypt(ctx, &kmsapi.EncryptRequest{Plaintext: dek, Uid: uid})
+	if err != nil {
+		return "", nil, fmt.Errorf("kubekms: Encrypt: %w", err)
+	}
+	if resp.GetKeyId() == "" {
+		return "", nil, errors.New("kubekms: Encrypt: plugin returned an empty key ID")
+	}
+	wrapped, err := marshalWrapped(resp.GetCiphertext(), resp.GetAnnotations())
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.GetKeyId(), wrapped, nil
+}
+
+// UnwrapKey has the plugin decrypt a DEK that WrapKey wrapped under the KEK
+// keyID.
+func (w *Wrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	ciphertext, annotations, err := unmarshalWrapped(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := newUID()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.rpc.Decrypt(ctx, &kmsapi.DecryptRequest{
+		Ciphertext:  ciphertext,
+		Uid:         uid,
+		KeyId:       keyID,
+		Annotations: annotations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kubekms: Decrypt: %w", err)
+	}
+	return resp.GetPlaintext(), nil
+}
+
+// newUID returns a random request UID, which plugins log to correlate
+// calls.
+func newUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("kubekms: generate request UID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// marshalWrapped encodes a plugin's ciphertext and annotations in the
+// wrapped DEK layout.
+func marshalWrapped(ciphertext []byte, annotations map[string][]byte) ([]byte, error) {
+	if len(ciphertext) == 0 {
+		return nil, errors.New("kubekms: Encrypt: plugin returned an empty ciphertext")
+	}
+	if len(ciphertext) > 0xffff || len(annotations) > 0xffff {
+		return nil, errors.New("kubekms: Encrypt: plugin response too large")
+	}
+	b := appendField([]byte{wrappedVersion}, ciphertext)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(annotations))) // #nosec G115 -- checked above
+	for _, name := range slices.Sorted(maps.Keys(annotations)) {
+		value := annotations[name]
+		if len(name) > 0xffff || len(value) > 0xffff {
+			return nil, fmt.Errorf("kubekms: Encrypt: annotation %q too large", name)
+		}
+		b = appendField(b, []byte(name))
+		b = appendField(b, value)
+	}
+	return b, nil
+}
+
+// appendField appends a u16 length-prefixed field. The caller checks that
+// field is at most 65535 bytes.
+func appendField(b, field []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(field))) // #nosec G115 -- checked by the caller
+	return append(b, field...)
+}
+
+// unmarshalWrapped decodes a wrapped DEK. It returns an error wrapping
+// crypto.ErrInvalidFormat if b is malformed.
+func unmarshalWrapped(b []byte) ([]byte, map[string][]byte, error) {
+	if len(b) == 0 || b[0] != wrappedVersion {
+		return nil, nil, fmt.Errorf("%w: unknown kubekms wrapped DEK version", crypto.ErrInvalidFormat)
+	}
+	b = b[1:]
+	ciphertext, b, ok := readField(b)
+	if !ok || len(ciphertext) == 0 || len(b) < 2 {
+		return nil, nil, fmt.Errorf("%w: truncated kubekms wrapped DEK", crypto.ErrInvalidFormat)
+	}
+	count := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	var annotations map[string][]byte
+	if count > 0 {
+		annotations = make(map[string][]byte, count)
+	}
+	for range count {
+		var name, value []byte
+		if name, b, ok = readField(b); !ok {
+			return nil, nil, fmt.Errorf("%w: truncated kubekms annotation", crypto.ErrInvalidFormat)
+		}
+		if value, b, ok = readField(b); !ok {
+			return nil, nil, fmt.Errorf("%w: truncated kubekms annotation", crypto.ErrInvalidFormat)
+		}
+		annotations[string(name)] = value
+	}
+	if len(b) != 0 {
+		return nil, nil, fmt.Errorf("%w: trailing bytes after kubekms wrapped DEK", crypto.ErrInvalidFormat)
+	}
+	return ciphertext, annotations, nil
+}
+
+// readField reads a u16 length-prefixed field.
+func readField(b []byte) (field, rest []byte, ok bool) {
+	if len(b) < 2 {
+		return nil, nil, false
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return nil, nil, false
+	}
+	return b[2 : 2+n], b[2+n:], true
+}
@@ -0,0 +1,182 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Operation names reported in Observation.Operation.
+const (
+	OpEncode  = "encode"  // Codec.Encode and Codec.Transform
+	OpDecode  = "decode"  // Codec.Decode, Codec.DecodeInto, and Codec.Reverse
+	OpEncrypt = "encrypt" // Provider.Encrypt through InstrumentProvider
+	OpDecrypt = "decrypt" // Provider.Decrypt through InstrumentProvider
+)
+
+// Observation describes one completed codec or provider operation passed to
+// Metrics. Like HookInfo it never carries plaintext or key material.
+type Observation struct {
+	// Component is the codec name (e.g. "encrypted:json") or provider name.
+	Component string
+
+	// Operation is one of OpEncode, OpDecode, OpEncrypt, or OpDecrypt.
+	Operation string
+
+	// KeyID is the KEK ID used, read from the ciphertext header. It is empty
+	// when no header is available, e.g. a failed encrypt against a provider
+	// that does not report its current key.
+	KeyID string
+
+	// Size is the size of the operation's input in bytes: the serialized
+	// plaintext for encode and encrypt, the ciphertext for decode and decrypt.
+	Size int
+
+	// Duration is the wall-clock time the operation took.
+	Duration time.Duration
+
+	// Err is the operation's error, or nil on success. Use ErrorKind for a
+	// low-cardinality label.
+	Err error
+}
+
+// Metrics receives an Observation for every instrumented operation. Attach
+// one to a Codec with WithMetrics or to a Provider with InstrumentProvider.
+// The prometheus subpackage provides a ready-made implementation.
+//
+// Observe runs synchronously on the calling goroutine and must be cheap and
+// safe for concurrent use.
+type Metrics interface {
+	Observe(ctx context.Context, obs Observation)
+}
+
+// MetricsFunc adapts a plain function to the Metrics interface.
+type MetricsFunc func(ctx context.Context, obs Observation)
+
+// Observe calls f.
+func (f MetricsFunc) Observe(ctx context.Context, obs Observation) { f(ctx, obs) }
+
+// WithMetrics reports an Observation for every encode and decode on a Codec
+// created by NewCodec. The duration covers encryption or decryption only, not
+// the inner codec or hooks; decodes served from the decode cache are
+// included.
+func WithMetrics(m Metrics) CodecOption {
+	return func(o *codecOptions) {
+		o.metrics = m
+	}
+}
+
+// errorKinds maps sentinel errors to the labels ErrorKind reports, in match
+// order.
+var errorKinds = []struct {
+	err  error
+	kind string
+}{
+	{ErrKeyNotFound, "key_not_found"},
+	{ErrInvalidFormat, "invalid_format"},
+	{ErrUnsupportedFormat, "unsupported_format"},
+	{ErrDecryptionFailed, "decryption_failed"},
+	{ErrProviderClosed, "provider_closed"},
+	{ErrKeyExpired, "key_expired"},
+	{ErrKeyUsageDenied, "key_usage_denied"},
+	{ErrNoProviderForNamespace, "no_provider"},
+	{ErrNoProviderForKeyID, "no_provider"},
+	{ErrSignatureInvalid, "signature_invalid"},
+	{ErrPlaintextLeak, "plaintext_leak"},
+	{ErrThrottled, "throttled"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "deadline_exceeded"},
+}
+
+// ErrorKind classifies err into a short, stable label suitable for a metric
+// dimension: "" for nil, a name such as "key_not_found" or
+// "decryption_failed" for the package's sentinel errors, and "other" for
+// anything else.
+func ErrorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	return "other"
+}
+
+// observe reports one Codec operation to the configured Metrics, if any.
+// header is the ciphertext the key ID is read from; it may be nil.
+func (c *Codec) observe(ctx context.Context, op string, start time.Time, size int, header []byte, err error) {
+	if c.metrics == nil {
+		return
+	}
+	c.metrics.Observe(ctx, Observation{
+		Component: c.name,
+		Operation: op,
+		KeyID:     headerKeyID(header),
+		Size:      size,
+		Duration:  time.Since(start),
+		Err:       err,
+	})
+}
+
+// headerKeyID returns the key ID in ciphertext's header, or "" if it has none.
+func headerKeyID(ciphertext []byte) string {
+	if len(ciphertext) == 0 {
+		return ""
+	}
+	id, _ := KeyIDOf(ciphertext)
+	return id
+}
+
+// instrumentedProvider reports an Observation for each Encrypt and Decrypt.
+type instrumentedProvider struct {
+	Provider
+	m Metrics
+}
+
+// InstrumentProvider returns a Provider that reports every Encrypt and
+// Decrypt on p to m, using p.Name() as the component. The wrapper forwards
+// all Provider methods and DecryptTo; rotation methods of a KeyRingProvider
+// remain reachable through Unwrap.
+func InstrumentProvider(p Provider, m Metrics) Provider {
+	return &instrumentedProvider{Provider: p, m: m}
+}
+
+// Compile-time interface check.
+var _ BufferDecrypter = (*instrumentedProvider)(nil)
+
+// Unwrap returns the underlying Provider.
+func (p *instrumentedProvider) Unwrap() Provider { return p.Provider }
+
+// Encrypt encrypts plaintext with the underlying provider and reports it.
+func (p *instrumentedProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	start := time.Now()
+	ct, err := p.Provider.Encrypt(ctx, plaintext)
+	p.observe(ctx, OpEncrypt, start, len(plaintext), ct, err)
+	return ct, err
+}
+
+// Decrypt decrypts ciphertext with the underlying provider and reports it.
+func (p *instrumentedProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.DecryptTo(ctx, nil, ciphertext)
+}
+
+// DecryptTo appends the plaintext of ciphertext to dst and reports it.
+func (p *instrumentedProvider) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	start := time.Now()
+	out, err := DecryptTo(ctx, dst, ciphertext, p.Provider)
+	p.observe(ctx, OpDecrypt, start, len(ciphertext), ciphertext, err)
+	return out, err
+}
+
+func (p *instrumentedProvider) observe(ctx context.Context, op string, start time.Time, size int, header []byte, err error) {
+	p.m.Observe(ctx, Observation{
+		Component: p.Provider.Name(),
+		Operation: op,
+		KeyID:     headerKeyID(header),
+		Size:      size,
+		Duration:  time.Since(start),
+		Err:       err,
+	})
+}
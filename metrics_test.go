@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+// recordingMetrics collects every Observation it receives.
+type recordingMetrics struct {
+	mu  sync.Mutex
+	obs []Observation
+}
+
+func (r *recordingMetrics) Observe(_ context.Context, obs Observation) {
+	r.mu.Lock()
+	r.obs = append(r.obs, obs)
+	r.mu.Unlock()
+}
+
+func TestCodecWithMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := &recordingMetrics{}
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "key-1"), WithMetrics(m))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+
+	data, err := c.Encode(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var s string
+	if err := c.Decode(ctx, data, &s); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	_ = c.Decode(ctx, []byte("garbage"), &s)
+	if _, err := c.Reverse(ctx, data); err != nil {
+		t.Fatalf("Reverse: %v", err)
+	}
+
+	if len(m.obs) != 4 {
+		t.Fatalf("got %d observations, want 4: %+v", len(m.obs), m.obs)
+	}
+	enc := m.obs[0]
+	if enc.Component != "encrypted:json" || enc.Operation != OpEncode || enc.KeyID != "key-1" ||
+		enc.Size != len(`"hello"`) || enc.Err != nil {
+		t.Errorf("encode observation = %+v", enc)
+	}
+	dec := m.obs[1]
+	if dec.Operation != OpDecode || dec.KeyID != "key-1" || dec.Size != len(data) || dec.Err != nil {
+		t.Errorf("decode observation = %+v", dec)
+	}
+	bad := m.obs[2]
+	if bad.Operation != OpDecode || bad.KeyID != "" || ErrorKind(bad.Err) != "invalid_format" {
+		t.Errorf("failed decode observation = %+v", bad)
+	}
+	if m.obs[3].Operation != OpDecode || m.obs[3].Err != nil {
+		t.Errorf("Reverse observation = %+v", m.obs[3])
+	}
+}
+
+func TestInstrumentProvider(t *testing.T) {
+	ctx := context.Background()
+	m := &recordingMetrics{}
+	inner := mustNewProvider(t, makeKey(32), "key-1")
+	p := InstrumentProvider(inner, m)
+
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+	other := mustNewProvider(t, makeKey(32), "key-2")
+	foreign, _ := other.Encrypt(ctx, []byte("x"))
+	if _, err := p.Decrypt(ctx, foreign); !IsKeyNotFound(err) {
+		t.Fatalf("Decrypt foreign: got %v, want ErrKeyNotFound", err)
+	}
+
+	want := []struct {
+		op, keyID, kind string
+		size            int
+	}{
+		{OpEncrypt, "key-1", "", len("secret")},
+		{OpDecrypt, "key-1", "", len(ct)},
+		{OpDecrypt, "key-2", "key_not_found", len(foreign)},
+	}
+	if len(m.obs) != len(want) {
+		t.Fatalf("got %d observations, want %d", len(m.obs), len(want))
+	}
+	for i, w := range want {
+		o := m.obs[i]
+		if o.Component != inner.Name() || o.Operation != w.op || o.KeyID != w.keyID ||
+			ErrorKind(o.Err) != w.kind || o.Size != w.size {
+			t.Errorf("observation %d = %+v, want %+v", i, o, w)
+		}
+	}
+	if p.(interface{ Unwrap() Provider }).Unwrap() != inner {
+		t.Error("Unwrap did not return the inner provider")
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{ErrDecryptionFailed, "decryption_failed"},
+		{fmt.Errorf("wrapped: %w", ErrKeyNotFound), "key_not_found"},
+		{&ThrottledError{}, "throttled"},
+		{context.Canceled, "canceled"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorKind(tt.err); got != tt.want {
+			t.Errorf("ErrorKind(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
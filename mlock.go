@@ -0,0 +1,113 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/awnumar/memcall"
+	"github.com/awnumar/memguard"
+)
+
+// MlockSupported reports whether this process can lock memory pages into
+// RAM (mlock on Unix, VirtualLock on Windows). It is probed once by locking
+// a single page. When it returns false — an unsupported platform, or a
+// RLIMIT_MEMLOCK of zero in a container — providers fall back to keeping
+// keys in ordinary heap memory, where they may be swapped to disk, unless
+// WithRequireMlock is set.
+func MlockSupported() bool {
+	return mlockSupported()
+}
+
+var mlockSupported = sync.OnceValue(func() bool {
+	b, err := memcall.Alloc(os.Getpagesize())
+	if err != nil {
+		return false
+	}
+	defer func() { _ = memcall.Free(b) }()
+	if err := memcall.Lock(b); err != nil {
+		return false
+	}
+	_ = memcall.Unlock(b)
+	return true
+})
+
+// WithRequireMlock makes NewProvider and NewKeyRingProvider fail with
+// ErrMlockUnavailable when MlockSupported reports false, instead of falling
+// back to unlocked heap storage for key bytes.
+func WithRequireMlock() ProviderOption {
+	return func(o *providerOptions) {
+		o.requireMlock = true
+	}
+}
+
+// sealedKey holds one KEK at rest. open returns the key bytes and a release
+// func that wipes them; wipe destroys the stored key.
+type sealedKey interface {
+	open() ([]byte, func(), error)
+	wipe()
+}
+
+// sealKey copies keyBytes into a memguard Enclave when memory can be locked,
+// and into a plain heap copy otherwise. The caller's slice is NOT modified;
+// callers are responsible for zeroing their own copy of the key material.
+func sealKey(keyBytes []byte, locked bool) sealedKey {
+	if !locked {
+		return &heapKey{b: bytes.Clone(keyBytes)}
+	}
+	lb := memguard.NewBuffer(len(keyBytes))
+	lb.Copy(keyBytes)
+	return &enclaveKey{enc: lb.Seal()}
+}
+
+// enclaveKey stores a key in a memguard Enclave:
+//   - mlock prevents the OS from paging it to disk.
+//   - XOR-at-rest makes the plaintext invisible to heap scans between uses.
+//   - open decrypts into a guard-paged, mlock'd LockedBuffer that release
+//     destroys.
+type enclaveKey struct {
+	enc *memguard.Enclave
+}
+
+func (k *enclaveKey) open() ([]byte, func(), error) {
+	lb, err := k.enc.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return lb.Bytes(), lb.Destroy, nil
+}
+
+// wipe opens the enclave and destroys the resulting LockedBuffer, zeroing
+// the plaintext key material in the mlock'd region. The encrypted blob in
+// the Enclave struct is left in heap but is cryptographically opaque without
+// the memguard session key.
+func (k *enclaveKey) wipe() {
+	if lb, err := k.enc.Open(); err == nil {
+		lb.Destroy()
+	}
+}
+
+// heapKey is the fallback storage used when memory cannot be locked. open
+// hands out a copy so that wipe never races with a key in use.
+type heapKey struct {
+	mu sync.RWMutex
+	b  []byte
+}
+
+func (k *heapKey) open() ([]byte, func(), error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.b == nil {
+		return nil, nil, fmt.Errorf("%w: key wiped", ErrProviderClosed)
+	}
+	b := bytes.Clone(k.b)
+	return b, func() { clear(b) }, nil
+}
+
+func (k *heapKey) wipe() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	clear(k.b)
+	k.b = nil
+}
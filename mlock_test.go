@@ -0,0 +1,72 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+)
+
+// withoutMlock makes MlockSupported report false for the duration of t.
+func withoutMlock(t *testing.T) {
+	t.Helper()
+	orig := mlockSupported
+	mlockSupported = func() bool { return false }
+	t.Cleanup(func() { mlockSupported = orig })
+}
+
+func TestHeapKeyFallback(t *testing.T) {
+	withoutMlock(t)
+	ctx := context.Background()
+
+	p := mustNewKeyRingProvider(t, makeKey(32), "key-1", 1)
+	if p.(*keyRingProvider).locked {
+		t.Fatal("provider reports locked keys without mlock support")
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if err := p.AddKey(makeKey(32), "key-2", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if err := p.SetCurrentKey("key-2"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if string(pt) != "secret" {
+		t.Errorf("Decrypt = %q", pt)
+	}
+
+	s := p.(*keyRingProvider).state.Load()
+	hk := s.keys["key-1"].key.(*heapKey)
+	if err := p.RemoveKey("key-1"); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+	if _, _, err := hk.open(); err == nil {
+		t.Error("removed heap key still opens")
+	}
+}
+
+func TestWithRequireMlockUnavailable(t *testing.T) {
+	withoutMlock(t)
+	_, err := NewProvider(makeKey(32), "key-1", WithRequireMlock())
+	if !IsMlockUnavailable(err) {
+		t.Errorf("got %v, want ErrMlockUnavailable", err)
+	}
+}
+
+func TestWithRequireMlockSupported(t *testing.T) {
+	if !MlockSupported() {
+		t.Skip("mlock not available in this environment")
+	}
+	p, err := NewKeyRingProvider(makeKey(32), "key-1", 0, WithRequireMlock())
+	if err != nil {
+		t.Fatalf("NewKeyRingProvider: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+	if _, ok := p.(*keyRingProvider).state.Load().keys["key-1"].key.(*enclaveKey); !ok {
+		t.Error("key not stored in an enclave")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
 	"sync"
 )
 
@@ -121,6 +122,34 @@ func (s *NamespaceSelector) RemoveAndClose(namespace string) error {
 	return p.Close()
 }
 
+// HealthCheck checks every Provider held by the selector (namespace-scoped
+// and fallback) and returns their failures joined via errors.Join, or nil if
+// all are healthy. It returns ErrProviderClosed once the selector is closed.
+// Providers are checked without holding the selector's lock.
+func (s *NamespaceSelector) HealthCheck(ctx context.Context) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrProviderClosed
+	}
+	providers := maps.Clone(s.providers)
+	fallback := s.fallback
+	s.mu.RUnlock()
+
+	var errs []error
+	for _, ns := range slices.Sorted(maps.Keys(providers)) {
+		if err := providers[ns].HealthCheck(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("namespace %q: %w", ns, err))
+		}
+	}
+	if fallback != nil {
+		if err := fallback.HealthCheck(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("fallback: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Close closes every Provider held by the selector (namespace-scoped and
 // fallback). Errors from individual closes are joined via errors.Join.
 // Safe to call multiple times; subsequent calls are no-ops.
@@ -3,6 +3,7 @@ package crypto
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -291,3 +292,33 @@ func TestScopedProvider_NameAndConnect(t *testing.T) {
 		t.Errorf("Connect after close: got %v, want ErrProviderClosed", err)
 	}
 }
+
+func TestNamespaceSelector_HealthCheck(t *testing.T) {
+	ctx := context.Background()
+	pa := mustNewProvider(t, makeKey(32), "key-a")
+	pb := mustNewProvider(t, makeKey(32), "key-b")
+	fb := mustNewProvider(t, makeKey(32), "key-fb")
+
+	sel, err := NewNamespaceSelector(
+		WithNamespaceProvider("a", pa),
+		WithNamespaceProvider("b", pb),
+		WithFallbackProvider(fb),
+	)
+	if err != nil {
+		t.Fatalf("NewNamespaceSelector: %v", err)
+	}
+	if err := sel.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck = %v, want nil", err)
+	}
+
+	_ = pb.Close()
+	err = sel.HealthCheck(ctx)
+	if !IsProviderClosed(err) || !strings.Contains(err.Error(), `namespace "b"`) {
+		t.Errorf("HealthCheck with closed provider = %v", err)
+	}
+
+	_ = sel.Close()
+	if err := sel.HealthCheck(ctx); !IsProviderClosed(err) {
+		t.Errorf("HealthCheck after Close = %v, want ErrProviderClosed", err)
+	}
+}
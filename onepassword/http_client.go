@@ -0,0 +1,189 @@
+package onepassword
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxResponse bounds the Connect responses read into memory.
+const maxResponse = 4 << 20
+
+// uuidPattern matches 1Password vault and item IDs.
+var uuidPattern = regexp.MustCompile(`^[a-z0-9]{26}$`)
+
+// StatusError is a 1Password Connect error response.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+}
+
+// HTTPClient reads items from a 1Password Connect server's REST API with
+// net/http. It implements Client. Vaults and items may be given by name or
+// ID; names are resolved with a filtered list on every call.
+type HTTPClient struct {
+	host  string
+	token string
+	hc    *http.Client
+}
+
+// Compile-time interface check.
+var _ Client = (*HTTPClient)(nil)
+
+// HTTPOption configures an HTTPClient.
+type HTTPOption func(*HTTPClient)
+
+// WithHTTPClient sets the http.Client used for Connect requests. Defaults
+// to http.DefaultClient.
+func WithHTTPClient(hc *http.Client) HTTPOption {
+	return func(c *HTTPClient) {
+		c.hc = hc
+	}
+}
+
+// NewHTTPClient returns an HTTPClient for the Connect server at host, such
+// as "https://op-connect.internal:8080", authenticating with the Connect
+// access token. Empty values use OP_CONNECT_HOST and OP_CONNECT_TOKEN, the
+// variables the Connect SDKs read.
+func NewHTTPClient(host, token string, opts ...HTTPOption) (*HTTPClient, error) {
+	if host == "" {
+		host = os.Getenv("OP_CONNECT_HOST")
+	}
+	if token == "" {
+		token = os.Getenv("OP_CONNECT_TOKEN")
+	}
+	u, err := url.Parse(host)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("onepassword: invalid Connect host %q", host)
+	}
+	if token == "" {
+		return nil, errors.New("onepassword: Connect token must not be empty")
+	}
+	c := &HTTPClient{host: strings.TrimSuffix(host, "/"), token: token, hc: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// connectItem is the Connect API's FullItem.
+type connectItem struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Version  int    `json:"version"`
+	Sections []struct {
+		ID    string `json:"id"`
+		Label string `json:"label"`
+	} `json:"sections"`
+	Fields []struct {
+		ID      string `json:"id"`
+		Label   string `json:"label"`
+		Value   string `json:"value"`
+		Section *struct {
+			ID string `json:"id"`
+		} `json:"section"`
+	} `json:"fields"`
+}
+
+// GetItem reads the item with ID or title item from the vault with ID or
+// name vault.
+func (c *HTTPClient) GetItem(ctx context.Context, vault, item string) (*Item, error) {
+	vaultID, err := c.resolve(ctx, "/v1/vaults", "vault", "name", vault)
+	if err != nil {
+		return nil, err
+	}
+	base := "/v1/vaults/" + url.PathEscape(vaultID) + "/items"
+	itemID, err := c.resolve(ctx, base, "item", "title", item)
+	if err != nil {
+		return nil, err
+	}
+	var it connectItem
+	if err := c.get(ctx, base+"/"+url.PathEscape(itemID), &it); err != nil {
+		return nil, err
+	}
+	sections := make(map[string]string, len(it.Sections))
+	for _, s := range it.Sections {
+		sections[s.ID] = s.Label
+	}
+	out := &Item{ID: it.ID, Title: it.Title, Version: it.Version}
+	for _, f := range it.Fields {
+		field := Field{ID: f.ID, Label: f.Label, Value: f.Value}
+		if f.Section != nil {
+			field.Section = sections[f.Section.ID]
+		}
+		out.Fields = append(out.Fields, field)
+	}
+	return out, nil
+}
+
+// resolve returns nameOrID if it is an ID, and otherwise looks up the ID of
+// the one kind under collection whose attr equals it.
+func (c *HTTPClient) resolve(ctx context.Context, collection, kind, attr, nameOrID string) (string, error) {
+	if uuidPattern.MatchString(nameOrID) {
+		return nameOrID, nil
+	}
+	filter := fmt.Sprintf("%s eq %q", attr, nameOrID)
+	var list []struct {
+		ID string `json:"id"`
+	}
+	if err := c.get(ctx, collection+"?filter="+url.QueryEscape(filter), &list); err != nil {
+		return "", err
+	}
+	switch len(list) {
+	case 0:
+		return "", fmt.Errorf("onepassword: no %s with %s %q", kind, attr, nameOrID)
+	case 1:
+		return list[0].ID, nil
+	}
+	return "", fmt.Errorf("onepassword: %d %ss with %s %q; use the ID", len(list), kind, attr, nameOrID)
+}
+
+// get sends an authenticated GET and decodes the JSON response into out.
+// The response body, which may hold key material, is cleared after use.
+func (c *HTTPClient) get(ctx context.Context, pathAndQuery string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+pathAndQuery, nil)
+	if err != nil {
+		return fmt.Errorf("onepassword: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("onepassword: GET %s: %w", pathAndQuery, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	defer clear(body)
+	if err != nil {
+		return fmt.Errorf("onepassword: GET %s: %w", pathAndQuery, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		se := &StatusError{StatusCode: resp.StatusCode}
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &e) == nil {
+			se.Message = e.Message
+		}
+		return fmt.Errorf("onepassword: GET %s: %w", pathAndQuery, se)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("onepassword: decode response: %w", err)
+	}
+	return nil
+}
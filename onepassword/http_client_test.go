@@ -0,0 +1,107 @@
+package onepassword
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+const (
+	testVaultID = "abcdefghijklmnopqrstuvwxyz"
+	testItemID  = "zyxwvutsrqponmlkjihgfedcba"
+)
+
+// newFakeConnect serves one vault, "Infra", holding one item, "config-kek".
+func newFakeConnect(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": 401, "message": "Invalid token signature"})
+			return
+		}
+		var v any
+		switch r.URL.Path {
+		case "/v1/vaults":
+			v = []map[string]any{}
+			if r.URL.Query().Get("filter") == `name eq "Infra"` {
+				v = []map[string]any{{"id": testVaultID, "name": "Infra"}}
+			}
+		case "/v1/vaults/" + testVaultID + "/items":
+			v = []map[string]any{}
+			if r.URL.Query().Get("filter") == `title eq "config-kek"` {
+				v = []map[string]any{{"id": testItemID, "title": "config-kek"}}
+			}
+		case "/v1/vaults/" + testVaultID + "/items/" + testItemID:
+			v = map[string]any{
+				"id": testItemID, "title": "config-kek", "version": 2,
+				"sections": []map[string]any{{"id": "s1", "label": "previous"}},
+				"fields": []map[string]any{
+					{"id": "key", "label": "key", "value": encodeKey(5)},
+					{"id": "f1", "label": "old", "value": encodeKey(4), "section": map[string]any{"id": "s1"}},
+				},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{"status": 404, "message": "Not found"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(v)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPClient_New(t *testing.T) {
+	srv := newFakeConnect(t)
+	ctx := context.Background()
+	c, err := NewHTTPClient(srv.URL, "tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{"op://Infra/config-kek/key", "op://" + testVaultID + "/" + testItemID + "/key"} {
+		p, err := New(ctx, c, WithField(ref, ""), WithRotationSection("op://Infra/config-kek", "previous"))
+		if err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		}
+		if want := crypto.KeyFingerprint(makeKey(5)); p.CurrentKeyID() != want {
+			t.Errorf("%s: CurrentKeyID = %q, want %q", ref, p.CurrentKeyID(), want)
+		}
+		if pt, err := p.Decrypt(ctx, encryptWith(t, 4)); err != nil || string(pt) != "secret" {
+			t.Errorf("%s: Decrypt with the retired key = %q, %v", ref, pt, err)
+		}
+		p.Close()
+	}
+
+	if _, err := c.GetItem(ctx, "Infra", "missing"); err == nil {
+		t.Error("expected error for a missing item")
+	}
+}
+
+func TestHTTPClient_Errors(t *testing.T) {
+	srv := newFakeConnect(t)
+	t.Setenv("OP_CONNECT_HOST", "")
+	t.Setenv("OP_CONNECT_TOKEN", "")
+	if _, err := NewHTTPClient("", "tok"); err == nil {
+		t.Error("expected error without a host")
+	}
+	if _, err := NewHTTPClient(srv.URL, ""); err == nil {
+		t.Error("expected error without a token")
+	}
+
+	c, err := NewHTTPClient(srv.URL, "wrong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetItem(context.Background(), "Infra", "config-kek")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusUnauthorized || se.Message != "Invalid token signature" {
+		t.Errorf("err = %v, want a 401 StatusError", err)
+	}
+}
@@ -0,0 +1,297 @@
+// Package onepassword provides a crypto.KeyRingProvider whose keys are
+// fields of 1Password items, read through a 1Password Connect server, for
+// teams that keep their secrets in 1Password and have no cloud KMS.
+//
+// Fields are named with 1Password secret references,
+// "op://vault/item/field" or "op://vault/item/section/field", where each
+// part is a name or an ID. Items are read at construction time through a
+// Client. Use NewHTTPClient, or wire up the Connect SDK with a one-method
+// wrapper:
+//
+//	type myConnect struct{ c connect.Client }
+//
+//	func (m *myConnect) GetItem(ctx context.Context, vault, item string) (*onepassword.Item, error) {
+//	    it, err := m.c.GetItem(item, vault)
+//	    if err != nil { return nil, err }
+//	    sections := map[string]string{}
+//	    for _, s := range it.Sections { sections[s.ID] = s.Label }
+//	    out := &onepassword.Item{ID: it.ID, Title: it.Title, Version: it.Version}
+//	    for _, f := range it.Fields {
+//	        field := onepassword.Field{ID: f.ID, Label: f.Label, Value: f.Value}
+//	        if f.Section != nil { field.Section = sections[f.Section.ID] }
+//	        out.Fields = append(out.Fields, field)
+//	    }
+//	    return out, nil
+//	}
+//
+//	provider, err := onepassword.New(ctx, &myConnect{connect.NewClientFromEnvironment()},
+//	    onepassword.WithField("op://Infra/config-kek/key", ""),
+//	    onepassword.WithRotationSection("op://Infra/config-kek", "previous"),
+//	)
+package onepassword
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// Client reads 1Password items.
+type Client interface {
+	// GetItem returns the item with ID or title item in the vault with ID
+	// or name vault. New calls GetItem concurrently for different items.
+	GetItem(ctx context.Context, vault, item string) (*Item, error)
+}
+
+// Item is a 1Password item.
+type Item struct {
+	ID      string
+	Title   string
+	Version int
+	Fields  []Field
+}
+
+// Field is one field of an Item.
+type Field struct {
+	ID      string
+	Label   string
+	Section string // label of the field's section; empty for top-level fields
+	Value   string
+}
+
+// Option configures the 1Password provider.
+type Option func(*options)
+
+type options struct {
+	keys      []keyRef
+	rawBase64 bool
+}
+
+// keyRef is one WithField or WithRotationSection option.
+type keyRef struct {
+	vault, item string
+	section     string
+	field       string // empty for a whole rotation section
+	id          string
+	ref         string // as given, for errors
+}
+
+// WithField registers the field named by the secret reference ref, such as
+// "op://Infra/config-kek/key" or "op://Infra/config-kek/keys/current". The
+// id identifies this key in the config-crypto system; an empty id uses the
+// key's crypto.KeyFingerprint, which stays the same when the value is later
+// moved to a rotation section.
+//
+// The first key registered is the current key used for new encryptions.
+// Later options register additional keys for decryption during key
+// rotation.
+func WithField(ref, id string) Option {
+	return func(o *options) {
+		k := keyRef{id: id, ref: ref}
+		parts, ok := parseRef(ref)
+		switch {
+		case ok && len(parts) == 3:
+			k.vault, k.item, k.field = parts[0], parts[1], parts[2]
+		case ok && len(parts) == 4:
+			k.vault, k.item, k.section, k.field = parts[0], parts[1], parts[2], parts[3]
+		}
+		o.keys = append(o.keys, k)
+	}
+}
+
+// WithRotationSection registers every non-empty field of the section
+// section in the item named by itemRef, "op://vault/item", as a key for
+// decryption, in field order, with IDs from crypto.KeyFingerprint.
+// 1Password Connect does not expose earlier versions of an item, so retired
+// keys are kept in the item instead: to rotate, move the current value into
+// a new field of this section, then set a new value on the current field.
+// Values encrypted under the old key keep decrypting, and deleting a field
+// from the section retires its key.
+func WithRotationSection(itemRef, section string) Option {
+	return func(o *options) {
+		k := keyRef{section: section, ref: itemRef}
+		if parts, ok := parseRef(itemRef); ok && len(parts) == 2 && section != "" {
+			k.vault, k.item = parts[0], parts[1]
+		}
+		o.keys = append(o.keys, k)
+	}
+}
+
+// WithRawBase64 reads field values as bare standard base64, such as the
+// output of "openssl rand -base64 32", instead of the prefixed forms
+// crypto.ParseKey accepts.
+func WithRawBase64() Option {
+	return func(o *options) { o.rawBase64 = true }
+}
+
+// parseRef splits a secret reference into its path parts.
+func parseRef(ref string) ([]string, bool) {
+	rest, ok := strings.CutPrefix(ref, "op://")
+	if !ok {
+		return nil, false
+	}
+	parts := strings.Split(rest, "/")
+	for _, p := range parts {
+		if p == "" {
+			return nil, false
+		}
+	}
+	return parts, true
+}
+
+// New creates a crypto.KeyRingProvider from 1Password item fields.
+//
+// At least one key must be provided via WithField or WithRotationSection.
+// The first key is the current key for new encryptions; additional keys
+// support decryption during key rotation.
+//
+// Every item is read once, during construction. If any read fails, the
+// errors for all failed keys are returned together. The Client is not
+// retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, errors.New("onepassword: Client must not be nil")
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, k := range o.keys {
+		if k.vault == "" {
+			return nil, fmt.Errorf("onepassword: invalid secret reference %q", k.ref)
+		}
+	}
+
+	items, err := o.fetch(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand rotation sections into one read per field. A value already
+	// read, such as a key copied into the rotation section before the
+	// current field is replaced, is read only once.
+	type read struct {
+		ref   string
+		value string
+		id    string
+		err   error
+	}
+	var reads []read
+	inRing := make(map[string]bool)
+	for _, k := range o.keys {
+		item := items[k.vault+"/"+k.item]
+		if k.field != "" {
+			f, err := item.field(k.section, k.field)
+			reads = append(reads, read{ref: k.ref, value: f.Value, id: k.id, err: err})
+			inRing[f.Value] = true
+			continue
+		}
+		n := 0
+		for _, f := range item.Fields {
+			if f.Section != k.section || f.Value == "" {
+				continue
+			}
+			n++
+			if !inRing[f.Value] {
+				inRing[f.Value] = true
+				reads = append(reads, read{ref: k.ref + "/" + k.section + "/" + f.Label, value: f.Value})
+			}
+		}
+		if n == 0 {
+			reads = append(reads, read{ref: k.ref, err: fmt.Errorf("no fields in section %q", k.section)})
+		}
+	}
+
+	return kmsring.Build(len(reads), "onepassword", func(i int) ([]byte, string, error) {
+		r := reads[i]
+		if r.err != nil {
+			return nil, r.ref, r.err
+		}
+		key, err := o.decode(r.value)
+		if err != nil {
+			return nil, r.ref, err
+		}
+		if r.id == "" {
+			return key, crypto.KeyFingerprint(key), nil
+		}
+		return key, r.id, nil
+	})
+}
+
+// fetch reads every distinct item the options name, concurrently, keyed by
+// "vault/item".
+func (o *options) fetch(ctx context.Context, client Client) (map[string]*Item, error) {
+	var names [][2]string
+	seen := make(map[string]bool)
+	for _, k := range o.keys {
+		if key := k.vault + "/" + k.item; !seen[key] {
+			seen[key] = true
+			names = append(names, [2]string{k.vault, k.item})
+		}
+	}
+
+	var mu sync.Mutex
+	items := make(map[string]*Item, len(names))
+	err := kmsring.ForEach(len(names), func(i int) error {
+		vault, name := names[i][0], names[i][1]
+		item, err := client.GetItem(ctx, vault, name)
+		if err == nil && item == nil {
+			err = errors.New("item not found")
+		}
+		if err != nil {
+			return fmt.Errorf("onepassword: get item %q in vault %q: %w", name, vault, err)
+		}
+		mu.Lock()
+		items[vault+"/"+name] = item
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// field finds a field by section label and field label or ID. An empty
+// section matches top-level fields first and then any field.
+func (it *Item) field(section, name string) (Field, error) {
+	var match []Field
+	for _, f := range it.Fields {
+		if f.Label == name || f.ID == name {
+			match = append(match, f)
+		}
+	}
+	for _, f := range match {
+		if f.Section == section {
+			return f, nil
+		}
+	}
+	if section == "" && len(match) == 1 {
+		return match[0], nil
+	}
+	if section == "" && len(match) > 1 {
+		return Field{}, fmt.Errorf("field %q is ambiguous; name its section", name)
+	}
+	return Field{}, fmt.Errorf("item %q has no field %q", it.Title, strings.TrimPrefix(section+"/"+name, "/"))
+}
+
+// decode turns a field value into key bytes.
+func (o *options) decode(value string) ([]byte, error) {
+	if !o.rawBase64 {
+		return crypto.ParseKey(value)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("decode base64: %w", err)
+	}
+	return key, nil
+}
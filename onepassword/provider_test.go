@@ -0,0 +1,158 @@
+package onepassword
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockConnect is an in-memory Connect server keyed by "vault/item".
+type mockConnect struct {
+	mu    sync.Mutex
+	items map[string]*Item
+	gets  int
+}
+
+func (m *mockConnect) GetItem(ctx context.Context, vault, item string) (*Item, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gets++
+	it, ok := m.items[vault+"/"+item]
+	if !ok {
+		return nil, errors.New("connect: 404 item not found")
+	}
+	return it, nil
+}
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func encodeKey(seed byte) string {
+	return "base64:" + base64.StdEncoding.EncodeToString(makeKey(seed))
+}
+
+func encryptWith(t *testing.T, seed byte) []byte {
+	t.Helper()
+	p, err := crypto.NewProvider(makeKey(seed), crypto.KeyFingerprint(makeKey(seed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(context.Background(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func kekItem() *mockConnect {
+	return &mockConnect{items: map[string]*Item{
+		"Infra/config-kek": {ID: "item1", Title: "config-kek", Version: 4, Fields: []Field{
+			{ID: "key", Label: "key", Value: encodeKey(3)},
+			{ID: "f1", Label: "2026-01", Section: "previous", Value: encodeKey(2)},
+			{ID: "f2", Label: "2025-06", Section: "previous", Value: encodeKey(1)},
+			{ID: "f3", Label: "notes", Section: "other", Value: "not a key"},
+		}},
+	}}
+}
+
+func TestNew_FieldWithRotationSection(t *testing.T) {
+	ctx := context.Background()
+	m := kekItem()
+	p, err := New(ctx, m,
+		WithField("op://Infra/config-kek/key", ""),
+		WithRotationSection("op://Infra/config-kek", "previous"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if m.gets != 1 {
+		t.Errorf("GetItem calls = %d, want 1", m.gets)
+	}
+	if want := crypto.KeyFingerprint(makeKey(3)); p.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want %q", p.CurrentKeyID(), want)
+	}
+	for seed := byte(1); seed <= 3; seed++ {
+		if pt, err := p.Decrypt(ctx, encryptWith(t, seed)); err != nil || string(pt) != "secret" {
+			t.Errorf("key %d: Decrypt = %q, %v", seed, pt, err)
+		}
+	}
+}
+
+func TestNew_RotationInProgress(t *testing.T) {
+	// The current value has been copied into the section but not replaced
+	// yet: it is loaded once.
+	m := kekItem()
+	it := m.items["Infra/config-kek"]
+	it.Fields = append(it.Fields, Field{ID: "f4", Label: "2026-07", Section: "previous", Value: encodeKey(3)})
+	p, err := New(context.Background(), m,
+		WithField("op://Infra/config-kek/key", ""),
+		WithRotationSection("op://Infra/config-kek", "previous"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+}
+
+func TestNew_SectionedFieldAndID(t *testing.T) {
+	m := kekItem()
+	p, err := New(context.Background(), m, WithField("op://Infra/config-kek/previous/2026-01", "k2"), WithRawBase64())
+	if err == nil {
+		p.Close()
+		t.Fatal("expected a decode error for a prefixed value with WithRawBase64")
+	}
+
+	it := m.items["Infra/config-kek"]
+	it.Fields[1].Value = base64.StdEncoding.EncodeToString(makeKey(2))
+	p, err = New(context.Background(), m, WithField("op://Infra/config-kek/previous/2026-01", "k2"), WithRawBase64())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "k2" {
+		t.Errorf("CurrentKeyID = %q, want k2", p.CurrentKeyID())
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"no keys", nil, "at least one"},
+		{"bad reference", []Option{WithField("Infra/config-kek/key", "")}, "invalid secret reference"},
+		{"item reference as field", []Option{WithField("op://Infra/config-kek", "")}, "invalid secret reference"},
+		{"missing item", []Option{WithField("op://Infra/nope/key", "")}, "404"},
+		{"missing field", []Option{WithField("op://Infra/config-kek/nope", "")}, `no field "nope"`},
+		{"empty section", []Option{WithField("op://Infra/config-kek/key", ""), WithRotationSection("op://Infra/config-kek", "gone")}, "no fields"},
+		{"not a key", []Option{WithField("op://Infra/config-kek/other/notes", "")}, "notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(ctx, kekItem(), tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := New(ctx, nil, WithField("op://a/b/c", "")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
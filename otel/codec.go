@@ -0,0 +1,111 @@
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// InstrumentedCodec wraps a crypto.Codec with OpenTelemetry tracing. Each
+// Encode, Decode, Transform, and Reverse runs in a span carrying the codec
+// name, key ID, and encrypted payload size; provider and key-lookup spans
+// started underneath become its children.
+//
+// Register the InstrumentedCodec in place of the Codec it wraps; it reports
+// the same name.
+type InstrumentedCodec struct {
+	codec  *crypto.Codec
+	tracer trace.Tracer
+}
+
+// WrapCodec wraps c with OpenTelemetry tracing. Tracing is disabled by
+// default, matching WrapProvider; pass WithTracesEnabled(true). Metrics
+// options are ignored: attach crypto.WithMetrics to the Codec instead.
+func WrapCodec(c *crypto.Codec, opts ...Option) *InstrumentedCodec {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ic := &InstrumentedCodec{codec: c}
+	if o.enableTraces {
+		ic.tracer = o.resolveTracer()
+	}
+	return ic
+}
+
+// Unwrap returns the underlying Codec.
+func (c *InstrumentedCodec) Unwrap() *crypto.Codec {
+	return c.codec
+}
+
+// Name returns the underlying codec's name.
+func (c *InstrumentedCodec) Name() string {
+	return c.codec.Name()
+}
+
+// Encode serializes and encrypts v in a "crypto.Encode" span.
+func (c *InstrumentedCodec) Encode(ctx context.Context, v any) ([]byte, error) {
+	if c.tracer == nil {
+		return c.codec.Encode(ctx, v)
+	}
+	ctx, span := c.start(ctx, "crypto.Encode")
+	defer span.End()
+
+	data, err := c.codec.Encode(ctx, v)
+	setPayload(span, data)
+	finishSpan(span, err)
+	return data, err
+}
+
+// Decode decrypts and deserializes data in a "crypto.Decode" span.
+func (c *InstrumentedCodec) Decode(ctx context.Context, data []byte, v any) error {
+	if c.tracer == nil {
+		return c.codec.Decode(ctx, data, v)
+	}
+	ctx, span := c.start(ctx, "crypto.Decode")
+	defer span.End()
+	setPayload(span, data)
+
+	err := c.codec.Decode(ctx, data, v)
+	finishSpan(span, err)
+	return err
+}
+
+// Transform encrypts data in a "crypto.Encode" span.
+func (c *InstrumentedCodec) Transform(ctx context.Context, data []byte) ([]byte, error) {
+	if c.tracer == nil {
+		return c.codec.Transform(ctx, data)
+	}
+	ctx, span := c.start(ctx, "crypto.Encode")
+	defer span.End()
+
+	out, err := c.codec.Transform(ctx, data)
+	setPayload(span, out)
+	finishSpan(span, err)
+	return out, err
+}
+
+// Reverse decrypts data in a "crypto.Decode" span.
+func (c *InstrumentedCodec) Reverse(ctx context.Context, data []byte) ([]byte, error) {
+	if c.tracer == nil {
+		return c.codec.Reverse(ctx, data)
+	}
+	ctx, span := c.start(ctx, "crypto.Decode")
+	defer span.End()
+	setPayload(span, data)
+
+	out, err := c.codec.Reverse(ctx, data)
+	finishSpan(span, err)
+	return out, err
+}
+
+// Close closes the underlying Codec.
+func (c *InstrumentedCodec) Close() error {
+	return c.codec.Close()
+}
+
+func (c *InstrumentedCodec) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, name, trace.WithAttributes(AttrCodec.String(c.codec.Name())))
+}
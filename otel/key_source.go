@@ -0,0 +1,66 @@
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// instrumentedKeySource traces key lookups on a crypto.KeySource.
+type instrumentedKeySource struct {
+	src    crypto.KeySource
+	tracer trace.Tracer
+}
+
+// WrapKeySource wraps src so each CurrentKey and KeyByID call runs in a
+// "crypto.KeySource.CurrentKey" or "crypto.KeySource.KeyByID" span with the
+// key ID attribute. Place it beneath a crypto.CachingKeySource to trace only
+// the lookups that reach the backend. Tracing is disabled by default,
+// matching WrapProvider; without WithTracesEnabled(true) src is returned
+// unchanged.
+//
+// The wrapper closes src on Close if src implements io.Closer.
+func WrapKeySource(src crypto.KeySource, opts ...Option) crypto.KeySource {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.enableTraces {
+		return src
+	}
+	return &instrumentedKeySource{src: src, tracer: o.resolveTracer()}
+}
+
+// CurrentKey fetches the current key in a span.
+func (s *instrumentedKeySource) CurrentKey(ctx context.Context) (crypto.Key, error) {
+	ctx, span := s.tracer.Start(ctx, "crypto.KeySource.CurrentKey")
+	defer span.End()
+
+	k, err := s.src.CurrentKey(ctx)
+	if err == nil {
+		span.SetAttributes(AttrKeyID.String(k.ID))
+	}
+	finishSpan(span, err)
+	return k, err
+}
+
+// KeyByID fetches the key with the given ID in a span.
+func (s *instrumentedKeySource) KeyByID(ctx context.Context, id string) (crypto.Key, error) {
+	ctx, span := s.tracer.Start(ctx, "crypto.KeySource.KeyByID",
+		trace.WithAttributes(AttrKeyID.String(id)))
+	defer span.End()
+
+	k, err := s.src.KeyByID(ctx, id)
+	finishSpan(span, err)
+	return k, err
+}
+
+// Close closes the wrapped source if it implements io.Closer.
+func (s *instrumentedKeySource) Close() error {
+	if c, ok := s.src.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rbaliyan/config-crypto/awskms"
+	"github.com/rbaliyan/config-crypto/azurekv"
+	"github.com/rbaliyan/config-crypto/gcpkms"
+	"github.com/rbaliyan/config-crypto/gpg"
+	"github.com/rbaliyan/config-crypto/vault"
+)
+
+// The Wrap*Client functions trace the remote calls a KMS package makes
+// through its Client, so slow key unwraps at startup and during polling
+// show up in traces. Each call runs in a "crypto.kms.<Method>" span with
+// AttrKMSSystem and, where the call names one, AttrKMSKey.
+//
+// Tracing is disabled by default, matching WrapProvider; without
+// WithTracesEnabled(true) the client is returned unchanged. The wrappers
+// implement only the package's Client interface, so wrap the client passed
+// to New, not a ListingClient passed to Poll.
+
+// WrapAWSKMSClient traces calls on an awskms.Client.
+func WrapAWSKMSClient(c awskms.Client, opts ...Option) awskms.Client {
+	t := kmsTracer(opts)
+	if t == nil {
+		return c
+	}
+	return &awsKMSClient{c: c, t: t}
+}
+
+type awsKMSClient struct {
+	c awskms.Client
+	t trace.Tracer
+}
+
+func (w *awsKMSClient) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	ctx, span := startKMSSpan(ctx, w.t, "Decrypt", "aws-kms", AttrKMSKey.String(keyID))
+	defer span.End()
+	pt, err := w.c.Decrypt(ctx, keyID, ciphertext)
+	finishSpan(span, err)
+	return pt, err
+}
+
+// WrapGCPKMSClient traces calls on a gcpkms.Client.
+func WrapGCPKMSClient(c gcpkms.Client, opts ...Option) gcpkms.Client {
+	t := kmsTracer(opts)
+	if t == nil {
+		return c
+	}
+	return &gcpKMSClient{c: c, t: t}
+}
+
+type gcpKMSClient struct {
+	c gcpkms.Client
+	t trace.Tracer
+}
+
+func (w *gcpKMSClient) Decrypt(ctx context.Context, resourceName string, ciphertext []byte) ([]byte, error) {
+	ctx, span := startKMSSpan(ctx, w.t, "Decrypt", "gcp-kms", AttrKMSKey.String(resourceName))
+	defer span.End()
+	pt, err := w.c.Decrypt(ctx, resourceName, ciphertext)
+	finishSpan(span, err)
+	return pt, err
+}
+
+// WrapAzureKVClient traces calls on an azurekv.Client.
+func WrapAzureKVClient(c azurekv.Client, opts ...Option) azurekv.Client {
+	t := kmsTracer(opts)
+	if t == nil {
+		return c
+	}
+	return &azureKVClient{c: c, t: t}
+}
+
+type azureKVClient struct {
+	c azurekv.Client
+	t trace.Tracer
+}
+
+func (w *azureKVClient) UnwrapKey(ctx context.Context, keyName, keyVersion, algorithm string, ciphertext []byte) ([]byte, error) {
+	ctx, span := startKMSSpan(ctx, w.t, "UnwrapKey", "azure-keyvault", AttrKMSKey.String(keyName+"/"+keyVersion))
+	defer span.End()
+	pt, err := w.c.UnwrapKey(ctx, keyName, keyVersion, algorithm, ciphertext)
+	finishSpan(span, err)
+	return pt, err
+}
+
+// WrapVaultClient traces calls on a vault.Client.
+func WrapVaultClient(c vault.Client, opts ...Option) vault.Client {
+	t := kmsTracer(opts)
+	if t == nil {
+		return c
+	}
+	return &vaultClient{c: c, t: t}
+}
+
+type vaultClient struct {
+	c vault.Client
+	t trace.Tracer
+}
+
+func (w *vaultClient) KVMetadata(ctx context.Context, mount, path string) ([]int, int, error) {
+	ctx, span := startKMSSpan(ctx, w.t, "KVMetadata", "vault", AttrKMSKey.String(mount+"/"+path))
+	defer span.End()
+	versions, current, err := w.c.KVMetadata(ctx, mount, path)
+	finishSpan(span, err)
+	return versions, current, err
+}
+
+func (w *vaultClient) KVGet(ctx context.Context, mount, path string, version int) (map[string]string, error) {
+	ctx, span := startKMSSpan(ctx, w.t, "KVGet", "vault",
+		AttrKMSKey.String(mount+"/"+path), attribute.Int("crypto.kms.version", version))
+	defer span.End()
+	data, err := w.c.KVGet(ctx, mount, path, version)
+	finishSpan(span, err)
+	return data, err
+}
+
+// WrapGPGClient traces calls on a gpg.Client.
+func WrapGPGClient(c gpg.Client, opts ...Option) gpg.Client {
+	t := kmsTracer(opts)
+	if t == nil {
+		return c
+	}
+	return &gpgClient{c: c, t: t}
+}
+
+type gpgClient struct {
+	c gpg.Client
+	t trace.Tracer
+}
+
+func (w *gpgClient) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	ctx, span := startKMSSpan(ctx, w.t, "Decrypt", "gpg")
+	defer span.End()
+	pt, err := w.c.Decrypt(ctx, ciphertext)
+	finishSpan(span, err)
+	return pt, err
+}
+
+// kmsTracer returns the tracer for a client wrapper, or nil when tracing is
+// disabled.
+func kmsTracer(opts []Option) trace.Tracer {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.enableTraces {
+		return nil
+	}
+	return o.resolveTracer()
+}
+
+func startKMSSpan(ctx context.Context, t trace.Tracer, method, system string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append([]attribute.KeyValue{AttrKMSSystem.String(system)}, attrs...)
+	return t.Start(ctx, "crypto.kms."+method,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
@@ -1,6 +1,7 @@
 package otel
 
 import (
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -57,3 +58,12 @@ func WithTracer(t trace.Tracer) Option {
 func WithMeter(m metric.Meter) Option {
 	return func(o *options) { o.meter = m }
 }
+
+// resolveTracer returns the configured tracer, or the global provider's
+// tracer named tracerName.
+func (o options) resolveTracer() trace.Tracer {
+	if o.tracer != nil {
+		return o.tracer
+	}
+	return otel.Tracer(o.tracerName)
+}
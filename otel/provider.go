@@ -42,11 +42,7 @@ func WrapProvider(provider crypto.Provider, opts ...Option) (*InstrumentedProvid
 	}
 
 	if o.enableTraces {
-		if o.tracer != nil {
-			ip.tracer = o.tracer
-		} else {
-			ip.tracer = otel.Tracer(o.tracerName)
-		}
+		ip.tracer = o.resolveTracer()
 	}
 
 	if o.enableMetrics {
@@ -120,6 +116,7 @@ func (p *InstrumentedProvider) Encrypt(ctx context.Context, plaintext []byte) ([
 	start := time.Now()
 	ct, err := p.provider.Encrypt(ctx, plaintext)
 	p.recordOperation(ctx, "encrypt", start, err)
+	setPayload(span, ct)
 
 	if err != nil {
 		span.RecordError(err)
@@ -141,6 +138,7 @@ func (p *InstrumentedProvider) Decrypt(ctx context.Context, ciphertext []byte) (
 
 	ctx, span := p.tracer.Start(ctx, "crypto.Decrypt",
 		trace.WithAttributes(p.commonAttributes()...))
+	setPayload(span, ciphertext)
 	defer span.End()
 
 	start := time.Now()
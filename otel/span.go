@@ -0,0 +1,50 @@
+package otel
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// Span attribute keys shared by all instrumented types.
+const (
+	// AttrKeyID is the KEK ID of the operation, read from the ciphertext
+	// header or returned by a key lookup.
+	AttrKeyID = attribute.Key("crypto.key_id")
+
+	// AttrPayloadSize is the size in bytes of the encrypted payload.
+	AttrPayloadSize = attribute.Key("crypto.payload.size")
+
+	// AttrCodec is the name of an instrumented codec.
+	AttrCodec = attribute.Key("crypto.codec")
+
+	// AttrKMSSystem identifies the remote key service of a KMS call, e.g. "aws-kms".
+	AttrKMSSystem = attribute.Key("crypto.kms.system")
+
+	// AttrKMSKey is the remote key name or ARN of a KMS call.
+	AttrKMSKey = attribute.Key("crypto.kms.key")
+)
+
+// setPayload records the size and key ID of an encrypted payload on span.
+// Nothing is recorded for an empty payload, e.g. after a failed encrypt.
+func setPayload(span trace.Span, ciphertext []byte) {
+	if len(ciphertext) == 0 {
+		return
+	}
+	span.SetAttributes(AttrPayloadSize.Int(len(ciphertext)))
+	if id, err := crypto.KeyIDOf(ciphertext); err == nil {
+		span.SetAttributes(AttrKeyID.String(id))
+	}
+}
+
+// finishSpan records err, if any, and the status on span.
+func finishSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+}
@@ -0,0 +1,224 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// recordingTracer records the spans it starts, with their attributes,
+// status, and parent, without an SDK.
+type recordingTracer struct {
+	embedded.Tracer
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	noop.Span
+	name   string
+	parent *recordedSpan
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	ended  bool
+}
+
+type spanKey struct{}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	s := &recordedSpan{name: name, attrs: map[attribute.Key]attribute.Value{}}
+	s.parent, _ = ctx.Value(spanKey{}).(*recordedSpan)
+	s.SetAttributes(cfg.Attributes()...)
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(c codes.Code, _ string) { s.status = c }
+
+func (s *recordedSpan) End(...trace.SpanEndOption) { s.ended = true }
+
+func (t *recordingTracer) find(tb testing.TB, name string) *recordedSpan {
+	tb.Helper()
+	for _, s := range t.spans {
+		if s.name == name {
+			return s
+		}
+	}
+	tb.Fatalf("no span %q among %d spans", name, len(t.spans))
+	return nil
+}
+
+func TestWrapCodecSpans(t *testing.T) {
+	ctx := context.Background()
+	tr := &recordingTracer{}
+	p, err := WrapProvider(mustProvider(t, "key-1"), WithTracesEnabled(true), WithTracer(tr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := crypto.NewCodec(jsoncodec.New(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := WrapCodec(c, WithTracesEnabled(true), WithTracer(tr))
+	if ic.Name() != c.Name() {
+		t.Errorf("Name() = %q, want %q", ic.Name(), c.Name())
+	}
+
+	data, err := ic.Encode(ctx, "hello")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var s string
+	if err := ic.Decode(ctx, data, &s); err != nil || s != "hello" {
+		t.Fatalf("Decode = %q, %v", s, err)
+	}
+
+	enc := tr.find(t, "crypto.Encode")
+	if got := enc.attrs[AttrKeyID].AsString(); got != "key-1" {
+		t.Errorf("Encode key ID = %q, want key-1", got)
+	}
+	if got := enc.attrs[AttrPayloadSize].AsInt64(); got != int64(len(data)) {
+		t.Errorf("Encode payload size = %d, want %d", got, len(data))
+	}
+	if got := enc.attrs[AttrCodec].AsString(); got != "encrypted:json" {
+		t.Errorf("Encode codec = %q", got)
+	}
+	if !enc.ended || enc.status != codes.Ok {
+		t.Errorf("Encode span ended=%v status=%v", enc.ended, enc.status)
+	}
+	if e := tr.find(t, "crypto.Encrypt"); e.parent != enc {
+		t.Error("provider Encrypt span is not a child of the codec Encode span")
+	}
+	if d := tr.find(t, "crypto.Decrypt"); d.parent != tr.find(t, "crypto.Decode") || d.attrs[AttrKeyID].AsString() != "key-1" {
+		t.Errorf("provider Decrypt span parent/key ID wrong: %+v", d.attrs)
+	}
+
+	if err := ic.Decode(ctx, []byte("garbage"), &s); err == nil {
+		t.Fatal("Decode garbage: expected error")
+	}
+	// Spans are recorded at start, so the failed Decode precedes its Decrypt.
+	if s := tr.spans[len(tr.spans)-2]; s.name != "crypto.Decode" || s.status != codes.Error {
+		t.Errorf("failed Decode span = %q status %v", s.name, s.status)
+	}
+}
+
+func TestWrapCodecTracesDisabled(t *testing.T) {
+	tr := &recordingTracer{}
+	c, err := crypto.NewCodec(jsoncodec.New(), mustProvider(t, "k"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := WrapCodec(c, WithTracer(tr))
+	data, err := ic.Transform(context.Background(), []byte(`"x"`))
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	if _, err := ic.Reverse(context.Background(), data); err != nil {
+		t.Fatalf("Reverse: %v", err)
+	}
+	if len(tr.spans) != 0 {
+		t.Errorf("recorded %d spans with tracing disabled", len(tr.spans))
+	}
+	if ic.Unwrap() != c {
+		t.Error("Unwrap did not return the original codec")
+	}
+}
+
+type stubKeySource struct{}
+
+func (stubKeySource) CurrentKey(ctx context.Context) (crypto.Key, error) {
+	return crypto.Key{ID: "k1", Bytes: makeKey(32)}, nil
+}
+
+func (stubKeySource) KeyByID(_ context.Context, id string) (crypto.Key, error) {
+	return crypto.Key{}, crypto.ErrKeyNotFound
+}
+
+func TestWrapKeySource(t *testing.T) {
+	ctx := context.Background()
+	if ks := WrapKeySource(stubKeySource{}); ks != (stubKeySource{}) {
+		t.Error("WrapKeySource without tracing should return the source unchanged")
+	}
+
+	tr := &recordingTracer{}
+	ks := WrapKeySource(stubKeySource{}, WithTracesEnabled(true), WithTracer(tr))
+	if _, err := ks.CurrentKey(ctx); err != nil {
+		t.Fatalf("CurrentKey: %v", err)
+	}
+	if _, err := ks.KeyByID(ctx, "gone"); !crypto.IsKeyNotFound(err) {
+		t.Fatalf("KeyByID: got %v, want ErrKeyNotFound", err)
+	}
+	if s := tr.find(t, "crypto.KeySource.CurrentKey"); s.attrs[AttrKeyID].AsString() != "k1" {
+		t.Errorf("CurrentKey span key ID = %q", s.attrs[AttrKeyID].AsString())
+	}
+	if s := tr.find(t, "crypto.KeySource.KeyByID"); s.attrs[AttrKeyID].AsString() != "gone" || s.status != codes.Error {
+		t.Errorf("KeyByID span = %+v status %v", s.attrs, s.status)
+	}
+}
+
+type awsClientFunc func(ctx context.Context, keyID string, ct []byte) ([]byte, error)
+
+func (f awsClientFunc) Decrypt(ctx context.Context, keyID string, ct []byte) ([]byte, error) {
+	return f(ctx, keyID, ct)
+}
+
+func TestWrapKMSClient(t *testing.T) {
+	ctx := context.Background()
+	boom := errors.New("access denied")
+	var inner awsClientFunc = func(ctx context.Context, keyID string, _ []byte) ([]byte, error) {
+		if keyID == "bad" {
+			return nil, boom
+		}
+		return makeKey(32), nil
+	}
+
+	tr := &recordingTracer{}
+	c := WrapAWSKMSClient(inner, WithTracesEnabled(true), WithTracer(tr))
+	if _, err := c.Decrypt(ctx, "alias/app", []byte("wrapped")); err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if _, err := c.Decrypt(ctx, "bad", nil); !errors.Is(err, boom) {
+		t.Fatalf("Decrypt: got %v, want %v", err, boom)
+	}
+	if len(tr.spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(tr.spans))
+	}
+	ok, failed := tr.spans[0], tr.spans[1]
+	if ok.name != "crypto.kms.Decrypt" || ok.attrs[AttrKMSSystem].AsString() != "aws-kms" ||
+		ok.attrs[AttrKMSKey].AsString() != "alias/app" || ok.status != codes.Ok {
+		t.Errorf("success span = %q %+v %v", ok.name, ok.attrs, ok.status)
+	}
+	if failed.status != codes.Error {
+		t.Errorf("failure span status = %v, want Error", failed.status)
+	}
+}
+
+func mustProvider(t *testing.T, id string) crypto.Provider {
+	t.Helper()
+	p, err := crypto.NewProvider(makeKey(32), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	return p
+}
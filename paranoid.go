@@ -0,0 +1,35 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// minParanoidCheckSize is the shortest plaintext the paranoid self-check
+// looks for. Shorter plaintexts occur by chance in random ciphertext often
+// enough to make the check produce false positives.
+const minParanoidCheckSize = 8
+
+// WithParanoidCheck makes a Codec verify, after every encryption, that the
+// output does not contain the plaintext, failing with ErrPlaintextLeak if it
+// does. It guards against regressions where a code path returns unencrypted
+// data, at the cost of a scan of each ciphertext.
+//
+// Building with the cryptoparanoid tag enables the check for every Codec.
+// Plaintexts shorter than 8 bytes are not checked.
+func WithParanoidCheck() CodecOption {
+	return func(o *codecOptions) {
+		o.paranoid = true
+	}
+}
+
+// checkNoPlaintext returns ErrPlaintextLeak if ciphertext contains plaintext.
+func checkNoPlaintext(plaintext, ciphertext []byte) error {
+	if len(plaintext) < minParanoidCheckSize {
+		return nil
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		return fmt.Errorf("%w: %d-byte plaintext present in %d-byte output", ErrPlaintextLeak, len(plaintext), len(ciphertext))
+	}
+	return nil
+}
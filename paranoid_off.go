@@ -0,0 +1,7 @@
+//go:build !cryptoparanoid
+
+package crypto
+
+// paranoidBuild enables the plaintext-leak self-check for every Codec.
+// Build with -tags cryptoparanoid to turn it on.
+const paranoidBuild = false
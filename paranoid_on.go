@@ -0,0 +1,6 @@
+//go:build cryptoparanoid
+
+package crypto
+
+// paranoidBuild enables the plaintext-leak self-check for every Codec.
+const paranoidBuild = true
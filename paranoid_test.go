@@ -0,0 +1,41 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+// leakyProvider is a broken Provider that returns its input unencrypted.
+type leakyProvider struct{ failingProvider }
+
+func (*leakyProvider) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	return append([]byte("EC\x02"), plaintext...), nil
+}
+
+func TestParanoidCheckDetectsLeak(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCodec(jsoncodec.New(), &leakyProvider{}, WithParanoidCheck())
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	if _, err := c.Encode(ctx, "a secret long enough to check"); !IsPlaintextLeak(err) {
+		t.Errorf("Encode with leaky provider: got %v, want ErrPlaintextLeak", err)
+	}
+	// Short plaintexts are not checked.
+	if _, err := c.Encode(ctx, 1); err != nil {
+		t.Errorf("Encode short value: %v", err)
+	}
+}
+
+func TestParanoidCheckPassesRealEncryption(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "k"), WithParanoidCheck())
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	if _, err := c.Encode(ctx, "a secret long enough to check"); err != nil {
+		t.Errorf("Encode: %v", err)
+	}
+}
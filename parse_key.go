@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseKey decodes a 32-byte AES-256 key from text. Supported forms:
+//
+//	base64:<standard or URL-safe base64, padded or not>
+//	hex:<64 hex digits>
+//	-----BEGIN ...----- PEM block of any type holding the raw 32 bytes
+//
+// Surrounding whitespace is ignored. The decoded key must be exactly 32 bytes,
+// otherwise ParseKey returns an error wrapping ErrInvalidKeySize. Text with
+// no recognised prefix is rejected rather than guessed at, since a bare
+// string could be any of several encodings or a passphrase.
+//
+// The caller owns the returned slice and should zero it once the key has
+// been passed to a provider. ParseKey does not judge key quality; see
+// CheckKey.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case strings.HasPrefix(s, "base64:"):
+		b, err = decodeBase64(strings.TrimSpace(strings.TrimPrefix(s, "base64:")))
+	case strings.HasPrefix(s, "hex:"):
+		b, err = hex.DecodeString(strings.TrimSpace(strings.TrimPrefix(s, "hex:")))
+	case strings.HasPrefix(s, "-----BEGIN "):
+		block, rest := pem.Decode([]byte(s))
+		switch {
+		case block == nil:
+			err = errors.New("malformed PEM block")
+		case len(strings.TrimSpace(string(rest))) != 0:
+			clear(block.Bytes)
+			err = errors.New("trailing data after PEM block")
+		default:
+			b = block.Bytes
+		}
+	default:
+		return nil, errors.New(`crypto: ParseKey: missing "base64:" or "hex:" prefix or PEM block`)
+	}
+	if err != nil {
+		clear(b)
+		return nil, fmt.Errorf("crypto: ParseKey: %w", err)
+	}
+	if len(b) != aesKeySize {
+		clear(b)
+		return nil, fmt.Errorf("%w: got %d bytes", ErrInvalidKeySize, len(b))
+	}
+	return b, nil
+}
+
+// decodeBase64 accepts standard and URL-safe alphabets, with or without
+// padding.
+func decodeBase64(s string) ([]byte, error) {
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if !strings.HasSuffix(s, "=") {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	return enc.DecodeString(s)
+}
@@ -0,0 +1,53 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	key := makeKey(32)
+	key[0], key[1] = 0xFB, 0xFF // force '+'/'/' and '-'/'_' in base64
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "AES KEY", Bytes: key}))
+
+	for name, in := range map[string]string{
+		"base64":        "base64:" + base64.StdEncoding.EncodeToString(key),
+		"base64 raw":    "base64:" + base64.RawStdEncoding.EncodeToString(key),
+		"base64 url":    "base64:" + base64.URLEncoding.EncodeToString(key),
+		"base64 rawurl": "base64:" + base64.RawURLEncoding.EncodeToString(key),
+		"hex":           "hex:" + hex.EncodeToString(key),
+		"hex spaces":    "  hex:" + hex.EncodeToString(key) + "\n",
+		"pem":           pemKey,
+	} {
+		got, err := ParseKey(in)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, key) {
+			t.Errorf("%s: got %x, want %x", name, got, key)
+		}
+	}
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	short := "base64:" + base64.StdEncoding.EncodeToString(make([]byte, 16))
+	if _, err := ParseKey(short); !IsInvalidKeySize(err) {
+		t.Errorf("short key: got %v, want ErrInvalidKeySize", err)
+	}
+	for _, in := range []string{
+		"",
+		hex.EncodeToString(makeKey(32)), // no prefix
+		"hex:zz",
+		"base64:!!!",
+		"-----BEGIN AES KEY-----\nnot base64\n-----END AES KEY-----",
+		string(pem.EncodeToMemory(&pem.Block{Type: "K", Bytes: makeKey(32)})) + "junk",
+	} {
+		if _, err := ParseKey(in); err == nil {
+			t.Errorf("ParseKey(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// maxPassphraseKeys bounds how many derived keys a passphrase provider
+// caches, so decrypting values with many distinct salts cannot grow it
+// without limit. Keys past the bound are derived for each use.
+const maxPassphraseKeys = 16
+
+// maxPassphraseDerivations bounds concurrent derivations per provider, so
+// many values naming different KDF parameters cannot each claim the memory
+// a derivation needs at once.
+const maxPassphraseDerivations = 2
+
+// passphraseProvider encrypts under a key derived from a passphrase and
+// decrypts values written under any KDF parameters, re-deriving from the
+// parameters recorded in the key ID.
+type passphraseProvider struct {
+	ring       *keyRingProvider
+	passphrase sealedKey
+	slots      chan struct{} // bounds concurrent derivations
+
+	mu       sync.Mutex // guards inflight and cache insertion
+	inflight map[string]*derivation
+}
+
+// derivation is one key derivation in progress, shared by every decrypt
+// that needs the same key ID.
+type derivation struct {
+	done chan struct{}
+	key  []byte
+	err  error
+	refs int // callers yet to copy key; guarded by passphraseProvider.mu
+}
+
+// Compile-time interface checks.
+var (
+	_ Provider        = (*passphraseProvider)(nil)
+	_ BufferDecrypter = (*passphraseProvider)(nil)
+)
+
+// NewPassphraseProvider returns a Provider whose KEK is derived from
+// passphrase with params: Argon2id from NewArgon2idParams, or scrypt or
+// PBKDF2 where compliance or legacy systems require them. The key ID is
+// params in PHC string form, so every ciphertext records the KDF, its costs,
+// and the salt that produced its key:
+//
+//	params, _ := crypto.NewArgon2idParams()
+//	p, err := crypto.NewPassphraseProvider(passphrase, params)
+//
+// Decryption parses the key ID of each ciphertext and derives the key it
+// names from the same passphrase, so values written under other parameters
+// (an older salt, a different KDF, raised costs) stay readable. Derived
+// keys are cached once a value decrypts under them; parameters outside sane
+// bounds are rejected with ErrInvalidKDFParams before any work is done.
+// Derivations run outside the provider's lock, so decrypts under cached keys
+// never wait for them.
+//
+// The passphrase is copied into the same protected storage as keys; the
+// caller should zero its own copy. opts are as for NewKeyRingProvider.
+func NewPassphraseProvider(passphrase []byte, params KDFParams, opts ...ProviderOption) (Provider, error) {
+	key, err := DeriveKey(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+
+	ring, err := NewKeyRingProvider(key, params.String(), 0, opts...)
+	if err != nil {
+		return nil, err
+	}
+	kr := ring.(*keyRingProvider)
+	return &passphraseProvider{
+		ring:       kr,
+		passphrase: sealKey(passphrase, kr.locked),
+		slots:      make(chan struct{}, maxPassphraseDerivations),
+		inflight:   make(map[string]*derivation),
+	}, nil
+}
+
+// Name returns the current key ID, the PHC string of the KDF parameters.
+func (p *passphraseProvider) Name() string { return p.ring.Name() }
+
+// Connect is a no-op.
+func (p *passphraseProvider) Connect(_ context.Context) error { return nil }
+
+// Encrypt encrypts plaintext under the key derived with the provider's
+// parameters.
+func (p *passphraseProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return p.ring.Encrypt(ctx, plaintext)
+}
+
+// Decrypt decrypts ciphertext, deriving its key first if needed.
+func (p *passphraseProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.DecryptTo(ctx, nil, ciphertext)
+}
+
+// DecryptTo decrypts ciphertext and appends the plaintext to dst. A key
+// derived for it is cached only if the value decrypts, so forged key IDs
+// cannot evict legitimate keys.
+func (p *passphraseProvider) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	id, err := KeyIDOf(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	s := p.ring.state.Load()
+	if _, ok := s.keys[id]; ok || s.closed {
+		return p.ring.DecryptTo(ctx, dst, ciphertext)
+	}
+	params, err := ParseKDFParams(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrKeyNotFound, id, err)
+	}
+
+	key, err := p.derive(id, params)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	out, err := decryptEnvelopeTo(dst, ciphertext, func(kid string) ([]byte, func(), error) {
+		if kid != id {
+			return nil, nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+		}
+		return key, func() {}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.cache(id, key)
+	return out, nil
+}
+
+// derive returns a caller-owned copy of the key for id, derived from the
+// passphrase with params. Concurrent calls for the same id share one
+// derivation.
+func (p *passphraseProvider) derive(id string, params KDFParams) ([]byte, error) {
+	p.mu.Lock()
+	d, joined := p.inflight[id]
+	if !joined {
+		d = &derivation{done: make(chan struct{})}
+		p.inflight[id] = d
+	}
+	d.refs++
+	p.mu.Unlock()
+
+	if joined {
+		<-d.done
+	} else {
+		d.key, d.err = p.deriveKey(params)
+		p.mu.Lock()
+		delete(p.inflight, id)
+		p.mu.Unlock()
+		close(d.done)
+	}
+
+	var key []byte
+	if d.err == nil {
+		key = bytes.Clone(d.key)
+	}
+	p.mu.Lock()
+	if d.refs--; d.refs == 0 {
+		clear(d.key)
+	}
+	p.mu.Unlock()
+	return key, d.err
+}
+
+// deriveKey runs one derivation once a slot is free.
+func (p *passphraseProvider) deriveKey(params KDFParams) ([]byte, error) {
+	p.slots <- struct{}{}
+	defer func() { <-p.slots }()
+	if p.ring.state.Load().closed {
+		return nil, ErrProviderClosed
+	}
+	pass, release, err := p.passphrase.open()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return DeriveKey(pass, params)
+}
+
+// cache adds a verified key to the ring while it has room. A key another
+// decrypt cached first, or a ring closed meanwhile, is not an error.
+func (p *passphraseProvider) cache(id string, key []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.ring.state.Load().keys) < maxPassphraseKeys {
+		_ = p.ring.AddKey(key, id, 0)
+	}
+}
+
+// HealthCheck reports whether the provider can encrypt.
+func (p *passphraseProvider) HealthCheck(ctx context.Context) error {
+	return p.ring.HealthCheck(ctx)
+}
+
+// Close wipes the passphrase and all derived keys. Safe to call multiple
+// times.
+func (p *passphraseProvider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.passphrase.wipe()
+	return p.ring.Close()
+}
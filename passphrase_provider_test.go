@@ -0,0 +1,167 @@
+package crypto
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestPassphraseProviderRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	pass := []byte("correct horse battery staple")
+	for _, params := range cheapKDFParams() {
+		t.Run(string(params.Algorithm), func(t *testing.T) {
+			p, err := NewPassphraseProvider(pass, params)
+			if err != nil {
+				t.Fatalf("NewPassphraseProvider: %v", err)
+			}
+			defer func() { _ = p.Close() }()
+
+			ct, err := p.Encrypt(ctx, []byte("secret"))
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if id, _ := KeyIDOf(ct); id != params.String() {
+				t.Errorf("key ID = %q, want %q", id, params.String())
+			}
+			pt, err := p.Decrypt(ctx, ct)
+			if err != nil || string(pt) != "secret" {
+				t.Errorf("Decrypt = %q, %v", pt, err)
+			}
+		})
+	}
+}
+
+func TestPassphraseProviderDecryptsOtherKDFs(t *testing.T) {
+	ctx := context.Background()
+	pass := []byte("correct horse battery staple")
+	all := cheapKDFParams()
+
+	// Values written under scrypt and PBKDF2...
+	var cts [][]byte
+	for _, params := range all[1:] {
+		old, err := NewPassphraseProvider(pass, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, err := old.Encrypt(ctx, []byte(params.Algorithm))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = old.Close()
+		cts = append(cts, ct)
+	}
+
+	// ...are readable by a provider configured for Argon2id.
+	p, err := NewPassphraseProvider(pass, all[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+	for i, ct := range cts {
+		pt, err := p.Decrypt(ctx, ct)
+		if err != nil || string(pt) != string(all[i+1].Algorithm) {
+			t.Errorf("Decrypt %s value = %q, %v", all[i+1].Algorithm, pt, err)
+		}
+	}
+
+	// A different passphrase derives a different key.
+	wrong, err := NewPassphraseProvider([]byte("wrong"), all[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = wrong.Close() }()
+	if _, err := wrong.Decrypt(ctx, cts[0]); !IsDecryptionFailed(err) {
+		t.Errorf("wrong passphrase: got %v, want ErrDecryptionFailed", err)
+	}
+	// A key that did not decrypt the value is not cached.
+	id, _ := KeyIDOf(cts[0])
+	if slices.Contains(wrong.(*passphraseProvider).ring.ListKeyIDs(), id) {
+		t.Errorf("key %q cached after a failed decrypt", id)
+	}
+}
+
+func TestPassphraseProviderConcurrentDerive(t *testing.T) {
+	ctx := context.Background()
+	pass := []byte("pass")
+	all := cheapKDFParams()
+	q, err := NewPassphraseProvider(pass, all[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := q.Encrypt(ctx, []byte("v"))
+	_ = q.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPassphraseProvider(pass, all[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "v" {
+				t.Errorf("Decrypt = %q, %v", pt, err)
+			}
+		})
+	}
+	wg.Wait()
+	if n := len(p.(*passphraseProvider).ring.state.Load().keys); n != 2 {
+		t.Errorf("cached keys = %d, want 2", n)
+	}
+	if n := len(p.(*passphraseProvider).inflight); n != 0 {
+		t.Errorf("inflight derivations = %d after decrypts returned", n)
+	}
+}
+
+func TestPassphraseProviderRejectsForeignKeyID(t *testing.T) {
+	ctx := context.Background()
+	other := mustNewProvider(t, makeKey(32), "key-1")
+	ct, err := other.Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPassphraseProvider([]byte("pass"), cheapKDFParams()[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+	if _, err := p.Decrypt(ctx, ct); !IsKeyNotFound(err) {
+		t.Errorf("foreign key ID: got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestPassphraseProviderCacheBound(t *testing.T) {
+	ctx := context.Background()
+	pass := []byte("pass")
+	base := cheapKDFParams()[2]
+	p, err := NewPassphraseProvider(pass, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	for i := range maxPassphraseKeys + 2 {
+		params := base
+		params.Salt = []byte{byte(i), 1, 2, 3, 4, 5, 6, 7}
+		q, err := NewPassphraseProvider(pass, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, err := q.Encrypt(ctx, []byte("v"))
+		_ = q.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Decrypt(ctx, ct); err != nil {
+			t.Fatalf("Decrypt %d: %v", i, err)
+		}
+	}
+	if n := len(p.(*passphraseProvider).ring.state.Load().keys); n != maxPassphraseKeys {
+		t.Errorf("cached keys = %d, want %d", n, maxPassphraseKeys)
+	}
+}
@@ -0,0 +1,147 @@
+package pkcs11
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockToken is a Client holding AES keys by label, standing in for an HSM.
+type mockToken struct {
+	keys   map[string][]byte
+	closed atomic.Bool
+}
+
+var errNoObject = errors.New("CKR_KEY_HANDLE_INVALID")
+
+func (m *mockToken) aead(label string) (cipher.AEAD, error) {
+	k, ok := m.keys[label]
+	if !ok {
+		return nil, errNoObject
+	}
+	b, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithRandomNonce(b)
+}
+
+func (m *mockToken) Wrap(_ context.Context, label string, dek []byte) ([]byte, error) {
+	a, err := m.aead(label)
+	if err != nil {
+		return nil, err
+	}
+	return a.Seal(nil, nil, dek, nil), nil
+}
+
+func (m *mockToken) Unwrap(_ context.Context, label string, wrapped []byte) ([]byte, error) {
+	a, err := m.aead(label)
+	if err != nil {
+		return nil, err
+	}
+	return a.Open(nil, nil, wrapped, nil)
+}
+
+func (m *mockToken) Close() error {
+	m.closed.Store(true)
+	return nil
+}
+
+var _ Client = (*mockToken)(nil)
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func newToken() *mockToken {
+	return &mockToken{keys: map[string][]byte{"label-1": makeKey(1), "label-2": makeKey(2)}}
+}
+
+func TestNew_RoundTripAndRotation(t *testing.T) {
+	ctx := context.Background()
+	token := newToken()
+
+	old, err := New(token, WithKey("label-1", "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := old.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := New(token, WithKey("label-2", "kek-2"), WithKey("label-1", "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "pkcs11" {
+		t.Errorf("Name = %q, want pkcs11", p.Name())
+	}
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt old value = %q, %v", pt, err)
+	}
+	ct2, err := p.Encrypt(ctx, []byte("new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct2); id != "kek-2" {
+		t.Errorf("new value key ID = %q, want kek-2", id)
+	}
+	if _, err := old.Decrypt(ctx, ct2); !crypto.IsKeyNotFound(err) {
+		t.Errorf("old provider decrypting kek-2 value: got %v, want ErrKeyNotFound", err)
+	}
+
+	_ = p.Close()
+	if !token.closed.Load() {
+		t.Error("Close did not close the client")
+	}
+}
+
+func TestNew_TokenError(t *testing.T) {
+	p, err := New(newToken(), WithKey("missing", "kek"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Encrypt(context.Background(), []byte("x")); !errors.Is(err, errNoObject) {
+		t.Errorf("got %v, want the token's error", err)
+	}
+	if err := p.HealthCheck(context.Background()); err == nil {
+		t.Error("HealthCheck with a missing key: want error")
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want error
+	}{
+		{"no keys", nil, nil},
+		{"empty id", []Option{WithKey("label-1", "")}, crypto.ErrInvalidKeyID},
+		{"empty label", []Option{WithKey("", "kek")}, nil},
+		{"duplicate id", []Option{WithKey("label-1", "kek"), WithKey("label-2", "kek")}, crypto.ErrDuplicateKeyID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(newToken(), tt.opts...)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if _, err := New(nil, WithKey("label-1", "kek")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
@@ -0,0 +1,34 @@
+package crypto
+
+import "sync"
+
+// maxPooledBufSize is the largest buffer returned to bufPool. Larger
+// buffers are left to the GC so one big value does not pin memory.
+const maxPooledBufSize = 64 << 10
+
+// bufPool recycles scratch buffers for decrypted plaintext on the Decode
+// hot path.
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// getBuf returns an empty scratch buffer from the pool.
+func getBuf() *[]byte {
+	bp, _ := bufPool.Get().(*[]byte)
+	*bp = (*bp)[:0]
+	return bp
+}
+
+// putBuf returns buf to the pool, recording it in bp so a buffer that grew
+// is recycled instead of the original. Callers holding sensitive data must
+// clear it first.
+func putBuf(bp *[]byte, buf []byte) {
+	if cap(buf) > maxPooledBufSize {
+		return
+	}
+	*bp = buf[:0]
+	bufPool.Put(bp)
+}
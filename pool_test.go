@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPutBufDropsLargeBuffers(t *testing.T) {
+	bp := getBuf()
+	big := make([]byte, 0, maxPooledBufSize+1)
+	putBuf(bp, big)
+	if cap(*bp) == cap(big) {
+		t.Error("oversized buffer should not be recorded for reuse")
+	}
+}
+
+func TestCodecDecodePooledBuffersConcurrent(t *testing.T) {
+	ctx := context.Background()
+	c := testCodec(t)
+
+	values := []string{"a", strings.Repeat("b", 2000), "cc", strings.Repeat("d", 70<<10), "eee"}
+	encoded := make([][]byte, len(values))
+	for i, v := range values {
+		data, err := c.Encode(ctx, v)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		encoded[i] = data
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 20 {
+				for i, data := range encoded {
+					var got string
+					if err := c.Decode(ctx, data, &got); err != nil {
+						t.Errorf("Decode: %v", err)
+						return
+					}
+					if got != values[i] {
+						t.Errorf("Decode item %d: got %d bytes, want %d", i, len(got), len(values[i]))
+						return
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
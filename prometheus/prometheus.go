@@ -0,0 +1,309 @@
+// Package prometheus exports config-crypto operation metrics in the
+// Prometheus text exposition format, without depending on the Prometheus
+// client library.
+//
+// A Collector implements crypto.Metrics. Attach it to codecs and providers,
+// then serve it on a scrape endpoint:
+//
+//	m := prometheus.New()
+//	c, _ := crypto.NewCodec(codec.JSON(), provider, crypto.WithMetrics(m))
+//	kms := crypto.InstrumentProvider(kmsProvider, m)
+//	http.Handle("/metrics/crypto", m)
+//
+// The following series are exported, prefixed with the namespace
+// ("config_crypto" by default):
+//
+//	_operations_total{component,operation,result}       counter; result is "ok" or "error"
+//	_operation_errors_total{component,operation,kind}   counter; kind is crypto.ErrorKind(err)
+//	_operation_duration_seconds{component,operation}    histogram
+//	_payload_bytes{component,operation}                 histogram
+//	_key_usage_total{component,operation,key_id}        counter
+package prometheus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// DefaultDurationBuckets are the operation duration histogram bounds in
+// seconds, from 50µs to 5s.
+var DefaultDurationBuckets = []float64{.00005, .0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// DefaultSizeBuckets are the payload size histogram bounds in bytes, from
+// 64B to 4MiB.
+var DefaultSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+
+// Option configures New.
+type Option func(*options)
+
+type options struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+	keyUsage        bool
+}
+
+// WithNamespace sets the metric name prefix. Default: "config_crypto".
+func WithNamespace(ns string) Option {
+	return func(o *options) { o.namespace = ns }
+}
+
+// WithDurationBuckets sets the duration histogram bounds in seconds.
+// Default: DefaultDurationBuckets.
+func WithDurationBuckets(b []float64) Option {
+	return func(o *options) { o.durationBuckets = b }
+}
+
+// WithSizeBuckets sets the payload size histogram bounds in bytes.
+// Default: DefaultSizeBuckets.
+func WithSizeBuckets(b []float64) Option {
+	return func(o *options) { o.sizeBuckets = b }
+}
+
+// WithoutKeyUsage disables the per-key-ID usage counter, for deployments
+// with many short-lived key IDs where it would be high-cardinality.
+func WithoutKeyUsage() Option {
+	return func(o *options) { o.keyUsage = false }
+}
+
+// Collector accumulates crypto.Observations and renders them in the
+// Prometheus text exposition format. It is safe for concurrent use.
+type Collector struct {
+	opts options
+
+	mu       sync.Mutex
+	ops      map[opLabels]*opStats
+	errors   map[errLabels]uint64
+	keyUsage map[keyLabels]uint64
+}
+
+type opLabels struct{ component, operation string }
+
+type errLabels struct{ component, operation, kind string }
+
+type keyLabels struct{ component, operation, keyID string }
+
+type opStats struct {
+	ok, failed uint64
+	duration   histogram
+	size       histogram
+}
+
+// histogram holds per-bucket (non-cumulative) counts plus the sum; the
+// final slot counts observations above every bound.
+type histogram struct {
+	counts []uint64
+	sum    float64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds)+1)
+	}
+	i, _ := slices.BinarySearch(bounds, v)
+	h.counts[i]++
+	h.sum += v
+}
+
+// Compile-time interface checks.
+var (
+	_ crypto.Metrics = (*Collector)(nil)
+	_ http.Handler   = (*Collector)(nil)
+)
+
+// New returns an empty Collector.
+func New(opts ...Option) *Collector {
+	o := options{
+		namespace:       "config_crypto",
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+		keyUsage:        true,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.durationBuckets = sortedCopy(o.durationBuckets)
+	o.sizeBuckets = sortedCopy(o.sizeBuckets)
+	return &Collector{
+		opts:     o,
+		ops:      make(map[opLabels]*opStats),
+		errors:   make(map[errLabels]uint64),
+		keyUsage: make(map[keyLabels]uint64),
+	}
+}
+
+// Observe records one operation.
+func (c *Collector) Observe(_ context.Context, obs crypto.Observation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ol := opLabels{obs.Component, obs.Operation}
+	st := c.ops[ol]
+	if st == nil {
+		st = &opStats{}
+		c.ops[ol] = st
+	}
+	if obs.Err != nil {
+		st.failed++
+		c.errors[errLabels{obs.Component, obs.Operation, crypto.ErrorKind(obs.Err)}]++
+	} else {
+		st.ok++
+	}
+	st.duration.observe(c.opts.durationBuckets, obs.Duration.Seconds())
+	st.size.observe(c.opts.sizeBuckets, float64(obs.Size))
+	if c.opts.keyUsage && obs.KeyID != "" {
+		c.keyUsage[keyLabels{obs.Component, obs.Operation, obs.KeyID}]++
+	}
+}
+
+// ServeHTTP writes the current metrics in the text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = c.WriteTo(w)
+}
+
+// WriteTo writes the current metrics to w in the text exposition format,
+// with series in a stable order.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	c.mu.Lock()
+	c.write(cw)
+	c.mu.Unlock()
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func (c *Collector) write(w *countingWriter) {
+	ns := c.opts.namespace
+	opKeys := sortedKeys(c.ops, func(a, b opLabels) int {
+		return strings.Compare(a.component+"\x00"+a.operation, b.component+"\x00"+b.operation)
+	})
+
+	name := ns + "_operations_total"
+	w.header(name, "counter", "Total number of crypto operations by result.")
+	for _, k := range opKeys {
+		st := c.ops[k]
+		for _, r := range []struct {
+			result string
+			n      uint64
+		}{{"ok", st.ok}, {"error", st.failed}} {
+			w.sample(name, labels("component", k.component, "operation", k.operation, "result", r.result), float64(r.n))
+		}
+	}
+
+	name = ns + "_operation_errors_total"
+	w.header(name, "counter", "Total number of failed crypto operations by error kind.")
+	for _, k := range sortedKeys(c.errors, func(a, b errLabels) int {
+		return strings.Compare(a.component+"\x00"+a.operation+"\x00"+a.kind, b.component+"\x00"+b.operation+"\x00"+b.kind)
+	}) {
+		w.sample(name, labels("component", k.component, "operation", k.operation, "kind", k.kind), float64(c.errors[k]))
+	}
+
+	name = ns + "_operation_duration_seconds"
+	w.header(name, "histogram", "Duration of crypto operations in seconds.")
+	for _, k := range opKeys {
+		w.histogram(name, labels("component", k.component, "operation", k.operation), c.opts.durationBuckets, &c.ops[k].duration)
+	}
+
+	name = ns + "_payload_bytes"
+	w.header(name, "histogram", "Size of crypto operation inputs in bytes.")
+	for _, k := range opKeys {
+		w.histogram(name, labels("component", k.component, "operation", k.operation), c.opts.sizeBuckets, &c.ops[k].size)
+	}
+
+	if c.opts.keyUsage {
+		name = ns + "_key_usage_total"
+		w.header(name, "counter", "Total number of crypto operations by key ID.")
+		for _, k := range sortedKeys(c.keyUsage, func(a, b keyLabels) int {
+			return strings.Compare(a.component+"\x00"+a.operation+"\x00"+a.keyID, b.component+"\x00"+b.operation+"\x00"+b.keyID)
+		}) {
+			w.sample(name, labels("component", k.component, "operation", k.operation, "key_id", k.keyID), float64(c.keyUsage[k]))
+		}
+	}
+}
+
+// countingWriter writes exposition lines, remembering the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *countingWriter) header(name, typ, help string) {
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (w *countingWriter) sample(name, labels string, v float64) {
+	w.printf("%s{%s} %s\n", name, labels, formatFloat(v))
+}
+
+func (w *countingWriter) histogram(name, lbls string, bounds []float64, h *histogram) {
+	var cum uint64
+	for i, b := range bounds {
+		if h.counts != nil {
+			cum += h.counts[i]
+		}
+		w.sample(name+"_bucket", lbls+`,le="`+formatFloat(b)+`"`, float64(cum))
+	}
+	if h.counts != nil {
+		cum += h.counts[len(bounds)]
+	}
+	w.sample(name+"_bucket", lbls+`,le="+Inf"`, float64(cum))
+	w.sample(name+"_sum", lbls, h.sum)
+	w.sample(name+"_count", lbls, float64(cum))
+}
+
+// labels renders name/value pairs as a label set body, escaping values.
+func labels(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(kv[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedCopy(b []float64) []float64 {
+	out := slices.Clone(b)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+func sortedKeys[K comparable, V any](m map[K]V, cmp func(a, b K) int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, cmp)
+	return keys
+}
@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+func TestCollectorExposition(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithDurationBuckets([]float64{0.01, 0.001}), WithSizeBuckets([]float64{100}))
+
+	c.Observe(ctx, crypto.Observation{Component: "encrypted:json", Operation: crypto.OpEncode, KeyID: "k1", Size: 10, Duration: time.Millisecond})
+	c.Observe(ctx, crypto.Observation{Component: "encrypted:json", Operation: crypto.OpEncode, KeyID: "k1", Size: 200, Duration: 20 * time.Millisecond})
+	c.Observe(ctx, crypto.Observation{Component: "encrypted:json", Operation: crypto.OpDecode, Size: 5, Err: crypto.ErrInvalidFormat})
+
+	var b strings.Builder
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE config_crypto_operations_total counter\n",
+		`config_crypto_operations_total{component="encrypted:json",operation="encode",result="ok"} 2` + "\n",
+		`config_crypto_operations_total{component="encrypted:json",operation="decode",result="error"} 1` + "\n",
+		`config_crypto_operation_errors_total{component="encrypted:json",operation="decode",kind="invalid_format"} 1` + "\n",
+		"# TYPE config_crypto_operation_duration_seconds histogram\n",
+		`config_crypto_operation_duration_seconds_bucket{component="encrypted:json",operation="encode",le="0.001"} 1` + "\n",
+		`config_crypto_operation_duration_seconds_bucket{component="encrypted:json",operation="encode",le="0.01"} 1` + "\n",
+		`config_crypto_operation_duration_seconds_bucket{component="encrypted:json",operation="encode",le="+Inf"} 2` + "\n",
+		`config_crypto_operation_duration_seconds_sum{component="encrypted:json",operation="encode"} 0.021` + "\n",
+		`config_crypto_payload_bytes_bucket{component="encrypted:json",operation="encode",le="100"} 1` + "\n",
+		`config_crypto_payload_bytes_count{component="encrypted:json",operation="encode"} 2` + "\n",
+		`config_crypto_key_usage_total{component="encrypted:json",operation="encode",key_id="k1"} 2` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("exposition missing %q\n%s", want, out)
+		}
+	}
+
+	var again strings.Builder
+	_, _ = c.WriteTo(&again)
+	if again.String() != out {
+		t.Error("exposition order is not stable")
+	}
+}
+
+func TestCollectorOptions(t *testing.T) {
+	c := New(WithNamespace("app"), WithoutKeyUsage())
+	c.Observe(context.Background(), crypto.Observation{Component: `we"ird`, Operation: crypto.OpDecrypt, KeyID: "k1", Err: errors.New("boom")})
+
+	var b strings.Builder
+	_, _ = c.WriteTo(&b)
+	out := b.String()
+	if !strings.Contains(out, `app_operation_errors_total{component="we\"ird",operation="decrypt",kind="other"} 1`) {
+		t.Errorf("missing escaped error series:\n%s", out)
+	}
+	if strings.Contains(out, "key_usage") {
+		t.Errorf("WithoutKeyUsage still exported key usage:\n%s", out)
+	}
+}
+
+func TestCollectorServeHTTP(t *testing.T) {
+	c := New()
+	p, err := crypto.NewProvider(make([]byte, 32), "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = p.Close() })
+	ip := crypto.InstrumentProvider(p, c)
+	if _, err := ip.Encrypt(context.Background(), []byte("x")); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `operation="encrypt",result="ok"} 1`) {
+		t.Errorf("body missing encrypt count:\n%s", rec.Body.String())
+	}
+}
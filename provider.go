@@ -1,6 +1,10 @@
 package crypto
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // Provider encrypts and decrypts data using envelope encryption.
 // Implementations must be safe for concurrent use.
@@ -30,13 +34,47 @@ type Provider interface {
 
 	// Close zeros all key material and releases resources.
 	// After Close, Encrypt, Decrypt, and HealthCheck return ErrProviderClosed.
+	// Close must be safe to call more than once; the built-in and KMS-backed
+	// providers make later calls no-ops.
 	Close() error
 }
 
+// ProviderOption configures NewProvider and NewKeyRingProvider.
+type ProviderOption func(*providerOptions)
+
+type providerOptions struct {
+	rand         io.Reader
+	algorithm    byte
+	requireMlock bool
+	dekReuseUses int
+	dekReuseAge  time.Duration
+
+	fingerprintIDs bool
+	keyCheck       keyCheck
+}
+
+// WithRandReader sets the randomness source used to generate DEKs and
+// nonces. The default is crypto/rand.Reader. A deterministic reader makes
+// ciphertexts reproducible, which is useful for golden files and fuzzing;
+// never use one in production, as repeating a nonce under the same DEK
+// breaks AES-GCM. A nil reader is ignored.
+//
+// Encrypt may be called concurrently, so r must be safe for concurrent use
+// unless the caller serialises encryption.
+func WithRandReader(r io.Reader) ProviderOption {
+	return func(o *providerOptions) {
+		if r != nil {
+			o.rand = r
+		}
+	}
+}
+
 // NewProvider builds a static Provider from raw 32-byte AES-256 key bytes.
 // Key bytes are copied internally; the caller may safely zero the original
 // after construction. The returned Provider does not expose key rotation
-// methods; use NewKeyRingProvider when runtime rotation is required.
-func NewProvider(keyBytes []byte, id string) (Provider, error) {
-	return NewKeyRingProvider(keyBytes, id, 0)
+// methods; use NewKeyRingProvider when keys must be added, removed, or
+// promoted at runtime. A Codec built on a KeyRingProvider sees such changes
+// immediately, so it does not need to be rebuilt or re-registered.
+func NewProvider(keyBytes []byte, id string, opts ...ProviderOption) (Provider, error) {
+	return NewKeyRingProvider(keyBytes, id, 0, opts...)
 }
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ProviderError reports a failed provider operation with enough structure
+// to act on it: which provider, which operation, which key, and whether
+// retrying may help. A Codec wraps every provider failure in one; match it
+// with errors.As, and the cause with errors.Is (IsKeyNotFound,
+// IsDecryptionFailed, ...).
+type ProviderError struct {
+	// Provider is the provider's Name.
+	Provider string
+
+	// Op is the failed operation, OpEncrypt or OpDecrypt.
+	Op string
+
+	// KeyID is the key involved: the key named in the ciphertext header for
+	// decryption, or the current key for encryption. Empty if unknown.
+	KeyID string
+
+	// Retryable reports whether the failure looks transient (a timeout, a
+	// throttled lookup, or a cause reporting Retryable() true) rather than a
+	// permanent problem such as an unknown key or tampered data.
+	Retryable bool
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error.
+func (e *ProviderError) Error() string {
+	msg := fmt.Sprintf("crypto: provider %q %s", e.Provider, e.Op)
+	if e.KeyID != "" {
+		msg += fmt.Sprintf(" key %q", e.KeyID)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// IsRetryable reports whether err is a *ProviderError marked Retryable, or
+// would be classified as retryable if it were wrapped in one.
+func IsRetryable(err error) bool {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.Retryable
+	}
+	return retryable(err)
+}
+
+// retryable classifies err as transient. Errors from KMS clients can opt in
+// or out by implementing Retryable() bool.
+func retryable(err error) bool {
+	var r interface{ Retryable() bool }
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
+	if errors.Is(err, context.DeadlineExceeded) || IsThrottled(err) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// newProviderError wraps err in a *ProviderError for p, unless it already
+// is one. A nil err stays nil.
+func newProviderError(p Provider, op, keyID string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &ProviderError{Provider: p.Name(), Op: op, KeyID: keyID, Retryable: retryable(err), Err: err}
+}
+
+// encryptKeyID returns the key p encrypts with, if p exposes it.
+func encryptKeyID(p Provider) string {
+	if kr, ok := p.(KeyRingProvider); ok {
+		return kr.CurrentKeyID()
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+type retryableErr struct{ retry bool }
+
+func (e retryableErr) Error() string   { return "kms: unavailable" }
+func (e retryableErr) Retryable() bool { return e.retry }
+
+func TestCodecWrapsProviderErrors(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	c, err := NewCodec(jsoncodec.New(), p)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	data, err := c.Encode(ctx, "v")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	other := makeKey(32)
+	other[0] = 0xFF
+	wrong, err := NewCodec(jsoncodec.New(), mustNewKeyRingProvider(t, other, "k2", 0))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	var s string
+	err = wrong.Decode(ctx, data, &s)
+	var pe *ProviderError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Decode error %v is not a *ProviderError", err)
+	}
+	if pe.Op != OpDecrypt || pe.KeyID != "k1" || pe.Provider != "k2" || pe.Retryable {
+		t.Errorf("ProviderError = %+v", pe)
+	}
+	if !IsKeyNotFound(err) || IsRetryable(err) {
+		t.Errorf("cause lost or misclassified: %v", err)
+	}
+
+	_ = p.Close()
+	_, err = c.Encode(ctx, "v")
+	if !errors.As(err, &pe) || pe.Op != OpEncrypt || !IsProviderClosed(err) {
+		t.Errorf("Encode on closed provider = %v", err)
+	}
+}
+
+func TestKeySourceProviderErrorsAreRetryable(t *testing.T) {
+	ctx := context.Background()
+	src := newFakeKeySource("k1")
+	p, err := NewKeySourceProvider(src)
+	if err != nil {
+		t.Fatalf("NewKeySourceProvider: %v", err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	src.setFail(fmt.Errorf("vault: %w", context.DeadlineExceeded))
+	_, err = p.Decrypt(ctx, ct)
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.Provider != "keysource" || pe.KeyID != "k1" || !pe.Retryable {
+		t.Errorf("Decrypt during outage = %v (%+v)", err, pe)
+	}
+	if _, err := p.Encrypt(ctx, []byte("x")); !IsRetryable(err) {
+		t.Errorf("Encrypt during outage = %v, want retryable", err)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{&ThrottledError{}, true},
+		{ErrKeyNotFound, false},
+		{retryableErr{retry: true}, true},
+		{fmt.Errorf("wrapped: %w", retryableErr{retry: false}), false},
+		{&ProviderError{Retryable: true, Err: ErrKeyNotFound}, true},
+	}
+	for _, c := range cases {
+		if got := IsRetryable(c.err); got != c.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
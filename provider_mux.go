@@ -0,0 +1,276 @@
+package crypto
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// ProviderMux is a Provider that routes decryption to one of several
+// underlying providers by the key ID in the ciphertext header, matching the
+// longest registered prefix. It lets one Codec read ciphertexts produced by
+// teams with different key backends, e.g. "aws:" → an awskms provider and
+// "static:" → a static provider.
+//
+// Prefixes are matched against the full key ID, which is also what the
+// underlying provider sees: a provider routed for "aws:" must hold its keys
+// under IDs like "aws:prod-1". The ID is authenticated as AAD, so it cannot
+// be stripped or rewritten on the way through.
+//
+// Encrypt uses the provider set with WithMuxEncryptProvider. The routing
+// table is fixed at construction; ProviderMux is safe for concurrent use.
+type ProviderMux struct {
+	routes  []muxRoute // sorted by descending prefix length
+	encrypt Provider
+	closed  atomic.Bool
+}
+
+type muxRoute struct {
+	prefix   string
+	provider Provider
+}
+
+// MuxOption configures a ProviderMux.
+type MuxOption func(*muxOptions)
+
+type muxOptions struct {
+	routes  []muxRoute
+	encrypt Provider
+}
+
+// WithMuxRoute routes ciphertexts whose key ID starts with prefix to p.
+// An empty prefix matches every key ID and acts as a catch-all. Nil
+// providers are ignored.
+func WithMuxRoute(prefix string, p Provider) MuxOption {
+	return func(o *muxOptions) {
+		if p != nil {
+			o.routes = append(o.routes, muxRoute{prefix: prefix, provider: p})
+		}
+	}
+}
+
+// WithMuxEncryptProvider sets the provider used for Encrypt. It is usually
+// also registered with WithMuxRoute under the prefix of its key IDs so the
+// mux can decrypt what it writes. Without it, Encrypt returns
+// ErrNoProviderForKeyID.
+func WithMuxEncryptProvider(p Provider) MuxOption {
+	return func(o *muxOptions) {
+		o.encrypt = p
+	}
+}
+
+// Compile-time interface checks.
+var (
+	_ Provider         = (*ProviderMux)(nil)
+	_ BufferDecrypter  = (*ProviderMux)(nil)
+	_ KeyLister        = (*ProviderMux)(nil)
+	_ KeyStatsProvider = (*ProviderMux)(nil)
+)
+
+// NewProviderMux creates a ProviderMux with the given routes. It returns an
+// error if the same prefix is registered twice.
+func NewProviderMux(opts ...MuxOption) (*ProviderMux, error) {
+	var o muxOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	seen := make(map[string]bool, len(o.routes))
+	for _, r := range o.routes {
+		if seen[r.prefix] {
+			return nil, fmt.Errorf("crypto: duplicate mux prefix %q", r.prefix)
+		}
+		seen[r.prefix] = true
+	}
+	routes := slices.Clone(o.routes)
+	slices.SortStableFunc(routes, func(a, b muxRoute) int {
+		return cmp.Compare(len(b.prefix), len(a.prefix))
+	})
+
+	return &ProviderMux{routes: routes, encrypt: o.encrypt}, nil
+}
+
+// Name returns "mux".
+func (m *ProviderMux) Name() string { return "mux" }
+
+// Connect connects every routed provider and the encrypt provider, joining
+// their errors.
+func (m *ProviderMux) Connect(ctx context.Context) error {
+	return m.each(func(label string, p Provider) error {
+		if err := p.Connect(ctx); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		return nil
+	})
+}
+
+// Encrypt encrypts plaintext with the encrypt provider.
+func (m *ProviderMux) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	if m.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	if m.encrypt == nil {
+		return nil, fmt.Errorf("%w: no encrypt provider configured", ErrNoProviderForKeyID)
+	}
+	return m.encrypt.Encrypt(ctx, plaintext)
+}
+
+// Decrypt decrypts ciphertext with the provider routed for its key ID.
+func (m *ProviderMux) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	p, err := m.route(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return p.Decrypt(ctx, ciphertext)
+}
+
+// DecryptTo decrypts ciphertext with the provider routed for its key ID and
+// appends the plaintext to dst.
+func (m *ProviderMux) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	p, err := m.route(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptTo(ctx, dst, ciphertext, p)
+}
+
+// HealthCheck checks every routed provider and the encrypt provider,
+// joining their failures.
+func (m *ProviderMux) HealthCheck(ctx context.Context) error {
+	if m.closed.Load() {
+		return ErrProviderClosed
+	}
+	return m.each(func(label string, p Provider) error {
+		if err := p.HealthCheck(ctx); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		return nil
+	})
+}
+
+// ListKeyIDs returns the sorted key IDs of every routed provider that
+// implements KeyLister and whose IDs match its route.
+func (m *ProviderMux) ListKeyIDs() []string {
+	if m.closed.Load() {
+		return nil
+	}
+	var ids []string
+	for i, r := range m.routes {
+		kl, ok := r.provider.(KeyLister)
+		if !ok {
+			continue
+		}
+		for _, id := range kl.ListKeyIDs() {
+			if m.routeIndex(id) == i {
+				ids = append(ids, id)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// KeyStats returns the usage statistics of every routed provider that
+// implements KeyStatsProvider, for the key IDs that match its route.
+func (m *ProviderMux) KeyStats() []KeyStats {
+	if m.closed.Load() {
+		return nil
+	}
+	var all []KeyStats
+	for i, r := range m.routes {
+		sp, ok := r.provider.(KeyStatsProvider)
+		if !ok {
+			continue
+		}
+		for _, st := range sp.KeyStats() {
+			if m.routeIndex(st.ID) == i {
+				all = append(all, st)
+			}
+		}
+	}
+	return mergeKeyStats(all)
+}
+
+// Close closes every routed provider and the encrypt provider once each,
+// joining their errors. Safe to call multiple times; subsequent calls are
+// no-ops.
+func (m *ProviderMux) Close() error {
+	if m.closed.Swap(true) {
+		return nil
+	}
+	return m.each(func(label string, p Provider) error {
+		if err := p.Close(); err != nil {
+			return fmt.Errorf("close %s: %w", label, err)
+		}
+		return nil
+	})
+}
+
+// route returns the provider for the key ID in ciphertext's header.
+func (m *ProviderMux) route(ciphertext []byte) (Provider, error) {
+	if m.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	id, err := KeyIDOf(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	p := m.lookup(id)
+	if p == nil {
+		return nil, fmt.Errorf("%w: %q", ErrNoProviderForKeyID, id)
+	}
+	return p, nil
+}
+
+// lookup returns the provider with the longest prefix of keyID, or nil.
+func (m *ProviderMux) lookup(keyID string) Provider {
+	if i := m.routeIndex(keyID); i >= 0 {
+		return m.routes[i].provider
+	}
+	return nil
+}
+
+// routeIndex returns the index of the route with the longest prefix of
+// keyID, or -1.
+func (m *ProviderMux) routeIndex(keyID string) int {
+	for i, r := range m.routes {
+		if strings.HasPrefix(keyID, r.prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// each calls fn once per distinct provider (routes first, then the encrypt
+// provider) and joins the errors.
+func (m *ProviderMux) each(fn func(label string, p Provider) error) error {
+	var errs []error
+	seen := make([]Provider, 0, len(m.routes))
+	isSeen := func(p Provider) bool {
+		return slices.ContainsFunc(seen, func(q Provider) bool { return sameProvider(p, q) })
+	}
+	for _, r := range m.routes {
+		if isSeen(r.provider) {
+			continue
+		}
+		seen = append(seen, r.provider)
+		errs = append(errs, fn(fmt.Sprintf("route %q", r.prefix), r.provider))
+	}
+	if m.encrypt != nil && !isSeen(m.encrypt) {
+		errs = append(errs, fn("encrypt provider", m.encrypt))
+	}
+	return errors.Join(errs...)
+}
+
+// sameProvider reports whether a and b are the same provider. Providers whose
+// dynamic values are not comparable, such as structs holding a slice, are
+// never the same, so the comparison cannot panic.
+func sameProvider(a, b Provider) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Type() == vb.Type() && va.Comparable() && a == b
+}
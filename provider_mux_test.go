@@ -0,0 +1,155 @@
+package crypto
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestProviderMux_RoutesByPrefix(t *testing.T) {
+	ctx := context.Background()
+	aws := mustNewKeyRingProvider(t, makeKey(32), "aws:prod-1", 0)
+	static := mustNewProvider(t, makeKey(32), "static:k1")
+	awsEU := mustNewProvider(t, makeKey(32), "aws:eu:k1")
+
+	mux, err := NewProviderMux(
+		WithMuxRoute("aws:", aws),
+		WithMuxRoute("aws:eu:", awsEU),
+		WithMuxRoute("static:", static),
+		WithMuxEncryptProvider(aws),
+	)
+	if err != nil {
+		t.Fatalf("NewProviderMux: %v", err)
+	}
+
+	for _, p := range []Provider{aws, static, awsEU} {
+		ct, err := p.Encrypt(ctx, []byte("from "+p.Name()))
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		pt, err := mux.Decrypt(ctx, ct)
+		if err != nil {
+			t.Fatalf("mux.Decrypt(%s): %v", p.Name(), err)
+		}
+		if string(pt) != "from "+p.Name() {
+			t.Errorf("mux.Decrypt(%s) = %q", p.Name(), pt)
+		}
+		pt, err = DecryptTo(ctx, nil, ct, mux)
+		if err != nil || string(pt) != "from "+p.Name() {
+			t.Errorf("DecryptTo(%s) = %q, %v", p.Name(), pt, err)
+		}
+	}
+
+	ct, err := mux.Encrypt(ctx, []byte("round trip"))
+	if err != nil {
+		t.Fatalf("mux.Encrypt: %v", err)
+	}
+	if pt, err := mux.Decrypt(ctx, ct); err != nil || string(pt) != "round trip" {
+		t.Errorf("mux round trip = %q, %v", pt, err)
+	}
+
+	if got, want := mux.ListKeyIDs(), []string{"aws:eu:k1", "aws:prod-1", "static:k1"}; !slices.Equal(got, want) {
+		t.Errorf("ListKeyIDs = %v, want %v", got, want)
+	}
+}
+
+func TestProviderMux_NoRoute(t *testing.T) {
+	ctx := context.Background()
+	other := mustNewProvider(t, makeKey(32), "gcp:k1")
+	ct, err := other.Encrypt(ctx, []byte("x"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	mux, err := NewProviderMux(WithMuxRoute("aws:", mustNewProvider(t, makeKey(32), "aws:k1")))
+	if err != nil {
+		t.Fatalf("NewProviderMux: %v", err)
+	}
+	if _, err := mux.Decrypt(ctx, ct); !IsNoProviderForKeyID(err) {
+		t.Errorf("Decrypt unrouted = %v, want ErrNoProviderForKeyID", err)
+	}
+	if _, err := mux.Encrypt(ctx, []byte("x")); !IsNoProviderForKeyID(err) {
+		t.Errorf("Encrypt without encrypt provider = %v, want ErrNoProviderForKeyID", err)
+	}
+	if _, err := mux.Decrypt(ctx, []byte("garbage")); !IsInvalidFormat(err) {
+		t.Errorf("Decrypt garbage = %v, want ErrInvalidFormat", err)
+	}
+}
+
+func TestProviderMux_DuplicatePrefix(t *testing.T) {
+	p := mustNewProvider(t, makeKey(32), "k")
+	if _, err := NewProviderMux(WithMuxRoute("a:", p), WithMuxRoute("a:", p)); err == nil {
+		t.Error("duplicate prefix accepted")
+	}
+}
+
+func TestProviderMux_Close(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewProvider(makeKey(32), "a:k")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	b, err := NewProvider(makeKey(32), "b:k")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	mux, err := NewProviderMux(WithMuxRoute("a:", a), WithMuxRoute("b:", b), WithMuxEncryptProvider(a))
+	if err != nil {
+		t.Fatalf("NewProviderMux: %v", err)
+	}
+	if err := mux.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	if err := mux.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := mux.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	for _, p := range []Provider{a, b} {
+		if err := p.HealthCheck(ctx); !IsProviderClosed(err) {
+			t.Errorf("%s not closed: %v", p.Name(), err)
+		}
+	}
+	if _, err := mux.Encrypt(ctx, []byte("x")); !IsProviderClosed(err) {
+		t.Errorf("Encrypt after Close = %v, want ErrProviderClosed", err)
+	}
+}
+
+// taggedProvider is a Provider whose dynamic value is not comparable.
+type taggedProvider struct {
+	Provider
+	tags []string
+}
+
+func TestProviderMux_NonComparableProvider(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewProvider(makeKey(32), "a:k")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	b, err := NewProvider(makeKey(32), "b:k")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	ta := taggedProvider{Provider: a, tags: []string{"team-a"}}
+	tb := taggedProvider{Provider: b, tags: []string{"team-b"}}
+	mux, err := NewProviderMux(WithMuxRoute("a:", ta), WithMuxRoute("b:", tb), WithMuxEncryptProvider(ta))
+	if err != nil {
+		t.Fatalf("NewProviderMux: %v", err)
+	}
+	if err := mux.HealthCheck(ctx); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	_ = mux.ListKeyIDs()
+	// The encrypt provider cannot be matched to its route, so a is closed
+	// twice; providers tolerate that.
+	if err := mux.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for _, p := range []Provider{a, b} {
+		if err := p.HealthCheck(ctx); !IsProviderClosed(err) {
+			t.Errorf("%s not closed: %v", p.Name(), err)
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewProvider_RoundTrip(t *testing.T) {
@@ -102,6 +104,33 @@ func TestNewProvider_HealthCheckAfterClose(t *testing.T) {
 	}
 }
 
+func TestKeyRingProvider_HealthCheckCurrentKey(t *testing.T) {
+	ctx := context.Background()
+	p := mustNewKeyRingProvider(t, makeKey(32), "key-1", 1)
+	kip := p.(KeyInfoProvider)
+
+	if err := kip.SetKeyMetadata("key-1", KeyMetadata{NotAfter: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	if err := p.HealthCheck(ctx); !IsKeyExpired(err) {
+		t.Errorf("HealthCheck with expired key = %v, want ErrKeyExpired", err)
+	}
+
+	if err := kip.SetKeyMetadata("key-1", KeyMetadata{Usage: KeyUsageDecryptOnly}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	if err := p.HealthCheck(ctx); !IsKeyUsageDenied(err) {
+		t.Errorf("HealthCheck with decrypt-only key = %v, want ErrKeyUsageDenied", err)
+	}
+
+	if err := kip.SetKeyMetadata("key-1", KeyMetadata{}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Errorf("HealthCheck = %v, want nil", err)
+	}
+}
+
 func TestNewProvider_Close(t *testing.T) {
 	p, err := NewProvider(makeKey(32), "key-1")
 	if err != nil {
@@ -466,3 +495,85 @@ func TestKeyRingProvider_NeedsReencryption_Concurrent(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestNewProvider_WithRandReader(t *testing.T) {
+	ctx := context.Background()
+	encrypt := func(seed byte) []byte {
+		t.Helper()
+		p, err := NewProvider(makeKey(32), "key-1", WithRandReader(bytes.NewReader(bytes.Repeat([]byte{seed}, 1024))))
+		if err != nil {
+			t.Fatalf("NewProvider: %v", err)
+		}
+		defer func() { _ = p.Close() }()
+		ct, err := p.Encrypt(ctx, []byte("reproducible"))
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		pt, err := p.Decrypt(ctx, ct)
+		if err != nil || string(pt) != "reproducible" {
+			t.Fatalf("Decrypt: got %q, %v", pt, err)
+		}
+		return ct
+	}
+
+	if a, b := encrypt(1), encrypt(1); !bytes.Equal(a, b) {
+		t.Error("same randomness source should give identical ciphertexts")
+	}
+	if a, b := encrypt(1), encrypt(2); bytes.Equal(a, b) {
+		t.Error("different randomness sources should give different ciphertexts")
+	}
+}
+
+func TestNewProvider_RandReaderExhausted(t *testing.T) {
+	p, err := NewProvider(makeKey(32), "key-1", WithRandReader(bytes.NewReader(nil)))
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer func() { _ = p.Close() }()
+	if _, err := p.Encrypt(context.Background(), []byte("x")); err == nil {
+		t.Error("expected error when the randomness source fails")
+	}
+}
+
+func TestKeyRingProvider_ConcurrentRotation(t *testing.T) {
+	rp := mustNewKeyRingProvider(t, makeKey(32), "v0", 0)
+	ctx := context.Background()
+	for i := 1; i <= 4; i++ {
+		k := makeKey(32)
+		k[0] = byte(i)
+		if err := rp.AddKey(k, fmt.Sprintf("v%d", i), uint64(i)); err != nil {
+			t.Fatalf("AddKey: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Go(func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			_ = rp.SetCurrentKey(fmt.Sprintf("v%d", i%5))
+		}
+	})
+	for range 8 {
+		wg.Go(func() {
+			for range 100 {
+				ct, err := rp.Encrypt(ctx, []byte("payload"))
+				if err != nil {
+					t.Errorf("Encrypt: %v", err)
+					return
+				}
+				if pt, err := rp.Decrypt(ctx, ct); err != nil || string(pt) != "payload" {
+					t.Errorf("Decrypt: %q, %v", pt, err)
+					return
+				}
+			}
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,35 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"log/slog"
+)
+
+// NewRandomProvider returns a key ring whose only key is freshly generated
+// by GenerateKey. It is meant for local development and integration tests
+// and is NOT SAFE FOR PRODUCTION: the key exists only in this process, so
+// everything encrypted with it becomes unreadable once the process exits.
+// Construction logs a warning via slog to make accidental production use
+// visible.
+//
+// keyString is the key in ParseKey's "base64:" form. Print or save it to
+// decrypt the same data in a later run, or discard it to keep the key truly
+// ephemeral. An empty id is derived with KeyFingerprint.
+func NewRandomProvider(id string, opts ...ProviderOption) (p KeyRingProvider, keyString string, err error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	defer clear(key)
+
+	if id == "" {
+		opts = append(opts, WithFingerprintKeyID())
+	}
+	p, err = NewKeyRingProvider(key, id, 0, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	slog.Default().Warn("config-crypto: using an ephemeral random key; not for production",
+		"key_id", p.CurrentKeyID())
+	return p, "base64:" + base64.StdEncoding.EncodeToString(key), nil
+}
@@ -0,0 +1,45 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewRandomProvider(t *testing.T) {
+	ctx := context.Background()
+	p, keyString, err := NewRandomProvider("dev")
+	if err != nil {
+		t.Fatalf("NewRandomProvider: %v", err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "dev" {
+		t.Errorf("CurrentKeyID = %q, want dev", p.CurrentKeyID())
+	}
+	ct, err := p.Encrypt(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// The printed key reproduces the provider in a later run.
+	key, err := ParseKey(keyString)
+	if err != nil {
+		t.Fatalf("ParseKey: %v", err)
+	}
+	again := mustNewProvider(t, key, "dev")
+	if got, err := again.Decrypt(ctx, ct); err != nil || string(got) != "hello" {
+		t.Errorf("Decrypt with reloaded key = %q, %v", got, err)
+	}
+
+	other, otherString, err := NewRandomProvider("")
+	if err != nil {
+		t.Fatalf("NewRandomProvider: %v", err)
+	}
+	defer other.Close()
+	if otherString == keyString {
+		t.Error("two random providers share a key")
+	}
+	k2, _ := ParseKey(otherString)
+	if other.CurrentKeyID() != KeyFingerprint(k2) {
+		t.Errorf("empty id not derived from fingerprint: %q", other.CurrentKeyID())
+	}
+}
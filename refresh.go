@@ -0,0 +1,266 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RefreshEntry is one key in the set returned by a RefreshFunc.
+type RefreshEntry struct {
+	// ID is the key ID used in ciphertext headers.
+	ID string
+
+	// Rank orders keys for NeedsReencryption; higher is newer.
+	Rank uint64
+
+	// Current marks the key to encrypt with. If no entry is marked, the
+	// first entry is current.
+	Current bool
+
+	// Unwrap returns the 32-byte key, typically by calling a KMS. It is only
+	// called for IDs the ring does not hold yet, so a refresh with no new
+	// keys makes no unwrap calls, unless WithRefreshRecheck is set. The
+	// Refresher zeroes the returned slice.
+	Unwrap func(ctx context.Context) ([]byte, error)
+}
+
+// RefreshFunc lists the keys a ring should hold, e.g. by reading wrapped
+// keys from a config store. It should be cheap: unwrapping happens lazily
+// through RefreshEntry.Unwrap.
+type RefreshFunc func(ctx context.Context) ([]RefreshEntry, error)
+
+// RefreshOption configures NewRefresher.
+type RefreshOption func(*refreshOptions)
+
+type refreshOptions struct {
+	interval time.Duration
+	onError  func(error)
+	recheck  bool
+}
+
+// WithRefreshInterval makes the Refresher call Refresh every d in a
+// background goroutine until Stop is called or the ring is closed. Without
+// it, refreshes happen only when Refresh is called.
+func WithRefreshInterval(d time.Duration) RefreshOption {
+	return func(o *refreshOptions) { o.interval = d }
+}
+
+// WithRefreshErrorHandler sets a callback for background refresh errors.
+// The callback runs on the refresh goroutine and must not block. If unset,
+// errors are logged via slog.
+func WithRefreshErrorHandler(fn func(error)) RefreshOption {
+	return func(o *refreshOptions) { o.onError = fn }
+}
+
+// WithRefreshRecheck makes each Refresh also unwrap the listed keys the ring
+// already holds, and report those that fail or whose unwrapped bytes differ
+// from the held key. A KMS key that was disabled or whose access was
+// revoked, or a wrapped key replaced under an existing ID, then surfaces on
+// the next refresh rather than on the next restart. Held keys stay in the
+// ring either way. Bytes are compared through MACProvider without exporting
+// the held key; rings that do not implement it are only checked for unwrap
+// failures. It costs one unwrap call per listed key per refresh.
+func WithRefreshRecheck() RefreshOption {
+	return func(o *refreshOptions) { o.recheck = true }
+}
+
+// Refresher keeps a KeyRingProvider in sync with a RefreshFunc: each
+// Refresh adds keys the ring does not hold yet and promotes the current
+// one. Keys missing from the listing are left in the ring so old values stay
+// readable; remove them explicitly with RemoveKey.
+//
+// It is the on-demand counterpart to Poll: the KMS is only called for new
+// keys, and Refresh can be triggered from an admin endpoint or a rotation
+// webhook as well as on an interval.
+type Refresher struct {
+	ring  KeyRingProvider
+	fetch RefreshFunc
+	opts  refreshOptions
+
+	mu sync.Mutex // serialises Refresh
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRefresher returns a Refresher for ring. It does not refresh
+// immediately; build ring from the initial key set first. With
+// WithRefreshInterval it starts the background goroutine, which exits on
+// Stop or, for rings implementing Watcher, as soon as the ring is closed.
+func NewRefresher(ring KeyRingProvider, fetch RefreshFunc, opts ...RefreshOption) (*Refresher, error) {
+	if ring == nil {
+		return nil, errors.New("crypto: NewRefresher ring is nil")
+	}
+	if fetch == nil {
+		return nil, errors.New("crypto: NewRefresher fetch is nil")
+	}
+	r := &Refresher{ring: ring, fetch: fetch}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if r.opts.interval < 0 {
+		return nil, errors.New("crypto: NewRefresher interval must not be negative")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	if r.opts.interval > 0 {
+		var closed <-chan KeyEvent
+		if w, ok := ring.(Watcher); ok {
+			closed = w.Watch(ctx)
+		}
+		r.wg.Go(func() { r.run(ctx, closed) })
+	}
+	return r, nil
+}
+
+// Refresh lists the key set once, unwraps and adds keys the ring does not
+// hold, rechecks held keys if WithRefreshRecheck is set, and switches the
+// current key if it changed. Failures for individual
+// keys are joined into the returned error; the other keys are still added.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := r.fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("crypto: refresh: %w", err)
+	}
+	if len(entries) == 0 {
+		return errors.New("crypto: refresh: key set is empty")
+	}
+
+	held := make(map[string]struct{})
+	if kl, ok := r.ring.(KeyLister); ok {
+		for _, id := range kl.ListKeyIDs() {
+			held[id] = struct{}{}
+		}
+	}
+
+	var errs []error
+	current := entries[0].ID
+	for _, e := range entries {
+		if e.Current {
+			current = e.ID
+		}
+	}
+	for _, e := range entries {
+		if _, ok := held[e.ID]; ok {
+			if r.opts.recheck {
+				if err := r.recheck(ctx, e); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+		if err := r.add(ctx, e); err != nil {
+			if IsProviderClosed(err) {
+				return err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		held[e.ID] = struct{}{}
+	}
+
+	if _, ok := held[current]; ok && r.ring.CurrentKeyID() != current {
+		if err := r.ring.SetCurrentKey(current); err != nil {
+			errs = append(errs, fmt.Errorf("crypto: refresh: promote %q: %w", current, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// add unwraps e and adds it to the ring. A duplicate ID counts as success,
+// for rings that do not implement KeyLister.
+func (r *Refresher) add(ctx context.Context, e RefreshEntry) error {
+	if e.Unwrap == nil {
+		return fmt.Errorf("crypto: refresh: key %q has no Unwrap func", e.ID)
+	}
+	b, err := e.Unwrap(ctx)
+	defer clear(b)
+	if err != nil {
+		return fmt.Errorf("crypto: refresh: unwrap %q: %w", e.ID, err)
+	}
+	if err := r.ring.AddKey(b, e.ID, e.Rank); err != nil && !IsDuplicateKeyID(err) {
+		if IsProviderClosed(err) {
+			return err
+		}
+		return fmt.Errorf("crypto: refresh: add %q: %w", e.ID, err)
+	}
+	return nil
+}
+
+// recheckProbe is the message MAC'd to compare a re-unwrapped key with the
+// held one.
+var recheckProbe = []byte("config-crypto/refresh-recheck")
+
+// recheck unwraps e, which the ring already holds, and checks that the
+// result is the held key.
+func (r *Refresher) recheck(ctx context.Context, e RefreshEntry) error {
+	if e.Unwrap == nil {
+		return fmt.Errorf("crypto: refresh: key %q has no Unwrap func", e.ID)
+	}
+	b, err := e.Unwrap(ctx)
+	defer clear(b)
+	if err != nil {
+		return fmt.Errorf("crypto: refresh: recheck %q: %w", e.ID, err)
+	}
+	mp, ok := r.ring.(MACProvider)
+	if !ok {
+		return nil
+	}
+	tag, err := macWithKey(b, e.ID, recheckProbe)
+	if err != nil {
+		return fmt.Errorf("crypto: refresh: recheck %q: %w", e.ID, err)
+	}
+	switch err := mp.VerifyMAC(ctx, e.ID, recheckProbe, tag); {
+	case IsSignatureInvalid(err):
+		return fmt.Errorf("crypto: refresh: recheck %q: unwrapped key differs from the key in the ring", e.ID)
+	case err != nil:
+		return fmt.Errorf("crypto: refresh: recheck %q: %w", e.ID, err)
+	}
+	return nil
+}
+
+// Stop stops the background goroutine, if any, and waits for it to exit.
+// It does not close the ring. Safe to call multiple times.
+func (r *Refresher) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Refresher) run(ctx context.Context, closed <-chan KeyEvent) {
+	ticker := time.NewTicker(r.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-closed:
+			if !ok {
+				return
+			}
+			continue
+		case <-ticker.C:
+		}
+		if err := r.Refresh(ctx); err != nil {
+			if IsProviderClosed(err) || ctx.Err() != nil {
+				return
+			}
+			r.report(err)
+		}
+	}
+}
+
+func (r *Refresher) report(err error) {
+	if r.opts.onError != nil {
+		r.opts.onError(err)
+		return
+	}
+	slog.Default().Error("config-crypto: refresh failed", "error", err)
+}
@@ -0,0 +1,218 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// refreshSet is a mutable key listing for Refresher tests.
+type refreshSet struct {
+	mu      sync.Mutex
+	ids     []string
+	current string
+	unwraps atomic.Int64
+	failID  string
+	salt    byte // changes the bytes every key unwraps to
+}
+
+// key returns the bytes id unwraps to.
+func (s *refreshSet) key(id string) []byte {
+	k := makeKey(32)
+	k[0] = byte(len(id)) + id[len(id)-1] + s.salt
+	return k
+}
+
+func (s *refreshSet) set(current string, ids ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current, s.ids = current, ids
+}
+
+func (s *refreshSet) fetch(context.Context) ([]RefreshEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]RefreshEntry, len(s.ids))
+	for i, id := range s.ids {
+		out[i] = RefreshEntry{
+			ID:      id,
+			Current: id == s.current,
+			Unwrap: func(context.Context) ([]byte, error) {
+				s.unwraps.Add(1)
+				if id == s.failID {
+					return nil, errors.New("kms: access denied")
+				}
+				return s.key(id), nil
+			},
+		}
+	}
+	return out, nil
+}
+
+func TestRefresherAddsAndPromotes(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	src := &refreshSet{}
+	src.set("k1", "k1")
+
+	r, err := NewRefresher(ring, src.fetch)
+	if err != nil {
+		t.Fatalf("NewRefresher: %v", err)
+	}
+	defer r.Stop()
+
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if n := src.unwraps.Load(); n != 0 {
+		t.Errorf("unwraps for held keys = %d, want 0", n)
+	}
+
+	old, err := ring.Encrypt(ctx, []byte("old"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	src.set("k2", "k1", "k2")
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if got := ring.CurrentKeyID(); got != "k2" {
+		t.Errorf("CurrentKeyID = %q, want k2", got)
+	}
+	if n := src.unwraps.Load(); n != 1 {
+		t.Errorf("unwraps = %d, want 1", n)
+	}
+	if _, err := ring.Decrypt(ctx, old); err != nil {
+		t.Errorf("Decrypt under old key: %v", err)
+	}
+
+	// Dropping a key from the listing leaves it in the ring.
+	src.set("k2", "k2")
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := ring.Decrypt(ctx, old); err != nil {
+		t.Errorf("Decrypt after delisting: %v", err)
+	}
+}
+
+func TestRefresherPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	src := &refreshSet{failID: "bad"}
+	src.set("bad", "k1", "bad", "k3")
+
+	r, err := NewRefresher(ring, src.fetch)
+	if err != nil {
+		t.Fatalf("NewRefresher: %v", err)
+	}
+	defer r.Stop()
+
+	if err := r.Refresh(ctx); err == nil {
+		t.Fatal("Refresh succeeded with a failing unwrap")
+	}
+	if got := ring.CurrentKeyID(); got != "k1" {
+		t.Errorf("CurrentKeyID = %q, want k1 kept", got)
+	}
+	ids := ring.(KeyLister).ListKeyIDs()
+	if len(ids) != 2 {
+		t.Errorf("ListKeyIDs = %v, want k1 and k3", ids)
+	}
+}
+
+func TestRefresherRecheck(t *testing.T) {
+	ctx := context.Background()
+	src := &refreshSet{}
+	src.set("k1", "k1")
+	ring := mustNewKeyRingProvider(t, src.key("k1"), "k1", 0)
+
+	r, err := NewRefresher(ring, src.fetch, WithRefreshRecheck())
+	if err != nil {
+		t.Fatalf("NewRefresher: %v", err)
+	}
+	defer r.Stop()
+
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if n := src.unwraps.Load(); n != 1 {
+		t.Errorf("unwraps = %d, want 1 recheck of the held key", n)
+	}
+
+	// A wrapped key replaced under the same ID is reported.
+	src.salt = 1
+	if err := r.Refresh(ctx); err == nil || !strings.Contains(err.Error(), "differs") {
+		t.Errorf("Refresh with replaced key material: %v, want mismatch error", err)
+	}
+
+	src.salt = 0
+	src.failID = "k1"
+	if err := r.Refresh(ctx); err == nil {
+		t.Fatal("Refresh succeeded with a held key that no longer unwraps")
+	}
+	if ids := ring.(KeyLister).ListKeyIDs(); len(ids) != 1 || ids[0] != "k1" {
+		t.Errorf("ListKeyIDs = %v, want k1 kept", ids)
+	}
+}
+
+func TestRefresherEmptyListing(t *testing.T) {
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	src := &refreshSet{}
+	r, err := NewRefresher(ring, src.fetch)
+	if err != nil {
+		t.Fatalf("NewRefresher: %v", err)
+	}
+	defer r.Stop()
+	if err := r.Refresh(context.Background()); err == nil {
+		t.Error("Refresh of an empty key set succeeded")
+	}
+}
+
+func TestRefresherInterval(t *testing.T) {
+	ring, err := NewKeyRingProvider(makeKey(32), "k1", 0)
+	if err != nil {
+		t.Fatalf("NewKeyRingProvider: %v", err)
+	}
+	src := &refreshSet{}
+	src.set("k2", "k1", "k2")
+
+	r, err := NewRefresher(ring, src.fetch, WithRefreshInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewRefresher: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for ring.CurrentKeyID() != "k2" {
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not promote k2")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Closing the ring ends the background goroutine; Stop must not hang.
+	_ = ring.Close()
+	done := make(chan struct{})
+	go func() { r.Stop(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return after ring Close")
+	}
+}
+
+func TestNewRefresherValidation(t *testing.T) {
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	src := &refreshSet{}
+	if _, err := NewRefresher(nil, src.fetch); err == nil {
+		t.Error("nil ring accepted")
+	}
+	if _, err := NewRefresher(ring, nil); err == nil {
+		t.Error("nil fetch accepted")
+	}
+	if _, err := NewRefresher(ring, src.fetch, WithRefreshInterval(-time.Second)); err == nil {
+		t.Error("negative interval accepted")
+	}
+}
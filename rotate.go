@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Rotate generates a fresh random 32-byte key, adds it to ring under newID,
+// and makes it current. The previous current key stays in the ring for
+// decryption. The new key's rank is one more than the highest rank in the
+// ring when ring implements KeyLister and KeyInfoProvider, as the built-in
+// key ring does, so NeedsReencryption reports values under older keys;
+// otherwise it is 0. An empty newID is derived with KeyFingerprint when the
+// ring was built with WithFingerprintKeyID.
+//
+// The returned Key is the only copy of the new key outside the ring: persist
+// it (wrapped) before relying on it, then zero Key.Bytes. If the key cannot
+// be made current it is removed again and an error is returned.
+func Rotate(ring KeyRingProvider, newID string) (Key, error) {
+	if ring == nil {
+		return Key{}, errors.New("crypto: Rotate ring is nil")
+	}
+	b, err := GenerateKey()
+	if err != nil {
+		return Key{}, err
+	}
+
+	if p, ok := ring.(*keyRingProvider); ok && p.fpIDs && newID == "" {
+		newID = KeyFingerprint(b)
+	}
+	if err := ring.AddKey(b, newID, nextRank(ring)); err != nil {
+		clear(b)
+		return Key{}, fmt.Errorf("crypto: rotate: %w", err)
+	}
+	if err := ring.SetCurrentKey(newID); err != nil {
+		_ = ring.RemoveKey(newID)
+		clear(b)
+		return Key{}, fmt.Errorf("crypto: rotate: %w", err)
+	}
+	return Key{ID: newID, Bytes: b}, nil
+}
+
+// nextRank returns one more than the highest rank held by ring, or 0 if the
+// ring does not expose its keys' metadata.
+func nextRank(ring KeyRingProvider) uint64 {
+	kl, ok := ring.(KeyLister)
+	if !ok {
+		return 0
+	}
+	ki, ok := ring.(KeyInfoProvider)
+	if !ok {
+		return 0
+	}
+	var rank uint64
+	for _, id := range kl.ListKeyIDs() {
+		if info, err := ki.KeyInfo(id); err == nil && info.Rank+1 > rank {
+			rank = info.Rank + 1
+		}
+	}
+	return rank
+}
@@ -0,0 +1,67 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 4)
+	old, err := ring.Encrypt(ctx, []byte("old"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	k, err := Rotate(ring, "k2")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if k.ID != "k2" || len(k.Bytes) != 32 {
+		t.Fatalf("Rotate returned %v", k)
+	}
+	if bytes.Equal(k.Bytes, make([]byte, 32)) {
+		t.Error("Rotate returned an all-zero key")
+	}
+	if got := ring.CurrentKeyID(); got != "k2" {
+		t.Errorf("CurrentKeyID = %q, want k2", got)
+	}
+	info, err := ring.(KeyInfoProvider).KeyInfo("k2")
+	if err != nil || info.Rank != 5 {
+		t.Errorf("KeyInfo(k2) = %+v, %v; want rank 5", info, err)
+	}
+	if need, err := ring.NeedsReencryption(old); err != nil || !need {
+		t.Errorf("NeedsReencryption(old) = %v, %v; want true", need, err)
+	}
+	if got, err := ring.Decrypt(ctx, old); err != nil || string(got) != "old" {
+		t.Errorf("Decrypt old = %q, %v", got, err)
+	}
+
+	// The returned key decrypts values encrypted after rotation.
+	ct, err := ring.Encrypt(ctx, []byte("new"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	p := mustNewProvider(t, k.Bytes, k.ID)
+	if got, err := p.Decrypt(ctx, ct); err != nil || string(got) != "new" {
+		t.Errorf("Decrypt with returned key = %q, %v", got, err)
+	}
+}
+
+func TestRotateErrors(t *testing.T) {
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 0)
+	if _, err := Rotate(ring, "k1"); !IsDuplicateKeyID(err) {
+		t.Errorf("Rotate to existing ID: got %v, want ErrDuplicateKeyID", err)
+	}
+	if _, err := Rotate(ring, ""); err == nil {
+		t.Error("Rotate with empty ID succeeded")
+	}
+	if got := ring.CurrentKeyID(); got != "k1" {
+		t.Errorf("CurrentKeyID = %q after failed rotations, want k1", got)
+	}
+	_ = ring.Close()
+	if _, err := Rotate(ring, "k2"); !IsProviderClosed(err) {
+		t.Errorf("Rotate on closed ring: got %v, want ErrProviderClosed", err)
+	}
+}
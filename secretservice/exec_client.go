@@ -0,0 +1,117 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package secretservice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// maxStderrLen is the maximum number of bytes included from secret-tool
+// stderr in error messages.
+const maxStderrLen = 200
+
+// ExecClient looks up and stores items by invoking libsecret's secret-tool,
+// which must be installed (libsecret-tools on Debian and Ubuntu). A
+// session bus with a running Secret Service is required.
+type ExecClient struct {
+	bin string // path to secret-tool binary, defaults to "secret-tool"
+}
+
+// ExecOption configures an ExecClient.
+type ExecOption func(*ExecClient)
+
+// WithSecretToolBinary sets the path to the secret-tool binary.
+// Defaults to "secret-tool" (resolved via PATH).
+func WithSecretToolBinary(path string) ExecOption {
+	return func(c *ExecClient) {
+		c.bin = path
+	}
+}
+
+// NewExecClient creates an ExecClient that delegates to secret-tool.
+func NewExecClient(opts ...ExecOption) *ExecClient {
+	c := &ExecClient{bin: "secret-tool"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Lookup returns the secret of the item whose attributes match attrs.
+// secret-tool exits with status 1 and no message when nothing matches,
+// which Lookup reports as ErrItemNotFound.
+func (c *ExecClient) Lookup(ctx context.Context, attrs map[string]string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, c.bin, append([]string{"lookup"}, attrArgs(attrs)...)...) // #nosec G204 -- bin is developer-configured, not user input
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	defer clear(stdout.Bytes())
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrItemNotFound, formatAttrs(attrs))
+		}
+		return nil, fmt.Errorf("secretservice: secret-tool: %w: %s", err, sanitizeStderr(stderr.Bytes()))
+	}
+
+	secret := bytes.TrimRight(stdout.Bytes(), "\n")
+	return bytes.Clone(secret), nil
+}
+
+// Store creates an item with the given label and attributes. The secret is
+// passed on secret-tool's standard input rather than on its command line,
+// where other processes could read it.
+func (c *ExecClient) Store(ctx context.Context, label string, attrs map[string]string, secret []byte) error {
+	args := append([]string{"store", "--label=" + label}, attrArgs(attrs)...)
+	cmd := exec.CommandContext(ctx, c.bin, args...) // #nosec G204 -- bin is developer-configured, not user input
+	cmd.Stdin = bytes.NewReader(secret)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("secretservice: secret-tool: %w: %s", err, sanitizeStderr(stderr.Bytes()))
+	}
+	return nil
+}
+
+// attrArgs returns attrs as alternating name and value arguments in name
+// order.
+func attrArgs(attrs map[string]string) []string {
+	args := make([]string, 0, 2*len(attrs))
+	for _, k := range slices.Sorted(maps.Keys(attrs)) {
+		args = append(args, k, attrs[k])
+	}
+	return args
+}
+
+// sanitizeStderr returns a truncated, printable-only excerpt of secret-tool
+// stderr suitable for inclusion in error messages.
+func sanitizeStderr(raw []byte) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) || r == '\n' {
+			return r
+		}
+		return -1
+	}, string(raw))
+	s = strings.TrimSpace(s)
+	if len(s) > maxStderrLen {
+		s = s[:maxStderrLen] + "..."
+	}
+	return s
+}
+
+// Compile-time interface checks.
+var (
+	_ Client = (*ExecClient)(nil)
+	_ Storer = (*ExecClient)(nil)
+)
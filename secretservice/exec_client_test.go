@@ -0,0 +1,69 @@
+//go:build linux || freebsd || openbsd || netbsd
+
+package secretservice
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSecretTool writes a shell script standing in for secret-tool.
+func fakeSecretTool(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret-tool")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewExecClientDefaults(t *testing.T) {
+	if c := NewExecClient(); c.bin != "secret-tool" {
+		t.Errorf("default bin: got %q, want %q", c.bin, "secret-tool")
+	}
+}
+
+func TestExecClientLookup(t *testing.T) {
+	// Echo the arguments back so the test can check their order.
+	c := NewExecClient(WithSecretToolBinary(fakeSecretTool(t, `echo "$@"`)))
+	got, err := c.Lookup(context.Background(), map[string]string{"service": "app", "key": "kek"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "lookup key kek service app"; string(got) != want {
+		t.Errorf("Lookup = %q, want %q", got, want)
+	}
+}
+
+func TestExecClientLookupNotFound(t *testing.T) {
+	c := NewExecClient(WithSecretToolBinary(fakeSecretTool(t, "exit 1")))
+	if _, err := c.Lookup(context.Background(), map[string]string{"k": "v"}); !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestExecClientLookupFailure(t *testing.T) {
+	c := NewExecClient(WithSecretToolBinary(fakeSecretTool(t, "echo 'no session bus' >&2; exit 1")))
+	_, err := c.Lookup(context.Background(), map[string]string{"k": "v"})
+	if err == nil || errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected a non-not-found error, got %v", err)
+	}
+}
+
+func TestExecClientStoreUsesStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "stdin")
+	c := NewExecClient(WithSecretToolBinary(fakeSecretTool(t, `cat > "`+out+`"`)))
+	if err := c.Store(context.Background(), "label", map[string]string{"k": "v"}, []byte("base64:AA==")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "base64:AA==" {
+		t.Errorf("secret-tool stdin = %q", got)
+	}
+}
@@ -0,0 +1,162 @@
+// Package secretservice provides a crypto.Provider whose keys live in a
+// freedesktop Secret Service (GNOME Keyring, KWallet, KeePassXC), for
+// desktop tooling that encrypts local config profiles.
+//
+// Each key is a secret item identified by its attributes, whose secret is
+// the key in a form crypto.ParseKey accepts (StoreKey writes "base64:...").
+// Keys are read at construction time through a Client; ExecClient talks to
+// the Secret Service D-Bus API through libsecret's secret-tool, so no D-Bus
+// library is needed:
+//
+//	client := secretservice.NewExecClient()
+//	provider, err := secretservice.New(ctx, client,
+//	    secretservice.WithKey(map[string]string{"service": "myapp", "key": "kek-2"}, "kek-2"),
+//	    secretservice.WithKey(map[string]string{"service": "myapp", "key": "kek-1"}, "kek-1"),
+//	)
+//	// kek-2 is current; kek-1 is available for decrypting existing data
+//
+// A locked collection is unlocked by the desktop's usual prompt. Implement
+// Client around a D-Bus library such as godbus to avoid the secret-tool
+// dependency.
+package secretservice
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// ErrItemNotFound is returned by a Client when no item matches the
+// requested attributes.
+var ErrItemNotFound = errors.New("secretservice: item not found")
+
+// Client looks up secret items.
+type Client interface {
+	// Lookup returns the secret of the item whose attributes match attrs,
+	// or an error wrapping ErrItemNotFound. New calls Lookup concurrently
+	// when several keys are configured.
+	Lookup(ctx context.Context, attrs map[string]string) ([]byte, error)
+}
+
+// Storer creates secret items.
+type Storer interface {
+	// Store creates an item with the given label and attributes in the
+	// default collection, replacing any item with the same attributes.
+	Store(ctx context.Context, label string, attrs map[string]string, secret []byte) error
+}
+
+// Option configures the Secret Service provider.
+type Option func(*options)
+
+type options struct {
+	items []item
+}
+
+type item struct {
+	attrs map[string]string
+	id    string
+}
+
+// WithKey registers the key held in the item whose attributes match attrs.
+// The id identifies this key in the config-crypto system; an empty id uses
+// crypto.KeyFingerprint of the key.
+//
+// The first call to WithKey sets the current key used for new encryptions.
+// Subsequent calls register additional keys for decryption during key
+// rotation.
+func WithKey(attrs map[string]string, id string) Option {
+	return func(o *options) {
+		o.items = append(o.items, item{attrs: maps.Clone(attrs), id: id})
+	}
+}
+
+// New creates a crypto.KeyRingProvider from keys stored in the Secret
+// Service.
+//
+// At least one key must be provided via WithKey. The first key is the
+// current key for new encryptions; additional keys support decryption
+// during key rotation.
+//
+// All keys are read during construction and cached. The Client is not
+// retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, client Client, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, fmt.Errorf("secretservice: Client must not be nil")
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, it := range o.items {
+		if len(it.attrs) == 0 {
+			return nil, fmt.Errorf("secretservice: key %q has no attributes", it.id)
+		}
+	}
+
+	return kmsring.Build(len(o.items), "secretservice", func(i int) ([]byte, string, error) {
+		it := o.items[i]
+		name := it.id
+		if name == "" {
+			name = formatAttrs(it.attrs)
+		}
+		secret, err := client.Lookup(ctx, it.attrs)
+		if err != nil {
+			return nil, name, err
+		}
+		key, err := crypto.ParseKey(string(secret))
+		clear(secret)
+		if err != nil {
+			return nil, name, err
+		}
+		if it.id == "" {
+			return key, crypto.KeyFingerprint(key), nil
+		}
+		return key, it.id, nil
+	})
+}
+
+// StoreKey stores key in an item with the given label and attributes,
+// encoded as "base64:..." so that New can read it back.
+func StoreKey(ctx context.Context, s Storer, label string, attrs map[string]string, key []byte) error {
+	if s == nil {
+		return fmt.Errorf("secretservice: Storer must not be nil")
+	}
+	if len(attrs) == 0 {
+		return fmt.Errorf("secretservice: at least one attribute is required")
+	}
+	if len(key) != kmsring.KeySize {
+		return fmt.Errorf("%w: got %d bytes", crypto.ErrInvalidKeySize, len(key))
+	}
+	const prefix = "base64:"
+	secret := make([]byte, len(prefix)+base64.StdEncoding.EncodedLen(len(key)))
+	copy(secret, prefix)
+	base64.StdEncoding.Encode(secret[len(prefix):], key)
+	defer clear(secret)
+	if err := s.Store(ctx, label, attrs, secret); err != nil {
+		return fmt.Errorf("secretservice: store %s: %w", formatAttrs(attrs), err)
+	}
+	return nil
+}
+
+// formatAttrs renders attrs as "k1=v1,k2=v2" in key order.
+func formatAttrs(attrs map[string]string) string {
+	var b strings.Builder
+	for i, k := range slices.Sorted(maps.Keys(attrs)) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k + "=" + attrs[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package secretservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockService is an in-memory Client and Storer keyed by formatted
+// attributes.
+type mockService struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func (m *mockService) Lookup(ctx context.Context, attrs map[string]string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, ok := m.items[formatAttrs(attrs)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrItemNotFound, formatAttrs(attrs))
+	}
+	return append([]byte(nil), secret...), nil
+}
+
+func (m *mockService) Store(_ context.Context, _ string, attrs map[string]string, secret []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.items == nil {
+		m.items = make(map[string][]byte)
+	}
+	m.items[formatAttrs(attrs)] = append([]byte(nil), secret...)
+	return nil
+}
+
+var (
+	_ Client = (*mockService)(nil)
+	_ Storer = (*mockService)(nil)
+)
+
+func makeKey(seed byte) []byte {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = seed + byte(i)
+	}
+	return k
+}
+
+func attrs(key string) map[string]string {
+	return map[string]string{"service": "app", "key": key}
+}
+
+func TestNew_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	svc := &mockService{}
+	for i, name := range []string{"kek-1", "kek-2"} {
+		if err := StoreKey(ctx, svc, name, attrs(name), makeKey(byte(i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := New(ctx, svc, WithKey(attrs("kek-2"), "kek-2"), WithKey(attrs("kek-1"), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "kek-2" {
+		t.Errorf("CurrentKeyID = %q, want kek-2", p.CurrentKeyID())
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+	if kl, ok := p.(crypto.KeyLister); ok {
+		fp := crypto.KeyFingerprint(makeKey(1))
+		found := false
+		for _, id := range kl.ListKeyIDs() {
+			found = found || id == fp
+		}
+		if !found {
+			t.Errorf("ListKeyIDs = %v, want fingerprint %s", kl.ListKeyIDs(), fp)
+		}
+	}
+}
+
+func TestNew_NilClient(t *testing.T) {
+	if _, err := New(context.Background(), nil, WithKey(attrs("k"), "k")); err == nil {
+		t.Error("expected error for nil client")
+	}
+}
+
+func TestNew_NoKeys(t *testing.T) {
+	if _, err := New(context.Background(), &mockService{}); err == nil {
+		t.Error("expected error when no keys are configured")
+	}
+}
+
+func TestNew_NoAttributes(t *testing.T) {
+	if _, err := New(context.Background(), &mockService{}, WithKey(nil, "k")); err == nil {
+		t.Error("expected error for a key without attributes")
+	}
+}
+
+func TestNew_ItemNotFound(t *testing.T) {
+	_, err := New(context.Background(), &mockService{}, WithKey(attrs("missing"), "k"))
+	if !errors.Is(err, ErrItemNotFound) {
+		t.Errorf("expected ErrItemNotFound, got %v", err)
+	}
+}
+
+func TestStoreKey_InvalidSize(t *testing.T) {
+	err := StoreKey(context.Background(), &mockService{}, "k", attrs("k"), make([]byte, 16))
+	if !crypto.IsInvalidKeySize(err) {
+		t.Errorf("expected ErrInvalidKeySize, got %v", err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package shamir splits a key encryption key into M-of-N shares with
+// Shamir's secret sharing over GF(2^8), and rebuilds a provider from a
+// quorum of shares.
+//
+// Any threshold shares reconstruct the key; fewer reveal nothing about it.
+// This lets a security policy require several operators to cooperate to
+// recover an offline master key, with no single person holding it.
+//
+// Usage:
+//
+//	key, _ := crypto.GenerateKey()
+//	id := crypto.KeyFingerprint(key)
+//	shares, _ := shamir.Split(key, 5, 3) // hand one share to each of 5 operators
+//	clear(key)
+//
+//	// Later, with any 3 shares:
+//	provider, err := shamir.NewProvider([][]byte{s1, s4, s5}, id)
+//
+// Combining fewer than threshold shares, or shares from different splits,
+// silently yields a wrong key. Use crypto.KeyFingerprint of the original key
+// as its ID; NewProvider then verifies the reconstructed key against it.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// ErrInvalidShares is returned by Combine when the shares are malformed,
+// inconsistent, or too few.
+var ErrInvalidShares = errors.New("shamir: invalid shares")
+
+// Split divides secret into n shares, any threshold of which reconstruct
+// it. Each share is len(secret)+1 bytes: the polynomial values followed by
+// the share's x coordinate. 2 <= threshold <= n <= 255.
+func Split(secret []byte, n, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("shamir: secret is empty")
+	}
+	if threshold < 2 || threshold > n || n > 255 {
+		return nil, fmt.Errorf("shamir: need 2 <= threshold <= n <= 255, got threshold %d, n %d", threshold, n)
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	coeffs := make([]byte, threshold)
+	defer clear(coeffs)
+	for j, s := range secret {
+		coeffs[0] = s
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("shamir: %w", err)
+		}
+		for _, share := range shares {
+			share[j] = evaluate(coeffs, share[len(secret)])
+		}
+	}
+	return shares, nil
+}
+
+// Combine reconstructs the secret from at least threshold shares produced
+// by one Split call. The caller owns the result and should zero it after
+// use.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, fmt.Errorf("%w: need at least 2 shares, got %d", ErrInvalidShares, len(shares))
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, fmt.Errorf("%w: share is too short", ErrInvalidShares)
+	}
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, s := range shares {
+		if len(s) != size {
+			return nil, fmt.Errorf("%w: shares have different lengths", ErrInvalidShares)
+		}
+		x := s[size-1]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("%w: duplicate or zero share index %d", ErrInvalidShares, x)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	defer clear(ys)
+	for j := range secret {
+		for i, s := range shares {
+			ys[i] = s[j]
+		}
+		secret[j] = interpolateAtZero(xs, ys)
+	}
+	return secret, nil
+}
+
+// NewProvider combines shares into a 32-byte key and returns a key ring
+// holding it under id. The combined key is zeroed before returning. opts
+// are passed to crypto.NewKeyRingProvider.
+//
+// If id has the "sha256:" form of crypto.KeyFingerprint, the reconstructed
+// key must match it, or NewProvider fails with ErrInvalidShares. This catches
+// too few shares, a corrupted share, or shares from different splits.
+func NewProvider(shares [][]byte, id string, opts ...crypto.ProviderOption) (crypto.KeyRingProvider, error) {
+	key, err := Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(key)
+	if isFingerprint(id) && crypto.KeyFingerprint(key) != id {
+		return nil, fmt.Errorf("%w: reconstructed key does not match fingerprint %s", ErrInvalidShares, id)
+	}
+	return crypto.NewKeyRingProvider(key, id, 0, opts...)
+}
+
+// evaluate returns the polynomial with coefficients coeffs (constant term
+// first) at x, using Horner's rule.
+func evaluate(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// interpolateAtZero returns the Lagrange interpolation at x=0 of the points
+// (xs[i], ys[i]).
+func interpolateAtZero(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		num, den := byte(1), byte(1)
+		for k := range xs {
+			if k == i {
+				continue
+			}
+			num = mul(num, xs[k])
+			den = mul(den, xs[i]^xs[k])
+		}
+		result ^= mul(ys[i], mul(num, inverse(den)))
+	}
+	return result
+}
+
+// mul multiplies in GF(2^8) modulo the AES polynomial x^8+x^4+x^3+x+1,
+// without data-dependent branches.
+func mul(a, b byte) byte {
+	var p byte
+	for range 8 {
+		p ^= a & -(b & 1)
+		hi := -(a >> 7)
+		a = (a << 1) ^ (0x1b & hi)
+		b >>= 1
+	}
+	return p
+}
+
+// inverse returns a^254, the multiplicative inverse of a non-zero a.
+func inverse(a byte) byte {
+	result := byte(1)
+	for range 254 {
+		result = mul(result, a)
+	}
+	return result
+}
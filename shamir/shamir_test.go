@@ -0,0 +1,122 @@
+package shamir
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+func TestSplitCombineAllQuorums(t *testing.T) {
+	secret, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if len(shares) != 5 || len(shares[0]) != 33 {
+		t.Fatalf("got %d shares of %d bytes", len(shares), len(shares[0]))
+	}
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				got, err := Combine([][]byte{shares[c], shares[a], shares[b]})
+				if err != nil {
+					t.Fatalf("Combine: %v", err)
+				}
+				if !bytes.Equal(got, secret) {
+					t.Errorf("shares %d,%d,%d reconstructed the wrong secret", a, b, c)
+				}
+			}
+		}
+	}
+
+	// Two shares are below the threshold and must not reveal the secret.
+	got, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatalf("Combine: %v", err)
+	}
+	if bytes.Equal(got, secret) {
+		t.Error("2 of a 3-of-5 split reconstructed the secret")
+	}
+}
+
+func TestGF256(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := mul(byte(a), inverse(byte(a))); got != 1 {
+			t.Fatalf("%d * inverse(%d) = %d", a, a, got)
+		}
+	}
+	if mul(0x57, 0x83) != 0xc1 { // FIPS-197 section 4.2 example
+		t.Errorf("mul(0x57, 0x83) = %#x, want 0xc1", mul(0x57, 0x83))
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	secret := []byte("s")
+	for _, c := range []struct{ n, threshold int }{{5, 1}, {3, 4}, {256, 2}} {
+		if _, err := Split(secret, c.n, c.threshold); err == nil {
+			t.Errorf("Split(n=%d, threshold=%d) succeeded", c.n, c.threshold)
+		}
+	}
+	if _, err := Split(nil, 3, 2); err == nil {
+		t.Error("Split of empty secret succeeded")
+	}
+}
+
+func TestCombineErrors(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string][][]byte{
+		"one share":  shares[:1],
+		"duplicate":  {shares[0], shares[0]},
+		"length":     {shares[0], shares[1][:3]},
+		"zero index": {shares[0], append(bytes.Clone(shares[1][:6]), 0)},
+	}
+	for name, in := range cases {
+		if _, err := Combine(in); !errors.Is(err, ErrInvalidShares) {
+			t.Errorf("%s: got %v, want ErrInvalidShares", name, err)
+		}
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := crypto.KeyFingerprint(key)
+	orig, err := crypto.NewProvider(key, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orig.Close()
+	ct, err := orig.Encrypt(ctx, []byte("recovered"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shares, err := Split(key, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProvider([][]byte{shares[4], shares[0], shares[2]}, id)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	defer p.Close()
+	if got, err := p.Decrypt(ctx, ct); err != nil || string(got) != "recovered" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+
+	if _, err := NewProvider(shares[:2], id); !errors.Is(err, ErrInvalidShares) {
+		t.Errorf("below threshold: got %v, want ErrInvalidShares", err)
+	}
+}
@@ -0,0 +1,220 @@
+package shamir
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// ErrSealed is returned by an Unsealer's provider until enough shares have
+// been submitted.
+var ErrSealed = errors.New("shamir: provider is sealed")
+
+// Unsealer collects shares one at a time, for example from an admin
+// endpoint, and builds the key ring once threshold shares have arrived,
+// like Vault's unseal flow. It is safe for concurrent use.
+//
+//	u, _ := shamir.NewUnsealer(3, id)
+//	codec, _ := crypto.NewCodec(inner, u.Provider()) // fails with ErrSealed until unsealed
+//
+//	// In the admin handler:
+//	done, err := u.Submit(share)
+//
+// Use a crypto.KeyFingerprint ID so a bad set of shares is rejected rather
+// than unsealing with a wrong key; submitted shares are then discarded and
+// collection starts over.
+type Unsealer struct {
+	threshold int
+	id        string
+	opts      []crypto.ProviderOption
+
+	mu     sync.Mutex
+	shares [][]byte
+	ring   crypto.KeyRingProvider
+	closed bool
+	ready  chan struct{}
+}
+
+// NewUnsealer returns a sealed Unsealer that needs threshold shares to
+// build a key ring holding the key under id. opts are passed to
+// crypto.NewKeyRingProvider.
+func NewUnsealer(threshold int, id string, opts ...crypto.ProviderOption) (*Unsealer, error) {
+	if threshold < 2 || threshold > 255 {
+		return nil, fmt.Errorf("shamir: threshold must be between 2 and 255, got %d", threshold)
+	}
+	return &Unsealer{threshold: threshold, id: id, opts: opts, ready: make(chan struct{})}, nil
+}
+
+// Submit adds one share. It reports true once the Unsealer is unsealed,
+// including for shares submitted after that. A malformed or duplicate share
+// is rejected with ErrInvalidShares and the shares already submitted are
+// kept. If threshold shares fail to produce the key, all submitted shares
+// are discarded and the error is returned.
+func (u *Unsealer) Submit(share []byte) (bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return false, crypto.ErrProviderClosed
+	}
+	if u.ring != nil {
+		return true, nil
+	}
+	if len(share) < 2 || share[len(share)-1] == 0 {
+		return false, fmt.Errorf("%w: malformed share", ErrInvalidShares)
+	}
+	for _, s := range u.shares {
+		if len(s) != len(share) {
+			return false, fmt.Errorf("%w: share length differs from earlier shares", ErrInvalidShares)
+		}
+		if s[len(s)-1] == share[len(share)-1] {
+			return false, fmt.Errorf("%w: share %d already submitted", ErrInvalidShares, share[len(share)-1])
+		}
+	}
+	u.shares = append(u.shares, bytes.Clone(share))
+	if len(u.shares) < u.threshold {
+		return false, nil
+	}
+
+	ring, err := NewProvider(u.shares, u.id, u.opts...)
+	u.resetLocked()
+	if err != nil {
+		return false, err
+	}
+	u.ring = ring
+	close(u.ready)
+	return true, nil
+}
+
+// Progress reports how many shares have been submitted towards the
+// threshold. Once unsealed it reports threshold, threshold.
+func (u *Unsealer) Progress() (submitted, threshold int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.ring != nil {
+		return u.threshold, u.threshold
+	}
+	return len(u.shares), u.threshold
+}
+
+// Reset discards and wipes the shares submitted so far. It does not reseal
+// an unsealed Unsealer.
+func (u *Unsealer) Reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.resetLocked()
+}
+
+func (u *Unsealer) resetLocked() {
+	for _, s := range u.shares {
+		clear(s)
+	}
+	u.shares = nil
+}
+
+// Wait blocks until the Unsealer is unsealed or ctx is done, then returns
+// the key ring.
+func (u *Unsealer) Wait(ctx context.Context) (crypto.KeyRingProvider, error) {
+	select {
+	case <-u.ready:
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		return u.ring, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Provider returns a crypto.Provider backed by the key ring once unsealed.
+// Until then every operation, including HealthCheck, fails with ErrSealed.
+// Closing it closes the Unsealer.
+func (u *Unsealer) Provider() crypto.Provider {
+	return (*unsealedProvider)(u)
+}
+
+// Close wipes pending shares and closes the key ring, if built. Safe to call
+// multiple times.
+func (u *Unsealer) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return nil
+	}
+	u.closed = true
+	u.resetLocked()
+	if u.ring != nil {
+		return u.ring.Close()
+	}
+	return nil
+}
+
+// current returns the key ring, or ErrSealed.
+func (u *Unsealer) current() (crypto.KeyRingProvider, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return nil, crypto.ErrProviderClosed
+	}
+	if u.ring == nil {
+		return nil, ErrSealed
+	}
+	return u.ring, nil
+}
+
+// unsealedProvider is the crypto.Provider view of an Unsealer.
+type unsealedProvider Unsealer
+
+func (p *unsealedProvider) u() *Unsealer { return (*Unsealer)(p) }
+
+// Name returns the key ID, or "sealed" before unsealing.
+func (p *unsealedProvider) Name() string {
+	if r, err := p.u().current(); err == nil {
+		return r.Name()
+	}
+	return "sealed"
+}
+
+// Connect is a no-op.
+func (p *unsealedProvider) Connect(_ context.Context) error { return nil }
+
+// Encrypt encrypts with the key ring, or fails with ErrSealed.
+func (p *unsealedProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	r, err := p.u().current()
+	if err != nil {
+		return nil, err
+	}
+	return r.Encrypt(ctx, plaintext)
+}
+
+// Decrypt decrypts with the key ring, or fails with ErrSealed.
+func (p *unsealedProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.DecryptTo(ctx, nil, ciphertext)
+}
+
+// DecryptTo decrypts ciphertext and appends the plaintext to dst.
+func (p *unsealedProvider) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	r, err := p.u().current()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecryptTo(ctx, dst, ciphertext, r)
+}
+
+// HealthCheck fails with ErrSealed until unsealed.
+func (p *unsealedProvider) HealthCheck(ctx context.Context) error {
+	r, err := p.u().current()
+	if err != nil {
+		return err
+	}
+	return r.HealthCheck(ctx)
+}
+
+// Close closes the Unsealer.
+func (p *unsealedProvider) Close() error { return p.u().Close() }
+
+// isFingerprint reports whether id is a crypto.KeyFingerprint.
+func isFingerprint(id string) bool { return strings.HasPrefix(id, "sha256:") }
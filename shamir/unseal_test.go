@@ -0,0 +1,130 @@
+package shamir
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+func splitKey(t *testing.T) (id string, shares [][]byte) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shares, err = Split(key, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crypto.KeyFingerprint(key), shares
+}
+
+func TestUnsealer(t *testing.T) {
+	ctx := context.Background()
+	id, shares := splitKey(t)
+	u, err := NewUnsealer(3, id)
+	if err != nil {
+		t.Fatalf("NewUnsealer: %v", err)
+	}
+	defer u.Close()
+	p := u.Provider()
+
+	if _, err := p.Encrypt(ctx, []byte("x")); !errors.Is(err, ErrSealed) {
+		t.Errorf("Encrypt while sealed: got %v, want ErrSealed", err)
+	}
+	if err := p.HealthCheck(ctx); !errors.Is(err, ErrSealed) {
+		t.Errorf("HealthCheck while sealed: got %v, want ErrSealed", err)
+	}
+
+	for i, s := range shares[1:3] {
+		done, err := u.Submit(s)
+		if err != nil || done {
+			t.Fatalf("Submit %d = %v, %v", i, done, err)
+		}
+	}
+	if _, err := u.Submit(shares[1]); !errors.Is(err, ErrInvalidShares) {
+		t.Errorf("duplicate share: got %v, want ErrInvalidShares", err)
+	}
+	if n, k := u.Progress(); n != 2 || k != 3 {
+		t.Errorf("Progress = %d/%d, want 2/3", n, k)
+	}
+
+	waited := make(chan error, 1)
+	go func() {
+		_, err := u.Wait(ctx)
+		waited <- err
+	}()
+	done, err := u.Submit(shares[4])
+	if err != nil || !done {
+		t.Fatalf("final Submit = %v, %v", done, err)
+	}
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("Wait: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait did not return after unseal")
+	}
+
+	ct, err := p.Encrypt(ctx, []byte("open"))
+	if err != nil {
+		t.Fatalf("Encrypt after unseal: %v", err)
+	}
+	if got, err := p.Decrypt(ctx, ct); err != nil || string(got) != "open" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if p.Name() != id {
+		t.Errorf("Name = %q, want %q", p.Name(), id)
+	}
+
+	_ = p.Close()
+	if _, err := p.Encrypt(ctx, []byte("x")); !crypto.IsProviderClosed(err) {
+		t.Errorf("Encrypt after Close: got %v, want ErrProviderClosed", err)
+	}
+}
+
+func TestUnsealerWrongSharesStartOver(t *testing.T) {
+	id, shares := splitKey(t)
+	_, other := splitKey(t)
+	u, err := NewUnsealer(3, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+
+	_, _ = u.Submit(shares[0])
+	_, _ = u.Submit(shares[1])
+	if _, err := u.Submit(other[2]); !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("mixed shares: got %v, want ErrInvalidShares", err)
+	}
+	if n, _ := u.Progress(); n != 0 {
+		t.Errorf("Progress after failure = %d, want 0", n)
+	}
+	for _, s := range shares[2:] {
+		if _, err := u.Submit(s); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	if _, err := u.Provider().Encrypt(context.Background(), []byte("x")); err != nil {
+		t.Errorf("Encrypt after retry: %v", err)
+	}
+}
+
+func TestUnsealerWaitCancelled(t *testing.T) {
+	u, err := NewUnsealer(2, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := u.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait: got %v, want context.Canceled", err)
+	}
+	if _, err := NewUnsealer(1, "k"); err == nil {
+		t.Error("threshold 1 accepted")
+	}
+}
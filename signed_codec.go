@@ -0,0 +1,235 @@
+package crypto
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/rbaliyan/config/codec"
+)
+
+// Signed payload format constants.
+const (
+	// signedMagic is the 2-byte signature of an integrity-protected payload.
+	signedMagic = "SG"
+
+	// signedVersionV1 is the current signed payload format version.
+	signedVersionV1 = 0x01
+
+	// macAlgHMACSHA256 identifies HMAC-SHA256 as the MAC algorithm.
+	macAlgHMACSHA256 = 0x01
+
+	// sigAlgEd25519 identifies Ed25519 as the signature algorithm.
+	sigAlgEd25519 = 0x02
+
+	// minSignedHeaderSize is magic(2) + version(1) + alg(1) + keyIDLen(1).
+	minSignedHeaderSize = 5
+)
+
+// SignedCodec wraps an inner codec with HMAC-SHA256 integrity protection.
+// The payload is not encrypted — it is the inner codec output, readable in
+// the store — but any modification is detected on Decode. Use it for values
+// that are not secret but must not be tampered with, such as routing tables
+// and feature flags.
+//
+// The MAC key is derived from the Provider's current KEK, so the same
+// Provider can back both a Codec and a SignedCodec. Verification failures are
+// reported as ErrSignatureInvalid, distinguishable from inner decode errors.
+//
+// Wire format:
+//
+//	[2B magic "SG"] [1B version = 0x01] [1B alg = 0x01 HMAC-SHA256]
+//	[1B key_id_len] [NB key_id] [32B tag] [remaining: inner codec output]
+//
+// The tag covers the magic, version, and algorithm bytes plus the payload;
+// the key ID is bound through MAC key derivation.
+//
+// SignedCodec is safe for concurrent use if the underlying Provider and inner
+// codec are safe for concurrent use.
+type SignedCodec struct {
+	inner    codec.Codec
+	provider MACProvider
+	name     string
+}
+
+// Compile-time interface checks.
+var (
+	_ codec.Codec       = (*SignedCodec)(nil)
+	_ codec.Transformer = (*SignedCodec)(nil)
+)
+
+// NewSignedCodec creates an integrity-only codec that wraps the given inner
+// codec. The codec name is "signed:<inner>", e.g. "signed:json"; the
+// WithClientCodec and WithCodecPrefix options are honoured.
+//
+// p must implement MACProvider (NewProvider and NewKeyRingProvider do).
+// Returns an error if inner or p is nil, or if p cannot compute MACs.
+func NewSignedCodec(inner codec.Codec, p Provider, opts ...CodecOption) (*SignedCodec, error) {
+	if inner == nil {
+		return nil, fmt.Errorf("crypto: NewSignedCodec inner codec is nil")
+	}
+	if p == nil {
+		return nil, fmt.Errorf("crypto: NewSignedCodec provider is nil")
+	}
+	mp, ok := p.(MACProvider)
+	if !ok {
+		return nil, fmt.Errorf("crypto: NewSignedCodec provider %q does not implement MACProvider", p.Name())
+	}
+
+	o := &codecOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	name := "signed:" + inner.Name()
+	if o.prefix != "" {
+		name = o.prefix + ":" + name
+	}
+
+	return &SignedCodec{
+		inner:    inner,
+		provider: mp,
+		name:     name,
+	}, nil
+}
+
+// Name returns the codec name, e.g. "signed:json".
+func (c *SignedCodec) Name() string {
+	return c.name
+}
+
+// Encode serializes the value using the inner codec, then signs the result.
+func (c *SignedCodec) Encode(ctx context.Context, v any) ([]byte, error) {
+	payload, err := c.inner.Encode(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: inner encode failed: %w", err)
+	}
+	signed, err := c.Transform(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: sign failed: %w", err)
+	}
+	return signed, nil
+}
+
+// Decode verifies the signature, then deserializes the payload using the
+// inner codec. A tampered payload returns an error wrapping ErrSignatureInvalid.
+func (c *SignedCodec) Decode(ctx context.Context, data []byte, v any) error {
+	payload, err := c.Reverse(ctx, data)
+	if err != nil {
+		return fmt.Errorf("crypto: verify failed: %w", err)
+	}
+	if err := c.inner.Decode(ctx, payload, v); err != nil {
+		return fmt.Errorf("crypto: inner decode failed: %w", err)
+	}
+	return nil
+}
+
+// Transform prepends a signed header to data.
+// This implements codec.Transformer for use with codec.NewChain.
+func (c *SignedCodec) Transform(ctx context.Context, data []byte) ([]byte, error) {
+	prefix := signedPrefix(macAlgHMACSHA256)
+	keyID, tag, err := c.provider.MAC(ctx, signedInput(prefix, data))
+	if err != nil {
+		return nil, err
+	}
+	return appendSigned(macAlgHMACSHA256, keyID, tag, data)
+}
+
+// Reverse verifies the signed header and returns a copy of the payload.
+// This implements codec.Transformer for use with codec.NewChain.
+func (c *SignedCodec) Reverse(ctx context.Context, data []byte) ([]byte, error) {
+	sp, err := parseSigned(data)
+	if err != nil {
+		return nil, err
+	}
+	if sp.alg != macAlgHMACSHA256 {
+		return nil, fmt.Errorf("%w: unsupported MAC algorithm %d", ErrInvalidFormat, sp.alg)
+	}
+	if err := c.provider.VerifyMAC(ctx, sp.keyID, signedInput(signedPrefix(sp.alg), sp.payload), sp.sig); err != nil {
+		return nil, err
+	}
+	return sp.payload, nil
+}
+
+// signedPayload is a parsed signed payload. All slices are copies.
+type signedPayload struct {
+	alg     byte
+	keyID   string
+	header  []byte // everything before the signature
+	sig     []byte
+	payload []byte
+}
+
+// signatureSize returns the signature length for a signed-format algorithm.
+func signatureSize(alg byte) (int, bool) {
+	switch alg {
+	case macAlgHMACSHA256:
+		return macSize, true
+	case sigAlgEd25519:
+		return ed25519.SignatureSize, true
+	default:
+		return 0, false
+	}
+}
+
+// signedPrefix returns the fixed magic, version, and algorithm bytes.
+func signedPrefix(alg byte) []byte {
+	return []byte{signedMagic[0], signedMagic[1], signedVersionV1, alg}
+}
+
+// appendSigned assembles a signed payload from its parts.
+func appendSigned(alg byte, keyID string, sig, payload []byte) ([]byte, error) {
+	if len(keyID) > maxKeyIDLen {
+		return nil, fmt.Errorf("%w: key ID too long (%d bytes, max %d)", ErrInvalidFormat, len(keyID), maxKeyIDLen)
+	}
+	out := make([]byte, 0, minSignedHeaderSize+len(keyID)+len(sig)+len(payload))
+	out = append(out, signedPrefix(alg)...)
+	out = append(out, byte(len(keyID))) // #nosec G115 -- keyID length validated above
+	out = append(out, keyID...)
+	out = append(out, sig...)
+	out = append(out, payload...)
+	return out, nil
+}
+
+// parseSigned splits a signed payload into its parts, validating the magic,
+// version, and that the algorithm is known.
+func parseSigned(data []byte) (*signedPayload, error) {
+	if len(data) < minSignedHeaderSize {
+		return nil, fmt.Errorf("%w: data too short", ErrInvalidFormat)
+	}
+	if string(data[0:2]) != signedMagic {
+		return nil, fmt.Errorf("%w: invalid magic bytes", ErrInvalidFormat)
+	}
+	if data[2] != signedVersionV1 {
+		return nil, fmt.Errorf("%w: unsupported signed version %d", ErrInvalidFormat, data[2])
+	}
+	alg := data[3]
+	sigLen, ok := signatureSize(alg)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported signature algorithm %d", ErrInvalidFormat, alg)
+	}
+
+	keyIDLen := int(data[4])
+	offset := minSignedHeaderSize
+	if len(data) < offset+keyIDLen+sigLen {
+		return nil, fmt.Errorf("%w: data too short for signed header", ErrInvalidFormat)
+	}
+	sp := &signedPayload{
+		alg:    alg,
+		keyID:  string(data[offset : offset+keyIDLen]),
+		header: append([]byte(nil), data[:offset+keyIDLen]...),
+	}
+	offset += keyIDLen
+	sp.sig = append([]byte(nil), data[offset:offset+sigLen]...)
+	offset += sigLen
+	sp.payload = append([]byte(nil), data[offset:]...)
+	return sp, nil
+}
+
+// signedInput returns the bytes covered by a signature: a header prefix
+// followed by the payload.
+func signedInput(header, payload []byte) []byte {
+	in := make([]byte, 0, len(header)+len(payload))
+	in = append(in, header...)
+	return append(in, payload...)
+}
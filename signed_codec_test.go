@@ -0,0 +1,191 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+func testSignedCodec(t *testing.T) *SignedCodec {
+	t.Helper()
+	c, err := NewSignedCodec(jsoncodec.New(), mustNewProvider(t, makeKey(32), "test-key"))
+	if err != nil {
+		t.Fatalf("NewSignedCodec: %v", err)
+	}
+	return c
+}
+
+func TestSignedCodecName(t *testing.T) {
+	c := testSignedCodec(t)
+	if c.Name() != "signed:json" {
+		t.Errorf("Name() = %q, want %q", c.Name(), "signed:json")
+	}
+}
+
+func TestNewSignedCodecValidation(t *testing.T) {
+	if _, err := NewSignedCodec(nil, mustNewProvider(t, makeKey(32), "k")); err == nil {
+		t.Error("expected error for nil inner codec")
+	}
+	if _, err := NewSignedCodec(jsoncodec.New(), nil); err == nil {
+		t.Error("expected error for nil provider")
+	}
+	if _, err := NewSignedCodec(jsoncodec.New(), &failingProvider{}); err == nil {
+		t.Error("expected error for provider without MAC support")
+	}
+}
+
+func TestSignedCodecRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	c := testSignedCodec(t)
+
+	original := map[string]any{"route": "/api", "weight": float64(3)}
+	data, err := c.Encode(ctx, original)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"route":"/api"`)) {
+		t.Errorf("signed payload should stay readable: %q", data)
+	}
+
+	var got map[string]any
+	if err := c.Decode(ctx, data, &got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got["route"] != "/api" || got["weight"] != float64(3) {
+		t.Errorf("Decode: got %v", got)
+	}
+}
+
+func TestSignedCodecTamperDetection(t *testing.T) {
+	ctx := context.Background()
+	c := testSignedCodec(t)
+
+	data, err := c.Encode(ctx, map[string]bool{"enabled": false})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	tampered := bytes.Replace(data, []byte("false"), []byte("true!"), 1)
+
+	var got map[string]bool
+	err = c.Decode(ctx, tampered, &got)
+	if !IsSignatureInvalid(err) {
+		t.Fatalf("Decode tampered: got %v, want ErrSignatureInvalid", err)
+	}
+
+	// Flip a tag byte.
+	tagged := append([]byte(nil), data...)
+	tagged[minSignedHeaderSize+len("test-key")] ^= 0xFF
+	if err := c.Decode(ctx, tagged, &got); !IsSignatureInvalid(err) {
+		t.Errorf("Decode with flipped tag: got %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestSignedCodecInnerDecodeErrorIsDistinct(t *testing.T) {
+	ctx := context.Background()
+	c := testSignedCodec(t)
+
+	data, err := c.Transform(ctx, []byte("not json"))
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	var got map[string]any
+	err = c.Decode(ctx, data, &got)
+	if err == nil {
+		t.Fatal("expected inner decode error")
+	}
+	if IsSignatureInvalid(err) {
+		t.Errorf("inner decode failure reported as signature failure: %v", err)
+	}
+}
+
+func TestSignedCodecRotation(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "key-v1", 1)
+	c, err := NewSignedCodec(jsoncodec.New(), ring)
+	if err != nil {
+		t.Fatalf("NewSignedCodec: %v", err)
+	}
+	data, err := c.Encode(ctx, "v1 value")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	v2 := makeKey(32)
+	v2[0] = 0xAA
+	if err := ring.AddKey(v2, "key-v2", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if err := ring.SetCurrentKey("key-v2"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+
+	var got string
+	if err := c.Decode(ctx, data, &got); err != nil || got != "v1 value" {
+		t.Errorf("Decode after rotation: got %q, %v", got, err)
+	}
+
+	if err := ring.RemoveKey("key-v1"); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+	if err := c.Decode(ctx, data, &got); !IsKeyNotFound(err) {
+		t.Errorf("Decode with removed key: got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestSignedCodecKeyIDSubstitution(t *testing.T) {
+	ctx := context.Background()
+	// Two IDs with identical key material must not accept each other's tags.
+	ring := mustNewKeyRingProvider(t, makeKey(32), "aaaa", 0)
+	if err := ring.AddKey(makeKey(32), "bbbb", 0); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	c, err := NewSignedCodec(jsoncodec.New(), ring)
+	if err != nil {
+		t.Fatalf("NewSignedCodec: %v", err)
+	}
+	data, err := c.Encode(ctx, "x")
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	swapped := bytes.Replace(data, []byte("aaaa"), []byte("bbbb"), 1)
+	var got string
+	if err := c.Decode(ctx, swapped, &got); !IsSignatureInvalid(err) {
+		t.Errorf("Decode with substituted key ID: got %v, want ErrSignatureInvalid", err)
+	}
+}
+
+func TestSignedCodecMalformed(t *testing.T) {
+	ctx := context.Background()
+	c := testSignedCodec(t)
+	cases := map[string][]byte{
+		"short":       []byte("SG"),
+		"bad magic":   []byte("XX\x01\x01\x00"),
+		"bad version": []byte("SG\x09\x01\x00"),
+		"bad alg":     []byte("SG\x01\x09\x00"),
+		"short tag":   []byte("SG\x01\x01\x01k123"),
+	}
+	for name, data := range cases {
+		if _, err := c.Reverse(ctx, data); !IsInvalidFormat(err) {
+			t.Errorf("%s: got %v, want ErrInvalidFormat", name, err)
+		}
+	}
+}
+
+func TestSignedCodecClosedProvider(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewProvider(makeKey(32), "k")
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	c, err := NewSignedCodec(jsoncodec.New(), p)
+	if err != nil {
+		t.Fatalf("NewSignedCodec: %v", err)
+	}
+	_ = p.Close()
+	if _, err := c.Encode(ctx, "x"); !errors.Is(err, ErrProviderClosed) {
+		t.Errorf("Encode after Close: got %v, want ErrProviderClosed", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package sshagent provides a crypto.Provider whose KEKs are derived from
+// SSH keys held in ssh-agent, so developers with existing SSH keys can
+// decrypt shared encrypted config without distributing separate key files.
+//
+// The agent signs a fixed context string with the selected key, and the
+// KEK is derived from the signature with HKDF-SHA256. This only works for
+// key types whose signatures are deterministic: Ed25519, and RSA with
+// rsa-sha2-256 (PKCS#1 v1.5). ECDSA and FIDO security keys (sk-*) sign with
+// fresh randomness or a counter and are rejected.
+//
+//	a, conn, err := sshagent.Dial() // $SSH_AUTH_SOCK
+//	defer conn.Close()
+//	provider, err := sshagent.New(ctx, a,
+//	    sshagent.WithKey("SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s", "alice"),
+//	)
+//
+// The fingerprint is the one "ssh-add -l" prints. Anyone who can use the
+// agent can derive the KEK, including hosts the agent is forwarded to.
+// Each SSH key derives a different KEK; crypto.WithEscrow adds a second
+// developer's key as a recipient of every value.
+package sshagent
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// DefaultContext is the string signed to derive a KEK unless
+// WithDerivationContext is used.
+const DefaultContext = "config-crypto ssh-agent kek v1"
+
+// hkdfInfo is the HKDF info for KEKs derived from agent signatures.
+const hkdfInfo = "config-crypto/ssh-agent"
+
+// Agent is the subset of agent.ExtendedAgent the provider uses. The client
+// returned by agent.NewClient satisfies it.
+type Agent interface {
+	// List returns the identities the agent holds.
+	List() ([]*agent.Key, error)
+
+	// SignWithFlags signs data with the key.
+	SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error)
+}
+
+// Dial connects to the agent listening on $SSH_AUTH_SOCK. The caller must
+// close the returned connection when done.
+func Dial() (Agent, io.Closer, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, fmt.Errorf("sshagent: SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sshagent: %w", err)
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+// Option configures the ssh-agent provider.
+type Option func(*options)
+
+type options struct {
+	keys    []agentKey
+	context string
+}
+
+type agentKey struct {
+	fingerprint string
+	id          string
+}
+
+// WithKey registers the KEK derived from the agent key with the given
+// SHA256 fingerprint ("SHA256:..."). The id identifies this key in the
+// config-crypto system; an empty id uses the fingerprint.
+//
+// The first call to WithKey sets the current key used for new encryptions.
+// Subsequent calls register additional keys for decryption during key
+// rotation.
+func WithKey(fingerprint, id string) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, agentKey{fingerprint: fingerprint, id: id})
+	}
+}
+
+// WithDerivationContext sets the string the agent signs. Different
+// contexts derive unrelated KEKs from the same SSH key, e.g. one per
+// project. Defaults to DefaultContext.
+func WithDerivationContext(s string) Option {
+	return func(o *options) {
+		o.context = s
+	}
+}
+
+// New creates a crypto.KeyRingProvider from KEKs derived with SSH keys in
+// the agent.
+//
+// At least one key must be provided via WithKey. The first key is the
+// current key for new encryptions; additional keys support decryption
+// during key rotation.
+//
+// KEKs are derived during construction and cached. The agent is not used
+// after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, a Agent, opts ...Option) (crypto.KeyRingProvider, error) {
+	if a == nil {
+		return nil, fmt.Errorf("sshagent: Agent must not be nil")
+	}
+	o := options{context: DefaultContext}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(o.keys) == 0 {
+		return nil, fmt.Errorf("sshagent: at least one key is required")
+	}
+
+	held, err := a.List()
+	if err != nil {
+		return nil, fmt.Errorf("sshagent: list keys: %w", err)
+	}
+	byFingerprint := make(map[string]*agent.Key, len(held))
+	for _, k := range held {
+		byFingerprint[ssh.FingerprintSHA256(k)] = k
+	}
+
+	return kmsring.Build(len(o.keys), "sshagent", func(i int) ([]byte, string, error) {
+		k := o.keys[i]
+		id := k.id
+		if id == "" {
+			id = k.fingerprint
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, id, err
+		}
+		pub, ok := byFingerprint[k.fingerprint]
+		if !ok {
+			return nil, id, fmt.Errorf("%w: agent holds no key %s", crypto.ErrKeyNotFound, k.fingerprint)
+		}
+		key, err := deriveKEK(a, pub, o.context)
+		return key, id, err
+	})
+}
+
+// deriveKEK has the agent sign msg with pub and derives a KEK from the
+// signature.
+func deriveKEK(a Agent, pub ssh.PublicKey, msg string) ([]byte, error) {
+	var flags agent.SignatureFlags
+	switch pub.Type() {
+	case ssh.KeyAlgoED25519:
+	case ssh.KeyAlgoRSA:
+		flags = agent.SignatureFlagRsaSha256
+	default:
+		return nil, fmt.Errorf("key type %s does not sign deterministically; use an Ed25519 or RSA key", pub.Type())
+	}
+
+	data := []byte(msg)
+	sig, err := a.SignWithFlags(pub, data, flags)
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+	defer clear(sig.Blob)
+	if err := pub.Verify(data, sig); err != nil {
+		return nil, fmt.Errorf("agent returned an invalid signature: %w", err)
+	}
+	return hkdf.Key(sha256.New, sig.Blob, pub.Marshal(), hkdfInfo, kmsring.KeySize)
+}
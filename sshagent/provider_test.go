@@ -0,0 +1,131 @@
+package sshagent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// newKeyring returns an in-memory agent holding priv and the fingerprint
+// of its public key.
+func newKeyring(t *testing.T, priv any) (agent.Agent, string) {
+	t.Helper()
+	a := agent.NewKeyring()
+	if err := a.Add(agent.AddedKey{PrivateKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := a.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, ssh.FingerprintSHA256(keys[0])
+}
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestNew_DeterministicKEK(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, priv := range map[string]any{"ed25519": newEd25519(t), "rsa": rsaKey} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			a, fp := newKeyring(t, priv)
+
+			p1, err := New(ctx, a.(Agent), WithKey(fp, "dev"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p1.Close()
+			ct, err := p1.Encrypt(ctx, []byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p2, err := New(ctx, a.(Agent), WithKey(fp, "dev"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p2.Close()
+			if pt, err := p2.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+				t.Errorf("Decrypt = %q, %v", pt, err)
+			}
+
+			p3, err := New(ctx, a.(Agent), WithKey(fp, "dev"), WithDerivationContext("other project"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p3.Close()
+			if _, err := p3.Decrypt(ctx, ct); !crypto.IsDecryptionFailed(err) {
+				t.Errorf("Decrypt under another context: got %v, want ErrDecryptionFailed", err)
+			}
+		})
+	}
+}
+
+func TestNew_EmptyIDUsesFingerprint(t *testing.T) {
+	a, fp := newKeyring(t, newEd25519(t))
+	p, err := New(context.Background(), a.(Agent), WithKey(fp, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != fp {
+		t.Errorf("CurrentKeyID = %q, want %q", p.CurrentKeyID(), fp)
+	}
+}
+
+func TestNew_RejectsECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, fp := newKeyring(t, priv)
+	_, err = New(context.Background(), a.(Agent), WithKey(fp, "dev"))
+	if err == nil || !strings.Contains(err.Error(), "deterministically") {
+		t.Errorf("expected non-deterministic key type error, got %v", err)
+	}
+}
+
+func TestNew_UnknownFingerprint(t *testing.T) {
+	a, _ := newKeyring(t, newEd25519(t))
+	_, err := New(context.Background(), a.(Agent), WithKey("SHA256:nope", "dev"))
+	if !crypto.IsKeyNotFound(err) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(context.Background(), nil, WithKey("SHA256:x", "dev")); err == nil {
+		t.Error("expected error for nil agent")
+	}
+	a, _ := newKeyring(t, newEd25519(t))
+	if _, err := New(context.Background(), a.(Agent)); err == nil {
+		t.Error("expected error when no keys are configured")
+	}
+}
+
+func TestDialWithoutSocket(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, _, err := Dial(); err == nil {
+		t.Error("expected error without SSH_AUTH_SOCK")
+	}
+}
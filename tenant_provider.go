@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// tenantKey is the unexported context key type for the tenant ID.
+type tenantKey struct{}
+
+// TenantContextKey is the context key used to pass the tenant ID to a
+// provider from NewTenantProvider. Set it with:
+//
+//	ctx = crypto.WithTenant(ctx, "acme")
+var TenantContextKey = tenantKey{}
+
+// WithTenant returns a new context carrying the given tenant ID.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, TenantContextKey, tenant)
+}
+
+// TenantFromContext extracts the tenant ID previously set by WithTenant.
+// Returns an empty string if no tenant is present.
+func TenantFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(TenantContextKey).(string)
+	return t
+}
+
+// TenantKeyLookup returns the Provider holding a tenant's keys. It should
+// return an error wrapping ErrKeyNotFound for unknown tenants. It is called
+// on every operation, so it should be cheap or cache its results.
+type TenantKeyLookup func(ctx context.Context, tenant string) (Provider, error)
+
+// tenantProvider routes each operation to the provider of the tenant in ctx.
+type tenantProvider struct {
+	lookup TenantKeyLookup
+	closed atomic.Bool
+}
+
+// NewTenantProvider returns a Provider that encrypts and decrypts with the
+// keys of the tenant named in the context (see WithTenant), resolved through
+// lookup. One Codec built on it serves every tenant:
+//
+//	codec, _ := crypto.NewCodec(jsoncodec.New(), crypto.NewTenantProvider(lookup))
+//	data, _ := codec.Encode(crypto.WithTenant(ctx, "acme"), cfg)
+//
+// Operations without a tenant in ctx fail with ErrNoTenant. Close does not
+// close the providers returned by lookup. Do not combine it with
+// WithDecodeCache: the cache is keyed by ciphertext alone, so it would serve
+// one tenant's plaintext to a request made for another.
+//
+// For cryptographic erasure, give each tenant its own key, e.g. with
+// SelectorTenantKeys; deleting the key (and every stored copy of it) makes
+// that tenant's ciphertexts unrecoverable. Keys from DerivedTenantKeys
+// cannot be erased individually, because they can always be re-derived
+// from the master.
+func NewTenantProvider(lookup TenantKeyLookup) (Provider, error) {
+	if lookup == nil {
+		return nil, errors.New("crypto: NewTenantProvider lookup is nil")
+	}
+	return &tenantProvider{lookup: lookup}, nil
+}
+
+// resolve returns the provider of the tenant in ctx.
+func (p *tenantProvider) resolve(ctx context.Context) (Provider, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return nil, ErrNoTenant
+	}
+	tp, err := p.lookup(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: tenant %q: %w", tenant, err)
+	}
+	if tp == nil {
+		return nil, fmt.Errorf("%w: tenant %q", ErrKeyNotFound, tenant)
+	}
+	return tp, nil
+}
+
+// Name returns "tenant".
+func (p *tenantProvider) Name() string { return "tenant" }
+
+// Connect is a no-op.
+func (p *tenantProvider) Connect(_ context.Context) error { return nil }
+
+// Encrypt encrypts plaintext with the provider of the tenant in ctx.
+func (p *tenantProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	tp, err := p.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tp.Encrypt(ctx, plaintext)
+}
+
+// Decrypt decrypts ciphertext with the provider of the tenant in ctx.
+func (p *tenantProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.DecryptTo(ctx, nil, ciphertext)
+}
+
+// DecryptTo decrypts ciphertext and appends the plaintext to dst.
+func (p *tenantProvider) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	tp, err := p.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptTo(ctx, dst, ciphertext, tp)
+}
+
+// HealthCheck checks the provider of the tenant in ctx, if any; without a
+// tenant it only reports whether p is closed.
+func (p *tenantProvider) HealthCheck(ctx context.Context) error {
+	if TenantFromContext(ctx) == "" {
+		if p.closed.Load() {
+			return ErrProviderClosed
+		}
+		return nil
+	}
+	tp, err := p.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	return tp.HealthCheck(ctx)
+}
+
+// Close stops p; the tenants' providers stay open. Safe to call multiple
+// times.
+func (p *tenantProvider) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+// DerivedTenantKeys returns a TenantKeyLookup that derives each tenant's
+// keys from master with HKDF, as NewDerivingKeyProvider does with the label
+// "tenant/<id>". No per-tenant key management is needed, but tenants cannot
+// be crypto-erased individually.
+func DerivedTenantKeys(master KeyRingProvider) (TenantKeyLookup, error) {
+	if _, ok := master.(*keyRingProvider); !ok {
+		return nil, fmt.Errorf("crypto: DerivedTenantKeys: %T does not expose key material", master)
+	}
+	return func(_ context.Context, tenant string) (Provider, error) {
+		return NewDerivingKeyProvider(master, "tenant/"+tenant)
+	}, nil
+}
+
+// SelectorTenantKeys returns a TenantKeyLookup that uses the provider
+// registered in sel under the tenant ID as namespace. Register a provider
+// with its own key per tenant; RemoveAndClose then wipes a removed tenant's
+// key from memory.
+func SelectorTenantKeys(sel *NamespaceSelector) TenantKeyLookup {
+	return func(_ context.Context, tenant string) (Provider, error) {
+		return sel.ForNamespace(tenant), nil
+	}
+}
@@ -0,0 +1,78 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTenantProviderDerived(t *testing.T) {
+	ctx := context.Background()
+	master := mustNewKeyRingProvider(t, makeKey(32), "m1", 0)
+	lookup, err := DerivedTenantKeys(master)
+	if err != nil {
+		t.Fatalf("DerivedTenantKeys: %v", err)
+	}
+	p, err := NewTenantProvider(lookup)
+	if err != nil {
+		t.Fatalf("NewTenantProvider: %v", err)
+	}
+	defer p.Close()
+
+	acme := WithTenant(ctx, "acme")
+	ct, err := p.Encrypt(acme, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if id, _ := KeyIDOf(ct); id != "m1#tenant/acme" {
+		t.Errorf("key ID = %q", id)
+	}
+	if got, err := p.Decrypt(acme, ct); err != nil || string(got) != "secret" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if _, err := p.Decrypt(WithTenant(ctx, "globex"), ct); err == nil {
+		t.Error("another tenant decrypted acme's value")
+	}
+	if _, err := p.Encrypt(ctx, []byte("x")); !IsNoTenant(err) {
+		t.Errorf("Encrypt without tenant: got %v, want ErrNoTenant", err)
+	}
+}
+
+func TestTenantProviderErasure(t *testing.T) {
+	ctx := context.Background()
+	keyA := makeKey(32)
+	keyB := makeKey(32)
+	keyB[0] = 0xFF
+	pa, _ := NewProvider(keyA, "acme-v1")
+	pb, _ := NewProvider(keyB, "globex-v1")
+	sel, err := NewNamespaceSelector(WithNamespaceProvider("acme", pa), WithNamespaceProvider("globex", pb))
+	if err != nil {
+		t.Fatalf("NewNamespaceSelector: %v", err)
+	}
+	defer sel.Close()
+
+	p, err := NewTenantProvider(SelectorTenantKeys(sel))
+	if err != nil {
+		t.Fatalf("NewTenantProvider: %v", err)
+	}
+	acme := WithTenant(ctx, "acme")
+	ct, err := p.Encrypt(acme, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	// Deleting the tenant's key makes its data unreadable; others are unaffected.
+	if err := sel.RemoveAndClose("acme"); err != nil {
+		t.Fatalf("RemoveAndClose: %v", err)
+	}
+	if _, err := p.Decrypt(acme, ct); err == nil {
+		t.Error("Decrypt succeeded after the tenant's key was removed")
+	}
+	if _, err := p.Encrypt(WithTenant(ctx, "globex"), []byte("x")); err != nil {
+		t.Errorf("other tenant Encrypt: %v", err)
+	}
+
+	_ = p.Close()
+	if _, err := p.Encrypt(WithTenant(ctx, "globex"), []byte("x")); !IsProviderClosed(err) {
+		t.Errorf("Encrypt after Close: got %v, want ErrProviderClosed", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package testvectors emits and verifies golden test vectors for the
+// config-crypto envelope format.
+//
+// A Vector pins every input to one encryption — KEK, key ID, DEK, both
+// nonces, and plaintext — together with the exact ciphertext this package
+// produces for them. Implementations of the format in other languages can
+// load the vectors and check that they decrypt each ciphertext and, given
+// the same DEK and nonces, produce byte-identical output.
+//
+// Usage:
+//
+//	vs, _ := testvectors.Canonical()
+//	_ = testvectors.Write(os.Stdout, vs) // JSON, byte fields hex-encoded
+//
+//	// Later, or in another release:
+//	vs, _ := testvectors.Read(f)
+//	for _, v := range vs {
+//	    if err := testvectors.Verify(ctx, v); err != nil { ... }
+//	}
+package testvectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// ErrMismatch is returned by Verify when the computed ciphertext or the
+// decrypted plaintext differs from the vector.
+var ErrMismatch = errors.New("testvectors: mismatch")
+
+// Hex is a byte slice that marshals to and from a lowercase hex string.
+type Hex []byte
+
+// MarshalText encodes h as lowercase hex.
+func (h Hex) MarshalText() ([]byte, error) {
+	out := make([]byte, hex.EncodedLen(len(h)))
+	hex.Encode(out, h)
+	return out, nil
+}
+
+// UnmarshalText decodes hex text into h.
+func (h *Hex) UnmarshalText(text []byte) error {
+	b := make([]byte, hex.DecodedLen(len(text)))
+	if _, err := hex.Decode(b, text); err != nil {
+		return fmt.Errorf("testvectors: %w", err)
+	}
+	*h = b
+	return nil
+}
+
+// Vector is one golden envelope-encryption test case.
+type Vector struct {
+	// Name is a short description of the case.
+	Name string `json:"name"`
+
+	// KeyID is the KEK identifier written into the header.
+	KeyID string `json:"key_id"`
+
+	// KEK is the 32-byte AES-256 key encryption key.
+	KEK Hex `json:"kek"`
+
+	// DEK is the 32-byte data encryption key.
+	DEK Hex `json:"dek"`
+
+	// DEKNonce is the 12-byte nonce used to wrap the DEK.
+	DEKNonce Hex `json:"dek_nonce"`
+
+	// DataNonce is the 12-byte nonce used to encrypt the plaintext.
+	DataNonce Hex `json:"data_nonce"`
+
+	// Plaintext is the data being encrypted.
+	Plaintext Hex `json:"plaintext"`
+
+	// Ciphertext is the expected envelope output.
+	Ciphertext Hex `json:"ciphertext"`
+}
+
+// Generate builds a Vector by encrypting plaintext with the given KEK,
+// DEK, and nonces, filling in Ciphertext.
+func Generate(ctx context.Context, name, keyID string, kek, dek, dekNonce, dataNonce, plaintext []byte) (Vector, error) {
+	v := Vector{
+		Name:      name,
+		KeyID:     keyID,
+		KEK:       bytes.Clone(kek),
+		DEK:       bytes.Clone(dek),
+		DEKNonce:  bytes.Clone(dekNonce),
+		DataNonce: bytes.Clone(dataNonce),
+		Plaintext: bytes.Clone(plaintext),
+	}
+	ct, err := v.encrypt(ctx)
+	if err != nil {
+		return Vector{}, err
+	}
+	v.Ciphertext = ct
+	return v, nil
+}
+
+// Verify checks that encrypting v's inputs reproduces v.Ciphertext exactly
+// and that decrypting v.Ciphertext with v.KEK yields v.Plaintext. It returns
+// an error wrapping ErrMismatch if either differs.
+func Verify(ctx context.Context, v Vector) error {
+	ct, err := v.encrypt(ctx)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ct, v.Ciphertext) {
+		return fmt.Errorf("%w: %s: ciphertext differs", ErrMismatch, v.Name)
+	}
+
+	p, err := crypto.NewProvider(v.KEK, v.KeyID)
+	if err != nil {
+		return fmt.Errorf("testvectors: %s: %w", v.Name, err)
+	}
+	defer func() { _ = p.Close() }()
+	pt, err := p.Decrypt(ctx, v.Ciphertext)
+	if err != nil {
+		return fmt.Errorf("testvectors: %s: %w", v.Name, err)
+	}
+	if !bytes.Equal(pt, v.Plaintext) {
+		return fmt.Errorf("%w: %s: plaintext differs", ErrMismatch, v.Name)
+	}
+	return nil
+}
+
+// encrypt runs the envelope encryption with v's DEK and nonces supplied as
+// the provider's randomness, in the order the provider consumes them.
+func (v Vector) encrypt(ctx context.Context) ([]byte, error) {
+	random := bytes.NewReader(bytes.Join([][]byte{v.DEK, v.DEKNonce, v.DataNonce}, nil))
+	p, err := crypto.NewProvider(v.KEK, v.KeyID, crypto.WithRandReader(random))
+	if err != nil {
+		return nil, fmt.Errorf("testvectors: %s: %w", v.Name, err)
+	}
+	defer func() { _ = p.Close() }()
+	ct, err := p.Encrypt(ctx, v.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("testvectors: %s: %w", v.Name, err)
+	}
+	if random.Len() != 0 {
+		return nil, fmt.Errorf("testvectors: %s: %d unused random bytes; check DEK and nonce sizes", v.Name, random.Len())
+	}
+	return ct, nil
+}
+
+// Canonical returns the standard vector set for the current format: an
+// empty plaintext, a short ASCII value, a JSON document, a maximum-length
+// key ID, and a multi-block binary payload.
+func Canonical() ([]Vector, error) {
+	seq := func(n int, start byte) []byte {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = start + byte(i)
+		}
+		return b
+	}
+	kek := seq(32, 0x00)
+	dek := seq(32, 0x40)
+	dekNonce := seq(12, 0x80)
+	dataNonce := seq(12, 0xA0)
+
+	cases := []struct {
+		name      string
+		keyID     string
+		plaintext []byte
+	}{
+		{"empty plaintext", "key-1", []byte{}},
+		{"short string", "key-1", []byte(`"hello"`)},
+		{"json document", "prod/2024-01", []byte(`{"host":"db.internal","password":"hunter2","port":5432}`)},
+		{"max key id", string(bytes.Repeat([]byte("k"), 255)), []byte("x")},
+		{"binary payload", "key-1", seq(100, 0xF0)},
+	}
+
+	ctx := context.Background()
+	vs := make([]Vector, 0, len(cases))
+	for _, c := range cases {
+		v, err := Generate(ctx, c.name, c.keyID, kek, dek, dekNonce, dataNonce, c.plaintext)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+// Write encodes vs as indented JSON.
+func Write(w io.Writer, vs []Vector) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(vs); err != nil {
+		return fmt.Errorf("testvectors: encode: %w", err)
+	}
+	return nil
+}
+
+// Read decodes vectors written by Write.
+func Read(r io.Reader) ([]Vector, error) {
+	var vs []Vector
+	if err := json.NewDecoder(r).Decode(&vs); err != nil {
+		return nil, fmt.Errorf("testvectors: decode: %w", err)
+	}
+	return vs, nil
+}
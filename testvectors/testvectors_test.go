@@ -0,0 +1,110 @@
+package testvectors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata golden files")
+
+const goldenFile = "envelope_v2.json"
+
+func TestCanonicalMatchesGolden(t *testing.T) {
+	vs, err := Canonical()
+	if err != nil {
+		t.Fatalf("Canonical: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, vs); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	path := filepath.Join("testdata", goldenFile)
+	if *update {
+		if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("canonical vectors differ from %s; the envelope format changed (run with -update only if intended)", path)
+	}
+}
+
+func TestVerifyGolden(t *testing.T) {
+	ctx := context.Background()
+	f, err := os.Open(filepath.Join("testdata", goldenFile))
+	if err != nil {
+		t.Fatalf("open golden: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	vs, err := Read(f)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(vs) == 0 {
+		t.Fatal("no vectors in golden file")
+	}
+	for _, v := range vs {
+		if err := Verify(ctx, v); err != nil {
+			t.Errorf("Verify(%s): %v", v.Name, err)
+		}
+	}
+}
+
+func TestVerifyDetectsMismatch(t *testing.T) {
+	ctx := context.Background()
+	vs, err := Canonical()
+	if err != nil {
+		t.Fatalf("Canonical: %v", err)
+	}
+	v := vs[1]
+	v.Ciphertext = bytes.Clone(v.Ciphertext)
+	v.Ciphertext[len(v.Ciphertext)-1] ^= 0x01
+	if err := Verify(ctx, v); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify tampered ciphertext: got %v, want ErrMismatch", err)
+	}
+
+	v = vs[1]
+	v.Plaintext = []byte("different")
+	if err := Verify(ctx, v); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Verify changed plaintext: got %v, want ErrMismatch", err)
+	}
+}
+
+func TestGenerateRejectsBadSizes(t *testing.T) {
+	ctx := context.Background()
+	kek := make([]byte, 32)
+	if _, err := Generate(ctx, "short dek", "k", kek, make([]byte, 16), make([]byte, 12), make([]byte, 12), nil); err == nil {
+		t.Error("expected error for short DEK")
+	}
+	if _, err := Generate(ctx, "long nonce", "k", kek, make([]byte, 32), make([]byte, 12), make([]byte, 16), nil); err == nil {
+		t.Error("expected error for leftover randomness")
+	}
+}
+
+func TestHexRoundTrip(t *testing.T) {
+	var h Hex
+	if err := h.UnmarshalText([]byte("00ff10")); err != nil {
+		t.Fatalf("UnmarshalText: %v", err)
+	}
+	if !bytes.Equal(h, []byte{0x00, 0xff, 0x10}) {
+		t.Errorf("UnmarshalText = %x", []byte(h))
+	}
+	text, _ := h.MarshalText()
+	if string(text) != "00ff10" {
+		t.Errorf("MarshalText = %s", text)
+	}
+	if err := h.UnmarshalText([]byte("zz")); err == nil {
+		t.Error("expected error for invalid hex")
+	}
+}
@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AuthMethod logs HTTPClient in to Vault. Login is called for the first
+// request, and again whenever the token can no longer be renewed or Vault
+// rejects it, so implementations should read credentials that may rotate,
+// such as a projected service account token, on every call.
+type AuthMethod interface {
+	// Login returns the login endpoint, relative to /v1/ (for example
+	// "auth/approle/login"), and the JSON body to POST to it.
+	Login(ctx context.Context) (path string, body map[string]any, err error)
+}
+
+// AppRoleAuth logs in with the AppRole auth method.
+type AppRoleAuth struct {
+	// Mount is the auth method's mount path. Defaults to "approle".
+	Mount string
+
+	// RoleID is the role's role_id.
+	RoleID string
+
+	// SecretID is the secret_id. If empty, it is read from SecretIDFile.
+	SecretID string
+
+	// SecretIDFile is a file holding the secret_id, as written by a
+	// response-wrapping unwrapper or Vault Agent. Read on every login.
+	SecretIDFile string
+}
+
+// Login implements AuthMethod.
+func (a AppRoleAuth) Login(context.Context) (string, map[string]any, error) {
+	if a.RoleID == "" {
+		return "", nil, errors.New("vault: AppRoleAuth: RoleID must not be empty")
+	}
+	body := map[string]any{"role_id": a.RoleID}
+	secretID := a.SecretID
+	if secretID == "" && a.SecretIDFile != "" {
+		b, err := os.ReadFile(a.SecretIDFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("vault: AppRoleAuth: %w", err)
+		}
+		secretID = strings.TrimSpace(string(b))
+	}
+	if secretID != "" {
+		body["secret_id"] = secretID
+	}
+	return "auth/" + orDefault(a.Mount, "approle") + "/login", body, nil
+}
+
+// defaultServiceAccountToken is where Kubernetes mounts a pod's service
+// account token.
+const defaultServiceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token" // #nosec G101 -- a path, not a credential
+
+// KubernetesAuth logs in with the Kubernetes auth method using the pod's
+// service account token.
+type KubernetesAuth struct {
+	// Mount is the auth method's mount path. Defaults to "kubernetes".
+	Mount string
+
+	// Role is the Vault role to log in as.
+	Role string
+
+	// TokenFile is the service account token file, read on every login
+	// because Kubernetes rotates projected tokens. Defaults to the pod's
+	// mounted service account token.
+	TokenFile string
+}
+
+// Login implements AuthMethod.
+func (a KubernetesAuth) Login(context.Context) (string, map[string]any, error) {
+	if a.Role == "" {
+		return "", nil, errors.New("vault: KubernetesAuth: Role must not be empty")
+	}
+	jwt, err := os.ReadFile(orDefault(a.TokenFile, defaultServiceAccountToken))
+	if err != nil {
+		return "", nil, fmt.Errorf("vault: KubernetesAuth: %w", err)
+	}
+	return "auth/" + orDefault(a.Mount, "kubernetes") + "/login",
+		map[string]any{"role": a.Role, "jwt": strings.TrimSpace(string(jwt))}, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// TransitResult is the outcome of decrypting one item of a batch.
+type TransitResult struct {
+	Plaintext []byte
+	Err       error
+}
+
+// BatchTransitClient extends TransitClient with Transit's batch decrypt,
+// which NewTransit uses to decrypt every key encrypted with the same
+// Transit key in one round trip. Implement it by sending batch_input:
+//
+//	func (c *myVault) TransitDecryptBatch(ctx context.Context, mount, keyName string, ciphertexts []string) ([]vault.TransitResult, error) {
+//	    items := make([]map[string]any, len(ciphertexts))
+//	    for i, ct := range ciphertexts { items[i] = map[string]any{"ciphertext": ct} }
+//	    s, err := c.api.Logical().WriteWithContext(ctx, mount+"/decrypt/"+keyName,
+//	        map[string]any{"batch_input": items})
+//	    if err != nil { return nil, err }
+//	    results := make([]vault.TransitResult, len(ciphertexts))
+//	    for i, r := range s.Data["batch_results"].([]any) {
+//	        m := r.(map[string]any)
+//	        if msg, _ := m["error"].(string); msg != "" {
+//	            results[i].Err = errors.New(msg)
+//	            continue
+//	        }
+//	        results[i].Plaintext, results[i].Err = base64.StdEncoding.DecodeString(m["plaintext"].(string))
+//	    }
+//	    return results, nil
+//	}
+type BatchTransitClient interface {
+	TransitClient
+
+	// TransitDecryptBatch decrypts ciphertexts with keyName in a single
+	// request and returns one result per ciphertext, in order. Vault
+	// reports failures per item; err is for the request as a whole.
+	TransitDecryptBatch(ctx context.Context, mount, keyName string, ciphertexts []string) ([]TransitResult, error)
+}
+
+// decryptTransitKeys decrypts every entry, one batch per Transit key if
+// client supports it and one call per entry otherwise, and returns the
+// results in entry order.
+func decryptTransitKeys(ctx context.Context, client TransitClient, mount string, entries []transitKeyEntry) []TransitResult {
+	results := make([]TransitResult, len(entries))
+	bc, ok := client.(BatchTransitClient)
+	if !ok {
+		_ = kmsring.ForEach(len(entries), func(i int) error {
+			e := entries[i]
+			results[i].Plaintext, results[i].Err = client.TransitDecrypt(ctx, mount, e.keyName, e.ciphertext)
+			return nil
+		})
+		return results
+	}
+
+	var names []string
+	byName := make(map[string][]int)
+	for i, e := range entries {
+		if _, seen := byName[e.keyName]; !seen {
+			names = append(names, e.keyName)
+		}
+		byName[e.keyName] = append(byName[e.keyName], i)
+	}
+	_ = kmsring.ForEach(len(names), func(n int) error {
+		idx := byName[names[n]]
+		ciphertexts := make([]string, len(idx))
+		for j, i := range idx {
+			ciphertexts[j] = entries[i].ciphertext
+		}
+		batch, err := bc.TransitDecryptBatch(ctx, mount, names[n], ciphertexts)
+		if err == nil && len(batch) != len(idx) {
+			for _, r := range batch {
+				clear(r.Plaintext)
+			}
+			err = fmt.Errorf("batch decrypt returned %d results for %d ciphertexts", len(batch), len(idx))
+		}
+		for j, i := range idx {
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			results[i] = batch[j]
+		}
+		return nil
+	})
+	return results
+}
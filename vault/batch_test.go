@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// batchTransitMock is a transitMock that also decrypts in batches.
+type batchTransitMock struct {
+	transitMock
+	batches  int
+	failItem int // index of an item to fail in every batch, or -1
+}
+
+func (m *batchTransitMock) TransitDecryptBatch(ctx context.Context, mount, keyName string, ciphertexts []string) ([]TransitResult, error) {
+	m.mu.Lock()
+	m.batches++
+	m.mu.Unlock()
+	results := make([]TransitResult, len(ciphertexts))
+	for i, ct := range ciphertexts {
+		if i == m.failItem {
+			results[i].Err = errors.New("transit: cipher: message authentication failed")
+			continue
+		}
+		results[i].Plaintext, results[i].Err = m.TransitDecrypt(ctx, mount, keyName, ct)
+	}
+	return results, nil
+}
+
+func transitEncrypt(t *testing.T, c TransitClient, keyName string, key []byte) string {
+	t.Helper()
+	ct, err := c.TransitEncrypt(context.Background(), "transit", keyName, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func TestNewTransit_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	tr := &transitMock{mount: "transit", keys: map[string]int{"config": 1}}
+	ring, err := NewTransit(ctx, tr, "transit",
+		WithTransitKey(transitEncrypt(t, tr, "config", mkKey(1)), "key-1", "config"),
+		WithTransitKey(transitEncrypt(t, tr, "config", mkKey(2)), "key-0", "config"))
+	if err != nil {
+		t.Fatalf("NewTransit: %v", err)
+	}
+	defer ring.Close()
+	if ring.CurrentKeyID() != "key-1" {
+		t.Errorf("CurrentKeyID = %q, want key-1", ring.CurrentKeyID())
+	}
+	if tr.decrypts != 2 {
+		t.Errorf("TransitDecrypt calls = %d, want 2", tr.decrypts)
+	}
+	ct, err := ring.Encrypt(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := ring.Decrypt(ctx, ct); err != nil || string(pt) != "hello" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNewTransit_BatchesPerTransitKey(t *testing.T) {
+	tr := &batchTransitMock{transitMock: transitMock{mount: "transit", keys: map[string]int{"a": 1, "b": 3}}, failItem: -1}
+	var opts []Option
+	for i := range 5 {
+		name := "a"
+		if i%2 == 1 {
+			name = "b"
+		}
+		opts = append(opts, WithTransitKey(transitEncrypt(t, tr, name, mkKey(byte(i))), string(rune('0'+i)), name))
+	}
+	ring, err := NewTransit(context.Background(), tr, "transit", opts...)
+	if err != nil {
+		t.Fatalf("NewTransit: %v", err)
+	}
+	defer ring.Close()
+	if tr.batches != 2 || tr.decrypts != 5 {
+		t.Errorf("batches = %d, items = %d, want 2 and 5", tr.batches, tr.decrypts)
+	}
+	if ring.CurrentKeyID() != "0" {
+		t.Errorf("CurrentKeyID = %q, want 0", ring.CurrentKeyID())
+	}
+}
+
+func TestNewTransit_BatchItemError(t *testing.T) {
+	tr := &batchTransitMock{transitMock: transitMock{mount: "transit", keys: map[string]int{"a": 1}}, failItem: 1}
+	_, err := NewTransit(context.Background(), tr, "transit",
+		WithTransitKey(transitEncrypt(t, tr, "a", mkKey(1)), "key-1", "a"),
+		WithTransitKey(transitEncrypt(t, tr, "a", mkKey(2)), "key-2", "a"))
+	if err == nil || !strings.Contains(err.Error(), `"key-2"`) || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("expected error for key-2, got %v", err)
+	}
+}
+
+func TestNewTransit_ValidationErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewTransit(ctx, nil, "transit"); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := NewTransit(ctx, &transitMock{}, ""); err == nil {
+		t.Error("expected error for empty mount")
+	}
+	if _, err := NewTransit(ctx, &transitMock{}, "transit"); err == nil {
+		t.Error("expected error for no keys")
+	}
+}
@@ -0,0 +1,116 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+)
+
+// DerivedTransitClient extends TransitClient for Transit keys created with
+// derived=true, which need a context with every encrypt and decrypt. Keys
+// that also set convergent_encryption=true derive the nonce from the
+// plaintext and context, so the same key under the same context always
+// encrypts to the same ciphertext. Implement it by adding the context to
+// the request:
+//
+//	func (c *myVault) TransitEncryptDerived(ctx context.Context, mount, keyName string, plaintext, keyContext []byte) (string, error) {
+//	    s, err := c.api.Logical().WriteWithContext(ctx, mount+"/encrypt/"+keyName, map[string]any{
+//	        "plaintext": base64.StdEncoding.EncodeToString(plaintext),
+//	        "context":   base64.StdEncoding.EncodeToString(keyContext),
+//	    })
+//	    if err != nil { return "", err }
+//	    return s.Data["ciphertext"].(string), nil
+//	}
+//
+// Pass it to NewDerivedTransitClient to use it wherever a TransitClient is
+// taken.
+type DerivedTransitClient interface {
+	TransitClient
+
+	// TransitEncryptDerived is TransitEncrypt with keyContext as the
+	// key derivation context.
+	TransitEncryptDerived(ctx context.Context, mount, keyName string, plaintext, keyContext []byte) (ciphertext string, err error)
+
+	// TransitDecryptDerived is TransitDecrypt with keyContext as the key
+	// derivation context.
+	TransitDecryptDerived(ctx context.Context, mount, keyName, ciphertext string, keyContext []byte) (plaintext []byte, err error)
+}
+
+// DerivedBatchTransitClient extends DerivedTransitClient with batch
+// decrypt, sending keyContext with every item.
+type DerivedBatchTransitClient interface {
+	DerivedTransitClient
+
+	// TransitDecryptBatchDerived is TransitDecryptBatch with keyContext as
+	// every item's key derivation context.
+	TransitDecryptBatchDerived(ctx context.Context, mount, keyName string, ciphertexts []string, keyContext []byte) ([]TransitResult, error)
+}
+
+// NewDerivedTransitClient returns a TransitClient that sends keyContext with
+// every Transit call made through client, for keys created with
+// derived=true. Use it with NewTransit, NewTransitRemote, and
+// GenerateWrappedKey:
+//
+//	tc, err := vault.NewDerivedTransitClient(client, []byte("config-crypto/prod"))
+//	ciphertext, ring, err := vault.GenerateWrappedKey(ctx, tc, "transit", "config", "")
+//
+// With convergent_encryption=true on the key, wrapping the same KEK or DEK
+// again yields the same ciphertext, so stored key blobs can be
+// deduplicated. The same context must be used to decrypt. If client
+// implements DerivedBatchTransitClient, the result implements
+// BatchTransitClient. GenerateWrappedKey draws keys from client if it
+// implements RandomGenerator, and from crypto/rand otherwise.
+func NewDerivedTransitClient(client DerivedTransitClient, keyContext []byte) (TransitClient, error) {
+	if client == nil {
+		return nil, errors.New("vault: DerivedTransitClient must not be nil")
+	}
+	if len(keyContext) == 0 {
+		return nil, errors.New("vault: key derivation context must not be empty")
+	}
+	d := &derivedClient{client: client, keyContext: bytes.Clone(keyContext)}
+	if bc, ok := client.(DerivedBatchTransitClient); ok {
+		return &derivedBatchClient{derivedClient: d, batch: bc}, nil
+	}
+	return d, nil
+}
+
+// derivedClient binds a key derivation context to a DerivedTransitClient.
+type derivedClient struct {
+	client     DerivedTransitClient
+	keyContext []byte
+}
+
+// TransitEncrypt encrypts plaintext under the bound context.
+func (d *derivedClient) TransitEncrypt(ctx context.Context, mount, keyName string, plaintext []byte) (string, error) {
+	return d.client.TransitEncryptDerived(ctx, mount, keyName, plaintext, d.keyContext)
+}
+
+// TransitDecrypt decrypts ciphertext under the bound context.
+func (d *derivedClient) TransitDecrypt(ctx context.Context, mount, keyName, ciphertext string) ([]byte, error) {
+	return d.client.TransitDecryptDerived(ctx, mount, keyName, ciphertext, d.keyContext)
+}
+
+// TransitRandom draws from the wrapped client if it is a RandomGenerator,
+// and from crypto/rand otherwise.
+func (d *derivedClient) TransitRandom(ctx context.Context, mount string, n int) ([]byte, error) {
+	if rg, ok := d.client.(RandomGenerator); ok {
+		return rg.TransitRandom(ctx, mount, n)
+	}
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// derivedBatchClient adds batch decrypt to derivedClient.
+type derivedBatchClient struct {
+	*derivedClient
+	batch DerivedBatchTransitClient
+}
+
+// TransitDecryptBatch decrypts ciphertexts under the bound context.
+func (d *derivedBatchClient) TransitDecryptBatch(ctx context.Context, mount, keyName string, ciphertexts []string) ([]TransitResult, error) {
+	return d.batch.TransitDecryptBatchDerived(ctx, mount, keyName, ciphertexts, d.keyContext)
+}
@@ -0,0 +1,140 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// convergentMock is an in-memory Transit engine with a convergent derived
+// key: ciphertexts are deterministic in the plaintext and context, and
+// decrypting requires the same context.
+type convergentMock struct {
+	mu       sync.Mutex
+	batches  int
+	contexts [][]byte
+}
+
+func (m *convergentMock) TransitEncrypt(context.Context, string, string, []byte) (string, error) {
+	return "", errors.New("transit: 400 missing 'context' for key derivation")
+}
+
+func (m *convergentMock) TransitDecrypt(context.Context, string, string, string) ([]byte, error) {
+	return nil, errors.New("transit: 400 missing 'context' for key derivation")
+}
+
+func (m *convergentMock) TransitEncryptDerived(_ context.Context, _, _ string, plaintext, keyContext []byte) (string, error) {
+	m.mu.Lock()
+	m.contexts = append(m.contexts, keyContext)
+	m.mu.Unlock()
+	return "vault:v1:" + base64.StdEncoding.EncodeToString(keyContext) + ":" + base64.StdEncoding.EncodeToString(plaintext), nil
+}
+
+func (m *convergentMock) TransitDecryptDerived(_ context.Context, _, _ string, ciphertext string, keyContext []byte) ([]byte, error) {
+	rest, ok := strings.CutPrefix(ciphertext, "vault:v1:"+base64.StdEncoding.EncodeToString(keyContext)+":")
+	if !ok {
+		return nil, errors.New("transit: 400 cipher: message authentication failed")
+	}
+	return base64.StdEncoding.DecodeString(rest)
+}
+
+// convergentBatchMock adds batch decrypt to convergentMock.
+type convergentBatchMock struct {
+	convergentMock
+}
+
+func (m *convergentBatchMock) TransitDecryptBatchDerived(ctx context.Context, mount, keyName string, ciphertexts []string, keyContext []byte) ([]TransitResult, error) {
+	m.mu.Lock()
+	m.batches++
+	m.mu.Unlock()
+	results := make([]TransitResult, len(ciphertexts))
+	for i, ct := range ciphertexts {
+		results[i].Plaintext, results[i].Err = m.TransitDecryptDerived(ctx, mount, keyName, ct, keyContext)
+	}
+	return results, nil
+}
+
+func TestNewDerivedTransitClient_Convergent(t *testing.T) {
+	ctx := context.Background()
+	m := &convergentMock{}
+	tc, err := NewDerivedTransitClient(m, []byte("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tc.(BatchTransitClient); ok {
+		t.Fatal("adapter implements BatchTransitClient without a batch-capable client")
+	}
+
+	// The same KEK wraps to the same blob.
+	kek := mkKey(3)
+	a, err := tc.TransitEncrypt(ctx, "transit", "config", kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := tc.TransitEncrypt(ctx, "transit", "config", kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("convergent ciphertexts differ: %q vs %q", a, b)
+	}
+	if !bytes.Equal(m.contexts[0], []byte("prod")) {
+		t.Errorf("context = %q, want prod", m.contexts[0])
+	}
+
+	remote, err := NewTransitRemote(tc, "transit", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := remote.Encrypt(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := remote.Decrypt(ctx, ct); err != nil || string(pt) != "hello" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+
+	// Another context cannot decrypt.
+	other, _ := NewDerivedTransitClient(m, []byte("staging"))
+	if _, err := NewTransit(ctx, other, "transit", WithTransitKey(a, "k1", "config")); err == nil {
+		t.Error("expected decrypt under another context to fail")
+	}
+}
+
+func TestNewDerivedTransitClient_Batch(t *testing.T) {
+	ctx := context.Background()
+	m := &convergentBatchMock{}
+	tc, err := NewDerivedTransitClient(m, []byte("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opts []Option
+	for i, id := range []string{"k1", "k2"} {
+		ct, err := tc.TransitEncrypt(ctx, "transit", "config", mkKey(byte(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts = append(opts, WithTransitKey(ct, id, "config"))
+	}
+	p, err := NewTransit(ctx, tc, "transit", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if m.batches != 1 {
+		t.Errorf("batches = %d, want 1", m.batches)
+	}
+}
+
+func TestNewDerivedTransitClient_Errors(t *testing.T) {
+	if _, err := NewDerivedTransitClient(nil, []byte("x")); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := NewDerivedTransitClient(&convergentMock{}, nil); err == nil {
+		t.Error("expected error for empty context")
+	}
+}
@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// RandomGenerator draws random bytes from a Transit engine. Implement it
+// with the random endpoint:
+//
+//	func (c *myVault) TransitRandom(ctx context.Context, mount string, n int) ([]byte, error) {
+//	    s, err := c.api.Logical().WriteWithContext(ctx, mount+"/random/"+strconv.Itoa(n), nil)
+//	    if err != nil { return nil, err }
+//	    return base64.StdEncoding.DecodeString(s.Data["random_bytes"].(string))
+//	}
+type RandomGenerator interface {
+	// TransitRandom returns n random bytes generated by the Transit engine
+	// mounted at mount.
+	TransitRandom(ctx context.Context, mount string, n int) ([]byte, error)
+}
+
+// GenerateWrappedKey bootstraps a new environment in one call: it generates
+// a 32-byte key, has Transit encrypt it with keyName in the engine mounted
+// at mount, and returns Vault's ciphertext ("vault:v1:...") to persist
+// together with a provider that already holds the key as its current key.
+// The key comes from crypto/rand, or from Vault if client also implements
+// RandomGenerator. id names the key in the ring; if empty, the key's
+// crypto.KeyFingerprint is used. Later processes load the same key with
+//
+//	vault.NewTransit(ctx, client, mount, vault.WithTransitKey(ciphertext, id, keyName))
+//
+// where id is the returned provider's CurrentKeyID. opts configure the
+// provider as for crypto.NewKeyRingProvider. The plaintext is wiped once it
+// is in the ring. The caller owns the returned provider and must Close it.
+func GenerateWrappedKey(ctx context.Context, client TransitClient, mount, keyName, id string, opts ...crypto.ProviderOption) (string, crypto.KeyRingProvider, error) {
+	if client == nil {
+		return "", nil, errors.New("vault: TransitClient must not be nil")
+	}
+	if mount == "" {
+		return "", nil, errors.New("vault: mount must not be empty")
+	}
+	if keyName == "" || strings.Contains(keyName, "/") {
+		return "", nil, fmt.Errorf("vault: %w: invalid Transit key name %q", crypto.ErrInvalidKeyID, keyName)
+	}
+
+	var (
+		key []byte
+		err error
+	)
+	if rg, ok := client.(RandomGenerator); ok {
+		key, err = rg.TransitRandom(ctx, mount, kmsring.KeySize)
+		if err == nil && len(key) != kmsring.KeySize {
+			err = fmt.Errorf("%w: got %d random bytes", crypto.ErrInvalidKeySize, len(key))
+		}
+	} else {
+		key, err = crypto.GenerateKey()
+	}
+	defer clear(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("vault: generate key: %w", err)
+	}
+
+	ciphertext, err := client.TransitEncrypt(ctx, mount, keyName, key)
+	if err != nil {
+		return "", nil, fmt.Errorf("vault: encrypt key: %w", err)
+	}
+	if id == "" {
+		id = crypto.KeyFingerprint(key)
+	}
+	ring, err := crypto.NewKeyRingProvider(key, id, 0, opts...)
+	if err != nil {
+		return "", nil, fmt.Errorf("vault: %w", err)
+	}
+	return ciphertext, ring, nil
+}
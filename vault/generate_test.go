@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// randomTransitMock is a transitMock that also generates random bytes.
+type randomTransitMock struct {
+	transitMock
+	n int
+}
+
+func (m *randomTransitMock) TransitRandom(_ context.Context, mount string, n int) ([]byte, error) {
+	if mount != m.mount {
+		return nil, errors.New("transit: 404 no mount")
+	}
+	m.n = n
+	return mkKey(9)[:n], nil
+}
+
+func TestGenerateWrappedKey_Bootstrap(t *testing.T) {
+	ctx := context.Background()
+	tr := &transitMock{mount: "transit", keys: map[string]int{"config": 2}}
+	wrapped, ring, err := GenerateWrappedKey(ctx, tr, "transit", "config", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if !strings.HasPrefix(wrapped, "vault:v2:") {
+		t.Errorf("ciphertext = %q, want vault:v2: prefix", wrapped)
+	}
+	ct, err := ring.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A later process loads the persisted ciphertext and reads the value.
+	p, err := NewTransit(ctx, tr, "transit", WithTransitKey(wrapped, ring.CurrentKeyID(), "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestGenerateWrappedKey_VaultRandom(t *testing.T) {
+	tr := &randomTransitMock{transitMock: transitMock{mount: "transit", keys: map[string]int{"config": 1}}}
+	_, ring, err := GenerateWrappedKey(context.Background(), tr, "transit", "config", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if tr.n != 32 {
+		t.Errorf("TransitRandom n = %d, want 32", tr.n)
+	}
+	if want := crypto.KeyFingerprint(mkKey(9)); ring.CurrentKeyID() != want {
+		t.Errorf("CurrentKeyID = %q, want fingerprint of Vault's key", ring.CurrentKeyID())
+	}
+}
+
+func TestGenerateWrappedKey_Errors(t *testing.T) {
+	ctx := context.Background()
+	tr := &transitMock{mount: "transit", keys: map[string]int{"config": 1}}
+	if _, _, err := GenerateWrappedKey(ctx, nil, "transit", "config", ""); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, _, err := GenerateWrappedKey(ctx, tr, "transit", "a/b", ""); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("err = %v, want ErrInvalidKeyID", err)
+	}
+	if _, _, err := GenerateWrappedKey(ctx, tr, "transit", "missing", ""); err == nil {
+		t.Error("expected error for a missing Transit key")
+	}
+}
@@ -0,0 +1,580 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponse bounds the Vault responses read into memory.
+const maxResponse = 4 << 20
+
+// StatusError is a Vault error response.
+type StatusError struct {
+	StatusCode int      // HTTP status
+	Errors     []string // Vault's error messages
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// HTTPClient talks to Vault's HTTP API directly with net/http. It
+// implements Client, BatchTransitClient, and RandomGenerator, so it works
+// with New, Poll, NewKeySource, NewTransit, NewTransitRemote, and
+// GenerateWrappedKey without the Vault SDK.
+//
+// It logs in with an AuthMethod or uses a fixed token, renews the token
+// before it expires, and logs in again when it cannot be renewed or Vault
+// rejects it, so long-running providers and pollers keep their access.
+// Concurrent requests share one login or renewal. While a renewal is in
+// flight, requests holding a still-valid token go ahead with it; only
+// requests without a usable token wait. It is safe for concurrent use.
+type HTTPClient struct {
+	addr      string
+	hc        *http.Client
+	auth      AuthMethod
+	namespace string
+
+	mu        sync.Mutex
+	token     string
+	renewable bool
+	issued    time.Time
+	ttl       time.Duration // zero if the token does not expire
+	looked    bool          // a fixed token's TTL has been looked up
+	inflight  *authCall     // login, renewal, or lookup in progress
+}
+
+// authStep is the work a token needs before use.
+type authStep int
+
+const (
+	authNone authStep = iota
+	authLogin
+	authRenew
+	authLookup
+)
+
+// authCall is one login, renewal, or lookup in progress, shared by every
+// request that needs it.
+type authCall struct {
+	done     chan struct{}
+	err      error
+	canceled bool // the request running it gave up; waiters retry
+}
+
+// Compile-time interface checks.
+var (
+	_ Client                    = (*HTTPClient)(nil)
+	_ BatchTransitClient        = (*HTTPClient)(nil)
+	_ RandomGenerator           = (*HTTPClient)(nil)
+	_ DerivedBatchTransitClient = (*HTTPClient)(nil)
+)
+
+// HTTPOption configures an HTTPClient.
+type HTTPOption func(*HTTPClient)
+
+// WithToken authenticates with a fixed Vault token. If the token is
+// renewable it is renewed as it nears expiry, but once it reaches its
+// max TTL requests fail; use WithAuth for services that outlive a token.
+func WithToken(token string) HTTPOption {
+	return func(c *HTTPClient) {
+		c.token, c.auth = token, nil
+	}
+}
+
+// WithAuth logs in with method, such as AppRoleAuth or KubernetesAuth, on
+// first use and whenever the token expires or is revoked.
+func WithAuth(method AuthMethod) HTTPOption {
+	return func(c *HTTPClient) {
+		c.auth, c.token = method, ""
+	}
+}
+
+// WithHTTPClient sets the http.Client used for Vault requests. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(hc *http.Client) HTTPOption {
+	return func(c *HTTPClient) {
+		c.hc = hc
+	}
+}
+
+// WithTLSConfig sets the TLS configuration for connections to Vault, for
+// example a RootCAs pool holding a private CA or a client certificate for
+// the cert auth method. It replaces any WithHTTPClient set before it.
+func WithTLSConfig(cfg *tls.Config) HTTPOption {
+	return func(c *HTTPClient) {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = cfg.Clone()
+		c.hc = &http.Client{Transport: t}
+	}
+}
+
+// WithNamespace sends every request, including logins, to the given Vault
+// Enterprise namespace, such as "team-a" or "org/team-a", with the
+// X-Vault-Namespace header. An empty namespace uses VAULT_NAMESPACE.
+func WithNamespace(namespace string) HTTPOption {
+	return func(c *HTTPClient) {
+		c.namespace = namespace
+	}
+}
+
+// NewHTTPClient returns an HTTPClient for the Vault server at addr, such as
+// "https://vault.example.com:8200". An empty addr uses VAULT_ADDR. Without
+// WithToken or WithAuth, the VAULT_TOKEN environment variable is used, and
+// without WithNamespace, VAULT_NAMESPACE.
+func NewHTTPClient(addr string, opts ...HTTPOption) (*HTTPClient, error) {
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	u, err := url.Parse(addr)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("vault: invalid Vault address %q", addr)
+	}
+	c := &HTTPClient{addr: strings.TrimSuffix(addr, "/"), hc: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.namespace == "" {
+		c.namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if c.token == "" && c.auth == nil {
+		c.token = os.Getenv("VAULT_TOKEN")
+	}
+	if c.token == "" && c.auth == nil {
+		return nil, errors.New("vault: no token or auth method configured")
+	}
+	return c, nil
+}
+
+// KVMetadata reads mount/metadata/path and returns the versions that are
+// neither deleted nor destroyed.
+func (c *HTTPClient) KVMetadata(ctx context.Context, mount, path string) ([]int, int, error) {
+	var out struct {
+		Data struct {
+			CurrentVersion int `json:"current_version"`
+			Versions       map[string]struct {
+				DeletionTime string `json:"deletion_time"`
+				Destroyed    bool   `json:"destroyed"`
+			} `json:"versions"`
+		} `json:"data"`
+	}
+	if err := c.request(ctx, http.MethodGet, apiPath(mount, "metadata", path), nil, &out); err != nil {
+		return nil, 0, err
+	}
+	var versions []int
+	for s, v := range out.Data.Versions {
+		n, err := strconv.Atoi(s)
+		if err != nil || v.Destroyed || v.DeletionTime != "" {
+			continue
+		}
+		versions = append(versions, n)
+	}
+	return versions, out.Data.CurrentVersion, nil
+}
+
+// KVGet reads one version of mount/data/path. Non-string values are
+// returned as JSON.
+func (c *HTTPClient) KVGet(ctx context.Context, mount, path string, version int) (map[string]string, error) {
+	var out struct {
+		Data struct {
+			Data map[string]json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	p := apiPath(mount, "data", path) + "?version=" + strconv.Itoa(version)
+	if err := c.request(ctx, http.MethodGet, p, nil, &out); err != nil {
+		return nil, err
+	}
+	data := make(map[string]string, len(out.Data.Data))
+	for k, raw := range out.Data.Data {
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			s = string(raw)
+		}
+		data[k] = s
+	}
+	return data, nil
+}
+
+// TransitEncrypt encrypts plaintext with mount/encrypt/keyName.
+func (c *HTTPClient) TransitEncrypt(ctx context.Context, mount, keyName string, plaintext []byte) (string, error) {
+	return c.TransitEncryptDerived(ctx, mount, keyName, plaintext, nil)
+}
+
+// TransitDecrypt decrypts ciphertext with mount/decrypt/keyName.
+func (c *HTTPClient) TransitDecrypt(ctx context.Context, mount, keyName, ciphertext string) ([]byte, error) {
+	return c.TransitDecryptDerived(ctx, mount, keyName, ciphertext, nil)
+}
+
+// TransitDecryptBatch decrypts ciphertexts with one batch_input request to
+// mount/decrypt/keyName.
+func (c *HTTPClient) TransitDecryptBatch(ctx context.Context, mount, keyName string, ciphertexts []string) ([]TransitResult, error) {
+	return c.TransitDecryptBatchDerived(ctx, mount, keyName, ciphertexts, nil)
+}
+
+// TransitEncryptDerived encrypts plaintext with mount/encrypt/keyName,
+// sending keyContext as the context if it is not empty.
+func (c *HTTPClient) TransitEncryptDerived(ctx context.Context, mount, keyName string, plaintext, keyContext []byte) (string, error) {
+	var out struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	in := withKeyContext(map[string]any{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, keyContext)
+	if err := c.request(ctx, http.MethodPost, apiPath(mount, "encrypt", keyName), in, &out); err != nil {
+		return "", err
+	}
+	if out.Data.Ciphertext == "" {
+		return "", errors.New("vault: Transit encrypt returned no ciphertext")
+	}
+	return out.Data.Ciphertext, nil
+}
+
+// TransitDecryptDerived decrypts ciphertext with mount/decrypt/keyName,
+// sending keyContext as the context if it is not empty.
+func (c *HTTPClient) TransitDecryptDerived(ctx context.Context, mount, keyName, ciphertext string, keyContext []byte) ([]byte, error) {
+	var out struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	in := withKeyContext(map[string]any{"ciphertext": ciphertext}, keyContext)
+	if err := c.request(ctx, http.MethodPost, apiPath(mount, "decrypt", keyName), in, &out); err != nil {
+		return nil, err
+	}
+	pt, err := base64.StdEncoding.DecodeString(out.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("vault: decode Transit plaintext: %w", err)
+	}
+	return pt, nil
+}
+
+// TransitDecryptBatchDerived decrypts ciphertexts with one batch_input
+// request to mount/decrypt/keyName, sending keyContext as every item's
+// context if it is not empty. It asks for per-item errors with status 200;
+// Vault before 1.14 instead fails the whole request if any item fails.
+func (c *HTTPClient) TransitDecryptBatchDerived(ctx context.Context, mount, keyName string, ciphertexts []string, keyContext []byte) ([]TransitResult, error) {
+	items := make([]map[string]any, len(ciphertexts))
+	for i, ct := range ciphertexts {
+		items[i] = withKeyContext(map[string]any{"ciphertext": ct}, keyContext)
+	}
+	var out struct {
+		Data struct {
+			BatchResults []struct {
+				Plaintext string `json:"plaintext"`
+				Error     string `json:"error"`
+			} `json:"batch_results"`
+		} `json:"data"`
+	}
+	in := map[string]any{"batch_input": items, "partial_failure_response_code": http.StatusOK}
+	if err := c.request(ctx, http.MethodPost, apiPath(mount, "decrypt", keyName), in, &out); err != nil {
+		return nil, err
+	}
+	results := make([]TransitResult, len(out.Data.BatchResults))
+	for i, r := range out.Data.BatchResults {
+		if r.Error != "" {
+			results[i].Err = fmt.Errorf("vault: %s", r.Error)
+			continue
+		}
+		results[i].Plaintext, results[i].Err = base64.StdEncoding.DecodeString(r.Plaintext)
+	}
+	return results, nil
+}
+
+// withKeyContext adds keyContext to a Transit request item if it is not
+// empty.
+func withKeyContext(item map[string]any, keyContext []byte) map[string]any {
+	if len(keyContext) > 0 {
+		item["context"] = base64.StdEncoding.EncodeToString(keyContext)
+	}
+	return item
+}
+
+// TransitRandom returns n random bytes from mount/random/n.
+func (c *HTTPClient) TransitRandom(ctx context.Context, mount string, n int) ([]byte, error) {
+	var out struct {
+		Data struct {
+			RandomBytes string `json:"random_bytes"`
+		} `json:"data"`
+	}
+	in := map[string]any{"format": "base64"}
+	if err := c.request(ctx, http.MethodPost, apiPath(mount, "random", strconv.Itoa(n)), in, &out); err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(out.Data.RandomBytes)
+	if err != nil {
+		return nil, fmt.Errorf("vault: decode random bytes: %w", err)
+	}
+	return b, nil
+}
+
+// apiPath joins path segments, escaping each element of each segment.
+func apiPath(segments ...string) string {
+	var b strings.Builder
+	for _, seg := range segments {
+		for _, part := range strings.Split(strings.Trim(seg, "/"), "/") {
+			b.WriteByte('/')
+			b.WriteString(url.PathEscape(part))
+		}
+	}
+	return b.String()
+}
+
+// authResponse is the auth block of login and renew responses.
+type authResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// currentToken returns a token to send, logging in or renewing first as
+// needed.
+func (c *HTTPClient) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	step := c.authStepLocked()
+	if step == authNone || (c.inflight != nil && c.tokenUsableLocked()) {
+		token := c.token
+		c.mu.Unlock()
+		return token, nil
+	}
+	return c.authenticateLocked(ctx, step)
+}
+
+// authStepLocked returns what the token needs before use. c.mu must be held.
+func (c *HTTPClient) authStepLocked() authStep {
+	switch {
+	case c.token == "":
+		return authLogin
+	case c.auth == nil && !c.looked:
+		return authLookup
+	case c.ttl <= 0:
+		return authNone
+	}
+	elapsed := time.Since(c.issued)
+	switch {
+	case elapsed >= c.ttl && c.auth != nil:
+		return authLogin
+	case elapsed >= c.ttl*2/3 && c.renewable:
+		return authRenew
+	}
+	return authNone
+}
+
+// tokenUsableLocked reports whether the token has not yet expired. c.mu must
+// be held.
+func (c *HTTPClient) tokenUsableLocked() bool {
+	return c.token != "" && (c.ttl <= 0 || time.Since(c.issued) < c.ttl)
+}
+
+// authenticateLocked runs step and returns the token to use afterwards. If
+// another request is already logging in, renewing, or looking up, it waits
+// for that instead. The network calls run with c.mu released, so requests
+// that need no new token are not held up. c.mu must be held; it is released
+// on return.
+func (c *HTTPClient) authenticateLocked(ctx context.Context, step authStep) (string, error) {
+	for c.inflight != nil {
+		call := c.inflight
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		c.mu.Lock()
+		if !call.canceled {
+			token := c.token
+			c.mu.Unlock()
+			return token, call.err
+		}
+	}
+
+	call := &authCall{done: make(chan struct{})}
+	c.inflight = call
+	token := c.token
+	if step == authLookup {
+		c.looked = true
+	}
+	c.mu.Unlock()
+
+	switch step {
+	case authLogin:
+		call.err = c.login(ctx)
+	case authRenew:
+		if err := c.renew(ctx, token); err != nil && c.auth != nil {
+			call.err = c.login(ctx)
+		}
+	case authLookup:
+		c.lookupSelf(ctx, token)
+	}
+	call.canceled = call.err != nil && ctx.Err() != nil
+
+	c.mu.Lock()
+	c.inflight = nil
+	token = c.token
+	c.mu.Unlock()
+	close(call.done)
+	if call.err != nil {
+		return "", call.err
+	}
+	return token, nil
+}
+
+// login authenticates with c.auth.
+func (c *HTTPClient) login(ctx context.Context) error {
+	if c.auth == nil {
+		return errors.New("vault: token expired and no auth method is configured")
+	}
+	path, body, err := c.auth.Login(ctx)
+	if err != nil {
+		return err
+	}
+	var out authResponse
+	if err := c.send(ctx, http.MethodPost, "/"+strings.TrimPrefix(path, "/"), "", body, &out); err != nil {
+		return err
+	}
+	if out.Auth.ClientToken == "" {
+		return errors.New("vault: login: response has no client token")
+	}
+	c.setAuth(out)
+	return nil
+}
+
+// renew renews token.
+func (c *HTTPClient) renew(ctx context.Context, token string) error {
+	var out authResponse
+	if err := c.send(ctx, http.MethodPost, "/auth/token/renew-self", token, map[string]any{}, &out); err != nil {
+		return err
+	}
+	if out.Auth.ClientToken == "" {
+		out.Auth.ClientToken = token
+	}
+	c.setAuth(out)
+	return nil
+}
+
+// lookupSelf learns a fixed token's remaining TTL and renewability. A
+// failure leaves the token to be used as is.
+func (c *HTTPClient) lookupSelf(ctx context.Context, token string) {
+	var out struct {
+		Data struct {
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+	}
+	if c.send(ctx, http.MethodGet, "/auth/token/lookup-self", token, nil, &out) == nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.issued = time.Now()
+		c.ttl = time.Duration(out.Data.TTL) * time.Second
+		c.renewable = out.Data.Renewable
+	}
+}
+
+// setAuth records a login or renew response.
+func (c *HTTPClient) setAuth(out authResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = out.Auth.ClientToken
+	c.renewable = out.Auth.Renewable
+	c.issued = time.Now()
+	c.ttl = time.Duration(out.Auth.LeaseDuration) * time.Second
+}
+
+// request sends an authenticated request. If Vault rejects the token with
+// 403 and an auth method is configured, it logs in again and retries
+// once, covering tokens revoked or expired behind the client's back.
+func (c *HTTPClient) request(ctx context.Context, method, path string, in, out any) error {
+	token, err := c.currentToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.send(ctx, method, path, token, in, out)
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusForbidden || c.auth == nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.token != token {
+		token = c.token
+		c.mu.Unlock()
+	} else {
+		var lerr error
+		if token, lerr = c.authenticateLocked(ctx, authLogin); lerr != nil {
+			return errors.Join(err, lerr)
+		}
+	}
+	return c.send(ctx, method, path, token, in, out)
+}
+
+// send performs one request against /v1 and decodes the JSON response into
+// out. Request and response bodies, which may hold key material, are
+// cleared after use.
+func (c *HTTPClient) send(ctx context.Context, method, path, token string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("vault: encode request: %w", err)
+		}
+		defer clear(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+"/v1"+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Vault-Request", "true")
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	defer clear(respBody)
+	if err != nil {
+		return fmt.Errorf("vault: %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		se := &StatusError{StatusCode: resp.StatusCode}
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &e) == nil {
+			se.Errors = e.Errors
+		}
+		return fmt.Errorf("vault: %s %s: %w", method, path, se)
+	}
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("vault: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,528 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// fakeVault is an httptest Vault serving one KV v2 secret and the token
+// and login endpoints HTTPClient uses.
+type fakeVault struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	versions  map[int]string // version -> base64 key; "" means deleted
+	current   int
+	tokens    map[string]bool // valid tokens
+	logins    []map[string]any
+	renews    int
+	lease     int    // lease_duration of issued tokens
+	namespace string // required X-Vault-Namespace
+	next      int
+	batches   int
+	contexts  []string // Transit contexts received
+
+	// gate, when set before use, holds login and renew requests until it is
+	// closed; each held request first sends its path on gated.
+	gate  chan struct{}
+	gated chan string
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	t.Helper()
+	f := &fakeVault{t: t, versions: map[int]string{}, tokens: map[string]bool{"root": true}, lease: 3600}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeVault) put(version int, key []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if key == nil {
+		f.versions[version] = ""
+	} else {
+		f.versions[version] = base64.StdEncoding.EncodeToString(key)
+	}
+	f.current = max(f.current, version)
+}
+
+func (f *fakeVault) revokeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.tokens)
+}
+
+func (f *fakeVault) issue() map[string]any {
+	f.next++
+	tok := "tok-" + strconv.Itoa(f.next)
+	f.tokens[tok] = true
+	return map[string]any{"auth": map[string]any{"client_token": tok, "lease_duration": f.lease, "renewable": true}}
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.gate != nil && (strings.HasSuffix(r.URL.Path, "/login") || r.URL.Path == "/v1/auth/token/renew-self") {
+		f.gated <- r.URL.Path
+		<-f.gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reply := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	if r.Header.Get("X-Vault-Namespace") != f.namespace {
+		reply(http.StatusNotFound, map[string]any{"errors": []string{"no handler for " + r.URL.Path}})
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/auth/approle/login", "/v1/auth/kubernetes/login":
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.logins = append(f.logins, body)
+		reply(http.StatusOK, f.issue())
+		return
+	}
+	if !f.tokens[r.Header.Get("X-Vault-Token")] {
+		reply(http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/auth/token/renew-self":
+		f.renews++
+		reply(http.StatusOK, map[string]any{"auth": map[string]any{
+			"client_token": r.Header.Get("X-Vault-Token"), "lease_duration": f.lease, "renewable": true,
+		}})
+	case "/v1/auth/token/lookup-self":
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"ttl": 0, "renewable": false}})
+	case "/v1/secret/metadata/app/kek":
+		versions := map[string]any{}
+		for v, k := range f.versions {
+			meta := map[string]any{"deletion_time": "", "destroyed": false}
+			if k == "" {
+				meta["deletion_time"] = "2026-01-01T00:00:00Z"
+			}
+			versions[strconv.Itoa(v)] = meta
+		}
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"current_version": f.current, "versions": versions}})
+	case "/v1/secret/data/app/kek":
+		v, _ := strconv.Atoi(r.URL.Query().Get("version"))
+		k, ok := f.versions[v]
+		if !ok || k == "" {
+			reply(http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+		reply(http.StatusOK, map[string]any{"data": map[string]any{
+			"data":     map[string]any{"key": k, "rotated": 2},
+			"metadata": map[string]any{"version": v},
+		}})
+	case "/v1/transit/random/32":
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"random_bytes": base64.StdEncoding.EncodeToString(mkKey(7))}})
+	case "/v1/transit/encrypt/config":
+		var in struct {
+			Plaintext string `json:"plaintext"`
+			Context   string `json:"context"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		f.contexts = append(f.contexts, in.Context)
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"ciphertext": "vault:v1:" + in.Plaintext}})
+	case "/v1/transit/decrypt/config":
+		var in struct {
+			Ciphertext string `json:"ciphertext"`
+			Context    string `json:"context"`
+			BatchInput []struct {
+				Ciphertext string `json:"ciphertext"`
+				Context    string `json:"context"`
+			} `json:"batch_input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		if in.BatchInput == nil {
+			f.contexts = append(f.contexts, in.Context)
+		}
+		for _, item := range in.BatchInput {
+			f.contexts = append(f.contexts, item.Context)
+		}
+		decrypt := func(ct string) (string, bool) {
+			return strings.CutPrefix(ct, "vault:v1:")
+		}
+		if in.BatchInput == nil {
+			pt, ok := decrypt(in.Ciphertext)
+			if !ok {
+				reply(http.StatusBadRequest, map[string]any{"errors": []string{"invalid ciphertext"}})
+				return
+			}
+			reply(http.StatusOK, map[string]any{"data": map[string]any{"plaintext": pt}})
+			return
+		}
+		f.batches++
+		var results []map[string]any
+		for _, item := range in.BatchInput {
+			if pt, ok := decrypt(item.Ciphertext); ok {
+				results = append(results, map[string]any{"plaintext": pt})
+			} else {
+				results = append(results, map[string]any{"error": "invalid ciphertext"})
+			}
+		}
+		reply(http.StatusOK, map[string]any{"data": map[string]any{"batch_results": results}})
+	default:
+		reply(http.StatusNotFound, map[string]any{"errors": []string{"no handler for " + r.URL.Path}})
+	}
+}
+
+func TestHTTPClient_Token(t *testing.T) {
+	f, srv := newFakeVault(t)
+	k1, k2 := mkKey(1), mkKey(2)
+	f.put(1, k1)
+	f.put(2, nil) // deleted
+	f.put(3, k2)
+
+	c, err := NewHTTPClient(srv.URL, WithToken("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, current, err := c.KVMetadata(context.Background(), "secret", "app/kek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != 3 || len(versions) != 2 {
+		t.Fatalf("KVMetadata = %v, %d; want 2 live versions, current 3", versions, current)
+	}
+	data, err := c.KVGet(context.Background(), "secret", "app/kek", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data["key"] != base64.StdEncoding.EncodeToString(k2) || data["rotated"] != "2" {
+		t.Fatalf("KVGet = %v", data)
+	}
+
+	p, err := New(context.Background(), c, "secret", "app/kek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Encrypt(context.Background(), []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClient_AppRoleLoginAndRelogin(t *testing.T) {
+	f, srv := newFakeVault(t)
+	f.put(1, mkKey(1))
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret-id")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewHTTPClient(srv.URL, WithAuth(AppRoleAuth{RoleID: "role", SecretIDFile: secretFile}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.KVMetadata(context.Background(), "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.logins) != 1 || f.logins[0]["role_id"] != "role" || f.logins[0]["secret_id"] != "s3cret" {
+		t.Fatalf("logins = %v", f.logins)
+	}
+
+	// A revoked token is replaced by logging in again.
+	f.revokeAll()
+	if _, _, err := c.KVMetadata(context.Background(), "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.logins) != 2 {
+		t.Fatalf("logins = %d, want 2", len(f.logins))
+	}
+}
+
+func TestHTTPClient_Renew(t *testing.T) {
+	f, srv := newFakeVault(t)
+	f.put(1, mkKey(1))
+	c, err := NewHTTPClient(srv.URL, WithAuth(AppRoleAuth{RoleID: "role", SecretID: "s"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, _, err := c.KVMetadata(ctx, "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Past two thirds of the TTL the token is renewed, not replaced.
+	c.mu.Lock()
+	c.issued = time.Now().Add(-50 * time.Minute)
+	c.mu.Unlock()
+	if _, _, err := c.KVMetadata(ctx, "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+	if f.renews != 1 || len(f.logins) != 1 {
+		t.Fatalf("renews = %d, logins = %d; want 1, 1", f.renews, len(f.logins))
+	}
+
+	// Past the TTL the client logs in again.
+	c.mu.Lock()
+	c.issued = time.Now().Add(-2 * time.Hour)
+	c.mu.Unlock()
+	if _, _, err := c.KVMetadata(ctx, "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.logins) != 2 {
+		t.Fatalf("logins = %d, want 2", len(f.logins))
+	}
+}
+
+func TestHTTPClient_ConcurrentLogin(t *testing.T) {
+	f, srv := newFakeVault(t)
+	f.put(1, mkKey(1))
+	f.gate, f.gated = make(chan struct{}), make(chan string, 16)
+	c, err := NewHTTPClient(srv.URL, WithAuth(AppRoleAuth{RoleID: "role", SecretID: "s"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests arriving while a login is in flight share it.
+	const n = 8
+	errs := make(chan error, n)
+	for range n {
+		go func() {
+			_, _, err := c.KVMetadata(context.Background(), "secret", "app/kek")
+			errs <- err
+		}()
+	}
+	<-f.gated
+	time.Sleep(20 * time.Millisecond) // let the other requests queue behind the login
+	close(f.gate)
+	for range n {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.logins) != 1 {
+		t.Fatalf("logins = %d, want 1", len(f.logins))
+	}
+}
+
+func TestHTTPClient_RenewDoesNotBlockRequests(t *testing.T) {
+	f, srv := newFakeVault(t)
+	f.put(1, mkKey(1))
+	c, err := NewHTTPClient(srv.URL, WithAuth(AppRoleAuth{RoleID: "role", SecretID: "s"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, _, err := c.KVMetadata(ctx, "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+
+	// One request renews; others go ahead with the still-valid token.
+	f.gate, f.gated = make(chan struct{}), make(chan string, 16)
+	c.mu.Lock()
+	c.issued = time.Now().Add(-50 * time.Minute)
+	c.mu.Unlock()
+	renewed := make(chan error, 1)
+	go func() {
+		_, _, err := c.KVMetadata(ctx, "secret", "app/kek")
+		renewed <- err
+	}()
+	if path := <-f.gated; path != "/v1/auth/token/renew-self" {
+		t.Fatalf("held %s, want the renewal", path)
+	}
+	if _, _, err := c.KVMetadata(ctx, "secret", "app/kek"); err != nil {
+		t.Fatalf("request during renewal: %v", err)
+	}
+	close(f.gate)
+	if err := <-renewed; err != nil {
+		t.Fatal(err)
+	}
+	if f.renews != 1 || len(f.logins) != 1 {
+		t.Fatalf("renews = %d, logins = %d; want 1, 1", f.renews, len(f.logins))
+	}
+}
+
+func TestHTTPClient_Kubernetes(t *testing.T) {
+	f, srv := newFakeVault(t)
+	f.put(1, mkKey(1))
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("jwt-1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewHTTPClient(srv.URL, WithAuth(KubernetesAuth{Role: "app", TokenFile: tokenFile}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.KVMetadata(context.Background(), "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.logins) != 1 || f.logins[0]["jwt"] != "jwt-1" || f.logins[0]["role"] != "app" {
+		t.Fatalf("logins = %v", f.logins)
+	}
+}
+
+func TestHTTPClient_Errors(t *testing.T) {
+	_, srv := newFakeVault(t)
+	t.Setenv("VAULT_TOKEN", "")
+	if _, err := NewHTTPClient(srv.URL); err == nil {
+		t.Fatal("expected error without a token or auth method")
+	}
+	if _, err := NewHTTPClient("vault.example.com", WithToken("t")); err == nil {
+		t.Fatal("expected error for an address without a scheme")
+	}
+
+	c, err := NewHTTPClient(srv.URL, WithToken("wrong"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = c.KVMetadata(context.Background(), "secret", "app/kek")
+	var se *StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusForbidden {
+		t.Fatalf("err = %v, want a 403 StatusError", err)
+	}
+}
+
+func TestHTTPClient_Namespace(t *testing.T) {
+	f, srv := newFakeVault(t)
+	f.put(1, mkKey(1))
+	f.namespace = "org/team-a"
+	ctx := context.Background()
+
+	t.Setenv("VAULT_NAMESPACE", "")
+	c, err := NewHTTPClient(srv.URL, WithToken("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.KVMetadata(ctx, "secret", "app/kek"); err == nil {
+		t.Fatal("expected error without the namespace")
+	}
+
+	c, err = NewHTTPClient(srv.URL, WithNamespace("org/team-a"), WithAuth(AppRoleAuth{RoleID: "role", SecretID: "s"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(ctx, c, "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.logins) != 1 {
+		t.Fatalf("logins = %d, want 1", len(f.logins))
+	}
+
+	t.Setenv("VAULT_NAMESPACE", "org/team-a")
+	c, err = NewHTTPClient(srv.URL, WithToken("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.KVMetadata(ctx, "secret", "app/kek"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPClient_Transit(t *testing.T) {
+	f := &fakeVault{t: t, versions: map[int]string{}, tokens: map[string]bool{"root": true}, lease: 3600}
+	srv := httptest.NewTLSServer(f)
+	t.Cleanup(srv.Close)
+	ctx := context.Background()
+
+	untrusted, err := NewHTTPClient(srv.URL, WithToken("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := untrusted.TransitEncrypt(ctx, "transit", "config", []byte("x")); err == nil {
+		t.Fatal("expected a certificate error without WithTLSConfig")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	c, err := NewHTTPClient(srv.URL, WithToken("root"), WithTLSConfig(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := NewTransitRemote(c, "transit", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := remote.Encrypt(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := remote.Decrypt(ctx, ct); err != nil || string(pt) != "hello" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+
+	var opts []Option
+	for i := range 3 {
+		wrapped, err := c.TransitEncrypt(ctx, "transit", "config", mkKey(byte(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts = append(opts, WithTransitKey(wrapped, "key-"+strconv.Itoa(i), "config"))
+	}
+	ring, err := NewTransit(ctx, c, "transit", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if f.batches != 1 {
+		t.Fatalf("batches = %d, want 1", f.batches)
+	}
+
+	wrapped, gen, err := GenerateWrappedKey(ctx, c, "transit", "config", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gen.Close()
+	if want := crypto.KeyFingerprint(mkKey(7)); gen.CurrentKeyID() != want || !strings.HasPrefix(wrapped, "vault:v1:") {
+		t.Fatalf("GenerateWrappedKey = %q, key %q; want Vault's random key", wrapped, gen.CurrentKeyID())
+	}
+
+	results, err := c.TransitDecryptBatch(ctx, "transit", "config", []string{"vault:v1:" + base64.StdEncoding.EncodeToString([]byte("ok")), "bogus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || string(results[0].Plaintext) != "ok" || results[1].Err == nil {
+		t.Fatalf("TransitDecryptBatch = %+v", results)
+	}
+}
+
+func TestHTTPClient_DerivedTransit(t *testing.T) {
+	f, srv := newFakeVault(t)
+	ctx := context.Background()
+	c, err := NewHTTPClient(srv.URL, WithToken("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc, err := NewDerivedTransitClient(c, []byte("tenant-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, ring, err := GenerateWrappedKey(ctx, tc, "transit", "config", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	p, err := NewTransit(ctx, tc, "transit", WithTransitKey(wrapped, ring.CurrentKeyID(), "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if f.batches != 1 {
+		t.Fatalf("batches = %d, want 1", f.batches)
+	}
+	want := base64.StdEncoding.EncodeToString([]byte("tenant-a"))
+	if len(f.contexts) != 2 || f.contexts[0] != want || f.contexts[1] != want {
+		t.Fatalf("contexts = %q, want %q for encrypt and batch decrypt", f.contexts, want)
+	}
+}
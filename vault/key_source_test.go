@@ -0,0 +1,86 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+func TestKeySource_CurrentAndByID(t *testing.T) {
+	ctx := context.Background()
+	m := newMock("secret", "app/keys")
+	m.putKey(1, mkKey(1))
+	m.putKey(2, mkKey(2))
+
+	src, err := NewKeySource(m, "secret", "app/keys", WithKeyIDFormat(func(v int) string { return fmt.Sprintf("kv-%d", v) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := src.CurrentKey(ctx)
+	if err != nil || k.ID != "kv-2" || !bytes.Equal(k.Bytes, mkKey(2)) {
+		t.Fatalf("CurrentKey = %q, %v", k.ID, err)
+	}
+	k, err = src.KeyByID(ctx, "kv-1")
+	if err != nil || !bytes.Equal(k.Bytes, mkKey(1)) {
+		t.Fatalf("KeyByID(kv-1) = %q, %v", k.ID, err)
+	}
+	if _, err := src.KeyByID(ctx, "kv-9"); !crypto.IsKeyNotFound(err) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+
+	// A new version becomes current on the next lookup.
+	m.putKey(3, mkKey(3))
+	if k, err := src.CurrentKey(ctx); err != nil || k.ID != "kv-3" {
+		t.Errorf("CurrentKey after rotation = %q, %v", k.ID, err)
+	}
+}
+
+func TestKeySource_Provider(t *testing.T) {
+	ctx := context.Background()
+	m := newMock("secret", "app/keys")
+	m.putKey(1, mkKey(1))
+	src, err := NewKeySource(m, "secret", "app/keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := crypto.NewKeySourceProvider(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ct, err := p.Encrypt(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.putKey(2, mkKey(2))
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "hello" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+
+	// A ring built with New from the same secret reads the same values.
+	ring, err := New(ctx, m, "secret", "app/keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ring.Close()
+	if pt, err := ring.Decrypt(ctx, ct); err != nil || string(pt) != "hello" {
+		t.Errorf("ring Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNewKeySource_ValidationErrors(t *testing.T) {
+	m := newMock("secret", "app/keys")
+	for name, fn := range map[string]func() error{
+		"nil client": func() error { _, err := NewKeySource(nil, "secret", "p"); return err },
+		"no mount":   func() error { _, err := NewKeySource(m, "", "p"); return err },
+		"no path":    func() error { _, err := NewKeySource(m, "secret", ""); return err },
+		"no field":   func() error { _, err := NewKeySource(m, "secret", "p", WithField("")); return err },
+	} {
+		if fn() == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -15,6 +15,13 @@
 //	    vault.WithRefreshErrorHandler(func(err error) { log.Println(err) }),
 //	)
 //	defer stop()
+//
+// NewTransit instead decrypts KEKs that were encrypted with the Transit
+// secrets engine, and NewTransitRemote wraps every DEK with Transit so no
+// KEK is ever held in process memory.
+//
+// Clients can come from the Vault SDK or from NewHTTPClient, which
+// implements every client interface here with net/http.
 package vault
 
 import (
@@ -30,6 +37,7 @@ import (
 	"time"
 
 	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
 )
 
 // Client abstracts Vault's KV v2 secrets engine read operations.
@@ -44,7 +52,8 @@ type Client interface {
 	KVMetadata(ctx context.Context, mount, path string) (versions []int, current int, err error)
 
 	// KVGet reads a specific version of the secret at mount/path and returns
-	// its data map (field name -> string value).
+	// its data map (field name -> string value). New calls KVGet concurrently
+	// for different versions.
 	KVGet(ctx context.Context, mount, path string, version int) (map[string]string, error)
 }
 
@@ -55,6 +64,7 @@ type options struct {
 	field          string
 	keyIDFormat    func(version int) string
 	onRefreshError func(error)
+	transitKeys    []transitKeyEntry
 }
 
 // WithField sets the field name within the KV secret data that holds the
@@ -65,7 +75,8 @@ func WithField(field string) Option {
 
 // WithKeyIDFormat sets the function that maps a KV version number to a key
 // ID stored in encrypted headers. The mapping must be deterministic and
-// stable across restarts, otherwise old ciphertexts will fail to decrypt.
+// stable across restarts, otherwise old ciphertexts will fail to decrypt,
+// and safe for concurrent use.
 // Default: strconv.Itoa (versions become "1", "2", ...).
 func WithKeyIDFormat(fn func(version int) string) Option {
 	return func(o *options) { o.keyIDFormat = fn }
@@ -82,13 +93,16 @@ func WithRefreshErrorHandler(fn func(error)) Option {
 // New creates a crypto.KeyRingProvider backed by a Vault KV v2 secret.
 //
 // At construction, KVMetadata is called once to enumerate every version,
-// each version is fetched, and the configured field is base64-decoded into
+// each version is fetched (up to 8 concurrently), and the configured field is base64-decoded into
 // the 32-byte AES-256 key. The version Vault reports as current becomes the
 // provider's current key; remaining versions are registered as old keys for
 // decryption.
 //
 // To automatically pick up new secret versions at runtime, call Poll after
 // construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the fetched key material and is safe to call more than once.
 func New(ctx context.Context, client Client, mount, path string, opts ...Option) (crypto.KeyRingProvider, error) {
 	if client == nil {
 		return nil, errors.New("vault: Client must not be nil")
@@ -130,7 +144,7 @@ func New(ctx context.Context, client Client, mount, path string, opts ...Option)
 		id      string
 		version int
 	}
-	keys := make([]fetched, 0, len(versions))
+	keys := make([]fetched, len(versions))
 	defer func() {
 		for _, k := range keys {
 			clear(k.bytes)
@@ -140,12 +154,19 @@ func New(ctx context.Context, client Client, mount, path string, opts ...Option)
 	sortedVersions := append([]int(nil), versions...)
 	sort.Ints(sortedVersions)
 
-	for _, v := range sortedVersions {
+	// Versions are fetched concurrently; results are stored by index so the
+	// ring is built in version order.
+	err = kmsring.ForEach(len(sortedVersions), func(i int) error {
+		v := sortedVersions[i]
 		b, err := fetchKeyVersion(ctx, client, mount, path, v, o.field)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		keys = append(keys, fetched{bytes: b, id: o.keyIDFormat(v), version: v})
+		keys[i] = fetched{bytes: b, id: o.keyIDFormat(v), version: v}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Build a KeyRingProvider with current key and old keys for decryption.
@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// TransitClient abstracts Vault's Transit secrets engine encrypt and
+// decrypt operations. Implement it with the Vault API client:
+//
+//	func (c *myVault) TransitEncrypt(ctx context.Context, mount, keyName string, plaintext []byte) (string, error) {
+//	    s, err := c.api.Logical().WriteWithContext(ctx, mount+"/encrypt/"+keyName,
+//	        map[string]any{"plaintext": base64.StdEncoding.EncodeToString(plaintext)})
+//	    if err != nil { return "", err }
+//	    return s.Data["ciphertext"].(string), nil
+//	}
+//
+//	func (c *myVault) TransitDecrypt(ctx context.Context, mount, keyName, ciphertext string) ([]byte, error) {
+//	    s, err := c.api.Logical().WriteWithContext(ctx, mount+"/decrypt/"+keyName,
+//	        map[string]any{"ciphertext": ciphertext})
+//	    if err != nil { return nil, err }
+//	    return base64.StdEncoding.DecodeString(s.Data["plaintext"].(string))
+//	}
+type TransitClient interface {
+	// TransitEncrypt encrypts plaintext with the Transit key keyName in the
+	// engine mounted at mount and returns Vault's ciphertext, such as
+	// "vault:v3:...".
+	TransitEncrypt(ctx context.Context, mount, keyName string, plaintext []byte) (ciphertext string, err error)
+
+	// TransitDecrypt decrypts a ciphertext returned by TransitEncrypt.
+	TransitDecrypt(ctx context.Context, mount, keyName, ciphertext string) (plaintext []byte, err error)
+}
+
+// NewTransitRemote returns a crypto.Provider that retains client and never
+// holds a plaintext KEK: every Encrypt generates a DEK locally and has
+// Transit encrypt it with keyName, embedding Vault's ciphertext in the
+// envelope, and every Decrypt has Transit decrypt it. Vault policies on
+// mount/decrypt/keyName then govern every read of config, and every read
+// shows up in Vault's audit log:
+//
+//	provider, err := vault.NewTransitRemote(client, "transit", "config")
+//
+// The header records the Transit key name; Vault's ciphertext carries the
+// key version, so values stay decryptable after the key rotates as long as
+// its min_decryption_version allows. Each Encrypt and Decrypt costs one
+// Vault call, so for hot paths enable crypto.WithDecodeCache on the codec.
+// opts are passed to crypto.NewWrappingProvider.
+//
+// Unlike New, values are tied to the Transit key: they can only be read
+// through this Vault, and a key ring provider cannot decrypt them.
+func NewTransitRemote(client TransitClient, mount, keyName string, opts ...crypto.ProviderOption) (crypto.Provider, error) {
+	if client == nil {
+		return nil, errors.New("vault: TransitClient must not be nil")
+	}
+	if mount == "" {
+		return nil, errors.New("vault: mount must not be empty")
+	}
+	if keyName == "" || strings.Contains(keyName, "/") {
+		return nil, fmt.Errorf("vault: %w: invalid Transit key name %q", crypto.ErrInvalidKeyID, keyName)
+	}
+	return crypto.NewWrappingProvider(&transitWrapper{client: client, mount: mount, keyName: keyName}, opts...)
+}
+
+// transitWrapper adapts a TransitClient to crypto.KeyWrapper.
+type transitWrapper struct {
+	client  TransitClient
+	mount   string
+	keyName string
+}
+
+// Name returns "vault".
+func (w *transitWrapper) Name() string { return "vault" }
+
+// WrapKey encrypts dek with the configured Transit key.
+func (w *transitWrapper) WrapKey(ctx context.Context, dek []byte) (string, []byte, error) {
+	ciphertext, err := w.client.TransitEncrypt(ctx, w.mount, w.keyName, dek)
+	if err != nil {
+		return "", nil, fmt.Errorf("vault: transit encrypt DEK: %w", err)
+	}
+	if !strings.HasPrefix(ciphertext, "vault:") {
+		return "", nil, errors.New("vault: transit encrypt DEK: unexpected ciphertext format")
+	}
+	return w.keyName, []byte(ciphertext), nil
+}
+
+// UnwrapKey decrypts a DEK with the Transit key recorded in the header.
+func (w *transitWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	dek, err := w.client.TransitDecrypt(ctx, w.mount, keyID, string(wrapped))
+	if err != nil {
+		return nil, fmt.Errorf("vault: transit decrypt DEK: %w", err)
+	}
+	return dek, nil
+}
+
+type transitKeyEntry struct {
+	ciphertext string
+	id         string
+	keyName    string
+}
+
+// WithTransitKey adds a KEK for NewTransit: ciphertext is the Transit
+// ciphertext ("vault:v1:...") of a 32-byte key encrypted with the Transit
+// key keyName, and id names the key in the config-crypto ring. The first
+// key added becomes the current key for new encryptions.
+func WithTransitKey(ciphertext, id, keyName string) Option {
+	return func(o *options) {
+		o.transitKeys = append(o.transitKeys, transitKeyEntry{ciphertext: ciphertext, id: id, keyName: keyName})
+	}
+}
+
+// NewTransit creates a crypto.KeyRingProvider from KEKs encrypted with the
+// Transit engine mounted at mount, added with WithTransitKey. The first
+// key is the current key for new encryptions; additional keys are
+// available for decryption (key rotation).
+//
+// Keys are decrypted concurrently during construction and cached; if
+// client implements BatchTransitClient, all keys encrypted with the same
+// Transit key are decrypted in a single request. If any key fails, the
+// errors for all failed keys are returned together. The client is not
+// retained after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the decrypted key material and is safe to call more than once.
+func NewTransit(ctx context.Context, client TransitClient, mount string, opts ...Option) (crypto.KeyRingProvider, error) {
+	if client == nil {
+		return nil, errors.New("vault: TransitClient must not be nil")
+	}
+	if mount == "" {
+		return nil, errors.New("vault: mount must not be empty")
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	results := decryptTransitKeys(ctx, client, mount, o.transitKeys)
+	return kmsring.Build(len(o.transitKeys), "vault", func(i int) ([]byte, string, error) {
+		return results[i].Plaintext, o.transitKeys[i].id, results[i].Err
+	})
+}
@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// transitMock is an in-memory Transit engine: ciphertexts are
+// "vault:v<N>:" and the base64 of the plaintext XORed with the key
+// version.
+type transitMock struct {
+	mu       sync.Mutex
+	mount    string
+	keys     map[string]int // key name -> latest version
+	decrypts int
+}
+
+func (m *transitMock) TransitEncrypt(_ context.Context, mount, keyName string, plaintext []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.keys[keyName]
+	if mount != m.mount || !ok {
+		return "", fmt.Errorf("transit: 404 no key %s/%s", mount, keyName)
+	}
+	b := bytes.Clone(plaintext)
+	for i := range b {
+		b[i] ^= byte(v)
+	}
+	return fmt.Sprintf("vault:v%d:%s", v, base64.StdEncoding.EncodeToString(b)), nil
+}
+
+func (m *transitMock) TransitDecrypt(_ context.Context, mount, keyName, ciphertext string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decrypts++
+	if _, ok := m.keys[keyName]; mount != m.mount || !ok {
+		return nil, fmt.Errorf("transit: 404 no key %s/%s", mount, keyName)
+	}
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, errors.New("transit: 400 invalid ciphertext")
+	}
+	v, _ := strconv.Atoi(strings.TrimPrefix(parts[1], "v"))
+	b, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	for i := range b {
+		b[i] ^= byte(v)
+	}
+	return b, nil
+}
+
+var _ TransitClient = (*transitMock)(nil)
+
+func TestNewTransitRemote_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	tr := &transitMock{mount: "transit", keys: map[string]int{"config": 1}}
+	p, err := NewTransitRemote(tr, "transit", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.Name() != "vault" {
+		t.Errorf("Name = %q, want vault", p.Name())
+	}
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := crypto.KeyIDOf(ct); id != "config" {
+		t.Errorf("KeyIDOf = %q, want config", id)
+	}
+
+	// Rotating the Transit key does not affect existing values.
+	tr.keys["config"] = 2
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+	if tr.decrypts != 1 {
+		t.Errorf("TransitDecrypt calls = %d, want 1", tr.decrypts)
+	}
+}
+
+func TestNewTransitRemote_Errors(t *testing.T) {
+	p, err := NewTransitRemote(&transitMock{mount: "transit"}, "transit", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.Encrypt(context.Background(), []byte("secret")); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected Transit error, got %v", err)
+	}
+	if _, err := NewTransitRemote(nil, "transit", "config"); err == nil {
+		t.Error("expected error for nil client")
+	}
+	if _, err := NewTransitRemote(&transitMock{}, "", "config"); err == nil {
+		t.Error("expected error for empty mount")
+	}
+	if _, err := NewTransitRemote(&transitMock{}, "transit", ""); !crypto.IsInvalidKeyID(err) {
+		t.Errorf("expected ErrInvalidKeyID, got %v", err)
+	}
+}
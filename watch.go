@@ -0,0 +1,162 @@
+package crypto
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// KeyEventType identifies what changed in a KeyEvent.
+type KeyEventType uint8
+
+const (
+	// KeyAdded reports a key added with AddKey.
+	KeyAdded KeyEventType = iota + 1
+
+	// KeyRemoved reports a key removed with RemoveKey.
+	KeyRemoved
+
+	// CurrentKeyChanged reports a new current key set with SetCurrentKey.
+	CurrentKeyChanged
+
+	// KeyMetadataChanged reports metadata replaced with SetKeyMetadata.
+	KeyMetadataChanged
+)
+
+// String returns the event type's name, e.g. "removed".
+func (t KeyEventType) String() string {
+	switch t {
+	case KeyAdded:
+		return "added"
+	case KeyRemoved:
+		return "removed"
+	case CurrentKeyChanged:
+		return "current-changed"
+	case KeyMetadataChanged:
+		return "metadata-changed"
+	default:
+		return fmt.Sprintf("KeyEventType(%d)", t)
+	}
+}
+
+// KeyEvent describes one change to a provider's key set.
+type KeyEvent struct {
+	Type KeyEventType
+
+	// KeyID is the key the event concerns; for CurrentKeyChanged it is the
+	// new current key.
+	KeyID string
+}
+
+// Watcher is implemented by providers whose key set can change at runtime,
+// so caches and refreshable wrappers can react to rotation and revocation.
+// The built-in key ring implements it, and so do the providers returned by
+// the KMS, Vault, and GPG packages. A Codec with a decode cache watches its
+// provider and purges the cache when a key is removed or its metadata
+// changes.
+type Watcher interface {
+	// Watch returns a channel of key events that occur after the call. The
+	// channel is closed when ctx is done or the provider is closed. Events
+	// are buffered; if the receiver falls behind and the buffer fills,
+	// further events are dropped until it catches up, so receivers should
+	// treat an event as "re-read the provider's state", not as a complete
+	// change log.
+	Watch(ctx context.Context) <-chan KeyEvent
+}
+
+// watchBuffer is the per-subscriber event buffer size.
+const watchBuffer = 64
+
+// watchHub fans key events out to Watch subscribers. The zero value is
+// ready to use.
+type watchHub struct {
+	mu     sync.Mutex
+	subs   map[*watchSub]struct{}
+	closed bool
+}
+
+type watchSub struct {
+	ch   chan KeyEvent
+	done chan struct{} // closed when the hub drops the subscriber
+}
+
+// watch registers a subscriber that is dropped when ctx is done or the hub
+// is closed.
+func (h *watchHub) watch(ctx context.Context) <-chan KeyEvent {
+	sub := &watchSub{ch: make(chan KeyEvent, watchBuffer), done: make(chan struct{})}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(sub.ch)
+		return sub.ch
+	}
+	if h.subs == nil {
+		h.subs = make(map[*watchSub]struct{})
+	}
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			h.drop(sub)
+		case <-sub.done:
+		}
+	}()
+	return sub.ch
+}
+
+// drop unregisters sub and closes its channels, once.
+func (h *watchHub) drop(sub *watchSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+	delete(h.subs, sub)
+	close(sub.ch)
+	close(sub.done)
+}
+
+// notify delivers ev to every subscriber without blocking.
+func (h *watchHub) notify(ev KeyEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		select {
+		case sub.ch <- ev:
+		default: // receiver is behind; drop
+		}
+	}
+}
+
+// close drops every subscriber and rejects future ones.
+func (h *watchHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+		close(sub.done)
+	}
+}
+
+// Compile-time interface check.
+var _ Watcher = (*keyRingProvider)(nil)
+
+// Watch returns a channel of changes to the key ring; see Watcher.
+func (p *keyRingProvider) Watch(ctx context.Context) <-chan KeyEvent {
+	return p.watchers.watch(ctx)
+}
+
+// watchProvider purges c's decode cache on key events that could make a
+// cached plaintext stale, until ch is closed.
+func (c *Codec) watchProvider(ch <-chan KeyEvent) {
+	for ev := range ch {
+		switch ev.Type {
+		case KeyRemoved, KeyMetadataChanged:
+			c.PurgeDecodeCache()
+		}
+	}
+}
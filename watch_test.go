@@ -0,0 +1,140 @@
+package crypto
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/rbaliyan/config/codec/json"
+)
+
+// nextEvent receives one event from ch or fails after a timeout.
+func nextEvent(t *testing.T, ch <-chan KeyEvent) KeyEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("watch channel closed unexpectedly")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for key event")
+		return KeyEvent{}
+	}
+}
+
+func TestKeyRingWatch(t *testing.T) {
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 1)
+	ch := ring.(Watcher).Watch(context.Background())
+
+	k2 := makeKey(32)
+	k2[0] = 2
+	if err := ring.AddKey(k2, "k2", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if err := ring.SetCurrentKey("k2"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	if err := ring.(KeyInfoProvider).SetKeyMetadata("k1", KeyMetadata{Usage: KeyUsageDecryptOnly}); err != nil {
+		t.Fatalf("SetKeyMetadata: %v", err)
+	}
+	if err := ring.RemoveKey("k1"); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+	// Failed mutations emit nothing.
+	_ = ring.RemoveKey("missing")
+
+	for _, want := range []KeyEvent{
+		{KeyAdded, "k2"},
+		{CurrentKeyChanged, "k2"},
+		{KeyMetadataChanged, "k1"},
+		{KeyRemoved, "k1"},
+	} {
+		if got := nextEvent(t, ch); got != want {
+			t.Errorf("event = %+v (%s), want %+v", got, got.Type, want)
+		}
+	}
+
+	if err := ring.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("watch channel not closed after provider Close")
+	}
+	if _, ok := <-ring.(Watcher).Watch(context.Background()); ok {
+		t.Error("Watch after Close returned an open channel")
+	}
+}
+
+func TestKeyRingWatchContextCancel(t *testing.T) {
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ring.(Watcher).Watch(ctx)
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("received an event instead of close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch channel not closed after cancel")
+	}
+	// Notifying after the subscriber is gone must not panic.
+	if err := ring.AddKey(makeKey(32), "k2", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+}
+
+func TestKeyRingWatchDropsWhenFull(t *testing.T) {
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 1)
+	ch := ring.(Watcher).Watch(context.Background())
+	for range watchBuffer + 10 {
+		if err := ring.SetCurrentKey("k1"); err != nil {
+			t.Fatalf("SetCurrentKey: %v", err)
+		}
+	}
+	if n := len(ch); n != watchBuffer {
+		t.Errorf("buffered %d events, want %d", n, watchBuffer)
+	}
+}
+
+func TestCodecDecodeCachePurgedOnKeyRemoval(t *testing.T) {
+	ctx := context.Background()
+	ring := mustNewKeyRingProvider(t, makeKey(32), "k1", 1)
+	k2 := makeKey(32)
+	k2[0] = 2
+	if err := ring.AddKey(k2, "k2", 2); err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	c, err := NewCodec(jsoncodec.New(), ring, WithDecodeCache(10, 0, time.Hour))
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	data, _ := c.Encode(ctx, "v")
+	var s string
+	if err := c.Decode(ctx, data, &s); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if c.cache.len() != 1 {
+		t.Fatalf("cache holds %d entries, want 1", c.cache.len())
+	}
+
+	if err := ring.SetCurrentKey("k2"); err != nil {
+		t.Fatalf("SetCurrentKey: %v", err)
+	}
+	if err := ring.RemoveKey("k1"); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for c.cache.len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("decode cache not purged after key removal")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.Decode(ctx, data, &s); !IsKeyNotFound(err) {
+		t.Errorf("Decode after removal: got %v, want ErrKeyNotFound", err)
+	}
+}
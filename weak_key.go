@@ -0,0 +1,134 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// WeakKeyError reports key material that CheckKey rejected.
+type WeakKeyError struct {
+	// KeyID is the ID the key was offered under; empty from CheckKey.
+	KeyID string
+
+	// Reason describes the problem, e.g. "all bytes are zero".
+	Reason string
+}
+
+// Error implements error.
+func (e *WeakKeyError) Error() string {
+	if e.KeyID == "" {
+		return fmt.Sprintf("%s: %s", ErrWeakKey, e.Reason)
+	}
+	return fmt.Sprintf("%s %q: %s", ErrWeakKey, e.KeyID, e.Reason)
+}
+
+// Unwrap returns ErrWeakKey so errors.Is and IsWeakKey match.
+func (e *WeakKeyError) Unwrap() error { return ErrWeakKey }
+
+// CheckKey rejects 32-byte keys that are obviously not random: all zero, a
+// short repeating pattern (such as one byte or a 16-byte half repeated), or
+// entirely printable ASCII, which usually means a passphrase or a decoded
+// password was used as the key. Random keys pass with overwhelming
+// probability. It returns a *WeakKeyError, or ErrInvalidKeySize for keys of
+// the wrong length.
+//
+// CheckKey cannot prove a key is strong; a key derived from a guessable
+// secret passes if it looks random. Generate keys with crypto/rand or a KMS.
+func CheckKey(keyBytes []byte) error {
+	if len(keyBytes) != aesKeySize {
+		return fmt.Errorf("%w: got %d bytes", ErrInvalidKeySize, len(keyBytes))
+	}
+	if allZero(keyBytes) {
+		return &WeakKeyError{Reason: "all bytes are zero"}
+	}
+	for period := 1; period <= len(keyBytes)/2; period++ {
+		if len(keyBytes)%period == 0 && bytes.Equal(keyBytes[period:], keyBytes[:len(keyBytes)-period]) {
+			return &WeakKeyError{Reason: fmt.Sprintf("repeats a %d-byte pattern", period)}
+		}
+	}
+	if printableASCII(keyBytes) {
+		return &WeakKeyError{Reason: "all bytes are printable ASCII; looks like a passphrase"}
+	}
+	return nil
+}
+
+// KeyEntropy returns the Shannon entropy of keyBytes' byte distribution, in
+// bits per byte. It is a rough estimate: 32 random bytes typically score
+// about 4.9 (the maximum for 32 bytes is 5), while keys built from a few
+// distinct byte values score far lower.
+func KeyEntropy(keyBytes []byte) float64 {
+	if len(keyBytes) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range keyBytes {
+		counts[b]++
+	}
+	n := float64(len(keyBytes))
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+// WithWeakKeyCheck makes NewProvider, NewKeyRingProvider, and the ring's
+// AddKey run CheckKey and refuse weak keys with a *WeakKeyError.
+func WithWeakKeyCheck() ProviderOption {
+	return func(o *providerOptions) {
+		o.keyCheck.enabled = true
+	}
+}
+
+// WithMinKeyEntropy enables WithWeakKeyCheck and additionally refuses keys
+// whose KeyEntropy is below bitsPerByte. A threshold of 4 rejects
+// low-diversity keys; random keys essentially never score that low.
+func WithMinKeyEntropy(bitsPerByte float64) ProviderOption {
+	return func(o *providerOptions) {
+		o.keyCheck = keyCheck{enabled: true, minEntropy: bitsPerByte}
+	}
+}
+
+// keyCheck holds the weak-key options of a provider.
+type keyCheck struct {
+	enabled    bool
+	minEntropy float64
+}
+
+// check applies c to keyBytes offered under id.
+func (c keyCheck) check(keyBytes []byte, id string) error {
+	if !c.enabled {
+		return nil
+	}
+	if err := CheckKey(keyBytes); err != nil {
+		if we, ok := err.(*WeakKeyError); ok {
+			we.KeyID = id
+		}
+		return err
+	}
+	if e := KeyEntropy(keyBytes); e < c.minEntropy {
+		return &WeakKeyError{KeyID: id, Reason: fmt.Sprintf("entropy %.2f bits/byte is below %.2f", e, c.minEntropy)}
+	}
+	return nil
+}
+
+func allZero(b []byte) bool {
+	var acc byte
+	for _, c := range b {
+		acc |= c
+	}
+	return acc == 0
+}
+
+func printableASCII(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestCheckKey(t *testing.T) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckKey(random); err != nil {
+		t.Errorf("random key rejected: %v", err)
+	}
+	if err := CheckKey(makeKey(32)); err != nil {
+		t.Errorf("sequential test key rejected: %v", err)
+	}
+	if err := CheckKey(make([]byte, 16)); !IsInvalidKeySize(err) {
+		t.Errorf("short key: got %v, want ErrInvalidKeySize", err)
+	}
+
+	half := random[:16]
+	weak := map[string][]byte{
+		"zero":       make([]byte, 32),
+		"one byte":   bytes.Repeat([]byte{0xAA}, 32),
+		"four bytes": bytes.Repeat([]byte{1, 2, 3, 4}, 8),
+		"halves":     append(bytes.Clone(half), half...),
+		"passphrase": []byte("correct-horse-battery-staple-!!!"),
+	}
+	for name, k := range weak {
+		err := CheckKey(k)
+		var we *WeakKeyError
+		if !IsWeakKey(err) || !errors.As(err, &we) || we.Reason == "" {
+			t.Errorf("%s: got %v, want *WeakKeyError", name, err)
+		}
+	}
+}
+
+func TestKeyEntropy(t *testing.T) {
+	if e := KeyEntropy(bytes.Repeat([]byte{7}, 32)); e != 0 {
+		t.Errorf("constant key entropy = %v, want 0", e)
+	}
+	if e := KeyEntropy(makeKey(32)); e != 5 {
+		t.Errorf("distinct-byte key entropy = %v, want 5", e)
+	}
+}
+
+func TestWithWeakKeyCheck(t *testing.T) {
+	if _, err := NewProvider(make([]byte, 32), "k1"); err != nil {
+		t.Fatalf("without the option a zero key is accepted: %v", err)
+	}
+	_, err := NewProvider(make([]byte, 32), "k1", WithWeakKeyCheck())
+	var we *WeakKeyError
+	if !errors.As(err, &we) || we.KeyID != "k1" {
+		t.Fatalf("NewProvider zero key: got %v, want *WeakKeyError for k1", err)
+	}
+
+	ring, err := NewKeyRingProvider(makeKey(32), "k1", 0, WithWeakKeyCheck())
+	if err != nil {
+		t.Fatalf("NewKeyRingProvider: %v", err)
+	}
+	defer ring.Close()
+	if err := ring.AddKey([]byte("0123456789abcdef0123456789abcdef"), "k2", 1); !IsWeakKey(err) {
+		t.Errorf("AddKey ASCII key: got %v, want ErrWeakKey", err)
+	}
+}
+
+func TestWithMinKeyEntropy(t *testing.T) {
+	lowDiversity := bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7}, 5)[:32]
+	if err := CheckKey(lowDiversity); err != nil {
+		t.Fatalf("CheckKey: %v", err)
+	}
+	if _, err := NewProvider(lowDiversity, "k1", WithMinKeyEntropy(4)); !IsWeakKey(err) {
+		t.Errorf("low-entropy key: got %v, want ErrWeakKey", err)
+	}
+	p, err := NewProvider(makeKey(32), "k1", WithMinKeyEntropy(4))
+	if err != nil {
+		t.Fatalf("high-entropy key rejected: %v", err)
+	}
+	_ = p.Close()
+}
@@ -0,0 +1,167 @@
+package crypto
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// KeyWrapper wraps and unwraps DEKs under a KEK that never leaves its
+// backend: an HSM, a KMS, or a remote key service. Wrap one with
+// NewWrappingProvider to use it as a Provider. Implementations must be safe
+// for concurrent use.
+type KeyWrapper interface {
+	// WrapKey wraps dek under the current KEK and returns that KEK's ID,
+	// which is recorded in the ciphertext header, and the wrapped bytes
+	// (at most 65535).
+	WrapKey(ctx context.Context, dek []byte) (keyID string, wrapped []byte, err error)
+
+	// UnwrapKey returns the DEK that WrapKey wrapped under the KEK keyID,
+	// or an error wrapping ErrKeyNotFound if the wrapper does not know
+	// keyID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// wrappingProvider is a Provider whose DEKs are wrapped by a KeyWrapper.
+type wrappingProvider struct {
+	w      KeyWrapper
+	rand   io.Reader
+	alg    byte
+	closed atomic.Bool
+}
+
+// Compile-time interface check.
+var _ BufferDecrypter = (*wrappingProvider)(nil)
+
+// NewWrappingProvider returns a Provider that keeps no KEK in process
+// memory: every Encrypt generates a DEK locally and has w wrap it, and every
+// Decrypt has w unwrap the DEK from the header, so each operation costs one
+// round trip to the backend. Values use a distinct envelope format that
+// only a wrapping provider can decrypt. WithRandReader and
+// WithAutoAlgorithm apply; key-ring options are ignored.
+//
+// Name returns w's Name if it has one, else "wrapping". HealthCheck calls
+// w's HealthCheck(ctx) error if it has one, else wraps a throwaway DEK.
+// Close closes w if it implements io.Closer.
+func NewWrappingProvider(w KeyWrapper, opts ...ProviderOption) (Provider, error) {
+	if w == nil {
+		return nil, errors.New("crypto: NewWrappingProvider wrapper is nil")
+	}
+	o := &providerOptions{rand: rand.Reader, algorithm: algAES256GCM}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &wrappingProvider{w: w, rand: o.rand, alg: o.algorithm}, nil
+}
+
+// Name returns the wrapper's name, or "wrapping".
+func (p *wrappingProvider) Name() string {
+	if n, ok := p.w.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return "wrapping"
+}
+
+// Connect is a no-op.
+func (p *wrappingProvider) Connect(_ context.Context) error { return nil }
+
+// Encrypt encrypts plaintext under a fresh DEK wrapped by the wrapper.
+func (p *wrappingProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	dek := make([]byte, aesKeySize)
+	if _, err := io.ReadFull(p.rand, dek); err != nil {
+		return nil, fmt.Errorf("crypto: failed to generate DEK: %w", err)
+	}
+	defer clear(dek)
+
+	keyID, wrapped, err := p.w.WrapKey(ctx, dek)
+	if err != nil {
+		return nil, newProviderError(p, OpEncrypt, "", fmt.Errorf("crypto: wrap DEK: %w", err))
+	}
+	if keyID == "" || len(keyID) > maxKeyIDLen {
+		return nil, fmt.Errorf("%w: wrapper returned key ID of %d bytes", ErrInvalidKeyID, len(keyID))
+	}
+	if len(wrapped) == 0 || len(wrapped) > maxEncryptedDEKLen {
+		return nil, fmt.Errorf("crypto: wrapper returned %d-byte wrapped DEK", len(wrapped))
+	}
+
+	aead, err := newAEAD(p.alg, dek)
+	if err != nil {
+		return nil, fmt.Errorf("crypto: failed to create DEK cipher: %w", err)
+	}
+	w := &wrappedDEK{
+		alg:          p.alg,
+		keyID:        keyID,
+		aead:         aead,
+		dekNonce:     make([]byte, gcmNonceSize),
+		encryptedDEK: wrapped,
+		remote:       true,
+	}
+	return w.seal(p.rand, plaintext)
+}
+
+// Decrypt decrypts ciphertext after having the wrapper unwrap its DEK.
+func (p *wrappingProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.DecryptTo(ctx, nil, ciphertext)
+}
+
+// DecryptTo decrypts ciphertext and appends the plaintext to dst.
+func (p *wrappingProvider) DecryptTo(ctx context.Context, dst, ciphertext []byte) ([]byte, error) {
+	if p.closed.Load() {
+		return nil, ErrProviderClosed
+	}
+	h, payload, err := readHeader(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if h.format != formatRemoteWrap {
+		return nil, fmt.Errorf("%w: DEK for key %q is not wrapped by a KeyWrapper", ErrUnsupportedFormat, h.keyID)
+	}
+	if len(payload) < gcmTagSize {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrInvalidFormat)
+	}
+
+	dek, err := p.w.UnwrapKey(ctx, h.keyID, h.encryptedDEK)
+	if err != nil {
+		return nil, newProviderError(p, OpDecrypt, h.keyID, err)
+	}
+	defer clear(dek)
+	if len(dek) != aesKeySize {
+		return nil, fmt.Errorf("%w: unwrapped DEK is %d bytes", ErrDecryptionFailed, len(dek))
+	}
+	return openPayload(dst, h, payload, dek)
+}
+
+// HealthCheck confirms the wrapper can wrap a key.
+func (p *wrappingProvider) HealthCheck(ctx context.Context) error {
+	if p.closed.Load() {
+		return ErrProviderClosed
+	}
+	if hc, ok := p.w.(interface{ HealthCheck(context.Context) error }); ok {
+		return hc.HealthCheck(ctx)
+	}
+	dek := make([]byte, aesKeySize)
+	if _, err := io.ReadFull(p.rand, dek); err != nil {
+		return err
+	}
+	defer clear(dek)
+	_, _, err := p.w.WrapKey(ctx, dek)
+	return err
+}
+
+// Close closes the wrapper if it implements io.Closer. Safe to call
+// multiple times; subsequent calls are no-ops.
+func (p *wrappingProvider) Close() error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+	if c, ok := p.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// gcmWrapper is a KeyWrapper holding AES keys in memory, standing in for
+// an HSM.
+type gcmWrapper struct {
+	current string
+	keys    map[string][]byte
+	wraps   atomic.Int32
+	closed  atomic.Bool
+}
+
+func (w *gcmWrapper) aead(id string) (cipher.AEAD, error) {
+	k, ok := w.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	b, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithRandomNonce(b)
+}
+
+func (w *gcmWrapper) WrapKey(_ context.Context, dek []byte) (string, []byte, error) {
+	w.wraps.Add(1)
+	a, err := w.aead(w.current)
+	if err != nil {
+		return "", nil, err
+	}
+	return w.current, a.Seal(nil, nil, dek, nil), nil
+}
+
+func (w *gcmWrapper) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	a, err := w.aead(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return a.Open(nil, nil, wrapped, nil)
+}
+
+func (w *gcmWrapper) Close() error {
+	w.closed.Store(true)
+	return nil
+}
+
+func newGCMWrapper() *gcmWrapper {
+	return &gcmWrapper{current: "hsm-1", keys: map[string][]byte{"hsm-1": makeKey(32)}}
+}
+
+func TestWrappingProviderRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	w := newGCMWrapper()
+	p, err := NewWrappingProvider(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "wrapping" {
+		t.Errorf("Name = %q, want wrapping", p.Name())
+	}
+
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := KeyIDOf(ct); id != "hsm-1" {
+		t.Errorf("KeyIDOf = %q, want hsm-1", id)
+	}
+	pt, err := p.Decrypt(ctx, ct)
+	if err != nil || string(pt) != "secret" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+
+	// Rotating the wrapper's current key keeps old values readable.
+	w.keys["hsm-2"] = make([]byte, 32)
+	w.current = "hsm-2"
+	if pt, err := p.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt after rotation = %q, %v", pt, err)
+	}
+
+	if err := p.HealthCheck(ctx); err != nil {
+		t.Errorf("HealthCheck: %v", err)
+	}
+	_ = p.Close()
+	_ = p.Close()
+	if !w.closed.Load() {
+		t.Error("Close did not close the wrapper")
+	}
+	if _, err := p.Encrypt(ctx, []byte("x")); !IsProviderClosed(err) {
+		t.Errorf("Encrypt after Close: got %v, want ErrProviderClosed", err)
+	}
+}
+
+func TestWrappingProviderTampered(t *testing.T) {
+	ctx := context.Background()
+	p, _ := NewWrappingProvider(newGCMWrapper())
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct[len(ct)-1] ^= 1
+	if _, err := p.Decrypt(ctx, ct); !IsDecryptionFailed(err) {
+		t.Errorf("tampered payload: got %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestWrappingProviderUnknownKey(t *testing.T) {
+	ctx := context.Background()
+	w := newGCMWrapper()
+	p, _ := NewWrappingProvider(w)
+	ct, err := p.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(w.keys, "hsm-1")
+	_, err = p.Decrypt(ctx, ct)
+	var pe *ProviderError
+	if !IsKeyNotFound(err) || !errors.As(err, &pe) || pe.KeyID != "hsm-1" {
+		t.Errorf("got %v, want ProviderError for hsm-1 wrapping ErrKeyNotFound", err)
+	}
+}
+
+func TestWrappingProviderFormatsDoNotMix(t *testing.T) {
+	ctx := context.Background()
+	wp, _ := NewWrappingProvider(newGCMWrapper())
+	ring := mustNewKeyRingProvider(t, makeKey(32), "hsm-1", 1)
+	defer ring.Close()
+
+	wrapped, err := wp.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.Decrypt(ctx, wrapped); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("ring decrypting wrapped value: got %v, want ErrUnsupportedFormat", err)
+	}
+	local, err := ring.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wp.Decrypt(ctx, local); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("wrapping provider decrypting local value: got %v, want ErrUnsupportedFormat", err)
+	}
+}
+
+func TestNewWrappingProviderNil(t *testing.T) {
+	if _, err := NewWrappingProvider(nil); err == nil {
+		t.Error("expected error for nil wrapper")
+	}
+}
@@ -0,0 +1,96 @@
+package yubikey
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxStderrLen is the maximum number of bytes included from ykman stderr
+// in error messages.
+const maxStderrLen = 200
+
+// ExecClient performs challenge-response by invoking the YubiKey Manager
+// CLI, ykman, which must be installed. Program the slot once with
+//
+//	ykman otp chalresp --generate --touch 2
+type ExecClient struct {
+	bin    string // path to ykman binary, defaults to "ykman"
+	device string // serial number; empty uses the only connected key
+}
+
+// ExecOption configures an ExecClient.
+type ExecOption func(*ExecClient)
+
+// WithYkmanBinary sets the path to the ykman binary.
+// Defaults to "ykman" (resolved via PATH).
+func WithYkmanBinary(path string) ExecOption {
+	return func(c *ExecClient) {
+		c.bin = path
+	}
+}
+
+// WithDevice selects the YubiKey with the given serial number when more
+// than one is connected.
+func WithDevice(serial string) ExecOption {
+	return func(c *ExecClient) {
+		c.device = serial
+	}
+}
+
+// NewExecClient creates an ExecClient that delegates to ykman.
+func NewExecClient(opts ...ExecOption) *ExecClient {
+	c := &ExecClient{bin: "ykman"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ChallengeResponse runs "ykman otp calculate" for slot and challenge.
+func (c *ExecClient) ChallengeResponse(ctx context.Context, slot int, challenge []byte) ([]byte, error) {
+	var args []string
+	if c.device != "" {
+		args = append(args, "--device", c.device)
+	}
+	args = append(args, "otp", "calculate", strconv.Itoa(slot), hex.EncodeToString(challenge))
+	cmd := exec.CommandContext(ctx, c.bin, args...) // #nosec G204 -- bin is developer-configured, not user input
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	defer clear(stdout.Bytes())
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("yubikey: ykman: %w: %s", err, sanitizeStderr(stderr.Bytes()))
+	}
+	resp, err := hex.DecodeString(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return nil, fmt.Errorf("yubikey: ykman: malformed response: %w", err)
+	}
+	return resp, nil
+}
+
+// sanitizeStderr returns a truncated, printable-only excerpt of ykman
+// stderr suitable for inclusion in error messages.
+func sanitizeStderr(raw []byte) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsPrint(r) || r == '\n' {
+			return r
+		}
+		return -1
+	}, string(raw))
+	s = strings.TrimSpace(s)
+	if len(s) > maxStderrLen {
+		s = s[:maxStderrLen] + "..."
+	}
+	return s
+}
+
+// Compile-time interface check.
+var _ ChallengeResponder = (*ExecClient)(nil)
@@ -0,0 +1,56 @@
+package yubikey
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// fakeYkman writes a shell script standing in for ykman.
+func fakeYkman(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script stand-in requires a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "ykman")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewExecClientDefaults(t *testing.T) {
+	if c := NewExecClient(); c.bin != "ykman" {
+		t.Errorf("default bin: got %q, want %q", c.bin, "ykman")
+	}
+}
+
+func TestExecClientChallengeResponse(t *testing.T) {
+	// Fail unless called with the expected arguments.
+	script := `[ "$*" = "--device 123 otp calculate 2 0102" ] || { echo "bad args: $*" >&2; exit 2; }
+echo 0a0b0c`
+	c := NewExecClient(WithYkmanBinary(fakeYkman(t, script)), WithDevice("123"))
+	resp, err := c.ChallengeResponse(context.Background(), 2, []byte{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != "\x0a\x0b\x0c" {
+		t.Errorf("response = %x, want 0a0b0c", resp)
+	}
+}
+
+func TestExecClientFailure(t *testing.T) {
+	c := NewExecClient(WithYkmanBinary(fakeYkman(t, "echo 'No YubiKey detected' >&2; exit 1")))
+	if _, err := c.ChallengeResponse(context.Background(), 2, []byte{1}); err == nil {
+		t.Error("expected error when ykman fails")
+	}
+}
+
+func TestExecClientMalformedResponse(t *testing.T) {
+	c := NewExecClient(WithYkmanBinary(fakeYkman(t, "echo not-hex")))
+	if _, err := c.ChallengeResponse(context.Background(), 2, []byte{1}); err == nil {
+		t.Error("expected error for a non-hex response")
+	}
+}
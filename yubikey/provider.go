@@ -0,0 +1,219 @@
+// Package yubikey provides a crypto.Provider whose keys can only be
+// recovered with a YubiKey, for operators who carry their decryption
+// capability on a hardware token. Two mechanisms are supported:
+//
+//   - HMAC-SHA1 challenge-response (OTP slot 1 or 2): the KEK is derived
+//     with HKDF-SHA256 from the token's response to a stored random
+//     challenge. Nothing secret is stored on disk.
+//   - PIV decrypt: the KEK is stored RSA-OAEP-encrypted to the public key
+//     of a PIV slot (WrapForPIV) and decrypted by the token.
+//
+// Keys are recovered once, at construction time, and cached. If the slot
+// requires touch, the token blinks until touched; WithTouchPrompt lets the
+// caller tell the user so:
+//
+//	challenge, _ := os.ReadFile("kek-1.challenge") // from NewChallenge
+//	provider, err := yubikey.New(ctx,
+//	    yubikey.WithHMACKey(yubikey.NewExecClient(), 2, challenge, "kek-1"),
+//	    yubikey.WithTouchPrompt(func(id string) {
+//	        fmt.Fprintf(os.Stderr, "Touch your YubiKey to unlock %s...\n", id)
+//	    }),
+//	)
+//
+// For PIV, pass the slot's private key from github.com/go-piv/piv-go, which
+// implements crypto.Decrypter for RSA keys:
+//
+//	priv, _ := yk.PrivateKey(piv.SlotKeyManagement, cert.PublicKey, piv.KeyAuth{PIN: pin})
+//	provider, err := yubikey.New(ctx,
+//	    yubikey.WithPIVKey(priv.(stdcrypto.Decrypter), wrappedKEK, "kek-1"),
+//	)
+package yubikey
+
+import (
+	"context"
+	stdcrypto "crypto"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	crypto "github.com/rbaliyan/config-crypto"
+	"github.com/rbaliyan/config-crypto/internal/kmsring"
+)
+
+// hmacKeyInfo is the HKDF info for KEKs derived from a challenge-response.
+const hmacKeyInfo = "config-crypto/yubikey-hmac"
+
+// challengeSize is the size of challenges from NewChallenge. The YubiKey
+// accepts up to 64 bytes.
+const challengeSize = 32
+
+// maxChallengeSize is the largest challenge the YubiKey accepts.
+const maxChallengeSize = 64
+
+// ChallengeResponder performs HMAC-SHA1 challenge-response with a YubiKey
+// OTP slot.
+type ChallengeResponder interface {
+	// ChallengeResponse sends challenge to slot (1 or 2) and returns the
+	// 20-byte HMAC-SHA1 response. It blocks until the key is touched if
+	// the slot requires touch.
+	ChallengeResponse(ctx context.Context, slot int, challenge []byte) ([]byte, error)
+}
+
+// Option configures the YubiKey provider.
+type Option func(*options)
+
+type options struct {
+	keys   []tokenKey
+	prompt func(id string)
+}
+
+type tokenKey struct {
+	id string
+
+	// HMAC challenge-response.
+	cr        ChallengeResponder
+	slot      int
+	challenge []byte
+
+	// PIV decrypt.
+	piv     stdcrypto.Decrypter
+	wrapped []byte
+}
+
+// WithHMACKey registers a KEK derived from the response of slot (1 or 2)
+// to challenge, which should come from NewChallenge and be stored
+// alongside the config. The id identifies this key in the config-crypto
+// system.
+//
+// The first key registered sets the current key used for new encryptions.
+// Subsequent keys are available for decryption during key rotation.
+func WithHMACKey(cr ChallengeResponder, slot int, challenge []byte, id string) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, tokenKey{id: id, cr: cr, slot: slot, challenge: challenge})
+	}
+}
+
+// WithPIVKey registers a KEK stored as wrapped, the output of WrapForPIV,
+// and decrypted with dec, the PIV slot's private key. The id identifies
+// this key in the config-crypto system.
+//
+// The first key registered sets the current key used for new encryptions.
+// Subsequent keys are available for decryption during key rotation.
+func WithPIVKey(dec stdcrypto.Decrypter, wrapped []byte, id string) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, tokenKey{id: id, piv: dec, wrapped: wrapped})
+	}
+}
+
+// WithTouchPrompt sets a callback run before each token operation, with
+// the ID of the key being recovered, so the caller can ask the user to
+// touch the YubiKey.
+func WithTouchPrompt(fn func(id string)) Option {
+	return func(o *options) {
+		o.prompt = fn
+	}
+}
+
+// New creates a crypto.KeyRingProvider from keys recovered with a
+// YubiKey.
+//
+// At least one key must be provided via WithHMACKey or WithPIVKey. The
+// first key is the current key for new encryptions; additional keys support
+// decryption during key rotation.
+//
+// Keys are recovered one at a time, so touch prompts arrive in order. The
+// token is not used after construction.
+//
+// The caller owns the returned provider and must Close it when done; Close
+// wipes the key material and is safe to call more than once.
+func New(ctx context.Context, opts ...Option) (crypto.KeyRingProvider, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, k := range o.keys {
+		switch {
+		case k.cr == nil && k.piv == nil:
+			return nil, fmt.Errorf("yubikey: key %q: ChallengeResponder or Decrypter must not be nil", k.id)
+		case k.cr != nil && (k.slot < 1 || k.slot > 2):
+			return nil, fmt.Errorf("yubikey: key %q: slot must be 1 or 2, got %d", k.id, k.slot)
+		case k.cr != nil && (len(k.challenge) == 0 || len(k.challenge) > maxChallengeSize):
+			return nil, fmt.Errorf("yubikey: key %q: challenge must be 1 to %d bytes", k.id, maxChallengeSize)
+		}
+	}
+
+	// The token handles one request at a time and a touch prompt per key
+	// only makes sense in order, so recover keys sequentially.
+	keys := make([][]byte, len(o.keys))
+	defer func() {
+		for _, k := range keys {
+			clear(k)
+		}
+	}()
+	for i, k := range o.keys {
+		if o.prompt != nil {
+			o.prompt(k.id)
+		}
+		key, err := recoverKey(ctx, k)
+		if err != nil {
+			return nil, fmt.Errorf("yubikey: failed to recover key %q: %w", k.id, err)
+		}
+		keys[i] = key
+	}
+	return kmsring.Build(len(o.keys), "yubikey", func(i int) ([]byte, string, error) {
+		// Build takes ownership and zeroes the key.
+		key := keys[i]
+		keys[i] = nil
+		return key, o.keys[i].id, nil
+	})
+}
+
+// recoverKey derives or decrypts the KEK for k.
+func recoverKey(ctx context.Context, k tokenKey) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if k.piv != nil {
+		return k.piv.Decrypt(rand.Reader, k.wrapped, &rsa.OAEPOptions{Hash: stdcrypto.SHA256})
+	}
+	resp, err := k.cr.ChallengeResponse(ctx, k.slot, k.challenge)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(resp)
+	if len(resp) == 0 {
+		return nil, errors.New("empty challenge response")
+	}
+	return hkdf.Key(sha256.New, resp, k.challenge, hmacKeyInfo, kmsring.KeySize)
+}
+
+// NewChallenge returns a random challenge for WithHMACKey. Store it with
+// the encrypted config; it is not secret, but the same challenge must be
+// used every time to derive the same key.
+func NewChallenge() ([]byte, error) {
+	c := make([]byte, challengeSize)
+	if _, err := rand.Read(c); err != nil {
+		return nil, fmt.Errorf("yubikey: generate challenge: %w", err)
+	}
+	return c, nil
+}
+
+// WrapForPIV encrypts key to pub, the public key of a PIV slot's
+// certificate, with RSA-OAEP-SHA256, for use with WithPIVKey. Only the
+// token holding the matching private key can recover it.
+func WrapForPIV(pub *rsa.PublicKey, key []byte) ([]byte, error) {
+	if pub == nil {
+		return nil, errors.New("yubikey: public key must not be nil")
+	}
+	if len(key) != kmsring.KeySize {
+		return nil, fmt.Errorf("%w: got %d bytes", crypto.ErrInvalidKeySize, len(key))
+	}
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("yubikey: wrap for PIV: %w", err)
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,181 @@
+package yubikey
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" // #nosec G505 -- the YubiKey OTP applet computes HMAC-SHA1
+	"errors"
+	"testing"
+
+	crypto "github.com/rbaliyan/config-crypto"
+)
+
+// mockYubiKey answers challenges with HMAC-SHA1 under a per-slot secret.
+type mockYubiKey struct {
+	secrets map[int][]byte
+	calls   int
+}
+
+func (m *mockYubiKey) ChallengeResponse(ctx context.Context, slot int, challenge []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.calls++
+	secret, ok := m.secrets[slot]
+	if !ok {
+		return nil, errors.New("slot not programmed")
+	}
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(challenge)
+	return mac.Sum(nil), nil
+}
+
+var _ ChallengeResponder = (*mockYubiKey)(nil)
+
+func newMockYubiKey() *mockYubiKey {
+	return &mockYubiKey{secrets: map[int][]byte{2: []byte("0123456789abcdefghij")}}
+}
+
+func TestNew_HMACDeterministic(t *testing.T) {
+	ctx := context.Background()
+	yk := newMockYubiKey()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prompts []string
+	p1, err := New(ctx, WithHMACKey(yk, 2, challenge, "kek-1"),
+		WithTouchPrompt(func(id string) { prompts = append(prompts, id) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p1.Close()
+	if len(prompts) != 1 || prompts[0] != "kek-1" {
+		t.Errorf("touch prompts = %v, want [kek-1]", prompts)
+	}
+	ct, err := p1.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same token and challenge derive the same key later.
+	p2, err := New(ctx, WithHMACKey(yk, 2, challenge, "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p2.Close()
+	if pt, err := p2.Decrypt(ctx, ct); err != nil || string(pt) != "secret" {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+
+	// A different token does not.
+	other := &mockYubiKey{secrets: map[int][]byte{2: []byte("another secret")}}
+	p3, err := New(ctx, WithHMACKey(other, 2, challenge, "kek-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p3.Close()
+	if _, err := p3.Decrypt(ctx, ct); !crypto.IsDecryptionFailed(err) {
+		t.Errorf("Decrypt with another token: got %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestNew_PIV(t *testing.T) {
+	ctx := context.Background()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kek := make([]byte, 32)
+	for i := range kek {
+		kek[i] = byte(i)
+	}
+	wrapped, err := WrapForPIV(&priv.PublicKey, kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, _ := NewChallenge()
+
+	p, err := New(ctx,
+		WithPIVKey(priv, wrapped, "piv-kek"),
+		WithHMACKey(newMockYubiKey(), 2, challenge, "hmac-kek"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.CurrentKeyID() != "piv-kek" {
+		t.Errorf("CurrentKeyID = %q, want piv-kek", p.CurrentKeyID())
+	}
+
+	ref, err := crypto.NewProvider(kek, "piv-kek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ref.Close()
+	ct, err := ref.Encrypt(ctx, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt, err := p.Decrypt(ctx, ct); err != nil || !bytes.Equal(pt, []byte("secret")) {
+		t.Errorf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	challenge, _ := NewChallenge()
+	yk := newMockYubiKey()
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"nil responder", WithHMACKey(nil, 2, challenge, "k")},
+		{"bad slot", WithHMACKey(yk, 3, challenge, "k")},
+		{"empty challenge", WithHMACKey(yk, 2, nil, "k")},
+		{"long challenge", WithHMACKey(yk, 2, make([]byte, 65), "k")},
+		{"unprogrammed slot", WithHMACKey(yk, 1, challenge, "k")},
+		{"empty id", WithHMACKey(yk, 2, challenge, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(context.Background(), tt.opt); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+	if _, err := New(context.Background()); err == nil {
+		t.Error("expected error when no keys are configured")
+	}
+}
+
+func TestNew_StopsAtFirstFailure(t *testing.T) {
+	yk := newMockYubiKey()
+	challenge, _ := NewChallenge()
+	_, err := New(context.Background(),
+		WithHMACKey(yk, 1, challenge, "unprogrammed"),
+		WithHMACKey(yk, 2, challenge, "never-asked"),
+	)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if yk.calls != 1 {
+		t.Errorf("token asked %d times, want 1 (no touch prompts after a failure)", yk.calls)
+	}
+}
+
+func TestWrapForPIV_Validation(t *testing.T) {
+	if _, err := WrapForPIV(nil, make([]byte, 32)); err == nil {
+		t.Error("expected error for nil public key")
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WrapForPIV(&priv.PublicKey, make([]byte, 16)); !crypto.IsInvalidKeySize(err) {
+		t.Errorf("expected ErrInvalidKeySize, got %v", err)
+	}
+}